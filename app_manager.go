@@ -1,16 +1,78 @@
 package gofc
 
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
 type IFMap map[string]interface{}
 
+// default priority used by RegistApplication.
+const DEFAULT_APP_PRIORITY = 0
+
+// priority reserved for gofc's own controller. it always runs first so that
+// connection handling (echo, features) can't be consumed by applications.
+const SYSTEM_APP_PRIORITY = math.MaxInt32
+
+/**
+ * App is implemented by applications which need lifecycle hooks.
+ * Applications which don't implement App are still dispatched messages,
+ * but they are not initialized, started nor stopped by AppManager.
+ */
+type App interface {
+	// unique name of application
+	Name() string
+	// called once before Start. dependencies are already initialized.
+	Init(manager *AppManager) error
+	// called when controller starts. dependencies are already started.
+	Start() error
+	// called when controller shuts down, in reverse order of Start.
+	Stop() error
+}
+
+/**
+ * AppDependency is implemented by App which depends on other applications.
+ */
+type AppDependency interface {
+	// names of applications which must be started before this one
+	Dependencies() []string
+}
+
+/**
+ * application entry in the pipeline
+ */
+type appEntry struct {
+	app      interface{}
+	priority int
+	name     string
+	started  bool
+	panics   int  // number of panics in handlers
+	disabled bool // disabled by too many panics
+}
+
+/**
+ * AppManager holds registered applications as an ordered pipeline.
+ * Messages are dispatched to applications in descending priority order.
+ * Applications registered with the same priority are called in
+ * registration order.
+ */
 type AppManager struct {
-	applications []interface{}
+	mutex        sync.RWMutex
+	applications []*appEntry
+	running      bool
+	panicHook    PanicHook
+	panicLimit   int
 }
 
 var appManager *AppManager = newAppManager()
 
 func newAppManager() *AppManager {
 	manager := new(AppManager)
-	manager.applications = make([]interface{}, 0)
+	manager.applications = make([]*appEntry, 0)
+	manager.panicHook = defaultPanicHook
 	return manager
 }
 
@@ -18,10 +80,378 @@ func GetAppManager() *AppManager {
 	return appManager
 }
 
-func (manager *AppManager) RegistApplication(app interface{}) {
-	manager.applications = append(manager.applications, app)
+// appName returns the name used to identify app in logs and errors.
+func appName(app interface{}) string {
+	if obj, ok := app.(App); ok {
+		return obj.Name()
+	}
+	return fmt.Sprintf("%T", app)
+}
+
+// RegistApplication regists app with DEFAULT_APP_PRIORITY.
+func (manager *AppManager) RegistApplication(app interface{}) error {
+	return manager.RegistApplicationWithPriority(app, DEFAULT_APP_PRIORITY)
+}
+
+// RegistApplicationWithPriority regists app with given priority.
+// Applications with higher priority receive messages first, and can stop
+// propagation to lower ones by returning EventConsumed from their handler.
+// If applications are already started, app is initialized and started
+// immediately, before it is added to the pipeline.
+func (manager *AppManager) RegistApplicationWithPriority(app interface{}, priority int) error {
+	manager.mutex.Lock()
+	entry := &appEntry{app: app, priority: priority}
+	if obj, ok := app.(App); ok {
+		entry.name = obj.Name()
+		if manager.lookup(entry.name) != nil {
+			manager.mutex.Unlock()
+			return fmt.Errorf("application %s is already registered.", entry.name)
+		}
+	}
+	running := manager.running
+	manager.mutex.Unlock()
+
+	// start application at runtime. it joins the pipeline only after
+	// Start succeeds, so it never receives messages before Start.
+	if running && entry.name != "" {
+		if err := manager.startEntries([]*appEntry{entry}); err != nil {
+			return err
+		}
+	}
+
+	manager.mutex.Lock()
+	if entry.name != "" && manager.lookup(entry.name) != nil {
+		// same name was registered while app was starting
+		started := entry.started
+		entry.started = false
+		manager.mutex.Unlock()
+		if started {
+			entry.app.(App).Stop()
+		}
+		return fmt.Errorf("application %s is already registered.", entry.name)
+	}
+	manager.applications = append(manager.applications, entry)
+	sort.SliceStable(manager.applications, func(i, j int) bool {
+		return manager.applications[i].priority > manager.applications[j].priority
+	})
+	// applications were stopped while app was starting
+	stop := entry.started && !manager.running
+	if stop {
+		entry.started = false
+	}
+	manager.mutex.Unlock()
+
+	if stop {
+		return entry.app.(App).Stop()
+	}
+	return nil
+}
+
+// UnregistApplication removes app from the pipeline and stops it if started.
+// It fails if another started application depends on app.
+func (manager *AppManager) UnregistApplication(app interface{}) error {
+	manager.mutex.RLock()
+	var entry *appEntry
+	for _, e := range manager.applications {
+		if e.app == app {
+			entry = e
+			break
+		}
+	}
+	manager.mutex.RUnlock()
+
+	if entry == nil {
+		return fmt.Errorf("application %s is not registered.", appName(app))
+	}
+	return manager.unregist(entry)
+}
+
+// UnregistApplicationByName removes App named name from the pipeline.
+func (manager *AppManager) UnregistApplicationByName(name string) error {
+	manager.mutex.RLock()
+	entry := manager.lookup(name)
+	manager.mutex.RUnlock()
+
+	if entry == nil {
+		return fmt.Errorf("application %s is not registered.", name)
+	}
+	return manager.unregist(entry)
 }
 
+func (manager *AppManager) unregist(entry *appEntry) error {
+	manager.mutex.Lock()
+	for _, e := range manager.applications {
+		if e.started && e != entry && dependsOn(e.app, entry.name) {
+			manager.mutex.Unlock()
+			return fmt.Errorf("application %s is required by %s.", entry.name, e.name)
+		}
+	}
+	manager.remove(entry)
+	started := entry.started
+	entry.started = false
+	manager.mutex.Unlock()
+
+	GetDispatcher().releaseApp(entry.app)
+	if started {
+		return entry.app.(App).Stop()
+	}
+	return nil
+}
+
+// remove entry from the pipeline. caller must hold the lock.
+func (manager *AppManager) remove(entry *appEntry) {
+	for i, e := range manager.applications {
+		if e == entry {
+			manager.applications = append(manager.applications[:i], manager.applications[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetApplications returns registered applications in dispatch order.
 func (manager *AppManager) GetApplications() []interface{} {
-	return manager.applications
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	apps := make([]interface{}, len(manager.applications))
+	for i, entry := range manager.applications {
+		apps[i] = entry.app
+	}
+	return apps
+}
+
+// applications to dispatch messages to, excluding disabled ones.
+func (manager *AppManager) activeApplications() []interface{} {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	apps := make([]interface{}, 0, len(manager.applications))
+	for _, entry := range manager.applications {
+		if !entry.disabled {
+			apps = append(apps, entry.app)
+		}
+	}
+	return apps
+}
+
+// GetApplicationByName returns App named name, or nil if not registered.
+func (manager *AppManager) GetApplicationByName(name string) interface{} {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	if entry := manager.lookup(name); entry != nil {
+		return entry.app
+	}
+	return nil
+}
+
+// FindApplication finds the first application in dispatch order which is
+// assignable to the type target points to, and sets target to it.
+// target must be a non-nil pointer, like errors.As.
+//
+//	var topo *TopologyApp
+//	if manager.FindApplication(&topo) { ... }
+func (manager *AppManager) FindApplication(target interface{}) bool {
+	val := reflect.ValueOf(target)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return false
+	}
+	typ := val.Type().Elem()
+
+	for _, app := range manager.GetApplications() {
+		if reflect.TypeOf(app).AssignableTo(typ) {
+			val.Elem().Set(reflect.ValueOf(app))
+			return true
+		}
+	}
+	return false
+}
+
+func (manager *AppManager) lookup(name string) *appEntry {
+	for _, entry := range manager.applications {
+		if entry.name != "" && entry.name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+func dependsOn(app interface{}, name string) bool {
+	if obj, ok := app.(AppDependency); ok {
+		for _, dep := range obj.Dependencies() {
+			if dep == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*****************************************************/
+/* Lifecycle                                         */
+/*****************************************************/
+
+// StartApplications initializes and starts every registered App.
+// Applications are started after their dependencies; independent
+// applications are started in dispatch order.
+// If an application fails, already started applications are stopped.
+func (manager *AppManager) StartApplications() error {
+	manager.mutex.Lock()
+	if manager.running {
+		manager.mutex.Unlock()
+		return nil
+	}
+	order, err := manager.resolveOrder()
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+	manager.running = true
+	manager.mutex.Unlock()
+
+	if err := manager.startEntries(order); err != nil {
+		manager.StopApplications()
+		return err
+	}
+	return nil
+}
+
+// StopApplications stops started applications in reverse order of start.
+// Every application is stopped even if some of them fail; the first error
+// is returned.
+func (manager *AppManager) StopApplications() error {
+	manager.mutex.Lock()
+	manager.running = false
+	order, err := manager.resolveOrder()
+	if err != nil {
+		// dependencies may be broken by unregistration,
+		// fall back to dispatch order.
+		order = make([]*appEntry, 0)
+		for _, entry := range manager.applications {
+			if entry.name != "" {
+				order = append(order, entry)
+			}
+		}
+	}
+	manager.mutex.Unlock()
+
+	var first error
+	for i := len(order) - 1; i >= 0; i-- {
+		entry := order[i]
+		manager.mutex.Lock()
+		started := entry.started
+		entry.started = false
+		manager.mutex.Unlock()
+		if !started {
+			continue
+		}
+		if err := entry.app.(App).Stop(); err != nil && first == nil {
+			first = fmt.Errorf("failed to stop application %s: %v", entry.name, err)
+		}
+	}
+	return first
+}
+
+// startEntries calls Init and Start of entries in given order.
+// lifecycle hooks are called without lock so that applications can look up
+// each other through AppManager.
+func (manager *AppManager) startEntries(order []*appEntry) error {
+	for i, entry := range order {
+		if err := manager.checkDependencies(entry, order[:i]); err != nil {
+			return err
+		}
+		if err := entry.app.(App).Init(manager); err != nil {
+			return fmt.Errorf("failed to init application %s: %v", entry.name, err)
+		}
+	}
+	for _, entry := range order {
+		if err := entry.app.(App).Start(); err != nil {
+			return fmt.Errorf("failed to start application %s: %v", entry.name, err)
+		}
+		manager.mutex.Lock()
+		entry.started = true
+		manager.mutex.Unlock()
+	}
+	return nil
+}
+
+// checkDependencies checks that every dependency of entry is started, or is
+// going to be started before entry as one of starting.
+func (manager *AppManager) checkDependencies(entry *appEntry, starting []*appEntry) error {
+	obj, ok := entry.app.(AppDependency)
+	if !ok {
+		return nil
+	}
+
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	for _, dep := range obj.Dependencies() {
+		depEntry := manager.lookup(dep)
+		if depEntry == nil {
+			return fmt.Errorf("application %s depends on unknown application %s.", entry.name, dep)
+		}
+		if depEntry.disabled {
+			return fmt.Errorf("application %s depends on disabled application %s.", entry.name, dep)
+		}
+		if !depEntry.started && !containsEntry(starting, depEntry) {
+			return fmt.Errorf("application %s depends on application %s which is not started.", entry.name, dep)
+		}
+	}
+	return nil
+}
+
+func containsEntry(entries []*appEntry, entry *appEntry) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveOrder sorts App entries so that dependencies come first.
+// caller must hold the lock.
+func (manager *AppManager) resolveOrder() ([]*appEntry, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*appEntry]int)
+	order := make([]*appEntry, 0)
+
+	var visit func(entry *appEntry) error
+	visit = func(entry *appEntry) error {
+		switch state[entry] {
+		case visiting:
+			return fmt.Errorf("circular dependency detected at application %s.", entry.name)
+		case visited:
+			return nil
+		}
+		state[entry] = visiting
+		if obj, ok := entry.app.(AppDependency); ok {
+			for _, dep := range obj.Dependencies() {
+				depEntry := manager.lookup(dep)
+				if depEntry == nil {
+					return fmt.Errorf("application %s depends on unknown application %s.", entry.name, dep)
+				}
+				if err := visit(depEntry); err != nil {
+					return err
+				}
+			}
+		}
+		state[entry] = visited
+		order = append(order, entry)
+		return nil
+	}
+
+	for _, entry := range manager.applications {
+		if entry.name == "" {
+			continue
+		}
+		if err := visit(entry); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
 }
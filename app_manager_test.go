@@ -0,0 +1,354 @@
+package gofc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/*****************************************************/
+/* test applications                                 */
+/*****************************************************/
+type recordApp struct {
+	name  string
+	trace *[]string
+}
+
+func (app *recordApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	*app.trace = append(*app.trace, app.name)
+}
+
+type consumeApp struct {
+	name   string
+	trace  *[]string
+	result EventResult
+}
+
+func (app *consumeApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) EventResult {
+	*app.trace = append(*app.trace, app.name)
+	return app.result
+}
+
+// replace global AppManager during test
+func withAppManager(f func(manager *AppManager)) {
+	saved := appManager
+	appManager = newAppManager()
+	defer func() { appManager = saved }()
+	f(appManager)
+}
+
+func equalTrace(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/*****************************************************/
+/* Pipeline                                          */
+/*****************************************************/
+func TestApplicationOrder(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplication(&recordApp{"default1", &trace})
+		manager.RegistApplicationWithPriority(&recordApp{"low", &trace}, -10)
+		manager.RegistApplicationWithPriority(&recordApp{"high", &trace}, 100)
+		manager.RegistApplication(&recordApp{"default2", &trace})
+
+		dp := NewDatapath(nil)
+		dp.dispatchHandler(ofp13.NewOfpPacketIn())
+
+		expect := []string{"high", "default1", "default2", "low"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected order is : ", expect)
+			t.Log("Actual order is   : ", trace)
+			t.Error("Applications are not called in priority order.")
+		}
+	})
+}
+
+func TestApplicationConsume(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplicationWithPriority(&consumeApp{"firewall", &trace, EventConsumed}, 100)
+		manager.RegistApplication(&recordApp{"forwarding", &trace})
+
+		dp := NewDatapath(nil)
+		dp.dispatchHandler(ofp13.NewOfpPacketIn())
+
+		expect := []string{"firewall"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Consumed message was dispatched to lower priority application.")
+		}
+	})
+}
+
+func TestApplicationContinue(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplicationWithPriority(&consumeApp{"firewall", &trace, EventContinue}, 100)
+		manager.RegistApplication(&recordApp{"forwarding", &trace})
+
+		dp := NewDatapath(nil)
+		dp.dispatchHandler(ofp13.NewOfpPacketIn())
+
+		expect := []string{"firewall", "forwarding"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Message was not dispatched to next application.")
+		}
+	})
+}
+
+/*****************************************************/
+/* Lifecycle                                         */
+/*****************************************************/
+type lifecycleApp struct {
+	name  string
+	deps  []string
+	trace *[]string
+}
+
+func (app *lifecycleApp) Name() string {
+	return app.name
+}
+
+func (app *lifecycleApp) Dependencies() []string {
+	return app.deps
+}
+
+func (app *lifecycleApp) Init(manager *AppManager) error {
+	for _, dep := range app.deps {
+		if manager.GetApplicationByName(dep) == nil {
+			return errors.New("dependency is not found")
+		}
+	}
+	*app.trace = append(*app.trace, "init:"+app.name)
+	return nil
+}
+
+func (app *lifecycleApp) Start() error {
+	*app.trace = append(*app.trace, "start:"+app.name)
+	return nil
+}
+
+func (app *lifecycleApp) Stop() error {
+	*app.trace = append(*app.trace, "stop:"+app.name)
+	return nil
+}
+
+func TestApplicationLifecycleOrder(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplicationWithPriority(&lifecycleApp{"routing", []string{"hosts", "topology"}, &trace}, 100)
+		manager.RegistApplication(&lifecycleApp{"hosts", []string{"topology"}, &trace})
+		manager.RegistApplication(&lifecycleApp{"topology", nil, &trace})
+		manager.RegistApplication(&recordApp{"legacy", &trace})
+
+		if err := manager.StartApplications(); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.StopApplications(); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := []string{
+			"init:topology", "init:hosts", "init:routing",
+			"start:topology", "start:hosts", "start:routing",
+			"stop:routing", "stop:hosts", "stop:topology",
+		}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Applications are not started in dependency order.")
+		}
+	})
+}
+
+func TestApplicationCircularDependency(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplication(&lifecycleApp{"a", []string{"b"}, &trace})
+		manager.RegistApplication(&lifecycleApp{"b", []string{"a"}, &trace})
+
+		if err := manager.StartApplications(); err == nil {
+			t.Error("Circular dependency is not detected.")
+		}
+		if len(trace) != 0 {
+			t.Log("Actual trace is : ", trace)
+			t.Error("Applications are started in spite of circular dependency.")
+		}
+	})
+}
+
+func TestApplicationLookup(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		topo := &lifecycleApp{"topology", nil, &trace}
+		legacy := &recordApp{"legacy", &trace}
+		manager.RegistApplication(topo)
+		manager.RegistApplication(legacy)
+
+		if manager.GetApplicationByName("topology") != topo {
+			t.Error("Failed to look up application by name.")
+		}
+		if manager.GetApplicationByName("unknown") != nil {
+			t.Error("Unknown application is found by name.")
+		}
+
+		var found *recordApp
+		if !manager.FindApplication(&found) || found != legacy {
+			t.Error("Failed to look up application by type.")
+		}
+		var handler Of13PacketInHandler
+		if !manager.FindApplication(&handler) || handler != legacy {
+			t.Error("Failed to look up application by interface.")
+		}
+	})
+}
+
+func TestApplicationRuntimeUnregist(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		manager.RegistApplication(&lifecycleApp{"topology", nil, &trace})
+		manager.RegistApplication(&lifecycleApp{"hosts", []string{"topology"}, &trace})
+		if err := manager.StartApplications(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := manager.UnregistApplicationByName("topology"); err == nil {
+			t.Error("Application required by others was unregistered.")
+		}
+
+		trace = trace[:0]
+		if err := manager.UnregistApplicationByName("hosts"); err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.UnregistApplicationByName("topology"); err != nil {
+			t.Fatal(err)
+		}
+		expect := []string{"stop:hosts", "stop:topology"}
+		if !equalTrace(expect, trace) || len(manager.GetApplications()) != 0 {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Applications are not stopped on unregistration.")
+		}
+
+		// regist at runtime starts application immediately
+		trace = trace[:0]
+		manager.RegistApplication(&lifecycleApp{"routing", nil, &trace})
+		expect = []string{"init:routing", "start:routing"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Application registered at runtime is not started.")
+		}
+	})
+}
+
+// application which receives a message while starting
+type startingApp struct {
+	lifecycleApp
+	dp  *Datapath
+	err error
+}
+
+func (app *startingApp) Start() error {
+	app.dp.dispatchHandler(ofp13.NewOfpPacketIn())
+	*app.trace = append(*app.trace, "start:"+app.name)
+	return app.err
+}
+
+func (app *startingApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	*app.trace = append(*app.trace, "packet_in:"+app.name)
+}
+
+func TestApplicationRuntimeRegistBeforeStart(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		if err := manager.StartApplications(); err != nil {
+			t.Fatal(err)
+		}
+		dp := NewDatapath(nil)
+
+		failed := &startingApp{lifecycleApp{"failed", nil, &trace}, dp, errors.New("failed")}
+		if err := manager.RegistApplication(failed); err == nil {
+			t.Error("Application failed to start is registered.")
+		}
+		if len(manager.GetApplications()) != 0 {
+			t.Error("Application failed to start is left in the pipeline.")
+		}
+
+		trace = trace[:0]
+		app := &startingApp{lifecycleApp{"routing", nil, &trace}, dp, nil}
+		if err := manager.RegistApplication(app); err != nil {
+			t.Fatal(err)
+		}
+		dp.dispatchHandler(ofp13.NewOfpPacketIn())
+		expect := []string{"init:routing", "start:routing", "packet_in:routing"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Application receives messages before it is started.")
+		}
+	})
+}
+
+// application blocked in Start until release is closed
+type blockingApp struct {
+	lifecycleApp
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (app *blockingApp) Start() error {
+	close(app.entered)
+	<-app.release
+	return app.lifecycleApp.Start()
+}
+
+func TestApplicationRuntimeDependency(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		topo := &blockingApp{lifecycleApp{"topology", nil, &trace},
+			make(chan struct{}), make(chan struct{})}
+		manager.RegistApplication(topo)
+		done := make(chan error)
+		go func() {
+			done <- manager.StartApplications()
+		}()
+
+		// topology is registered, but not started yet
+		<-topo.entered
+		if err := manager.RegistApplication(&lifecycleApp{"hosts", []string{"topology"}, &trace}); err == nil {
+			t.Error("Application is started before its dependency.")
+		}
+		close(topo.release)
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		if err := manager.RegistApplication(&lifecycleApp{"hosts", []string{"topology"}, &trace}); err != nil {
+			t.Fatal(err)
+		}
+
+		// dependency disabled by panics
+		manager.mutex.Lock()
+		manager.lookup("topology").disabled = true
+		manager.mutex.Unlock()
+		if err := manager.RegistApplication(&lifecycleApp{"routing", []string{"topology"}, &trace}); err == nil {
+			t.Error("Application is started with disabled dependency.")
+		}
+		if manager.GetApplicationByName("routing") != nil {
+			t.Error("Application failed to start is registered.")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package builder
+
+import (
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/**
+ * Action is an action of apply-actions, write-actions or bucket. Like
+ * Field, it holds the error of creating the action instead.
+ */
+type Action struct {
+	action ofp13.OfpAction
+	err    error
+}
+
+// Action returns the action and the error of creating it.
+func (a Action) Action() (ofp13.OfpAction, error) {
+	return a.action, a.err
+}
+
+// Raw wraps action created by ofp13, such as experimenter action.
+func Raw(a ofp13.OfpAction) Action {
+	return Action{action: a}
+}
+
+/**
+ * Output sends packet to port. Whole packet is sent to the controller when
+ * port is OFPP_CONTROLLER.
+ */
+func Output(port uint32) Action {
+	return Action{action: ofp13.NewOfpActionOutput(port, ofp13.OFPCML_NO_BUFFER)}
+}
+
+// OutputMaxLen sends packet to port, with up to maxLen bytes to controller.
+func OutputMaxLen(port uint32, maxLen uint16) Action {
+	return Action{action: ofp13.NewOfpActionOutput(port, maxLen)}
+}
+
+// Controller sends whole packet to the controller.
+func Controller() Action {
+	return Output(ofp13.OFPP_CONTROLLER)
+}
+
+func Flood() Action {
+	return Output(ofp13.OFPP_FLOOD)
+}
+
+func Group(id uint32) Action {
+	return Action{action: ofp13.NewOfpActionGroup(id)}
+}
+
+func SetQueue(id uint32) Action {
+	return Action{action: ofp13.NewOfpActionSetQueue(id)}
+}
+
+func PushVlan() Action {
+	return Action{action: ofp13.NewOfpActionPushVlan()}
+}
+
+// PushVlanType pushes vlan tag of ethType, such as 0x88a8 of 802.1ad.
+func PushVlanType(ethType uint16) Action {
+	return Action{action: ofp13.NewOfpActionPush(ofp13.OFPAT_PUSH_VLAN, ethType)}
+}
+
+func PopVlan() Action {
+	return Action{action: ofp13.NewOfpActionPopVlan(0)}
+}
+
+func PushMpls() Action {
+	return Action{action: ofp13.NewOfpActionPushMpls()}
+}
+
+// PopMpls pops mpls shim header, and the payload is of ethType.
+func PopMpls(ethType uint16) Action {
+	return Action{action: ofp13.NewOfpActionPopMpls(ethType)}
+}
+
+func PushPbb() Action {
+	return Action{action: ofp13.NewOfpActionPushPbb()}
+}
+
+func PopPbb() Action {
+	return Action{action: ofp13.NewOfpActionPopPbb(0)}
+}
+
+func SetMplsTtl(ttl uint8) Action {
+	return Action{action: ofp13.NewOfpActionSetMplsTtl(ttl)}
+}
+
+func DecMplsTtl() Action {
+	return Action{action: ofp13.NewOfpActionDecMplsTtl()}
+}
+
+func SetNwTtl(ttl uint8) Action {
+	return Action{action: ofp13.NewOfpActionSetNwTtl(ttl)}
+}
+
+func DecNwTtl() Action {
+	return Action{action: ofp13.NewOfpActionDecNwTtl()}
+}
+
+func CopyTtlOut() Action {
+	return Action{action: ofp13.NewOfpActionCopyTtlOut()}
+}
+
+func CopyTtlIn() Action {
+	return Action{action: ofp13.NewOfpActionCopyTtlIn()}
+}
+
+/**
+ * SetField sets field of packet to the value of f, such as
+ * SetField(EthDst("00:11:22:33:44:55")).
+ */
+func SetField(f Field) Action {
+	if f.err != nil {
+		return Action{err: f.err}
+	}
+	return Action{action: ofp13.NewOfpActionSetField(f.oxm)}
+}
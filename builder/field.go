@@ -0,0 +1,317 @@
+package builder
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/**
+ * Field is an OXM field to match or to set by SetField. It holds the error
+ * of creating the field instead, which is reported by the terminal method
+ * of the builder, so that fields can be written inline without error
+ * checks.
+ */
+type Field struct {
+	oxm ofp13.OxmField
+	err error
+}
+
+// Oxm returns the field and the error of creating it.
+func (f Field) Oxm() (ofp13.OxmField, error) {
+	return f.oxm, f.err
+}
+
+// Oxm wraps field created by ofp13, such as experimenter field.
+func Oxm(f ofp13.OxmField) Field {
+	return Field{oxm: f}
+}
+
+func fieldOf(f ofp13.OxmField, err error) Field {
+	if err != nil {
+		return Field{err: err}
+	}
+	return Field{oxm: f}
+}
+
+// split "addr/mask" into addr and mask. mask is "" if not given.
+func splitMask(s string) (string, string) {
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+/**
+ * parse "addr/len" into the address masked by prefix and the prefix length,
+ * so that "10.1.2.3/8" is 10.0.0.0/8 as switches reject bits outside mask.
+ */
+func parsePrefix(name string, s string, addr string, mask string, bits int) (string, int, error) {
+	n, err := strconv.Atoi(mask)
+	if err != nil || n < 0 || n > bits {
+		return "", 0, fmt.Errorf("%s %s has invalid prefix length.", name, s)
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", 0, fmt.Errorf("%s %s has invalid address.", name, s)
+	}
+	if bits == 32 {
+		ip = ip.To4()
+		if ip == nil {
+			return "", 0, fmt.Errorf("%s %s has invalid address.", name, s)
+		}
+	}
+	return ip.Mask(net.CIDRMask(n, bits)).String(), n, nil
+}
+
+/*****************************************************/
+/* Match Fields                                      */
+/*****************************************************/
+
+func InPort(port uint32) Field {
+	return Field{oxm: ofp13.NewOxmInPort(port)}
+}
+
+func InPhyPort(port uint32) Field {
+	return Field{oxm: ofp13.NewOxmInPhyPort(port)}
+}
+
+func Metadata(metadata uint64) Field {
+	return Field{oxm: ofp13.NewOxmMetadata(metadata)}
+}
+
+func MetadataMasked(metadata uint64, mask uint64) Field {
+	return Field{oxm: ofp13.NewOxmMetadataW(metadata, mask)}
+}
+
+/**
+ * EthDst returns eth_dst of hwAddr such as "00:11:22:33:44:55", or masked
+ * one of "01:00:00:00:00:00/01:00:00:00:00:00".
+ */
+func EthDst(hwAddr string) Field {
+	if addr, mask := splitMask(hwAddr); mask != "" {
+		return fieldOf(ofp13.NewOxmEthDstW(addr, mask))
+	}
+	return fieldOf(ofp13.NewOxmEthDst(hwAddr))
+}
+
+// EthSrc returns eth_src, like EthDst.
+func EthSrc(hwAddr string) Field {
+	if addr, mask := splitMask(hwAddr); mask != "" {
+		return fieldOf(ofp13.NewOxmEthSrcW(addr, mask))
+	}
+	return fieldOf(ofp13.NewOxmEthSrc(hwAddr))
+}
+
+func EthType(ethType uint16) Field {
+	return Field{oxm: ofp13.NewOxmEthType(ethType)}
+}
+
+/**
+ * VlanVid returns vlan_vid of packets tagged with vid. OFPVID_PRESENT is
+ * added to vid, so VlanVid(10) matches vlan 10.
+ */
+func VlanVid(vid uint16) Field {
+	return Field{oxm: ofp13.NewOxmVlanVid(vid | ofp13.OFPVID_PRESENT)}
+}
+
+// VlanAny returns vlan_vid of packets tagged with any vid.
+func VlanAny() Field {
+	return Field{oxm: ofp13.NewOxmVlanVidW(ofp13.OFPVID_PRESENT, ofp13.OFPVID_PRESENT)}
+}
+
+// VlanNone returns vlan_vid of packets without vlan tag.
+func VlanNone() Field {
+	return Field{oxm: ofp13.NewOxmVlanVid(ofp13.OFPVID_NONE)}
+}
+
+func VlanPcp(pcp uint8) Field {
+	return Field{oxm: ofp13.NewOxmVlanPcp(pcp)}
+}
+
+func IpDscp(dscp uint8) Field {
+	return Field{oxm: ofp13.NewOxmIpDscp(dscp)}
+}
+
+func IpEcn(ecn uint8) Field {
+	return Field{oxm: ofp13.NewOxmIpEcn(ecn)}
+}
+
+func IpProto(proto uint8) Field {
+	return Field{oxm: ofp13.NewOxmIpProto(proto)}
+}
+
+// Ipv4Src returns ipv4_src of addr such as "10.0.0.1" or "10.0.0.0/8".
+func Ipv4Src(addr string) Field {
+	return ipv4Field("ipv4_src", addr, ofp13.NewOxmIpv4Src, ofp13.NewOxmIpv4SrcW)
+}
+
+// Ipv4Dst returns ipv4_dst, like Ipv4Src.
+func Ipv4Dst(addr string) Field {
+	return ipv4Field("ipv4_dst", addr, ofp13.NewOxmIpv4Dst, ofp13.NewOxmIpv4DstW)
+}
+
+func ipv4Field(name string, s string,
+	exact func(string) (*ofp13.OxmIpv4, error),
+	masked func(string, int) (*ofp13.OxmIpv4, error)) Field {
+	addr, mask := splitMask(s)
+	if mask == "" {
+		return fieldOf(exact(addr))
+	}
+	prefix, n, err := parsePrefix(name, s, addr, mask, 32)
+	if err != nil {
+		return Field{err: err}
+	}
+	return fieldOf(masked(prefix, n))
+}
+
+func TcpSrc(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmTcpSrc(port)}
+}
+
+func TcpDst(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmTcpDst(port)}
+}
+
+func UdpSrc(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmUdpSrc(port)}
+}
+
+func UdpDst(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmUdpDst(port)}
+}
+
+func SctpSrc(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmSctpSrc(port)}
+}
+
+func SctpDst(port uint16) Field {
+	return Field{oxm: ofp13.NewOxmSctpDst(port)}
+}
+
+func Icmpv4Type(t uint8) Field {
+	return Field{oxm: ofp13.NewOxmIcmpType(t)}
+}
+
+func Icmpv4Code(code uint8) Field {
+	return Field{oxm: ofp13.NewOxmIcmpCode(code)}
+}
+
+func ArpOp(op uint16) Field {
+	return Field{oxm: ofp13.NewOxmArpOp(op)}
+}
+
+// ArpSpa returns arp_spa of addr such as "10.0.0.1" or "10.0.0.0/8".
+func ArpSpa(addr string) Field {
+	return arpPaField("arp_spa", addr, ofp13.NewOxmArpSpa, ofp13.NewOxmArpSpaW)
+}
+
+// ArpTpa returns arp_tpa, like ArpSpa.
+func ArpTpa(addr string) Field {
+	return arpPaField("arp_tpa", addr, ofp13.NewOxmArpTpa, ofp13.NewOxmArpTpaW)
+}
+
+func arpPaField(name string, s string,
+	exact func(string) (*ofp13.OxmArpPa, error),
+	masked func(string, int) (*ofp13.OxmArpPa, error)) Field {
+	addr, mask := splitMask(s)
+	if mask == "" {
+		return fieldOf(exact(addr))
+	}
+	prefix, n, err := parsePrefix(name, s, addr, mask, 32)
+	if err != nil {
+		return Field{err: err}
+	}
+	return fieldOf(masked(prefix, n))
+}
+
+func ArpSha(hwAddr string) Field {
+	return fieldOf(ofp13.NewOxmArpSha(hwAddr))
+}
+
+func ArpTha(hwAddr string) Field {
+	return fieldOf(ofp13.NewOxmArpTha(hwAddr))
+}
+
+// Ipv6Src returns ipv6_src of addr such as "2001:db8::1" or "2001:db8::/32".
+func Ipv6Src(addr string) Field {
+	return ipv6Field("ipv6_src", addr, ofp13.NewOxmIpv6Src, ofp13.NewOxmIpv6SrcW)
+}
+
+// Ipv6Dst returns ipv6_dst, like Ipv6Src.
+func Ipv6Dst(addr string) Field {
+	return ipv6Field("ipv6_dst", addr, ofp13.NewOxmIpv6Dst, ofp13.NewOxmIpv6DstW)
+}
+
+func ipv6Field(name string, s string,
+	exact func(string) (*ofp13.OxmIpv6, error),
+	masked func(string, int) (*ofp13.OxmIpv6, error)) Field {
+	addr, mask := splitMask(s)
+	if mask == "" {
+		return fieldOf(exact(addr))
+	}
+	prefix, n, err := parsePrefix(name, s, addr, mask, 128)
+	if err != nil {
+		return Field{err: err}
+	}
+	return fieldOf(masked(prefix, n))
+}
+
+func Ipv6FLabel(label uint32) Field {
+	return Field{oxm: ofp13.NewOxmIpv6FLabel(label)}
+}
+
+func Icmpv6Type(t uint8) Field {
+	return Field{oxm: ofp13.NewOxmIcmpv6Type(t)}
+}
+
+func Icmpv6Code(code uint8) Field {
+	return Field{oxm: ofp13.NewOxmIcmpv6Code(code)}
+}
+
+func Ipv6NdTarget(addr string) Field {
+	return fieldOf(ofp13.NewOxmIpv6NdTarget(addr))
+}
+
+func Ipv6NdSll(hwAddr string) Field {
+	return fieldOf(ofp13.NewOxmIpv6NdSll(hwAddr))
+}
+
+func Ipv6NdTll(hwAddr string) Field {
+	return fieldOf(ofp13.NewOxmIpv6NdTll(hwAddr))
+}
+
+func MplsLabel(label uint32) Field {
+	return Field{oxm: ofp13.NewOxmMplsLabel(label)}
+}
+
+func MplsTc(tc uint8) Field {
+	return Field{oxm: ofp13.NewOxmMplsTc(tc)}
+}
+
+func MplsBos(bos uint8) Field {
+	return Field{oxm: ofp13.NewOxmMplsBos(bos)}
+}
+
+func PbbIsid(isid uint32) Field {
+	return Field{oxm: ofp13.NewOxmPbbIsid([3]uint8{uint8(isid >> 16), uint8(isid >> 8), uint8(isid)})}
+}
+
+func TunnelId(id uint64) Field {
+	return Field{oxm: ofp13.NewOxmTunnelId(id)}
+}
+
+func TunnelIdMasked(id uint64, mask uint64) Field {
+	return Field{oxm: ofp13.NewOxmTunnelIdW(id, mask)}
+}
+
+func Ipv6ExtHeader(flags uint16) Field {
+	return Field{oxm: ofp13.NewOxmIpv6ExtHeader(flags)}
+}
+
+func Ipv6ExtHeaderMasked(flags uint16, mask uint16) Field {
+	return Field{oxm: ofp13.NewOxmIpv6ExtHeaderW(flags, mask)}
+}
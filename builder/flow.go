@@ -0,0 +1,251 @@
+package builder
+
+import (
+	"errors"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/**
+ * FlowBuilder builds OfpFlowMod by chained calls, such as
+ *
+ *   fm, err := Flow().Table(0).Priority(100).
+ *       Match(EthType(0x0800), Ipv4Dst("10.0.0.0/8")).
+ *       Apply(Output(2)).IdleTimeout(30).Add()
+ *
+ * Errors of fields and actions are accumulated, and returned by the
+ * terminal method, Add, Modify, ModifyStrict, Delete or DeleteStrict.
+ * Instructions are added in the order of the first call of Meter, Apply,
+ * Clear, Write, WriteMetadata and GotoTable, and calling Apply or Write
+ * again appends actions to the same instruction.
+ */
+type FlowBuilder struct {
+	cookie       uint64
+	cookieMask   uint64
+	tableId      uint8
+	priority     uint16
+	idleTimeout  uint16
+	hardTimeout  uint16
+	bufferId     uint32
+	outPort      uint32
+	outGroup     uint32
+	flags        uint16
+	match        *ofp13.OfpMatch
+	instructions []ofp13.OfpInstruction
+	apply        *ofp13.OfpInstructionActions
+	write        *ofp13.OfpInstructionActions
+	errs         []error
+}
+
+// Flow returns builder of flow in table 0 of priority 0, which matches
+// all packets and has no instructions.
+func Flow() *FlowBuilder {
+	return &FlowBuilder{
+		bufferId: ofp13.OFP_NO_BUFFER,
+		outPort:  ofp13.OFPP_ANY,
+		outGroup: ofp13.OFPG_ANY,
+		match:    ofp13.NewOfpMatch(),
+	}
+}
+
+func (b *FlowBuilder) Table(id uint8) *FlowBuilder {
+	b.tableId = id
+	return b
+}
+
+func (b *FlowBuilder) Priority(priority uint16) *FlowBuilder {
+	b.priority = priority
+	return b
+}
+
+func (b *FlowBuilder) Cookie(cookie uint64) *FlowBuilder {
+	b.cookie = cookie
+	return b
+}
+
+/**
+ * CookieMask restricts modify and delete to flows whose cookie is equal to
+ * Cookie in bits of mask. It is ignored by Add.
+ */
+func (b *FlowBuilder) CookieMask(mask uint64) *FlowBuilder {
+	b.cookieMask = mask
+	return b
+}
+
+// IdleTimeout removes the flow after it matches no packet for seconds.
+func (b *FlowBuilder) IdleTimeout(seconds uint16) *FlowBuilder {
+	b.idleTimeout = seconds
+	return b
+}
+
+// HardTimeout removes the flow after seconds since it is added.
+func (b *FlowBuilder) HardTimeout(seconds uint16) *FlowBuilder {
+	b.hardTimeout = seconds
+	return b
+}
+
+// Flags adds OFPFF_* flags, such as OFPFF_SEND_FLOW_REM.
+func (b *FlowBuilder) Flags(flags uint16) *FlowBuilder {
+	b.flags |= flags
+	return b
+}
+
+// BufferId applies the flow to the packet buffered in the switch.
+func (b *FlowBuilder) BufferId(id uint32) *FlowBuilder {
+	b.bufferId = id
+	return b
+}
+
+// OutPort restricts delete to flows which output to port.
+func (b *FlowBuilder) OutPort(port uint32) *FlowBuilder {
+	b.outPort = port
+	return b
+}
+
+// OutGroup restricts delete to flows which output to group.
+func (b *FlowBuilder) OutGroup(group uint32) *FlowBuilder {
+	b.outGroup = group
+	return b
+}
+
+/**
+ * Match adds fields to match. A field of the same type as the one already
+ * added replaces it. Fields are sorted so that prerequisites come first
+ * when the flow is built.
+ */
+func (b *FlowBuilder) Match(fields ...Field) *FlowBuilder {
+	for _, f := range fields {
+		if f.err != nil {
+			b.errs = append(b.errs, f.err)
+			continue
+		}
+		b.match.Set(f.oxm)
+	}
+	return b
+}
+
+func (b *FlowBuilder) addActions(inst *ofp13.OfpInstructionActions, actions []Action) {
+	for _, a := range actions {
+		if a.err != nil {
+			b.errs = append(b.errs, a.err)
+			continue
+		}
+		inst.Append(a.action)
+	}
+}
+
+// Apply adds actions to apply-actions instruction.
+func (b *FlowBuilder) Apply(actions ...Action) *FlowBuilder {
+	if b.apply == nil {
+		b.apply = ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+		b.instructions = append(b.instructions, b.apply)
+	}
+	b.addActions(b.apply, actions)
+	return b
+}
+
+// Write adds actions to write-actions instruction.
+func (b *FlowBuilder) Write(actions ...Action) *FlowBuilder {
+	if b.write == nil {
+		b.write = ofp13.NewOfpInstructionActions(ofp13.OFPIT_WRITE_ACTIONS)
+		b.instructions = append(b.instructions, b.write)
+	}
+	b.addActions(b.write, actions)
+	return b
+}
+
+// Clear adds clear-actions instruction.
+func (b *FlowBuilder) Clear() *FlowBuilder {
+	return b.Instruction(ofp13.NewOfpInstructionActions(ofp13.OFPIT_CLEAR_ACTIONS))
+}
+
+func (b *FlowBuilder) WriteMetadata(metadata uint64, mask uint64) *FlowBuilder {
+	return b.Instruction(ofp13.NewOfpInstructionWriteMetadata(metadata, mask))
+}
+
+func (b *FlowBuilder) GotoTable(id uint8) *FlowBuilder {
+	return b.Instruction(ofp13.NewOfpInstructionGotoTable(id))
+}
+
+func (b *FlowBuilder) Meter(id uint32) *FlowBuilder {
+	return b.Instruction(ofp13.NewOfpInstructionMeter(id))
+}
+
+// Instruction adds instruction created by ofp13, such as experimenter one.
+func (b *FlowBuilder) Instruction(inst ofp13.OfpInstruction) *FlowBuilder {
+	b.instructions = append(b.instructions, inst)
+	return b
+}
+
+// Err returns errors accumulated so far, or nil.
+func (b *FlowBuilder) Err() error {
+	return errors.Join(b.errs...)
+}
+
+func (b *FlowBuilder) build(command uint8) (*ofp13.OfpFlowMod, error) {
+	if err := b.Err(); err != nil {
+		return nil, err
+	}
+	// builder can be reused to build another flow
+	match := ofp13.NewOfpMatch()
+	for _, f := range b.match.OxmFields {
+		match.Append(f)
+	}
+	match.Sort()
+	// action lists are copied too, since Apply and Write append to them
+	instructions := make([]ofp13.OfpInstruction, len(b.instructions))
+	for i, inst := range b.instructions {
+		if actions, ok := inst.(*ofp13.OfpInstructionActions); ok {
+			copied := ofp13.NewOfpInstructionActions(actions.Header.Type)
+			for _, a := range actions.Actions {
+				copied.Append(a)
+			}
+			inst = copied
+		}
+		instructions[i] = inst
+	}
+	m := ofp13.NewOfpFlowModAdd(b.cookie, b.cookieMask, b.tableId, b.priority, b.flags,
+		match, instructions)
+	m.Command = command
+	m.IdleTimeout = b.idleTimeout
+	m.HardTimeout = b.hardTimeout
+	m.BufferId = b.bufferId
+	m.OutPort = b.outPort
+	m.OutGroup = b.outGroup
+	if command == ofp13.OFPFC_ADD {
+		m.CookieMask = 0
+	}
+	return m, nil
+}
+
+// Add returns FlowMod of OFPFC_ADD.
+func (b *FlowBuilder) Add() (*ofp13.OfpFlowMod, error) {
+	return b.build(ofp13.OFPFC_ADD)
+}
+
+// Modify returns FlowMod of OFPFC_MODIFY, which modifies instructions of
+// flows matching the match as wildcards.
+func (b *FlowBuilder) Modify() (*ofp13.OfpFlowMod, error) {
+	return b.build(ofp13.OFPFC_MODIFY)
+}
+
+// ModifyStrict returns FlowMod of OFPFC_MODIFY_STRICT, which modifies the
+// flow of the same match and priority.
+func (b *FlowBuilder) ModifyStrict() (*ofp13.OfpFlowMod, error) {
+	return b.build(ofp13.OFPFC_MODIFY_STRICT)
+}
+
+/**
+ * Delete returns FlowMod of OFPFC_DELETE, which deletes flows matching the
+ * match as wildcards in the table, or in all tables with
+ * Table(ofp13.OFPTT_ALL).
+ */
+func (b *FlowBuilder) Delete() (*ofp13.OfpFlowMod, error) {
+	return b.build(ofp13.OFPFC_DELETE)
+}
+
+// DeleteStrict returns FlowMod of OFPFC_DELETE_STRICT, which deletes the
+// flow of the same match and priority.
+func (b *FlowBuilder) DeleteStrict() (*ofp13.OfpFlowMod, error) {
+	return b.build(ofp13.OFPFC_DELETE_STRICT)
+}
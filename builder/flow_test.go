@@ -0,0 +1,157 @@
+package builder
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/*****************************************************/
+/* FlowBuilder                                       */
+/*****************************************************/
+func TestFlowBuilderAdd(t *testing.T) {
+	fm, err := Flow().Table(1).Priority(100).Cookie(0x10).CookieMask(0xff).
+		Match(InPort(1), EthType(0x0800), Ipv4Dst("10.1.2.3/8")).
+		Apply(PushVlan(), SetField(VlanVid(10)), Output(2)).
+		IdleTimeout(30).Flags(ofp13.OFPFF_SEND_FLOW_REM).GotoTable(2).Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same flow built by ofp13
+	match := ofp13.NewOfpMatch()
+	match.Append(ofp13.NewOxmInPort(1))
+	match.Append(ofp13.NewOxmEthType(0x0800))
+	ipDst, _ := ofp13.NewOxmIpv4DstW("10.0.0.0", 8)
+	match.Append(ipDst)
+	apply := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+	apply.Append(ofp13.NewOfpActionPushVlan())
+	apply.Append(ofp13.NewOfpActionSetField(ofp13.NewOxmVlanVid(ofp13.OFPVID_PRESENT | 10)))
+	apply.Append(ofp13.NewOfpActionOutput(2, ofp13.OFPCML_NO_BUFFER))
+	expected := ofp13.NewOfpFlowModAdd(0x10, 0, 1, 100, ofp13.OFPFF_SEND_FLOW_REM, match,
+		[]ofp13.OfpInstruction{apply, ofp13.NewOfpInstructionGotoTable(2)})
+	expected.IdleTimeout = 30
+	expected.Header.Xid = fm.Header.Xid
+
+	if !bytes.Equal(fm.Serialize(), expected.Serialize()) {
+		t.Log("Expected flow is : ", expected.Serialize())
+		t.Log("Actual flow is   : ", fm.Serialize())
+		t.Error("Built flow is invalid.")
+	}
+	if err := fm.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFlowBuilderCommands(t *testing.T) {
+	b := Flow().Table(ofp13.OFPTT_ALL).Cookie(0x100).CookieMask(0xf00).
+		Match(EthType(0x0806)).OutPort(3)
+	cases := []struct {
+		build   func() (*ofp13.OfpFlowMod, error)
+		command uint8
+	}{
+		{b.Modify, ofp13.OFPFC_MODIFY},
+		{b.ModifyStrict, ofp13.OFPFC_MODIFY_STRICT},
+		{b.Delete, ofp13.OFPFC_DELETE},
+		{b.DeleteStrict, ofp13.OFPFC_DELETE_STRICT},
+	}
+	for _, c := range cases {
+		fm, err := c.build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fm.Command != c.command || fm.CookieMask != 0xf00 || fm.OutPort != 3 {
+			t.Log("Actual flow is : ", fm)
+			t.Errorf("Flow of command %d is invalid.", c.command)
+		}
+	}
+
+	// the match of built flows is not shared with the builder
+	fm, _ := b.Delete()
+	b.Match(Ipv4Dst("10.0.0.1"))
+	if len(fm.Match.OxmFields) != 1 {
+		t.Error("Match of built flow is modified by builder.")
+	}
+}
+
+func TestFlowBuilderMatchOrder(t *testing.T) {
+	fm, err := Flow().Match(TcpDst(80), IpProto(6), EthType(0x0800), InPort(1)).Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fm.Match.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFlowBuilderReuse(t *testing.T) {
+	b := Flow().Priority(10).Apply(Output(1)).Write(Output(2))
+	first, err := b.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := first.Serialize()
+
+	// actions added after build are not shared with the built flow
+	second, err := b.Apply(Output(3)).Write(Output(4)).Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := first.Serialize(); !bytes.Equal(actual, expected) {
+		t.Log("Expected flow is : ", expected)
+		t.Log("Actual flow is   : ", actual)
+		t.Error("Built flow is modified by builder.")
+	}
+	for i, inst := range second.Instructions {
+		if n := len(inst.(*ofp13.OfpInstructionActions).Actions); n != 2 {
+			t.Errorf("Instruction %d of second flow has %d actions, expected 2.", i, n)
+		}
+	}
+}
+
+func TestFlowBuilderErrors(t *testing.T) {
+	b := Flow().Match(Ipv4Dst("10.0.0.300"), EthDst("zz")).
+		Apply(SetField(Ipv6Dst("2001:db8::/200")), Output(1))
+	if fm, err := b.Add(); err == nil || fm != nil {
+		t.Error("Errors are not reported.")
+	}
+	if n := len(b.errs); n != 3 {
+		t.Log("Actual errors are : ", b.Err())
+		t.Error("Errors are not accumulated.")
+	}
+}
+
+func TestFields(t *testing.T) {
+	match := ofp13.NewOfpMatch()
+	for _, f := range []Field{
+		Ipv4Src("192.168.1.1/24"), Ipv6Src("2001:db8::1/32"), EthSrc("01:00:00:00:00:00/01:00:00:00:00:00"),
+		VlanVid(100), PbbIsid(0x123456),
+	} {
+		oxm, err := f.Oxm()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match.Append(oxm)
+	}
+	if addr, mask, _ := match.Ipv4Src(); !addr.Equal(net.ParseIP("192.168.1.0")) || len(mask) == 0 {
+		t.Log("Actual address is : ", addr, mask)
+		t.Error("Ipv4Src of prefix is invalid.")
+	}
+	if addr, _, _ := match.Ipv6Src(); !addr.Equal(net.ParseIP("2001:db8::")) {
+		t.Log("Actual address is : ", addr)
+		t.Error("Ipv6Src of prefix is invalid.")
+	}
+	if _, mask, ok := match.EthSrc(); !ok || mask.String() != "01:00:00:00:00:00" {
+		t.Error("Masked EthSrc is invalid.")
+	}
+	if vid, _, _ := match.VlanVid(); vid != ofp13.OFPVID_PRESENT|100 {
+		t.Log("Actual vid is : ", vid)
+		t.Error("VlanVid doesn't have OFPVID_PRESENT.")
+	}
+	if isid, _, _ := match.PbbIsid(); isid != 0x123456 {
+		t.Log("Actual isid is : ", isid)
+		t.Error("PbbIsid is invalid.")
+	}
+}
@@ -67,26 +67,45 @@ func ServerLoop(listenPort int) {
 	listener, err := net.ListenTCP("tcp", tcpAddr)
 
 	ofc := NewOFController()
-	GetAppManager().RegistApplication(ofc)
+	GetAppManager().RegistApplicationWithPriority(ofc, SYSTEM_APP_PRIORITY)
 
 	if err != nil {
 		return
 	}
 
+	// start applications in dependency order,
+	// and stop them when server loop exits.
+	if err := GetAppManager().StartApplications(); err != nil {
+		fmt.Println(err)
+		listener.Close()
+		return
+	}
+	defer GetAppManager().StopApplications()
+
 	// wait for connect from switch
 	for {
 		conn, err := listener.AcceptTCP()
 		if err != nil {
 			return
 		}
-		go handleConnection(conn)
+		go ServeConn(conn)
 	}
 }
 
 /**
- *
+ * ServeConn starts datapath on connection from switch, and returns it.
+ * ServerLoop calls it for accepted connections. It can be used with other
+ * conn, such as one end of net.Pipe to emulated switch; applications must
+ * be registered and started by the caller in that case.
  */
-func handleConnection(conn *net.TCPConn) {
+func ServeConn(conn net.Conn) *Datapath {
+	// create datapath
+	dp := NewDatapath(conn)
+
+	// start receiving before hello, since write to unbuffered conn like
+	// net.Pipe blocks until the switch reads it.
+	go dp.recvLoop()
+
 	// send hello
 	hello := ofp13.NewOfpHello()
 	_, err := conn.Write(hello.Serialize())
@@ -94,10 +113,6 @@ func handleConnection(conn *net.TCPConn) {
 		fmt.Println(err)
 	}
 
-	// create datapath
-	dp := NewDatapath(conn)
-
-	// launch goroutine
-	go dp.recvLoop()
 	go dp.sendLoop()
+	return dp
 }
@@ -2,225 +2,588 @@ package gofc
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 
 	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
 )
 
+// behavior of Send when send queue is full
+type SendPolicy int32
+
+const (
+	// wait until the message is queued, the context is done or the
+	// datapath is closed.
+	SEND_BLOCK SendPolicy = iota
+	// drop the message being sent and return nil.
+	SEND_DROP_NEWEST
+	// drop the message being sent and return ErrSendQueueFull.
+	SEND_FAIL_FAST
+)
+
+// size and policy of send queue of datapaths created after they are set.
+var DEFAULT_SEND_QUEUE_SIZE = 10
+var DEFAULT_SEND_POLICY = SEND_BLOCK
+
+// size of queue for liveness-critical messages, which are sent ahead of
+// the others.
+const CONTROL_SEND_QUEUE_SIZE = 64
+
+// if true, PacketIn is decoded lazily by ofp13.ParseLazy. handlers must use
+// GetMatch and GetData of OfpPacketIn instead of its Match and Data fields,
+// and call Retain to keep the message after they return.
+var LAZY_PACKET_IN = false
+
+// if true, Send validates messages implementing ofp13.Validator, such as
+// FlowMod and GroupMod, and returns *ofp13.ValidationError instead of
+// sending invalid ones.
+var VALIDATE_ON_SEND = false
+
+var ErrDatapathClosed = errors.New("datapath is closed.")
+var ErrSendQueueFull = errors.New("send queue is full.")
+
+/**
+ * SendStats is counters of messages sent to a datapath.
+ */
+type SendStats struct {
+	// messages pushed to send queue
+	Queued uint64
+	// messages written to connection
+	Sent uint64
+	// messages dropped because queue was full or context was done
+	Dropped uint64
+}
+
 // datapath
 type Datapath struct {
 	buffer     chan *bytes.Buffer
-	conn       *net.TCPConn
+	conn       net.Conn
 	datapathId uint64
 	sendBuffer chan *ofp13.OFMessage
+	ctrlBuffer chan *ofp13.OFMessage // high priority send queue
 	ofpversion string
 	ports      int
+	seq        uint64 // sequence number used to pin datapath to a worker
+	sendPolicy SendPolicy
+	sendStats  SendStats
+	done       chan struct{} // closed when connection is closed
+	closeOnce  sync.Once
+
+	sendInterceptors interceptorChain
+	recvInterceptors interceptorChain
 }
 
+var datapathSeq uint64 = 0
+
 /**
  * ctor
  */
-func NewDatapath(conn *net.TCPConn) *Datapath {
+func NewDatapath(conn net.Conn) *Datapath {
 	dp := new(Datapath)
-	dp.sendBuffer = make(chan *ofp13.OFMessage, 10)
+	size := DEFAULT_SEND_QUEUE_SIZE
+	if size <= 0 {
+		size = 1
+	}
+	dp.sendBuffer = make(chan *ofp13.OFMessage, size)
+	dp.ctrlBuffer = make(chan *ofp13.OFMessage, CONTROL_SEND_QUEUE_SIZE)
+	dp.sendPolicy = DEFAULT_SEND_POLICY
+	dp.done = make(chan struct{})
 	dp.conn = conn
+	dp.seq = atomic.AddUint64(&datapathSeq, 1)
 	return dp
 }
 
+// messages queued in send queue are written at once up to this size.
+const SEND_BATCH_SIZE = 64 * 1024
+
+// buffers to batch messages in sendLoop
+var batchPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, SEND_BATCH_SIZE)
+		return &buf
+	},
+}
+
 func (dp *Datapath) sendLoop() {
 	for {
 		// wait channel
-		msg := <-(dp.sendBuffer)
-		// serialize data
-		byteData := (*msg).Serialize()
-		_, err := dp.conn.Write(byteData)
+		msg := dp.nextMessage(true)
+		if msg == nil {
+			return
+		}
+
+		// serialize queued messages into a batch, and write it when queue
+		// becomes empty or batch exceeds SEND_BATCH_SIZE.
+		bufp := batchPool.Get().(*[]byte)
+		batch := (*bufp)[:0]
+		count := 0
+		var err error
+		for msg != nil {
+			count++
+			if (*msg).Size() >= SEND_BATCH_SIZE/2 {
+				// write large message with batch by writev, without copy
+				bufs := net.Buffers{batch, (*msg).Serialize()}
+				_, err = bufs.WriteTo(dp.conn)
+				batch = batch[:0]
+			} else {
+				batch = (*msg).SerializeTo(batch)
+			}
+			if err != nil || len(batch) >= SEND_BATCH_SIZE {
+				break
+			}
+			msg = dp.nextMessage(false)
+		}
+		if err == nil && len(batch) > 0 {
+			_, err = dp.conn.Write(batch)
+		}
+		*bufp = batch[:0]
+		batchPool.Put(bufp)
+
 		if err != nil {
 			fmt.Println("failed to write conn")
 			fmt.Println(err)
+			dp.Close()
 			return
 		}
+		atomic.AddUint64(&dp.sendStats.Sent, uint64(count))
+	}
+}
+
+// take next message from send queues, control messages first.
+// if block is false, it returns nil when queues are empty. otherwise it
+// waits for a message, and returns nil when dp is closed.
+func (dp *Datapath) nextMessage(block bool) *ofp13.OFMessage {
+	select {
+	case msg := <-dp.ctrlBuffer:
+		return msg
+	default:
+	}
+	if !block {
+		select {
+		case msg := <-dp.sendBuffer:
+			return msg
+		default:
+			return nil
+		}
+	}
+	select {
+	case msg := <-dp.ctrlBuffer:
+		return msg
+	case msg := <-dp.sendBuffer:
+		return msg
+	case <-dp.done:
+		return nil
 	}
 }
 
 func (dp *Datapath) recvLoop() {
 	buf := make([]byte, 1024*64)
+	filled := 0
 	for {
 		// read
-		size, err := dp.conn.Read(buf)
+		size, err := dp.conn.Read(buf[filled:])
 		if err != nil {
 			fmt.Println("failed to read conn")
 			fmt.Println(err)
+			dp.Close()
 			return
 		}
+		filled += size
 
-		// tmp := make([]byte, 2048)
-		for i := 0; i < size; {
-			msgLen := binary.BigEndian.Uint16(buf[i+2:])
-			dp.handlePacket(buf[i : i+(int)(msgLen)])
-			i += (int)(msgLen)
+		// handle complete messages. a message split by stream is kept
+		// at head of buf until rest of it is read.
+		i := 0
+		for filled-i >= 4 {
+			msgLen := (int)(binary.BigEndian.Uint16(buf[i+2:]))
+			if msgLen < 8 {
+				fmt.Println("invalid message length")
+				dp.Close()
+				return
+			}
+			if filled-i < msgLen {
+				break
+			}
+			dp.handlePacket(buf[i : i+msgLen])
+			i += msgLen
 		}
+		filled = copy(buf, buf[i:filled])
 	}
 }
 
 func (dp *Datapath) handlePacket(buf []byte) {
 	// parse data
-	msg := ofp13.Parse(buf[0:])
+	var msg ofp13.OFMessage
+	if LAZY_PACKET_IN {
+		// buf is reused by recvLoop after handlers return
+		msg = ofp13.ParseLazy(buf[0:])
+	} else {
+		msg = ofp13.Parse(buf[0:])
+	}
+	if msg == nil {
+		fmt.Println("UnSupport Message")
+		return
+	}
 
+	dp.interceptRecv(msg, receiveMessage)
+}
+
+// last stage of recv interceptors
+func receiveMessage(msg ofp13.OFMessage, dp *Datapath) {
 	if _, ok := msg.(*ofp13.OfpHello); ok {
 		// handle hello
 		featureReq := ofp13.NewOfpFeaturesRequest()
 		dp.Send(featureReq)
 	} else {
 		// dispatch handler
-		dp.dispatchHandler(msg)
+		GetDispatcher().Dispatch(dp, msg)
 	}
 }
 
+/**
+ * dispatch message to applications in pipeline order.
+ * dispatching stops when an application consumes the message.
+ */
 func (dp *Datapath) dispatchHandler(msg ofp13.OFMessage) {
-	apps := GetAppManager().GetApplications()
+	apps := GetAppManager().activeApplications()
 	for _, app := range apps {
-		switch msgi := msg.(type) {
-		// if message is OfpHeader
-		case *ofp13.OfpHeader:
-			switch msgi.Type {
-			// handle echo request
-			case ofp13.OFPT_ECHO_REQUEST:
-				if obj, ok := app.(Of13EchoRequestHandler); ok {
-					obj.HandleEchoRequest(msgi, dp)
-				}
-
-			// handle echo reply
-			case ofp13.OFPT_ECHO_REPLY:
-				if obj, ok := app.(Of13EchoReplyHandler); ok {
-					obj.HandleEchoReply(msgi, dp)
-				}
-
-			// handle Barrier reply
-			case ofp13.OFPT_BARRIER_REPLY:
-				if obj, ok := app.(Of13BarrierReplyHandler); ok {
-					obj.HandleBarrierReply(msgi, dp)
-				}
-			default:
-			}
-
-		// Recv Error
-		case *ofp13.OfpErrorMsg:
-			if obj, ok := app.(Of13ErrorMsgHandler); ok {
-				obj.HandleErrorMsg(msgi, dp)
-			}
-
-		// Recv RoleReply
-		case *ofp13.OfpRole:
-			if obj, ok := app.(Of13RoleReplyHandler); ok {
-				obj.HandleRoleReply(msgi, dp)
-			}
-
-		// Recv GetAsyncReply
-		case *ofp13.OfpAsyncConfig:
-			if obj, ok := app.(Of13AsyncConfigHandler); ok {
-				obj.HandleAsyncConfig(msgi, dp)
-			}
-
-		// case SwitchFeatures
-		case *ofp13.OfpSwitchFeatures:
-			if obj, ok := app.(Of13SwitchFeaturesHandler); ok {
-				obj.HandleSwitchFeatures(msgi, dp)
-			}
-
-		// case GetConfigReply
-		case *ofp13.OfpSwitchConfig:
-			if obj, ok := app.(Of13SwitchConfigHandler); ok {
-				obj.HandleSwitchConfig(msgi, dp)
-			}
-		// case PacketIn
-		case *ofp13.OfpPacketIn:
-			if obj, ok := app.(Of13PacketInHandler); ok {
-				obj.HandlePacketIn(msgi, dp)
-			}
-
-		// case FlowRemoved
-		case *ofp13.OfpFlowRemoved:
-			if obj, ok := app.(Of13FlowRemovedHandler); ok {
-				obj.HandleFlowRemoved(msgi, dp)
-			}
-
-		// case MultipartReply
-		case *ofp13.OfpMultipartReply:
-			switch msgi.Type {
-			case ofp13.OFPMP_DESC:
-				if obj, ok := app.(Of13DescStatsReplyHandler); ok {
-					obj.HandleDescStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_FLOW:
-				if obj, ok := app.(Of13FlowStatsReplyHandler); ok {
-					obj.HandleFlowStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_AGGREGATE:
-				if obj, ok := app.(Of13AggregateStatsReplyHandler); ok {
-					obj.HandleAggregateStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_TABLE:
-				if obj, ok := app.(Of13TableStatsReplyHandler); ok {
-					obj.HandleTableStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_PORT_STATS:
-				if obj, ok := app.(Of13PortStatsReplyHandler); ok {
-					obj.HandlePortStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_QUEUE:
-				if obj, ok := app.(Of13QueueStatsReplyHandler); ok {
-					obj.HandleQueueStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_GROUP:
-				if obj, ok := app.(Of13GroupStatsReplyHandler); ok {
-					obj.HandleGroupStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_GROUP_DESC:
-				if obj, ok := app.(Of13GroupDescStatsReplyHandler); ok {
-					obj.HandleGroupDescStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_GROUP_FEATURES:
-				if obj, ok := app.(Of13GroupFeaturesStatsReplyHandler); ok {
-					obj.HandleGroupFeaturesStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_METER:
-				if obj, ok := app.(Of13MeterStatsReplyHandler); ok {
-					obj.HandleMeterStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_METER_CONFIG:
-				if obj, ok := app.(Of13MeterConfigStatsReplyHandler); ok {
-					obj.HandleMeterConfigStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_METER_FEATURES:
-				if obj, ok := app.(Of13MeterFeaturesStatsReplyHandler); ok {
-					obj.HandleMeterFeaturesStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_TABLE_FEATURES:
-				if obj, ok := app.(Of13TableFeaturesStatsReplyHandler); ok {
-					obj.HandleTableFeaturesStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_PORT_DESC:
-				if obj, ok := app.(Of13PortDescStatsReplyHandler); ok {
-					obj.HandlePortDescStatsReply(msgi, dp)
-				}
-			case ofp13.OFPMP_EXPERIMENTER:
-				// TODO: implement
-			default:
+		if _, ok := app.(AsyncApp); ok {
+			GetDispatcher().dispatchAsync(app, dp, msg)
+			continue
+		}
+		if dp.safeInvokeHandler(app, msg) == EventConsumed {
+			return
+		}
+	}
+}
+
+func (dp *Datapath) invokeHandler(app interface{}, msg ofp13.OFMessage) EventResult {
+	switch msgi := msg.(type) {
+	// if message is OfpHeader
+	case *ofp13.OfpHeader:
+		switch msgi.Type {
+		// handle echo request
+		case ofp13.OFPT_ECHO_REQUEST:
+			if obj, ok := app.(Of13EchoRequestConsumer); ok {
+				return obj.HandleEchoRequest(msgi, dp)
+			}
+			if obj, ok := app.(Of13EchoRequestHandler); ok {
+				obj.HandleEchoRequest(msgi, dp)
 			}
 
+		// handle echo reply
+		case ofp13.OFPT_ECHO_REPLY:
+			if obj, ok := app.(Of13EchoReplyConsumer); ok {
+				return obj.HandleEchoReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13EchoReplyHandler); ok {
+				obj.HandleEchoReply(msgi, dp)
+			}
+
+		// handle Barrier reply
+		case ofp13.OFPT_BARRIER_REPLY:
+			if obj, ok := app.(Of13BarrierReplyConsumer); ok {
+				return obj.HandleBarrierReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13BarrierReplyHandler); ok {
+				obj.HandleBarrierReply(msgi, dp)
+			}
 		default:
-			fmt.Println("UnSupport Message")
 		}
+
+	// Recv Error
+	case *ofp13.OfpErrorMsg:
+		if obj, ok := app.(Of13ErrorMsgConsumer); ok {
+			return obj.HandleErrorMsg(msgi, dp)
+		}
+		if obj, ok := app.(Of13ErrorMsgHandler); ok {
+			obj.HandleErrorMsg(msgi, dp)
+		}
+
+	// Recv RoleReply
+	case *ofp13.OfpRole:
+		if obj, ok := app.(Of13RoleReplyConsumer); ok {
+			return obj.HandleRoleReply(msgi, dp)
+		}
+		if obj, ok := app.(Of13RoleReplyHandler); ok {
+			obj.HandleRoleReply(msgi, dp)
+		}
+
+	// Recv GetAsyncReply
+	case *ofp13.OfpAsyncConfig:
+		if obj, ok := app.(Of13AsyncConfigConsumer); ok {
+			return obj.HandleAsyncConfig(msgi, dp)
+		}
+		if obj, ok := app.(Of13AsyncConfigHandler); ok {
+			obj.HandleAsyncConfig(msgi, dp)
+		}
+
+	// case SwitchFeatures
+	case *ofp13.OfpSwitchFeatures:
+		if obj, ok := app.(Of13SwitchFeaturesConsumer); ok {
+			return obj.HandleSwitchFeatures(msgi, dp)
+		}
+		if obj, ok := app.(Of13SwitchFeaturesHandler); ok {
+			obj.HandleSwitchFeatures(msgi, dp)
+		}
+
+	// case GetConfigReply
+	case *ofp13.OfpSwitchConfig:
+		if obj, ok := app.(Of13SwitchConfigConsumer); ok {
+			return obj.HandleSwitchConfig(msgi, dp)
+		}
+		if obj, ok := app.(Of13SwitchConfigHandler); ok {
+			obj.HandleSwitchConfig(msgi, dp)
+		}
+	// case PacketIn
+	case *ofp13.OfpPacketIn:
+		if obj, ok := app.(Of13PacketInConsumer); ok {
+			return obj.HandlePacketIn(msgi, dp)
+		}
+		if obj, ok := app.(Of13PacketInHandler); ok {
+			obj.HandlePacketIn(msgi, dp)
+		}
+
+	// case PortStatus
+	case *ofp13.OfpPortStatus:
+		if obj, ok := app.(Of13PortStatusConsumer); ok {
+			return obj.HandlePortStatus(msgi, dp)
+		}
+		if obj, ok := app.(Of13PortStatusHandler); ok {
+			obj.HandlePortStatus(msgi, dp)
+		}
+
+	// case FlowRemoved
+	case *ofp13.OfpFlowRemoved:
+		if obj, ok := app.(Of13FlowRemovedConsumer); ok {
+			return obj.HandleFlowRemoved(msgi, dp)
+		}
+		if obj, ok := app.(Of13FlowRemovedHandler); ok {
+			obj.HandleFlowRemoved(msgi, dp)
+		}
+
+	// case MultipartReply
+	case *ofp13.OfpMultipartReply:
+		switch msgi.Type {
+		case ofp13.OFPMP_DESC:
+			if obj, ok := app.(Of13DescStatsReplyConsumer); ok {
+				return obj.HandleDescStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13DescStatsReplyHandler); ok {
+				obj.HandleDescStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_FLOW:
+			if obj, ok := app.(Of13FlowStatsReplyConsumer); ok {
+				return obj.HandleFlowStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13FlowStatsReplyHandler); ok {
+				obj.HandleFlowStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_AGGREGATE:
+			if obj, ok := app.(Of13AggregateStatsReplyConsumer); ok {
+				return obj.HandleAggregateStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13AggregateStatsReplyHandler); ok {
+				obj.HandleAggregateStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_TABLE:
+			if obj, ok := app.(Of13TableStatsReplyConsumer); ok {
+				return obj.HandleTableStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13TableStatsReplyHandler); ok {
+				obj.HandleTableStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_PORT_STATS:
+			if obj, ok := app.(Of13PortStatsReplyConsumer); ok {
+				return obj.HandlePortStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13PortStatsReplyHandler); ok {
+				obj.HandlePortStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_QUEUE:
+			if obj, ok := app.(Of13QueueStatsReplyConsumer); ok {
+				return obj.HandleQueueStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13QueueStatsReplyHandler); ok {
+				obj.HandleQueueStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_GROUP:
+			if obj, ok := app.(Of13GroupStatsReplyConsumer); ok {
+				return obj.HandleGroupStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13GroupStatsReplyHandler); ok {
+				obj.HandleGroupStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_GROUP_DESC:
+			if obj, ok := app.(Of13GroupDescStatsReplyConsumer); ok {
+				return obj.HandleGroupDescStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13GroupDescStatsReplyHandler); ok {
+				obj.HandleGroupDescStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_GROUP_FEATURES:
+			if obj, ok := app.(Of13GroupFeaturesStatsReplyConsumer); ok {
+				return obj.HandleGroupFeaturesStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13GroupFeaturesStatsReplyHandler); ok {
+				obj.HandleGroupFeaturesStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_METER:
+			if obj, ok := app.(Of13MeterStatsReplyConsumer); ok {
+				return obj.HandleMeterStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13MeterStatsReplyHandler); ok {
+				obj.HandleMeterStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_METER_CONFIG:
+			if obj, ok := app.(Of13MeterConfigStatsReplyConsumer); ok {
+				return obj.HandleMeterConfigStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13MeterConfigStatsReplyHandler); ok {
+				obj.HandleMeterConfigStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_METER_FEATURES:
+			if obj, ok := app.(Of13MeterFeaturesStatsReplyConsumer); ok {
+				return obj.HandleMeterFeaturesStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13MeterFeaturesStatsReplyHandler); ok {
+				obj.HandleMeterFeaturesStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_TABLE_FEATURES:
+			if obj, ok := app.(Of13TableFeaturesStatsReplyConsumer); ok {
+				return obj.HandleTableFeaturesStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13TableFeaturesStatsReplyHandler); ok {
+				obj.HandleTableFeaturesStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_PORT_DESC:
+			if obj, ok := app.(Of13PortDescStatsReplyConsumer); ok {
+				return obj.HandlePortDescStatsReply(msgi, dp)
+			}
+			if obj, ok := app.(Of13PortDescStatsReplyHandler); ok {
+				obj.HandlePortDescStatsReply(msgi, dp)
+			}
+		case ofp13.OFPMP_EXPERIMENTER:
+			// TODO: implement
+		default:
+		}
+
+	default:
+		fmt.Println("UnSupport Message")
+	}
+	return EventContinue
+}
+
+/**
+ * Close closes connection to the datapath. Send fails after Close.
+ */
+func (dp *Datapath) Close() {
+	dp.closeOnce.Do(func() {
+		close(dp.done)
+		if dp.conn != nil {
+			dp.conn.Close()
+		}
+	})
+}
+
+// SetSendPolicy changes behavior of Send when send queue is full.
+func (dp *Datapath) SetSendPolicy(policy SendPolicy) {
+	atomic.StoreInt32((*int32)(&dp.sendPolicy), int32(policy))
+}
+
+// SendStats returns counters of messages sent to dp.
+func (dp *Datapath) SendStats() SendStats {
+	return SendStats{
+		Queued:  atomic.LoadUint64(&dp.sendStats.Queued),
+		Sent:    atomic.LoadUint64(&dp.sendStats.Sent),
+		Dropped: atomic.LoadUint64(&dp.sendStats.Dropped),
 	}
 }
 
 /**
- *
+ * Send queues message to be sent to the datapath.
+ * It returns ErrDatapathClosed if connection is closed. When queue is full,
+ * it behaves as configured by SetSendPolicy.
  */
-func (dp *Datapath) Send(message ofp13.OFMessage) bool {
-	// push data
-	(dp.sendBuffer) <- &message
-	return true
+func (dp *Datapath) Send(message ofp13.OFMessage) error {
+	return dp.SendContext(context.Background(), message)
+}
+
+// SendContext is like Send, but gives up waiting when ctx is done
+// in SEND_BLOCK policy.
+func (dp *Datapath) SendContext(ctx context.Context, message ofp13.OFMessage) error {
+	var err error
+	dp.interceptSend(message, func(msg ofp13.OFMessage, dp *Datapath) {
+		// message injected by interceptor may fail too,
+		// report first error.
+		if e := dp.queueMessage(ctx, msg); e != nil && err == nil {
+			err = e
+		}
+	})
+	return err
+}
+
+// last stage of send interceptors
+func (dp *Datapath) queueMessage(ctx context.Context, msg ofp13.OFMessage) error {
+	select {
+	case <-dp.done:
+		return ErrDatapathClosed
+	default:
+	}
+
+	if VALIDATE_ON_SEND {
+		if v, ok := msg.(ofp13.Validator); ok {
+			if err := v.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	queue := dp.sendBuffer
+	if isControlMessage(msg) {
+		queue = dp.ctrlBuffer
+	}
+
+	policy := SendPolicy(atomic.LoadInt32((*int32)(&dp.sendPolicy)))
+	if policy == SEND_BLOCK {
+		// push data
+		select {
+		case queue <- &msg:
+		case <-dp.done:
+			return ErrDatapathClosed
+		case <-ctx.Done():
+			atomic.AddUint64(&dp.sendStats.Dropped, 1)
+			return ctx.Err()
+		}
+	} else {
+		select {
+		case queue <- &msg:
+		default:
+			atomic.AddUint64(&dp.sendStats.Dropped, 1)
+			if policy == SEND_FAIL_FAST {
+				return ErrSendQueueFull
+			}
+			return nil
+		}
+	}
+	atomic.AddUint64(&dp.sendStats.Queued, 1)
+	return nil
+}
+
+/**
+ * liveness-critical messages, which are sent ahead of bulk messages.
+ * barrier is not included since it must stay behind the messages it orders.
+ */
+func isControlMessage(msg ofp13.OFMessage) bool {
+	switch m := msg.(type) {
+	case *ofp13.OfpHeader:
+		return m.Type == ofp13.OFPT_ECHO_REQUEST || m.Type == ofp13.OFPT_ECHO_REPLY
+	case *ofp13.OfpHello, *ofp13.OfpRole, *ofp13.OfpErrorMsg:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,304 @@
+package gofc
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// create datapath with send queue of given size
+func newTestDatapath(size int, policy SendPolicy) *Datapath {
+	saved := DEFAULT_SEND_QUEUE_SIZE
+	DEFAULT_SEND_QUEUE_SIZE = size
+	defer func() { DEFAULT_SEND_QUEUE_SIZE = saved }()
+
+	dp := NewDatapath(nil)
+	dp.SetSendPolicy(policy)
+	return dp
+}
+
+// create pair of connected TCP connections on loopback
+func newTCPPair(t testing.TB) (*net.TCPConn, *net.TCPConn) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := listener.AcceptTCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+/*****************************************************/
+/* Send                                              */
+/*****************************************************/
+func TestSendFailFast(t *testing.T) {
+	dp := newTestDatapath(2, SEND_FAIL_FAST)
+	for i := 0; i < 2; i++ {
+		if err := dp.Send(ofp13.NewOfpBarrierRequest()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dp.Send(ofp13.NewOfpBarrierRequest()); err != ErrSendQueueFull {
+		t.Log("Expected error is : ", ErrSendQueueFull)
+		t.Log("Actual error is   : ", err)
+		t.Error("Send doesn't fail when queue is full.")
+	}
+	stats := dp.SendStats()
+	if stats.Queued != 2 || stats.Dropped != 1 {
+		t.Log("Actual stats is : ", stats)
+		t.Error("Send counters are wrong.")
+	}
+}
+
+func TestSendDropNewest(t *testing.T) {
+	dp := newTestDatapath(1, SEND_DROP_NEWEST)
+	first := ofp13.NewOfpBarrierRequest()
+	dp.Send(first)
+	if err := dp.Send(ofp13.NewOfpBarrierRequest()); err != nil {
+		t.Error("Send fails in spite of drop newest policy.")
+	}
+	msgs := drainSendBuffer(dp)
+	if len(msgs) != 1 || msgs[0] != first || dp.SendStats().Dropped != 1 {
+		t.Error("Newest message is not dropped.")
+	}
+}
+
+func TestSendBlockContext(t *testing.T) {
+	dp := newTestDatapath(1, SEND_BLOCK)
+	dp.Send(ofp13.NewOfpBarrierRequest())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := dp.SendContext(ctx, ofp13.NewOfpBarrierRequest()); err != context.DeadlineExceeded {
+		t.Log("Expected error is : ", context.DeadlineExceeded)
+		t.Log("Actual error is   : ", err)
+		t.Error("Blocked Send doesn't return when context is done.")
+	}
+}
+
+func TestSendClosed(t *testing.T) {
+	dp := newTestDatapath(1, SEND_BLOCK)
+	dp.Send(ofp13.NewOfpBarrierRequest())
+
+	// blocked Send is released by Close
+	result := make(chan error)
+	go func() {
+		result <- dp.Send(ofp13.NewOfpBarrierRequest())
+	}()
+	dp.Close()
+	select {
+	case err := <-result:
+		if err != ErrDatapathClosed {
+			t.Log("Expected error is : ", ErrDatapathClosed)
+			t.Log("Actual error is   : ", err)
+			t.Error("Blocked Send doesn't fail when datapath is closed.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Blocked Send is not released by Close.")
+	}
+
+	if err := dp.Send(ofp13.NewOfpBarrierRequest()); err != ErrDatapathClosed {
+		t.Error("Send doesn't fail after datapath is closed.")
+	}
+}
+
+func TestSendLoop(t *testing.T) {
+	server, client := newTCPPair(t)
+	defer client.Close()
+
+	dp := NewDatapath(server)
+	go dp.sendLoop()
+	defer dp.Close()
+
+	echo := ofp13.NewOfpEchoRequest()
+	dp.Send(echo)
+	buf := make([]byte, echo.Size())
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && dp.SendStats().Sent == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := dp.SendStats(); stats.Queued != 1 || stats.Sent != 1 {
+		t.Log("Actual stats is : ", stats)
+		t.Error("Send counters are wrong.")
+	}
+}
+
+func TestSendControlPriority(t *testing.T) {
+	server, client := newTCPPair(t)
+	defer client.Close()
+
+	dp := newTestDatapath(100, SEND_BLOCK)
+	dp.conn = server
+	defer dp.Close()
+
+	// echo reply is queued behind bulk messages, but is sent first
+	for i := 0; i < 100; i++ {
+		dp.Send(ofp13.NewOfpBarrierRequest())
+	}
+	dp.Send(ofp13.NewOfpEchoReply())
+	go dp.sendLoop()
+
+	buf := make([]byte, 8)
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(client, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf[1] != ofp13.OFPT_ECHO_REPLY {
+		t.Log("Expected type is : ", ofp13.OFPT_ECHO_REPLY)
+		t.Log("Actual type is   : ", buf[1])
+		t.Error("Control message is not sent ahead of bulk messages.")
+	}
+}
+
+func TestSendLoopBatch(t *testing.T) {
+	server, client := newTCPPair(t)
+	defer client.Close()
+
+	const count = 5000
+	dp := newTestDatapath(count+1, SEND_BLOCK)
+	dp.conn = server
+	defer dp.Close()
+
+	// large message is written together with batch
+	large := ofp13.NewOfpPacketOut(ofp13.OFP_NO_BUFFER, ofp13.OFPP_CONTROLLER, nil, make([]byte, SEND_BATCH_SIZE/2))
+	for i := 0; i < count; i++ {
+		barrier := ofp13.NewOfpBarrierRequest()
+		barrier.Xid = uint32(i)
+		dp.Send(barrier)
+		if i == count/2 {
+			dp.Send(large)
+		}
+	}
+	go dp.sendLoop()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, 8)
+	for i := 0; i < count; {
+		if _, err := io.ReadFull(client, header); err != nil {
+			t.Fatal(err)
+		}
+		if header[1] == ofp13.OFPT_PACKET_OUT {
+			length := int(binary.BigEndian.Uint16(header[2:]))
+			if _, err := io.CopyN(io.Discard, client, int64(length-8)); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if xid := binary.BigEndian.Uint32(header[4:]); xid != uint32(i) {
+			t.Log("Expected xid is : ", i)
+			t.Log("Actual xid is   : ", xid)
+			t.Fatal("Batched messages are broken or reordered.")
+		}
+		i++
+	}
+}
+
+/*****************************************************/
+/* Recv                                              */
+/*****************************************************/
+func TestRecvLoopStream(t *testing.T) {
+	server, client := newTCPPair(t)
+	defer client.Close()
+
+	dp := NewDatapath(server)
+	defer dp.Close()
+	xids := make(chan uint32, 4)
+	dp.AddRecvInterceptor(func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler) {
+		if h, ok := msg.(*ofp13.OfpHeader); ok {
+			xids <- h.Xid
+		}
+	})
+	go dp.recvLoop()
+
+	// messages are split and coalesced by stream
+	stream := make([]byte, 0)
+	for xid := uint32(1); xid <= 3; xid++ {
+		echo := ofp13.NewOfpEchoRequest()
+		echo.Xid = xid
+		stream = echo.SerializeTo(stream)
+	}
+	client.SetNoDelay(true)
+	for _, chunk := range [][]byte{stream[:3], stream[3:12], stream[12:]} {
+		if _, err := client.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for expected := uint32(1); expected <= 3; expected++ {
+		select {
+		case xid := <-xids:
+			if xid != expected {
+				t.Errorf("Message of xid %d is received, expected %d.", xid, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Message is not received.")
+		}
+	}
+}
+
+/*****************************************************/
+/* Benchmark                                         */
+/*****************************************************/
+func newBenchFlowMod() *ofp13.OfpFlowMod {
+	match := ofp13.NewOfpMatch()
+	match.Append(ofp13.NewOxmInPort(1))
+	instruction := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+	instruction.Append(ofp13.NewOfpActionOutput(2, 0))
+	return ofp13.NewOfpFlowModAdd(0, 0, 0, 100, 0, match, []ofp13.OfpInstruction{instruction})
+}
+
+// throughput of sendLoop over loopback connection
+func BenchmarkSendLoop(b *testing.B) {
+	server, client := newTCPPair(b)
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+
+	dp := newTestDatapath(1024, SEND_BLOCK)
+	dp.conn = server
+	defer dp.Close()
+	go dp.sendLoop()
+
+	fm := newBenchFlowMod()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dp.Send(fm)
+	}
+	for dp.SendStats().Sent < uint64(b.N) {
+		time.Sleep(time.Microsecond)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+// throughput of writing each message by a syscall, for comparison
+func BenchmarkSendUnbatched(b *testing.B) {
+	server, client := newTCPPair(b)
+	defer client.Close()
+	defer server.Close()
+	go io.Copy(io.Discard, client)
+
+	fm := newBenchFlowMod()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := server.Write(fm.Serialize()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
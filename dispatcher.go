@@ -0,0 +1,259 @@
+package gofc
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// dispatch model
+type DispatchMode int
+
+const (
+	// handlers are called on the receiving goroutine of each datapath.
+	// this is the default.
+	DISPATCH_SYNC DispatchMode = iota
+	// handlers are called on a fixed pool of workers. messages from one
+	// datapath are always handled by the same worker, so they are processed
+	// in order.
+	DISPATCH_POOL
+)
+
+const DEFAULT_DISPATCH_WORKERS = 8
+const DEFAULT_DISPATCH_QUEUE_SIZE = 256
+
+/**
+ * AsyncApp is implemented by applications which are called on their own
+ * goroutine regardless of dispatch mode. Messages are queued up to
+ * QueueSize and dropped when the queue is full.
+ * Because they run apart from the pipeline, async applications can't
+ * consume messages.
+ */
+type AsyncApp interface {
+	QueueSize() int
+}
+
+type dispatchItem struct {
+	dp  *Datapath
+	msg ofp13.OFMessage
+}
+
+/**
+ * worker of DISPATCH_POOL
+ */
+type dispatchWorker struct {
+	queue chan dispatchItem
+	// number of Dispatch calls sending to queue
+	senders sync.WaitGroup
+}
+
+// close queue after pending senders finish. the worker exits after
+// draining the queue.
+func (w *dispatchWorker) stop() {
+	w.senders.Wait()
+	close(w.queue)
+}
+
+/**
+ * queue of an async application
+ */
+type appQueue struct {
+	queue   chan dispatchItem
+	dropped uint64
+	// set by releaseApp. messages left in queue are dropped.
+	closed int32
+}
+
+/**
+ * DispatchStats is a snapshot of dispatcher queues.
+ */
+type DispatchStats struct {
+	Mode DispatchMode
+	// number of queued messages of each worker
+	WorkerQueueDepth []int
+	// number of queued messages of each async application
+	AppQueueDepth map[string]int
+	// number of messages dropped by each async application's queue
+	AppDropped map[string]uint64
+}
+
+/**
+ * Dispatcher passes received messages to the application pipeline.
+ */
+type Dispatcher struct {
+	mutex   sync.RWMutex
+	mode    DispatchMode
+	workers []*dispatchWorker
+	apps    map[interface{}]*appQueue
+}
+
+var dispatcher *Dispatcher = newDispatcher()
+
+func newDispatcher() *Dispatcher {
+	d := new(Dispatcher)
+	d.mode = DISPATCH_SYNC
+	d.apps = make(map[interface{}]*appQueue)
+	return d
+}
+
+func GetDispatcher() *Dispatcher {
+	return dispatcher
+}
+
+// SetMode changes dispatch model. workers and queueSize are used in
+// DISPATCH_POOL; 0 means default. It should be called before ServerLoop,
+// messages being handled by old workers may be reordered otherwise.
+func (d *Dispatcher) SetMode(mode DispatchMode, workers int, queueSize int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// stop current workers. they exit after draining their queue.
+	// Dispatch may still be sending to them without lock, so their queues
+	// are closed asynchronously.
+	for _, w := range d.workers {
+		go w.stop()
+	}
+	d.workers = nil
+	d.mode = mode
+
+	if mode != DISPATCH_POOL {
+		return
+	}
+	if workers <= 0 {
+		workers = DEFAULT_DISPATCH_WORKERS
+	}
+	if queueSize <= 0 {
+		queueSize = DEFAULT_DISPATCH_QUEUE_SIZE
+	}
+	d.workers = make([]*dispatchWorker, workers)
+	for i := range d.workers {
+		d.workers[i] = &dispatchWorker{queue: make(chan dispatchItem, queueSize)}
+		go d.workerLoop(d.workers[i].queue)
+	}
+}
+
+// Mode returns current dispatch model.
+func (d *Dispatcher) Mode() DispatchMode {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.mode
+}
+
+// Dispatch passes msg received from dp to the pipeline.
+// In DISPATCH_POOL, it blocks while the worker's queue is full, which
+// applies backpressure to the datapath connection.
+func (d *Dispatcher) Dispatch(dp *Datapath, msg ofp13.OFMessage) {
+	d.mutex.RLock()
+	if d.mode != DISPATCH_POOL {
+		d.mutex.RUnlock()
+		dp.dispatchHandler(msg)
+		return
+	}
+	// receive buffer is reused before worker handles msg
+	if pin, ok := msg.(*ofp13.OfpPacketIn); ok {
+		pin.Retain()
+	}
+	worker := d.workers[dp.seq%uint64(len(d.workers))]
+	worker.senders.Add(1)
+	// send without lock. the worker may need the lock to handle queued
+	// messages, so blocking with it held could deadlock.
+	d.mutex.RUnlock()
+	worker.queue <- dispatchItem{dp, msg}
+	worker.senders.Done()
+}
+
+func (d *Dispatcher) workerLoop(queue chan dispatchItem) {
+	for item := range queue {
+		item.dp.dispatchHandler(item.msg)
+	}
+}
+
+// enqueue msg to the queue of async application app.
+// queue is written under lock so that releaseApp doesn't close it meanwhile.
+func (d *Dispatcher) dispatchAsync(app interface{}, dp *Datapath, msg ofp13.OFMessage) {
+	// msg is shared with async application's goroutine. Retain copies the
+	// receive buffer and decodes match, so that msg isn't modified
+	// concurrently.
+	if pin, ok := msg.(*ofp13.OfpPacketIn); ok {
+		pin.Retain()
+	}
+
+	d.mutex.RLock()
+	if q, ok := d.apps[app]; ok {
+		d.enqueue(app, q, dispatchItem{dp, msg})
+		d.mutex.RUnlock()
+		return
+	}
+	d.mutex.RUnlock()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	q, ok := d.apps[app]
+	if !ok {
+		size := app.(AsyncApp).QueueSize()
+		if size <= 0 {
+			size = DEFAULT_DISPATCH_QUEUE_SIZE
+		}
+		q = &appQueue{queue: make(chan dispatchItem, size)}
+		d.apps[app] = q
+		go d.appLoop(app, q)
+	}
+	d.enqueue(app, q, dispatchItem{dp, msg})
+}
+
+func (d *Dispatcher) enqueue(app interface{}, q *appQueue, item dispatchItem) {
+	select {
+	case q.queue <- item:
+	default:
+		if atomic.AddUint64(&q.dropped, 1) == 1 {
+			fmt.Println("queue of application", appName(app), "is full, message is dropped")
+		}
+	}
+}
+
+func (d *Dispatcher) appLoop(app interface{}, q *appQueue) {
+	for item := range q.queue {
+		// skip messages queued before app was unregistered or disabled
+		if atomic.LoadInt32(&q.closed) != 0 || GetAppManager().IsApplicationDisabled(app) {
+			continue
+		}
+		item.dp.safeInvokeHandler(app, item.msg)
+	}
+}
+
+// release the queue of app. called when app is unregistered, before it is
+// stopped. queued messages are not handled any more, but a handler already
+// running is not waited for.
+func (d *Dispatcher) releaseApp(app interface{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if q, ok := d.apps[app]; ok {
+		atomic.StoreInt32(&q.closed, 1)
+		close(q.queue)
+		delete(d.apps, app)
+	}
+}
+
+// Stats returns current depth of dispatcher queues.
+func (d *Dispatcher) Stats() DispatchStats {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	stats := DispatchStats{}
+	stats.Mode = d.mode
+	stats.WorkerQueueDepth = make([]int, len(d.workers))
+	for i, w := range d.workers {
+		stats.WorkerQueueDepth[i] = len(w.queue)
+	}
+	stats.AppQueueDepth = make(map[string]int)
+	stats.AppDropped = make(map[string]uint64)
+	for app, q := range d.apps {
+		name := appName(app)
+		stats.AppQueueDepth[name] = len(q.queue)
+		stats.AppDropped[name] = atomic.LoadUint64(&q.dropped)
+	}
+	return stats
+}
@@ -0,0 +1,355 @@
+package gofc
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/*****************************************************/
+/* test applications                                 */
+/*****************************************************/
+// records xid of PacketIn for each datapath
+type orderApp struct {
+	mutex sync.Mutex
+	wg    *sync.WaitGroup
+	xids  map[*Datapath][]uint32
+}
+
+func (app *orderApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	app.mutex.Lock()
+	app.xids[dp] = append(app.xids[dp], msg.Header.Xid)
+	app.mutex.Unlock()
+	app.wg.Done()
+}
+
+// async application blocked until release is closed
+type slowApp struct {
+	size     int
+	received chan struct{}
+	release  chan struct{}
+	wg       *sync.WaitGroup
+}
+
+func (app *slowApp) QueueSize() int {
+	return app.size
+}
+
+func (app *slowApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	app.received <- struct{}{}
+	<-app.release
+	app.wg.Done()
+}
+
+// synchronous application blocked until release is closed
+type gateApp struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (app *gateApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	select {
+	case app.entered <- struct{}{}:
+	default:
+	}
+	<-app.release
+}
+
+// async application counting received messages
+type countApp struct {
+	wg *sync.WaitGroup
+}
+
+func (app *countApp) QueueSize() int {
+	return 16
+}
+
+func (app *countApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	app.wg.Done()
+}
+
+// async App counting handler calls after Stop
+type stopApp struct {
+	received chan struct{}
+	release  chan struct{}
+	mutex    sync.Mutex
+	stopped  bool
+	calls    int
+	late     int
+}
+
+func (app *stopApp) Name() string {
+	return "stop"
+}
+
+func (app *stopApp) Init(manager *AppManager) error {
+	return nil
+}
+
+func (app *stopApp) Start() error {
+	return nil
+}
+
+func (app *stopApp) Stop() error {
+	app.mutex.Lock()
+	app.stopped = true
+	app.mutex.Unlock()
+	return nil
+}
+
+func (app *stopApp) QueueSize() int {
+	return 8
+}
+
+func (app *stopApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	app.mutex.Lock()
+	app.calls++
+	if app.stopped {
+		app.late++
+	}
+	app.mutex.Unlock()
+	app.received <- struct{}{}
+	<-app.release
+}
+
+// replace global Dispatcher during test
+func withDispatcher(f func(d *Dispatcher)) {
+	saved := dispatcher
+	dispatcher = newDispatcher()
+	defer func() {
+		dispatcher.SetMode(DISPATCH_SYNC, 0, 0)
+		dispatcher = saved
+	}()
+	f(dispatcher)
+}
+
+func newPacketIn(xid uint32) *ofp13.OfpPacketIn {
+	msg := ofp13.NewOfpPacketIn()
+	msg.Header.Xid = xid
+	return msg
+}
+
+/*****************************************************/
+/* Dispatch                                          */
+/*****************************************************/
+func TestDispatchPoolOrder(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		withDispatcher(func(d *Dispatcher) {
+			const count = 1000
+			wg := new(sync.WaitGroup)
+			app := &orderApp{wg: wg, xids: make(map[*Datapath][]uint32)}
+			manager.RegistApplication(app)
+			d.SetMode(DISPATCH_POOL, 4, 16)
+
+			dps := make([]*Datapath, 6)
+			for i := range dps {
+				dps[i] = NewDatapath(nil)
+			}
+			wg.Add(count * len(dps))
+			for _, dp := range dps {
+				go func(dp *Datapath) {
+					for xid := uint32(0); xid < count; xid++ {
+						d.Dispatch(dp, newPacketIn(xid))
+					}
+				}(dp)
+			}
+			wg.Wait()
+
+			for i, dp := range dps {
+				xids := app.xids[dp]
+				if len(xids) != count {
+					t.Errorf("datapath %d received %d messages, expected %d.", i, len(xids), count)
+					continue
+				}
+				for j, xid := range xids {
+					if xid != uint32(j) {
+						t.Log("Expected xid is : ", j)
+						t.Log("Actual xid is   : ", xid)
+						t.Errorf("Messages of datapath %d are reordered.", i)
+						break
+					}
+				}
+			}
+		})
+	})
+}
+
+func TestDispatchAsyncApp(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		withDispatcher(func(d *Dispatcher) {
+			trace := make([]string, 0)
+			wg := new(sync.WaitGroup)
+			slow := &slowApp{2, make(chan struct{}, 5), make(chan struct{}), wg}
+			manager.RegistApplicationWithPriority(slow, 100)
+			manager.RegistApplication(&recordApp{"forwarding", &trace})
+
+			// slow app holds the first message and queues next two.
+			// the rest are dropped without blocking the pipeline.
+			dp := NewDatapath(nil)
+			wg.Add(3)
+			done := make(chan struct{})
+			go func() {
+				for i := 0; i < 5; i++ {
+					d.Dispatch(dp, newPacketIn(uint32(i)))
+					// let slow app take the first message out of queue
+					if i == 0 {
+						<-slow.received
+					}
+				}
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Pipeline is blocked by async application.")
+			}
+
+			if len(trace) != 5 {
+				t.Log("Actual trace is : ", trace)
+				t.Error("Messages were not dispatched to synchronous application.")
+			}
+			stats := d.Stats()
+			if stats.AppDropped["*gofc.slowApp"] != 2 || stats.AppQueueDepth["*gofc.slowApp"] != 2 {
+				t.Log("Expected dropped/depth is : ", 2, 2)
+				t.Log("Actual dropped/depth is   : ", stats.AppDropped["*gofc.slowApp"], stats.AppQueueDepth["*gofc.slowApp"])
+				t.Error("Queue of async application is not bounded.")
+			}
+
+			close(slow.release)
+			wg.Wait()
+			manager.UnregistApplication(slow)
+			if _, ok := d.Stats().AppQueueDepth["*gofc.slowApp"]; ok {
+				t.Error("Queue of unregistered application is not released.")
+			}
+		})
+	})
+}
+
+func TestDispatchAsyncAppUnregist(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		withDispatcher(func(d *Dispatcher) {
+			app := &stopApp{received: make(chan struct{}, 4), release: make(chan struct{})}
+			manager.RegistApplication(app)
+			if err := manager.StartApplications(); err != nil {
+				t.Fatal(err)
+			}
+
+			// app holds the first message and queues the rest
+			dp := NewDatapath(nil)
+			d.Dispatch(dp, newPacketIn(0))
+			<-app.received
+			for i := 1; i < 4; i++ {
+				d.Dispatch(dp, newPacketIn(uint32(i)))
+			}
+
+			if err := manager.UnregistApplication(app); err != nil {
+				t.Fatal(err)
+			}
+			close(app.release)
+			// let queue goroutine drain remaining messages
+			time.Sleep(50 * time.Millisecond)
+
+			app.mutex.Lock()
+			defer app.mutex.Unlock()
+			if app.calls != 1 || app.late != 0 {
+				t.Log("Actual calls/after stop are : ", app.calls, app.late)
+				t.Error("Queued messages are handled after application is unregistered.")
+			}
+		})
+	})
+}
+
+func TestDispatchPoolFullQueue(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		withDispatcher(func(d *Dispatcher) {
+			wg := new(sync.WaitGroup)
+			gate := &gateApp{make(chan struct{}, 1), make(chan struct{})}
+			manager.RegistApplicationWithPriority(gate, 100)
+			manager.RegistApplication(&countApp{wg})
+			d.SetMode(DISPATCH_POOL, 1, 1)
+
+			// worker holds the first message in gate and queues the second.
+			// the third blocks Dispatch until worker takes the lock for the
+			// new async application.
+			const count = 3
+			dp := NewDatapath(nil)
+			wg.Add(count)
+			done := make(chan struct{})
+			go func() {
+				for i := 0; i < count; i++ {
+					d.Dispatch(dp, newPacketIn(uint32(i)))
+					if i == 0 {
+						<-gate.entered
+					}
+				}
+				close(done)
+			}()
+			time.Sleep(50 * time.Millisecond)
+			close(gate.release)
+
+			finished := make(chan struct{})
+			go func() {
+				// SetMode also takes the lock while Dispatch may be blocked
+				d.SetMode(DISPATCH_POOL, 1, 1)
+				<-done
+				wg.Wait()
+				close(finished)
+			}()
+			select {
+			case <-finished:
+			case <-time.After(5 * time.Second):
+				// leave deadlocked dispatcher so that cleanup doesn't block
+				dispatcher = newDispatcher()
+				t.Fatal("Dispatch with full worker queue is deadlocked.")
+			}
+		})
+	})
+}
+
+type dataApp struct {
+	wg   *sync.WaitGroup
+	data [][]byte
+}
+
+func (app *dataApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) {
+	app.data = append(app.data, msg.GetData())
+	app.wg.Done()
+}
+
+func TestDispatchPoolLazyPacketIn(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		withDispatcher(func(d *Dispatcher) {
+			LAZY_PACKET_IN = true
+			defer func() { LAZY_PACKET_IN = false }()
+
+			wg := new(sync.WaitGroup)
+			app := &dataApp{wg: wg}
+			manager.RegistApplication(app)
+			d.SetMode(DISPATCH_POOL, 1, 16)
+
+			buf := []byte{
+				0x04, 0x0a, 0x00, 0x26, 0x00, 0x00, 0x00, 0x00, // header
+				0xff, 0xff, 0xff, 0xff, 0x00, 0x04, 0x00, 0x00, // BufferId, TotalLen, Reason, TableId
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Cookie
+				0x00, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, // empty match
+				0x00, 0x00, 0xde, 0xad, 0xbe, 0xef, // padding, data
+			}
+			dp := NewDatapath(nil)
+			wg.Add(1)
+			dp.handlePacket(buf)
+			// recvLoop reuses buffer
+			for i := range buf {
+				buf[i] = 0
+			}
+			wg.Wait()
+
+			if len(app.data) != 1 || !bytes.Equal(app.data[0], []byte{0xde, 0xad, 0xbe, 0xef}) {
+				t.Log("Actual data is : ", app.data)
+				t.Error("PacketIn handled by worker refers reused buffer.")
+			}
+		})
+	})
+}
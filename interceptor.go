@@ -0,0 +1,117 @@
+package gofc
+
+import (
+	"sync"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// MessageHandler passes msg to the next stage of interceptor chain.
+type MessageHandler func(msg ofp13.OFMessage, dp *Datapath)
+
+/**
+ * Interceptor is called for each message sent to or received from a
+ * datapath. It passes the message to the rest of chain by calling next.
+ * An interceptor can
+ *   - log the message and call next(msg, dp),
+ *   - mutate or replace the message and pass the result to next,
+ *   - drop the message by not calling next,
+ *   - inject messages by calling next more than once.
+ */
+type Interceptor func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler)
+
+/**
+ * list of interceptors. the slice is replaced on every change,
+ * so that it can be run without holding the lock.
+ */
+type interceptorChain struct {
+	mutex        sync.RWMutex
+	interceptors []Interceptor
+}
+
+func (c *interceptorChain) add(i Interceptor) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	list := make([]Interceptor, len(c.interceptors), len(c.interceptors)+1)
+	copy(list, c.interceptors)
+	c.interceptors = append(list, i)
+}
+
+func (c *interceptorChain) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.interceptors = nil
+}
+
+func (c *interceptorChain) list() []Interceptor {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.interceptors
+}
+
+func runChain(chain []Interceptor, msg ofp13.OFMessage, dp *Datapath, last MessageHandler) {
+	if msg == nil {
+		return
+	}
+	if len(chain) == 0 {
+		last(msg, dp)
+		return
+	}
+	chain[0](msg, dp, func(m ofp13.OFMessage, d *Datapath) {
+		runChain(chain[1:], m, d, last)
+	})
+}
+
+// interceptors applied to all datapaths
+var sendInterceptors = new(interceptorChain)
+var recvInterceptors = new(interceptorChain)
+
+// AddSendInterceptor adds i to the chain run for messages sent to any
+// datapath. Global interceptors run before per-datapath ones.
+func AddSendInterceptor(i Interceptor) {
+	sendInterceptors.add(i)
+}
+
+// AddRecvInterceptor adds i to the chain run for messages received from any
+// datapath, before they are dispatched to applications.
+func AddRecvInterceptor(i Interceptor) {
+	recvInterceptors.add(i)
+}
+
+// ClearInterceptors removes all global interceptors.
+func ClearInterceptors() {
+	sendInterceptors.clear()
+	recvInterceptors.clear()
+}
+
+// AddSendInterceptor adds i to the chain run for messages sent to dp.
+func (dp *Datapath) AddSendInterceptor(i Interceptor) {
+	dp.sendInterceptors.add(i)
+}
+
+// AddRecvInterceptor adds i to the chain run for messages received from dp.
+func (dp *Datapath) AddRecvInterceptor(i Interceptor) {
+	dp.recvInterceptors.add(i)
+}
+
+// ClearInterceptors removes all interceptors of dp.
+func (dp *Datapath) ClearInterceptors() {
+	dp.sendInterceptors.clear()
+	dp.recvInterceptors.clear()
+}
+
+// run global and per-datapath send interceptors, then last.
+func (dp *Datapath) interceptSend(msg ofp13.OFMessage, last MessageHandler) {
+	local := dp.sendInterceptors.list()
+	runChain(sendInterceptors.list(), msg, dp, func(m ofp13.OFMessage, d *Datapath) {
+		runChain(local, m, d, last)
+	})
+}
+
+// run global and per-datapath recv interceptors, then last.
+func (dp *Datapath) interceptRecv(msg ofp13.OFMessage, last MessageHandler) {
+	local := dp.recvInterceptors.list()
+	runChain(recvInterceptors.list(), msg, dp, func(m ofp13.OFMessage, d *Datapath) {
+		runChain(local, m, d, last)
+	})
+}
@@ -0,0 +1,108 @@
+package gofc
+
+import (
+	"testing"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+type echoApp struct {
+	xids []uint32
+}
+
+func (app *echoApp) HandleEchoRequest(msg *ofp13.OfpHeader, dp *Datapath) {
+	app.xids = append(app.xids, msg.Xid)
+}
+
+// receive all queued messages of dp, control messages first
+func drainSendBuffer(dp *Datapath) []ofp13.OFMessage {
+	msgs := make([]ofp13.OFMessage, 0)
+	for _, queue := range []chan *ofp13.OFMessage{dp.ctrlBuffer, dp.sendBuffer} {
+		for len(queue) > 0 {
+			msgs = append(msgs, *<-queue)
+		}
+	}
+	return msgs
+}
+
+/*****************************************************/
+/* Interceptor                                       */
+/*****************************************************/
+func TestSendInterceptor(t *testing.T) {
+	defer ClearInterceptors()
+	trace := make([]string, 0)
+
+	// drop FlowMod with table id 100, like a policy blocking some flows
+	AddSendInterceptor(func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler) {
+		trace = append(trace, "global")
+		if fm, ok := msg.(*ofp13.OfpFlowMod); ok && fm.TableId == 100 {
+			return
+		}
+		next(msg, dp)
+	})
+	dp := NewDatapath(nil)
+	// inject barrier after each FlowMod
+	dp.AddSendInterceptor(func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler) {
+		trace = append(trace, "local")
+		next(msg, dp)
+		if _, ok := msg.(*ofp13.OfpFlowMod); ok {
+			next(ofp13.NewOfpBarrierRequest(), dp)
+		}
+	})
+
+	allowed := ofp13.NewOfpFlowModAdd(0, 0, 0, 0, 0, ofp13.NewOfpMatch(), nil)
+	blocked := ofp13.NewOfpFlowModAdd(0, 0, 100, 0, 0, ofp13.NewOfpMatch(), nil)
+	dp.Send(blocked)
+	dp.Send(allowed)
+
+	msgs := drainSendBuffer(dp)
+	if len(msgs) != 2 || msgs[0] != allowed {
+		t.Log("Actual messages are : ", msgs)
+		t.Fatal("Send interceptors are not applied.")
+	}
+	if h, ok := msgs[1].(*ofp13.OfpHeader); !ok || h.Type != ofp13.OFPT_BARRIER_REQUEST {
+		t.Error("Injected message is not sent.")
+	}
+	expect := []string{"global", "global", "local"}
+	if !equalTrace(expect, trace) {
+		t.Log("Expected trace is : ", expect)
+		t.Log("Actual trace is   : ", trace)
+		t.Error("Send interceptors are not called in order.")
+	}
+}
+
+func TestRecvInterceptor(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		defer ClearInterceptors()
+		app := &echoApp{}
+		manager.RegistApplication(app)
+
+		// rewrite xid
+		AddRecvInterceptor(func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler) {
+			if h, ok := msg.(*ofp13.OfpHeader); ok {
+				h.Xid += 100
+			}
+			next(msg, dp)
+		})
+		dp := NewDatapath(nil)
+		// drop messages with odd xid
+		dp.AddRecvInterceptor(func(msg ofp13.OFMessage, dp *Datapath, next MessageHandler) {
+			if h, ok := msg.(*ofp13.OfpHeader); ok && h.Xid%2 == 1 {
+				return
+			}
+			next(msg, dp)
+		})
+
+		for xid := uint32(0); xid < 4; xid++ {
+			echo := ofp13.NewOfpEchoRequest()
+			echo.Xid = xid
+			dp.handlePacket(echo.Serialize())
+		}
+
+		if len(app.xids) != 2 || app.xids[0] != 100 || app.xids[1] != 102 {
+			t.Log("Expected xids are : ", []uint32{100, 102})
+			t.Log("Actual xids are   : ", app.xids)
+			t.Error("Recv interceptors are not applied.")
+		}
+	})
+}
@@ -4,6 +4,19 @@ import (
 	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
 )
 
+/*****************************************************/
+/* EventResult                                       */
+/*****************************************************/
+// Every Of13*Handler has an Of13*Consumer counterpart whose method returns
+// EventResult. Applications implementing the consumer variant can stop
+// propagation of the message to lower priority applications.
+type EventResult int
+
+const (
+	EventContinue EventResult = iota // pass message to next application
+	EventConsumed                    // stop dispatching this message
+)
+
 /*****************************************************/
 /* OfpErrorMsg                                       */
 /*****************************************************/
@@ -11,6 +24,10 @@ type Of13ErrorMsgHandler interface {
 	HandleErrorMsg(*ofp13.OfpErrorMsg, *Datapath)
 }
 
+type Of13ErrorMsgConsumer interface {
+	HandleErrorMsg(*ofp13.OfpErrorMsg, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* Echo Message                                      */
 /*****************************************************/
@@ -18,10 +35,18 @@ type Of13EchoRequestHandler interface {
 	HandleEchoRequest(*ofp13.OfpHeader, *Datapath)
 }
 
+type Of13EchoRequestConsumer interface {
+	HandleEchoRequest(*ofp13.OfpHeader, *Datapath) EventResult
+}
+
 type Of13EchoReplyHandler interface {
 	HandleEchoReply(*ofp13.OfpHeader, *Datapath)
 }
 
+type Of13EchoReplyConsumer interface {
+	HandleEchoReply(*ofp13.OfpHeader, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* BarrierReply Message                              */
 /*****************************************************/
@@ -29,6 +54,10 @@ type Of13BarrierReplyHandler interface {
 	HandleBarrierReply(*ofp13.OfpHeader, *Datapath)
 }
 
+type Of13BarrierReplyConsumer interface {
+	HandleBarrierReply(*ofp13.OfpHeader, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpSwitchFeatures                                 */
 /*****************************************************/
@@ -36,6 +65,10 @@ type Of13SwitchFeaturesHandler interface {
 	HandleSwitchFeatures(*ofp13.OfpSwitchFeatures, *Datapath)
 }
 
+type Of13SwitchFeaturesConsumer interface {
+	HandleSwitchFeatures(*ofp13.OfpSwitchFeatures, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpSwitchConfig                                   */
 /*****************************************************/
@@ -43,6 +76,10 @@ type Of13SwitchConfigHandler interface {
 	HandleSwitchConfig(*ofp13.OfpSwitchConfig, *Datapath)
 }
 
+type Of13SwitchConfigConsumer interface {
+	HandleSwitchConfig(*ofp13.OfpSwitchConfig, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpPacketIn                                       */
 /*****************************************************/
@@ -50,6 +87,21 @@ type Of13PacketInHandler interface {
 	HandlePacketIn(*ofp13.OfpPacketIn, *Datapath)
 }
 
+type Of13PacketInConsumer interface {
+	HandlePacketIn(*ofp13.OfpPacketIn, *Datapath) EventResult
+}
+
+/*****************************************************/
+/* OfpPortStatus                                     */
+/*****************************************************/
+type Of13PortStatusHandler interface {
+	HandlePortStatus(*ofp13.OfpPortStatus, *Datapath)
+}
+
+type Of13PortStatusConsumer interface {
+	HandlePortStatus(*ofp13.OfpPortStatus, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpFlowRemoved                                    */
 /*****************************************************/
@@ -57,6 +109,10 @@ type Of13FlowRemovedHandler interface {
 	HandleFlowRemoved(*ofp13.OfpFlowRemoved, *Datapath)
 }
 
+type Of13FlowRemovedConsumer interface {
+	HandleFlowRemoved(*ofp13.OfpFlowRemoved, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpDescStatsReply                                 */
 /*****************************************************/
@@ -64,6 +120,10 @@ type Of13DescStatsReplyHandler interface {
 	HandleDescStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13DescStatsReplyConsumer interface {
+	HandleDescStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpFlowStatsReply                                 */
 /*****************************************************/
@@ -71,6 +131,10 @@ type Of13FlowStatsReplyHandler interface {
 	HandleFlowStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13FlowStatsReplyConsumer interface {
+	HandleFlowStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpAggregateStatsReply                            */
 /*****************************************************/
@@ -78,6 +142,10 @@ type Of13AggregateStatsReplyHandler interface {
 	HandleAggregateStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13AggregateStatsReplyConsumer interface {
+	HandleAggregateStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpTableStatsReply                                */
 /*****************************************************/
@@ -85,6 +153,10 @@ type Of13TableStatsReplyHandler interface {
 	HandleTableStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13TableStatsReplyConsumer interface {
+	HandleTableStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpPortStatsReply                                 */
 /*****************************************************/
@@ -92,6 +164,10 @@ type Of13PortStatsReplyHandler interface {
 	HandlePortStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13PortStatsReplyConsumer interface {
+	HandlePortStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpQueueStatsReply                                */
 /*****************************************************/
@@ -99,6 +175,10 @@ type Of13QueueStatsReplyHandler interface {
 	HandleQueueStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13QueueStatsReplyConsumer interface {
+	HandleQueueStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpGroupStatsReply                                */
 /*****************************************************/
@@ -106,6 +186,10 @@ type Of13GroupStatsReplyHandler interface {
 	HandleGroupStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13GroupStatsReplyConsumer interface {
+	HandleGroupStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpGroupDescStatsReply                            */
 /*****************************************************/
@@ -113,6 +197,10 @@ type Of13GroupDescStatsReplyHandler interface {
 	HandleGroupDescStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13GroupDescStatsReplyConsumer interface {
+	HandleGroupDescStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpGroupFeaturesStatsReply                        */
 /*****************************************************/
@@ -120,6 +208,10 @@ type Of13GroupFeaturesStatsReplyHandler interface {
 	HandleGroupFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13GroupFeaturesStatsReplyConsumer interface {
+	HandleGroupFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpMeterStatsReply                                */
 /*****************************************************/
@@ -127,6 +219,10 @@ type Of13MeterStatsReplyHandler interface {
 	HandleMeterStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13MeterStatsReplyConsumer interface {
+	HandleMeterStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpMeterConfigStatsReply                          */
 /*****************************************************/
@@ -134,6 +230,10 @@ type Of13MeterConfigStatsReplyHandler interface {
 	HandleMeterConfigStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13MeterConfigStatsReplyConsumer interface {
+	HandleMeterConfigStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpMeterFeaturesStatsReply                        */
 /*****************************************************/
@@ -141,6 +241,10 @@ type Of13MeterFeaturesStatsReplyHandler interface {
 	HandleMeterFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13MeterFeaturesStatsReplyConsumer interface {
+	HandleMeterFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpTableFeaturesStatsReply                        */
 /*****************************************************/
@@ -148,6 +252,10 @@ type Of13TableFeaturesStatsReplyHandler interface {
 	HandleTableFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13TableFeaturesStatsReplyConsumer interface {
+	HandleTableFeaturesStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* OfpPortDescStatsReply                             */
 /*****************************************************/
@@ -155,6 +263,10 @@ type Of13PortDescStatsReplyHandler interface {
 	HandlePortDescStatsReply(*ofp13.OfpMultipartReply, *Datapath)
 }
 
+type Of13PortDescStatsReplyConsumer interface {
+	HandlePortDescStatsReply(*ofp13.OfpMultipartReply, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* RoleReply Message                                 */
 /*****************************************************/
@@ -162,9 +274,17 @@ type Of13RoleReplyHandler interface {
 	HandleRoleReply(*ofp13.OfpRole, *Datapath)
 }
 
+type Of13RoleReplyConsumer interface {
+	HandleRoleReply(*ofp13.OfpRole, *Datapath) EventResult
+}
+
 /*****************************************************/
 /* GetAsyncReply Message                             */
 /*****************************************************/
 type Of13AsyncConfigHandler interface {
 	HandleAsyncConfig(*ofp13.OfpAsyncConfig, *Datapath)
 }
+
+type Of13AsyncConfigConsumer interface {
+	HandleAsyncConfig(*ofp13.OfpAsyncConfig, *Datapath) EventResult
+}
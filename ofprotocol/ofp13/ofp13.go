@@ -6,6 +6,7 @@ import (
 
 type OFMessage interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 }
@@ -808,6 +809,7 @@ type OfpMatch struct {
 
 type OxmField interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse([]byte)
 	OxmClass() uint32
 	OxmField() uint32
@@ -1004,6 +1006,7 @@ type OfpOxmExperimenterHeader struct {
 
 type OfpAction interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	OfpActionType() uint16
@@ -1091,6 +1094,7 @@ type OfpActionExperimenter struct {
 
 type OfpInstruction interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	InstructionType() uint16
@@ -1186,6 +1190,8 @@ type OfpPacketIn struct {
 	Match    *OfpMatch
 	Pad      [2]uint8
 	Data     []uint8
+	raw      []byte // received packet, referred by ParseLazy
+	retained bool   // raw is owned by message
 }
 
 type OfpFlowRemoved struct {
@@ -1205,6 +1211,7 @@ type OfpFlowRemoved struct {
 
 type OfpMeterBand interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	MeterBandType() uint16
@@ -1258,6 +1265,7 @@ type OfpErrorExperimenterMsg struct {
 
 type OfpMultipartBody interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	MPType() uint16
@@ -1336,6 +1344,7 @@ type OfpAggregateStats struct {
 
 type OfpTableFeatureProp interface {
 	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	Property() uint16
@@ -1520,6 +1529,8 @@ type OfpExperimenterMultipartHeader struct {
 }
 
 type OfpQueueProp interface {
+	Serialize() []byte
+	SerializeTo(dst []byte) []byte
 	Parse(packet []byte)
 	Size() int
 	Property() uint16
@@ -0,0 +1,70 @@
+package ofp13
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+/*****************************************************/
+/* BinaryMessage                                     */
+/*****************************************************/
+
+/**
+ * BinaryMessage adapts OFMessage to encoding.BinaryMarshaler,
+ * encoding.BinaryUnmarshaler and encoding.BinaryAppender.
+ */
+type BinaryMessage struct {
+	Message OFMessage
+}
+
+/// create BinaryMessage instance wrapping msg.
+func NewBinaryMessage(msg OFMessage) *BinaryMessage {
+	return &BinaryMessage{msg}
+}
+
+/// Serialize wrapped message.
+func (b *BinaryMessage) MarshalBinary() ([]byte, error) {
+	return b.AppendBinary(nil)
+}
+
+/// Serialize wrapped message and append it to dst.
+func (b *BinaryMessage) AppendBinary(dst []byte) ([]byte, error) {
+	if b.Message == nil {
+		return dst, errors.New("message is nil.")
+	}
+	size := len(dst)
+	dst = b.Message.SerializeTo(dst)
+	if len(dst) == size {
+		return dst, fmt.Errorf("serialization of %T is not supported.", b.Message)
+	}
+	return dst, nil
+}
+
+/// Parse data into wrapped message. If no message is wrapped, a message is
+/// created according to the type in header, as Parse does.
+func (b *BinaryMessage) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < 8 {
+		return errors.New("message is shorter than header.")
+	}
+	if length := int(binary.BigEndian.Uint16(data[2:])); length != len(data) {
+		return fmt.Errorf("length in header is %d, but data is %d bytes.", length, len(data))
+	}
+
+	// Parse of each message doesn't check bounds
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse message: %v", r)
+		}
+	}()
+
+	if b.Message == nil {
+		b.Message = Parse(data)
+		if b.Message == nil {
+			return fmt.Errorf("message type %d is not supported.", data[1])
+		}
+		return nil
+	}
+	b.Message.Parse(data)
+	return nil
+}
@@ -0,0 +1,143 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"testing"
+)
+
+// interfaces BinaryMessage must satisfy
+var _ encoding.BinaryMarshaler = &BinaryMessage{}
+var _ encoding.BinaryUnmarshaler = &BinaryMessage{}
+var _ encoding.BinaryAppender = &BinaryMessage{}
+
+func newTestFlowMod() *OfpFlowMod {
+	match := NewOfpMatch()
+	match.Append(NewOxmInPort(1))
+	match.Append(NewOxmEthType(0x0800))
+	ipv4, _ := NewOxmIpv4DstW("192.168.0.0", 16)
+	match.Append(ipv4)
+	instruction := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+	instruction.Append(NewOfpActionSetField(NewOxmVlanVid(100)))
+	instruction.Append(NewOfpActionOutput(2, 0))
+	return NewOfpFlowModAdd(0, 0, 0, 100, 0, match, []OfpInstruction{instruction})
+}
+
+func newTestPacketOut() *OfpPacketOut {
+	actions := []OfpAction{NewOfpActionOutput(OFPP_FLOOD, 0)}
+	return NewOfpPacketOut(OFP_NO_BUFFER, OFPP_CONTROLLER, actions, make([]byte, 64))
+}
+
+/*****************************************************/
+/* SerializeTo                                       */
+/*****************************************************/
+func TestSerializeTo(t *testing.T) {
+	messages := []OFMessage{newTestFlowMod(), newTestPacketOut(), NewOfpHello()}
+	for _, msg := range messages {
+		expect := msg.Serialize()
+		prefix := []byte{0xff, 0xff}
+		actual := msg.SerializeTo(prefix)
+		if !bytes.Equal(prefix, actual[:2]) || !bytes.Equal(expect, actual[2:]) {
+			t.Log("Expected Value is : ", hex.EncodeToString(expect))
+			t.Log("Actual Value is   : ", hex.EncodeToString(actual))
+			t.Errorf("SerializeTo of %T is not equal to Serialize.", msg)
+		}
+
+		// garbage in reused buffer must be cleared
+		dirty := bytes.Repeat([]byte{0xaa}, len(expect)+16)
+		actual = msg.SerializeTo(dirty[:0])
+		if !bytes.Equal(expect, actual) {
+			t.Log("Expected Value is : ", hex.EncodeToString(expect))
+			t.Log("Actual Value is   : ", hex.EncodeToString(actual))
+			t.Errorf("SerializeTo of %T doesn't clear reused buffer.", msg)
+		}
+	}
+}
+
+func TestSerializeToAllocs(t *testing.T) {
+	fm := newTestFlowMod()
+	po := newTestPacketOut()
+	buf := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = fm.SerializeTo(buf[:0])
+		buf = po.SerializeTo(buf[:0])
+	})
+	if allocs != 0 {
+		t.Log("Actual allocs is : ", allocs)
+		t.Error("SerializeTo allocates with enough buffer.")
+	}
+}
+
+/*****************************************************/
+/* BinaryMessage                                     */
+/*****************************************************/
+func TestBinaryMessage(t *testing.T) {
+	echo := NewOfpEchoRequest()
+	data, err := NewBinaryMessage(echo).MarshalBinary()
+	if err != nil || !bytes.Equal(data, echo.Serialize()) {
+		t.Error("Failed to marshal message.")
+	}
+
+	b := new(BinaryMessage)
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if h, ok := b.Message.(*OfpHeader); !ok || h.Type != OFPT_ECHO_REQUEST || h.Xid != echo.Xid {
+		t.Log("Actual message is : ", b.Message)
+		t.Error("Failed to unmarshal message.")
+	}
+
+	// broken data is reported as error
+	if err := new(BinaryMessage).UnmarshalBinary(data[:4]); err == nil {
+		t.Error("Short data is not reported.")
+	}
+	data[3] = 0x10
+	if err := new(BinaryMessage).UnmarshalBinary(data); err == nil {
+		t.Error("Wrong length is not reported.")
+	}
+	truncated := []byte{0x04, OFPT_PACKET_IN, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	if err := new(BinaryMessage).UnmarshalBinary(truncated); err == nil {
+		t.Error("Truncated body is not reported.")
+	}
+	if _, err := NewBinaryMessage(nil).MarshalBinary(); err == nil {
+		t.Error("Nil message is not reported.")
+	}
+}
+
+/*****************************************************/
+/* Benchmark                                         */
+/*****************************************************/
+func BenchmarkFlowModSerialize(b *testing.B) {
+	fm := newTestFlowMod()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fm.Serialize()
+	}
+}
+
+func BenchmarkFlowModSerializeTo(b *testing.B) {
+	fm := newTestFlowMod()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = fm.SerializeTo(buf[:0])
+	}
+}
+
+func BenchmarkPacketOutSerialize(b *testing.B) {
+	po := newTestPacketOut()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		po.Serialize()
+	}
+}
+
+func BenchmarkPacketOutSerializeTo(b *testing.B) {
+	po := newTestPacketOut()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = po.SerializeTo(buf[:0])
+	}
+}
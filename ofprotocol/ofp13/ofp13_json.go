@@ -0,0 +1,3032 @@
+package ofp13
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************/
+/* JSON Representation                               */
+/*****************************************************/
+
+/**
+ * Messages and their nested types are encoded in JSON objects whose keys are
+ * the names of fields in the OpenFlow 1.3 spec.
+ *
+ *  - message has "type" such as "OFPT_FLOW_MOD" and "xid". version, lengths
+ *    and paddings are omitted, and computed on decoding.
+ *  - enums are names such as "OFPFC_ADD", and flags are arrays of names.
+ *  - reserved ports, groups, meters, tables, queues, buffer id and max_len
+ *    are names such as "OFPP_CONTROLLER", and the others are numbers.
+ *  - cookie, metadata, datapath_id and ethertype are hex strings.
+ *  - match is an array of OXM fields such as
+ *    {"field": "ipv4_dst", "value": "10.0.0.0", "mask": "255.0.0.0"}.
+ *  - actions, instructions, meter bands and properties are objects with
+ *    "type" and arguments of the type.
+ *  - payloads are base64 strings.
+ *
+ * Numbers are accepted in place of names on decoding.
+ */
+
+/// parse s as unsigned integer of bits, in decimal or hex with 0x.
+func jsonUint(s string, bits int) (uint64, bool) {
+	v, err := strconv.ParseUint(s, 0, bits)
+	return v, err == nil
+}
+
+/// value of name in names indexed by value, or name as number.
+func ofpValue(name string, names []string, bits int) (uint64, bool) {
+	for v, n := range names {
+		if n != "" && n == name {
+			return uint64(v), true
+		}
+	}
+	return jsonUint(name, bits)
+}
+
+/// value of name in names, or name as number.
+func ofpMapValue(name string, names map[uint64]string, bits int) (uint64, bool) {
+	for v, n := range names {
+		if n == name {
+			return v, true
+		}
+	}
+	return jsonUint(name, bits)
+}
+
+/// converter of names decoded from JSON into values, which keeps the
+/// first error.
+type jsonNames struct {
+	err error
+}
+
+func (c *jsonNames) fail(key string, name string) {
+	if c.err == nil {
+		c.err = fmt.Errorf("%s %s is invalid.", key, name)
+	}
+}
+
+func (c *jsonNames) name(key string, name string, names []string, bits int) uint64 {
+	v, ok := ofpValue(name, names, bits)
+	if !ok {
+		c.fail(key, name)
+	}
+	return v
+}
+
+func (c *jsonNames) mapName(key string, name string, names map[uint64]string, bits int) uint64 {
+	v, ok := ofpMapValue(name, names, bits)
+	if !ok {
+		c.fail(key, name)
+	}
+	return v
+}
+
+func (c *jsonNames) flags(key string, list []string, flags []ofpFlag, bits int) uint64 {
+	var v uint64
+	for _, name := range list {
+		found := false
+		for _, f := range flags {
+			if f.name == name {
+				v |= f.flag
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if n, ok := jsonUint(name, bits); ok {
+			v |= n
+		} else {
+			c.fail(key, name)
+		}
+	}
+	return v
+}
+
+/// encode v as its name in names, or as number.
+func marshalReserved(v uint64, names map[uint64]string) ([]byte, error) {
+	if name, ok := names[v]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(v)
+}
+
+/// decode name in names or number. null leaves v as it is.
+func unmarshalReserved(data []byte, v uint64, names map[uint64]string, bits int) (uint64, error) {
+	s := string(data)
+	if s == "null" {
+		return v, nil
+	}
+	if strings.HasPrefix(s, "\"") {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return v, err
+		}
+		if n, ok := ofpMapValue(s, names, bits); ok {
+			return n, nil
+		}
+	} else if n, ok := jsonUint(s, bits); ok {
+		return n, nil
+	}
+	return v, fmt.Errorf("%s is invalid.", s)
+}
+
+/// scalar of JSON, string or number, as string
+func jsonScalar(data json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", fmt.Errorf("%s is not a string or number.", string(data))
+	}
+	return n.String(), nil
+}
+
+/// set v decoded from JSON to dst, which is a pointer of the same type.
+func setDecodedJSON(dst interface{}, v interface{}) error {
+	dv, vv := reflect.ValueOf(dst), reflect.ValueOf(v)
+	if dv.Type() != vv.Type() {
+		return fmt.Errorf("%s can't be decoded into %T.", vv.Type(), dst)
+	}
+	dv.Elem().Set(vv.Elem())
+	return nil
+}
+
+/// port number, encoded as name of reserved port or number
+type jsonPort uint32
+
+func (v jsonPort) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpPortNames)
+}
+
+func (v *jsonPort) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpPortNames, 32)
+	*v = jsonPort(n)
+	return err
+}
+
+/// group id, encoded as name of reserved group or number
+type jsonGroup uint32
+
+func (v jsonGroup) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpGroupNames)
+}
+
+func (v *jsonGroup) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpGroupNames, 32)
+	*v = jsonGroup(n)
+	return err
+}
+
+/// meter id, encoded as name of reserved meter or number
+type jsonMeter uint32
+
+func (v jsonMeter) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpMeterNames)
+}
+
+func (v *jsonMeter) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpMeterNames, 32)
+	*v = jsonMeter(n)
+	return err
+}
+
+/// table id, encoded as "OFPTT_ALL" or number
+type jsonTable uint8
+
+func (v jsonTable) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpTableNames)
+}
+
+func (v *jsonTable) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpTableNames, 8)
+	*v = jsonTable(n)
+	return err
+}
+
+/// queue id, encoded as "OFPQ_ALL" or number
+type jsonQueue uint32
+
+func (v jsonQueue) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpQueueNames)
+}
+
+func (v *jsonQueue) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpQueueNames, 32)
+	*v = jsonQueue(n)
+	return err
+}
+
+/// buffer id, encoded as "OFP_NO_BUFFER" or number
+type jsonBuffer uint32
+
+func (v jsonBuffer) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpBufferNames)
+}
+
+func (v *jsonBuffer) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpBufferNames, 32)
+	*v = jsonBuffer(n)
+	return err
+}
+
+/// max_len, encoded as "OFPCML_NO_BUFFER" or number
+type jsonMaxLen uint16
+
+func (v jsonMaxLen) MarshalJSON() ([]byte, error) {
+	return marshalReserved(uint64(v), ofpMaxLenNames)
+}
+
+func (v *jsonMaxLen) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), ofpMaxLenNames, 16)
+	*v = jsonMaxLen(n)
+	return err
+}
+
+/// cookie, metadata and so on, encoded as hex string such as "0x10".
+/// numbers are also accepted on decoding.
+type jsonHex uint64
+
+func (v jsonHex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%x", uint64(v)))
+}
+
+func (v *jsonHex) UnmarshalJSON(data []byte) error {
+	n, err := unmarshalReserved(data, uint64(*v), nil, 64)
+	*v = jsonHex(n)
+	return err
+}
+
+/*****************************************************/
+/* JSONMessage                                       */
+/*****************************************************/
+
+/**
+ * JSONMessage adapts OFMessage to json.Marshaler and json.Unmarshaler,
+ * so that message of any type can be a field of other JSON objects.
+ */
+type JSONMessage struct {
+	Message OFMessage
+}
+
+/// create JSONMessage instance wrapping msg.
+func NewJSONMessage(msg OFMessage) *JSONMessage {
+	return &JSONMessage{msg}
+}
+
+/// Encode wrapped message.
+func (j *JSONMessage) MarshalJSON() ([]byte, error) {
+	if j.Message == nil {
+		return nil, errors.New("message is nil.")
+	}
+	return json.Marshal(j.Message)
+}
+
+/// Decode data into wrapped message. If no message is wrapped, a message is
+/// created according to the type, as ParseJSON does.
+func (j *JSONMessage) UnmarshalJSON(data []byte) error {
+	if j.Message == nil {
+		msg, err := ParseJSON(data)
+		if err != nil {
+			return err
+		}
+		j.Message = msg
+		return nil
+	}
+	return json.Unmarshal(data, j.Message)
+}
+
+/// Decode JSON representation of message. The type of message is chosen
+/// by "type" in data, as Parse does by header.
+func ParseJSON(data []byte) (OFMessage, error) {
+	var j struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	t, ok := ofpValue(j.Type, ofpTypeNames, 8)
+	if !ok {
+		return nil, fmt.Errorf("message type %s is invalid.", j.Type)
+	}
+
+	var msg OFMessage
+	switch t {
+	case OFPT_HELLO:
+		msg = new(OfpHello)
+	case OFPT_ERROR:
+		msg = new(OfpErrorMsg)
+	case OFPT_ECHO_REQUEST, OFPT_ECHO_REPLY, OFPT_FEATURES_REQUEST, OFPT_GET_CONFIG_REQUEST,
+		OFPT_BARRIER_REQUEST, OFPT_BARRIER_REPLY, OFPT_GET_ASYNC_REQUEST:
+		msg = new(OfpHeader)
+	case OFPT_FEATURES_REPLY:
+		msg = new(OfpSwitchFeatures)
+	case OFPT_GET_CONFIG_REPLY, OFPT_SET_CONFIG:
+		msg = new(OfpSwitchConfig)
+	case OFPT_PACKET_IN:
+		msg = new(OfpPacketIn)
+	case OFPT_FLOW_REMOVED:
+		msg = new(OfpFlowRemoved)
+	case OFPT_PORT_STATUS:
+		msg = new(OfpPortStatus)
+	case OFPT_PACKET_OUT:
+		msg = new(OfpPacketOut)
+	case OFPT_FLOW_MOD:
+		msg = new(OfpFlowMod)
+	case OFPT_GROUP_MOD:
+		msg = new(OfpGroupMod)
+	case OFPT_PORT_MOD:
+		msg = new(OfpPortMod)
+	case OFPT_TABLE_MOD:
+		msg = new(OfpTableMod)
+	case OFPT_MULTIPART_REQUEST:
+		msg = new(OfpMultipartRequest)
+	case OFPT_MULTIPART_REPLY:
+		msg = new(OfpMultipartReply)
+	case OFPT_QUEUE_GET_CONFIG_REQUEST:
+		msg = new(OfpQueueGetConfigRequest)
+	case OFPT_QUEUE_GET_CONFIG_REPLY:
+		msg = new(OfpQueueGetConfigReply)
+	case OFPT_ROLE_REQUEST, OFPT_ROLE_REPLY:
+		msg = new(OfpRole)
+	case OFPT_GET_ASYNC_REPLY, OFPT_SET_ASYNC:
+		msg = new(OfpAsyncConfig)
+	case OFPT_METER_MOD:
+		msg = new(OfpMeterMod)
+	default:
+		return nil, fmt.Errorf("message type %s is not supported.", j.Type)
+	}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+/*****************************************************/
+/* Header and Symmetric Messages                     */
+/*****************************************************/
+
+/// header of message in JSON, embedded in JSON of each message
+type ofpHeaderJSON struct {
+	Type string `json:"type"`
+	Xid  uint32 `json:"xid"`
+}
+
+func newOfpHeaderJSON(h *OfpHeader) ofpHeaderJSON {
+	return ofpHeaderJSON{TypeString(h.Type), h.Xid}
+}
+
+func (j *ofpHeaderJSON) header(c *jsonNames) OfpHeader {
+	h := NewOfpHeader(uint8(c.name("type", j.Type, ofpTypeNames, 8)))
+	h.Xid = j.Xid
+	return h
+}
+
+/// Messages without body, such as echo and barrier, are encoded by this.
+func (h *OfpHeader) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newOfpHeaderJSON(h))
+}
+
+func (h *OfpHeader) UnmarshalJSON(data []byte) error {
+	var j ofpHeaderJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	header := j.header(c)
+	if c.err != nil {
+		return c.err
+	}
+	*h = header
+	return nil
+}
+
+type helloElemJSON struct {
+	Type    string   `json:"type"`
+	Bitmaps []uint32 `json:"bitmaps,omitempty"`
+}
+
+func (c *jsonNames) helloElemType(name string) uint16 {
+	if name == "OFPHET_VERSIONBITMAP" {
+		return OFPHET_VERSIONBITMAP
+	}
+	return uint16(c.name("type", name, nil, 16))
+}
+
+func (h *OfpHelloElemHeader) MarshalJSON() ([]byte, error) {
+	return json.Marshal(helloElemJSON{Type: h.String()})
+}
+
+func (h *OfpHelloElemHeader) UnmarshalJSON(data []byte) error {
+	var j helloElemJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	e := NewOfpHelloElemHeader()
+	e.Type = c.helloElemType(j.Type)
+	*h = *e
+	return c.err
+}
+
+func (h *OfpHelloElemVersionBitmap) MarshalJSON() ([]byte, error) {
+	bitmaps := h.Bitmaps
+	if bitmaps == nil {
+		bitmaps = []uint32{}
+	}
+	e := OfpHelloElemHeader{Type: h.Type}
+	return json.Marshal(helloElemJSON{e.String(), bitmaps})
+}
+
+func (h *OfpHelloElemVersionBitmap) UnmarshalJSON(data []byte) error {
+	var j helloElemJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	h.Type = c.helloElemType(j.Type)
+	h.Bitmaps = j.Bitmaps
+	h.Length = uint16(4 + 4*len(j.Bitmaps))
+	return c.err
+}
+
+type helloJSON struct {
+	ofpHeaderJSON
+	Elements []OfpHelloElemHeader `json:"elements"`
+}
+
+func (m *OfpHello) MarshalJSON() ([]byte, error) {
+	elements := m.Elements
+	if elements == nil {
+		elements = []OfpHelloElemHeader{}
+	}
+	return json.Marshal(helloJSON{newOfpHeaderJSON(&m.Header), elements})
+}
+
+func (m *OfpHello) UnmarshalJSON(data []byte) error {
+	var j helloJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Elements = j.Elements
+	if m.Elements == nil {
+		m.Elements = make([]OfpHelloElemHeader, 0)
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+func (c *jsonNames) errorType(name string) uint16 {
+	if name == "OFPET_EXPERIMENTER" {
+		return OFPET_EXPERIMENTER
+	}
+	return uint16(c.name("error_type", name, ofpErrorTypeNames, 16))
+}
+
+type errorMsgJSON struct {
+	ofpHeaderJSON
+	ErrorType string `json:"error_type"`
+	Code      string `json:"code"`
+	Data      []byte `json:"data"`
+}
+
+func (m *OfpErrorMsg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorMsgJSON{newOfpHeaderJSON(&m.Header),
+		ErrorTypeString(m.Type), ErrorCodeString(m.Type, m.Code), m.Data})
+}
+
+func (m *OfpErrorMsg) UnmarshalJSON(data []byte) error {
+	var j errorMsgJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Type = c.errorType(j.ErrorType)
+	m.Code = uint16(c.name("code", j.Code, ofpErrorCodeNames[m.Type], 16))
+	m.Data = j.Data
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type errorExperimenterMsgJSON struct {
+	ofpHeaderJSON
+	ErrorType    string `json:"error_type"`
+	ExpType      uint16 `json:"exp_type"`
+	Experimenter uint32 `json:"experimenter"`
+	Data         []byte `json:"data"`
+}
+
+func (m *OfpErrorExperimenterMsg) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorExperimenterMsgJSON{newOfpHeaderJSON(&m.Header),
+		ErrorTypeString(m.Type), m.ExpType, m.Experimenter, m.Data})
+}
+
+func (m *OfpErrorExperimenterMsg) UnmarshalJSON(data []byte) error {
+	var j errorExperimenterMsgJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Type = c.errorType(j.ErrorType)
+	m.ExpType = j.ExpType
+	m.Experimenter = j.Experimenter
+	m.Data = j.Data
+	m.Header.Length = uint16(m.Header.Size() + 8 + len(m.Data))
+	return c.err
+}
+
+/*****************************************************/
+/* Switch Configuration                              */
+/*****************************************************/
+
+type switchFeaturesJSON struct {
+	ofpHeaderJSON
+	DatapathId   jsonHex  `json:"datapath_id"`
+	NBuffers     uint32   `json:"n_buffers"`
+	NTables      uint8    `json:"n_tables"`
+	AuxiliaryId  uint8    `json:"auxiliary_id"`
+	Capabilities []string `json:"capabilities"`
+	Reserved     uint32   `json:"reserved"`
+}
+
+func (m *OfpSwitchFeatures) MarshalJSON() ([]byte, error) {
+	return json.Marshal(switchFeaturesJSON{newOfpHeaderJSON(&m.Header),
+		jsonHex(m.DatapathId), m.NBuffers, m.NTables, m.AuxiliaryId,
+		ofpFlagsList(uint64(m.Capabilities), ofpCapabilitiesFlags), m.Reserved})
+}
+
+func (m *OfpSwitchFeatures) UnmarshalJSON(data []byte) error {
+	var j switchFeaturesJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	*m = OfpSwitchFeatures{}
+	m.Header = j.header(c)
+	m.DatapathId = uint64(j.DatapathId)
+	m.NBuffers = j.NBuffers
+	m.NTables = j.NTables
+	m.AuxiliaryId = j.AuxiliaryId
+	m.Capabilities = uint32(c.flags("capabilities", j.Capabilities, ofpCapabilitiesFlags, 32))
+	m.Reserved = j.Reserved
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type switchConfigJSON struct {
+	ofpHeaderJSON
+	Flags       string     `json:"flags"`
+	MissSendLen jsonMaxLen `json:"miss_send_len"`
+}
+
+func (m *OfpSwitchConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(switchConfigJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpConfigFragNames, uint64(m.Flags)), jsonMaxLen(m.MissSendLen)})
+}
+
+func (m *OfpSwitchConfig) UnmarshalJSON(data []byte) error {
+	j := switchConfigJSON{Flags: "OFPC_FRAG_NORMAL"}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Flags = uint16(c.name("flags", j.Flags, ofpConfigFragNames, 16))
+	m.MissSendLen = uint16(j.MissSendLen)
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type tableModJSON struct {
+	ofpHeaderJSON
+	TableId jsonTable `json:"table_id"`
+	Config  uint32    `json:"config"`
+}
+
+func (m *OfpTableMod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tableModJSON{newOfpHeaderJSON(&m.Header), jsonTable(m.TableId), m.Config})
+}
+
+func (m *OfpTableMod) UnmarshalJSON(data []byte) error {
+	var j tableModJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	*m = OfpTableMod{}
+	m.Header = j.header(c)
+	m.TableId = uint8(j.TableId)
+	m.Config = j.Config
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type portJSON struct {
+	PortNo     jsonPort `json:"port_no"`
+	HwAddr     string   `json:"hw_addr"`
+	Name       string   `json:"name"`
+	Config     []string `json:"config"`
+	State      []string `json:"state"`
+	Curr       []string `json:"curr"`
+	Advertised []string `json:"advertised"`
+	Supported  []string `json:"supported"`
+	Peer       []string `json:"peer"`
+	CurrSpeed  uint32   `json:"curr_speed"`
+	MaxSpeed   uint32   `json:"max_speed"`
+}
+
+// hardware address in JSON, or nil for empty string
+func parseJSONHwAddr(s string) (net.HardwareAddr, error) {
+	if s == "" {
+		return nil, nil
+	}
+	addr, err := net.ParseMAC(s)
+	if err != nil || len(addr) != 6 {
+		return nil, fmt.Errorf("hw_addr %s is invalid.", s)
+	}
+	return addr, nil
+}
+
+func (p *OfpPort) MarshalJSON() ([]byte, error) {
+	features := func(v uint32) []string {
+		return ofpFlagsList(uint64(v), ofpPortFeaturesFlags)
+	}
+	return json.Marshal(portJSON{jsonPort(p.PortNo), p.HwAddr.String(), ofpCString(p.Name),
+		ofpFlagsList(uint64(p.Config), ofpPortConfigFlags),
+		ofpFlagsList(uint64(p.State), ofpPortStateFlags),
+		features(p.Curr), features(p.Advertised), features(p.Supported), features(p.Peer),
+		p.CurrSpeed, p.MaxSpeed})
+}
+
+func (p *OfpPort) UnmarshalJSON(data []byte) error {
+	var j portJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	addr, err := parseJSONHwAddr(j.HwAddr)
+	if err != nil {
+		return err
+	}
+	if len(j.Name) >= OFP_MAX_PORT_NAME_LEN {
+		return fmt.Errorf("name %s is too long.", j.Name)
+	}
+	c := &jsonNames{}
+	features := func(key string, list []string) uint32 {
+		return uint32(c.flags(key, list, ofpPortFeaturesFlags, 32))
+	}
+	port := newOfpPort()
+	port.PortNo = uint32(j.PortNo)
+	port.HwAddr = addr
+	port.Name = make([]byte, OFP_MAX_PORT_NAME_LEN)
+	copy(port.Name, j.Name)
+	port.Config = uint32(c.flags("config", j.Config, ofpPortConfigFlags, 32))
+	port.State = uint32(c.flags("state", j.State, ofpPortStateFlags, 32))
+	port.Curr = features("curr", j.Curr)
+	port.Advertised = features("advertised", j.Advertised)
+	port.Supported = features("supported", j.Supported)
+	port.Peer = features("peer", j.Peer)
+	port.CurrSpeed = j.CurrSpeed
+	port.MaxSpeed = j.MaxSpeed
+	*p = *port
+	return c.err
+}
+
+type portStatusJSON struct {
+	ofpHeaderJSON
+	Reason string   `json:"reason"`
+	Desc   *OfpPort `json:"desc"`
+}
+
+func (m *OfpPortStatus) MarshalJSON() ([]byte, error) {
+	desc := m.Desc
+	if desc == nil {
+		desc = newOfpPort()
+	}
+	return json.Marshal(portStatusJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpPortReasonNames, uint64(m.Reason)), desc})
+}
+
+func (m *OfpPortStatus) UnmarshalJSON(data []byte) error {
+	var j portStatusJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Reason = uint8(c.name("reason", j.Reason, ofpPortReasonNames, 8))
+	m.Desc = j.Desc
+	if m.Desc == nil {
+		return errors.New("desc of port status is missing.")
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type portModJSON struct {
+	ofpHeaderJSON
+	PortNo    jsonPort `json:"port_no"`
+	HwAddr    string   `json:"hw_addr"`
+	Config    []string `json:"config"`
+	Mask      []string `json:"mask"`
+	Advertise []string `json:"advertise"`
+}
+
+func (m *OfpPortMod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(portModJSON{newOfpHeaderJSON(&m.Header), jsonPort(m.PortNo),
+		m.HwAddr.String(),
+		ofpFlagsList(uint64(m.Config), ofpPortConfigFlags),
+		ofpFlagsList(uint64(m.Mask), ofpPortConfigFlags),
+		ofpFlagsList(uint64(m.Advertise), ofpPortFeaturesFlags)})
+}
+
+func (m *OfpPortMod) UnmarshalJSON(data []byte) error {
+	var j portModJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	addr, err := parseJSONHwAddr(j.HwAddr)
+	if err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.PortNo = uint32(j.PortNo)
+	m.HwAddr = addr
+	m.Config = uint32(c.flags("config", j.Config, ofpPortConfigFlags, 32))
+	m.Mask = uint32(c.flags("mask", j.Mask, ofpPortConfigFlags, 32))
+	m.Advertise = uint32(c.flags("advertise", j.Advertise, ofpPortFeaturesFlags, 32))
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type roleJSON struct {
+	ofpHeaderJSON
+	Role         string `json:"role"`
+	GenerationId uint64 `json:"generation_id"`
+}
+
+func (m *OfpRole) MarshalJSON() ([]byte, error) {
+	return json.Marshal(roleJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpRoleNames, uint64(m.Role)), m.GenerationId})
+}
+
+func (m *OfpRole) UnmarshalJSON(data []byte) error {
+	j := roleJSON{Role: "OFPCR_ROLE_NOCHANGE"}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Role = uint32(c.name("role", j.Role, ofpRoleNames, 32))
+	m.GenerationId = j.GenerationId
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/// reasons of async messages for master and slave
+type asyncMaskJSON struct {
+	Master []string `json:"master"`
+	Slave  []string `json:"slave"`
+}
+
+type asyncConfigJSON struct {
+	ofpHeaderJSON
+	PacketInMask    asyncMaskJSON `json:"packet_in_mask"`
+	PortStatusMask  asyncMaskJSON `json:"port_status_mask"`
+	FlowRemovedMask asyncMaskJSON `json:"flow_removed_mask"`
+}
+
+func newAsyncMaskJSON(mask [2]uint32, names []string) asyncMaskJSON {
+	flags := ofpBitFlags(names)
+	return asyncMaskJSON{ofpFlagsList(uint64(mask[0]), flags), ofpFlagsList(uint64(mask[1]), flags)}
+}
+
+func (j *asyncMaskJSON) mask(c *jsonNames, key string, names []string) [2]uint32 {
+	flags := ofpBitFlags(names)
+	return [2]uint32{uint32(c.flags(key, j.Master, flags, 32)), uint32(c.flags(key, j.Slave, flags, 32))}
+}
+
+func (m *OfpAsyncConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(asyncConfigJSON{newOfpHeaderJSON(&m.Header),
+		newAsyncMaskJSON(m.PacketInMask, ofpPacketInReasonNames),
+		newAsyncMaskJSON(m.PortStatusMask, ofpPortReasonNames),
+		newAsyncMaskJSON(m.FlowRemovedMask, ofpFlowRemovedReasonNames)})
+}
+
+func (m *OfpAsyncConfig) UnmarshalJSON(data []byte) error {
+	var j asyncConfigJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.PacketInMask = j.PacketInMask.mask(c, "packet_in_mask", ofpPacketInReasonNames)
+	m.PortStatusMask = j.PortStatusMask.mask(c, "port_status_mask", ofpPortReasonNames)
+	m.FlowRemovedMask = j.FlowRemovedMask.mask(c, "flow_removed_mask", ofpFlowRemovedReasonNames)
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/*****************************************************/
+/* OXM Fields and Match                              */
+/*****************************************************/
+
+/// OXM field in JSON, such as {"field": "tcp_dst", "value": 80}. the value
+/// and mask are written as:
+///  - in_port and in_phy_port: name of reserved port or number
+///  - vlan_vid, eth_type, metadata and other hex fields: hex string
+///  - addresses: string in normal notation, and mask in the same notation
+///  - others: number
+type oxmJSON struct {
+	Field string          `json:"field,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Mask  json.RawMessage `json:"mask,omitempty"`
+}
+
+// value or mask of basic field in JSON
+func oxmJSONValue(field uint32, b []byte) interface{} {
+	if field == OFPXMT_OFB_VLAN_VID {
+		return fmt.Sprintf("0x%04x", oxmValue(b))
+	}
+	switch ofctlFields[field].kind {
+	case ofctlInt:
+		return oxmValue(b)
+	case ofctlHex:
+		if field == OFPXMT_OFB_ETH_TYPE {
+			return fmt.Sprintf("0x%04x", oxmValue(b))
+		}
+		return fmt.Sprintf("0x%x", oxmValue(b))
+	case ofctlPort:
+		return jsonPort(oxmValue(b))
+	case ofctlMac:
+		return net.HardwareAddr(b).String()
+	}
+	return net.IP(b).String()
+}
+
+func newOxmJSON(f OxmField) (oxmJSON, error) {
+	e := newOxmEntry(f)
+	j := oxmJSON{Field: oxmFieldName(e.class, e.field)}
+	if e.opaque != nil || int(e.field) >= len(ofctlFields) {
+		return j, fmt.Errorf("OXM field %s is not supported.", j.Field)
+	}
+	var err error
+	if j.Value, err = json.Marshal(oxmJSONValue(e.field, e.value)); err != nil {
+		return j, err
+	}
+	if e.mask != nil {
+		if j.Mask, err = json.Marshal(oxmJSONValue(e.field, e.mask)); err != nil {
+			return j, err
+		}
+	}
+	return j, nil
+}
+
+func (j *oxmJSON) oxmField() (OxmField, error) {
+	field, ok := ofctlFieldAliases[j.Field]
+	if !ok {
+		return nil, fmt.Errorf("OXM field %s is not supported.", j.Field)
+	}
+	if len(j.Value) == 0 {
+		return nil, fmt.Errorf("value of %s is missing.", j.Field)
+	}
+	value, err := jsonScalar(j.Value)
+	if err != nil {
+		return nil, err
+	}
+	if ofctlFields[field].kind == ofctlPort {
+		value = strings.TrimPrefix(value, "OFPP_")
+	}
+	mask, masked := "", len(j.Mask) > 0
+	if masked {
+		if !oxmFieldSpecs[field].maskable {
+			return nil, fmt.Errorf("%s is not maskable.", j.Field)
+		}
+		if mask, err = jsonScalar(j.Mask); err != nil {
+			return nil, err
+		}
+	}
+	e, err := parseOxmValue(field, value, mask, masked)
+	if err != nil {
+		return nil, err
+	}
+	return e.oxmField(), nil
+}
+
+// decode OXM field of any type
+func decodeOxmJSON(data []byte) (OxmField, error) {
+	var j oxmJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return j.oxmField()
+}
+
+func marshalOxmJSON(f OxmField) ([]byte, error) {
+	j, err := newOxmJSON(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalOxmJSON(data []byte, dst OxmField) error {
+	f, err := decodeOxmJSON(data)
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, f)
+}
+
+func (m *OxmInPort) MarshalJSON() ([]byte, error)        { return marshalOxmJSON(m) }
+func (m *OxmInPhyPort) MarshalJSON() ([]byte, error)     { return marshalOxmJSON(m) }
+func (m *OxmMetadata) MarshalJSON() ([]byte, error)      { return marshalOxmJSON(m) }
+func (m *OxmEth) MarshalJSON() ([]byte, error)           { return marshalOxmJSON(m) }
+func (m *OxmEthType) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmVlanVid) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmVlanPcp) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmIpDscp) MarshalJSON() ([]byte, error)        { return marshalOxmJSON(m) }
+func (m *OxmIpEcn) MarshalJSON() ([]byte, error)         { return marshalOxmJSON(m) }
+func (m *OxmIpProto) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmIpv4) MarshalJSON() ([]byte, error)          { return marshalOxmJSON(m) }
+func (m *OxmTcp) MarshalJSON() ([]byte, error)           { return marshalOxmJSON(m) }
+func (m *OxmUdp) MarshalJSON() ([]byte, error)           { return marshalOxmJSON(m) }
+func (m *OxmSctp) MarshalJSON() ([]byte, error)          { return marshalOxmJSON(m) }
+func (m *OxmIcmpType) MarshalJSON() ([]byte, error)      { return marshalOxmJSON(m) }
+func (m *OxmIcmpCode) MarshalJSON() ([]byte, error)      { return marshalOxmJSON(m) }
+func (m *OxmArpOp) MarshalJSON() ([]byte, error)         { return marshalOxmJSON(m) }
+func (m *OxmArpPa) MarshalJSON() ([]byte, error)         { return marshalOxmJSON(m) }
+func (m *OxmArpHa) MarshalJSON() ([]byte, error)         { return marshalOxmJSON(m) }
+func (m *OxmIpv6) MarshalJSON() ([]byte, error)          { return marshalOxmJSON(m) }
+func (m *OxmIpv6FLabel) MarshalJSON() ([]byte, error)    { return marshalOxmJSON(m) }
+func (m *OxmIcmpv6Type) MarshalJSON() ([]byte, error)    { return marshalOxmJSON(m) }
+func (m *OxmIcmpv6Code) MarshalJSON() ([]byte, error)    { return marshalOxmJSON(m) }
+func (m *OxmIpv6NdTarget) MarshalJSON() ([]byte, error)  { return marshalOxmJSON(m) }
+func (m *OxmIpv6NdSll) MarshalJSON() ([]byte, error)     { return marshalOxmJSON(m) }
+func (m *OxmIpv6NdTll) MarshalJSON() ([]byte, error)     { return marshalOxmJSON(m) }
+func (m *OxmMplsLabel) MarshalJSON() ([]byte, error)     { return marshalOxmJSON(m) }
+func (m *OxmMplsTc) MarshalJSON() ([]byte, error)        { return marshalOxmJSON(m) }
+func (m *OxmMplsBos) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmPbbIsid) MarshalJSON() ([]byte, error)       { return marshalOxmJSON(m) }
+func (m *OxmTunnelId) MarshalJSON() ([]byte, error)      { return marshalOxmJSON(m) }
+func (m *OxmIpv6ExtHeader) MarshalJSON() ([]byte, error) { return marshalOxmJSON(m) }
+
+func (m *OxmInPort) UnmarshalJSON(data []byte) error        { return unmarshalOxmJSON(data, m) }
+func (m *OxmInPhyPort) UnmarshalJSON(data []byte) error     { return unmarshalOxmJSON(data, m) }
+func (m *OxmMetadata) UnmarshalJSON(data []byte) error      { return unmarshalOxmJSON(data, m) }
+func (m *OxmEth) UnmarshalJSON(data []byte) error           { return unmarshalOxmJSON(data, m) }
+func (m *OxmEthType) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmVlanVid) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmVlanPcp) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpDscp) UnmarshalJSON(data []byte) error        { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpEcn) UnmarshalJSON(data []byte) error         { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpProto) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv4) UnmarshalJSON(data []byte) error          { return unmarshalOxmJSON(data, m) }
+func (m *OxmTcp) UnmarshalJSON(data []byte) error           { return unmarshalOxmJSON(data, m) }
+func (m *OxmUdp) UnmarshalJSON(data []byte) error           { return unmarshalOxmJSON(data, m) }
+func (m *OxmSctp) UnmarshalJSON(data []byte) error          { return unmarshalOxmJSON(data, m) }
+func (m *OxmIcmpType) UnmarshalJSON(data []byte) error      { return unmarshalOxmJSON(data, m) }
+func (m *OxmIcmpCode) UnmarshalJSON(data []byte) error      { return unmarshalOxmJSON(data, m) }
+func (m *OxmArpOp) UnmarshalJSON(data []byte) error         { return unmarshalOxmJSON(data, m) }
+func (m *OxmArpPa) UnmarshalJSON(data []byte) error         { return unmarshalOxmJSON(data, m) }
+func (m *OxmArpHa) UnmarshalJSON(data []byte) error         { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6) UnmarshalJSON(data []byte) error          { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6FLabel) UnmarshalJSON(data []byte) error    { return unmarshalOxmJSON(data, m) }
+func (m *OxmIcmpv6Type) UnmarshalJSON(data []byte) error    { return unmarshalOxmJSON(data, m) }
+func (m *OxmIcmpv6Code) UnmarshalJSON(data []byte) error    { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6NdTarget) UnmarshalJSON(data []byte) error  { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6NdSll) UnmarshalJSON(data []byte) error     { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6NdTll) UnmarshalJSON(data []byte) error     { return unmarshalOxmJSON(data, m) }
+func (m *OxmMplsLabel) UnmarshalJSON(data []byte) error     { return unmarshalOxmJSON(data, m) }
+func (m *OxmMplsTc) UnmarshalJSON(data []byte) error        { return unmarshalOxmJSON(data, m) }
+func (m *OxmMplsBos) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmPbbIsid) UnmarshalJSON(data []byte) error       { return unmarshalOxmJSON(data, m) }
+func (m *OxmTunnelId) UnmarshalJSON(data []byte) error      { return unmarshalOxmJSON(data, m) }
+func (m *OxmIpv6ExtHeader) UnmarshalJSON(data []byte) error { return unmarshalOxmJSON(data, m) }
+
+/// Match is encoded as an array of OXM fields in the order of match.
+func (m *OfpMatch) MarshalJSON() ([]byte, error) {
+	fields := make([]oxmJSON, len(m.OxmFields))
+	for i, f := range m.OxmFields {
+		j, err := newOxmJSON(f)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = j
+	}
+	return json.Marshal(fields)
+}
+
+func (m *OfpMatch) UnmarshalJSON(data []byte) error {
+	var fields []oxmJSON
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	match := NewOfpMatch()
+	for _, j := range fields {
+		f, err := j.oxmField()
+		if err != nil {
+			return err
+		}
+		match.Append(f)
+	}
+	*m = *match
+	return nil
+}
+
+// match to be encoded, which is empty for nil
+func jsonMatch(m *OfpMatch) *OfpMatch {
+	if m == nil {
+		return NewOfpMatch()
+	}
+	return m
+}
+
+// decoded match, which is empty for null
+func decodedMatch(m *OfpMatch) *OfpMatch {
+	if m == nil {
+		return NewOfpMatch()
+	}
+	return m
+}
+
+/*****************************************************/
+/* Actions                                           */
+/*****************************************************/
+
+/// action in JSON. arguments of other types are omitted, and set-field
+/// has the OXM field inline, such as
+/// {"type": "OFPAT_SET_FIELD", "field": "vlan_vid", "value": "0x100a"}.
+type actionJSON struct {
+	Type         string      `json:"type"`
+	Port         *jsonPort   `json:"port,omitempty"`
+	MaxLen       *jsonMaxLen `json:"max_len,omitempty"`
+	MplsTtl      *uint8      `json:"mpls_ttl,omitempty"`
+	EtherType    *jsonHex    `json:"ethertype,omitempty"`
+	GroupId      *jsonGroup  `json:"group_id,omitempty"`
+	QueueId      *uint32     `json:"queue_id,omitempty"`
+	NwTtl        *uint8      `json:"nw_ttl,omitempty"`
+	Experimenter *uint32     `json:"experimenter,omitempty"`
+	oxmJSON
+}
+
+func newActionJSON(a OfpAction) (*actionJSON, error) {
+	j := &actionJSON{Type: ofpMapName(ofpActionNames, uint64(a.OfpActionType()))}
+	switch a := a.(type) {
+	case *OfpActionOutput:
+		port, maxLen := jsonPort(a.Port), jsonMaxLen(a.MaxLen)
+		j.Port, j.MaxLen = &port, &maxLen
+	case *OfpActionSetMplsTtl:
+		ttl := a.MplsTtl
+		j.MplsTtl = &ttl
+	case *OfpActionPush:
+		t := jsonHex(a.EtherType)
+		j.EtherType = &t
+	case *OfpActionPop:
+		t := jsonHex(a.EtherType)
+		j.EtherType = &t
+	case *OfpActionGroup:
+		id := jsonGroup(a.GroupId)
+		j.GroupId = &id
+	case *OfpActionSetQueue:
+		id := a.QueueId
+		j.QueueId = &id
+	case *OfpActionSetNwTtl:
+		ttl := a.NwTtl
+		j.NwTtl = &ttl
+	case *OfpActionSetField:
+		if a.Oxm == nil {
+			return nil, errors.New("field of set-field is missing.")
+		}
+		oxm, err := newOxmJSON(a.Oxm)
+		if err != nil {
+			return nil, err
+		}
+		j.oxmJSON = oxm
+	case *OfpActionExperimenter:
+		experimenter := a.Experimenter
+		j.Experimenter = &experimenter
+	case *OfpActionCopyTtlOut, *OfpActionCopyTtlIn, *OfpActionDecMplsTtl, *OfpActionDecNwTtl:
+	default:
+		return nil, fmt.Errorf("action %T is not supported.", a)
+	}
+	return j, nil
+}
+
+func (j *actionJSON) action() (OfpAction, error) {
+	t, ok := ofpMapValue(j.Type, ofpActionNames, 16)
+	if !ok {
+		return nil, fmt.Errorf("action type %s is invalid.", j.Type)
+	}
+	missing := func(key string) error {
+		return fmt.Errorf("%s of %s is missing.", key, j.Type)
+	}
+	ethertype := func() (uint16, error) {
+		if j.EtherType == nil {
+			return 0, missing("ethertype")
+		}
+		if *j.EtherType > 0xffff {
+			return 0, fmt.Errorf("ethertype of %s is invalid.", j.Type)
+		}
+		return uint16(*j.EtherType), nil
+	}
+
+	switch t {
+	case OFPAT_OUTPUT:
+		if j.Port == nil {
+			return nil, missing("port")
+		}
+		maxLen := uint16(OFPCML_NO_BUFFER)
+		if j.MaxLen != nil {
+			maxLen = uint16(*j.MaxLen)
+		}
+		return NewOfpActionOutput(uint32(*j.Port), maxLen), nil
+	case OFPAT_COPY_TTL_OUT:
+		return NewOfpActionCopyTtlOut(), nil
+	case OFPAT_COPY_TTL_IN:
+		return NewOfpActionCopyTtlIn(), nil
+	case OFPAT_SET_MPLS_TTL:
+		if j.MplsTtl == nil {
+			return nil, missing("mpls_ttl")
+		}
+		return NewOfpActionSetMplsTtl(*j.MplsTtl), nil
+	case OFPAT_DEC_MPLS_TTL:
+		return NewOfpActionDecMplsTtl(), nil
+	case OFPAT_PUSH_VLAN, OFPAT_PUSH_MPLS, OFPAT_PUSH_PBB:
+		etherType, err := ethertype()
+		if err != nil {
+			return nil, err
+		}
+		return NewOfpActionPush(uint16(t), etherType), nil
+	case OFPAT_POP_VLAN:
+		return NewOfpActionPopVlan(0), nil
+	case OFPAT_POP_MPLS:
+		etherType, err := ethertype()
+		if err != nil {
+			return nil, err
+		}
+		return NewOfpActionPopMpls(etherType), nil
+	case OFPAT_POP_PBB:
+		return NewOfpActionPopPbb(0), nil
+	case OFPAT_GROUP:
+		if j.GroupId == nil {
+			return nil, missing("group_id")
+		}
+		return NewOfpActionGroup(uint32(*j.GroupId)), nil
+	case OFPAT_SET_QUEUE:
+		if j.QueueId == nil {
+			return nil, missing("queue_id")
+		}
+		return NewOfpActionSetQueue(*j.QueueId), nil
+	case OFPAT_SET_NW_TTL:
+		if j.NwTtl == nil {
+			return nil, missing("nw_ttl")
+		}
+		return NewOfpActionSetNwTtl(*j.NwTtl), nil
+	case OFPAT_DEC_NW_TTL:
+		return NewOfpActionDecNwTtl(), nil
+	case OFPAT_SET_FIELD:
+		if j.Field == "" {
+			return nil, missing("field")
+		}
+		oxm, err := j.oxmField()
+		if err != nil {
+			return nil, err
+		}
+		return NewOfpActionSetField(oxm), nil
+	case OFPAT_EXPERIMENTER:
+		if j.Experimenter == nil {
+			return nil, missing("experimenter")
+		}
+		return NewOfpActionExperimenter(*j.Experimenter), nil
+	}
+	return nil, fmt.Errorf("action type %s is not supported.", j.Type)
+}
+
+// decode action of any type
+func decodeActionJSON(data []byte) (OfpAction, error) {
+	var j actionJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return j.action()
+}
+
+func marshalActionJSON(a OfpAction) ([]byte, error) {
+	j, err := newActionJSON(a)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalActionJSON(data []byte, dst OfpAction) error {
+	a, err := decodeActionJSON(data)
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, a)
+}
+
+func (a *OfpActionOutput) MarshalJSON() ([]byte, error)       { return marshalActionJSON(a) }
+func (a *OfpActionCopyTtlOut) MarshalJSON() ([]byte, error)   { return marshalActionJSON(a) }
+func (a *OfpActionCopyTtlIn) MarshalJSON() ([]byte, error)    { return marshalActionJSON(a) }
+func (a *OfpActionSetMplsTtl) MarshalJSON() ([]byte, error)   { return marshalActionJSON(a) }
+func (a *OfpActionDecMplsTtl) MarshalJSON() ([]byte, error)   { return marshalActionJSON(a) }
+func (a *OfpActionPush) MarshalJSON() ([]byte, error)         { return marshalActionJSON(a) }
+func (a *OfpActionPop) MarshalJSON() ([]byte, error)          { return marshalActionJSON(a) }
+func (a *OfpActionGroup) MarshalJSON() ([]byte, error)        { return marshalActionJSON(a) }
+func (a *OfpActionSetQueue) MarshalJSON() ([]byte, error)     { return marshalActionJSON(a) }
+func (a *OfpActionSetNwTtl) MarshalJSON() ([]byte, error)     { return marshalActionJSON(a) }
+func (a *OfpActionDecNwTtl) MarshalJSON() ([]byte, error)     { return marshalActionJSON(a) }
+func (a *OfpActionSetField) MarshalJSON() ([]byte, error)     { return marshalActionJSON(a) }
+func (a *OfpActionExperimenter) MarshalJSON() ([]byte, error) { return marshalActionJSON(a) }
+
+func (a *OfpActionOutput) UnmarshalJSON(data []byte) error       { return unmarshalActionJSON(data, a) }
+func (a *OfpActionCopyTtlOut) UnmarshalJSON(data []byte) error   { return unmarshalActionJSON(data, a) }
+func (a *OfpActionCopyTtlIn) UnmarshalJSON(data []byte) error    { return unmarshalActionJSON(data, a) }
+func (a *OfpActionSetMplsTtl) UnmarshalJSON(data []byte) error   { return unmarshalActionJSON(data, a) }
+func (a *OfpActionDecMplsTtl) UnmarshalJSON(data []byte) error   { return unmarshalActionJSON(data, a) }
+func (a *OfpActionPush) UnmarshalJSON(data []byte) error         { return unmarshalActionJSON(data, a) }
+func (a *OfpActionPop) UnmarshalJSON(data []byte) error          { return unmarshalActionJSON(data, a) }
+func (a *OfpActionGroup) UnmarshalJSON(data []byte) error        { return unmarshalActionJSON(data, a) }
+func (a *OfpActionSetQueue) UnmarshalJSON(data []byte) error     { return unmarshalActionJSON(data, a) }
+func (a *OfpActionSetNwTtl) UnmarshalJSON(data []byte) error     { return unmarshalActionJSON(data, a) }
+func (a *OfpActionDecNwTtl) UnmarshalJSON(data []byte) error     { return unmarshalActionJSON(data, a) }
+func (a *OfpActionSetField) UnmarshalJSON(data []byte) error     { return unmarshalActionJSON(data, a) }
+func (a *OfpActionExperimenter) UnmarshalJSON(data []byte) error { return unmarshalActionJSON(data, a) }
+
+/// list of actions, encoded as an array which is empty for nil
+type jsonActions []OfpAction
+
+func (l jsonActions) MarshalJSON() ([]byte, error) {
+	list := make([]*actionJSON, len(l))
+	for i, a := range l {
+		j, err := newActionJSON(a)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = j
+	}
+	return json.Marshal(list)
+}
+
+func (l *jsonActions) UnmarshalJSON(data []byte) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	actions := make(jsonActions, len(list))
+	for i, raw := range list {
+		a, err := decodeActionJSON(raw)
+		if err != nil {
+			return err
+		}
+		actions[i] = a
+	}
+	*l = actions
+	return nil
+}
+
+/*****************************************************/
+/* Instructions                                      */
+/*****************************************************/
+
+/// instruction in JSON. metadata_mask of write-metadata is all ones if it
+/// is omitted.
+type instructionJSON struct {
+	Type         string       `json:"type"`
+	TableId      *uint8       `json:"table_id,omitempty"`
+	Metadata     *jsonHex     `json:"metadata,omitempty"`
+	MetadataMask *jsonHex     `json:"metadata_mask,omitempty"`
+	Actions      *jsonActions `json:"actions,omitempty"`
+	MeterId      *jsonMeter   `json:"meter_id,omitempty"`
+	Experimenter *uint32      `json:"experimenter,omitempty"`
+}
+
+func newInstructionJSON(i OfpInstruction) (*instructionJSON, error) {
+	j := &instructionJSON{Type: ofpMapName(ofpInstructionNames, uint64(i.InstructionType()))}
+	switch i := i.(type) {
+	case *OfpInstructionGotoTable:
+		id := i.TableId
+		j.TableId = &id
+	case *OfpInstructionWriteMetadata:
+		metadata, mask := jsonHex(i.Metadata), jsonHex(i.MetadataMask)
+		j.Metadata, j.MetadataMask = &metadata, &mask
+	case *OfpInstructionActions:
+		actions := jsonActions(i.Actions)
+		j.Actions = &actions
+	case *OfpInstructionMeter:
+		id := jsonMeter(i.MeterId)
+		j.MeterId = &id
+	case *OfpInstructionExperimenter:
+		experimenter := i.Experimenter
+		j.Experimenter = &experimenter
+	default:
+		return nil, fmt.Errorf("instruction %T is not supported.", i)
+	}
+	return j, nil
+}
+
+func (j *instructionJSON) instruction() (OfpInstruction, error) {
+	t, ok := ofpMapValue(j.Type, ofpInstructionNames, 16)
+	if !ok {
+		return nil, fmt.Errorf("instruction type %s is invalid.", j.Type)
+	}
+	missing := func(key string) error {
+		return fmt.Errorf("%s of %s is missing.", key, j.Type)
+	}
+
+	switch t {
+	case OFPIT_GOTO_TABLE:
+		if j.TableId == nil {
+			return nil, missing("table_id")
+		}
+		return NewOfpInstructionGotoTable(*j.TableId), nil
+	case OFPIT_WRITE_METADATA:
+		if j.Metadata == nil {
+			return nil, missing("metadata")
+		}
+		mask := uint64(0xffffffffffffffff)
+		if j.MetadataMask != nil {
+			mask = uint64(*j.MetadataMask)
+		}
+		return NewOfpInstructionWriteMetadata(uint64(*j.Metadata), mask), nil
+	case OFPIT_WRITE_ACTIONS, OFPIT_APPLY_ACTIONS, OFPIT_CLEAR_ACTIONS:
+		instruction := NewOfpInstructionActions(uint16(t))
+		if j.Actions != nil {
+			for _, a := range *j.Actions {
+				instruction.Append(a)
+			}
+		}
+		return instruction, nil
+	case OFPIT_METER:
+		if j.MeterId == nil {
+			return nil, missing("meter_id")
+		}
+		return NewOfpInstructionMeter(uint32(*j.MeterId)), nil
+	case OFPIT_EXPERIMENTER:
+		if j.Experimenter == nil {
+			return nil, missing("experimenter")
+		}
+		return NewOfpInstructionExperimenter(*j.Experimenter), nil
+	}
+	return nil, fmt.Errorf("instruction type %s is not supported.", j.Type)
+}
+
+// decode instruction of any type
+func decodeInstructionJSON(data []byte) (OfpInstruction, error) {
+	var j instructionJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return j.instruction()
+}
+
+func marshalInstructionJSON(i OfpInstruction) ([]byte, error) {
+	j, err := newInstructionJSON(i)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalInstructionJSON(data []byte, dst OfpInstruction) error {
+	i, err := decodeInstructionJSON(data)
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, i)
+}
+
+func (i *OfpInstructionGotoTable) MarshalJSON() ([]byte, error)     { return marshalInstructionJSON(i) }
+func (i *OfpInstructionWriteMetadata) MarshalJSON() ([]byte, error) { return marshalInstructionJSON(i) }
+func (i *OfpInstructionActions) MarshalJSON() ([]byte, error)       { return marshalInstructionJSON(i) }
+func (i *OfpInstructionMeter) MarshalJSON() ([]byte, error)         { return marshalInstructionJSON(i) }
+func (i *OfpInstructionExperimenter) MarshalJSON() ([]byte, error)  { return marshalInstructionJSON(i) }
+
+func (i *OfpInstructionGotoTable) UnmarshalJSON(data []byte) error {
+	return unmarshalInstructionJSON(data, i)
+}
+
+func (i *OfpInstructionWriteMetadata) UnmarshalJSON(data []byte) error {
+	return unmarshalInstructionJSON(data, i)
+}
+
+func (i *OfpInstructionActions) UnmarshalJSON(data []byte) error {
+	return unmarshalInstructionJSON(data, i)
+}
+
+func (i *OfpInstructionMeter) UnmarshalJSON(data []byte) error {
+	return unmarshalInstructionJSON(data, i)
+}
+
+func (i *OfpInstructionExperimenter) UnmarshalJSON(data []byte) error {
+	return unmarshalInstructionJSON(data, i)
+}
+
+/// list of instructions, encoded as an array which is empty for nil
+type jsonInstructions []OfpInstruction
+
+func (l jsonInstructions) MarshalJSON() ([]byte, error) {
+	list := make([]*instructionJSON, len(l))
+	for i, instruction := range l {
+		j, err := newInstructionJSON(instruction)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = j
+	}
+	return json.Marshal(list)
+}
+
+func (l *jsonInstructions) UnmarshalJSON(data []byte) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	instructions := make(jsonInstructions, len(list))
+	for i, raw := range list {
+		instruction, err := decodeInstructionJSON(raw)
+		if err != nil {
+			return err
+		}
+		instructions[i] = instruction
+	}
+	*l = instructions
+	return nil
+}
+
+/*****************************************************/
+/* Flow, Group and Meter Modification                */
+/*****************************************************/
+
+type flowModJSON struct {
+	ofpHeaderJSON
+	Command      string           `json:"command"`
+	TableId      jsonTable        `json:"table_id"`
+	Priority     uint16           `json:"priority"`
+	Cookie       jsonHex          `json:"cookie"`
+	CookieMask   jsonHex          `json:"cookie_mask"`
+	IdleTimeout  uint16           `json:"idle_timeout"`
+	HardTimeout  uint16           `json:"hard_timeout"`
+	BufferId     jsonBuffer       `json:"buffer_id"`
+	OutPort      jsonPort         `json:"out_port"`
+	OutGroup     jsonGroup        `json:"out_group"`
+	Flags        []string         `json:"flags"`
+	Match        *OfpMatch        `json:"match"`
+	Instructions jsonInstructions `json:"instructions"`
+}
+
+func (m *OfpFlowMod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flowModJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpFlowModCommandNames, uint64(m.Command)), jsonTable(m.TableId), m.Priority,
+		jsonHex(m.Cookie), jsonHex(m.CookieMask), m.IdleTimeout, m.HardTimeout,
+		jsonBuffer(m.BufferId), jsonPort(m.OutPort), jsonGroup(m.OutGroup),
+		ofpFlagsList(uint64(m.Flags), ofpFlowModFlags), jsonMatch(m.Match),
+		jsonInstructions(m.Instructions)})
+}
+
+func (m *OfpFlowMod) UnmarshalJSON(data []byte) error {
+	j := flowModJSON{Command: "OFPFC_ADD", BufferId: OFP_NO_BUFFER, OutPort: OFPP_ANY, OutGroup: OFPG_ANY}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Command = uint8(c.name("command", j.Command, ofpFlowModCommandNames, 8))
+	m.TableId = uint8(j.TableId)
+	m.Priority = j.Priority
+	m.Cookie = uint64(j.Cookie)
+	m.CookieMask = uint64(j.CookieMask)
+	m.IdleTimeout = j.IdleTimeout
+	m.HardTimeout = j.HardTimeout
+	m.BufferId = uint32(j.BufferId)
+	m.OutPort = uint32(j.OutPort)
+	m.OutGroup = uint32(j.OutGroup)
+	m.Flags = uint16(c.flags("flags", j.Flags, ofpFlowModFlags, 16))
+	m.Match = decodedMatch(j.Match)
+	m.Instructions = []OfpInstruction(j.Instructions)
+	if m.Instructions == nil {
+		m.Instructions = make([]OfpInstruction, 0)
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type bucketJSON struct {
+	Weight     uint16      `json:"weight"`
+	WatchPort  jsonPort    `json:"watch_port"`
+	WatchGroup jsonGroup   `json:"watch_group"`
+	Actions    jsonActions `json:"actions"`
+}
+
+func (b *OfpBucket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bucketJSON{b.Weight, jsonPort(b.WatchPort), jsonGroup(b.WatchGroup),
+		jsonActions(b.Actions)})
+}
+
+func (b *OfpBucket) UnmarshalJSON(data []byte) error {
+	j := bucketJSON{WatchPort: OFPP_ANY, WatchGroup: OFPG_ANY}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	bucket := NewOfpBucket(j.Weight, uint32(j.WatchPort), uint32(j.WatchGroup))
+	for _, a := range j.Actions {
+		bucket.Append(a)
+	}
+	bucket.Length = uint16(bucket.Size())
+	*b = *bucket
+	return nil
+}
+
+// buckets to be encoded, which is empty for nil
+func jsonBuckets(buckets []*OfpBucket) []*OfpBucket {
+	if buckets == nil {
+		return []*OfpBucket{}
+	}
+	return buckets
+}
+
+type groupModJSON struct {
+	ofpHeaderJSON
+	Command   string       `json:"command"`
+	GroupType string       `json:"group_type"`
+	GroupId   jsonGroup    `json:"group_id"`
+	Buckets   []*OfpBucket `json:"buckets"`
+}
+
+func (m *OfpGroupMod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(groupModJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpGroupModCommandNames, uint64(m.Command)),
+		ofpName(ofpGroupTypeNames, uint64(m.Type)), jsonGroup(m.GroupId), jsonBuckets(m.Buckets)})
+}
+
+func (m *OfpGroupMod) UnmarshalJSON(data []byte) error {
+	j := groupModJSON{Command: "OFPGC_ADD", GroupType: "OFPGT_ALL"}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Command = uint16(c.name("command", j.Command, ofpGroupModCommandNames, 16))
+	m.Type = uint8(c.name("group_type", j.GroupType, ofpGroupTypeNames, 8))
+	m.GroupId = uint32(j.GroupId)
+	m.Buckets = make([]*OfpBucket, 0)
+	for _, b := range j.Buckets {
+		if b != nil {
+			m.Buckets = append(m.Buckets, b)
+		}
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/// meter band in JSON. prec_level and experimenter are given for the
+/// types of band which have them.
+type meterBandJSON struct {
+	Type         string  `json:"type"`
+	Rate         uint32  `json:"rate"`
+	BurstSize    uint32  `json:"burst_size"`
+	PrecLevel    *uint8  `json:"prec_level,omitempty"`
+	Experimenter *uint32 `json:"experimenter,omitempty"`
+}
+
+func newMeterBandJSON(b OfpMeterBand) (*meterBandJSON, error) {
+	j := &meterBandJSON{Type: ofpMapName(ofpMeterBandNames, uint64(b.MeterBandType()))}
+	switch b := b.(type) {
+	case *OfpMeterBandDrop:
+		j.Rate, j.BurstSize = b.Header.Rate, b.Header.BurstSize
+	case *OfpMeterBandDscpRemark:
+		j.Rate, j.BurstSize = b.Header.Rate, b.Header.BurstSize
+		level := b.PrecLevel
+		j.PrecLevel = &level
+	case *OfpMeterBandExperimenter:
+		j.Rate, j.BurstSize = b.Header.Rate, b.Header.BurstSize
+		experimenter := b.Experimenter
+		j.Experimenter = &experimenter
+	default:
+		return nil, fmt.Errorf("meter band %T is not supported.", b)
+	}
+	return j, nil
+}
+
+func (j *meterBandJSON) meterBand() (OfpMeterBand, error) {
+	t, ok := ofpMapValue(j.Type, ofpMeterBandNames, 16)
+	if !ok {
+		return nil, fmt.Errorf("meter band type %s is invalid.", j.Type)
+	}
+	switch t {
+	case OFPMBT_DROP:
+		return NewOfpMeterBandDrop(j.Rate, j.BurstSize), nil
+	case OFPMBT_DSCP_REMARK:
+		if j.PrecLevel == nil {
+			return nil, fmt.Errorf("prec_level of %s is missing.", j.Type)
+		}
+		return NewOfpMeterBandDscpRemark(j.Rate, j.BurstSize, *j.PrecLevel), nil
+	case OFPMBT_EXPERIMENTER:
+		if j.Experimenter == nil {
+			return nil, fmt.Errorf("experimenter of %s is missing.", j.Type)
+		}
+		return NewOfpMeterBandExperimenter(j.Rate, j.BurstSize, *j.Experimenter), nil
+	}
+	return nil, fmt.Errorf("meter band type %s is not supported.", j.Type)
+}
+
+func marshalMeterBandJSON(b OfpMeterBand) ([]byte, error) {
+	j, err := newMeterBandJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalMeterBandJSON(data []byte, dst OfpMeterBand) error {
+	var j meterBandJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	b, err := j.meterBand()
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, b)
+}
+
+func (b *OfpMeterBandDrop) MarshalJSON() ([]byte, error)         { return marshalMeterBandJSON(b) }
+func (b *OfpMeterBandDscpRemark) MarshalJSON() ([]byte, error)   { return marshalMeterBandJSON(b) }
+func (b *OfpMeterBandExperimenter) MarshalJSON() ([]byte, error) { return marshalMeterBandJSON(b) }
+
+func (b *OfpMeterBandDrop) UnmarshalJSON(data []byte) error {
+	return unmarshalMeterBandJSON(data, b)
+}
+
+func (b *OfpMeterBandDscpRemark) UnmarshalJSON(data []byte) error {
+	return unmarshalMeterBandJSON(data, b)
+}
+
+func (b *OfpMeterBandExperimenter) UnmarshalJSON(data []byte) error {
+	return unmarshalMeterBandJSON(data, b)
+}
+
+/// list of meter bands, encoded as an array which is empty for nil
+type jsonMeterBands []OfpMeterBand
+
+func (l jsonMeterBands) MarshalJSON() ([]byte, error) {
+	list := make([]*meterBandJSON, len(l))
+	for i, b := range l {
+		j, err := newMeterBandJSON(b)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = j
+	}
+	return json.Marshal(list)
+}
+
+func (l *jsonMeterBands) UnmarshalJSON(data []byte) error {
+	var list []meterBandJSON
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	bands := make(jsonMeterBands, len(list))
+	for i := range list {
+		b, err := list[i].meterBand()
+		if err != nil {
+			return err
+		}
+		bands[i] = b
+	}
+	*l = bands
+	return nil
+}
+
+type meterModJSON struct {
+	ofpHeaderJSON
+	Command string         `json:"command"`
+	Flags   []string       `json:"flags"`
+	MeterId jsonMeter      `json:"meter_id"`
+	Bands   jsonMeterBands `json:"bands"`
+}
+
+func (m *OfpMeterMod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(meterModJSON{newOfpHeaderJSON(&m.Header),
+		ofpName(ofpMeterModCommandNames, uint64(m.Command)),
+		ofpFlagsList(uint64(m.Flags), ofpMeterFlags), jsonMeter(m.MeterId), jsonMeterBands(m.Bands)})
+}
+
+func (m *OfpMeterMod) UnmarshalJSON(data []byte) error {
+	j := meterModJSON{Command: "OFPMC_ADD"}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Command = uint16(c.name("command", j.Command, ofpMeterModCommandNames, 16))
+	m.Flags = uint16(c.flags("flags", j.Flags, ofpMeterFlags, 16))
+	m.MeterId = uint32(j.MeterId)
+	m.Bands = []OfpMeterBand(j.Bands)
+	if m.Bands == nil {
+		m.Bands = make([]OfpMeterBand, 0)
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/*****************************************************/
+/* Packet and Flow Removed                           */
+/*****************************************************/
+
+type packetInJSON struct {
+	ofpHeaderJSON
+	BufferId jsonBuffer `json:"buffer_id"`
+	TotalLen uint16     `json:"total_len"`
+	Reason   string     `json:"reason"`
+	TableId  uint8      `json:"table_id"`
+	Cookie   jsonHex    `json:"cookie"`
+	Match    *OfpMatch  `json:"match"`
+	Data     []byte     `json:"data"`
+}
+
+/// match and data of message parsed by ParseLazy are decoded on encoding.
+func (m *OfpPacketIn) MarshalJSON() ([]byte, error) {
+	return json.Marshal(packetInJSON{newOfpHeaderJSON(&m.Header), jsonBuffer(m.BufferId),
+		m.TotalLen, ofpName(ofpPacketInReasonNames, uint64(m.Reason)), m.TableId,
+		jsonHex(m.Cookie), jsonMatch(m.match()), m.GetData()})
+}
+
+func (m *OfpPacketIn) UnmarshalJSON(data []byte) error {
+	j := packetInJSON{BufferId: OFP_NO_BUFFER}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	msg := NewOfpPacketIn()
+	msg.Header = j.header(c)
+	msg.BufferId = uint32(j.BufferId)
+	msg.TotalLen = j.TotalLen
+	msg.Reason = uint8(c.name("reason", j.Reason, ofpPacketInReasonNames, 8))
+	msg.TableId = j.TableId
+	msg.Cookie = uint64(j.Cookie)
+	msg.Match = decodedMatch(j.Match)
+	msg.Data = j.Data
+	msg.Header.Length = uint16(msg.Size())
+	*m = *msg
+	return c.err
+}
+
+type packetOutJSON struct {
+	ofpHeaderJSON
+	BufferId jsonBuffer  `json:"buffer_id"`
+	InPort   jsonPort    `json:"in_port"`
+	Actions  jsonActions `json:"actions"`
+	Data     []byte      `json:"data"`
+}
+
+func (m *OfpPacketOut) MarshalJSON() ([]byte, error) {
+	return json.Marshal(packetOutJSON{newOfpHeaderJSON(&m.Header), jsonBuffer(m.BufferId),
+		jsonPort(m.InPort), jsonActions(m.Actions), m.Data})
+}
+
+func (m *OfpPacketOut) UnmarshalJSON(data []byte) error {
+	j := packetOutJSON{BufferId: OFP_NO_BUFFER, InPort: OFPP_CONTROLLER}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	msg := NewOfpPacketOut(uint32(j.BufferId), uint32(j.InPort), []OfpAction(j.Actions), j.Data)
+	msg.Header = j.header(c)
+	msg.Header.Length = uint16(msg.Size())
+	*m = *msg
+	return c.err
+}
+
+type flowRemovedJSON struct {
+	ofpHeaderJSON
+	Cookie       jsonHex   `json:"cookie"`
+	Priority     uint16    `json:"priority"`
+	Reason       string    `json:"reason"`
+	TableId      uint8     `json:"table_id"`
+	DurationSec  uint32    `json:"duration_sec"`
+	DurationNSec uint32    `json:"duration_nsec"`
+	IdleTimeout  uint16    `json:"idle_timeout"`
+	HardTimeout  uint16    `json:"hard_timeout"`
+	PacketCount  uint64    `json:"packet_count"`
+	ByteCount    uint64    `json:"byte_count"`
+	Match        *OfpMatch `json:"match"`
+}
+
+func (m *OfpFlowRemoved) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flowRemovedJSON{newOfpHeaderJSON(&m.Header), jsonHex(m.Cookie), m.Priority,
+		ofpName(ofpFlowRemovedReasonNames, uint64(m.Reason)), m.TableId,
+		m.DurationSec, m.DurationNSec, m.IdleTimeout, m.HardTimeout,
+		m.PacketCount, m.ByteCount, jsonMatch(m.Match)})
+}
+
+func (m *OfpFlowRemoved) UnmarshalJSON(data []byte) error {
+	var j flowRemovedJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Cookie = uint64(j.Cookie)
+	m.Priority = j.Priority
+	m.Reason = uint8(c.name("reason", j.Reason, ofpFlowRemovedReasonNames, 8))
+	m.TableId = j.TableId
+	m.DurationSec = j.DurationSec
+	m.DurationNSec = j.DurationNSec
+	m.IdleTimeout = j.IdleTimeout
+	m.HardTimeout = j.HardTimeout
+	m.PacketCount = j.PacketCount
+	m.ByteCount = j.ByteCount
+	m.Match = decodedMatch(j.Match)
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/*****************************************************/
+/* Queue Configuration                               */
+/*****************************************************/
+
+/// queue property in JSON. rate is given for min and max rate, and
+/// experimenter and data for experimenter.
+type queuePropJSON struct {
+	Type         string  `json:"type"`
+	Rate         *uint16 `json:"rate,omitempty"`
+	Experimenter *uint32 `json:"experimenter,omitempty"`
+	Data         []byte  `json:"data,omitempty"`
+}
+
+func newQueuePropJSON(p OfpQueueProp) (*queuePropJSON, error) {
+	j := &queuePropJSON{Type: ofpMapName(ofpQueuePropNames, uint64(p.Property()))}
+	switch p := p.(type) {
+	case *OfpQueuePropMinRate:
+		rate := p.Rate
+		j.Rate = &rate
+	case *OfpQueuePropMaxRate:
+		rate := p.Rate
+		j.Rate = &rate
+	case *OfpQueuePropExperimenter:
+		experimenter := p.Experimenter
+		j.Experimenter = &experimenter
+		j.Data = p.Data
+	default:
+		return nil, fmt.Errorf("queue property %T is not supported.", p)
+	}
+	return j, nil
+}
+
+func (j *queuePropJSON) queueProp() (OfpQueueProp, error) {
+	t, ok := ofpMapValue(j.Type, ofpQueuePropNames, 16)
+	if !ok {
+		return nil, fmt.Errorf("queue property %s is invalid.", j.Type)
+	}
+	switch t {
+	case OFPQT_MIN_RATE, OFPQT_MAX_RATE:
+		if j.Rate == nil {
+			return nil, fmt.Errorf("rate of %s is missing.", j.Type)
+		}
+		if t == OFPQT_MIN_RATE {
+			p := newOfpQueuePropMinRate()
+			p.Rate = *j.Rate
+			return p, nil
+		}
+		p := newOfpQueuePropMaxRate()
+		p.Rate = *j.Rate
+		return p, nil
+	case OFPQT_EXPERIMENTER:
+		if j.Experimenter == nil {
+			return nil, fmt.Errorf("experimenter of %s is missing.", j.Type)
+		}
+		p := newOfpQueuePropExperimenter()
+		p.Experimenter = *j.Experimenter
+		p.Data = j.Data
+		p.PropHeader.Length = uint16(p.Size())
+		return p, nil
+	}
+	return nil, fmt.Errorf("queue property %s is not supported.", j.Type)
+}
+
+func marshalQueuePropJSON(p OfpQueueProp) ([]byte, error) {
+	j, err := newQueuePropJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalQueuePropJSON(data []byte, dst OfpQueueProp) error {
+	var j queuePropJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	p, err := j.queueProp()
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, p)
+}
+
+func (p *OfpQueuePropMinRate) MarshalJSON() ([]byte, error)      { return marshalQueuePropJSON(p) }
+func (p *OfpQueuePropMaxRate) MarshalJSON() ([]byte, error)      { return marshalQueuePropJSON(p) }
+func (p *OfpQueuePropExperimenter) MarshalJSON() ([]byte, error) { return marshalQueuePropJSON(p) }
+
+func (p *OfpQueuePropMinRate) UnmarshalJSON(data []byte) error {
+	return unmarshalQueuePropJSON(data, p)
+}
+
+func (p *OfpQueuePropMaxRate) UnmarshalJSON(data []byte) error {
+	return unmarshalQueuePropJSON(data, p)
+}
+
+func (p *OfpQueuePropExperimenter) UnmarshalJSON(data []byte) error {
+	return unmarshalQueuePropJSON(data, p)
+}
+
+type packetQueueJSON struct {
+	QueueId    uint32           `json:"queue_id"`
+	Port       jsonPort         `json:"port"`
+	Properties []*queuePropJSON `json:"properties"`
+}
+
+func (q *OfpPacketQueue) MarshalJSON() ([]byte, error) {
+	j := packetQueueJSON{q.QueueId, jsonPort(q.Port), make([]*queuePropJSON, len(q.Properties))}
+	for i, p := range q.Properties {
+		prop, err := newQueuePropJSON(p)
+		if err != nil {
+			return nil, err
+		}
+		j.Properties[i] = prop
+	}
+	return json.Marshal(j)
+}
+
+func (q *OfpPacketQueue) UnmarshalJSON(data []byte) error {
+	var j packetQueueJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	queue := newOfpPacketQueue()
+	queue.QueueId = j.QueueId
+	queue.Port = uint32(j.Port)
+	queue.Properties = make([]OfpQueueProp, 0, len(j.Properties))
+	for _, prop := range j.Properties {
+		if prop == nil {
+			continue
+		}
+		p, err := prop.queueProp()
+		if err != nil {
+			return err
+		}
+		queue.Properties = append(queue.Properties, p)
+	}
+	queue.Length = uint16(queue.Size())
+	*q = *queue
+	return nil
+}
+
+type queueGetConfigRequestJSON struct {
+	ofpHeaderJSON
+	Port jsonPort `json:"port"`
+}
+
+func (m *OfpQueueGetConfigRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(queueGetConfigRequestJSON{newOfpHeaderJSON(&m.Header), jsonPort(m.Port)})
+}
+
+func (m *OfpQueueGetConfigRequest) UnmarshalJSON(data []byte) error {
+	j := queueGetConfigRequestJSON{Port: OFPP_ANY}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Port = uint32(j.Port)
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+type queueGetConfigReplyJSON struct {
+	ofpHeaderJSON
+	Port   jsonPort          `json:"port"`
+	Queues []*OfpPacketQueue `json:"queues"`
+}
+
+func (m *OfpQueueGetConfigReply) MarshalJSON() ([]byte, error) {
+	queues := m.Queue
+	if queues == nil {
+		queues = []*OfpPacketQueue{}
+	}
+	return json.Marshal(queueGetConfigReplyJSON{newOfpHeaderJSON(&m.Header), jsonPort(m.Port), queues})
+}
+
+func (m *OfpQueueGetConfigReply) UnmarshalJSON(data []byte) error {
+	var j queueGetConfigReplyJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	m.Header = j.header(c)
+	m.Port = uint32(j.Port)
+	m.Queue = make([]*OfpPacketQueue, 0, len(j.Queues))
+	for _, q := range j.Queues {
+		if q != nil {
+			m.Queue = append(m.Queue, q)
+		}
+	}
+	m.Header.Length = uint16(m.Size())
+	return c.err
+}
+
+/*****************************************************/
+/* Multipart Messages                                */
+/*****************************************************/
+
+/// body of multipart request, or nil for types without body
+func newMultipartRequestBody(t uint16) OfpMultipartBody {
+	switch t {
+	case OFPMP_FLOW:
+		return new(OfpFlowStatsRequest)
+	case OFPMP_AGGREGATE:
+		return new(OfpAggregateStatsRequest)
+	case OFPMP_PORT_STATS:
+		return new(OfpPortStatsRequest)
+	case OFPMP_QUEUE:
+		return new(OfpQueueStatsRequest)
+	case OFPMP_GROUP:
+		return new(OfpGroupStatsRequest)
+	case OFPMP_METER, OFPMP_METER_CONFIG:
+		return new(OfpMeterMultipartRequest)
+	case OFPMP_TABLE_FEATURES:
+		return new(OfpTableFeatures)
+	}
+	return nil
+}
+
+/// an element of body of multipart reply, or nil for unknown type
+func newMultipartReplyBody(t uint16) OfpMultipartBody {
+	switch t {
+	case OFPMP_DESC:
+		return new(OfpDescStats)
+	case OFPMP_FLOW:
+		return new(OfpFlowStats)
+	case OFPMP_AGGREGATE:
+		return new(OfpAggregateStats)
+	case OFPMP_TABLE:
+		return new(OfpTableStats)
+	case OFPMP_PORT_STATS:
+		return new(OfpPortStats)
+	case OFPMP_QUEUE:
+		return new(OfpQueueStats)
+	case OFPMP_GROUP:
+		return new(OfpGroupStats)
+	case OFPMP_GROUP_DESC:
+		return new(OfpGroupDescStats)
+	case OFPMP_GROUP_FEATURES:
+		return new(OfpGroupFeaturesStats)
+	case OFPMP_METER:
+		return new(OfpMeterStats)
+	case OFPMP_METER_CONFIG:
+		return new(OfpMeterConfig)
+	case OFPMP_METER_FEATURES:
+		return new(OfpMeterFeatures)
+	case OFPMP_TABLE_FEATURES:
+		return new(OfpTableFeatures)
+	case OFPMP_PORT_DESC:
+		return new(OfpPort)
+	}
+	return nil
+}
+
+/// multipart request in JSON. body is omitted for types without body.
+type multipartRequestJSON struct {
+	ofpHeaderJSON
+	MultipartType string          `json:"multipart_type"`
+	Flags         []string        `json:"flags"`
+	Body          json.RawMessage `json:"body,omitempty"`
+}
+
+func (m *OfpMultipartRequest) MarshalJSON() ([]byte, error) {
+	j := multipartRequestJSON{ofpHeaderJSON: newOfpHeaderJSON(&m.Header),
+		MultipartType: ofpMapName(ofpMultipartNames, uint64(m.Type)),
+		Flags:         ofpFlagsList(uint64(m.Flags), ofpMultipartRequestFlags)}
+	if m.Body != nil {
+		body, err := json.Marshal(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		j.Body = body
+	}
+	return json.Marshal(j)
+}
+
+func (m *OfpMultipartRequest) UnmarshalJSON(data []byte) error {
+	var j multipartRequestJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	msg := NewOfpMultipartRequest(0, 0)
+	msg.Header = j.header(c)
+	msg.Type = uint16(c.mapName("multipart_type", j.MultipartType, ofpMultipartNames, 16))
+	msg.Flags = uint16(c.flags("flags", j.Flags, ofpMultipartRequestFlags, 16))
+	if c.err != nil {
+		return c.err
+	}
+	if len(j.Body) > 0 && string(j.Body) != "null" {
+		body := newMultipartRequestBody(msg.Type)
+		if body == nil {
+			return fmt.Errorf("body of %s is not supported.", j.MultipartType)
+		}
+		if err := json.Unmarshal(j.Body, body); err != nil {
+			return err
+		}
+		msg.Body = body
+	}
+	msg.Header.Length = uint16(msg.Size())
+	*m = *msg
+	return nil
+}
+
+/// multipart reply in JSON. body is an array of replies of the type.
+type multipartReplyJSON struct {
+	ofpHeaderJSON
+	MultipartType string            `json:"multipart_type"`
+	Flags         []string          `json:"flags"`
+	Body          []json.RawMessage `json:"body"`
+}
+
+func (m *OfpMultipartReply) MarshalJSON() ([]byte, error) {
+	j := multipartReplyJSON{newOfpHeaderJSON(&m.Header),
+		ofpMapName(ofpMultipartNames, uint64(m.Type)),
+		ofpFlagsList(uint64(m.Flags), ofpMultipartReplyFlags), make([]json.RawMessage, len(m.Body))}
+	for i, body := range m.Body {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		j.Body[i] = b
+	}
+	return json.Marshal(j)
+}
+
+func (m *OfpMultipartReply) UnmarshalJSON(data []byte) error {
+	var j multipartReplyJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	msg := NewOfpMultipartReply()
+	msg.Header = j.header(c)
+	msg.Type = uint16(c.mapName("multipart_type", j.MultipartType, ofpMultipartNames, 16))
+	msg.Flags = uint16(c.flags("flags", j.Flags, ofpMultipartReplyFlags, 16))
+	if c.err != nil {
+		return c.err
+	}
+	msg.Body = make([]OfpMultipartBody, 0, len(j.Body))
+	for _, raw := range j.Body {
+		body := newMultipartReplyBody(msg.Type)
+		if body == nil {
+			return fmt.Errorf("body of %s is not supported.", j.MultipartType)
+		}
+		if err := json.Unmarshal(raw, body); err != nil {
+			return err
+		}
+		msg.Body = append(msg.Body, body)
+	}
+	msg.Header.Length = uint16(msg.Size())
+	*m = *msg
+	return nil
+}
+
+/*****************************************************/
+/* Multipart Bodies                                  */
+/*****************************************************/
+
+type descStatsJSON struct {
+	MfrDesc   string `json:"mfr_desc"`
+	HwDesc    string `json:"hw_desc"`
+	SwDesc    string `json:"sw_desc"`
+	SerialNum string `json:"serial_num"`
+	DpDesc    string `json:"dp_desc"`
+}
+
+func (mp *OfpDescStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(descStatsJSON{ofpCString(mp.MfrDesc), ofpCString(mp.HwDesc),
+		ofpCString(mp.SwDesc), ofpCString(mp.SerialNum), ofpCString(mp.DpDesc)})
+}
+
+func (mp *OfpDescStats) UnmarshalJSON(data []byte) error {
+	var j descStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	for _, s := range []string{j.MfrDesc, j.HwDesc, j.SwDesc, j.DpDesc} {
+		if len(s) >= DESC_STR_LEN {
+			return fmt.Errorf("description %s is too long.", s)
+		}
+	}
+	if len(j.SerialNum) >= SERIAL_NUM_LEN {
+		return fmt.Errorf("serial_num %s is too long.", j.SerialNum)
+	}
+	*mp = *NewOfpDescStats(j.MfrDesc, j.HwDesc, j.SwDesc, j.SerialNum, j.DpDesc)
+	return nil
+}
+
+/// body of flow and aggregate stats request in JSON
+type flowStatsRequestJSON struct {
+	TableId    jsonTable `json:"table_id"`
+	OutPort    jsonPort  `json:"out_port"`
+	OutGroup   jsonGroup `json:"out_group"`
+	Cookie     jsonHex   `json:"cookie"`
+	CookieMask jsonHex   `json:"cookie_mask"`
+	Match      *OfpMatch `json:"match"`
+}
+
+func newFlowStatsRequestJSON(tableId uint8, outPort uint32, outGroup uint32,
+	cookie uint64, cookieMask uint64, match *OfpMatch) flowStatsRequestJSON {
+	return flowStatsRequestJSON{jsonTable(tableId), jsonPort(outPort), jsonGroup(outGroup),
+		jsonHex(cookie), jsonHex(cookieMask), jsonMatch(match)}
+}
+
+func unmarshalFlowStatsRequestJSON(data []byte) (flowStatsRequestJSON, error) {
+	j := flowStatsRequestJSON{TableId: OFPTT_ALL, OutPort: OFPP_ANY, OutGroup: OFPG_ANY}
+	err := json.Unmarshal(data, &j)
+	j.Match = decodedMatch(j.Match)
+	return j, err
+}
+
+func (mp *OfpFlowStatsRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newFlowStatsRequestJSON(mp.TableId, mp.OutPort, mp.OutGroup,
+		mp.Cookie, mp.CookieMask, mp.Match))
+}
+
+func (mp *OfpFlowStatsRequest) UnmarshalJSON(data []byte) error {
+	j, err := unmarshalFlowStatsRequestJSON(data)
+	if err != nil {
+		return err
+	}
+	*mp = OfpFlowStatsRequest{uint8(j.TableId), uint32(j.OutPort), uint32(j.OutGroup),
+		uint64(j.Cookie), uint64(j.CookieMask), j.Match}
+	return nil
+}
+
+func (mp *OfpAggregateStatsRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newFlowStatsRequestJSON(mp.TableId, mp.OutPort, mp.OutGroup,
+		mp.Cookie, mp.CookieMask, mp.Match))
+}
+
+func (mp *OfpAggregateStatsRequest) UnmarshalJSON(data []byte) error {
+	j, err := unmarshalFlowStatsRequestJSON(data)
+	if err != nil {
+		return err
+	}
+	*mp = OfpAggregateStatsRequest{uint8(j.TableId), uint32(j.OutPort), uint32(j.OutGroup),
+		uint64(j.Cookie), uint64(j.CookieMask), j.Match}
+	return nil
+}
+
+type flowStatsJSON struct {
+	TableId      uint8            `json:"table_id"`
+	DurationSec  uint32           `json:"duration_sec"`
+	DurationNSec uint32           `json:"duration_nsec"`
+	Priority     uint16           `json:"priority"`
+	IdleTimeout  uint16           `json:"idle_timeout"`
+	HardTimeout  uint16           `json:"hard_timeout"`
+	Flags        []string         `json:"flags"`
+	Cookie       jsonHex          `json:"cookie"`
+	PacketCount  uint64           `json:"packet_count"`
+	ByteCount    uint64           `json:"byte_count"`
+	Match        *OfpMatch        `json:"match"`
+	Instructions jsonInstructions `json:"instructions"`
+}
+
+func (mp *OfpFlowStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flowStatsJSON{mp.TableId, mp.DurationSec, mp.DurationNSec, mp.Priority,
+		mp.IdleTimeout, mp.HardTimeout, ofpFlagsList(uint64(mp.Flags), ofpFlowModFlags),
+		jsonHex(mp.Cookie), mp.PacketCount, mp.ByteCount, jsonMatch(mp.Match),
+		jsonInstructions(mp.Instructions)})
+}
+
+func (mp *OfpFlowStats) UnmarshalJSON(data []byte) error {
+	var j flowStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	stats := newOfpFlowStats()
+	stats.TableId = j.TableId
+	stats.DurationSec = j.DurationSec
+	stats.DurationNSec = j.DurationNSec
+	stats.Priority = j.Priority
+	stats.IdleTimeout = j.IdleTimeout
+	stats.HardTimeout = j.HardTimeout
+	stats.Flags = uint16(c.flags("flags", j.Flags, ofpFlowModFlags, 16))
+	stats.Cookie = uint64(j.Cookie)
+	stats.PacketCount = j.PacketCount
+	stats.ByteCount = j.ByteCount
+	stats.Match = decodedMatch(j.Match)
+	stats.Instructions = []OfpInstruction(j.Instructions)
+	if stats.Instructions == nil {
+		stats.Instructions = make([]OfpInstruction, 0)
+	}
+	stats.Length = uint16(stats.Size())
+	*mp = *stats
+	return c.err
+}
+
+/// fields of OfpAggregateStats with names in JSON
+type aggregateStatsJSON struct {
+	PacketCount uint64 `json:"packet_count"`
+	ByteCount   uint64 `json:"byte_count"`
+	FlowCount   uint32 `json:"flow_count"`
+}
+
+func (mp *OfpAggregateStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(aggregateStatsJSON(*mp))
+}
+
+func (mp *OfpAggregateStats) UnmarshalJSON(data []byte) error {
+	var j aggregateStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpAggregateStats(j)
+	return nil
+}
+
+/// table feature property in JSON. ids are given for the type of property:
+///  - instruction_ids and action_ids: names of types
+///  - next_table_ids: table ids
+///  - oxm_ids: names of fields such as "eth_dst" or "eth_dst/mask"
+type tableFeaturePropJSON struct {
+	Type             string   `json:"type"`
+	InstructionIds   []string `json:"instruction_ids,omitempty"`
+	NextTableIds     []int    `json:"next_table_ids,omitempty"`
+	ActionIds        []string `json:"action_ids,omitempty"`
+	OxmIds           []string `json:"oxm_ids,omitempty"`
+	Experimenter     *uint32  `json:"experimenter,omitempty"`
+	ExpType          *uint32  `json:"exp_type,omitempty"`
+	ExperimenterData []uint32 `json:"experimenter_data,omitempty"`
+}
+
+/// name of OXM id, or header in hex if its length is not of the spec
+func oxmIdJSON(header uint32) string {
+	class, field := oxmClass(header), oxmField(header)
+	if class == OFPXMC_OPENFLOW_BASIC && int(field) < len(oxmFieldSpecs) {
+		length := uint32(oxmFieldSpecs[field].size)
+		if oxmHasMask(header) == 1 {
+			length *= 2
+		}
+		if header&0xff == length {
+			return oxmIdString(header)
+		}
+	}
+	return fmt.Sprintf("0x%08x", header)
+}
+
+func parseOxmIdJSON(s string) (uint32, error) {
+	if header, ok := jsonUint(s, 32); ok {
+		return uint32(header), nil
+	}
+	name, masked := strings.TrimSuffix(s, "/mask"), strings.HasSuffix(s, "/mask")
+	field, ok := ofctlFieldAliases[name]
+	if !ok {
+		return 0, fmt.Errorf("oxm_id %s is invalid.", s)
+	}
+	size := uint32(oxmFieldSpecs[field].size)
+	if masked {
+		return oxmHeaderW(OFPXMC_OPENFLOW_BASIC, field, size), nil
+	}
+	return oxmHeader(OFPXMC_OPENFLOW_BASIC, field, size), nil
+}
+
+func newTableFeaturePropJSON(p OfpTableFeatureProp) (*tableFeaturePropJSON, error) {
+	j := &tableFeaturePropJSON{Type: ofpMapName(ofpTableFeaturePropNames, uint64(p.Property()))}
+	switch p := p.(type) {
+	case *OfpTableFeaturePropInstructions:
+		j.InstructionIds = make([]string, len(p.InstructionIds))
+		for i, id := range p.InstructionIds {
+			j.InstructionIds[i] = ofpMapName(ofpInstructionNames, uint64(id.Type))
+		}
+	case *OfpTableFeaturePropNextTables:
+		j.NextTableIds = make([]int, len(p.NextTableIds))
+		for i, id := range p.NextTableIds {
+			j.NextTableIds[i] = int(id)
+		}
+	case *OfpTableFeaturePropActions:
+		j.ActionIds = make([]string, len(p.ActionIds))
+		for i, id := range p.ActionIds {
+			j.ActionIds[i] = ofpMapName(ofpActionNames, uint64(id.Type))
+		}
+	case *OfpTableFeaturePropOxm:
+		j.OxmIds = make([]string, len(p.OxmIds))
+		for i, id := range p.OxmIds {
+			j.OxmIds[i] = oxmIdJSON(id)
+		}
+	case *OfpTableFeaturePropExperimenter:
+		experimenter, expType := p.Experimenter, p.ExpType
+		j.Experimenter, j.ExpType = &experimenter, &expType
+		j.ExperimenterData = p.ExperimenterData
+	default:
+		return nil, fmt.Errorf("table feature property %T is not supported.", p)
+	}
+	return j, nil
+}
+
+func (j *tableFeaturePropJSON) tableFeatureProp() (OfpTableFeatureProp, error) {
+	t, ok := ofpMapValue(j.Type, ofpTableFeaturePropNames, 16)
+	if !ok {
+		return nil, fmt.Errorf("table feature property %s is invalid.", j.Type)
+	}
+	c := &jsonNames{}
+	switch t {
+	case OFPTFPT_INSTRUCTIONS, OFPTFPT_INSTRUCTIONS_MISS:
+		ids := make([]*OfpInstructionId, len(j.InstructionIds))
+		for i, name := range j.InstructionIds {
+			ids[i] = NewOfpInstructionId(uint16(c.mapName("instruction_ids", name, ofpInstructionNames, 16)), 4)
+		}
+		return NewOfpTableFeaturePropInstructions(uint16(t), ids), c.err
+	case OFPTFPT_NEXT_TABLES, OFPTFPT_NEXT_TABLES_MISS:
+		ids := make([]uint8, len(j.NextTableIds))
+		for i, id := range j.NextTableIds {
+			if id < 0 || id > 0xff {
+				return nil, fmt.Errorf("next_table_ids %d is invalid.", id)
+			}
+			ids[i] = uint8(id)
+		}
+		return NewOfpTableFeaturePropNextTables(uint16(t), ids), nil
+	case OFPTFPT_WRITE_ACTIONS, OFPTFPT_WRITE_ACTIONS_MISS,
+		OFPTFPT_APPLY_ACTIONS, OFPTFPT_APPLY_ACTIONS_MISS:
+		ids := make([]OfpActionHeader, len(j.ActionIds))
+		for i, name := range j.ActionIds {
+			ids[i] = NewOfpActionHeader(uint16(c.mapName("action_ids", name, ofpActionNames, 16)), 8)
+		}
+		return NewOfpTableFeaturePropActions(uint16(t), ids), c.err
+	case OFPTFPT_MATCH, OFPTFPT_WILDCARDS, OFPTFPT_WRITE_SETFIELD, OFPTFPT_WRITE_SETFIELD_MISS,
+		OFPTFPT_APPLY_SETFIELD, OFPTFPT_APPLY_SETFIELD_MISS:
+		ids := make([]uint32, len(j.OxmIds))
+		for i, s := range j.OxmIds {
+			id, err := parseOxmIdJSON(s)
+			if err != nil {
+				return nil, err
+			}
+			ids[i] = id
+		}
+		return NewOfpTableFeaturePropOxm(uint16(t), ids), nil
+	case OFPTFPT_EXPERIMENTER, OFPTFPT_EXPERIMENTER_MISS:
+		if j.Experimenter == nil || j.ExpType == nil {
+			return nil, fmt.Errorf("experimenter and exp_type of %s are required.", j.Type)
+		}
+		return NewOfpTableFeaturePropExperimenter(uint16(t), *j.Experimenter, *j.ExpType,
+			j.ExperimenterData), nil
+	}
+	return nil, fmt.Errorf("table feature property %s is not supported.", j.Type)
+}
+
+func marshalTableFeaturePropJSON(p OfpTableFeatureProp) ([]byte, error) {
+	j, err := newTableFeaturePropJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func unmarshalTableFeaturePropJSON(data []byte, dst OfpTableFeatureProp) error {
+	var j tableFeaturePropJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	p, err := j.tableFeatureProp()
+	if err != nil {
+		return err
+	}
+	return setDecodedJSON(dst, p)
+}
+
+func (p *OfpTableFeaturePropInstructions) MarshalJSON() ([]byte, error) {
+	return marshalTableFeaturePropJSON(p)
+}
+
+func (p *OfpTableFeaturePropNextTables) MarshalJSON() ([]byte, error) {
+	return marshalTableFeaturePropJSON(p)
+}
+
+func (p *OfpTableFeaturePropActions) MarshalJSON() ([]byte, error) {
+	return marshalTableFeaturePropJSON(p)
+}
+
+func (p *OfpTableFeaturePropOxm) MarshalJSON() ([]byte, error) {
+	return marshalTableFeaturePropJSON(p)
+}
+
+func (p *OfpTableFeaturePropExperimenter) MarshalJSON() ([]byte, error) {
+	return marshalTableFeaturePropJSON(p)
+}
+
+func (p *OfpTableFeaturePropInstructions) UnmarshalJSON(data []byte) error {
+	return unmarshalTableFeaturePropJSON(data, p)
+}
+
+func (p *OfpTableFeaturePropNextTables) UnmarshalJSON(data []byte) error {
+	return unmarshalTableFeaturePropJSON(data, p)
+}
+
+func (p *OfpTableFeaturePropActions) UnmarshalJSON(data []byte) error {
+	return unmarshalTableFeaturePropJSON(data, p)
+}
+
+func (p *OfpTableFeaturePropOxm) UnmarshalJSON(data []byte) error {
+	return unmarshalTableFeaturePropJSON(data, p)
+}
+
+func (p *OfpTableFeaturePropExperimenter) UnmarshalJSON(data []byte) error {
+	return unmarshalTableFeaturePropJSON(data, p)
+}
+
+type tableFeaturesJSON struct {
+	TableId       uint8                   `json:"table_id"`
+	Name          string                  `json:"name"`
+	MetadataMatch jsonHex                 `json:"metadata_match"`
+	MetadataWrite jsonHex                 `json:"metadata_write"`
+	Config        uint32                  `json:"config"`
+	MaxEntries    uint32                  `json:"max_entries"`
+	Properties    []*tableFeaturePropJSON `json:"properties"`
+}
+
+func (mp *OfpTableFeatures) MarshalJSON() ([]byte, error) {
+	j := tableFeaturesJSON{mp.TableId, ofpCString(mp.Name), jsonHex(mp.MetadataMatch),
+		jsonHex(mp.MetadataWrite), mp.Config, mp.MaxEntries,
+		make([]*tableFeaturePropJSON, len(mp.Properties))}
+	for i, p := range mp.Properties {
+		prop, err := newTableFeaturePropJSON(p)
+		if err != nil {
+			return nil, err
+		}
+		j.Properties[i] = prop
+	}
+	return json.Marshal(j)
+}
+
+func (mp *OfpTableFeatures) UnmarshalJSON(data []byte) error {
+	var j tableFeaturesJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if len(j.Name) >= OFP_MAX_TABLE_NAME_LEN {
+		return fmt.Errorf("name %s is too long.", j.Name)
+	}
+	props := make([]OfpTableFeatureProp, 0, len(j.Properties))
+	for _, prop := range j.Properties {
+		if prop == nil {
+			continue
+		}
+		p, err := prop.tableFeatureProp()
+		if err != nil {
+			return err
+		}
+		props = append(props, p)
+	}
+	name := make([]byte, OFP_MAX_TABLE_NAME_LEN)
+	copy(name, j.Name)
+	*mp = *NewOfpTableFeatures(j.TableId, name, uint64(j.MetadataMatch), uint64(j.MetadataWrite),
+		j.Config, j.MaxEntries, props)
+	return nil
+}
+
+/// fields of OfpTableStats with names in JSON
+type tableStatsJSON struct {
+	TableId      uint8    `json:"table_id"`
+	Pad          [3]uint8 `json:"-"`
+	ActiveCount  uint32   `json:"active_count"`
+	LookupCount  uint64   `json:"lookup_count"`
+	MatchedCount uint64   `json:"matched_count"`
+}
+
+func (mp *OfpTableStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tableStatsJSON(*mp))
+}
+
+func (mp *OfpTableStats) UnmarshalJSON(data []byte) error {
+	var j tableStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpTableStats(j)
+	mp.Pad = [3]uint8{}
+	return nil
+}
+
+type portStatsRequestJSON struct {
+	PortNo jsonPort `json:"port_no"`
+}
+
+func (mp *OfpPortStatsRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(portStatsRequestJSON{jsonPort(mp.PortNo)})
+}
+
+func (mp *OfpPortStatsRequest) UnmarshalJSON(data []byte) error {
+	j := portStatsRequestJSON{OFPP_ANY}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	mp.PortNo = uint32(j.PortNo)
+	return nil
+}
+
+type portStatsJSON struct {
+	PortNo       jsonPort `json:"port_no"`
+	RxPackets    uint64   `json:"rx_packets"`
+	TxPackets    uint64   `json:"tx_packets"`
+	RxBytes      uint64   `json:"rx_bytes"`
+	TxBytes      uint64   `json:"tx_bytes"`
+	RxDropped    uint64   `json:"rx_dropped"`
+	TxDropped    uint64   `json:"tx_dropped"`
+	RxErrors     uint64   `json:"rx_errors"`
+	TxErrors     uint64   `json:"tx_errors"`
+	RxFrameErr   uint64   `json:"rx_frame_err"`
+	RxOverErr    uint64   `json:"rx_over_err"`
+	RxCrcErr     uint64   `json:"rx_crc_err"`
+	Collisions   uint64   `json:"collisions"`
+	DurationSec  uint32   `json:"duration_sec"`
+	DurationNSec uint32   `json:"duration_nsec"`
+}
+
+func (mp *OfpPortStats) MarshalJSON() ([]byte, error) {
+	j := portStatsJSON{jsonPort(mp.PortNo), mp.RxPackets, mp.TxPackets, mp.RxBytes, mp.TxBytes,
+		mp.RxDropped, mp.TxDropped, mp.RxErrors, mp.TxErrors, mp.RxFrameErr, mp.RxOverErr,
+		mp.RxCrcErr, mp.Collisions, mp.DurationSec, mp.DurationNSec}
+	return json.Marshal(j)
+}
+
+func (mp *OfpPortStats) UnmarshalJSON(data []byte) error {
+	var j portStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpPortStats{uint32(j.PortNo), j.RxPackets, j.TxPackets, j.RxBytes, j.TxBytes,
+		j.RxDropped, j.TxDropped, j.RxErrors, j.TxErrors, j.RxFrameErr, j.RxOverErr,
+		j.RxCrcErr, j.Collisions, j.DurationSec, j.DurationNSec}
+	return nil
+}
+
+type queueStatsRequestJSON struct {
+	PortNo  jsonPort  `json:"port_no"`
+	QueueId jsonQueue `json:"queue_id"`
+}
+
+func (mp *OfpQueueStatsRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(queueStatsRequestJSON{jsonPort(mp.PortNo), jsonQueue(mp.QueueId)})
+}
+
+func (mp *OfpQueueStatsRequest) UnmarshalJSON(data []byte) error {
+	j := queueStatsRequestJSON{OFPP_ANY, OFPQ_ALL}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpQueueStatsRequest{uint32(j.PortNo), uint32(j.QueueId)}
+	return nil
+}
+
+type queueStatsJSON struct {
+	PortNo       jsonPort `json:"port_no"`
+	QueueId      uint32   `json:"queue_id"`
+	TxBytes      uint64   `json:"tx_bytes"`
+	TxPackets    uint64   `json:"tx_packets"`
+	TxErrors     uint64   `json:"tx_errors"`
+	DurationSec  uint32   `json:"duration_sec"`
+	DurationNSec uint32   `json:"duration_nsec"`
+}
+
+func (mp *OfpQueueStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(queueStatsJSON{jsonPort(mp.PortNo), mp.QueueId, mp.TxBytes, mp.TxPackets,
+		mp.TxErrors, mp.DurationSec, mp.DurationNSec})
+}
+
+func (mp *OfpQueueStats) UnmarshalJSON(data []byte) error {
+	var j queueStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpQueueStats{uint32(j.PortNo), j.QueueId, j.TxBytes, j.TxPackets,
+		j.TxErrors, j.DurationSec, j.DurationNSec}
+	return nil
+}
+
+type groupStatsRequestJSON struct {
+	GroupId jsonGroup `json:"group_id"`
+}
+
+func (mp *OfpGroupStatsRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(groupStatsRequestJSON{jsonGroup(mp.GroupId)})
+}
+
+func (mp *OfpGroupStatsRequest) UnmarshalJSON(data []byte) error {
+	j := groupStatsRequestJSON{OFPG_ALL}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*mp = OfpGroupStatsRequest{GroupId: uint32(j.GroupId)}
+	return nil
+}
+
+/// fields of OfpBucketCounter with names in JSON
+type bucketCounterJSON struct {
+	PacketCount uint64 `json:"packet_count"`
+	ByteCount   uint64 `json:"byte_count"`
+}
+
+func (c *OfpBucketCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bucketCounterJSON(*c))
+}
+
+func (c *OfpBucketCounter) UnmarshalJSON(data []byte) error {
+	var j bucketCounterJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*c = OfpBucketCounter(j)
+	return nil
+}
+
+type groupStatsJSON struct {
+	GroupId      uint32              `json:"group_id"`
+	RefCount     uint32              `json:"ref_count"`
+	PacketCount  uint64              `json:"packet_count"`
+	ByteCount    uint64              `json:"byte_count"`
+	DurationSec  uint32              `json:"duration_sec"`
+	DurationNSec uint32              `json:"duration_nsec"`
+	BucketStats  []*OfpBucketCounter `json:"bucket_stats"`
+}
+
+func (mp *OfpGroupStats) MarshalJSON() ([]byte, error) {
+	counters := mp.BucketStats
+	if counters == nil {
+		counters = []*OfpBucketCounter{}
+	}
+	return json.Marshal(groupStatsJSON{mp.GroupId, mp.RefCount, mp.PacketCount, mp.ByteCount,
+		mp.DurationSec, mp.DurationNSec, counters})
+}
+
+func (mp *OfpGroupStats) UnmarshalJSON(data []byte) error {
+	var j groupStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	stats := newOfpGroupStats()
+	stats.GroupId = j.GroupId
+	stats.RefCount = j.RefCount
+	stats.PacketCount = j.PacketCount
+	stats.ByteCount = j.ByteCount
+	stats.DurationSec = j.DurationSec
+	stats.DurationNSec = j.DurationNSec
+	stats.BucketStats = make([]*OfpBucketCounter, 0, len(j.BucketStats))
+	for _, counter := range j.BucketStats {
+		if counter != nil {
+			stats.BucketStats = append(stats.BucketStats, counter)
+		}
+	}
+	stats.Length = uint16(stats.Size())
+	*mp = *stats
+	return nil
+}
+
+type groupDescStatsJSON struct {
+	GroupType string       `json:"group_type"`
+	GroupId   uint32       `json:"group_id"`
+	Buckets   []*OfpBucket `json:"buckets"`
+}
+
+func (mp *OfpGroupDescStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(groupDescStatsJSON{ofpName(ofpGroupTypeNames, uint64(mp.Type)), mp.GroupId,
+		jsonBuckets(mp.Buckets)})
+}
+
+func (mp *OfpGroupDescStats) UnmarshalJSON(data []byte) error {
+	j := groupDescStatsJSON{GroupType: "OFPGT_ALL"}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	stats := &OfpGroupDescStats{}
+	stats.Type = uint8(c.name("group_type", j.GroupType, ofpGroupTypeNames, 8))
+	stats.GroupId = j.GroupId
+	stats.Buckets = make([]*OfpBucket, 0, len(j.Buckets))
+	for _, b := range j.Buckets {
+		if b != nil {
+			stats.Buckets = append(stats.Buckets, b)
+		}
+	}
+	stats.Length = uint16(stats.Size())
+	*mp = *stats
+	return c.err
+}
+
+/// action types by bit index, used in group features
+func ofpActionBits() []string {
+	names := make([]string, 32)
+	for t, name := range ofpActionNames {
+		if t < 32 {
+			names[t] = name
+		}
+	}
+	return names
+}
+
+/// group features in JSON. max_groups and actions are objects keyed by
+/// group type, such as {"OFPGT_ALL": 16}.
+type groupFeaturesJSON struct {
+	Types        []string            `json:"types"`
+	Capabilities []string            `json:"capabilities"`
+	MaxGroups    map[string]uint32   `json:"max_groups"`
+	Actions      map[string][]string `json:"actions"`
+}
+
+func (mp *OfpGroupFeaturesStats) MarshalJSON() ([]byte, error) {
+	j := groupFeaturesJSON{ofpFlagsList(uint64(mp.Type), ofpBitFlags(ofpGroupTypeNames)),
+		ofpFlagsList(uint64(mp.Capabilities), ofpGroupCapabilitiesFlags),
+		map[string]uint32{}, map[string][]string{}}
+	actions := ofpBitFlags(ofpActionBits())
+	for i, name := range ofpGroupTypeNames {
+		j.MaxGroups[name] = mp.MaxGroups[i]
+		j.Actions[name] = ofpFlagsList(uint64(mp.Actions[i]), actions)
+	}
+	return json.Marshal(j)
+}
+
+func (mp *OfpGroupFeaturesStats) UnmarshalJSON(data []byte) error {
+	var j groupFeaturesJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	stats := OfpGroupFeaturesStats{}
+	stats.Type = uint32(c.flags("types", j.Types, ofpBitFlags(ofpGroupTypeNames), 32))
+	stats.Capabilities = uint32(c.flags("capabilities", j.Capabilities, ofpGroupCapabilitiesFlags, 32))
+	groupType := func(name string) int {
+		t := c.name("group type", name, ofpGroupTypeNames, 8)
+		if t >= uint64(len(stats.MaxGroups)) {
+			c.fail("group type", name)
+			return 0
+		}
+		return int(t)
+	}
+	for name, max := range j.MaxGroups {
+		stats.MaxGroups[groupType(name)] = max
+	}
+	actions := ofpBitFlags(ofpActionBits())
+	for name, list := range j.Actions {
+		stats.Actions[groupType(name)] = uint32(c.flags("actions", list, actions, 32))
+	}
+	*mp = stats
+	return c.err
+}
+
+type meterMultipartRequestJSON struct {
+	MeterId jsonMeter `json:"meter_id"`
+}
+
+func (mp *OfpMeterMultipartRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(meterMultipartRequestJSON{jsonMeter(mp.MeterId)})
+}
+
+func (mp *OfpMeterMultipartRequest) UnmarshalJSON(data []byte) error {
+	j := meterMultipartRequestJSON{OFPM_ALL}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	mp.MeterId = uint32(j.MeterId)
+	return nil
+}
+
+/// fields of OfpMeterBandStats with names in JSON
+type meterBandStatsJSON struct {
+	PacketBandCount uint64 `json:"packet_band_count"`
+	ByteBandCount   uint64 `json:"byte_band_count"`
+}
+
+func (s *OfpMeterBandStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(meterBandStatsJSON(*s))
+}
+
+func (s *OfpMeterBandStats) UnmarshalJSON(data []byte) error {
+	var j meterBandStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*s = OfpMeterBandStats(j)
+	return nil
+}
+
+type meterStatsJSON struct {
+	MeterId       uint32               `json:"meter_id"`
+	FlowCount     uint32               `json:"flow_count"`
+	PacketInCount uint64               `json:"packet_in_count"`
+	ByteInCount   uint64               `json:"byte_in_count"`
+	DurationSec   uint32               `json:"duration_sec"`
+	DurationNSec  uint32               `json:"duration_nsec"`
+	BandStats     []*OfpMeterBandStats `json:"band_stats"`
+}
+
+func (mp *OfpMeterStats) MarshalJSON() ([]byte, error) {
+	bands := mp.BandStats
+	if bands == nil {
+		bands = []*OfpMeterBandStats{}
+	}
+	return json.Marshal(meterStatsJSON{mp.MeterId, mp.FlowCount, mp.PacketInCount, mp.ByteInCount,
+		mp.DurationSec, mp.DurationNSec, bands})
+}
+
+func (mp *OfpMeterStats) UnmarshalJSON(data []byte) error {
+	var j meterStatsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	stats := newOfpMeterStats()
+	stats.MeterId = j.MeterId
+	stats.FlowCount = j.FlowCount
+	stats.PacketInCount = j.PacketInCount
+	stats.ByteInCount = j.ByteInCount
+	stats.DurationSec = j.DurationSec
+	stats.DurationNSec = j.DurationNSec
+	stats.BandStats = make([]*OfpMeterBandStats, 0, len(j.BandStats))
+	for _, band := range j.BandStats {
+		if band != nil {
+			stats.BandStats = append(stats.BandStats, band)
+		}
+	}
+	stats.Length = uint16(stats.Size())
+	*mp = *stats
+	return nil
+}
+
+type meterConfigJSON struct {
+	Flags   []string       `json:"flags"`
+	MeterId uint32         `json:"meter_id"`
+	Bands   jsonMeterBands `json:"bands"`
+}
+
+func (mp *OfpMeterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(meterConfigJSON{ofpFlagsList(uint64(mp.Flags), ofpMeterFlags), mp.MeterId,
+		jsonMeterBands(mp.Bands)})
+}
+
+func (mp *OfpMeterConfig) UnmarshalJSON(data []byte) error {
+	var j meterConfigJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	config := newOfpMeterConfig()
+	config.Flags = uint16(c.flags("flags", j.Flags, ofpMeterFlags, 16))
+	config.MeterId = j.MeterId
+	config.Bands = []OfpMeterBand(j.Bands)
+	if config.Bands == nil {
+		config.Bands = make([]OfpMeterBand, 0)
+	}
+	config.Length = uint16(config.Size())
+	*mp = *config
+	return c.err
+}
+
+type meterFeaturesJSON struct {
+	MaxMeter     uint32   `json:"max_meter"`
+	BandTypes    []string `json:"band_types"`
+	Capabilities []string `json:"capabilities"`
+	MaxBands     uint8    `json:"max_bands"`
+	MaxColor     uint8    `json:"max_color"`
+}
+
+func (mp *OfpMeterFeatures) MarshalJSON() ([]byte, error) {
+	return json.Marshal(meterFeaturesJSON{mp.MaxMeter,
+		ofpFlagsList(uint64(mp.BandTypes), ofpBitFlags(ofpMeterBandBits)),
+		ofpFlagsList(uint64(mp.Capabilities), ofpMeterFlags), mp.MaxBands, mp.MaxColor})
+}
+
+func (mp *OfpMeterFeatures) UnmarshalJSON(data []byte) error {
+	var j meterFeaturesJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	c := &jsonNames{}
+	*mp = OfpMeterFeatures{}
+	mp.MaxMeter = j.MaxMeter
+	mp.BandTypes = uint32(c.flags("band_types", j.BandTypes, ofpBitFlags(ofpMeterBandBits), 32))
+	mp.Capabilities = uint32(c.flags("capabilities", j.Capabilities, ofpMeterFlags, 32))
+	mp.MaxBands = j.MaxBands
+	mp.MaxColor = j.MaxColor
+	return c.err
+}
@@ -0,0 +1,245 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// interfaces JSONMessage must satisfy
+var _ json.Marshaler = &JSONMessage{}
+var _ json.Unmarshaler = &JSONMessage{}
+
+// encode msg, decode it by ParseJSON and compare serialized messages.
+func expectJSONRoundTrip(t *testing.T, msg OFMessage) []byte {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Errorf("Failed to marshal %T: %s", msg, err)
+		return nil
+	}
+	decoded, err := ParseJSON(data)
+	if err != nil {
+		t.Log("JSON is : ", string(data))
+		t.Errorf("Failed to parse %T: %s", msg, err)
+		return data
+	}
+	expect, actual := msg.Serialize(), decoded.Serialize()
+	if !bytes.Equal(expect, actual) {
+		t.Log("JSON is : ", string(data))
+		t.Log("Expected Value is : ", hex.EncodeToString(expect))
+		t.Log("Actual Value is   : ", hex.EncodeToString(actual))
+		t.Errorf("%T is changed by JSON round trip.", msg)
+	}
+	return data
+}
+
+func expectJSONContains(t *testing.T, data []byte, expected ...string) {
+	for _, s := range expected {
+		if !strings.Contains(string(data), s) {
+			t.Log("Expected Value is : ", s)
+			t.Log("Actual Value is   : ", string(data))
+			t.Error("JSON doesn't contain expected value.")
+		}
+	}
+}
+
+/*****************************************************/
+/* Messages                                          */
+/*****************************************************/
+func TestJSONFlowMod(t *testing.T) {
+	msg := newTestFlowMod()
+	msg.Cookie = 0x1234
+	msg.Flags = OFPFF_SEND_FLOW_REM
+	msg.Instructions = append(msg.Instructions,
+		NewOfpInstructionWriteMetadata(0x10, 0xff), NewOfpInstructionGotoTable(2))
+	output := NewOfpInstructionActions(OFPIT_WRITE_ACTIONS)
+	output.Append(NewOfpActionOutput(OFPP_CONTROLLER, OFPCML_NO_BUFFER))
+	output.Append(NewOfpActionPush(OFPAT_PUSH_VLAN, 0x8100))
+	output.Append(NewOfpActionGroup(3))
+	msg.Instructions = append(msg.Instructions, output)
+
+	data := expectJSONRoundTrip(t, msg)
+	expectJSONContains(t, data,
+		`"type":"OFPT_FLOW_MOD"`,
+		`"command":"OFPFC_ADD"`,
+		`"cookie":"0x1234"`,
+		`"flags":["OFPFF_SEND_FLOW_REM"]`,
+		`{"field":"ipv4_dst","value":"192.168.0.0","mask":"255.255.0.0"}`,
+		`{"type":"OFPAT_OUTPUT","port":"OFPP_CONTROLLER","max_len":"OFPCML_NO_BUFFER"}`,
+		`{"type":"OFPAT_SET_FIELD","field":"vlan_vid","value":"0x0064"}`,
+		`{"type":"OFPAT_PUSH_VLAN","ethertype":"0x8100"}`)
+}
+
+func TestJSONDecodeFlowMod(t *testing.T) {
+	data := `{"type": "OFPT_FLOW_MOD", "xid": 10, "priority": 100,
+		"match": [{"field": "in_port", "value": 1}, {"field": "tcp_dst", "value": 80}],
+		"instructions": [{"type": "OFPIT_APPLY_ACTIONS",
+			"actions": [{"type": "OFPAT_OUTPUT", "port": "OFPP_FLOOD"}]}]}`
+	msg, err := ParseJSON([]byte(data))
+	if err != nil {
+		t.Fatal("Failed to parse flow mod: ", err)
+	}
+	fm, ok := msg.(*OfpFlowMod)
+	if !ok {
+		t.Fatalf("%T is parsed for flow mod.", msg)
+	}
+	if fm.Header.Xid != 10 || fm.Priority != 100 || fm.Command != OFPFC_ADD ||
+		fm.BufferId != OFP_NO_BUFFER || fm.OutPort != OFPP_ANY || fm.OutGroup != OFPG_ANY {
+		t.Error("Failed to decode fields of flow mod.")
+	}
+	if fm.Header.Length != uint16(fm.Size()) {
+		t.Log("Expected Value is : ", fm.Size())
+		t.Log("Actual Value is   : ", fm.Header.Length)
+		t.Error("Length of decoded flow mod is wrong.")
+	}
+	if len(fm.Match.OxmFields) != 2 || len(fm.Instructions) != 1 {
+		t.Error("Failed to decode match and instructions of flow mod.")
+	}
+	output := fm.Instructions[0].(*OfpInstructionActions).Actions[0].(*OfpActionOutput)
+	if output.Port != OFPP_FLOOD || output.MaxLen != OFPCML_NO_BUFFER {
+		t.Error("Failed to decode output action.")
+	}
+}
+
+func TestJSONPacketIn(t *testing.T) {
+	msg := NewOfpPacketIn()
+	msg.BufferId = OFP_NO_BUFFER
+	msg.TotalLen = 4
+	msg.Reason = OFPR_ACTION
+	msg.Cookie = 1
+	msg.Match.Append(NewOxmInPort(3))
+	msg.Data = []byte{0xde, 0xad, 0xbe, 0xef}
+	data := expectJSONRoundTrip(t, msg)
+	expectJSONContains(t, data, `"reason":"OFPR_ACTION"`, `"data":"3q2+7w=="`,
+		`"buffer_id":"OFP_NO_BUFFER"`)
+
+	// lazily parsed message
+	lazy := ParseLazy(msg.Serialize())
+	if lazy == nil {
+		t.Fatal("Failed to parse packet in.")
+	}
+	actual, err := json.Marshal(lazy)
+	if err != nil || !bytes.Equal(data, actual) {
+		t.Log("Expected Value is : ", string(data))
+		t.Log("Actual Value is   : ", string(actual))
+		t.Error("Lazily parsed packet in is encoded differently.")
+	}
+}
+
+func TestJSONGroupAndMeterMod(t *testing.T) {
+	gm := NewOfpGroupMod(OFPGC_ADD, OFPGT_SELECT, 1)
+	bucket := NewOfpBucket(10, OFPP_ANY, OFPG_ANY)
+	bucket.Append(NewOfpActionOutput(2, 0))
+	gm.Append(bucket)
+	data := expectJSONRoundTrip(t, gm)
+	expectJSONContains(t, data, `"group_type":"OFPGT_SELECT"`, `"watch_group":"OFPG_ANY"`)
+
+	mm := NewOfpMeterMod(OFPMC_ADD, OFPMF_KBPS, 1)
+	mm.AppendMeterBand(NewOfpMeterBandDrop(1000, 100))
+	mm.AppendMeterBand(NewOfpMeterBandDscpRemark(2000, 200, 1))
+	data = expectJSONRoundTrip(t, mm)
+	expectJSONContains(t, data, `"flags":["OFPMF_KBPS"]`,
+		`{"type":"OFPMBT_DSCP_REMARK","rate":2000,"burst_size":200,"prec_level":1}`)
+}
+
+func TestJSONMessages(t *testing.T) {
+	features := NewOfpFeaturesReply()
+	features.DatapathId = 0xabcd
+	features.Capabilities = OFPC_FLOW_STATS | OFPC_PORT_STATS
+	port, _ := NewOfpPort(1, "00:11:22:33:44:55", "eth1")
+	status := NewOfpPortStatus()
+	status.Desc = port
+	errMsg := NewOfpErrorMsg()
+	errMsg.Type = OFPET_BAD_REQUEST
+	errMsg.Code = OFPBRC_BAD_TYPE
+	async := newOfpAsyncConfig(OFPT_SET_ASYNC)
+	async.PacketInMask[0] = 1<<OFPR_NO_MATCH | 1<<OFPR_ACTION
+
+	messages := []OFMessage{
+		NewOfpHello(), NewOfpEchoRequest(), NewOfpBarrierRequest(), features, status, errMsg,
+		newOfpSwitchConfig(OFPT_SET_CONFIG, OFPC_FLAG_DROP, OFPCML_NO_BUFFER),
+		NewOfpTableMod(1, 0), NewOfpRoleRequest(OFPCT_ROLE_MASTER, 1), async,
+		newTestPacketOut(), NewOfpQueueGetConfigRequest(OFPP_ANY),
+	}
+	for _, msg := range messages {
+		expectJSONRoundTrip(t, msg)
+	}
+}
+
+/*****************************************************/
+/* Multipart Messages                                */
+/*****************************************************/
+func TestJSONMultipart(t *testing.T) {
+	request := NewOfpFlowStatsRequest(0, OFPTT_ALL, OFPP_ANY, OFPG_ANY, 0, 0, matchOf(NewOxmInPort(1)))
+	data := expectJSONRoundTrip(t, request)
+	expectJSONContains(t, data, `"multipart_type":"OFPMP_FLOW"`, `"table_id":"OFPTT_ALL"`)
+	expectJSONRoundTrip(t, NewOfpPortDescStatsRequest(0))
+
+	reply := NewOfpMultipartReply()
+	reply.Type = OFPMP_FLOW
+	stats := newOfpFlowStats()
+	stats.Match = matchOf(NewOxmEthType(0x0806))
+	instruction := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+	instruction.Append(NewOfpActionOutput(OFPP_NORMAL, 0))
+	stats.Instructions = []OfpInstruction{instruction}
+	stats.Length = uint16(stats.Size())
+	reply.Append(stats)
+	data = expectJSONRoundTrip(t, reply)
+	expectJSONContains(t, data, `{"field":"eth_type","value":"0x0806"}`)
+
+	reply = NewOfpMultipartReply()
+	reply.Type = OFPMP_TABLE_FEATURES
+	props := []OfpTableFeatureProp{
+		NewOfpTableFeaturePropInstructions(OFPTFPT_INSTRUCTIONS,
+			[]*OfpInstructionId{NewOfpInstructionId(OFPIT_GOTO_TABLE, 4)}),
+		NewOfpTableFeaturePropNextTables(OFPTFPT_NEXT_TABLES, []uint8{1, 2}),
+		NewOfpTableFeaturePropOxm(OFPTFPT_MATCH, []uint32{OXM_OF_ETH_DST, OXM_OF_ETH_DST_W}),
+	}
+	name := make([]byte, OFP_MAX_TABLE_NAME_LEN)
+	copy(name, "table0")
+	reply.Append(NewOfpTableFeatures(0, name, 0, 0, 0, 1000, props))
+	data = expectJSONRoundTrip(t, reply)
+	expectJSONContains(t, data, `"next_table_ids":[1,2]`, `"oxm_ids":["eth_dst","eth_dst/mask"]`)
+}
+
+/*****************************************************/
+/* JSONMessage                                       */
+/*****************************************************/
+func TestJSONMessage(t *testing.T) {
+	echo := NewOfpEchoRequest()
+	data, err := json.Marshal(NewJSONMessage(echo))
+	if err != nil {
+		t.Fatal("Failed to marshal message: ", err)
+	}
+	decoded := &JSONMessage{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal("Failed to unmarshal message: ", err)
+	}
+	if !bytes.Equal(echo.Serialize(), decoded.Message.Serialize()) {
+		t.Error("Failed to decode message by JSONMessage.")
+	}
+	if _, err := json.Marshal(&JSONMessage{}); err == nil {
+		t.Error("nil message must not be marshaled.")
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	invalids := []string{
+		`{"type": "OFPT_UNKNOWN"}`,
+		`{"type": "OFPT_FLOW_MOD", "command": "OFPFC_UNKNOWN"}`,
+		`{"type": "OFPT_FLOW_MOD", "match": [{"field": "unknown", "value": 1}]}`,
+		`{"type": "OFPT_FLOW_MOD", "match": [{"field": "ip_proto", "value": 6, "mask": 255}]}`,
+		`{"type": "OFPT_FLOW_MOD", "instructions": [{"type": "OFPIT_APPLY_ACTIONS",
+			"actions": [{"type": "OFPAT_OUTPUT"}]}]}`,
+		`{"type": "OFPT_PACKET_OUT", "in_port": "OFPP_UNKNOWN"}`,
+		`{"type": "OFPT_MULTIPART_REQUEST", "multipart_type": "OFPMP_DESC", "body": {}}`,
+	}
+	for _, data := range invalids {
+		if _, err := ParseJSON([]byte(data)); err == nil {
+			t.Log("JSON is : ", data)
+			t.Error("Invalid JSON must not be parsed.")
+		}
+	}
+}
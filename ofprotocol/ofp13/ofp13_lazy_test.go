@@ -0,0 +1,155 @@
+package ofp13
+
+import (
+	"bytes"
+	"testing"
+)
+
+// PacketIn with in_port and eth_type match, followed by 128 bytes frame
+func newTestPacketInBinary() []byte {
+	packet := []byte{
+		0x04, 0x0a, 0x00, 0xb2, // Version, Type, Length
+		0x00, 0x00, 0x00, 0x01, // Transaction ID
+		0xff, 0xff, 0xff, 0xff, // BufferId
+		0x00, 0x80, // TotalLen
+		0x01,                                           // Reason
+		0x02,                                           // TableId
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, // Cookie
+		0x00, 0x01, 0x00, 0x12, // Match Type, Length
+		0x80, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x03, // in_port = 3
+		0x80, 0x00, 0x0a, 0x02, 0x08, 0x00, // eth_type = 0x0800
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // match padding
+		0x00, 0x00, // padding
+	}
+	for i := 0; i < 128; i++ {
+		packet = append(packet, byte(i))
+	}
+	return packet
+}
+
+/*****************************************************/
+/* Lazy PacketIn                                     */
+/*****************************************************/
+func TestParseLazyPacketIn(t *testing.T) {
+	packet := newTestPacketInBinary()
+	eager := Parse(packet).(*OfpPacketIn)
+	lazy := ParseLazy(packet).(*OfpPacketIn)
+
+	if lazy.BufferId != eager.BufferId || lazy.TotalLen != eager.TotalLen ||
+		lazy.Reason != eager.Reason || lazy.TableId != eager.TableId ||
+		lazy.Cookie != eager.Cookie || lazy.Header != eager.Header {
+		t.Log("Expected Value is : ", eager)
+		t.Log("Actual Value is   : ", lazy)
+		t.Error("Fixed fields of lazy PacketIn are invalid.")
+	}
+	if lazy.Match != nil || lazy.Data != nil {
+		t.Error("Match or Data is decoded eagerly.")
+	}
+
+	data := lazy.GetData()
+	if !bytes.Equal(data, eager.Data) || len(data) != 128 {
+		t.Log("Expected Value is : ", eager.Data)
+		t.Log("Actual Value is   : ", data)
+		t.Error("Data of lazy PacketIn is invalid.")
+	}
+	if &data[0] != &packet[len(packet)-128] {
+		t.Error("Data of lazy PacketIn is copied.")
+	}
+
+	match := lazy.GetMatch()
+	if len(match.OxmFields) != 2 || match.OxmFields[0].(*OxmInPort).Value != 3 ||
+		match.OxmFields[1].(*OxmEthType).Value != 0x0800 {
+		t.Log("Actual Value is : ", match.OxmFields)
+		t.Error("Match of lazy PacketIn is invalid.")
+	}
+	if lazy.Size() != eager.Size() {
+		t.Errorf("Size of lazy PacketIn is %d, expected %d.", lazy.Size(), eager.Size())
+	}
+}
+
+func TestRetainLazyPacketIn(t *testing.T) {
+	packet := newTestPacketInBinary()
+	lazy := ParseLazy(packet).(*OfpPacketIn)
+	lazy.Retain()
+
+	// reuse receive buffer
+	for i := range packet {
+		packet[i] = 0
+	}
+	data := lazy.GetData()
+	if len(data) != 128 || data[1] != 1 || lazy.GetMatch().OxmFields[0].(*OxmInPort).Value != 3 {
+		t.Error("Retained PacketIn refers reused buffer.")
+	}
+}
+
+func TestLazyPacketInReadOnly(t *testing.T) {
+	packet := newTestPacketInBinary()
+	eager := Parse(packet).(*OfpPacketIn)
+	lazy := ParseLazy(packet).(*OfpPacketIn)
+
+	// these may be called on a message shared among goroutines
+	lazy.Size()
+	actual := lazy.Serialize()
+	_ = lazy.String()
+	lazy.InPort()
+	if lazy.Match != nil {
+		t.Error("Match of lazy PacketIn is cached by read-only methods.")
+	}
+	if !bytes.Equal(actual, eager.Serialize()) {
+		t.Error("Serialized lazy PacketIn is invalid.")
+	}
+
+	lazy.Retain()
+	if lazy.Match == nil {
+		t.Error("Match of lazy PacketIn is not decoded by Retain.")
+	}
+}
+
+func TestLazyPacketInMalformedMatch(t *testing.T) {
+	packet := newTestPacketInBinary()
+	// match length exceeds the packet
+	packet[26], packet[27] = 0xff, 0xf0
+	lazy := ParseLazy(packet).(*OfpPacketIn)
+	if data := lazy.GetData(); data != nil {
+		t.Log("Actual Value is : ", data)
+		t.Error("Data of PacketIn with malformed match is returned.")
+	}
+	if match := lazy.GetMatch(); len(match.OxmFields) != 0 {
+		t.Log("Actual Value is : ", match.OxmFields)
+		t.Error("Malformed match of PacketIn is decoded.")
+	}
+}
+
+/*****************************************************/
+/* Benchmark                                         */
+/*****************************************************/
+func BenchmarkParsePacketIn(b *testing.B) {
+	packet := newTestPacketInBinary()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg := NewOfpPacketIn()
+		msg.Parse(packet)
+	}
+}
+
+func BenchmarkParseLazyPacketIn(b *testing.B) {
+	packet := newTestPacketInBinary()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg := new(OfpPacketIn)
+		msg.ParseLazy(packet)
+		msg.GetData()
+	}
+}
+
+// lazy decoding of handler which reads match
+func BenchmarkParseLazyPacketInMatch(b *testing.B) {
+	packet := newTestPacketInBinary()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg := new(OfpPacketIn)
+		msg.ParseLazy(packet)
+		msg.GetMatch()
+		msg.GetData()
+	}
+}
@@ -0,0 +1,396 @@
+package ofp13
+
+import (
+	"net"
+	"sort"
+)
+
+/*****************************************************/
+/* OfpMatch accessors                                */
+/*****************************************************/
+
+/// return the first field of OFPXMC_OPENFLOW_BASIC class whose type is
+/// field (OFPXMT_OFB_*), or nil.
+func (m *OfpMatch) Get(field uint32) OxmField {
+	if i := m.index(field); i >= 0 {
+		return m.OxmFields[i]
+	}
+	return nil
+}
+
+/// report whether match has field (OFPXMT_OFB_*).
+func (m *OfpMatch) Has(field uint32) bool {
+	return m.index(field) >= 0
+}
+
+/// replace the field of the same type with f, or add f if match doesn't
+/// have it. Length is updated.
+func (m *OfpMatch) Set(f OxmField) {
+	if f.OxmClass() == OFPXMC_OPENFLOW_BASIC {
+		if i := m.index(f.OxmField()); i >= 0 {
+			m.OxmFields[i] = f
+			m.updateLength()
+			return
+		}
+	}
+	m.Append(f)
+}
+
+/// remove all fields of type field (OFPXMT_OFB_*), and report whether
+/// any field was removed. Length is updated.
+func (m *OfpMatch) Remove(field uint32) bool {
+	fields := m.OxmFields[:0]
+	removed := false
+	for _, f := range m.OxmFields {
+		if f.OxmClass() == OFPXMC_OPENFLOW_BASIC && f.OxmField() == field {
+			removed = true
+			continue
+		}
+		fields = append(fields, f)
+	}
+	// clear references to removed fields
+	for i := len(fields); i < len(m.OxmFields); i++ {
+		m.OxmFields[i] = nil
+	}
+	m.OxmFields = fields
+	m.updateLength()
+	return removed
+}
+
+/// return fields in canonical order, which is ascending order of class and
+/// field type. every prerequisite of OFPXMC_OPENFLOW_BASIC fields precedes
+/// the field in this order. OxmFields is not modified.
+func (m *OfpMatch) Fields() []OxmField {
+	fields := append([]OxmField(nil), m.OxmFields...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].OxmClass() != fields[j].OxmClass() {
+			return fields[i].OxmClass() < fields[j].OxmClass()
+		}
+		return fields[i].OxmField() < fields[j].OxmField()
+	})
+	return fields
+}
+
+/// sort OxmFields in canonical order of Fields.
+func (m *OfpMatch) Sort() {
+	m.OxmFields = m.Fields()
+}
+
+func (m *OfpMatch) index(field uint32) int {
+	if m == nil {
+		return -1
+	}
+	for i, f := range m.OxmFields {
+		if f.OxmClass() == OFPXMC_OPENFLOW_BASIC && f.OxmField() == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// Length is the size of match without padding
+func (m *OfpMatch) updateLength() {
+	m.Length = 4
+	for _, f := range m.OxmFields {
+		m.Length += uint16(f.Size())
+	}
+}
+
+/*****************************************************/
+/* Typed getters                                     */
+/*****************************************************/
+// Getters return false if match doesn't have the field. Mask is the zero
+// value if the field is not masked.
+
+func (m *OfpMatch) InPort() (uint32, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IN_PORT).(*OxmInPort); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) InPhyPort() (uint32, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IN_PHY_PORT).(*OxmInPhyPort); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) Metadata() (uint64, uint64, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_METADATA).(*OxmMetadata); ok {
+		return f.Value, f.Mask, true
+	}
+	return 0, 0, false
+}
+
+func (m *OfpMatch) EthDst() (net.HardwareAddr, net.HardwareAddr, bool) {
+	return m.eth(OFPXMT_OFB_ETH_DST)
+}
+
+func (m *OfpMatch) EthSrc() (net.HardwareAddr, net.HardwareAddr, bool) {
+	return m.eth(OFPXMT_OFB_ETH_SRC)
+}
+
+func (m *OfpMatch) eth(field uint32) (net.HardwareAddr, net.HardwareAddr, bool) {
+	if f, ok := m.Get(field).(*OxmEth); ok {
+		return f.Value, f.Mask, true
+	}
+	return nil, nil, false
+}
+
+func (m *OfpMatch) EthType() (uint16, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ETH_TYPE).(*OxmEthType); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+/// return vlan_vid including OFPVID_PRESENT bit.
+func (m *OfpMatch) VlanVid() (uint16, uint16, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_VLAN_VID).(*OxmVlanVid); ok {
+		return f.Value, f.Mask, true
+	}
+	return 0, 0, false
+}
+
+func (m *OfpMatch) VlanPcp() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_VLAN_PCP).(*OxmVlanPcp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) IpDscp() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IP_DSCP).(*OxmIpDscp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) IpEcn() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IP_ECN).(*OxmIpEcn); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) IpProto() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IP_PROTO).(*OxmIpProto); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) Ipv4Src() (net.IP, net.IPMask, bool) {
+	return m.ipv4(OFPXMT_OFB_IPV4_SRC)
+}
+
+func (m *OfpMatch) Ipv4Dst() (net.IP, net.IPMask, bool) {
+	return m.ipv4(OFPXMT_OFB_IPV4_DST)
+}
+
+func (m *OfpMatch) ipv4(field uint32) (net.IP, net.IPMask, bool) {
+	if f, ok := m.Get(field).(*OxmIpv4); ok {
+		return f.Value, f.Mask, true
+	}
+	return nil, nil, false
+}
+
+func (m *OfpMatch) TcpSrc() (uint16, bool) {
+	return m.tcp(OFPXMT_OFB_TCP_SRC)
+}
+
+func (m *OfpMatch) TcpDst() (uint16, bool) {
+	return m.tcp(OFPXMT_OFB_TCP_DST)
+}
+
+func (m *OfpMatch) tcp(field uint32) (uint16, bool) {
+	if f, ok := m.Get(field).(*OxmTcp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) UdpSrc() (uint16, bool) {
+	return m.udp(OFPXMT_OFB_UDP_SRC)
+}
+
+func (m *OfpMatch) UdpDst() (uint16, bool) {
+	return m.udp(OFPXMT_OFB_UDP_DST)
+}
+
+func (m *OfpMatch) udp(field uint32) (uint16, bool) {
+	if f, ok := m.Get(field).(*OxmUdp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) SctpSrc() (uint16, bool) {
+	return m.sctp(OFPXMT_OFB_SCTP_SRC)
+}
+
+func (m *OfpMatch) SctpDst() (uint16, bool) {
+	return m.sctp(OFPXMT_OFB_SCTP_DST)
+}
+
+func (m *OfpMatch) sctp(field uint32) (uint16, bool) {
+	if f, ok := m.Get(field).(*OxmSctp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) IcmpType() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ICMPV4_TYPE).(*OxmIcmpType); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) IcmpCode() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ICMPV4_CODE).(*OxmIcmpCode); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) ArpOp() (uint16, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ARP_OP).(*OxmArpOp); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) ArpSpa() (net.IP, net.IPMask, bool) {
+	return m.arpPa(OFPXMT_OFB_ARP_SPA)
+}
+
+func (m *OfpMatch) ArpTpa() (net.IP, net.IPMask, bool) {
+	return m.arpPa(OFPXMT_OFB_ARP_TPA)
+}
+
+func (m *OfpMatch) arpPa(field uint32) (net.IP, net.IPMask, bool) {
+	if f, ok := m.Get(field).(*OxmArpPa); ok {
+		return f.Value, f.Mask, true
+	}
+	return nil, nil, false
+}
+
+func (m *OfpMatch) ArpSha() (net.HardwareAddr, bool) {
+	return m.arpHa(OFPXMT_OFB_ARP_SHA)
+}
+
+func (m *OfpMatch) ArpTha() (net.HardwareAddr, bool) {
+	return m.arpHa(OFPXMT_OFB_ARP_THA)
+}
+
+func (m *OfpMatch) arpHa(field uint32) (net.HardwareAddr, bool) {
+	if f, ok := m.Get(field).(*OxmArpHa); ok {
+		return f.Value, true
+	}
+	return nil, false
+}
+
+func (m *OfpMatch) Ipv6Src() (net.IP, net.IPMask, bool) {
+	return m.ipv6(OFPXMT_OFB_IPV6_SRC)
+}
+
+func (m *OfpMatch) Ipv6Dst() (net.IP, net.IPMask, bool) {
+	return m.ipv6(OFPXMT_OFB_IPV6_DST)
+}
+
+func (m *OfpMatch) ipv6(field uint32) (net.IP, net.IPMask, bool) {
+	if f, ok := m.Get(field).(*OxmIpv6); ok {
+		return f.Value, f.Mask, true
+	}
+	return nil, nil, false
+}
+
+func (m *OfpMatch) Ipv6FLabel() (uint32, uint32, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IPV6_FLABEL).(*OxmIpv6FLabel); ok {
+		return f.Value, f.Mask, true
+	}
+	return 0, 0, false
+}
+
+func (m *OfpMatch) Icmpv6Type() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ICMPV6_TYPE).(*OxmIcmpv6Type); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) Icmpv6Code() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_ICMPV6_CODE).(*OxmIcmpv6Code); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) Ipv6NdTarget() (net.IP, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IPV6_ND_TARGET).(*OxmIpv6NdTarget); ok {
+		return f.Value, true
+	}
+	return nil, false
+}
+
+func (m *OfpMatch) Ipv6NdSll() (net.HardwareAddr, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IPV6_ND_SLL).(*OxmIpv6NdSll); ok {
+		return f.Value, true
+	}
+	return nil, false
+}
+
+func (m *OfpMatch) Ipv6NdTll() (net.HardwareAddr, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IPV6_ND_TLL).(*OxmIpv6NdTll); ok {
+		return f.Value, true
+	}
+	return nil, false
+}
+
+func (m *OfpMatch) MplsLabel() (uint32, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_MPLS_LABEL).(*OxmMplsLabel); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) MplsTc() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_MPLS_TC).(*OxmMplsTc); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+func (m *OfpMatch) MplsBos() (uint8, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_MPLS_BOS).(*OxmMplsBos); ok {
+		return f.Value, true
+	}
+	return 0, false
+}
+
+/// return 24bit pbb_isid and its mask.
+func (m *OfpMatch) PbbIsid() (uint32, uint32, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_PBB_ISID).(*OxmPbbIsid); ok {
+		value := uint32(f.Value[0])<<16 | uint32(f.Value[1])<<8 | uint32(f.Value[2])
+		mask := uint32(f.Mask[0])<<16 | uint32(f.Mask[1])<<8 | uint32(f.Mask[2])
+		return value, mask, true
+	}
+	return 0, 0, false
+}
+
+func (m *OfpMatch) TunnelId() (uint64, uint64, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_TUNNEL_ID).(*OxmTunnelId); ok {
+		return f.Value, f.Mask, true
+	}
+	return 0, 0, false
+}
+
+/// return OFPIEH_* flags of ipv6_exthdr and its mask.
+func (m *OfpMatch) Ipv6ExtHeader() (uint16, uint16, bool) {
+	if f, ok := m.Get(OFPXMT_OFB_IPV6_EXTHDR).(*OxmIpv6ExtHeader); ok {
+		return f.Value, f.Mask, true
+	}
+	return 0, 0, false
+}
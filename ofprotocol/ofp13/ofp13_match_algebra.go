@@ -0,0 +1,336 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+/*****************************************************/
+/* Match algebra                                     */
+/*****************************************************/
+
+/// field of match as value and mask bytes. mask is nil for exact match.
+/// fields of classes other than OFPXMC_OPENFLOW_BASIC are kept as opaque
+/// value of serialized bytes.
+type oxmEntry struct {
+	class uint32
+	field uint32
+	value []byte
+	mask  []byte
+	// field of other class
+	opaque OxmField
+}
+
+func newOxmEntry(f OxmField) oxmEntry {
+	b := f.Serialize()
+	e := oxmEntry{class: f.OxmClass(), field: f.OxmField()}
+	if e.class != OFPXMC_OPENFLOW_BASIC {
+		e.value = b
+		e.opaque = f
+		return e
+	}
+	header := binary.BigEndian.Uint32(b)
+	length := int(oxmLength(header))
+	if oxmHasMask(header) == 1 {
+		e.value = append([]byte(nil), b[4:4+length/2]...)
+		e.mask = append([]byte(nil), b[4+length/2:4+length]...)
+	} else {
+		e.value = append([]byte(nil), b[4:4+length]...)
+	}
+	return e
+}
+
+func (e oxmEntry) less(o oxmEntry) bool {
+	if e.class != o.class {
+		return e.class < o.class
+	}
+	if e.field != o.field {
+		return e.field < o.field
+	}
+	return bytes.Compare(e.value, o.value) < 0
+}
+
+// apply mask to value, and remove mask of all ones.
+// it returns false if mask is all zeros, so that field is wildcarded.
+func (e *oxmEntry) normalize() bool {
+	if e.mask == nil {
+		return true
+	}
+	ones, zeros := true, true
+	for i := range e.mask {
+		e.value[i] &= e.mask[i]
+		if e.mask[i] != 0xff {
+			ones = false
+		}
+		if e.mask[i] != 0 {
+			zeros = false
+		}
+	}
+	if ones {
+		e.mask = nil
+	}
+	return !zeros
+}
+
+// mask bit of byte i
+func (e oxmEntry) maskAt(i int) byte {
+	if e.mask == nil {
+		return 0xff
+	}
+	return e.mask[i]
+}
+
+func (e oxmEntry) serialize() []byte {
+	if e.opaque != nil {
+		return e.value
+	}
+	b := make([]byte, 4, 4+2*len(e.value))
+	if e.mask == nil {
+		binary.BigEndian.PutUint32(b, oxmHeader(e.class, e.field, uint32(len(e.value))))
+		return append(b, e.value...)
+	}
+	binary.BigEndian.PutUint32(b, oxmHeaderW(e.class, e.field, uint32(len(e.value))))
+	return append(append(b, e.value...), e.mask...)
+}
+
+func (e oxmEntry) oxmField() OxmField {
+	if e.opaque != nil {
+		return e.opaque
+	}
+	return parseOxmField(e.serialize())
+}
+
+/// prerequisite which is uniquely determined by a field, such as
+/// ip_proto=6 for tcp_src.
+type oxmPrereq struct {
+	field uint32
+	value []byte
+	mask  []byte
+}
+
+func ethTypePrereq(t uint16) oxmPrereq {
+	return oxmPrereq{OFPXMT_OFB_ETH_TYPE, []byte{byte(t >> 8), byte(t)}, nil}
+}
+
+func ipProtoPrereq(p uint8) oxmPrereq {
+	return oxmPrereq{OFPXMT_OFB_IP_PROTO, []byte{p}, nil}
+}
+
+func icmpv6TypePrereq(t uint8) oxmPrereq {
+	return oxmPrereq{OFPXMT_OFB_ICMPV6_TYPE, []byte{t}, nil}
+}
+
+var impliedPrerequisites = map[uint32][]oxmPrereq{
+	OFPXMT_OFB_VLAN_PCP:       {{OFPXMT_OFB_VLAN_VID, []byte{0x10, 0x00}, []byte{0x10, 0x00}}},
+	OFPXMT_OFB_IPV4_SRC:       {ethTypePrereq(0x0800)},
+	OFPXMT_OFB_IPV4_DST:       {ethTypePrereq(0x0800)},
+	OFPXMT_OFB_TCP_SRC:        {ipProtoPrereq(6)},
+	OFPXMT_OFB_TCP_DST:        {ipProtoPrereq(6)},
+	OFPXMT_OFB_UDP_SRC:        {ipProtoPrereq(17)},
+	OFPXMT_OFB_UDP_DST:        {ipProtoPrereq(17)},
+	OFPXMT_OFB_SCTP_SRC:       {ipProtoPrereq(132)},
+	OFPXMT_OFB_SCTP_DST:       {ipProtoPrereq(132)},
+	OFPXMT_OFB_ICMPV4_TYPE:    {ethTypePrereq(0x0800), ipProtoPrereq(1)},
+	OFPXMT_OFB_ICMPV4_CODE:    {ethTypePrereq(0x0800), ipProtoPrereq(1)},
+	OFPXMT_OFB_ARP_OP:         {ethTypePrereq(0x0806)},
+	OFPXMT_OFB_ARP_SPA:        {ethTypePrereq(0x0806)},
+	OFPXMT_OFB_ARP_TPA:        {ethTypePrereq(0x0806)},
+	OFPXMT_OFB_ARP_SHA:        {ethTypePrereq(0x0806)},
+	OFPXMT_OFB_ARP_THA:        {ethTypePrereq(0x0806)},
+	OFPXMT_OFB_IPV6_SRC:       {ethTypePrereq(0x86dd)},
+	OFPXMT_OFB_IPV6_DST:       {ethTypePrereq(0x86dd)},
+	OFPXMT_OFB_IPV6_FLABEL:    {ethTypePrereq(0x86dd)},
+	OFPXMT_OFB_ICMPV6_TYPE:    {ethTypePrereq(0x86dd), ipProtoPrereq(58)},
+	OFPXMT_OFB_ICMPV6_CODE:    {ethTypePrereq(0x86dd), ipProtoPrereq(58)},
+	OFPXMT_OFB_IPV6_ND_TARGET: {ethTypePrereq(0x86dd), ipProtoPrereq(58)},
+	OFPXMT_OFB_IPV6_ND_SLL:    {ethTypePrereq(0x86dd), ipProtoPrereq(58), icmpv6TypePrereq(135)},
+	OFPXMT_OFB_IPV6_ND_TLL:    {ethTypePrereq(0x86dd), ipProtoPrereq(58), icmpv6TypePrereq(136)},
+	OFPXMT_OFB_PBB_ISID:       {ethTypePrereq(0x88e7)},
+	OFPXMT_OFB_IPV6_EXTHDR:    {ethTypePrereq(0x86dd)},
+}
+
+/// canonical entries of match. masks are normalized, fields are sorted, and
+/// prerequisites implied by fields are added if implied is true.
+func (m *OfpMatch) entries(implied bool) []oxmEntry {
+	entries := make([]oxmEntry, 0, len(m.OxmFields))
+	has := make(map[uint32]bool)
+	for _, f := range m.OxmFields {
+		e := newOxmEntry(f)
+		if !e.normalize() {
+			continue
+		}
+		if e.opaque == nil {
+			has[e.field] = true
+		}
+		entries = append(entries, e)
+	}
+	if implied {
+		for _, e := range append([]oxmEntry(nil), entries...) {
+			if e.opaque != nil {
+				continue
+			}
+			for _, p := range impliedPrerequisites[e.field] {
+				if has[p.field] {
+					continue
+				}
+				has[p.field] = true
+				entries = append(entries, oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: p.field,
+					value: append([]byte(nil), p.value...), mask: p.mask})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].less(entries[j])
+	})
+	return entries
+}
+
+func newOfpMatchFromEntries(entries []oxmEntry) *OfpMatch {
+	match := NewOfpMatch()
+	for _, e := range entries {
+		if f := e.oxmField(); f != nil {
+			match.Append(f)
+		}
+	}
+	return match
+}
+
+/// return canonical form of match. fields are sorted in canonical order of
+/// Fields, values are masked, and masks of all ones or all zeros are
+/// removed with the field of all zeros mask. match is not modified.
+func (m *OfpMatch) Canonical() *OfpMatch {
+	return newOfpMatchFromEntries(m.entries(false))
+}
+
+/// report whether m and other match the same packets, regardless of order
+/// of fields, masks of all ones, and prerequisites implied by fields.
+func (m *OfpMatch) Equal(other *OfpMatch) bool {
+	a, b := m.entries(true), other.entries(true)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].serialize(), b[i].serialize()) {
+			return false
+		}
+	}
+	return true
+}
+
+/// report whether m matches all packets other matches. for example
+/// eth_type=0x0800 covers eth_type=0x0800,ipv4_dst=10.0.0.1, and
+/// ipv4_dst=10.0.0.0/8 covers ipv4_dst=10.0.0.1.
+/// prerequisites implied by fields are considered, so ip_proto=6 covers
+/// tcp_dst=80 without ip_proto.
+func (m *OfpMatch) Covers(other *OfpMatch) bool {
+	b := other.entries(true)
+	for _, ea := range m.entries(true) {
+		found := false
+		for _, eb := range b {
+			if ea.class != eb.class || ea.field != eb.field || len(ea.value) != len(eb.value) {
+				continue
+			}
+			if ea.opaque != nil {
+				found = bytes.Equal(ea.value, eb.value)
+			} else {
+				found = ea.covers(eb)
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// report whether every value matching o matches e
+func (e oxmEntry) covers(o oxmEntry) bool {
+	for i := range e.value {
+		ma, mb := e.maskAt(i), o.maskAt(i)
+		// bits matched by e must be matched by o with the same value
+		if ma&^mb != 0 || (e.value[i]^o.value[i])&ma != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+/// return match of packets which match both m and other, or nil if no
+/// packet matches both. prerequisites implied by fields are included in
+/// the result.
+func (m *OfpMatch) Intersect(other *OfpMatch) *OfpMatch {
+	a, b := m.entries(true), other.entries(true)
+	result := make([]oxmEntry, 0, len(a)+len(b))
+	used := make([]bool, len(b))
+	for _, ea := range a {
+		merged := ea
+		for j, eb := range b {
+			if used[j] || ea.class != eb.class || ea.field != eb.field {
+				continue
+			}
+			if ea.opaque != nil || eb.opaque != nil || len(ea.value) != len(eb.value) {
+				if !bytes.Equal(ea.value, eb.value) {
+					return nil
+				}
+				used[j] = true
+				break
+			}
+			var ok bool
+			if merged, ok = ea.intersect(eb); !ok {
+				return nil
+			}
+			used[j] = true
+			break
+		}
+		result = append(result, merged)
+	}
+	for j, eb := range b {
+		if !used[j] {
+			result = append(result, eb)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].less(result[j])
+	})
+	return newOfpMatchFromEntries(result)
+}
+
+// intersection of values of the same field, or false if disjoint
+func (e oxmEntry) intersect(o oxmEntry) (oxmEntry, bool) {
+	value := make([]byte, len(e.value))
+	mask := make([]byte, len(e.value))
+	for i := range e.value {
+		ma, mb := e.maskAt(i), o.maskAt(i)
+		if (e.value[i]^o.value[i])&ma&mb != 0 {
+			return e, false
+		}
+		value[i] = e.value[i]&ma | o.value[i]&mb
+		mask[i] = ma | mb
+	}
+	r := oxmEntry{class: e.class, field: e.field, value: value, mask: mask}
+	r.normalize()
+	return r, true
+}
+
+/// return key of canonical form of match including implied prerequisites.
+/// matches which are Equal have the same key, so it can be used as key of
+/// map.
+func (m *OfpMatch) Key() string {
+	var b []byte
+	for _, e := range m.entries(true) {
+		b = append(b, e.serialize()...)
+	}
+	return string(b)
+}
+
+/// return stable 64bit hash of Key.
+func (m *OfpMatch) Hash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.Key()))
+	return h.Sum64()
+}
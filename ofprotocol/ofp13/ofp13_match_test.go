@@ -0,0 +1,199 @@
+package ofp13
+
+import (
+	"net"
+	"testing"
+)
+
+/*****************************************************/
+/* OfpMatch accessors                                */
+/*****************************************************/
+func TestOfpMatchAccessors(t *testing.T) {
+	match := NewOfpMatch()
+	ipDst, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	ethDst, _ := NewOxmEthDst("00:00:00:00:00:02")
+	match.Append(NewOxmTcpDst(80))
+	match.Append(ipDst)
+	match.Append(NewOxmIpProto(6))
+	match.Append(NewOxmEthType(0x0800))
+	match.Append(ethDst)
+	match.Append(NewOxmInPort(3))
+
+	if inPort, ok := match.InPort(); !ok || inPort != 3 {
+		t.Error("InPort is invalid.")
+	}
+	if ip, mask, ok := match.Ipv4Dst(); !ok || ip.String() != "10.0.0.0" || mask.String() != "ff000000" {
+		t.Log("Actual ipv4_dst is : ", ip, mask)
+		t.Error("Ipv4Dst is invalid.")
+	}
+	if dst, mask, ok := match.EthDst(); !ok || dst.String() != "00:00:00:00:00:02" || mask != nil {
+		t.Error("EthDst is invalid.")
+	}
+	if _, ok := match.UdpDst(); ok {
+		t.Error("Missing field is found.")
+	}
+	if f, ok := match.Get(OFPXMT_OFB_TCP_DST).(*OxmTcp); !ok || f.Value != 80 {
+		t.Error("Get is invalid.")
+	}
+
+	// canonical order
+	expected := []uint32{
+		OFPXMT_OFB_IN_PORT, OFPXMT_OFB_ETH_DST, OFPXMT_OFB_ETH_TYPE,
+		OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_IPV4_DST, OFPXMT_OFB_TCP_DST,
+	}
+	fields := match.Fields()
+	for i, f := range fields {
+		if f.OxmField() != expected[i] {
+			t.Log("Actual field is : ", f)
+			t.Errorf("Field %d is expected to be %d.", i, expected[i])
+		}
+	}
+	if match.OxmFields[0].OxmField() != OFPXMT_OFB_TCP_DST {
+		t.Error("OxmFields is modified by Fields.")
+	}
+
+	// Length is kept consistent, without recomputed by Serialize
+	length := func() uint16 {
+		serialized := *match
+		m := NewOfpMatch()
+		m.Parse(serialized.Serialize())
+		return m.Length
+	}
+	match.Set(NewOxmTcpDst(443))
+	if port, _ := match.TcpDst(); port != 443 || len(match.OxmFields) != 6 || match.Length != length() {
+		t.Log("Actual length is : ", match.Length)
+		t.Error("Set of existing field is invalid.")
+	}
+	match.Set(NewOxmTcpSrc(1000))
+	if !match.Has(OFPXMT_OFB_TCP_SRC) || match.Length != length() {
+		t.Error("Set of new field is invalid.")
+	}
+	if !match.Remove(OFPXMT_OFB_IPV4_DST) || match.Has(OFPXMT_OFB_IPV4_DST) || match.Length != length() {
+		t.Log("Actual length is : ", match.Length)
+		t.Error("Remove is invalid.")
+	}
+	if match.Remove(OFPXMT_OFB_IPV4_DST) {
+		t.Error("Missing field is removed.")
+	}
+}
+
+/*****************************************************/
+/* OfpMatch algebra                                  */
+/*****************************************************/
+func matchOf(fields ...OxmField) *OfpMatch {
+	match := NewOfpMatch()
+	for _, f := range fields {
+		match.Append(f)
+	}
+	return match
+}
+
+func TestOfpMatchCanonical(t *testing.T) {
+	ethDst, _ := NewOxmEthDstW("00:11:22:33:44:55", "ff:ff:ff:ff:ff:ff")
+	ipDst, _ := NewOxmIpv4DstW("10.1.2.3", 8)
+	match := matchOf(NewOxmTcpDst(80), ipDst, ethDst,
+		NewOxmVlanVidW(0, 0), NewOxmIpProto(6), NewOxmEthType(0x0800))
+
+	canonical := match.Canonical()
+	expected := []uint32{
+		OFPXMT_OFB_ETH_DST, OFPXMT_OFB_ETH_TYPE,
+		OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_IPV4_DST, OFPXMT_OFB_TCP_DST,
+	}
+	if len(canonical.OxmFields) != len(expected) {
+		t.Log("Actual fields are : ", canonical.OxmFields)
+		t.Fatal("Wildcarded field is not removed.")
+	}
+	for i, f := range canonical.OxmFields {
+		if f.OxmField() != expected[i] {
+			t.Log("Actual field is : ", f)
+			t.Errorf("Field %d is expected to be %d.", i, expected[i])
+		}
+	}
+	if _, mask, _ := canonical.EthDst(); mask != nil {
+		t.Log("Actual mask is : ", mask)
+		t.Error("Mask of all ones is not removed.")
+	}
+	if addr, _, _ := canonical.Ipv4Dst(); !addr.Equal(net.ParseIP("10.0.0.0")) {
+		t.Log("Actual address is : ", addr)
+		t.Error("Value is not masked.")
+	}
+	if len(match.OxmFields) != 6 {
+		t.Error("Match is modified by Canonical.")
+	}
+}
+
+func TestOfpMatchEqual(t *testing.T) {
+	a := matchOf(NewOxmIpProto(6), NewOxmTcpDst(80))
+	b := matchOf(NewOxmTcpDst(80))
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("Matches with implied prerequisites are not equal.")
+	}
+	if a.Key() != b.Key() || a.Hash() != b.Hash() {
+		t.Error("Keys of equal matches differ.")
+	}
+	c := matchOf(NewOxmTcpDst(443))
+	if a.Equal(c) || a.Key() == c.Key() {
+		t.Error("Different matches are equal.")
+	}
+
+	flows := map[string]int{a.Key(): 1}
+	if flows[b.Key()] != 1 {
+		t.Error("Key is not usable as key of map.")
+	}
+}
+
+func TestOfpMatchCovers(t *testing.T) {
+	net8, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	net16, _ := NewOxmIpv4DstW("10.1.0.0", 16)
+	host, _ := NewOxmIpv4Dst("10.1.2.3")
+	other, _ := NewOxmIpv4DstW("192.168.0.0", 16)
+	wide := matchOf(NewOxmEthType(0x0800), net8)
+	narrow := matchOf(NewOxmEthType(0x0800), net16, NewOxmIpProto(6))
+
+	if !wide.Covers(narrow) || narrow.Covers(wide) {
+		t.Error("Covers of masked field is invalid.")
+	}
+	if !wide.Covers(matchOf(host)) {
+		t.Error("Covers with implied prerequisite is invalid.")
+	}
+	if wide.Covers(matchOf(NewOxmEthType(0x0800), other)) {
+		t.Error("Disjoint match is covered.")
+	}
+	if !NewOfpMatch().Covers(narrow) {
+		t.Error("Empty match does not cover all.")
+	}
+	if !matchOf(NewOxmIpProto(6)).Covers(matchOf(NewOxmTcpDst(80))) {
+		t.Error("ip_proto is not implied by tcp_dst.")
+	}
+	if matchOf(NewOxmIpProto(17)).Covers(matchOf(NewOxmTcpDst(80))) {
+		t.Error("udp covers tcp.")
+	}
+}
+
+func TestOfpMatchIntersect(t *testing.T) {
+	net8, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	net16, _ := NewOxmIpv4DstW("10.1.0.0", 16)
+	other, _ := NewOxmIpv4DstW("192.168.0.0", 16)
+
+	a := matchOf(NewOxmInPort(1), net8)
+	b := matchOf(net16, NewOxmTcpDst(80))
+	overlap := a.Intersect(b)
+	if overlap == nil {
+		t.Fatal("Overlapping matches do not intersect.")
+	}
+	expected := matchOf(NewOxmInPort(1), net16, NewOxmTcpDst(80))
+	if !overlap.Equal(expected) {
+		t.Log("Actual fields are : ", overlap.OxmFields)
+		t.Error("Intersection is invalid.")
+	}
+	if !a.Covers(overlap) || !b.Covers(overlap) {
+		t.Error("Intersection is not covered by both matches.")
+	}
+
+	if a.Intersect(matchOf(other)) != nil {
+		t.Error("Disjoint matches intersect.")
+	}
+	if matchOf(NewOxmTcpDst(80)).Intersect(matchOf(NewOxmUdpDst(53))) != nil {
+		t.Error("tcp and udp intersect.")
+	}
+}
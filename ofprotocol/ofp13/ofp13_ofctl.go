@@ -0,0 +1,1080 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************/
+/* ovs-ofctl syntax                                  */
+/*****************************************************/
+
+/// kinds of values of fields in ovs-ofctl syntax
+const (
+	ofctlInt  = iota // decimal
+	ofctlHex         // hexadecimal with 0x
+	ofctlPort        // port number or reserved port name
+	ofctlMac
+	ofctlIPv4
+	ofctlIPv6
+)
+
+/// name of field printed in ovs-ofctl syntax, and kind of its value.
+type ofctlField struct {
+	name string
+	kind int
+}
+
+var ofctlFields = [...]ofctlField{
+	OFPXMT_OFB_IN_PORT:        {"in_port", ofctlPort},
+	OFPXMT_OFB_IN_PHY_PORT:    {"in_phy_port", ofctlPort},
+	OFPXMT_OFB_METADATA:       {"metadata", ofctlHex},
+	OFPXMT_OFB_ETH_DST:        {"dl_dst", ofctlMac},
+	OFPXMT_OFB_ETH_SRC:        {"dl_src", ofctlMac},
+	OFPXMT_OFB_ETH_TYPE:       {"dl_type", ofctlHex},
+	OFPXMT_OFB_VLAN_VID:       {"vlan_vid", ofctlInt},
+	OFPXMT_OFB_VLAN_PCP:       {"dl_vlan_pcp", ofctlInt},
+	OFPXMT_OFB_IP_DSCP:        {"ip_dscp", ofctlInt},
+	OFPXMT_OFB_IP_ECN:         {"nw_ecn", ofctlInt},
+	OFPXMT_OFB_IP_PROTO:       {"nw_proto", ofctlInt},
+	OFPXMT_OFB_IPV4_SRC:       {"nw_src", ofctlIPv4},
+	OFPXMT_OFB_IPV4_DST:       {"nw_dst", ofctlIPv4},
+	OFPXMT_OFB_TCP_SRC:        {"tcp_src", ofctlInt},
+	OFPXMT_OFB_TCP_DST:        {"tcp_dst", ofctlInt},
+	OFPXMT_OFB_UDP_SRC:        {"udp_src", ofctlInt},
+	OFPXMT_OFB_UDP_DST:        {"udp_dst", ofctlInt},
+	OFPXMT_OFB_SCTP_SRC:       {"sctp_src", ofctlInt},
+	OFPXMT_OFB_SCTP_DST:       {"sctp_dst", ofctlInt},
+	OFPXMT_OFB_ICMPV4_TYPE:    {"icmp_type", ofctlInt},
+	OFPXMT_OFB_ICMPV4_CODE:    {"icmp_code", ofctlInt},
+	OFPXMT_OFB_ARP_OP:         {"arp_op", ofctlInt},
+	OFPXMT_OFB_ARP_SPA:        {"arp_spa", ofctlIPv4},
+	OFPXMT_OFB_ARP_TPA:        {"arp_tpa", ofctlIPv4},
+	OFPXMT_OFB_ARP_SHA:        {"arp_sha", ofctlMac},
+	OFPXMT_OFB_ARP_THA:        {"arp_tha", ofctlMac},
+	OFPXMT_OFB_IPV6_SRC:       {"ipv6_src", ofctlIPv6},
+	OFPXMT_OFB_IPV6_DST:       {"ipv6_dst", ofctlIPv6},
+	OFPXMT_OFB_IPV6_FLABEL:    {"ipv6_label", ofctlHex},
+	OFPXMT_OFB_ICMPV6_TYPE:    {"icmpv6_type", ofctlInt},
+	OFPXMT_OFB_ICMPV6_CODE:    {"icmpv6_code", ofctlInt},
+	OFPXMT_OFB_IPV6_ND_TARGET: {"nd_target", ofctlIPv6},
+	OFPXMT_OFB_IPV6_ND_SLL:    {"nd_sll", ofctlMac},
+	OFPXMT_OFB_IPV6_ND_TLL:    {"nd_tll", ofctlMac},
+	OFPXMT_OFB_MPLS_LABEL:     {"mpls_label", ofctlInt},
+	OFPXMT_OFB_MPLS_TC:        {"mpls_tc", ofctlInt},
+	OFPXMT_OFB_MPLS_BOS:       {"mpls_bos", ofctlInt},
+	OFPXMT_OFB_PBB_ISID:       {"pbb_isid", ofctlInt},
+	OFPXMT_OFB_TUNNEL_ID:      {"tun_id", ofctlHex},
+	OFPXMT_OFB_IPV6_EXTHDR:    {"ipv6_exthdr", ofctlHex},
+}
+
+/// names of fields accepted by parser. names of ofctlFields and
+/// oxmFieldSpecs are added by init.
+var ofctlFieldAliases = map[string]uint32{
+	"eth_dst":      OFPXMT_OFB_ETH_DST,
+	"eth_src":      OFPXMT_OFB_ETH_SRC,
+	"eth_type":     OFPXMT_OFB_ETH_TYPE,
+	"vlan_pcp":     OFPXMT_OFB_VLAN_PCP,
+	"ip_proto":     OFPXMT_OFB_IP_PROTO,
+	"ip_ecn":       OFPXMT_OFB_IP_ECN,
+	"ip_src":       OFPXMT_OFB_IPV4_SRC,
+	"ip_dst":       OFPXMT_OFB_IPV4_DST,
+	"ipv6_flabel":  OFPXMT_OFB_IPV6_FLABEL,
+	"tunnel_id":    OFPXMT_OFB_TUNNEL_ID,
+	"icmpv4_type":  OFPXMT_OFB_ICMPV4_TYPE,
+	"icmpv4_code":  OFPXMT_OFB_ICMPV4_CODE,
+	"icmp6_type":   OFPXMT_OFB_ICMPV6_TYPE,
+	"icmp6_code":   OFPXMT_OFB_ICMPV6_CODE,
+	"ipv6_nd_sll":  OFPXMT_OFB_IPV6_ND_SLL,
+	"ipv6_nd_tll":  OFPXMT_OFB_IPV6_ND_TLL,
+}
+
+func init() {
+	for field := range ofctlFields {
+		ofctlFieldAliases[ofctlFields[field].name] = uint32(field)
+		ofctlFieldAliases[oxmFieldSpecs[field].name] = uint32(field)
+	}
+}
+
+/// shorthands of eth_type and ip_proto
+var ofctlProtocols = map[string][2]uint16{
+	"ip":    {0x0800, 0},
+	"ipv6":  {0x86dd, 0},
+	"icmp":  {0x0800, 1},
+	"icmp6": {0x86dd, 58},
+	"tcp":   {0x0800, 6},
+	"tcp6":  {0x86dd, 6},
+	"udp":   {0x0800, 17},
+	"udp6":  {0x86dd, 17},
+	"sctp":  {0x0800, 132},
+	"sctp6": {0x86dd, 132},
+	"arp":   {0x0806, 0},
+	"mpls":  {0x8847, 0},
+	"mplsm": {0x8848, 0},
+}
+
+var ofctlPortNames = map[string]uint32{
+	"in_port":    OFPP_IN_PORT,
+	"table":      OFPP_TABLE,
+	"normal":     OFPP_NORMAL,
+	"flood":      OFPP_FLOOD,
+	"all":        OFPP_ALL,
+	"controller": OFPP_CONTROLLER,
+	"local":      OFPP_LOCAL,
+	"any":        OFPP_ANY,
+	"none":       OFPP_ANY,
+}
+
+func parseOfctlPort(s string) (uint32, error) {
+	if port, ok := ofctlPortNames[strings.ToLower(s)]; ok {
+		return port, nil
+	}
+	port, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("port %s is invalid.", s)
+	}
+	return uint32(port), nil
+}
+
+func ofctlPortString(port uint32) string {
+	switch port {
+	case OFPP_IN_PORT:
+		return "IN_PORT"
+	case OFPP_TABLE:
+		return "TABLE"
+	case OFPP_NORMAL:
+		return "NORMAL"
+	case OFPP_FLOOD:
+		return "FLOOD"
+	case OFPP_ALL:
+		return "ALL"
+	case OFPP_CONTROLLER:
+		return "CONTROLLER"
+	case OFPP_LOCAL:
+		return "LOCAL"
+	case OFPP_ANY:
+		return "ANY"
+	}
+	return strconv.FormatUint(uint64(port), 10)
+}
+
+// split s at the first sep, or return s and "" without sep
+func splitOfctl(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+/// split s by commas and white spaces outside of parentheses.
+func splitOfctlList(s string) []string {
+	var list []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == ',' || c == ' ' || c == '\t' || c == '\n'):
+			if i > start {
+				list = append(list, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		list = append(list, s[start:])
+	}
+	return list
+}
+
+func ofctlUint(s string, bits int) (uint64, error) {
+	if bits > 64 {
+		bits = 64
+	}
+	return strconv.ParseUint(s, 0, bits)
+}
+
+func ofctlBytes(v uint64, size int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b[8-size:]
+}
+
+/// parse value[/mask] of field into entry. the value is masked, and false is
+/// returned for all zeros mask, which matches any value.
+func parseOfctlValue(field uint32, s string) (oxmEntry, bool, error) {
+	value, mask, masked := splitOfctl(s, "/")
+	e, err := parseOxmValue(field, value, mask, masked)
+	if err != nil {
+		return e, false, err
+	}
+	return e, e.normalize(), nil
+}
+
+/// parse value and mask of field into entry as they are. the value is
+/// written in the syntax of ofctlFields kind of field.
+func parseOxmValue(field uint32, value string, mask string, masked bool) (oxmEntry, error) {
+	spec := oxmFieldSpecs[field]
+	e := oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: field}
+	s := value
+	if masked {
+		s += "/" + mask
+	}
+	invalid := fmt.Errorf("value %s of %s is invalid.", s, spec.name)
+
+	switch ofctlFields[field].kind {
+	case ofctlInt, ofctlHex:
+		v, err := ofctlUint(value, spec.size*8)
+		if err != nil {
+			return e, invalid
+		}
+		e.value = ofctlBytes(v, spec.size)
+		if masked {
+			m, err := ofctlUint(mask, spec.size*8)
+			if err != nil {
+				return e, invalid
+			}
+			e.mask = ofctlBytes(m, spec.size)
+		}
+	case ofctlPort:
+		port, err := parseOfctlPort(value)
+		if err != nil || masked {
+			return e, invalid
+		}
+		e.value = ofctlBytes(uint64(port), 4)
+	case ofctlMac:
+		hw, err := net.ParseMAC(value)
+		if err != nil || len(hw) != 6 {
+			return e, invalid
+		}
+		e.value = hw
+		if masked {
+			if e.mask, err = net.ParseMAC(mask); err != nil || len(e.mask) != 6 {
+				return e, invalid
+			}
+		}
+	case ofctlIPv4, ofctlIPv6:
+		bits := 32
+		ip := net.ParseIP(value)
+		if ofctlFields[field].kind == ofctlIPv4 {
+			ip = ip.To4()
+		} else {
+			bits = 128
+		}
+		if ip == nil {
+			return e, invalid
+		}
+		e.value = append([]byte(nil), ip...)
+		if masked {
+			if n, err := strconv.Atoi(mask); err == nil && n >= 0 && n <= bits {
+				e.mask = net.CIDRMask(n, bits)
+			} else if m := net.ParseIP(mask); m != nil && (bits == 128 || m.To4() != nil) {
+				if bits == 32 {
+					m = m.To4()
+				}
+				e.mask = append([]byte(nil), m...)
+			} else {
+				return e, invalid
+			}
+		}
+	}
+	if e.mask != nil {
+		e.mask = append([]byte(nil), e.mask...)
+		e.value = append([]byte(nil), e.value...)
+	}
+	return e, nil
+}
+
+// format value[/mask] of entry
+func ofctlValueString(e oxmEntry) string {
+	var s string
+	switch ofctlFields[e.field].kind {
+	case ofctlInt:
+		if e.mask != nil {
+			return fmt.Sprintf("0x%x/0x%x", oxmValue(e.value), oxmValue(e.mask))
+		}
+		return strconv.FormatUint(oxmValue(e.value), 10)
+	case ofctlHex:
+		if e.field == OFPXMT_OFB_ETH_TYPE {
+			return fmt.Sprintf("0x%04x", oxmValue(e.value))
+		}
+		s = fmt.Sprintf("0x%x", oxmValue(e.value))
+		if e.mask != nil {
+			s += fmt.Sprintf("/0x%x", oxmValue(e.mask))
+		}
+		return s
+	case ofctlPort:
+		return ofctlPortString(uint32(oxmValue(e.value)))
+	case ofctlMac:
+		s = net.HardwareAddr(e.value).String()
+		if e.mask != nil {
+			s += "/" + net.HardwareAddr(e.mask).String()
+		}
+		return s
+	default:
+		s = net.IP(e.value).String()
+		if e.mask != nil {
+			if ones, bits := net.IPMask(e.mask).Size(); bits != 0 {
+				s += "/" + strconv.Itoa(ones)
+			} else {
+				s += "/" + net.IP(e.mask).String()
+			}
+		}
+		return s
+	}
+}
+
+// format vlan_vid of entry as field of match
+func ofctlVlanString(e oxmEntry) string {
+	v := oxmValue(e.value)
+	switch {
+	case e.mask == nil && v&OFPVID_PRESENT != 0:
+		return fmt.Sprintf("dl_vlan=%d", v&^OFPVID_PRESENT)
+	case e.mask == nil && v == OFPVID_NONE:
+		return "vlan_tci=0x0000/0x1fff"
+	case e.mask != nil:
+		return fmt.Sprintf("vlan_vid=0x%04x/0x%04x", v, oxmValue(e.mask))
+	}
+	return fmt.Sprintf("vlan_vid=0x%04x/0x1fff", v)
+}
+
+/*****************************************************/
+/* Match                                             */
+/*****************************************************/
+
+/// parser of match in ovs-ofctl syntax. fields whose meaning depends on
+/// other fields, such as tp_dst of tcp or udp, are resolved at last.
+type ofctlMatchParser struct {
+	match    *OfpMatch
+	deferred [][2]string
+}
+
+func newOfctlMatchParser() *ofctlMatchParser {
+	return &ofctlMatchParser{match: NewOfpMatch()}
+}
+
+func (p *ofctlMatchParser) add(e oxmEntry) error {
+	f := e.oxmField()
+	if f == nil {
+		return fmt.Errorf("field %s is invalid.", oxmFieldSpecs[e.field].name)
+	}
+	if old := p.match.Get(e.field); old != nil {
+		if !bytes.Equal(old.Serialize(), f.Serialize()) {
+			return fmt.Errorf("field %s is specified twice.", oxmFieldSpecs[e.field].name)
+		}
+		return nil
+	}
+	p.match.Append(f)
+	return nil
+}
+
+func (p *ofctlMatchParser) addValue(field uint32, value string) error {
+	e, ok, err := parseOfctlValue(field, value)
+	if err != nil || !ok {
+		return err
+	}
+	return p.add(e)
+}
+
+func (p *ofctlMatchParser) addExact(field uint32, v uint64) error {
+	return p.add(oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: field,
+		value: ofctlBytes(v, oxmFieldSpecs[field].size)})
+}
+
+/// parse a token of match. it returns false if key is not of match.
+func (p *ofctlMatchParser) parse(key string, value string, hasValue bool) (bool, error) {
+	if !hasValue {
+		proto, ok := ofctlProtocols[key]
+		if !ok {
+			return false, nil
+		}
+		if err := p.addExact(OFPXMT_OFB_ETH_TYPE, uint64(proto[0])); err != nil {
+			return true, err
+		}
+		if proto[1] != 0 {
+			return true, p.addExact(OFPXMT_OFB_IP_PROTO, uint64(proto[1]))
+		}
+		return true, nil
+	}
+
+	switch key {
+	case "nw_src", "nw_dst", "tp_src", "tp_dst", "icmp_type", "icmp_code":
+		p.deferred = append(p.deferred, [2]string{key, value})
+		return true, nil
+	case "dl_vlan":
+		v, err := ofctlUint(value, 16)
+		if err != nil {
+			return true, fmt.Errorf("value %s of dl_vlan is invalid.", value)
+		}
+		if v == 0xffff {
+			return true, p.addExact(OFPXMT_OFB_VLAN_VID, OFPVID_NONE)
+		}
+		return true, p.addExact(OFPXMT_OFB_VLAN_VID, v|OFPVID_PRESENT)
+	case "vlan_vid":
+		if _, _, masked := splitOfctl(value, "/"); masked {
+			return true, p.addValue(OFPXMT_OFB_VLAN_VID, value)
+		}
+		v, err := ofctlUint(value, 12)
+		if err != nil {
+			return true, fmt.Errorf("value %s of vlan_vid is invalid.", value)
+		}
+		return true, p.addExact(OFPXMT_OFB_VLAN_VID, v|OFPVID_PRESENT)
+	case "vlan_tci":
+		return true, p.parseVlanTci(value)
+	case "nw_tos":
+		v, err := ofctlUint(value, 8)
+		if err != nil {
+			return true, fmt.Errorf("value %s of nw_tos is invalid.", value)
+		}
+		return true, p.addExact(OFPXMT_OFB_IP_DSCP, v>>2)
+	}
+	field, ok := ofctlFieldAliases[key]
+	if !ok {
+		return false, nil
+	}
+	return true, p.addValue(field, value)
+}
+
+// vlan_tci=tci[/mask] of 802.1Q tag control information with CFI bit as
+// OFPVID_PRESENT
+func (p *ofctlMatchParser) parseVlanTci(s string) error {
+	value, mask, masked := splitOfctl(s, "/")
+	tci, err := ofctlUint(value, 16)
+	m := uint64(0xffff)
+	if err == nil && masked {
+		m, err = ofctlUint(mask, 16)
+	}
+	if err != nil {
+		return fmt.Errorf("value %s of vlan_tci is invalid.", s)
+	}
+	if vidMask := m & 0x1fff; vidMask != 0 {
+		e := oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: OFPXMT_OFB_VLAN_VID,
+			value: ofctlBytes(tci&vidMask, 2), mask: ofctlBytes(vidMask, 2)}
+		e.normalize()
+		if err := p.add(e); err != nil {
+			return err
+		}
+	}
+	if m&0xe000 == 0xe000 {
+		return p.addExact(OFPXMT_OFB_VLAN_PCP, tci>>13)
+	}
+	return nil
+}
+
+// resolve fields depending on eth_type and ip_proto, and sort fields in
+// canonical order so that prerequisites come first.
+func (p *ofctlMatchParser) resolve() (*OfpMatch, error) {
+	ethType, _ := p.match.EthType()
+	ipProto, _ := p.match.IpProto()
+	for _, kv := range p.deferred {
+		var field uint32
+		switch kv[0] {
+		case "nw_src", "nw_dst":
+			field = OFPXMT_OFB_IPV4_SRC
+			if ethType == 0x0806 {
+				field = OFPXMT_OFB_ARP_SPA
+			}
+			if kv[0] == "nw_dst" {
+				field++
+			}
+		case "tp_src", "tp_dst":
+			switch ipProto {
+			case 6:
+				field = OFPXMT_OFB_TCP_SRC
+			case 17:
+				field = OFPXMT_OFB_UDP_SRC
+			case 132:
+				field = OFPXMT_OFB_SCTP_SRC
+			default:
+				return nil, fmt.Errorf("%s requires tcp, udp or sctp.", kv[0])
+			}
+			if kv[0] == "tp_dst" {
+				field++
+			}
+		case "icmp_type", "icmp_code":
+			field = OFPXMT_OFB_ICMPV4_TYPE
+			if ipProto == 58 {
+				field = OFPXMT_OFB_ICMPV6_TYPE
+			}
+			if kv[0] == "icmp_code" {
+				field++
+			}
+		}
+		if err := p.addValue(field, kv[1]); err != nil {
+			return nil, err
+		}
+	}
+	p.match.Sort()
+	return p.match, nil
+}
+
+/// ParseOfctlMatch parses match in ovs-ofctl syntax, such as
+/// "tcp,in_port=1,nw_dst=10.0.0.0/8,tp_dst=80".
+func ParseOfctlMatch(s string) (*OfpMatch, error) {
+	p := newOfctlMatchParser()
+	for _, token := range splitOfctlList(s) {
+		key, value, hasValue := splitOfctl(token, "=")
+		ok, err := p.parse(strings.ToLower(key), value, hasValue)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("field %s is unknown.", key)
+		}
+	}
+	return p.resolve()
+}
+
+/// OfctlString returns match in ovs-ofctl syntax. fields are printed in the
+/// canonical order, such as "in_port=1,dl_type=0x0800,nw_dst=10.0.0.0/8".
+func (m *OfpMatch) OfctlString() string {
+	var list []string
+	for _, e := range m.entries(false) {
+		if e.opaque != nil || int(e.field) >= len(ofctlFields) {
+			continue
+		}
+		if e.field == OFPXMT_OFB_VLAN_VID {
+			list = append(list, ofctlVlanString(e))
+			continue
+		}
+		list = append(list, ofctlFields[e.field].name+"="+ofctlValueString(e))
+	}
+	return strings.Join(list, ",")
+}
+
+/*****************************************************/
+/* Actions and Instructions                          */
+/*****************************************************/
+
+/// parse set_field:value->field, or mod_*:value of field.
+func parseOfctlSetField(name string, value string) (OfpAction, error) {
+	if name == "vlan_vid" || name == "dl_vlan" {
+		v, err := ofctlUint(value, 12)
+		if err != nil {
+			return nil, fmt.Errorf("value %s of vlan_vid is invalid.", value)
+		}
+		return NewOfpActionSetField(NewOxmVlanVid(uint16(v) | OFPVID_PRESENT)), nil
+	}
+	if name == "nw_tos" {
+		v, err := ofctlUint(value, 8)
+		if err != nil {
+			return nil, fmt.Errorf("value %s of nw_tos is invalid.", value)
+		}
+		return NewOfpActionSetField(NewOxmIpDscp(uint8(v >> 2))), nil
+	}
+	field, ok := ofctlFieldAliases[name]
+	if !ok {
+		return nil, fmt.Errorf("field %s is unknown.", name)
+	}
+	e, _, err := parseOfctlValue(field, value)
+	if err != nil {
+		return nil, err
+	}
+	f := e.oxmField()
+	if f == nil {
+		return nil, fmt.Errorf("field %s is invalid.", name)
+	}
+	return NewOfpActionSetField(f), nil
+}
+
+// mod_* actions of ovs-ofctl and the fields they set
+var ofctlModActions = map[string]string{
+	"mod_dl_src":   "eth_src",
+	"mod_dl_dst":   "eth_dst",
+	"mod_vlan_vid": "vlan_vid",
+	"mod_vlan_pcp": "vlan_pcp",
+	"mod_nw_src":   "ipv4_src",
+	"mod_nw_dst":   "ipv4_dst",
+	"mod_nw_tos":   "nw_tos",
+	"mod_nw_ecn":   "ip_ecn",
+}
+
+/// parse an action. match is used to resolve mod_tp_src and mod_tp_dst, and
+/// may be nil.
+func parseOfctlAction(s string, match *OfpMatch) (OfpAction, error) {
+	name, arg, hasArg := splitOfctl(s, ":")
+	if !hasArg && strings.HasSuffix(s, ")") {
+		name, arg, hasArg = splitOfctl(strings.TrimSuffix(s, ")"), "(")
+	}
+	name = strings.ToLower(name)
+	argUint := func(bits int) (uint64, error) {
+		v, err := ofctlUint(arg, bits)
+		if err != nil || !hasArg {
+			return 0, fmt.Errorf("argument of %s is invalid.", s)
+		}
+		return v, nil
+	}
+
+	if port, ok := ofctlPortNames[name]; ok && name != "any" && name != "none" {
+		if port == OFPP_CONTROLLER && hasArg {
+			maxLen := arg
+			if k, v, ok := splitOfctl(arg, "="); ok && k == "max_len" {
+				maxLen = v
+			}
+			n, err := ofctlUint(maxLen, 16)
+			if err != nil {
+				return nil, fmt.Errorf("argument of %s is invalid.", s)
+			}
+			return NewOfpActionOutput(port, uint16(n)), nil
+		}
+		return NewOfpActionOutput(port, OFPCML_NO_BUFFER), nil
+	}
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil && !hasArg {
+		return NewOfpActionOutput(uint32(n), OFPCML_NO_BUFFER), nil
+	}
+
+	switch name {
+	case "output":
+		port, err := parseOfctlPort(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewOfpActionOutput(port, OFPCML_NO_BUFFER), nil
+	case "group":
+		v, err := argUint(32)
+		return NewOfpActionGroup(uint32(v)), err
+	case "set_queue":
+		v, err := argUint(32)
+		return NewOfpActionSetQueue(uint32(v)), err
+	case "push_vlan":
+		v, err := argUint(16)
+		return NewOfpActionPush(OFPAT_PUSH_VLAN, uint16(v)), err
+	case "push_mpls":
+		v, err := argUint(16)
+		return NewOfpActionPush(OFPAT_PUSH_MPLS, uint16(v)), err
+	case "push_pbb":
+		v, err := argUint(16)
+		return NewOfpActionPush(OFPAT_PUSH_PBB, uint16(v)), err
+	case "pop_vlan", "strip_vlan":
+		return NewOfpActionPopVlan(0), nil
+	case "pop_mpls":
+		v, err := argUint(16)
+		return NewOfpActionPopMpls(uint16(v)), err
+	case "pop_pbb":
+		return NewOfpActionPopPbb(0), nil
+	case "set_mpls_ttl":
+		v, err := argUint(8)
+		return NewOfpActionSetMplsTtl(uint8(v)), err
+	case "dec_mpls_ttl":
+		return NewOfpActionDecMplsTtl(), nil
+	case "mod_nw_ttl", "set_nw_ttl":
+		v, err := argUint(8)
+		return NewOfpActionSetNwTtl(uint8(v)), err
+	case "dec_ttl":
+		return NewOfpActionDecNwTtl(), nil
+	case "copy_ttl_out":
+		return NewOfpActionCopyTtlOut(), nil
+	case "copy_ttl_in":
+		return NewOfpActionCopyTtlIn(), nil
+	case "set_field":
+		value, field, ok := splitOfctl(arg, "->")
+		if !ok {
+			return nil, fmt.Errorf("set_field %s has no field.", arg)
+		}
+		return parseOfctlSetField(strings.ToLower(field), value)
+	case "mod_tp_src", "mod_tp_dst":
+		proto := uint8(0)
+		if match != nil {
+			proto, _ = match.IpProto()
+		}
+		field := map[uint8]string{6: "tcp", 17: "udp", 132: "sctp"}[proto]
+		if field == "" {
+			return nil, fmt.Errorf("%s requires tcp, udp or sctp.", name)
+		}
+		return parseOfctlSetField(field+strings.TrimPrefix(name, "mod_tp"), arg)
+	}
+	if field, ok := ofctlModActions[name]; ok {
+		return parseOfctlSetField(field, arg)
+	}
+	return nil, fmt.Errorf("action %s is unknown.", name)
+}
+
+func parseOfctlActions(s string, match *OfpMatch) ([]OfpAction, error) {
+	actions := make([]OfpAction, 0)
+	for _, token := range splitOfctlList(s) {
+		if strings.ToLower(token) == "drop" {
+			continue
+		}
+		a, err := parseOfctlAction(token, match)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+/// ParseOfctlActions parses list of actions in ovs-ofctl syntax, such as
+/// "push_vlan:0x8100,set_field:10->vlan_vid,output:2".
+func ParseOfctlActions(s string) ([]OfpAction, error) {
+	return parseOfctlActions(s, nil)
+}
+
+/// ParseOfctlInstructions parses actions= of flow in ovs-ofctl syntax.
+/// Actions are of apply-actions instruction, and meter, clear_actions,
+/// write_actions(...), write_metadata and goto_table are instructions.
+/// "drop" results in no instruction.
+func ParseOfctlInstructions(s string) ([]OfpInstruction, error) {
+	return parseOfctlInstructions(s, nil)
+}
+
+func parseOfctlInstructions(s string, match *OfpMatch) ([]OfpInstruction, error) {
+	var apply *OfpInstructionActions
+	instructions := make([]OfpInstruction, 0)
+	for _, token := range splitOfctlList(s) {
+		name, arg, _ := splitOfctl(token, ":")
+		var inst OfpInstruction
+		switch strings.ToLower(name) {
+		case "meter":
+			v, err := ofctlUint(arg, 32)
+			if err != nil {
+				return nil, fmt.Errorf("argument of %s is invalid.", token)
+			}
+			inst = NewOfpInstructionMeter(uint32(v))
+		case "goto_table":
+			v, err := ofctlUint(arg, 8)
+			if err != nil {
+				return nil, fmt.Errorf("argument of %s is invalid.", token)
+			}
+			inst = NewOfpInstructionGotoTable(uint8(v))
+		case "write_metadata":
+			value, mask, masked := splitOfctl(arg, "/")
+			v, err := ofctlUint(value, 64)
+			m := uint64(0xffffffffffffffff)
+			if err == nil && masked {
+				m, err = ofctlUint(mask, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("argument of %s is invalid.", token)
+			}
+			inst = NewOfpInstructionWriteMetadata(v, m)
+		case "clear_actions":
+			inst = NewOfpInstructionActions(OFPIT_CLEAR_ACTIONS)
+		default:
+			if strings.HasPrefix(strings.ToLower(token), "write_actions(") && strings.HasSuffix(token, ")") {
+				actions, err := parseOfctlActions(token[len("write_actions("):len(token)-1], match)
+				if err != nil {
+					return nil, err
+				}
+				write := NewOfpInstructionActions(OFPIT_WRITE_ACTIONS)
+				write.Actions = actions
+				inst = write
+				break
+			}
+			actions, err := parseOfctlActions(token, match)
+			if err != nil {
+				return nil, err
+			}
+			if len(actions) == 0 {
+				// drop
+				continue
+			}
+			if apply == nil {
+				apply = NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+				instructions = append(instructions, apply)
+			}
+			apply.Actions = append(apply.Actions, actions...)
+			continue
+		}
+		instructions = append(instructions, inst)
+	}
+	return instructions, nil
+}
+
+/// ofctl string of set-field action
+func ofctlSetFieldString(oxm OxmField) string {
+	e := newOxmEntry(oxm)
+	if e.opaque != nil || int(e.field) >= len(ofctlFields) {
+		return "set_field:" + fmt.Sprintf("0x%x", e.value)
+	}
+	if e.field == OFPXMT_OFB_VLAN_VID && e.mask == nil {
+		return fmt.Sprintf("set_field:%d->vlan_vid", oxmValue(e.value)&^OFPVID_PRESENT)
+	}
+	return "set_field:" + ofctlValueString(e) + "->" + ofctlFields[e.field].name
+}
+
+func ofctlActionString(action OfpAction) string {
+	switch a := action.(type) {
+	case *OfpActionOutput:
+		switch a.Port {
+		case OFPP_CONTROLLER:
+			return fmt.Sprintf("CONTROLLER:%d", a.MaxLen)
+		case OFPP_IN_PORT, OFPP_NORMAL, OFPP_FLOOD, OFPP_ALL, OFPP_LOCAL:
+			return ofctlPortString(a.Port)
+		}
+		return "output:" + ofctlPortString(a.Port)
+	case *OfpActionGroup:
+		return fmt.Sprintf("group:%d", a.GroupId)
+	case *OfpActionSetQueue:
+		return fmt.Sprintf("set_queue:%d", a.QueueId)
+	case *OfpActionPush:
+		switch a.ActionHeader.Type {
+		case OFPAT_PUSH_VLAN:
+			return fmt.Sprintf("push_vlan:0x%04x", a.EtherType)
+		case OFPAT_PUSH_MPLS:
+			return fmt.Sprintf("push_mpls:0x%04x", a.EtherType)
+		}
+		return fmt.Sprintf("push_pbb:0x%04x", a.EtherType)
+	case *OfpActionPop:
+		switch a.ActionHeader.Type {
+		case OFPAT_POP_VLAN:
+			return "pop_vlan"
+		case OFPAT_POP_MPLS:
+			return fmt.Sprintf("pop_mpls:0x%04x", a.EtherType)
+		}
+		return "pop_pbb"
+	case *OfpActionSetMplsTtl:
+		return fmt.Sprintf("set_mpls_ttl:%d", a.MplsTtl)
+	case *OfpActionDecMplsTtl:
+		return "dec_mpls_ttl"
+	case *OfpActionSetNwTtl:
+		return fmt.Sprintf("mod_nw_ttl:%d", a.NwTtl)
+	case *OfpActionDecNwTtl:
+		return "dec_ttl"
+	case *OfpActionCopyTtlOut:
+		return "copy_ttl_out"
+	case *OfpActionCopyTtlIn:
+		return "copy_ttl_in"
+	case *OfpActionSetField:
+		return ofctlSetFieldString(a.Oxm)
+	case *OfpActionExperimenter:
+		return fmt.Sprintf("experimenter:0x%x", a.Experimenter)
+	}
+	return fmt.Sprintf("unknown:%d", action.OfpActionType())
+}
+
+/// OfctlActionsString returns list of actions in ovs-ofctl syntax, or
+/// "drop" for empty list.
+func OfctlActionsString(actions []OfpAction) string {
+	if len(actions) == 0 {
+		return "drop"
+	}
+	list := make([]string, len(actions))
+	for i, a := range actions {
+		list[i] = ofctlActionString(a)
+	}
+	return strings.Join(list, ",")
+}
+
+/// OfctlInstructionsString returns instructions as actions= of flow in
+/// ovs-ofctl syntax. They are printed in the order of execution.
+func OfctlInstructionsString(instructions []OfpInstruction) string {
+	sorted := append([]OfpInstruction(nil), instructions...)
+	order := map[uint16]int{OFPIT_METER: 0, OFPIT_APPLY_ACTIONS: 1, OFPIT_CLEAR_ACTIONS: 2,
+		OFPIT_WRITE_ACTIONS: 3, OFPIT_WRITE_METADATA: 4, OFPIT_GOTO_TABLE: 5}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order[sorted[i].InstructionType()] < order[sorted[j].InstructionType()]
+	})
+
+	var list []string
+	for _, inst := range sorted {
+		switch i := inst.(type) {
+		case *OfpInstructionMeter:
+			list = append(list, fmt.Sprintf("meter:%d", i.MeterId))
+		case *OfpInstructionActions:
+			switch i.Header.Type {
+			case OFPIT_APPLY_ACTIONS:
+				if len(i.Actions) > 0 {
+					list = append(list, OfctlActionsString(i.Actions))
+				}
+			case OFPIT_CLEAR_ACTIONS:
+				list = append(list, "clear_actions")
+			case OFPIT_WRITE_ACTIONS:
+				list = append(list, "write_actions("+OfctlActionsString(i.Actions)+")")
+			}
+		case *OfpInstructionWriteMetadata:
+			list = append(list, fmt.Sprintf("write_metadata:0x%x/0x%x", i.Metadata, i.MetadataMask))
+		case *OfpInstructionGotoTable:
+			list = append(list, fmt.Sprintf("goto_table:%d", i.TableId))
+		case *OfpInstructionExperimenter:
+			list = append(list, fmt.Sprintf("experimenter:0x%x", i.Experimenter))
+		}
+	}
+	if len(list) == 0 {
+		return "drop"
+	}
+	return strings.Join(list, ",")
+}
+
+/*****************************************************/
+/* Flow                                              */
+/*****************************************************/
+
+var ofctlFlowFlags = []struct {
+	name string
+	flag uint16
+}{
+	{"send_flow_rem", OFPFF_SEND_FLOW_REM},
+	{"check_overlap", OFPFF_CHECK_OVERLAP},
+	{"reset_counts", OFPFF_RESET_COUNTS},
+	{"no_packet_counts", OFPFF_NO_PKT_COUNTS},
+	{"no_byte_counts", OFPFF_NO_BYT_COUNTS},
+}
+
+// fields of dump-flows output, which are ignored by ParseOfctlFlow
+var ofctlStatsKeys = map[string]bool{
+	"duration": true, "n_packets": true, "n_bytes": true, "idle_age": true, "hard_age": true,
+}
+
+/// ParseOfctlFlow parses flow in ovs-ofctl syntax of add-flow, such as
+/// "table=0,priority=100,in_port=1,dl_type=0x0800,nw_dst=10.0.0.0/8,
+/// actions=push_vlan:0x8100,set_field:10->vlan_vid,output:2".
+/// Lines of dump-flows output are accepted too, whose counters are ignored.
+/// The command of returned FlowMod is OFPFC_ADD, and priority is
+/// OFP_DEFAULT_PRIORITY if not specified.
+func ParseOfctlFlow(s string) (*OfpFlowMod, error) {
+	flow, actions := s, ""
+	for i := strings.Index(s, "actions="); i >= 0; {
+		if i == 0 || strings.ContainsAny(s[i-1:i], ", \t") {
+			flow, actions = s[:i], s[i+len("actions="):]
+			break
+		}
+		next := strings.Index(s[i+1:], "actions=")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	m := NewOfpFlowModAdd(0, 0, 0, OFP_DEFAULT_PRIORITY, 0, nil, nil)
+	p := newOfctlMatchParser()
+	for _, token := range splitOfctlList(flow) {
+		key, value, hasValue := splitOfctl(token, "=")
+		key = strings.ToLower(key)
+		if ofctlStatsKeys[key] {
+			continue
+		}
+		var v uint64
+		var err error
+		switch key {
+		case "table":
+			v, err = ofctlUint(value, 8)
+			m.TableId = uint8(v)
+		case "priority":
+			v, err = ofctlUint(value, 16)
+			m.Priority = uint16(v)
+		case "idle_timeout":
+			v, err = ofctlUint(value, 16)
+			m.IdleTimeout = uint16(v)
+		case "hard_timeout":
+			v, err = ofctlUint(value, 16)
+			m.HardTimeout = uint16(v)
+		case "cookie":
+			cookie, mask, masked := splitOfctl(value, "/")
+			m.Cookie, err = ofctlUint(cookie, 64)
+			if err == nil && masked {
+				m.CookieMask, err = ofctlUint(mask, 64)
+			}
+		case "out_port":
+			m.OutPort, err = parseOfctlPort(value)
+		case "out_group":
+			v, err = ofctlUint(value, 32)
+			if strings.ToLower(value) == "any" {
+				v, err = OFPG_ANY, nil
+			}
+			m.OutGroup = uint32(v)
+		default:
+			flag := uint16(0)
+			for _, f := range ofctlFlowFlags {
+				if f.name == key && !hasValue {
+					flag = f.flag
+				}
+			}
+			if flag != 0 {
+				m.Flags |= flag
+				continue
+			}
+			ok, err := p.parse(key, value, hasValue)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("field %s is unknown.", key)
+			}
+			continue
+		}
+		if err != nil || !hasValue {
+			return nil, fmt.Errorf("value of %s is invalid.", key)
+		}
+	}
+
+	match, err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+	m.Match = match
+	if m.Instructions, err = parseOfctlInstructions(actions, match); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// flow parameters, match and actions common to FlowMod and FlowStats
+func ofctlFlowString(head []string, flags uint16, match *OfpMatch, instructions []OfpInstruction,
+	withActions bool, sep string) string {
+	list := head
+	for _, f := range ofctlFlowFlags {
+		if flags&f.flag != 0 {
+			list = append(list, f.name)
+		}
+	}
+	if s := match.OfctlString(); s != "" {
+		list = append(list, s)
+	}
+	s := strings.Join(list, ",")
+	if withActions {
+		s += sep + "actions=" + OfctlInstructionsString(instructions)
+	}
+	return s
+}
+
+/// OfctlString returns FlowMod in ovs-ofctl syntax, which is parsed by
+/// ParseOfctlFlow. Actions are omitted for delete commands.
+func (m *OfpFlowMod) OfctlString() string {
+	head := []string{fmt.Sprintf("table=%d", m.TableId), fmt.Sprintf("priority=%d", m.Priority)}
+	if m.CookieMask != 0 {
+		head = append(head, fmt.Sprintf("cookie=0x%x/0x%x", m.Cookie, m.CookieMask))
+	} else if m.Cookie != 0 {
+		head = append(head, fmt.Sprintf("cookie=0x%x", m.Cookie))
+	}
+	if m.IdleTimeout != 0 {
+		head = append(head, fmt.Sprintf("idle_timeout=%d", m.IdleTimeout))
+	}
+	if m.HardTimeout != 0 {
+		head = append(head, fmt.Sprintf("hard_timeout=%d", m.HardTimeout))
+	}
+	if m.OutPort != OFPP_ANY && m.OutPort != 0 {
+		head = append(head, "out_port="+ofctlPortString(m.OutPort))
+	}
+	if m.OutGroup != OFPG_ANY && m.OutGroup != 0 {
+		head = append(head, fmt.Sprintf("out_group=%d", m.OutGroup))
+	}
+	deleting := m.Command == OFPFC_DELETE || m.Command == OFPFC_DELETE_STRICT
+	return ofctlFlowString(head, m.Flags, m.Match, m.Instructions, !deleting, ",")
+}
+
+/// OfctlString returns flow stats in the format of ovs-ofctl dump-flows,
+/// such as "cookie=0x0, duration=1.500s, table=0, n_packets=0, n_bytes=0,
+/// priority=100,in_port=1 actions=output:2". It is parsed by
+/// ParseOfctlFlow, so that flows of the switch can be compared with flows
+/// of controller.
+func (s *OfpFlowStats) OfctlString() string {
+	head := fmt.Sprintf("cookie=0x%x, duration=%d.%03ds, table=%d, n_packets=%d, n_bytes=%d, ",
+		s.Cookie, s.DurationSec, s.DurationNSec/1000000, s.TableId, s.PacketCount, s.ByteCount)
+	params := []string{}
+	if s.IdleTimeout != 0 {
+		params = append(params, fmt.Sprintf("idle_timeout=%d", s.IdleTimeout))
+	}
+	if s.HardTimeout != 0 {
+		params = append(params, fmt.Sprintf("hard_timeout=%d", s.HardTimeout))
+	}
+	params = append(params, fmt.Sprintf("priority=%d", s.Priority))
+	return head + ofctlFlowString(params, s.Flags, s.Match, s.Instructions, true, " ")
+}
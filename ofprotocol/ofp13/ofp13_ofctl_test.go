@@ -0,0 +1,210 @@
+package ofp13
+
+import (
+	"bytes"
+	"testing"
+)
+
+/*****************************************************/
+/* ovs-ofctl Flow                                    */
+/*****************************************************/
+func TestParseOfctlFlow(t *testing.T) {
+	s := "table=0,priority=100,in_port=1,dl_type=0x0800,nw_dst=10.0.0.0/8," +
+		"actions=push_vlan:0x8100,set_field:10->vlan_vid,output:2"
+	fm, err := ParseOfctlFlow(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipDst, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	match := matchOf(NewOxmInPort(1), NewOxmEthType(0x0800), ipDst)
+	apply := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+	apply.Append(NewOfpActionPushVlan())
+	apply.Append(NewOfpActionSetField(NewOxmVlanVid(OFPVID_PRESENT | 10)))
+	apply.Append(NewOfpActionOutput(2, OFPCML_NO_BUFFER))
+	expected := NewOfpFlowModAdd(0, 0, 0, 100, 0, match, []OfpInstruction{apply})
+	expected.Header.Xid = fm.Header.Xid
+	if !bytes.Equal(fm.Serialize(), expected.Serialize()) {
+		t.Log("Expected flow is : ", expected.Serialize())
+		t.Log("Actual flow is   : ", fm.Serialize())
+		t.Error("Parsed flow is invalid.")
+	}
+
+	if actual := fm.OfctlString(); actual != s {
+		t.Log("Actual string is : ", actual)
+		t.Error("Flow string is not round-tripped.")
+	}
+}
+
+func TestParseOfctlFlowParams(t *testing.T) {
+	fm, err := ParseOfctlFlow("tcp,tp_dst=80 cookie=0x10/0xff idle_timeout=30 hard_timeout=60 " +
+		"send_flow_rem out_port=LOCAL " +
+		"actions=meter:1,dec_ttl,write_actions(group:3),write_metadata:0x1/0xff,goto_table:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.Priority != OFP_DEFAULT_PRIORITY || fm.Cookie != 0x10 || fm.CookieMask != 0xff ||
+		fm.IdleTimeout != 30 || fm.HardTimeout != 60 || fm.Flags != OFPFF_SEND_FLOW_REM ||
+		fm.OutPort != OFPP_LOCAL {
+		t.Log("Actual flow is : ", fm)
+		t.Error("Parameters of flow are invalid.")
+	}
+	if port, ok := fm.Match.TcpDst(); !ok || port != 80 {
+		t.Error("tp_dst of tcp is not parsed to tcp_dst.")
+	}
+	if len(fm.Instructions) != 5 {
+		t.Fatal("Number of instructions is invalid.")
+	}
+
+	expected := "table=0,priority=32768,cookie=0x10/0xff,idle_timeout=30,hard_timeout=60," +
+		"out_port=LOCAL,send_flow_rem,dl_type=0x0800,nw_proto=6,tcp_dst=80," +
+		"actions=meter:1,dec_ttl,write_actions(group:3),write_metadata:0x1/0xff,goto_table:2"
+	if actual := fm.OfctlString(); actual != expected {
+		t.Log("Actual string is : ", actual)
+		t.Error("Flow string is invalid.")
+	}
+	if _, err := ParseOfctlFlow(expected); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseOfctlDumpFlows(t *testing.T) {
+	s := "cookie=0x0, duration=12.345s, table=1, n_packets=3, n_bytes=180, " +
+		"idle_timeout=10, idle_age=2, priority=10,arp,nw_dst=10.0.0.1 actions=FLOOD"
+	fm, err := ParseOfctlFlow(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.TableId != 1 || fm.Priority != 10 || fm.IdleTimeout != 10 {
+		t.Log("Actual flow is : ", fm)
+		t.Error("Parameters of dumped flow are invalid.")
+	}
+	if addr, _, ok := fm.Match.ArpTpa(); !ok || addr.String() != "10.0.0.1" {
+		t.Error("nw_dst of arp is not parsed to arp_tpa.")
+	}
+
+	stats := &OfpFlowStats{TableId: 1, DurationSec: 12, DurationNSec: 345000000,
+		Priority: 10, IdleTimeout: 10, PacketCount: 3, ByteCount: 180,
+		Match: fm.Match, Instructions: fm.Instructions}
+	expected := "cookie=0x0, duration=12.345s, table=1, n_packets=3, n_bytes=180, " +
+		"idle_timeout=10,priority=10,dl_type=0x0806,arp_tpa=10.0.0.1 actions=FLOOD"
+	if actual := stats.OfctlString(); actual != expected {
+		t.Log("Actual string is : ", actual)
+		t.Error("Flow stats string is invalid.")
+	}
+}
+
+/*****************************************************/
+/* ovs-ofctl Match and Actions                       */
+/*****************************************************/
+func TestParseOfctlMatch(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected string
+	}{
+		{"ip,nw_src=10.1.2.3/255.255.0.0", "dl_type=0x0800,nw_src=10.1.0.0/16"},
+		{"dl_vlan=10,dl_vlan_pcp=3", "dl_vlan=10,dl_vlan_pcp=3"},
+		{"dl_vlan=0xffff", "vlan_tci=0x0000/0x1fff"},
+		{"vlan_tci=0x1000/0x1000", "vlan_vid=0x1000/0x1000"},
+		{"dl_dst=01:00:00:00:00:00/01:00:00:00:00:00", "dl_dst=01:00:00:00:00:00/01:00:00:00:00:00"},
+		{"ipv6,ipv6_dst=2001:db8::1/32", "dl_type=0x86dd,ipv6_dst=2001:db8::/32"},
+		{"icmp6,icmp_type=135,nd_target=2001:db8::1", "dl_type=0x86dd,nw_proto=58,icmpv6_type=135,nd_target=2001:db8::1"},
+		{"ip,nw_tos=16,nw_src=10.0.0.0/0", "dl_type=0x0800,ip_dscp=4"},
+		{"in_port=LOCAL,tun_id=0x10,metadata=0x1/0xf", "in_port=LOCAL,metadata=0x1/0xf,tun_id=0x10"},
+	}
+	for _, c := range cases {
+		match, err := ParseOfctlMatch(c.s)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if actual := match.OfctlString(); actual != c.expected {
+			t.Log("Actual string is : ", actual)
+			t.Errorf("Match string of %s is invalid.", c.s)
+		}
+		if err := match.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, s := range []string{"nw_dst=10.0.0.300", "tp_dst=80", "foo=1", "ip,dl_type=0x86dd", "in_port=1/1"} {
+		if _, err := ParseOfctlMatch(s); err == nil {
+			t.Errorf("Error of %s is not reported.", s)
+		}
+	}
+}
+
+func TestParseOfctlMatchOrder(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected []uint32
+	}{
+		{"tcp_dst=80,tcp", []uint32{OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_TCP_DST}},
+		{"tp_dst=80,in_port=1,udp", []uint32{OFPXMT_OFB_IN_PORT, OFPXMT_OFB_ETH_TYPE,
+			OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_UDP_DST}},
+		{"icmp6,icmp_type=135,nd_target=fe80::1", []uint32{OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_PROTO,
+			OFPXMT_OFB_ICMPV6_TYPE, OFPXMT_OFB_IPV6_ND_TARGET}},
+	}
+	for _, c := range cases {
+		match, err := ParseOfctlMatch(c.s)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		actual := make([]uint32, len(match.OxmFields))
+		for i, f := range match.OxmFields {
+			actual[i] = f.OxmField()
+		}
+		if len(actual) != len(c.expected) {
+			t.Log("Actual fields are : ", actual)
+			t.Errorf("Fields of %s are invalid.", c.s)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != c.expected[i] {
+				t.Log("Actual fields are : ", actual)
+				t.Errorf("Prerequisites of %s are not ordered.", c.s)
+				break
+			}
+		}
+		if err := match.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestParseOfctlActions(t *testing.T) {
+	s := "mod_dl_dst:00:11:22:33:44:55,set_field:10.0.0.1->ip_dst,pop_vlan," +
+		"push_mpls:0x8847,set_mpls_ttl:64,CONTROLLER:128,in_port,5,group:1"
+	actions, err := ParseOfctlActions(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "set_field:00:11:22:33:44:55->dl_dst,set_field:10.0.0.1->nw_dst,pop_vlan," +
+		"push_mpls:0x8847,set_mpls_ttl:64,CONTROLLER:128,IN_PORT,output:5,group:1"
+	if actual := OfctlActionsString(actions); actual != expected {
+		t.Log("Actual string is : ", actual)
+		t.Error("Actions string is invalid.")
+	}
+	if a, ok := actions[5].(*OfpActionOutput); !ok || a.Port != OFPP_CONTROLLER || a.MaxLen != 128 {
+		t.Error("Controller action is invalid.")
+	}
+
+	if actual := OfctlActionsString(nil); actual != "drop" {
+		t.Log("Actual string is : ", actual)
+		t.Error("Empty actions are not drop.")
+	}
+	instructions, err := ParseOfctlInstructions("drop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instructions) != 0 {
+		t.Log("Actual instructions are : ", instructions)
+		t.Error("drop is parsed to instructions.")
+	}
+	for _, s := range []string{"output", "mod_tp_dst:80", "set_field:1", "foo:1"} {
+		if _, err := ParseOfctlActions(s); err == nil {
+			t.Errorf("Error of %s is not reported.", s)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package ofp13
+
+import (
+	"github.com/Kmotiko/gofc/packet"
+)
+
+/*****************************************************/
+/* Packet                                            */
+/*****************************************************/
+
+/// decode ethernet frame of PacketIn. if the frame is truncated or
+/// malformed, it returns the layers decoded so far with error.
+/// layers refer the data of message without copy.
+func (m *OfpPacketIn) Packet() (*packet.Packet, error) {
+	return packet.Decode(m.GetData())
+}
+
+/// return in_port of match field, or 0 if it is not included.
+func (m *OfpPacketIn) InPort() uint32 {
+	inPort, _ := m.match().InPort()
+	return inPort
+}
+
+/// layers of fields derived from packet by NewOfpMatchFromPacketIn.
+/// prerequisites of selected fields, such as eth_type of ip addresses and
+/// ip_proto of ports, are always included.
+const (
+	// in_port
+	MATCH_IN_PORT = 1 << iota
+	// eth_dst, eth_src, eth_type, vlan_vid and vlan_pcp
+	MATCH_L2
+	// ip, arp and mpls fields
+	MATCH_L3
+	// tcp, udp and sctp ports, icmp and ipv6 nd fields
+	MATCH_L4
+	// ip_proto, ip addresses and tcp, udp and sctp ports
+	MATCH_5TUPLE
+
+	MATCH_ALL = MATCH_IN_PORT | MATCH_L2 | MATCH_L3 | MATCH_L4
+)
+
+/// create OfpMatch which exactly matches all fields of pkt received on
+/// inPort. fields are appended in order of prerequisites, and fields of
+/// layers which the switch can't match, such as ip over mpls, are omitted.
+/// inPort is omitted if it is 0.
+func NewOfpMatchFromPacket(inPort uint32, pkt *packet.Packet) *OfpMatch {
+	return newOfpMatchFromPacket(inPort, pkt, MATCH_ALL)
+}
+
+/// create OfpMatch which exactly matches fields of layers of the packet in
+/// PacketIn, such as MATCH_IN_PORT|MATCH_5TUPLE. if the packet can't be
+/// decoded entirely, it returns the match of layers decoded so far with
+/// error.
+func NewOfpMatchFromPacketIn(msg *OfpPacketIn, layers int) (*OfpMatch, error) {
+	pkt, err := msg.Packet()
+	return newOfpMatchFromPacket(msg.InPort(), pkt, layers), err
+}
+
+func newOfpMatchFromPacket(inPort uint32, pkt *packet.Packet, layers int) *OfpMatch {
+	match := NewOfpMatch()
+	add := func(in int, f OxmField) {
+		if layers&in != 0 {
+			match.Append(f)
+		}
+	}
+	if inPort != 0 {
+		add(MATCH_IN_PORT, NewOxmInPort(inPort))
+	}
+	eth := pkt.Ethernet
+	if eth == nil {
+		return match
+	}
+
+	// l2
+	dst, _ := NewOxmEthDst(eth.Dst.String())
+	src, _ := NewOxmEthSrc(eth.Src.String())
+	add(MATCH_L2, dst)
+	add(MATCH_L2, src)
+	add(MATCH_L2|MATCH_L3|MATCH_L4|MATCH_5TUPLE, NewOxmEthType(eth.EtherType))
+	if len(pkt.Vlans) > 0 {
+		vlan := pkt.Vlans[0]
+		add(MATCH_L2, NewOxmVlanVid(OFPVID_PRESENT|vlan.VID))
+		add(MATCH_L2, NewOxmVlanPcp(vlan.Priority))
+	} else {
+		add(MATCH_L2, NewOxmVlanVid(OFPVID_NONE))
+	}
+
+	switch eth.EtherType {
+	case packet.ETH_TYPE_MPLS, packet.ETH_TYPE_MPLSMC:
+		if len(pkt.Mpls) > 0 {
+			label := pkt.Mpls[0]
+			var bos uint8
+			if label.BoS {
+				bos = 1
+			}
+			add(MATCH_L3, NewOxmMplsLabel(label.Label))
+			add(MATCH_L3, NewOxmMplsTc(label.TC))
+			add(MATCH_L3, NewOxmMplsBos(bos))
+		}
+		return match
+	case packet.ETH_TYPE_ARP:
+		if arp := pkt.Arp; arp != nil {
+			spa, _ := NewOxmArpSpa(arp.SPA.String())
+			tpa, _ := NewOxmArpTpa(arp.TPA.String())
+			sha, _ := NewOxmArpSha(arp.SHA.String())
+			tha, _ := NewOxmArpTha(arp.THA.String())
+			add(MATCH_L3, NewOxmArpOp(arp.Op))
+			add(MATCH_L3, spa)
+			add(MATCH_L3, tpa)
+			add(MATCH_L3, sha)
+			add(MATCH_L3, tha)
+		}
+		return match
+	}
+
+	// l3
+	switch {
+	case pkt.Ipv4 != nil && eth.EtherType == packet.ETH_TYPE_IPV4:
+		ip := pkt.Ipv4
+		src, _ := NewOxmIpv4Src(ip.Src.String())
+		dst, _ := NewOxmIpv4Dst(ip.Dst.String())
+		add(MATCH_L3, NewOxmIpDscp(ip.DSCP))
+		add(MATCH_L3, NewOxmIpEcn(ip.ECN))
+		add(MATCH_L3|MATCH_L4|MATCH_5TUPLE, NewOxmIpProto(ip.Protocol))
+		add(MATCH_L3|MATCH_5TUPLE, src)
+		add(MATCH_L3|MATCH_5TUPLE, dst)
+	case pkt.Ipv6 != nil && eth.EtherType == packet.ETH_TYPE_IPV6:
+		ip := pkt.Ipv6
+		src, _ := NewOxmIpv6Src(ip.Src.String())
+		dst, _ := NewOxmIpv6Dst(ip.Dst.String())
+		add(MATCH_L3, NewOxmIpDscp(ip.DSCP))
+		add(MATCH_L3, NewOxmIpEcn(ip.ECN))
+		add(MATCH_L3|MATCH_L4|MATCH_5TUPLE, NewOxmIpProto(ip.Protocol))
+		add(MATCH_L3|MATCH_5TUPLE, src)
+		add(MATCH_L3|MATCH_5TUPLE, dst)
+		add(MATCH_L3, NewOxmIpv6FLabel(ip.FlowLabel))
+		add(MATCH_L3, NewOxmIpv6ExtHeader(ip.ExtHeaderFlags()))
+	default:
+		return match
+	}
+
+	// l4
+	switch {
+	case pkt.Tcp != nil:
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmTcpSrc(pkt.Tcp.SrcPort))
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmTcpDst(pkt.Tcp.DstPort))
+	case pkt.Udp != nil:
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmUdpSrc(pkt.Udp.SrcPort))
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmUdpDst(pkt.Udp.DstPort))
+	case pkt.Sctp != nil:
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmSctpSrc(pkt.Sctp.SrcPort))
+		add(MATCH_L4|MATCH_5TUPLE, NewOxmSctpDst(pkt.Sctp.DstPort))
+	case pkt.Icmpv4 != nil:
+		add(MATCH_L4, NewOxmIcmpType(pkt.Icmpv4.Type))
+		add(MATCH_L4, NewOxmIcmpCode(pkt.Icmpv4.Code))
+	case pkt.Icmpv6 != nil:
+		icmp := pkt.Icmpv6
+		add(MATCH_L4, NewOxmIcmpv6Type(icmp.Type))
+		add(MATCH_L4, NewOxmIcmpv6Code(icmp.Code))
+		if icmp.Target != nil {
+			target, _ := NewOxmIpv6NdTarget(icmp.Target.String())
+			add(MATCH_L4, target)
+		}
+		if icmp.SLL != nil {
+			sll, _ := NewOxmIpv6NdSll(icmp.SLL.String())
+			add(MATCH_L4, sll)
+		}
+		if icmp.TLL != nil {
+			tll, _ := NewOxmIpv6NdTll(icmp.TLL.String())
+			add(MATCH_L4, tll)
+		}
+	}
+	return match
+}
+
+/// create PacketOut which sends pkt out of the port on which PacketIn was
+/// received, such as arp reply to the arp request in PacketIn.
+/// in_port of PacketOut is OFPP_CONTROLLER, since pkt is created by the
+/// controller.
+func NewOfpPacketOutReply(msg *OfpPacketIn, pkt *packet.Packet) (*OfpPacketOut, error) {
+	data, err := pkt.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	actions := []OfpAction{NewOfpActionOutput(msg.InPort(), 0)}
+	return NewOfpPacketOut(OFP_NO_BUFFER, OFPP_CONTROLLER, actions, data), nil
+}
+
+/// create PacketOut which applies actions to the packet of PacketIn.
+/// buffer_id of PacketIn is reused if the packet is buffered on the switch,
+/// otherwise the data is copied into PacketOut. in_port is kept, so that
+/// output to OFPP_IN_PORT and OFPP_FLOOD work as for the received packet.
+func NewOfpPacketOutForward(msg *OfpPacketIn, actions []OfpAction) *OfpPacketOut {
+	var data []byte
+	if msg.BufferId == OFP_NO_BUFFER {
+		data = append([]byte(nil), msg.GetData()...)
+	}
+	return NewOfpPacketOut(msg.BufferId, msg.InPort(), actions, data)
+}
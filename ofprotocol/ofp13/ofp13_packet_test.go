@@ -0,0 +1,186 @@
+package ofp13
+
+import (
+	"testing"
+)
+
+// udp over ipv4 frame tagged with vlan 10 and priority 3
+func newTestVlanUdpFrame() []byte {
+	return []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // eth_dst
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // eth_src
+		0x81, 0x00, 0x60, 0x0a, // vlan
+		0x08, 0x00, // eth_type
+		0x45, 0x29, 0x00, 0x1c, 0x00, 0x00, 0x00, 0x00, // ipv4, dscp 10, ecn 1
+		0x40, 0x11, 0x00, 0x00, // ttl, udp
+		0x0a, 0x00, 0x00, 0x01, // ipv4_src
+		0x0a, 0x00, 0x00, 0x02, // ipv4_dst
+		0x27, 0x10, 0x00, 0x35, 0x00, 0x08, 0x00, 0x00, // udp
+	}
+}
+
+/*****************************************************/
+/* Packet                                            */
+/*****************************************************/
+func TestPacketInPacket(t *testing.T) {
+	msg := NewOfpPacketIn()
+	msg.Match = NewOfpMatch()
+	msg.Match.Append(NewOxmInPort(3))
+	msg.Data = newTestVlanUdpFrame()
+
+	pkt, err := msg.Packet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkt.String() != "Ethernet/Dot1Q/IPv4/UDP" || pkt.Udp.DstPort != 53 {
+		t.Log("Actual packet is : ", pkt)
+		t.Error("Packet is invalid.")
+	}
+	if msg.InPort() != 3 {
+		t.Log("Actual in_port is : ", msg.InPort())
+		t.Error("InPort is invalid.")
+	}
+}
+
+func TestNewOfpMatchFromPacket(t *testing.T) {
+	msg := NewOfpPacketIn()
+	msg.Data = newTestVlanUdpFrame()
+	pkt, _ := msg.Packet()
+	match := NewOfpMatchFromPacket(3, pkt)
+
+	expected := []uint32{
+		OFPXMT_OFB_IN_PORT, OFPXMT_OFB_ETH_DST, OFPXMT_OFB_ETH_SRC, OFPXMT_OFB_ETH_TYPE,
+		OFPXMT_OFB_VLAN_VID, OFPXMT_OFB_VLAN_PCP, OFPXMT_OFB_IP_DSCP, OFPXMT_OFB_IP_ECN,
+		OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_IPV4_SRC, OFPXMT_OFB_IPV4_DST,
+		OFPXMT_OFB_UDP_SRC, OFPXMT_OFB_UDP_DST,
+	}
+	if len(match.OxmFields) != len(expected) {
+		t.Log("Actual match is : ", match)
+		t.Fatal("Number of fields is invalid.")
+	}
+	for i, f := range match.OxmFields {
+		if f.OxmField() != expected[i] || f.OxmHasMask() != 0 {
+			t.Log("Actual field is : ", f)
+			t.Errorf("Field %d is expected to be %d.", i, expected[i])
+		}
+	}
+
+	vid := match.OxmFields[4].(*OxmVlanVid)
+	ipDst := match.OxmFields[10].(*OxmIpv4)
+	if vid.Value != OFPVID_PRESENT|10 || ipDst.Value.String() != "10.0.0.2" {
+		t.Log("Actual vlan_vid is : ", vid.Value)
+		t.Log("Actual ipv4_dst is : ", ipDst.Value)
+		t.Error("Values of fields are invalid.")
+	}
+
+	// untagged arp
+	msg.Data = []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // eth_dst
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // eth_src
+		0x08, 0x06, // eth_type
+		0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x01, // arp request
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x01, // sha, spa
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x02, // tha, tpa
+	}
+	pkt, err := msg.Packet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	match = NewOfpMatchFromPacket(0, pkt)
+	if len(match.OxmFields) != 9 || match.OxmFields[3].(*OxmVlanVid).Value != OFPVID_NONE {
+		t.Log("Actual match is : ", match)
+		t.Error("Match of arp is invalid.")
+	}
+}
+
+func TestNewOfpMatchFromPacketIn(t *testing.T) {
+	msg := NewOfpPacketIn()
+	msg.Match = NewOfpMatch()
+	msg.Match.Append(NewOxmInPort(3))
+	msg.Data = newTestVlanUdpFrame()
+
+	tests := []struct {
+		layers   int
+		expected []uint32
+	}{
+		{MATCH_IN_PORT | MATCH_L2, []uint32{
+			OFPXMT_OFB_IN_PORT, OFPXMT_OFB_ETH_DST, OFPXMT_OFB_ETH_SRC, OFPXMT_OFB_ETH_TYPE,
+			OFPXMT_OFB_VLAN_VID, OFPXMT_OFB_VLAN_PCP}},
+		{MATCH_L3, []uint32{
+			OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_DSCP, OFPXMT_OFB_IP_ECN, OFPXMT_OFB_IP_PROTO,
+			OFPXMT_OFB_IPV4_SRC, OFPXMT_OFB_IPV4_DST}},
+		{MATCH_L4, []uint32{
+			OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_UDP_SRC, OFPXMT_OFB_UDP_DST}},
+		{MATCH_5TUPLE, []uint32{
+			OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_IPV4_SRC, OFPXMT_OFB_IPV4_DST,
+			OFPXMT_OFB_UDP_SRC, OFPXMT_OFB_UDP_DST}},
+	}
+	for _, test := range tests {
+		match, err := NewOfpMatchFromPacketIn(msg, test.layers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := make([]uint32, 0, len(match.OxmFields))
+		for _, f := range match.OxmFields {
+			fields = append(fields, f.OxmField())
+		}
+		if len(fields) != len(test.expected) {
+			t.Log("Actual fields are : ", fields)
+			t.Errorf("Fields of layers %x are invalid.", test.layers)
+			continue
+		}
+		for i := range fields {
+			if fields[i] != test.expected[i] {
+				t.Log("Actual fields are : ", fields)
+				t.Errorf("Fields of layers %x are invalid.", test.layers)
+				break
+			}
+		}
+	}
+
+	// layers decoded so far are matched with error
+	msg.Data = msg.Data[:len(msg.Data)-4]
+	match, err := NewOfpMatchFromPacketIn(msg, MATCH_5TUPLE)
+	if err == nil || len(match.OxmFields) != 4 {
+		t.Log("Actual match is : ", match)
+		t.Error("Match of truncated packet is invalid.")
+	}
+}
+
+/*****************************************************/
+/* PacketOut                                         */
+/*****************************************************/
+func TestNewOfpPacketOutReply(t *testing.T) {
+	msg := NewOfpPacketIn()
+	msg.BufferId = 5
+	msg.Match = NewOfpMatch()
+	msg.Match.Append(NewOxmInPort(3))
+	msg.Data = newTestVlanUdpFrame()
+	pkt, _ := msg.Packet()
+
+	reply, err := NewOfpPacketOutReply(msg, pkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, ok := reply.Actions[0].(*OfpActionOutput)
+	if reply.BufferId != OFP_NO_BUFFER || reply.InPort != OFPP_CONTROLLER || !ok || output.Port != 3 {
+		t.Log("Actual message is : ", reply)
+		t.Error("Reply is not sent to in_port.")
+	}
+	if len(reply.Data) != len(msg.Data) {
+		t.Error("Data of reply is invalid.")
+	}
+
+	// buffered packet is forwarded by buffer_id
+	actions := []OfpAction{NewOfpActionOutput(OFPP_FLOOD, 0)}
+	forward := NewOfpPacketOutForward(msg, actions)
+	if forward.BufferId != 5 || forward.InPort != 3 || len(forward.Data) != 0 {
+		t.Log("Actual message is : ", forward)
+		t.Error("Buffered packet is not forwarded by buffer_id.")
+	}
+	msg.BufferId = OFP_NO_BUFFER
+	forward = NewOfpPacketOutForward(msg, actions)
+	if forward.BufferId != OFP_NO_BUFFER || len(forward.Data) != len(msg.Data) {
+		t.Error("Unbuffered packet is not forwarded with data.")
+	}
+}
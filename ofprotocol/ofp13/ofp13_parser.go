@@ -4,12 +4,49 @@ import (
 	"encoding/binary"
 	"errors"
 	"net"
+	"sync/atomic"
 )
 
+/**
+ * Direction is the sender of messages to decode.
+ * Controllers decode messages from switch, and switches and switch emulators
+ * decode messages from controller.
+ */
+type Direction int
+
+const (
+	FROM_SWITCH Direction = iota
+	FROM_CONTROLLER
+)
+
+/// direction of messages decoded by Parse.
+/// set FROM_CONTROLLER when this library is used on the switch side.
+var PARSE_DIRECTION = FROM_SWITCH
+
+/**
+ * Parse decodes packet into a message. Message types sent by the other
+ * direction than PARSE_DIRECTION are not decoded and nil is returned.
+ */
 func Parse(packet []byte) (msg OFMessage) {
+	return ParseDirection(packet, PARSE_DIRECTION)
+}
+
+/**
+ * ParseDirection is like Parse, but decodes messages of the given direction
+ * instead of PARSE_DIRECTION.
+ */
+func ParseDirection(packet []byte, dir Direction) (msg OFMessage) {
+	if dir == FROM_CONTROLLER {
+		return parseControllerMessage(packet)
+	}
+	return parseSwitchMessage(packet)
+}
+
+// decode message sent by switch
+func parseSwitchMessage(packet []byte) (msg OFMessage) {
 	switch packet[1] {
 	case OFPT_HELLO:
-		msg = new(OfpHello)
+		msg = NewOfpHello()
 		msg.Parse(packet)
 	case OFPT_ERROR:
 		msg = new(OfpErrorMsg)
@@ -24,7 +61,7 @@ func Parse(packet []byte) (msg OFMessage) {
 		msg = NewOfpFeaturesReply()
 		msg.Parse(packet)
 	case OFPT_GET_CONFIG_REPLY:
-		msg = NewOfpGetConfig()
+		msg = NewOfpGetConfigReply(0, 0)
 		msg.Parse(packet)
 	case OFPT_PACKET_IN:
 		msg = NewOfpPacketIn()
@@ -55,12 +92,108 @@ func Parse(packet []byte) (msg OFMessage) {
 	return msg
 }
 
+// decode message sent by controller
+func parseControllerMessage(packet []byte) (msg OFMessage) {
+	switch packet[1] {
+	case OFPT_HELLO:
+		msg = NewOfpHello()
+		msg.Parse(packet)
+	case OFPT_ERROR:
+		msg = new(OfpErrorMsg)
+		msg.Parse(packet)
+	case OFPT_ECHO_REQUEST:
+		msg = NewOfpEchoRequest()
+		msg.Parse(packet)
+	case OFPT_ECHO_REPLY:
+		msg = NewOfpEchoReply()
+		msg.Parse(packet)
+	case OFPT_FEATURES_REQUEST:
+		msg = NewOfpFeaturesRequest()
+		msg.Parse(packet)
+	case OFPT_GET_CONFIG_REQUEST:
+		msg = NewOfpGetConfig()
+		msg.Parse(packet)
+	case OFPT_SET_CONFIG:
+		msg = NewOfpSetConfig(0, 0)
+		msg.Parse(packet)
+	case OFPT_PACKET_OUT:
+		msg = NewOfpPacketOut(0, 0, nil, nil)
+		msg.Parse(packet)
+	case OFPT_FLOW_MOD:
+		msg = newOfpFlowMod(0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, nil, nil)
+		msg.Parse(packet)
+	case OFPT_GROUP_MOD:
+		msg = NewOfpGroupMod(0, 0, 0)
+		msg.Parse(packet)
+	case OFPT_PORT_MOD:
+		msg = new(OfpPortMod)
+		msg.Parse(packet)
+	case OFPT_TABLE_MOD:
+		msg = NewOfpTableMod(0, 0)
+		msg.Parse(packet)
+	case OFPT_MULTIPART_REQUEST:
+		msg = NewOfpMultipartRequest(0, 0)
+		msg.Parse(packet)
+	case OFPT_BARRIER_REQUEST:
+		msg = NewOfpBarrierRequest()
+		msg.Parse(packet)
+	case OFPT_QUEUE_GET_CONFIG_REQUEST:
+		msg = NewOfpQueueGetConfigRequest(0)
+		msg.Parse(packet)
+	case OFPT_ROLE_REQUEST:
+		msg = NewOfpRoleRequest(0, 0)
+		msg.Parse(packet)
+	case OFPT_GET_ASYNC_REQUEST:
+		msg = NewOfpGetAsyncRequest()
+		msg.Parse(packet)
+	case OFPT_SET_ASYNC:
+		msg = newOfpAsyncConfig(OFPT_SET_ASYNC)
+		msg.Parse(packet)
+	case OFPT_METER_MOD:
+		msg = NewOfpMeterMod(0, 0, 0)
+		msg.Parse(packet)
+	default:
+	}
+	return msg
+}
+
+/**
+ * ParseLazy is like Parse, but PacketIn is decoded by ParseLazy of
+ * OfpPacketIn, which refers packet instead of copying it.
+ * packet must not be modified while the message is used, unless Retain
+ * of the message is called.
+ */
+func ParseLazy(packet []byte) (msg OFMessage) {
+	if packet[1] == OFPT_PACKET_IN {
+		m := new(OfpPacketIn)
+		m.ParseLazy(packet)
+		return m
+	}
+	return Parse(packet)
+}
+
+// extend dst by n bytes filled with zero. serializers write into the
+// extended region, and nested ones append to the region of their parent,
+// so that a message is serialized without extra allocation.
+func grow(dst []byte, n int) []byte {
+	if cap(dst)-len(dst) < n {
+		buf := make([]byte, len(dst), 2*cap(dst)+n)
+		copy(buf, dst)
+		dst = buf
+	}
+	dst = dst[:len(dst)+n]
+	tail := dst[len(dst)-n:]
+	for i := range tail {
+		tail[i] = 0
+	}
+	return dst
+}
+
 var xid uint32 = 0
 
+// messages may be created on several goroutines, e.g. dispatch workers.
 func nextXid() uint32 {
-	tmp := xid
-	xid += 1
-	return tmp
+	return atomic.AddUint32(&xid, 1) - 1
 }
 
 /*****************************************************/
@@ -76,12 +209,19 @@ func NewOfpHeader(t uint8) OfpHeader {
 
 /// Serialize OfpHeader and return it as slice of byte.
 func (h *OfpHeader) Serialize() []byte {
-	packet := make([]byte, 8)
+	return h.SerializeTo(nil)
+}
+
+/// Serialize OfpHeader and append it to dst.
+func (h *OfpHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, 8)
+	packet := dst[start:]
 	packet[0] = h.Version
 	packet[1] = h.Type
 	binary.BigEndian.PutUint16(packet[2:], h.Length)
 	binary.BigEndian.PutUint32(packet[4:], h.Xid)
-	return packet
+	return dst
 }
 
 /// Parse packet data and set value to OfpHeader instance.
@@ -133,11 +273,18 @@ func NewOfpHelloElemHeader() *OfpHelloElemHeader {
 }
 
 func (h *OfpHelloElemHeader) Serialize() []byte {
-	packet := make([]byte, 8)
+	return h.SerializeTo(nil)
+}
+
+/// Serialize OfpHelloElemHeader and append it to dst.
+func (h *OfpHelloElemHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, 8)
+	packet := dst[start:]
 	binary.BigEndian.PutUint16(packet[0:], h.Type)
 	binary.BigEndian.PutUint16(packet[2:], h.Length)
 
-	return packet
+	return dst
 }
 
 func (h *OfpHelloElemHeader) Parse(packet []byte) {
@@ -162,22 +309,26 @@ func NewOfpHello() *OfpHello {
 ///
 ///
 func (m *OfpHello) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpHello and append it to dst.
+func (m *OfpHello) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	// header
-	h_packet := m.Header.Serialize()
 	// append header
-	copy(packet[0:], h_packet)
+	h_packet := m.Header.SerializeTo(packet[0:0])
 
 	// serialize hello body
 	index := len(h_packet)
-	e_packet := make([]byte, 0)
 	for _, elem := range m.Elements {
-		e_packet = elem.Serialize()
-		copy(packet[index:], elem.Serialize())
+		e_packet := elem.SerializeTo(packet[index:index])
 		index += len(e_packet)
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OfpHello) Parse(packet []byte) {
@@ -213,6 +364,10 @@ func NewOfpSetConfig(flags uint16, missSendLen uint16) *OfpSwitchConfig {
 	return newOfpSwitchConfig(OFPT_SET_CONFIG, flags, missSendLen)
 }
 
+func NewOfpGetConfigReply(flags uint16, missSendLen uint16) *OfpSwitchConfig {
+	return newOfpSwitchConfig(OFPT_GET_CONFIG_REPLY, flags, missSendLen)
+}
+
 func newOfpSwitchConfig(t uint8, flags uint16, missSendLen uint16) *OfpSwitchConfig {
 	h := NewOfpHeader(t)
 	m := new(OfpSwitchConfig)
@@ -224,11 +379,17 @@ func newOfpSwitchConfig(t uint8, flags uint16, missSendLen uint16) *OfpSwitchCon
 }
 
 func (m *OfpSwitchConfig) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpSwitchConfig and append it to dst.
+func (m *OfpSwitchConfig) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint16(packet[index:], m.Flags)
@@ -236,7 +397,7 @@ func (m *OfpSwitchConfig) Serialize() []byte {
 
 	binary.BigEndian.PutUint16(packet[index:], m.MissSendLen)
 
-	return packet
+	return dst
 }
 
 func (m *OfpSwitchConfig) Parse(packet []byte) {
@@ -269,11 +430,17 @@ func NewOfpTableMod(tableId uint8, config uint32) *OfpTableMod {
 }
 
 func (m *OfpTableMod) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpTableMod and append it to dst.
+func (m *OfpTableMod) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	packet[index] = m.TableId
@@ -281,11 +448,18 @@ func (m *OfpTableMod) Serialize() []byte {
 
 	binary.BigEndian.PutUint32(packet[index:], m.Config)
 
-	return packet
+	return dst
 }
 
-func (m *OfpTableMod) Parse() {
-	return
+func (m *OfpTableMod) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.TableId = packet[index]
+	index += 4
+
+	m.Config = binary.BigEndian.Uint32(packet[index:])
 }
 
 func (m *OfpTableMod) Size() int {
@@ -299,8 +473,65 @@ func newOfpPort() *OfpPort {
 	return new(OfpPort)
 }
 
+/// create port description with given number, hardware address and name.
+/// name longer than OFP_MAX_PORT_NAME_LEN - 1 is truncated.
+func NewOfpPort(portNo uint32, hwAddr string, name string) (*OfpPort, error) {
+	addr, err := net.ParseMAC(hwAddr)
+	if err != nil {
+		return nil, err
+	}
+	p := newOfpPort()
+	p.PortNo = portNo
+	p.HwAddr = addr
+	p.Name = make([]byte, OFP_MAX_PORT_NAME_LEN)
+	copy(p.Name[:OFP_MAX_PORT_NAME_LEN-1], name)
+	return p, nil
+}
+
 func (p *OfpPort) Serialize() []byte {
-	return nil
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpPort and append it to dst.
+func (p *OfpPort) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint32(packet[index:], p.PortNo)
+	index += 8
+
+	copy(packet[index:index+6], p.HwAddr)
+	index += 8
+
+	copy(packet[index:index+OFP_MAX_PORT_NAME_LEN], p.Name)
+	index += OFP_MAX_PORT_NAME_LEN
+
+	binary.BigEndian.PutUint32(packet[index:], p.Config)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.State)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.Curr)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.Advertised)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.Supported)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.Peer)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.CurrSpeed)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], p.MaxSpeed)
+
+	return dst
 }
 
 func (p *OfpPort) Parse(packet []byte) {
@@ -363,7 +594,28 @@ func NewOfpPortStatus() *OfpPortStatus {
 }
 
 func (m *OfpPortStatus) Serialize() []byte {
-	return nil
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpPortStatus and append it to dst.
+func (m *OfpPortStatus) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	index := 0
+
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[index:index])
+	index += m.Header.Size()
+
+	packet[index] = m.Reason
+	index += 8
+
+	if m.Desc != nil {
+		m.Desc.SerializeTo(packet[index:index])
+	}
+
+	return dst
 }
 
 func (m *OfpPortStatus) Parse(packet []byte) {
@@ -410,11 +662,17 @@ func NewOfpPortMod(
 }
 
 func (m *OfpPortMod) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpPortMod and append it to dst.
+func (m *OfpPortMod) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.PortNo)
@@ -432,11 +690,29 @@ func (m *OfpPortMod) Serialize() []byte {
 	binary.BigEndian.PutUint32(packet[index:], m.Advertise)
 	index += 4
 
-	return packet
+	return dst
 }
 
 func (m *OfpPortMod) Parse(packet []byte) {
-	return
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.PortNo = binary.BigEndian.Uint32(packet[index:])
+	index += 8
+
+	addr := make(net.HardwareAddr, 6)
+	copy(addr, packet[index:])
+	m.HwAddr = addr
+	index += 8
+
+	m.Config = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.Mask = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.Advertise = binary.BigEndian.Uint32(packet[index:])
 }
 
 func (m *OfpPortMod) Size() int {
@@ -461,13 +737,20 @@ func NewOfpFeaturesReply() *OfpSwitchFeatures {
 }
 
 func (m *OfpSwitchFeatures) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
-	copy(packet[0:], h_packet)
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpSwitchFeatures and append it to dst.
+func (m *OfpSwitchFeatures) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[0:0])
 	index := m.Header.Size()
-	binary.BigEndian.PutUint64(packet[index:8], m.DatapathId)
+	binary.BigEndian.PutUint64(packet[index:], m.DatapathId)
 	index += 8
-	binary.BigEndian.PutUint32(packet[index:4], m.NBuffers)
+	binary.BigEndian.PutUint32(packet[index:], m.NBuffers)
 	index += 4
 	packet[index] = m.NTables
 	index += 1
@@ -477,11 +760,11 @@ func (m *OfpSwitchFeatures) Serialize() []byte {
 	index += 1
 	packet[index] = m.Pad[1]
 	index += 1
-	binary.BigEndian.PutUint32(packet[index:4], m.Capabilities)
+	binary.BigEndian.PutUint32(packet[index:], m.Capabilities)
 	index += 4
-	binary.BigEndian.PutUint32(packet[index:4], m.Reserved)
+	binary.BigEndian.PutUint32(packet[index:], m.Reserved)
 
-	return packet
+	return dst
 }
 
 func (m *OfpSwitchFeatures) Parse(packet []byte) {
@@ -626,10 +909,16 @@ func newOfpFlowMod(
 }
 
 func (m *OfpFlowMod) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpFlowMod and append it to dst.
+func (m *OfpFlowMod) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	m.Header.Length = uint16(m.Size())
-	h_packet := m.Header.Serialize()
-	copy(packet[0:], h_packet)
+	m.Header.SerializeTo(packet[0:0])
 	index := m.Header.Size()
 
 	binary.BigEndian.PutUint64(packet[index:], m.Cookie)
@@ -659,21 +948,59 @@ func (m *OfpFlowMod) Serialize() []byte {
 	packet[index] = 0x00
 	index++
 
-	m_packet := m.Match.Serialize()
-	copy(packet[index:], m_packet)
+	m_packet := m.Match.SerializeTo(packet[index:index])
 	//index += m.Match.Size()
 	index += len(m_packet)
 
 	for _, inst := range m.Instructions {
-		copy(packet[index:], inst.Serialize())
+		inst.SerializeTo(packet[index:index])
 		index += inst.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OfpFlowMod) Parse(packet []byte) {
-	// not implement
+	m.Header.Parse(packet)
+	index := m.Header.Size()
+
+	m.Cookie = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+	m.CookieMask = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+	m.TableId = packet[index]
+	index++
+	m.Command = packet[index]
+	index++
+	m.IdleTimeout = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+	m.HardTimeout = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+	m.Priority = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+	m.BufferId = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+	m.OutPort = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+	m.OutGroup = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+	m.Flags = binary.BigEndian.Uint16(packet[index:])
+	index += 4 // include Padding
+
+	m.Match = NewOfpMatch()
+	m.Match.Parse(packet[index:])
+	index += m.Match.Size()
+
+	m.Instructions = make([]OfpInstruction, 0)
+	for index < (int)(m.Header.Length) {
+		inst := ParseInstruction(packet[index:])
+		if inst == nil {
+			// TODO: Error Handling
+			break
+		}
+		m.Instructions = append(m.Instructions, inst)
+		index += inst.Size()
+	}
 }
 
 func (m *OfpFlowMod) Size() int {
@@ -707,7 +1034,14 @@ func NewOfpBucket(weight uint16, watchPort uint32, watchGroup uint32) *OfpBucket
 }
 
 func (b *OfpBucket) Serialize() []byte {
-	packet := make([]byte, b.Size())
+	return b.SerializeTo(nil)
+}
+
+/// Serialize OfpBucket and append it to dst.
+func (b *OfpBucket) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, b.Size())
+	packet := dst[start:]
 	index := 0
 
 	b.Length = (uint16)(b.Size())
@@ -724,12 +1058,11 @@ func (b *OfpBucket) Serialize() []byte {
 	index += 8
 
 	for _, a := range b.Actions {
-		a_packet := a.Serialize()
-		copy(packet[index:], a_packet)
+		a.SerializeTo(packet[index:index])
 		index += a.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (b *OfpBucket) Parse(packet []byte) {
@@ -784,12 +1117,18 @@ func NewOfpGroupMod(command uint16, t uint8, id uint32) *OfpGroupMod {
 }
 
 func (m *OfpGroupMod) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpGroupMod and append it to dst.
+func (m *OfpGroupMod) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	m.Header.Length = (uint16)(m.Size())
-	h_packet := m.Header.Serialize()
 
 	index := 0
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint16(packet[index:], m.Command)
@@ -802,15 +1141,34 @@ func (m *OfpGroupMod) Serialize() []byte {
 	index += 4
 
 	for _, b := range m.Buckets {
-		b_bucket := b.Serialize()
-		copy(packet[index:], b_bucket)
+		b.SerializeTo(packet[index:index])
 		index += b.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OfpGroupMod) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.Command = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+
+	m.Type = packet[index]
+	index += 2
+
+	m.GroupId = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.Buckets = make([]*OfpBucket, 0)
+	for index < (int)(m.Header.Length) {
+		b := NewOfpBucket(0, 0, 0)
+		b.Parse(packet[index:])
+		m.Append(b)
+		index += b.Size()
+	}
 }
 
 func (m *OfpGroupMod) Size() int {
@@ -854,12 +1212,18 @@ func NewOfpPacketOut(
 }
 
 func (m *OfpPacketOut) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpPacketOut and append it to dst.
+func (m *OfpPacketOut) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
 
 	m.Header.Length = (uint16)(m.Size())
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.BufferId)
@@ -872,8 +1236,7 @@ func (m *OfpPacketOut) Serialize() []byte {
 	aSize := 0
 	for _, a := range m.Actions {
 		actionLen += a.Size()
-		a_packet := a.Serialize()
-		copy(packet[(index+8+aSize):], a_packet)
+		a_packet := a.SerializeTo(packet[(index+8+aSize):][:0])
 		aSize += len(a_packet)
 	}
 
@@ -884,10 +1247,41 @@ func (m *OfpPacketOut) Serialize() []byte {
 	if m.Data != nil {
 		copy(packet[index:], m.Data)
 	}
-	return packet
+	return dst
 }
 
 func (m *OfpPacketOut) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.BufferId = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.InPort = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.ActionLen = binary.BigEndian.Uint16(packet[index:])
+	index += 8
+
+	m.Actions = make([]OfpAction, 0)
+	end := index + (int)(m.ActionLen)
+	for index < end {
+		action := ParseAction(packet[index:])
+		if action == nil {
+			// TODO: Error Handling
+			break
+		}
+		m.Actions = append(m.Actions, action)
+		index += action.Size()
+	}
+	index = end
+
+	m.Data = nil
+	if index < (int)(m.Header.Length) {
+		m.Data = make([]byte, (int)(m.Header.Length)-index)
+		copy(m.Data, packet[index:])
+	}
 }
 
 func (m *OfpPacketOut) Size() int {
@@ -943,7 +1337,14 @@ func NewOfpMeterBandHeader(t uint16, rate uint32, burstSize uint32) OfpMeterBand
 }
 
 func (m *OfpMeterBandHeader) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterBandHeader and append it to dst.
+func (m *OfpMeterBandHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
 	binary.BigEndian.PutUint16(packet[index:], m.Type)
 	index += 2
@@ -957,7 +1358,7 @@ func (m *OfpMeterBandHeader) Serialize() []byte {
 	binary.BigEndian.PutUint32(packet[index:], m.BurstSize)
 	index += 4
 
-	return packet
+	return dst
 }
 
 func (m *OfpMeterBandHeader) Parse(packet []byte) {
@@ -991,12 +1392,18 @@ func NewOfpMeterBandDrop(rate uint32, burstSize uint32) *OfpMeterBandDrop {
 }
 
 func (m *OfpMeterBandDrop) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterBandDrop and append it to dst.
+func (m *OfpMeterBandDrop) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (m *OfpMeterBandDrop) Parse(packet []byte) {
@@ -1023,16 +1430,22 @@ func NewOfpMeterBandDscpRemark(rate uint32, burstSize uint32, precLevel uint8) *
 }
 
 func (m *OfpMeterBandDscpRemark) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterBandDscpRemark and append it to dst.
+func (m *OfpMeterBandDscpRemark) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	packet[index] = m.PrecLevel
 
-	return packet
+	return dst
 }
 
 func (m *OfpMeterBandDscpRemark) Parse(packet []byte) {
@@ -1065,16 +1478,22 @@ func NewOfpMeterBandExperimenter(rate uint32, burstSize uint32, experimenter uin
 }
 
 func (m *OfpMeterBandExperimenter) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterBandExperimenter and append it to dst.
+func (m *OfpMeterBandExperimenter) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.Experimenter)
 
-	return packet
+	return dst
 }
 
 func (m *OfpMeterBandExperimenter) Parse(packet []byte) {
@@ -1109,11 +1528,18 @@ func NewOfpMeterMod(command uint16, flags uint16, id uint32) *OfpMeterMod {
 }
 
 func (m *OfpMeterMod) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterMod and append it to dst.
+func (m *OfpMeterMod) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	index := 0
-	copy(packet[index:], h_packet)
+	m.Header.Length = (uint16)(m.Size())
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint16(packet[index:], m.Command)
@@ -1126,15 +1552,37 @@ func (m *OfpMeterMod) Serialize() []byte {
 	index += 4
 
 	for _, b := range m.Bands {
-		b_packet := b.Serialize()
-		copy(packet[index:], b_packet)
+		b.SerializeTo(packet[index:index])
 		index += b.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OfpMeterMod) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.Command = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+
+	m.Flags = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+
+	m.MeterId = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.Bands = make([]OfpMeterBand, 0)
+	for index < (int)(m.Header.Length) {
+		mb := ParseMeter(packet[index:])
+		if mb == nil {
+			// TODO: Error Handling
+			break
+		}
+		m.Bands = append(m.Bands, mb)
+		index += mb.Size()
+	}
 }
 
 func (m *OfpMeterMod) Size() int {
@@ -1162,24 +1610,39 @@ func NewOfpPacketIn() *OfpPacketIn {
 }
 
 func (m *OfpPacketIn) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
-	copy(packet[0:], h_packet)
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpPacketIn and append it to dst.
+func (m *OfpPacketIn) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[0:0])
 	index := m.Header.Size()
 
-	binary.BigEndian.PutUint32(packet[index:4], m.BufferId)
+	binary.BigEndian.PutUint32(packet[index:], m.BufferId)
 	index += 4
-	binary.BigEndian.PutUint16(packet[index:2], m.TotalLen)
+	binary.BigEndian.PutUint16(packet[index:], m.TotalLen)
 	index += 2
 	packet[index] = m.Reason
 	index++
 	packet[index] = m.TableId
 	index++
+	binary.BigEndian.PutUint64(packet[index:], m.Cookie)
+	index += 8
 
-	m_packet := m.Match.Serialize()
-	copy(packet[index:], m_packet)
+	match := m.match()
+	match.SerializeTo(packet[index:index])
+	index += match.Size()
 
-	return packet
+	// padding
+	index += 2
+
+	copy(packet[index:], m.GetData())
+
+	return dst
 }
 
 /* From openflow 1.3 spec
@@ -1229,8 +1692,105 @@ func (m *OfpPacketIn) Parse(packet []byte) {
 	copy(m.Data, packet[index:])
 }
 
+/**
+ * ParseLazy decodes fixed fields of PacketIn, and keeps reference to packet.
+ * Match and Data are left nil and decoded on first access by GetMatch and
+ * GetData. packet is not copied, so the caller must keep it unmodified while
+ * the message is used, or call Retain.
+ */
+func (m *OfpPacketIn) ParseLazy(packet []byte) {
+	m.Header.Parse(packet)
+	index := m.Header.Size()
+
+	m.BufferId = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+	m.TotalLen = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+	m.Reason = packet[index]
+	index++
+	m.TableId = packet[index]
+	index++
+	m.Cookie = binary.BigEndian.Uint64(packet[index:])
+
+	m.Match = nil
+	m.Data = nil
+	m.raw = packet[:m.Header.Length]
+	m.retained = false
+}
+
+/// offset of match field in PacketIn
+const packetInMatchOffset = 24
+
+/// return match field. it is decoded on first call if message was parsed by
+/// ParseLazy, and cached in the message. GetMatch and GetData are the only
+/// methods which may modify the message; other methods such as Size,
+/// SerializeTo and String decode match without caching it. Call Retain
+/// before sharing the message among goroutines.
+func (m *OfpPacketIn) GetMatch() *OfpMatch {
+	if m.Match == nil && m.raw != nil {
+		m.Match = m.match()
+	}
+	return m.Match
+}
+
+/// return match field without caching it in the message.
+func (m *OfpPacketIn) match() *OfpMatch {
+	if m.Match != nil || m.raw == nil {
+		return m.Match
+	}
+	match := NewOfpMatch()
+	if length, ok := m.rawMatchLength(); ok {
+		match.Parse(m.raw[packetInMatchOffset : packetInMatchOffset+length])
+	}
+	return match
+}
+
+/// length of match field in the received packet including padding, or
+/// false if it exceeds the packet.
+func (m *OfpPacketIn) rawMatchLength() (int, bool) {
+	if len(m.raw) < packetInMatchOffset+4 {
+		return 0, false
+	}
+	length := int(binary.BigEndian.Uint16(m.raw[packetInMatchOffset+2:]))
+	length += (8 - (length % 8)) & 7 // match is padded to 8 bytes
+	if length < 4 || packetInMatchOffset+length+2 > len(m.raw) {
+		return 0, false
+	}
+	return length, true
+}
+
+/// return ethernet frame. if message was parsed by ParseLazy, it refers the
+/// received packet without copy, and is valid while the packet is.
+/// It returns nil if match field of the received packet is malformed.
+func (m *OfpPacketIn) GetData() []byte {
+	if m.Data == nil && m.raw != nil {
+		length, ok := m.rawMatchLength()
+		if !ok {
+			return nil
+		}
+		return m.raw[packetInMatchOffset+length+2:]
+	}
+	return m.Data
+}
+
+/// copy the received packet referred by message parsed by ParseLazy,
+/// so that the message can be kept after the packet is reused.
+/// match is decoded at the same time, so that retained message can be
+/// shared among goroutines without being modified.
+/// It does nothing for message parsed by Parse.
+func (m *OfpPacketIn) Retain() {
+	if m.raw == nil || m.retained {
+		return
+	}
+	raw := make([]byte, len(m.raw))
+	copy(raw, m.raw)
+	m.raw = raw
+	m.retained = true
+	m.GetMatch()
+}
+
 func (m *OfpPacketIn) Size() int {
-	return m.Header.Size() + 16 + m.Match.Size() + 2 + len(m.Data)
+	return m.Header.Size() + 16 + m.match().Size() + 2 + len(m.GetData())
 }
 
 /*****************************************************/
@@ -1244,7 +1804,53 @@ func NewOfpFlowRemoved() *OfpFlowRemoved {
 }
 
 func (m *OfpFlowRemoved) Serialize() []byte {
-	return nil
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpFlowRemoved and append it to dst.
+func (m *OfpFlowRemoved) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	index := 0
+
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[index:index])
+	index += m.Header.Size()
+
+	binary.BigEndian.PutUint64(packet[index:], m.Cookie)
+	index += 8
+
+	binary.BigEndian.PutUint16(packet[index:], m.Priority)
+	index += 2
+
+	packet[index] = m.Reason
+	index += 1
+
+	packet[index] = m.TableId
+	index += 1
+
+	binary.BigEndian.PutUint32(packet[index:], m.DurationSec)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], m.DurationNSec)
+	index += 4
+
+	binary.BigEndian.PutUint16(packet[index:], m.IdleTimeout)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], m.HardTimeout)
+	index += 2
+
+	binary.BigEndian.PutUint64(packet[index:], m.PacketCount)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], m.ByteCount)
+	index += 8
+
+	m.Match.SerializeTo(packet[index:index])
+
+	return dst
 }
 
 func (m *OfpFlowRemoved) Parse(packet []byte) {
@@ -1342,28 +1948,35 @@ func (m *OfpFlowRemoved) Size() int {
 func NewOfpMatch() *OfpMatch {
 	m := new(OfpMatch)
 	m.Type = OFPMT_OXM
+	m.Length = 4
 	m.OxmFields = make([]OxmField, 0)
 	return m
 }
 
 func (m *OfpMatch) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMatch and append it to dst.
+func (m *OfpMatch) SerializeTo(dst []byte) []byte {
 	// set Size
 	m.Length = 4
 	for _, e := range m.OxmFields {
 		m.Length += uint16(e.Size())
 	}
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
 	binary.BigEndian.PutUint16(packet[index:], m.Type)
 	index += 2
 	binary.BigEndian.PutUint16(packet[index:], m.Length)
 	index += 2
 	for _, e := range m.OxmFields {
-		mf_packet := e.Serialize()
-		copy(packet[index:], mf_packet)
+		e.SerializeTo(packet[index:index])
 		index += e.Size()
 	}
-	return packet
+	return dst
 }
 
 func (m *OfpMatch) Parse(packet []byte) {
@@ -1391,6 +2004,7 @@ func (m *OfpMatch) Size() int {
 
 func (m *OfpMatch) Append(f OxmField) {
 	m.OxmFields = append(m.OxmFields, f)
+	m.updateLength()
 }
 
 func parseOxmField(packet []byte) OxmField {
@@ -1615,8 +2229,15 @@ func NewOxmInPort(port uint32) *OxmInPort {
 
 // Serialize
 func (m *OxmInPort) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmInPort and append it to dst.
+func (m *OxmInPort) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	// serialize header
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
@@ -1625,7 +2246,7 @@ func (m *OxmInPort) Serialize() []byte {
 	// serialize value
 	binary.BigEndian.PutUint32(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 // Parse
@@ -1674,15 +2295,22 @@ func NewOxmInPhyPort(port uint32) *OxmInPhyPort {
 }
 
 func (m *OxmInPhyPort) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmInPhyPort and append it to dst.
+func (m *OxmInPhyPort) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
 	binary.BigEndian.PutUint32(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmInPhyPort) Parse(packet []byte) {
@@ -1734,8 +2362,15 @@ func NewOxmMetadataW(metadata uint64, mask uint64) *OxmMetadata {
 }
 
 func (m *OxmMetadata) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmMetadata and append it to dst.
+func (m *OxmMetadata) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -1746,7 +2381,7 @@ func (m *OxmMetadata) Serialize() []byte {
 		binary.BigEndian.PutUint64(packet[index:], m.Mask)
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmMetadata) Parse(packet []byte) {
@@ -1825,8 +2460,15 @@ func NewOxmEthW(header uint32, hwAddr string, mask string) (*OxmEth, error) {
 }
 
 func (m *OxmEth) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmEth and append it to dst.
+func (m *OxmEth) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -1843,7 +2485,7 @@ func (m *OxmEth) Serialize() []byte {
 		}
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmEth) Parse(packet []byte) {
@@ -1893,13 +2535,20 @@ func NewOxmEthType(ethType uint16) *OxmEthType {
 }
 
 func (m *OxmEthType) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmEthType and append it to dst.
+func (m *OxmEthType) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], m.Value)
-	return packet
+	return dst
 }
 
 func (m *OxmEthType) Parse(packet []byte) {
@@ -1951,8 +2600,15 @@ func NewOxmVlanVidW(vid uint16, mask uint16) *OxmVlanVid {
 }
 
 func (m *OxmVlanVid) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmVlanVid and append it to dst.
+func (m *OxmVlanVid) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -1961,7 +2617,7 @@ func (m *OxmVlanVid) Serialize() []byte {
 	if oxmHasMask(m.TlvHeader) == 1 {
 		binary.BigEndian.PutUint16(packet[index:], m.Mask)
 	}
-	return packet
+	return dst
 }
 
 func (m *OxmVlanVid) Parse(packet []byte) {
@@ -2009,14 +2665,21 @@ func NewOxmVlanPcp(pcp uint8) *OxmVlanPcp {
 }
 
 func (m *OxmVlanPcp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmVlanPcp and append it to dst.
+func (m *OxmVlanPcp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmVlanPcp) Parse(packet []byte) {
@@ -2058,14 +2721,21 @@ func NewOxmIpDscp(dscp uint8) *OxmIpDscp {
 }
 
 func (m *OxmIpDscp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpDscp and append it to dst.
+func (m *OxmIpDscp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpDscp) Parse(packet []byte) {
@@ -2106,15 +2776,22 @@ func NewOxmIpEcn(ecn uint8) *OxmIpEcn {
 	return &field
 }
 
-func (m *OxmIpEcn) Serialize() []byte {
+func (m *OxmIpEcn) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpEcn and append it to dst.
+func (m *OxmIpEcn) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpEcn) Parse(packet []byte) {
@@ -2156,14 +2833,21 @@ func NewOxmIpProto(proto uint8) *OxmIpProto {
 }
 
 func (m *OxmIpProto) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpProto and append it to dst.
+func (m *OxmIpProto) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpProto) Parse(packet []byte) {
@@ -2209,7 +2893,7 @@ func NewOxmIpv4DstW(addr string, mask int) (*OxmIpv4, error) {
 
 func NewOxmIpv4(header uint32, addr string) (*OxmIpv4, error) {
 	// parse string as IPAddr
-	v4addr := net.ParseIP(addr)
+	v4addr := net.ParseIP(addr).To4()
 	if v4addr == nil {
 		return nil, errors.New("failed to parse IPv4 address.")
 	}
@@ -2222,7 +2906,7 @@ func NewOxmIpv4(header uint32, addr string) (*OxmIpv4, error) {
 
 func NewOxmIpv4W(header uint32, addr string, mask int) (*OxmIpv4, error) {
 	// parse string as IPAddr
-	v4addr := net.ParseIP(addr)
+	v4addr := net.ParseIP(addr).To4()
 	if v4addr == nil {
 		return nil, errors.New("failed to parse IPv4 address.")
 	}
@@ -2235,16 +2919,21 @@ func NewOxmIpv4W(header uint32, addr string, mask int) (*OxmIpv4, error) {
 }
 
 func (m *OxmIpv4) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv4 and append it to dst.
+func (m *OxmIpv4) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
-	for i := 0; i < 4; i++ {
-		packet[index] = m.Value[12+i]
-		index++
-	}
+	copy(packet[index:index+4], m.Value.To4())
+	index += 4
 
 	if oxmHasMask(m.TlvHeader) == 1 {
 		for i := 0; i < 4; i++ {
@@ -2253,7 +2942,7 @@ func (m *OxmIpv4) Serialize() []byte {
 		}
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv4) Parse(packet []byte) {
@@ -2313,14 +3002,21 @@ func NewOxmTcp(header uint32, port uint16) *OxmTcp {
 }
 
 func (m *OxmTcp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmTcp and append it to dst.
+func (m *OxmTcp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmTcp) Parse(packet []byte) {
@@ -2364,14 +3060,21 @@ func NewOxmUdp(header uint32, port uint16) *OxmUdp {
 }
 
 func (m *OxmUdp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmUdp and append it to dst.
+func (m *OxmUdp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmUdp) Parse(packet []byte) {
@@ -2415,14 +3118,21 @@ func NewOxmSctp(header uint32, port uint16) *OxmSctp {
 }
 
 func (m *OxmSctp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmSctp and append it to dst.
+func (m *OxmSctp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmSctp) Parse(packet []byte) {
@@ -2463,14 +3173,21 @@ func NewOxmIcmpType(value uint8) *OxmIcmpType {
 }
 
 func (m *OxmIcmpType) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIcmpType and append it to dst.
+func (m *OxmIcmpType) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIcmpType) Parse(packet []byte) {
@@ -2511,14 +3228,21 @@ func NewOxmIcmpCode(value uint8) *OxmIcmpCode {
 }
 
 func (m *OxmIcmpCode) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIcmpCode and append it to dst.
+func (m *OxmIcmpCode) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIcmpCode) Parse(packet []byte) {
@@ -2559,14 +3283,21 @@ func NewOxmArpOp(op uint16) *OxmArpOp {
 }
 
 func (m *OxmArpOp) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmArpOp and append it to dst.
+func (m *OxmArpOp) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmArpOp) Parse(packet []byte) {
@@ -2611,7 +3342,7 @@ func NewOxmArpTpaW(addr string, mask int) (*OxmArpPa, error) {
 
 func NewOxmArpPa(header uint32, addr string) (*OxmArpPa, error) {
 	// parse addr
-	v4addr := net.ParseIP(addr)
+	v4addr := net.ParseIP(addr).To4()
 	if v4addr == nil {
 		return nil, errors.New("failed to parse IPv4 address.")
 	}
@@ -2623,7 +3354,7 @@ func NewOxmArpPa(header uint32, addr string) (*OxmArpPa, error) {
 
 func NewOxmArpPaW(header uint32, addr string, mask int) (*OxmArpPa, error) {
 	// parse addr
-	v4addr := net.ParseIP(addr)
+	v4addr := net.ParseIP(addr).To4()
 	if v4addr == nil {
 		return nil, errors.New("failed to parse IPv4 address.")
 	}
@@ -2635,16 +3366,21 @@ func NewOxmArpPaW(header uint32, addr string, mask int) (*OxmArpPa, error) {
 }
 
 func (m *OxmArpPa) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmArpPa and append it to dst.
+func (m *OxmArpPa) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
-	for i := 0; i < 4; i++ {
-		packet[index] = m.Value[12+i]
-		index++
-	}
+	copy(packet[index:index+4], m.Value.To4())
+	index += 4
 
 	if oxmHasMask(m.TlvHeader) == 1 {
 		for i := 0; i < 4; i++ {
@@ -2653,7 +3389,7 @@ func (m *OxmArpPa) Serialize() []byte {
 		}
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmArpPa) Parse(packet []byte) {
@@ -2719,8 +3455,15 @@ func NewOxmArpHa(header uint32, hwAddr string) (*OxmArpHa, error) {
 }
 
 func (m *OxmArpHa) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmArpHa and append it to dst.
+func (m *OxmArpHa) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -2730,7 +3473,7 @@ func (m *OxmArpHa) Serialize() []byte {
 		index++
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmArpHa) Parse(packet []byte) {
@@ -2814,8 +3557,15 @@ func NewOxmIpv6W(header uint32, addr string, mask int) (*OxmIpv6, error) {
 }
 
 func (m *OxmIpv6) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6 and append it to dst.
+func (m *OxmIpv6) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -2832,7 +3582,7 @@ func (m *OxmIpv6) Serialize() []byte {
 		}
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6) Parse(packet []byte) {
@@ -2899,8 +3649,15 @@ func NewOxmIpv6FLabelW(label uint32, mask uint32) *OxmIpv6FLabel {
 }
 
 func (m *OxmIpv6FLabel) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6FLabel and append it to dst.
+func (m *OxmIpv6FLabel) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -2911,7 +3668,7 @@ func (m *OxmIpv6FLabel) Serialize() []byte {
 		binary.BigEndian.PutUint32(packet[index:], m.Mask)
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6FLabel) Parse(packet []byte) {
@@ -2957,15 +3714,22 @@ func NewOxmIcmpv6Type(value uint8) *OxmIcmpv6Type {
 }
 
 func (m *OxmIcmpv6Type) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIcmpv6Type and append it to dst.
+func (m *OxmIcmpv6Type) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIcmpv6Type) Parse(packet []byte) {
@@ -3007,15 +3771,22 @@ func NewOxmIcmpv6Code(value uint8) *OxmIcmpv6Code {
 }
 
 func (m *OxmIcmpv6Code) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIcmpv6Code and append it to dst.
+func (m *OxmIcmpv6Code) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmIcmpv6Code) Parse(packet []byte) {
@@ -3062,8 +3833,15 @@ func NewOxmIpv6NdTarget(addr string) (*OxmIpv6NdTarget, error) {
 }
 
 func (m *OxmIpv6NdTarget) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6NdTarget and append it to dst.
+func (m *OxmIpv6NdTarget) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3073,7 +3851,7 @@ func (m *OxmIpv6NdTarget) Serialize() []byte {
 		index++
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6NdTarget) Parse(packet []byte) {
@@ -3125,8 +3903,15 @@ func NewOxmIpv6NdSll(hwAddr string) (*OxmIpv6NdSll, error) {
 }
 
 func (m *OxmIpv6NdSll) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6NdSll and append it to dst.
+func (m *OxmIpv6NdSll) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3136,7 +3921,7 @@ func (m *OxmIpv6NdSll) Serialize() []byte {
 		index++
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6NdSll) Parse(packet []byte) {
@@ -3188,8 +3973,15 @@ func NewOxmIpv6NdTll(hwAddr string) (*OxmIpv6NdTll, error) {
 }
 
 func (m *OxmIpv6NdTll) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6NdTll and append it to dst.
+func (m *OxmIpv6NdTll) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3199,7 +3991,7 @@ func (m *OxmIpv6NdTll) Serialize() []byte {
 		index++
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6NdTll) Parse(packet []byte) {
@@ -3246,14 +4038,21 @@ func NewOxmMplsLabel(label uint32) *OxmMplsLabel {
 }
 
 func (m *OxmMplsLabel) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmMplsLabel and append it to dst.
+func (m *OxmMplsLabel) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 	binary.BigEndian.PutUint32(packet[index:], m.Value)
 
-	return packet
+	return dst
 }
 
 func (m *OxmMplsLabel) Parse(packet []byte) {
@@ -3295,15 +4094,22 @@ func NewOxmMplsTc(tc uint8) *OxmMplsTc {
 }
 
 func (m *OxmMplsTc) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmMplsTc and append it to dst.
+func (m *OxmMplsTc) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmMplsTc) Parse(packet []byte) {
@@ -3345,15 +4151,22 @@ func NewOxmMplsBos(bos uint8) *OxmMplsBos {
 }
 
 func (m *OxmMplsBos) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmMplsBos and append it to dst.
+func (m *OxmMplsBos) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
 
 	packet[index] = m.Value
 
-	return packet
+	return dst
 }
 
 func (m *OxmMplsBos) Parse(packet []byte) {
@@ -3405,8 +4218,15 @@ func NewOxmPbbIsidW(isid [3]uint8, mask [3]uint8) *OxmPbbIsid {
 }
 
 func (m *OxmPbbIsid) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmPbbIsid and append it to dst.
+func (m *OxmPbbIsid) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3423,7 +4243,7 @@ func (m *OxmPbbIsid) Serialize() []byte {
 		}
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmPbbIsid) Parse(packet []byte) {
@@ -3485,8 +4305,15 @@ func NewOxmTunnelIdW(id uint64, mask uint64) *OxmTunnelId {
 }
 
 func (m *OxmTunnelId) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmTunnelId and append it to dst.
+func (m *OxmTunnelId) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3498,7 +4325,7 @@ func (m *OxmTunnelId) Serialize() []byte {
 		binary.BigEndian.PutUint64(packet[index:], m.Mask)
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmTunnelId) Parse(packet []byte) {
@@ -3555,8 +4382,15 @@ func NewOxmIpv6ExtHeaderW(value uint16, mask uint16) *OxmIpv6ExtHeader {
 }
 
 func (m *OxmIpv6ExtHeader) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OxmIpv6ExtHeader and append it to dst.
+func (m *OxmIpv6ExtHeader) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], m.TlvHeader)
 	index += 4
@@ -3568,7 +4402,7 @@ func (m *OxmIpv6ExtHeader) Serialize() []byte {
 		binary.BigEndian.PutUint16(packet[index:], m.Value)
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OxmIpv6ExtHeader) Parse(packet []byte) {
@@ -3603,6 +4437,34 @@ func (m *OxmIpv6ExtHeader) Size() int {
 	return int(m.Length() + 4)
 }
 
+/*
+ * OfpInstruction Parser
+ */
+func ParseInstruction(packet []byte) (instruction OfpInstruction) {
+	index := 0
+	i_type := binary.BigEndian.Uint16(packet[index:])
+	switch i_type {
+	case OFPIT_GOTO_TABLE:
+		instruction = NewOfpInstructionGotoTable(0)
+		instruction.Parse(packet[index:])
+	case OFPIT_WRITE_METADATA:
+		instruction = NewOfpInstructionWriteMetadata(0, 0)
+		instruction.Parse(packet[index:])
+	case OFPIT_WRITE_ACTIONS, OFPIT_APPLY_ACTIONS, OFPIT_CLEAR_ACTIONS:
+		instruction = NewOfpInstructionActions(i_type)
+		instruction.Parse(packet[index:])
+	case OFPIT_METER:
+		instruction = NewOfpInstructionMeter(0)
+		instruction.Parse(packet[index:])
+	case OFPIT_EXPERIMENTER:
+		instruction = NewOfpInstructionExperimenter(0)
+		instruction.Parse(packet[index:])
+	default:
+		// TODO: error handling
+	}
+	return instruction
+}
+
 /*****************************************************/
 /* OfpInstruction                                    */
 /*****************************************************/
@@ -3612,12 +4474,19 @@ func NewOfpInstructionHeader(t uint16) OfpInstructionHeader {
 }
 
 func (h *OfpInstructionHeader) Serialize() []byte {
-	packet := make([]byte, h.Size())
+	return h.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionHeader and append it to dst.
+func (h *OfpInstructionHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, h.Size())
+	packet := dst[start:]
 	index := 0
 	binary.BigEndian.PutUint16(packet[index:], h.Type)
 	index += 2
 	binary.BigEndian.PutUint16(packet[index:], h.Length)
-	return packet
+	return dst
 }
 
 func (h *OfpInstructionHeader) Parse(packet []byte) {
@@ -3644,14 +4513,20 @@ func NewOfpInstructionGotoTable(id uint8) *OfpInstructionGotoTable {
 }
 
 func (i *OfpInstructionGotoTable) Serialize() []byte {
-	packet := make([]byte, i.Size())
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionGotoTable and append it to dst.
+func (i *OfpInstructionGotoTable) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 	index := 0
-	h_packet := i.Header.Serialize()
-	copy(packet[0:], h_packet)
+	i.Header.SerializeTo(packet[0:0])
 	index += i.Header.Size()
 	packet[index] = i.TableId
 	index += 1
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionGotoTable) Parse(packet []byte) {
@@ -3685,16 +4560,22 @@ func NewOfpInstructionWriteMetadata(metadata uint64, mask uint64) *OfpInstructio
 }
 
 func (i *OfpInstructionWriteMetadata) Serialize() []byte {
-	packet := make([]byte, i.Size())
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionWriteMetadata and append it to dst.
+func (i *OfpInstructionWriteMetadata) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 	index := 0
-	h_packet := i.Header.Serialize()
-	copy(packet[index:], h_packet)
+	i.Header.SerializeTo(packet[index:index])
 	index += i.Header.Size()
 	index += 4
 	binary.BigEndian.PutUint64(packet[index:], i.Metadata)
 	index += 8
 	binary.BigEndian.PutUint64(packet[index:], i.MetadataMask)
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionWriteMetadata) Parse(packet []byte) {
@@ -3733,12 +4614,18 @@ func NewOfpInstructionActions(
 }
 
 func (i *OfpInstructionActions) Serialize() []byte {
-	packet := make([]byte, i.Size())
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionActions and append it to dst.
+func (i *OfpInstructionActions) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 	index := 0
 	// set actual length
 	i.Header.Length = uint16(i.Size())
-	h_packet := i.Header.Serialize()
-	copy(packet[index:], h_packet)
+	i.Header.SerializeTo(packet[index:index])
 	index += i.Header.Size()
 
 	// Padding
@@ -3746,11 +4633,10 @@ func (i *OfpInstructionActions) Serialize() []byte {
 
 	// Actions
 	for _, a := range i.Actions {
-		a_packet := a.Serialize()
-		copy(packet[index:], a_packet)
+		a.SerializeTo(packet[index:index])
 		index += a.Size()
 	}
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionActions) Parse(packet []byte) {
@@ -3799,16 +4685,22 @@ func NewOfpInstructionMeter(
 }
 
 func (i *OfpInstructionMeter) Serialize() []byte {
-	packet := make([]byte, i.Size())
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionMeter and append it to dst.
+func (i *OfpInstructionMeter) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 	index := 0
-	h_packet := i.Header.Serialize()
 
-	copy(packet[index:], h_packet)
+	i.Header.SerializeTo(packet[index:index])
 	index += i.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], i.MeterId)
 
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionMeter) Parse(packet []byte) {
@@ -3841,16 +4733,22 @@ func NewOfpInstructionExperimenter(
 }
 
 func (i *OfpInstructionExperimenter) Serialize() []byte {
-	packet := make([]byte, i.Size())
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionExperimenter and append it to dst.
+func (i *OfpInstructionExperimenter) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 	index := 0
-	h_packet := i.Header.Serialize()
 
-	copy(packet[index:], h_packet)
+	i.Header.SerializeTo(packet[index:index])
 	index += i.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], i.Experimenter)
 
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionExperimenter) Parse(packet []byte) {
@@ -3878,11 +4776,18 @@ func NewOfpActionHeader(t uint16, length uint16) OfpActionHeader {
 }
 
 func (h *OfpActionHeader) Serialize() []byte {
-	packet := make([]byte, h.Size())
+	return h.SerializeTo(nil)
+}
+
+/// Serialize OfpActionHeader and append it to dst.
+func (h *OfpActionHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, h.Size())
+	packet := dst[start:]
 	binary.BigEndian.PutUint16(packet[0:], h.Type)
 	binary.BigEndian.PutUint16(packet[2:], h.Length)
 
-	return packet
+	return dst
 }
 
 func (h *OfpActionHeader) Parse(packet []byte) {
@@ -3972,22 +4877,28 @@ func NewOfpActionOutput(port uint32, max_len uint16) *OfpActionOutput {
 }
 
 func (a *OfpActionOutput) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionOutput and append it to dst.
+func (a *OfpActionOutput) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
 
 	// ActionHeader must be 64-bit aligned when it is used in it's own terms.
 	// But used as Header of any Action, in here ActionOutput,
 	// alignment is adjusted in terms of whole of Action structure.
 	// Because of that, the size of ActionHeader here is 4.
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 
 	binary.BigEndian.PutUint32(packet[index:], a.Port)
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], a.MaxLen)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionOutput) Parse(packet []byte) {
@@ -4019,13 +4930,19 @@ func NewOfpActionCopyTtlOut() *OfpActionCopyTtlOut {
 }
 
 func (a *OfpActionCopyTtlOut) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionCopyTtlOut and append it to dst.
+func (a *OfpActionCopyTtlOut) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionCopyTtlOut) Parse(packet []byte) {
@@ -4053,12 +4970,18 @@ func NewOfpActionCopyTtlIn() *OfpActionCopyTtlIn {
 }
 
 func (a *OfpActionCopyTtlIn) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionCopyTtlIn and append it to dst.
+func (a *OfpActionCopyTtlIn) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionCopyTtlIn) Parse(packet []byte) {
@@ -4085,14 +5008,20 @@ func NewOfpActionSetMplsTtl(ttl uint8) *OfpActionSetMplsTtl {
 }
 
 func (a *OfpActionSetMplsTtl) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionSetMplsTtl and append it to dst.
+func (a *OfpActionSetMplsTtl) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	packet[index] = a.MplsTtl
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionSetMplsTtl) Parse(packet []byte) {
@@ -4121,12 +5050,18 @@ func NewOfpActionDecMplsTtl() *OfpActionDecMplsTtl {
 }
 
 func (a *OfpActionDecMplsTtl) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionDecMplsTtl and append it to dst.
+func (a *OfpActionDecMplsTtl) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionDecMplsTtl) Parse(packet []byte) {
@@ -4184,14 +5119,20 @@ func NewOfpActionPush(actionType uint16, etherType uint16) *OfpActionPush {
 }
 
 func (a *OfpActionPush) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionPush and append it to dst.
+func (a *OfpActionPush) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], a.EtherType)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionPush) Parse(packet []byte) {
@@ -4240,14 +5181,20 @@ func NewOfpActionPopPbb(etherType uint16) *OfpActionPop {
 }
 
 func (a *OfpActionPop) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionPop and append it to dst.
+func (a *OfpActionPop) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	binary.BigEndian.PutUint16(packet[index:], a.EtherType)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionPop) Parse(packet []byte) {
@@ -4278,14 +5225,20 @@ func NewOfpActionGroup(id uint32) *OfpActionGroup {
 }
 
 func (a *OfpActionGroup) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionGroup and append it to dst.
+func (a *OfpActionGroup) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	binary.BigEndian.PutUint32(packet[index:], a.GroupId)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionGroup) Parse(packet []byte) {
@@ -4316,14 +5269,20 @@ func NewOfpActionSetQueue(id uint32) *OfpActionSetQueue {
 }
 
 func (a *OfpActionSetQueue) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionSetQueue and append it to dst.
+func (a *OfpActionSetQueue) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	binary.BigEndian.PutUint32(packet[index:], a.QueueId)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionSetQueue) Parse(packet []byte) {
@@ -4354,14 +5313,20 @@ func NewOfpActionSetNwTtl(ttl uint8) *OfpActionSetNwTtl {
 }
 
 func (a *OfpActionSetNwTtl) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionSetNwTtl and append it to dst.
+func (a *OfpActionSetNwTtl) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	packet[index] = a.NwTtl
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionSetNwTtl) Parse(packet []byte) {
@@ -4390,12 +5355,18 @@ func NewOfpActionDecNwTtl() *OfpActionDecNwTtl {
 }
 
 func (a *OfpActionDecNwTtl) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionDecNwTtl and append it to dst.
+func (a *OfpActionDecNwTtl) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionDecNwTtl) Parse(packet []byte) {
@@ -4428,16 +5399,21 @@ func newEmptyOfpActionSetField() *OfpActionSetField {
 }
 
 func (a *OfpActionSetField) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionSetField and append it to dst.
+func (a *OfpActionSetField) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, a.Size())
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, a.Size())
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 
-	m_packet := a.Oxm.Serialize()
-	copy(packet[index:], m_packet)
+	a.Oxm.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionSetField) Parse(packet []byte) {
@@ -4450,202 +5426,202 @@ func (a *OfpActionSetField) Parse(packet []byte) {
 	switch oxmField(tlvheader) {
 	case OFPXMT_OFB_IN_PORT:
 		mf := NewOxmInPort(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IN_PHY_PORT:
 		mf := NewOxmInPhyPort(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_METADATA:
 		mf := NewOxmMetadata(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ETH_DST:
 		mf, err := NewOxmEthDst("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ETH_SRC:
 		mf, err := NewOxmEthSrc("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ETH_TYPE:
 		mf := NewOxmEthType(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_VLAN_VID:
 		mf := NewOxmVlanVid(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_VLAN_PCP:
 		mf := NewOxmVlanPcp(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IP_DSCP:
 		mf := NewOxmIpDscp(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IP_ECN:
 		mf := NewOxmIpEcn(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IP_PROTO:
 		mf := NewOxmIpProto(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV4_SRC:
 		mf, err := NewOxmIpv4Src("0.0.0.0")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV4_DST:
 		mf, err := NewOxmIpv4Dst("0.0.0.0")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_TCP_SRC:
 		mf := NewOxmTcpSrc(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_TCP_DST:
 		mf := NewOxmTcpDst(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_UDP_SRC:
 		mf := NewOxmUdpSrc(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_UDP_DST:
 		mf := NewOxmUdpDst(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_SCTP_SRC:
 		mf := NewOxmSctpSrc(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_SCTP_DST:
 		mf := NewOxmSctpDst(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ICMPV4_TYPE:
 		mf := NewOxmIcmpType(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ICMPV4_CODE:
 		mf := NewOxmIcmpCode(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ARP_OP:
 		mf := NewOxmArpOp(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ARP_SPA:
 		mf, err := NewOxmArpSpa("0.0.0.0")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ARP_TPA:
 		mf, err := NewOxmArpTpa("0.0.0.0")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ARP_SHA:
 		mf, err := NewOxmArpSha("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ARP_THA:
 		mf, err := NewOxmArpTha("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_SRC:
 		mf, err := NewOxmIpv6Src("::")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_DST:
 		mf, err := NewOxmIpv6Dst("::")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_FLABEL:
 		mf := NewOxmIpv6FLabel(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ICMPV6_TYPE:
 		mf := NewOxmIcmpv6Type(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_ICMPV6_CODE:
 		mf := NewOxmIcmpv6Code(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_ND_TARGET:
 		mf, err := NewOxmIpv6NdTarget("0.0.0.0")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_ND_SLL:
 		mf, err := NewOxmIpv6NdSll("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_ND_TLL:
 		mf, err := NewOxmIpv6NdTll("00:00:00:00:00:00")
 		if err != nil {
 			// TODO: error handling
 		}
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_MPLS_LABEL:
 		mf := NewOxmMplsLabel(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_MPLS_TC:
 		mf := NewOxmMplsTc(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_MPLS_BOS:
 		mf := NewOxmMplsBos(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_PBB_ISID:
 		mf := NewOxmPbbIsid([3]uint8{0, 0, 0})
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_TUNNEL_ID:
 		mf := NewOxmTunnelId(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	case OFPXMT_OFB_IPV6_EXTHDR:
 		mf := NewOxmIpv6ExtHeader(0)
-		mf.Parse(packet)
+		mf.Parse(packet[index:])
 		a.Oxm = mf
 	default:
 		//TODO: Error handling
@@ -4677,14 +5653,20 @@ func NewOfpActionExperimenter(experimenter uint32) *OfpActionExperimenter {
 }
 
 func (a *OfpActionExperimenter) Serialize() []byte {
+	return a.SerializeTo(nil)
+}
+
+/// Serialize OfpActionExperimenter and append it to dst.
+func (a *OfpActionExperimenter) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, 8)
-	h_packet := a.ActionHeader.Serialize()
-	copy(packet[index:], h_packet)
+	start := len(dst)
+	dst = grow(dst, 8)
+	packet := dst[start:]
+	a.ActionHeader.SerializeTo(packet[index:index])
 	index += 4
 	binary.BigEndian.PutUint32(packet[index:], a.Experimenter)
 
-	return packet
+	return dst
 }
 
 func (a *OfpActionExperimenter) Parse(packet []byte) {
@@ -4713,9 +5695,16 @@ func NewOfpErrorMsg() *OfpErrorMsg {
 }
 
 func (m *OfpErrorMsg) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
-	copy(packet[0:], h_packet)
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpErrorMsg and append it to dst.
+func (m *OfpErrorMsg) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[0:0])
 	index := m.Header.Size()
 	binary.BigEndian.PutUint16(packet[index:], m.Type)
 	index += 2
@@ -4725,7 +5714,7 @@ func (m *OfpErrorMsg) Serialize() []byte {
 		packet[index] = d
 		index += 1
 	}
-	return packet
+	return dst
 }
 
 func (m *OfpErrorMsg) Parse(packet []byte) {
@@ -4735,14 +5724,14 @@ func (m *OfpErrorMsg) Parse(packet []byte) {
 	index += 2
 	m.Code = binary.BigEndian.Uint16(packet[index:])
 	index += 2
-	for int(index) < len(packet) {
+	for index < int(m.Header.Length) {
 		m.Data = append(m.Data, packet[index])
 		index += 1
 	}
 }
 
 func (m *OfpErrorMsg) Size() int {
-	return m.Header.Size() + 8 + len(m.Data)
+	return m.Header.Size() + 4 + len(m.Data)
 }
 
 /*****************************************************/
@@ -4896,11 +5885,18 @@ func NewOfpMultipartRequest(t uint16, flags uint16) *OfpMultipartRequest {
 }
 
 func (m *OfpMultipartRequest) Serialize() []byte {
-	packet := make([]byte, m.Size())
-	h_packet := m.Header.Serialize()
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMultipartRequest and append it to dst.
+func (m *OfpMultipartRequest) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
 	index := 0
-	copy(packet[index:], h_packet)
+	m.Header.Length = (uint16)(m.Size())
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint16(packet[index:], m.Type)
@@ -4910,15 +5906,70 @@ func (m *OfpMultipartRequest) Serialize() []byte {
 	index += 6
 
 	if m.Body != nil {
-		mp_packet := m.Body.Serialize()
-		copy(packet[index:], mp_packet)
+		m.Body.SerializeTo(packet[index:index])
 		index += m.Body.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (m *OfpMultipartRequest) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.Type = binary.BigEndian.Uint16(packet[index:])
+	index += 2
+
+	m.Flags = binary.BigEndian.Uint16(packet[index:])
+	index += 6
+
+	m.Body = nil
+	if index >= (int)(m.Header.Length) {
+		return
+	}
+
+	switch m.Type {
+	case OFPMP_FLOW:
+		mp := newOfpFlowStatsRequestBody(0, 0, 0, 0, 0, nil)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_AGGREGATE:
+		mp := newOfpAggregateStatsRequestBody(0, 0, 0, 0, 0, nil)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_PORT_STATS:
+		mp := newOfpPortStatsRequestBody(0)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_QUEUE:
+		mp := newOfpQueueStatsRequestBody(0, 0)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_GROUP:
+		mp := newOfpGroupStatsRequestBody(0)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_METER, OFPMP_METER_CONFIG:
+		mp := newOfpMeterMultipartRequestBody(0)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_TABLE_FEATURES:
+		mp := NewOfpTableFeatures(
+			0,
+			nil,
+			0,
+			0,
+			0,
+			0,
+			nil)
+		mp.Parse(packet[index:])
+		m.Body = mp
+	case OFPMP_EXPERIMENTER:
+		// TODO: implements
+	default:
+	}
+
 	return
 }
 
@@ -4944,7 +5995,32 @@ func NewOfpMultipartReply() *OfpMultipartReply {
 }
 
 func (m *OfpMultipartReply) Serialize() []byte {
-	return nil
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpMultipartReply and append it to dst.
+func (m *OfpMultipartReply) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	index := 0
+
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[index:index])
+	index += m.Header.Size()
+
+	binary.BigEndian.PutUint16(packet[index:], m.Type)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], m.Flags)
+	index += 6
+
+	for _, mp := range m.Body {
+		mp.SerializeTo(packet[index:index])
+		index += mp.Size()
+	}
+
+	return dst
 }
 
 func (m *OfpMultipartReply) Parse(packet []byte) {
@@ -5097,9 +6173,41 @@ func newOfpDescStats() *OfpDescStats {
 	return mp
 }
 
+/// create switch description. each string is truncated to fit its field
+/// with terminating null.
+func NewOfpDescStats(mfrDesc string, hwDesc string, swDesc string, serialNum string, dpDesc string) *OfpDescStats {
+	mp := newOfpDescStats()
+	copy(mp.MfrDesc[:DESC_STR_LEN-1], mfrDesc)
+	copy(mp.HwDesc[:DESC_STR_LEN-1], hwDesc)
+	copy(mp.SwDesc[:DESC_STR_LEN-1], swDesc)
+	copy(mp.SerialNum[:SERIAL_NUM_LEN-1], serialNum)
+	copy(mp.DpDesc[:DESC_STR_LEN-1], dpDesc)
+	return mp
+}
+
 func (mp *OfpDescStats) Serialize() []byte {
-	// not implement
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpDescStats and append it to dst.
+func (mp *OfpDescStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	// strings are truncated or padded with zero
+	copy(packet[index:(index+DESC_STR_LEN)], mp.MfrDesc)
+	index += DESC_STR_LEN
+	copy(packet[index:(index+DESC_STR_LEN)], mp.HwDesc)
+	index += DESC_STR_LEN
+	copy(packet[index:(index+DESC_STR_LEN)], mp.SwDesc)
+	index += DESC_STR_LEN
+	copy(packet[index:(index+SERIAL_NUM_LEN)], mp.SerialNum)
+	index += SERIAL_NUM_LEN
+	copy(packet[index:(index+DESC_STR_LEN)], mp.DpDesc)
+
+	return dst
 }
 
 func (mp *OfpDescStats) Parse(packet []byte) {
@@ -5149,7 +6257,14 @@ func newOfpFlowStatsRequestBody(
 }
 
 func (m *OfpFlowStatsRequest) Serialize() []byte {
-	packet := make([]byte, m.Size())
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpFlowStatsRequest and append it to dst.
+func (m *OfpFlowStatsRequest) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 	index := 0
 
 	packet[index] = m.TableId
@@ -5167,13 +6282,32 @@ func (m *OfpFlowStatsRequest) Serialize() []byte {
 	binary.BigEndian.PutUint64(packet[index:], m.CookieMask)
 	index += 8
 
-	m_packet := m.Match.Serialize()
-	copy(packet[index:], m_packet)
+	m.Match.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (m *OfpFlowStatsRequest) Parse(packet []byte) {
+	index := 0
+
+	m.TableId = packet[index]
+	index += 4
+
+	m.OutPort = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	m.OutGroup = binary.BigEndian.Uint32(packet[index:])
+	index += 8
+
+	m.Cookie = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+
+	m.CookieMask = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+
+	m.Match = NewOfpMatch()
+	m.Match.Parse(packet[index:])
+
 	return
 }
 
@@ -5194,8 +6328,60 @@ func newOfpFlowStats() *OfpFlowStats {
 	return m
 }
 
-func (mp *OfpFlowStats) Serialize() []byte {
-	return nil
+func (mp *OfpFlowStats) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpFlowStats and append it to dst.
+func (mp *OfpFlowStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	mp.Length = uint16(mp.Size())
+	binary.BigEndian.PutUint16(packet[index:], mp.Length)
+	index += 2
+
+	packet[index] = mp.TableId
+	index += 2 // include Padding
+
+	binary.BigEndian.PutUint32(packet[index:], mp.DurationSec)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], mp.DurationNSec)
+	index += 4
+
+	binary.BigEndian.PutUint16(packet[index:], mp.Priority)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], mp.IdleTimeout)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], mp.HardTimeout)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], mp.Flags)
+	index += 6 // include Padding
+
+	binary.BigEndian.PutUint64(packet[index:], mp.Cookie)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], mp.PacketCount)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], mp.ByteCount)
+	index += 8
+
+	mp.Match.SerializeTo(packet[index:index])
+	index += mp.Match.Size()
+
+	for _, i := range mp.Instructions {
+		i.SerializeTo(packet[index:index])
+		index += i.Size()
+	}
+
+	return dst
 }
 
 func (mp *OfpFlowStats) Parse(packet []byte) {
@@ -5238,46 +6424,13 @@ func (mp *OfpFlowStats) Parse(packet []byte) {
 	index += mp.Match.Size()
 
 	for index < (int)(mp.Length) {
-		t := binary.BigEndian.Uint16(packet[index:])
-
-		// don't forward index ,
-		// because type and length will be parsed in instruction's paraser
-
-		switch t {
-		case OFPIT_GOTO_TABLE:
-			instruction := NewOfpInstructionGotoTable(0)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_WRITE_METADATA:
-			instruction := NewOfpInstructionWriteMetadata(0, 0)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_WRITE_ACTIONS:
-			instruction := NewOfpInstructionActions(OFPIT_WRITE_ACTIONS)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_APPLY_ACTIONS:
-			instruction := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_CLEAR_ACTIONS:
-			instruction := NewOfpInstructionActions(OFPIT_CLEAR_ACTIONS)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_METER:
-			instruction := NewOfpInstructionMeter(0)
-			instruction.Parse(packet[index:])
-			mp.Instructions = append(mp.Instructions, instruction)
-			index += instruction.Size()
-		case OFPIT_EXPERIMENTER:
-		default:
-
+		instruction := ParseInstruction(packet[index:])
+		if instruction == nil {
+			// TODO: Error Handling
+			break
 		}
+		mp.Instructions = append(mp.Instructions, instruction)
+		index += instruction.Size()
 	}
 
 	return
@@ -5316,7 +6469,14 @@ func newOfpAggregateStatsRequestBody(
 }
 
 func (mp *OfpAggregateStatsRequest) Serialize() []byte {
-	packet := make([]byte, mp.Size())
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpAggregateStatsRequest and append it to dst.
+func (mp *OfpAggregateStatsRequest) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 	index := 0
 
 	packet[index] = mp.TableId
@@ -5334,13 +6494,32 @@ func (mp *OfpAggregateStatsRequest) Serialize() []byte {
 	binary.BigEndian.PutUint64(packet[index:], mp.CookieMask)
 	index += 8
 
-	m_packet := mp.Match.Serialize()
-	copy(packet[index:], m_packet)
+	mp.Match.SerializeTo(packet[index:index])
 
-	return packet
+	return dst
 }
 
 func (mp *OfpAggregateStatsRequest) Parse(packet []byte) {
+	index := 0
+
+	mp.TableId = packet[index]
+	index += 4
+
+	mp.OutPort = binary.BigEndian.Uint32(packet[index:])
+	index += 4
+
+	mp.OutGroup = binary.BigEndian.Uint32(packet[index:])
+	index += 8
+
+	mp.Cookie = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+
+	mp.CookieMask = binary.BigEndian.Uint64(packet[index:])
+	index += 8
+
+	mp.Match = NewOfpMatch()
+	mp.Match.Parse(packet[index:])
+
 	return
 }
 
@@ -5362,7 +6541,25 @@ func newOfpAggregateStats() *OfpAggregateStats {
 }
 
 func (mp *OfpAggregateStats) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpAggregateStats and append it to dst.
+func (mp *OfpAggregateStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint64(packet[index:], mp.PacketCount)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], mp.ByteCount)
+	index += 8
+
+	binary.BigEndian.PutUint32(packet[index:], mp.FlowCount)
+
+	return dst
 }
 
 func (mp *OfpAggregateStats) Parse(packet []byte) {
@@ -5394,15 +6591,22 @@ func NewOfpTableFeaturePropHeader(t uint16, length uint16) OfpTableFeaturePropHe
 }
 
 func (p *OfpTableFeaturePropHeader) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropHeader and append it to dst.
+func (p *OfpTableFeaturePropHeader) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint16(packet[index:], p.Type)
 	index += 2
 
 	binary.BigEndian.PutUint16(packet[index:], p.Length)
 
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropHeader) Parse(packet []byte) {
@@ -5430,15 +6634,22 @@ func NewOfpInstructionId(t uint16, length uint16) *OfpInstructionId {
 }
 
 func (i *OfpInstructionId) Serialize() []byte {
+	return i.SerializeTo(nil)
+}
+
+/// Serialize OfpInstructionId and append it to dst.
+func (i *OfpInstructionId) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, i.Size())
+	start := len(dst)
+	dst = grow(dst, i.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint16(packet[index:], i.Type)
 	index += 2
 
 	binary.BigEndian.PutUint16(packet[index:], i.Length)
 
-	return packet
+	return dst
 }
 
 func (i *OfpInstructionId) Parse(packet []byte) {
@@ -5474,20 +6685,25 @@ func NewOfpTableFeaturePropInstructions(
 }
 
 func (p *OfpTableFeaturePropInstructions) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropInstructions and append it to dst.
+func (p *OfpTableFeaturePropInstructions) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
-	h_packet := p.PropHeader.Serialize()
-	copy(packet[index:], h_packet)
+	p.PropHeader.SerializeTo(packet[index:index])
 	index += p.PropHeader.Size()
 
 	for _, id := range p.InstructionIds {
-		id_packet := id.Serialize()
-		copy(packet[index:], id_packet)
+		id.SerializeTo(packet[index:index])
 		index += id.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropInstructions) Parse(packet []byte) {
@@ -5532,16 +6748,22 @@ func NewOfpTableFeaturePropNextTables(t uint16, ids []uint8) *OfpTableFeaturePro
 }
 
 func (p *OfpTableFeaturePropNextTables) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropNextTables and append it to dst.
+func (p *OfpTableFeaturePropNextTables) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
-	h_packet := p.PropHeader.Serialize()
-	copy(packet[index:], h_packet)
+	p.PropHeader.SerializeTo(packet[index:index])
 	index += p.PropHeader.Size()
 
 	copy(packet[index:], p.NextTableIds)
 
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropNextTables) Parse(packet []byte) {
@@ -5585,20 +6807,25 @@ func NewOfpTableFeaturePropActions(t uint16, ids []OfpActionHeader) *OfpTableFea
 }
 
 func (p *OfpTableFeaturePropActions) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropActions and append it to dst.
+func (p *OfpTableFeaturePropActions) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
-	h_packet := p.PropHeader.Serialize()
-	copy(packet[index:], h_packet)
+	p.PropHeader.SerializeTo(packet[index:index])
 	index += p.PropHeader.Size()
 
 	for _, id := range p.ActionIds {
-		id_packet := id.Serialize()
-		copy(packet[index:], id_packet)
+		id.SerializeTo(packet[index:index])
 		index += id.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropActions) Parse(packet []byte) {
@@ -5644,18 +6871,24 @@ func NewOfpTableFeaturePropOxm(t uint16, ids []uint32) *OfpTableFeaturePropOxm {
 }
 
 func (p *OfpTableFeaturePropOxm) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropOxm and append it to dst.
+func (p *OfpTableFeaturePropOxm) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
-	h_packet := p.PropHeader.Serialize()
-	copy(packet[index:], h_packet)
+	p.PropHeader.SerializeTo(packet[index:index])
 	index += p.PropHeader.Size()
 
 	for _, id := range p.OxmIds {
 		binary.BigEndian.PutUint32(packet[index:], id)
 		index += 4
 	}
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropOxm) Parse(packet []byte) {
@@ -5712,11 +6945,17 @@ func NewOfpTableFeaturePropExperimenter(
 }
 
 func (p *OfpTableFeaturePropExperimenter) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeaturePropExperimenter and append it to dst.
+func (p *OfpTableFeaturePropExperimenter) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, p.Size())
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
 
-	h_packet := p.PropHeader.Serialize()
-	copy(packet[index:], h_packet)
+	p.PropHeader.SerializeTo(packet[index:index])
 	index += p.PropHeader.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], p.Experimenter)
@@ -5730,7 +6969,7 @@ func (p *OfpTableFeaturePropExperimenter) Serialize() []byte {
 		index += 4
 	}
 
-	return packet
+	return dst
 }
 
 func (p *OfpTableFeaturePropExperimenter) Parse(packet []byte) {
@@ -5744,7 +6983,7 @@ func (p *OfpTableFeaturePropExperimenter) Parse(packet []byte) {
 	p.ExpType = binary.BigEndian.Uint32(packet[index:])
 	index += 4
 
-	p.ExperimenterData = make([]uint32, p.PropHeader.Length-12)
+	p.ExperimenterData = make([]uint32, (p.PropHeader.Length-12)/4)
 	d_index := 0
 
 	for index < (int)(p.PropHeader.Length) {
@@ -5803,8 +7042,15 @@ func NewOfpTableFeatures(
 }
 
 func (mp *OfpTableFeatures) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpTableFeatures and append it to dst.
+func (mp *OfpTableFeatures) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint16(packet[index:], mp.Length)
 	index += 2
@@ -5828,12 +7074,11 @@ func (mp *OfpTableFeatures) Serialize() []byte {
 	index += 4
 
 	for _, prop := range mp.Properties {
-		p_packet := prop.Serialize()
-		copy(packet[index:], p_packet)
+		prop.SerializeTo(packet[index:index])
 		index += prop.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (mp *OfpTableFeatures) Parse(packet []byte) {
@@ -5922,7 +7167,28 @@ func newOfpTableStats() *OfpTableStats {
 }
 
 func (mp *OfpTableStats) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpTableStats and append it to dst.
+func (mp *OfpTableStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	packet[index] = mp.TableId
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], mp.ActiveCount)
+	index += 4
+
+	binary.BigEndian.PutUint64(packet[index:], mp.LookupCount)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], mp.MatchedCount)
+
+	return dst
 }
 
 func (mp *OfpTableStats) Parse(packet []byte) {
@@ -5959,11 +7225,18 @@ func newOfpPortStatsRequestBody(portNo uint32) *OfpPortStatsRequest {
 }
 
 func (mp *OfpPortStatsRequest) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpPortStatsRequest and append it to dst.
+func (mp *OfpPortStatsRequest) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 	binary.BigEndian.PutUint32(packet[index:], mp.PortNo)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpPortStatsRequest) Parse(packet []byte) {
@@ -5989,11 +7262,18 @@ func newOfpPortStats() *OfpPortStats {
 }
 
 func (mp *OfpPortStats) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpPortStats and append it to dst.
+func (mp *OfpPortStats) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.PortNo)
-	index += 4
+	index += 8 // include Padding
 
 	binary.BigEndian.PutUint64(packet[index:], mp.RxPackets)
 	index += 8
@@ -6036,7 +7316,7 @@ func (mp *OfpPortStats) Serialize() []byte {
 
 	binary.BigEndian.PutUint32(packet[index:], mp.DurationNSec)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpPortStats) Parse(packet []byte) {
@@ -6108,15 +7388,22 @@ func newOfpQueueStatsRequestBody(portNo uint32, queueId uint32) *OfpQueueStatsRe
 }
 
 func (mp *OfpQueueStatsRequest) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpQueueStatsRequest and append it to dst.
+func (mp *OfpQueueStatsRequest) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.PortNo)
 	index += 4
 
 	binary.BigEndian.PutUint32(packet[index:], mp.QueueId)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpQueueStatsRequest) Parse(packet []byte) {
@@ -6147,8 +7434,15 @@ func newOfpQueueStats() *OfpQueueStats {
 }
 
 func (mp *OfpQueueStats) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpQueueStats and append it to dst.
+func (mp *OfpQueueStats) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.PortNo)
 	index += 4
@@ -6170,7 +7464,7 @@ func (mp *OfpQueueStats) Serialize() []byte {
 
 	binary.BigEndian.PutUint32(packet[index:], mp.DurationNSec)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpQueueStats) Parse(packet []byte) {
@@ -6217,12 +7511,19 @@ func newOfpGroupStatsRequestBody(groupId uint32) *OfpGroupStatsRequest {
 }
 
 func (mp *OfpGroupStatsRequest) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpGroupStatsRequest and append it to dst.
+func (mp *OfpGroupStatsRequest) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.GroupId)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpGroupStatsRequest) Parse(packet []byte) {
@@ -6255,15 +7556,22 @@ func newOfpBucketCounter(
 }
 
 func (bc *OfpBucketCounter) Serialize() []byte {
+	return bc.SerializeTo(nil)
+}
+
+/// Serialize OfpBucketCounter and append it to dst.
+func (bc *OfpBucketCounter) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, bc.Size())
+	start := len(dst)
+	dst = grow(dst, bc.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint64(packet[index:], bc.PacketCount)
 	index += 8
 
 	binary.BigEndian.PutUint64(packet[index:], bc.ByteCount)
 
-	return packet
+	return dst
 }
 
 func (bc *OfpBucketCounter) Parse(packet []byte) {
@@ -6290,8 +7598,15 @@ func newOfpGroupStats() *OfpGroupStats {
 }
 
 func (mp *OfpGroupStats) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpGroupStats and append it to dst.
+func (mp *OfpGroupStats) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint16(packet[index:], mp.Length)
 	index += 4
@@ -6315,12 +7630,11 @@ func (mp *OfpGroupStats) Serialize() []byte {
 	index += 4
 
 	for _, bc := range mp.BucketStats {
-		bc_packet := bc.Serialize()
-		copy(packet[index:], bc_packet)
+		bc.SerializeTo(packet[index:index])
 		index += bc.Size()
 	}
 
-	return packet
+	return dst
 }
 
 func (mp *OfpGroupStats) Parse(packet []byte) {
@@ -6378,7 +7692,32 @@ func newOfpGroupDescStats() *OfpGroupDescStats {
 }
 
 func (mp *OfpGroupDescStats) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpGroupDescStats and append it to dst.
+func (mp *OfpGroupDescStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	mp.Length = uint16(mp.Size())
+	binary.BigEndian.PutUint16(packet[index:], mp.Length)
+	index += 2
+
+	packet[index] = mp.Type
+	index += 2
+
+	binary.BigEndian.PutUint32(packet[index:], mp.GroupId)
+	index += 4
+
+	for _, b := range mp.Buckets {
+		b.SerializeTo(packet[index:index])
+		index += b.Size()
+	}
+
+	return dst
 }
 
 func (mp *OfpGroupDescStats) Parse(packet []byte) {
@@ -6404,7 +7743,7 @@ func (mp *OfpGroupDescStats) Parse(packet []byte) {
 }
 
 func (mp *OfpGroupDescStats) Size() int {
-	size := 16
+	size := 8
 	for _, b := range mp.Buckets {
 		size += b.Size()
 	}
@@ -6424,8 +7763,15 @@ func newOfpGroupFeaturesStats() *OfpGroupFeaturesStats {
 }
 
 func (mp *OfpGroupFeaturesStats) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpGroupFeaturesStats and append it to dst.
+func (mp *OfpGroupFeaturesStats) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.Type)
 	index += 4
@@ -6451,7 +7797,7 @@ func (mp *OfpGroupFeaturesStats) Serialize() []byte {
 	binary.BigEndian.PutUint32(packet[index:], mp.Actions[3])
 	index += 4
 
-	return packet
+	return dst
 }
 
 func (mp *OfpGroupFeaturesStats) Parse(packet []byte) {
@@ -6502,15 +7848,23 @@ func newOfpMeterMultipartRequestBody(meterId uint32) *OfpMeterMultipartRequest {
 }
 
 func (mp *OfpMeterMultipartRequest) Serialize() []byte {
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterMultipartRequest and append it to dst.
+func (mp *OfpMeterMultipartRequest) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, mp.Size())
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
 
 	binary.BigEndian.PutUint32(packet[index:], mp.MeterId)
 
-	return packet
+	return dst
 }
 
 func (mp *OfpMeterMultipartRequest) Parse(packet []byte) {
+	mp.MeterId = binary.BigEndian.Uint32(packet)
 	return
 }
 
@@ -6535,7 +7889,21 @@ func newOfpMeterBandStats(
 }
 
 func (mb *OfpMeterBandStats) Serialize() []byte {
-	return nil
+	return mb.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterBandStats and append it to dst.
+func (mb *OfpMeterBandStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mb.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint64(packet[index:], mb.PacketBandCount)
+	index += 8
+	binary.BigEndian.PutUint64(packet[index:], mb.ByteBandCount)
+
+	return dst
 }
 
 func (mb *OfpMeterBandStats) Parse(packet []byte) {
@@ -6561,7 +7929,44 @@ func newOfpMeterStats() *OfpMeterStats {
 }
 
 func (mp *OfpMeterStats) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterStats and append it to dst.
+func (mp *OfpMeterStats) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint32(packet[index:], mp.MeterId)
+	index += 4
+
+	mp.Length = uint16(mp.Size())
+	binary.BigEndian.PutUint16(packet[index:], mp.Length)
+	index += 8
+
+	binary.BigEndian.PutUint32(packet[index:], mp.FlowCount)
+	index += 4
+
+	binary.BigEndian.PutUint64(packet[index:], mp.PacketInCount)
+	index += 8
+
+	binary.BigEndian.PutUint64(packet[index:], mp.ByteInCount)
+	index += 8
+
+	binary.BigEndian.PutUint32(packet[index:], mp.DurationSec)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], mp.DurationNSec)
+	index += 4
+
+	for _, mb := range mp.BandStats {
+		mb.SerializeTo(packet[index:index])
+		index += mb.Size()
+	}
+
+	return dst
 }
 
 func (mp *OfpMeterStats) Parse(packet []byte) {
@@ -6619,7 +8024,32 @@ func newOfpMeterConfig() *OfpMeterConfig {
 }
 
 func (mp *OfpMeterConfig) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterConfig and append it to dst.
+func (mp *OfpMeterConfig) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	mp.Length = uint16(mp.Size())
+	binary.BigEndian.PutUint16(packet[index:], mp.Length)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], mp.Flags)
+	index += 2
+
+	binary.BigEndian.PutUint32(packet[index:], mp.MeterId)
+	index += 4
+
+	for _, mb := range mp.Bands {
+		mb.SerializeTo(packet[index:index])
+		index += mb.Size()
+	}
+
+	return dst
 }
 
 func (mp *OfpMeterConfig) Parse(packet []byte) {
@@ -6664,7 +8094,31 @@ func newOfpMeterFeaturesStats() *OfpMeterFeatures {
 }
 
 func (mp *OfpMeterFeatures) Serialize() []byte {
-	return nil
+	return mp.SerializeTo(nil)
+}
+
+/// Serialize OfpMeterFeatures and append it to dst.
+func (mp *OfpMeterFeatures) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, mp.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint32(packet[index:], mp.MaxMeter)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], mp.BandTypes)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], mp.Capabilities)
+	index += 4
+
+	packet[index] = mp.MaxBands
+	index++
+
+	packet[index] = mp.MaxColor
+
+	return dst
 }
 
 func (mp *OfpMeterFeatures) Parse(packet []byte) {
@@ -6709,19 +8163,30 @@ func NewOfpQueueGetConfigRequest(port uint32) *OfpQueueGetConfigRequest {
 }
 
 func (m *OfpQueueGetConfigRequest) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpQueueGetConfigRequest and append it to dst.
+func (m *OfpQueueGetConfigRequest) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.Port)
 
-	return packet
+	return dst
 }
 
 func (m *OfpQueueGetConfigRequest) Parse(packet []byte) {
+	index := 0
+	m.Header.Parse(packet[index:])
+	index += m.Header.Size()
+
+	m.Port = binary.BigEndian.Uint32(packet[index:])
 	return
 }
 
@@ -6744,7 +8209,7 @@ func newOfpQueuePropHeader(prop uint16, length uint16) OfpQueuePropHeader {
 	return h
 }
 
-func (h OfpQueuePropHeader) Parse(packet []byte) {
+func (h *OfpQueuePropHeader) Parse(packet []byte) {
 	index := 0
 	h.Property = binary.BigEndian.Uint16(packet[index:])
 	index += 2
@@ -6754,6 +8219,25 @@ func (h OfpQueuePropHeader) Parse(packet []byte) {
 	return
 }
 
+func (h *OfpQueuePropHeader) Serialize() []byte {
+	return h.SerializeTo(nil)
+}
+
+/// Serialize OfpQueuePropHeader and append it to dst.
+func (h *OfpQueuePropHeader) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, h.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint16(packet[index:], h.Property)
+	index += 2
+
+	binary.BigEndian.PutUint16(packet[index:], h.Length)
+
+	return dst
+}
+
 func (h OfpQueuePropHeader) Size() int {
 	return 8
 }
@@ -6769,6 +8253,26 @@ func newOfpQueuePropMinRate() *OfpQueuePropMinRate {
 	return p
 }
 
+func (p *OfpQueuePropMinRate) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpQueuePropMinRate and append it to dst.
+func (p *OfpQueuePropMinRate) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
+	index := 0
+
+	p.PropHeader.Length = uint16(p.Size())
+	p.PropHeader.SerializeTo(packet[index:index])
+	index += p.PropHeader.Size()
+
+	binary.BigEndian.PutUint16(packet[index:], p.Rate)
+
+	return dst
+}
+
 func (p *OfpQueuePropMinRate) Parse(packet []byte) {
 	index := 0
 	p.PropHeader.Parse(packet[index:])
@@ -6798,6 +8302,26 @@ func newOfpQueuePropMaxRate() *OfpQueuePropMaxRate {
 	return p
 }
 
+func (p *OfpQueuePropMaxRate) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpQueuePropMaxRate and append it to dst.
+func (p *OfpQueuePropMaxRate) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
+	index := 0
+
+	p.PropHeader.Length = uint16(p.Size())
+	p.PropHeader.SerializeTo(packet[index:index])
+	index += p.PropHeader.Size()
+
+	binary.BigEndian.PutUint16(packet[index:], p.Rate)
+
+	return dst
+}
+
 func (p *OfpQueuePropMaxRate) Parse(packet []byte) {
 	index := 0
 	p.PropHeader.Parse(packet[index:])
@@ -6827,6 +8351,29 @@ func newOfpQueuePropExperimenter() *OfpQueuePropExperimenter {
 	return p
 }
 
+func (p *OfpQueuePropExperimenter) Serialize() []byte {
+	return p.SerializeTo(nil)
+}
+
+/// Serialize OfpQueuePropExperimenter and append it to dst.
+func (p *OfpQueuePropExperimenter) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, p.Size())
+	packet := dst[start:]
+	index := 0
+
+	p.PropHeader.Length = uint16(p.Size())
+	p.PropHeader.SerializeTo(packet[index:index])
+	index += p.PropHeader.Size()
+
+	binary.BigEndian.PutUint32(packet[index:], p.Experimenter)
+	index += 8
+
+	copy(packet[index:], p.Data)
+
+	return dst
+}
+
 func (p *OfpQueuePropExperimenter) Parse(packet []byte) {
 	index := 0
 	p.PropHeader.Parse(packet[index:])
@@ -6860,6 +8407,35 @@ func newOfpPacketQueue() *OfpPacketQueue {
 	return q
 }
 
+func (q *OfpPacketQueue) Serialize() []byte {
+	return q.SerializeTo(nil)
+}
+
+/// Serialize OfpPacketQueue and append it to dst.
+func (q *OfpPacketQueue) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, q.Size())
+	packet := dst[start:]
+	index := 0
+
+	binary.BigEndian.PutUint32(packet[index:], q.QueueId)
+	index += 4
+
+	binary.BigEndian.PutUint32(packet[index:], q.Port)
+	index += 4
+
+	q.Length = uint16(q.Size())
+	binary.BigEndian.PutUint16(packet[index:], q.Length)
+	index += 8
+
+	for _, p := range q.Properties {
+		p.SerializeTo(packet[index:index])
+		index += p.Size()
+	}
+
+	return dst
+}
+
 func (q *OfpPacketQueue) Parse(packet []byte) {
 	index := 0
 	q.QueueId = binary.BigEndian.Uint32(packet[index:])
@@ -6878,17 +8454,17 @@ func (q *OfpPacketQueue) Parse(packet []byte) {
 			prop := newOfpQueuePropMinRate()
 			prop.Parse(packet[index:])
 			q.Properties = append(q.Properties, prop)
-			index += q.Size()
+			index += prop.Size()
 		case OFPQT_MAX_RATE:
 			prop := newOfpQueuePropMaxRate()
 			prop.Parse(packet[index:])
 			q.Properties = append(q.Properties, prop)
-			index += q.Size()
+			index += prop.Size()
 		case OFPQT_EXPERIMENTER:
 			prop := newOfpQueuePropExperimenter()
 			prop.Parse(packet[index:])
 			q.Properties = append(q.Properties, prop)
-			index += q.Size()
+			index += prop.Size()
 		default:
 			// TODO: Error Handling
 			index = (int)(q.Length)
@@ -6918,7 +8494,29 @@ func NewOfpQueueGetConfigReply() *OfpQueueGetConfigReply {
 }
 
 func (m *OfpQueueGetConfigReply) Serialize() []byte {
-	return nil
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpQueueGetConfigReply and append it to dst.
+func (m *OfpQueueGetConfigReply) SerializeTo(dst []byte) []byte {
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
+	index := 0
+
+	m.Header.Length = uint16(m.Size())
+	m.Header.SerializeTo(packet[index:index])
+	index += m.Header.Size()
+
+	binary.BigEndian.PutUint32(packet[index:], m.Port)
+	index += 8
+
+	for _, q := range m.Queue {
+		q.SerializeTo(packet[index:index])
+		index += q.Size()
+	}
+
+	return dst
 }
 
 func (m *OfpQueueGetConfigReply) Parse(packet []byte) {
@@ -6929,7 +8527,7 @@ func (m *OfpQueueGetConfigReply) Parse(packet []byte) {
 	m.Port = binary.BigEndian.Uint32(packet[index:])
 	index += 8
 
-	for index < len(packet) {
+	for index < int(m.Header.Length) {
 		q := newOfpPacketQueue()
 		q.Parse(packet[index:])
 		m.Queue = append(m.Queue, q)
@@ -6971,11 +8569,17 @@ func newOfpRole(t uint8, role uint32, generationId uint64) *OfpRole {
 }
 
 func (m *OfpRole) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpRole and append it to dst.
+func (m *OfpRole) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.Role)
@@ -6983,7 +8587,7 @@ func (m *OfpRole) Serialize() []byte {
 
 	binary.BigEndian.PutUint64(packet[index:], m.GenerationId)
 
-	return packet
+	return dst
 }
 
 func (m *OfpRole) Parse(packet []byte) {
@@ -7037,11 +8641,17 @@ func newOfpAsyncConfig(t uint8) *OfpAsyncConfig {
 }
 
 func (m *OfpAsyncConfig) Serialize() []byte {
+	return m.SerializeTo(nil)
+}
+
+/// Serialize OfpAsyncConfig and append it to dst.
+func (m *OfpAsyncConfig) SerializeTo(dst []byte) []byte {
 	index := 0
-	packet := make([]byte, m.Size())
+	start := len(dst)
+	dst = grow(dst, m.Size())
+	packet := dst[start:]
 
-	h_packet := m.Header.Serialize()
-	copy(packet[index:], h_packet)
+	m.Header.SerializeTo(packet[index:index])
 	index += m.Header.Size()
 
 	binary.BigEndian.PutUint32(packet[index:], m.PacketInMask[0])
@@ -7059,7 +8669,7 @@ func (m *OfpAsyncConfig) Serialize() []byte {
 	binary.BigEndian.PutUint32(packet[index:], m.FlowRemovedMask[1])
 	index += 4
 
-	return packet
+	return dst
 }
 
 func (m *OfpAsyncConfig) Parse(packet []byte) {
@@ -0,0 +1,402 @@
+package ofp13
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// serialize msg, parse it again and compare with the original
+func testRoundTrip(t *testing.T, msg OFMessage) {
+	testRoundTripDirection(t, msg, FROM_SWITCH)
+}
+
+func testRoundTripDirection(t *testing.T, msg OFMessage, dir Direction) {
+	packet := msg.Serialize()
+	if len(packet) != msg.Size() {
+		t.Errorf("Serialized length of %T is %d, expected %d.", msg, len(packet), msg.Size())
+		return
+	}
+	if length := int(binary.BigEndian.Uint16(packet[2:])); length != len(packet) {
+		t.Errorf("Length in header of %T is %d, expected %d.", msg, length, len(packet))
+		return
+	}
+
+	// trailing bytes must not be parsed as part of msg
+	parsed := ParseDirection(append(packet, 0xff, 0xff, 0xff, 0xff)[:len(packet)], dir)
+	if !reflect.DeepEqual(msg, parsed) {
+		t.Log("Serialized Value is : ", hex.EncodeToString(packet))
+		t.Log("Expected Value is   : ", msg)
+		t.Log("Actual Value is     : ", parsed)
+		t.Errorf("Round trip of %T is invalid.", msg)
+	}
+}
+
+// wrap bodies into multipart reply
+func newTestMultipartReply(t uint16, bodies ...OfpMultipartBody) *OfpMultipartReply {
+	m := NewOfpMultipartReply()
+	m.Type = t
+	m.Flags = OFPMPF_REPLY_MORE
+	for _, mp := range bodies {
+		m.Append(mp)
+	}
+	return m
+}
+
+func newTestPort(portNo uint32) *OfpPort {
+	p := newOfpPort()
+	p.PortNo = portNo
+	p.HwAddr, _ = net.ParseMAC("00:11:22:33:44:55")
+	p.Name = make([]byte, OFP_MAX_PORT_NAME_LEN)
+	copy(p.Name, "eth1")
+	p.Config = OFPPC_NO_PACKET_IN
+	p.State = OFPPS_LIVE
+	p.Curr = OFPPF_1GB_FD | OFPPF_COPPER
+	p.Advertised = OFPPF_1GB_FD
+	p.Supported = OFPPF_1GB_FD | OFPPF_10GB_FD
+	p.Peer = OFPPF_1GB_FD
+	p.CurrSpeed = 1000000
+	p.MaxSpeed = 10000000
+	return p
+}
+
+func newTestMatch() *OfpMatch {
+	match := NewOfpMatch()
+	match.Append(NewOxmInPort(1))
+	match.Append(NewOxmEthType(0x0800))
+	return match
+}
+
+func newTestBucket() *OfpBucket {
+	b := NewOfpBucket(10, OFPP_ANY, OFPG_ANY)
+	b.Append(NewOfpActionOutput(2, 0))
+	return b
+}
+
+func newTestTableFeatures(props []OfpTableFeatureProp) *OfpTableFeatures {
+	name := make([]byte, OFP_MAX_TABLE_NAME_LEN)
+	copy(name, "table0")
+	return NewOfpTableFeatures(0, name, 0xff, 0xff, 0, 1000, props)
+}
+
+/*****************************************************/
+/* Switch-originated messages                        */
+/*****************************************************/
+func TestRoundTripMessages(t *testing.T) {
+	features := NewOfpFeaturesReply()
+	features.DatapathId = 0x0102030405060708
+	features.NBuffers = 256
+	features.NTables = 254
+	features.AuxiliaryId = 1
+	features.Capabilities = OFPC_FLOW_STATS | OFPC_PORT_STATS
+	testRoundTrip(t, features)
+
+	config := newOfpSwitchConfig(OFPT_GET_CONFIG_REPLY, 1, 128)
+	testRoundTrip(t, config)
+
+	packetIn := NewOfpPacketIn()
+	packetIn.BufferId = OFP_NO_BUFFER
+	packetIn.TotalLen = 64
+	packetIn.Reason = OFPR_ACTION
+	packetIn.TableId = 1
+	packetIn.Cookie = 0x1234
+	packetIn.Match = newTestMatch()
+	packetIn.Data = make([]byte, 64)
+	packetIn.Data[0] = 0xff
+	testRoundTrip(t, packetIn)
+
+	removed := NewOfpFlowRemoved()
+	removed.Cookie = 0x1234
+	removed.Priority = 100
+	removed.Reason = OFPRR_IDLE_TIMEOUT
+	removed.TableId = 1
+	removed.DurationSec = 10
+	removed.DurationNSec = 20
+	removed.IdleTimeout = 30
+	removed.HardTimeout = 40
+	removed.PacketCount = 50
+	removed.ByteCount = 60
+	removed.Match = newTestMatch()
+	testRoundTrip(t, removed)
+
+	status := NewOfpPortStatus()
+	status.Reason = OFPPR_MODIFY
+	status.Desc = newTestPort(3)
+	testRoundTrip(t, status)
+
+	errMsg := NewOfpErrorMsg()
+	errMsg.Type = OFPET_BAD_REQUEST
+	errMsg.Code = OFPBRC_BAD_TYPE
+	errMsg.Data = []byte{0x04, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01}
+	testRoundTrip(t, errMsg)
+
+	role := NewOfpRoleReply()
+	role.Role = OFPCT_ROLE_MASTER
+	role.GenerationId = 100
+	testRoundTrip(t, role)
+
+	async := NewOfpGetAsyncReply()
+	async.PacketInMask = [2]uint32{1, 2}
+	async.PortStatusMask = [2]uint32{3, 4}
+	async.FlowRemovedMask = [2]uint32{5, 6}
+	testRoundTrip(t, async)
+
+	testRoundTrip(t, NewOfpHello())
+	testRoundTrip(t, NewOfpEchoReply())
+	testRoundTrip(t, NewOfpBarrierReply())
+}
+
+func TestRoundTripQueueGetConfigReply(t *testing.T) {
+	minRate := newOfpQueuePropMinRate()
+	minRate.Rate = 100
+	maxRate := newOfpQueuePropMaxRate()
+	maxRate.Rate = 1000
+	experimenter := newOfpQueuePropExperimenter()
+	experimenter.Experimenter = 0x2320
+	experimenter.Data = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	queue := newOfpPacketQueue()
+	queue.QueueId = 1
+	queue.Port = 2
+	queue.Properties = []OfpQueueProp{minRate, maxRate, experimenter}
+	empty := newOfpPacketQueue()
+	empty.QueueId = 2
+	empty.Port = 2
+
+	m := NewOfpQueueGetConfigReply()
+	m.Port = 2
+	m.Queue = []*OfpPacketQueue{queue, empty}
+	testRoundTrip(t, m)
+}
+
+/*****************************************************/
+/* Multipart bodies                                  */
+/*****************************************************/
+func TestRoundTripMultipartReply(t *testing.T) {
+	desc := newOfpDescStats()
+	copy(desc.MfrDesc, "gofc")
+	copy(desc.HwDesc, "hardware")
+	copy(desc.SwDesc, "software")
+	copy(desc.SerialNum, "0001")
+	copy(desc.DpDesc, "datapath")
+	testRoundTrip(t, newTestMultipartReply(OFPMP_DESC, desc))
+
+	instruction := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+	instruction.Append(NewOfpActionOutput(2, 0))
+	flow := newOfpFlowStats()
+	flow.TableId = 1
+	flow.DurationSec = 10
+	flow.DurationNSec = 20
+	flow.Priority = 100
+	flow.IdleTimeout = 30
+	flow.HardTimeout = 40
+	flow.Flags = OFPFF_SEND_FLOW_REM
+	flow.Cookie = 0x1234
+	flow.PacketCount = 50
+	flow.ByteCount = 60
+	flow.Match = newTestMatch()
+	flow.Instructions = []OfpInstruction{instruction}
+	flow2 := newOfpFlowStats()
+	flow2.Match = NewOfpMatch()
+	testRoundTrip(t, newTestMultipartReply(OFPMP_FLOW, flow, flow2))
+
+	aggregate := newOfpAggregateStats()
+	aggregate.PacketCount = 10
+	aggregate.ByteCount = 20
+	aggregate.FlowCount = 30
+	testRoundTrip(t, newTestMultipartReply(OFPMP_AGGREGATE, aggregate))
+
+	table := newOfpTableStats()
+	table.TableId = 1
+	table.ActiveCount = 10
+	table.LookupCount = 20
+	table.MatchedCount = 30
+	testRoundTrip(t, newTestMultipartReply(OFPMP_TABLE, table))
+
+	port := newOfpPortStats()
+	port.PortNo = 1
+	port.RxPackets = 10
+	port.TxBytes = 20
+	port.Collisions = 30
+	port.DurationSec = 40
+	testRoundTrip(t, newTestMultipartReply(OFPMP_PORT_STATS, port))
+
+	queue := newOfpQueueStats()
+	queue.PortNo = 1
+	queue.QueueId = 2
+	queue.TxBytes = 30
+	testRoundTrip(t, newTestMultipartReply(OFPMP_QUEUE, queue))
+
+	group := newOfpGroupStats()
+	group.GroupId = 1
+	group.RefCount = 2
+	group.PacketCount = 30
+	group.BucketStats = []*OfpBucketCounter{newOfpBucketCounter(1, 2), newOfpBucketCounter(3, 4)}
+	group.Length = uint16(group.Size())
+	testRoundTrip(t, newTestMultipartReply(OFPMP_GROUP, group))
+
+	groupDesc := newOfpGroupDescStats()
+	groupDesc.Type = OFPGT_SELECT
+	groupDesc.GroupId = 1
+	groupDesc.Buckets = []*OfpBucket{newTestBucket(), newTestBucket()}
+	testRoundTrip(t, newTestMultipartReply(OFPMP_GROUP_DESC, groupDesc))
+
+	groupFeatures := newOfpGroupFeaturesStats()
+	groupFeatures.Type = OFPGT_ALL
+	groupFeatures.Capabilities = OFPGC_SELECT_WEIGHT
+	groupFeatures.MaxGroups = [4]uint32{1, 2, 3, 4}
+	groupFeatures.Actions = [4]uint32{5, 6, 7, 8}
+	testRoundTrip(t, newTestMultipartReply(OFPMP_GROUP_FEATURES, groupFeatures))
+
+	meter := newOfpMeterStats()
+	meter.MeterId = 1
+	meter.FlowCount = 2
+	meter.PacketInCount = 3
+	meter.ByteInCount = 4
+	meter.DurationSec = 5
+	meter.DurationNSec = 6
+	meter.BandStats = []*OfpMeterBandStats{newOfpMeterBandStats(7, 8)}
+	testRoundTrip(t, newTestMultipartReply(OFPMP_METER, meter))
+
+	meterConfig := newOfpMeterConfig()
+	meterConfig.Flags = OFPMF_KBPS
+	meterConfig.MeterId = 1
+	meterConfig.Bands = []OfpMeterBand{NewOfpMeterBandDrop(100, 10), NewOfpMeterBandDscpRemark(200, 20, 1)}
+	testRoundTrip(t, newTestMultipartReply(OFPMP_METER_CONFIG, meterConfig))
+
+	meterFeatures := newOfpMeterFeaturesStats()
+	meterFeatures.MaxMeter = 100
+	meterFeatures.BandTypes = 1 << OFPMBT_DROP
+	meterFeatures.Capabilities = OFPMF_KBPS
+	meterFeatures.MaxBands = 2
+	meterFeatures.MaxColor = 3
+	testRoundTrip(t, newTestMultipartReply(OFPMP_METER_FEATURES, meterFeatures))
+
+	testRoundTrip(t, newTestMultipartReply(OFPMP_TABLE_FEATURES, newTestTableFeatures(nil)))
+
+	testRoundTrip(t, newTestMultipartReply(OFPMP_PORT_DESC, newTestPort(1), newTestPort(2)))
+}
+
+func TestRoundTripTableFeatures(t *testing.T) {
+	props := []OfpTableFeatureProp{
+		NewOfpTableFeaturePropInstructions(OFPTFPT_INSTRUCTIONS, []*OfpInstructionId{
+			NewOfpInstructionId(OFPIT_GOTO_TABLE, 4),
+			NewOfpInstructionId(OFPIT_APPLY_ACTIONS, 4),
+			NewOfpInstructionId(OFPIT_METER, 4)}),
+		NewOfpTableFeaturePropInstructions(OFPTFPT_INSTRUCTIONS_MISS, []*OfpInstructionId{
+			NewOfpInstructionId(OFPIT_CLEAR_ACTIONS, 4)}),
+		NewOfpTableFeaturePropNextTables(OFPTFPT_NEXT_TABLES, []uint8{1, 2, 3}),
+		NewOfpTableFeaturePropNextTables(OFPTFPT_NEXT_TABLES_MISS, []uint8{1, 2, 3, 4, 5, 6, 7, 8}),
+		NewOfpTableFeaturePropActions(OFPTFPT_APPLY_ACTIONS, []OfpActionHeader{
+			NewOfpActionHeader(OFPAT_OUTPUT, 8),
+			NewOfpActionHeader(OFPAT_PUSH_VLAN, 8)}),
+		NewOfpTableFeaturePropActions(OFPTFPT_WRITE_ACTIONS_MISS, []OfpActionHeader{
+			NewOfpActionHeader(OFPAT_GROUP, 8)}),
+		NewOfpTableFeaturePropOxm(OFPTFPT_MATCH, []uint32{
+			oxmHeader(OFPXMC_OPENFLOW_BASIC, OFPXMT_OFB_IN_PORT, 4),
+			oxmHeader(OFPXMC_OPENFLOW_BASIC, OFPXMT_OFB_ETH_TYPE, 2),
+			oxmHeader(OFPXMC_OPENFLOW_BASIC, OFPXMT_OFB_IPV4_DST, 4)}),
+		NewOfpTableFeaturePropOxm(OFPTFPT_APPLY_SETFIELD, []uint32{
+			oxmHeader(OFPXMC_OPENFLOW_BASIC, OFPXMT_OFB_VLAN_VID, 2)}),
+		NewOfpTableFeaturePropExperimenter(OFPTFPT_EXPERIMENTER, 0x2320, 1, []uint32{1, 2, 3}),
+		NewOfpTableFeaturePropExperimenter(OFPTFPT_EXPERIMENTER_MISS, 0x2320, 2, []uint32{4}),
+	}
+
+	// each type of property alone, and all of them in one table
+	for _, prop := range props {
+		testRoundTrip(t, newTestMultipartReply(OFPMP_TABLE_FEATURES, newTestTableFeatures(
+			[]OfpTableFeatureProp{prop})))
+	}
+	features := newTestTableFeatures(props)
+	testRoundTrip(t, newTestMultipartReply(OFPMP_TABLE_FEATURES, features, newTestTableFeatures(nil)))
+	testRoundTripDirection(t, NewOfpTableFeaturesStatsRequest(0, features), FROM_CONTROLLER)
+}
+
+/*****************************************************/
+/* Controller-originated messages                    */
+/*****************************************************/
+func TestRoundTripControllerMessages(t *testing.T) {
+	messages := []OFMessage{
+		NewOfpHello(),
+		NewOfpEchoRequest(),
+		NewOfpFeaturesRequest(),
+		NewOfpGetConfig(),
+		NewOfpSetConfig(1, 128),
+		NewOfpBarrierRequest(),
+		NewOfpQueueGetConfigRequest(2),
+		NewOfpRoleRequest(OFPCT_ROLE_MASTER, 100),
+		NewOfpGetAsyncRequest(),
+		NewOfpSetAsync([2]uint32{1, 2}, [2]uint32{3, 4}, [2]uint32{5, 6}),
+		NewOfpTableMod(1, 3),
+		newTestFlowMod(),
+		newTestPacketOut(),
+		NewOfpPacketOut(1, 2, nil, nil),
+	}
+
+	portMod, _ := NewOfpPortMod(1, "00:11:22:33:44:55", OFPPC_NO_PACKET_IN, OFPPC_NO_PACKET_IN, OFPPF_1GB_FD)
+	messages = append(messages, portMod)
+
+	goTable := NewOfpInstructionGotoTable(2)
+	metadata := NewOfpInstructionWriteMetadata(1, 0xff)
+	meter := NewOfpInstructionMeter(1)
+	clear := NewOfpInstructionActions(OFPIT_CLEAR_ACTIONS)
+	messages = append(messages, NewOfpFlowModAdd(0, 0, 0, 100, 0, newTestMatch(),
+		[]OfpInstruction{meter, clear, metadata, goTable}))
+	messages = append(messages, NewOfpFlowModDelete(0, 0, 0, 0, OFPP_ANY, OFPG_ANY, 0, NewOfpMatch()))
+
+	group := NewOfpGroupMod(OFPGC_ADD, OFPGT_SELECT, 1)
+	group.Append(newTestBucket())
+	group.Append(newTestBucket())
+	messages = append(messages, group)
+
+	meterMod := NewOfpMeterMod(OFPMC_ADD, OFPMF_KBPS, 1)
+	meterMod.AppendMeterBand(NewOfpMeterBandDrop(100, 10))
+	meterMod.AppendMeterBand(NewOfpMeterBandDscpRemark(200, 20, 1))
+	messages = append(messages, meterMod)
+
+	for _, msg := range messages {
+		testRoundTripDirection(t, msg, FROM_CONTROLLER)
+	}
+}
+
+func TestRoundTripMultipartRequest(t *testing.T) {
+	messages := []OFMessage{
+		NewOfpDescStatsRequest(0),
+		NewOfpFlowStatsRequest(0, 1, OFPP_ANY, OFPG_ANY, 0x1234, 0xffff, newTestMatch()),
+		NewOfpAggregateStatsRequest(0, 1, OFPP_ANY, OFPG_ANY, 0x1234, 0xffff, NewOfpMatch()),
+		NewOfpTableStatsRequest(0),
+		NewOfpPortStatsRequest(OFPP_ANY, 0),
+		NewOfpQueueStatsRequest(1, 2, 0),
+		NewOfpGroupStatsRequest(1, 0),
+		NewOfpGroupDescStatsRequest(0),
+		NewOfpGroupFeaturesStatsRequest(0),
+		NewOfpMeterStatsRequest(1, 0),
+		NewOfpMeterConfigStatsRequest(0),
+		NewOfpMeterFeaturesStatsRequest(0),
+		NewOfpTableFeaturesStatsRequest(OFPMPF_REQ_MORE, newTestTableFeatures(nil)),
+		NewOfpPortDescStatsRequest(0),
+	}
+	for _, msg := range messages {
+		testRoundTripDirection(t, msg, FROM_CONTROLLER)
+	}
+}
+
+func TestParseDirection(t *testing.T) {
+	flowMod := newTestFlowMod().Serialize()
+	if msg := ParseDirection(flowMod, FROM_SWITCH); msg != nil {
+		t.Error("FlowMod is decoded as message from switch.")
+	}
+	packetIn := newTestPacketInBinary()
+	if msg := ParseDirection(packetIn, FROM_CONTROLLER); msg != nil {
+		t.Error("PacketIn is decoded as message from controller.")
+	}
+
+	saved := PARSE_DIRECTION
+	defer func() { PARSE_DIRECTION = saved }()
+	PARSE_DIRECTION = FROM_CONTROLLER
+	if _, ok := Parse(flowMod).(*OfpFlowMod); !ok {
+		t.Error("Parse doesn't follow PARSE_DIRECTION.")
+	}
+}
@@ -0,0 +1,1169 @@
+package ofp13
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Kmotiko/gofc/packet"
+)
+
+/*****************************************************/
+/* Symbolic Names                                    */
+/*****************************************************/
+
+/// name of v in names indexed by value, or v in decimal if it has no name.
+func ofpName(names []string, v uint64) string {
+	if v < uint64(len(names)) && names[v] != "" {
+		return names[v]
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+/// name of v in names, or v in decimal if it has no name.
+func ofpMapName(names map[uint64]string, v uint64) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+type ofpFlag struct {
+	flag uint64
+	name string
+}
+
+/// names of flags set in v. unknown bits are listed in hex.
+func ofpFlagsList(v uint64, flags []ofpFlag) []string {
+	list := make([]string, 0)
+	for _, f := range flags {
+		if v&f.flag != 0 {
+			list = append(list, f.name)
+			v &^= f.flag
+		}
+	}
+	if v != 0 {
+		list = append(list, fmt.Sprintf("0x%x", v))
+	}
+	return list
+}
+
+/// names of flags set in v joined by "|", such as
+/// "OFPFF_SEND_FLOW_REM|OFPFF_CHECK_OVERLAP". unknown bits are printed in
+/// hex, and "0" is returned if no flag is set.
+func ofpFlagsString(v uint64, flags []ofpFlag) string {
+	list := ofpFlagsList(v, flags)
+	if len(list) == 0 {
+		return "0"
+	}
+	return strings.Join(list, "|")
+}
+
+var ofpTypeNames = []string{
+	OFPT_HELLO:                    "OFPT_HELLO",
+	OFPT_ERROR:                    "OFPT_ERROR",
+	OFPT_ECHO_REQUEST:             "OFPT_ECHO_REQUEST",
+	OFPT_ECHO_REPLY:               "OFPT_ECHO_REPLY",
+	OFPT_EXPERIMENTER:             "OFPT_EXPERIMENTER",
+	OFPT_FEATURES_REQUEST:         "OFPT_FEATURES_REQUEST",
+	OFPT_FEATURES_REPLY:           "OFPT_FEATURES_REPLY",
+	OFPT_GET_CONFIG_REQUEST:       "OFPT_GET_CONFIG_REQUEST",
+	OFPT_GET_CONFIG_REPLY:         "OFPT_GET_CONFIG_REPLY",
+	OFPT_SET_CONFIG:               "OFPT_SET_CONFIG",
+	OFPT_PACKET_IN:                "OFPT_PACKET_IN",
+	OFPT_FLOW_REMOVED:             "OFPT_FLOW_REMOVED",
+	OFPT_PORT_STATUS:              "OFPT_PORT_STATUS",
+	OFPT_PACKET_OUT:               "OFPT_PACKET_OUT",
+	OFPT_FLOW_MOD:                 "OFPT_FLOW_MOD",
+	OFPT_GROUP_MOD:                "OFPT_GROUP_MOD",
+	OFPT_PORT_MOD:                 "OFPT_PORT_MOD",
+	OFPT_TABLE_MOD:                "OFPT_TABLE_MOD",
+	OFPT_MULTIPART_REQUEST:        "OFPT_MULTIPART_REQUEST",
+	OFPT_MULTIPART_REPLY:          "OFPT_MULTIPART_REPLY",
+	OFPT_BARRIER_REQUEST:          "OFPT_BARRIER_REQUEST",
+	OFPT_BARRIER_REPLY:            "OFPT_BARRIER_REPLY",
+	OFPT_QUEUE_GET_CONFIG_REQUEST: "OFPT_QUEUE_GET_CONFIG_REQUEST",
+	OFPT_QUEUE_GET_CONFIG_REPLY:   "OFPT_QUEUE_GET_CONFIG_REPLY",
+	OFPT_ROLE_REQUEST:             "OFPT_ROLE_REQUEST",
+	OFPT_ROLE_REPLY:               "OFPT_ROLE_REPLY",
+	OFPT_GET_ASYNC_REQUEST:        "OFPT_GET_ASYNC_REQUEST",
+	OFPT_GET_ASYNC_REPLY:          "OFPT_GET_ASYNC_REPLY",
+	OFPT_SET_ASYNC:                "OFPT_SET_ASYNC",
+	OFPT_METER_MOD:                "OFPT_METER_MOD",
+}
+
+var ofpPortNames = map[uint64]string{
+	OFPP_IN_PORT:    "OFPP_IN_PORT",
+	OFPP_TABLE:      "OFPP_TABLE",
+	OFPP_NORMAL:     "OFPP_NORMAL",
+	OFPP_FLOOD:      "OFPP_FLOOD",
+	OFPP_ALL:        "OFPP_ALL",
+	OFPP_CONTROLLER: "OFPP_CONTROLLER",
+	OFPP_LOCAL:      "OFPP_LOCAL",
+	OFPP_ANY:        "OFPP_ANY",
+}
+
+func ofpPortString(port uint32) string {
+	return ofpMapName(ofpPortNames, uint64(port))
+}
+
+var ofpGroupNames = map[uint64]string{OFPG_ALL: "OFPG_ALL", OFPG_ANY: "OFPG_ANY"}
+
+var ofpMeterNames = map[uint64]string{OFPM_SLOWPATH: "OFPM_SLOWPATH",
+	OFPM_CONTROLLER: "OFPM_CONTROLLER", OFPM_ALL: "OFPM_ALL"}
+
+var ofpTableNames = map[uint64]string{OFPTT_ALL: "OFPTT_ALL"}
+
+var ofpQueueNames = map[uint64]string{OFPQ_ALL: "OFPQ_ALL"}
+
+var ofpBufferNames = map[uint64]string{OFP_NO_BUFFER: "OFP_NO_BUFFER"}
+
+var ofpMaxLenNames = map[uint64]string{OFPCML_NO_BUFFER: "OFPCML_NO_BUFFER"}
+
+func ofpGroupString(group uint32) string {
+	return ofpMapName(ofpGroupNames, uint64(group))
+}
+
+func ofpMeterString(meter uint32) string {
+	return ofpMapName(ofpMeterNames, uint64(meter))
+}
+
+func ofpTableString(table uint8) string {
+	return ofpMapName(ofpTableNames, uint64(table))
+}
+
+func ofpQueueString(queue uint32) string {
+	return ofpMapName(ofpQueueNames, uint64(queue))
+}
+
+func ofpBufferString(buffer uint32) string {
+	return ofpMapName(ofpBufferNames, uint64(buffer))
+}
+
+func ofpMaxLenString(maxLen uint16) string {
+	return ofpMapName(ofpMaxLenNames, uint64(maxLen))
+}
+
+func ofpDurationString(sec uint32, nsec uint32) string {
+	return fmt.Sprintf("%d.%09ds", sec, nsec)
+}
+
+var ofpActionNames = map[uint64]string{
+	OFPAT_OUTPUT:       "OFPAT_OUTPUT",
+	OFPAT_COPY_TTL_OUT: "OFPAT_COPY_TTL_OUT",
+	OFPAT_COPY_TTL_IN:  "OFPAT_COPY_TTL_IN",
+	OFPAT_SET_MPLS_TTL: "OFPAT_SET_MPLS_TTL",
+	OFPAT_DEC_MPLS_TTL: "OFPAT_DEC_MPLS_TTL",
+	OFPAT_PUSH_VLAN:    "OFPAT_PUSH_VLAN",
+	OFPAT_POP_VLAN:     "OFPAT_POP_VLAN",
+	OFPAT_PUSH_MPLS:    "OFPAT_PUSH_MPLS",
+	OFPAT_POP_MPLS:     "OFPAT_POP_MPLS",
+	OFPAT_SET_QUEUE:    "OFPAT_SET_QUEUE",
+	OFPAT_GROUP:        "OFPAT_GROUP",
+	OFPAT_SET_NW_TTL:   "OFPAT_SET_NW_TTL",
+	OFPAT_DEC_NW_TTL:   "OFPAT_DEC_NW_TTL",
+	OFPAT_SET_FIELD:    "OFPAT_SET_FIELD",
+	OFPAT_PUSH_PBB:     "OFPAT_PUSH_PBB",
+	OFPAT_POP_PBB:      "OFPAT_POP_PBB",
+	OFPAT_EXPERIMENTER: "OFPAT_EXPERIMENTER",
+}
+
+var ofpInstructionNames = map[uint64]string{
+	OFPIT_GOTO_TABLE:     "OFPIT_GOTO_TABLE",
+	OFPIT_WRITE_METADATA: "OFPIT_WRITE_METADATA",
+	OFPIT_WRITE_ACTIONS:  "OFPIT_WRITE_ACTIONS",
+	OFPIT_APPLY_ACTIONS:  "OFPIT_APPLY_ACTIONS",
+	OFPIT_CLEAR_ACTIONS:  "OFPIT_CLEAR_ACTIONS",
+	OFPIT_METER:          "OFPIT_METER",
+	OFPIT_EXPERIMENTER:   "OFPIT_EXPERIMENTER",
+}
+
+var ofpMeterBandNames = map[uint64]string{
+	OFPMBT_DROP:         "OFPMBT_DROP",
+	OFPMBT_DSCP_REMARK:  "OFPMBT_DSCP_REMARK",
+	OFPMBT_EXPERIMENTER: "OFPMBT_EXPERIMENTER",
+}
+
+var ofpMultipartNames = map[uint64]string{
+	OFPMP_DESC:           "OFPMP_DESC",
+	OFPMP_FLOW:           "OFPMP_FLOW",
+	OFPMP_AGGREGATE:      "OFPMP_AGGREGATE",
+	OFPMP_TABLE:          "OFPMP_TABLE",
+	OFPMP_PORT_STATS:     "OFPMP_PORT_STATS",
+	OFPMP_QUEUE:          "OFPMP_QUEUE",
+	OFPMP_GROUP:          "OFPMP_GROUP",
+	OFPMP_GROUP_DESC:     "OFPMP_GROUP_DESC",
+	OFPMP_GROUP_FEATURES: "OFPMP_GROUP_FEATURES",
+	OFPMP_METER:          "OFPMP_METER",
+	OFPMP_METER_CONFIG:   "OFPMP_METER_CONFIG",
+	OFPMP_METER_FEATURES: "OFPMP_METER_FEATURES",
+	OFPMP_TABLE_FEATURES: "OFPMP_TABLE_FEATURES",
+	OFPMP_PORT_DESC:      "OFPMP_PORT_DESC",
+	OFPMP_EXPERIMENTER:   "OFPMP_EXPERIMENTER",
+}
+
+var ofpTableFeaturePropNames = map[uint64]string{
+	OFPTFPT_INSTRUCTIONS:        "OFPTFPT_INSTRUCTIONS",
+	OFPTFPT_INSTRUCTIONS_MISS:   "OFPTFPT_INSTRUCTIONS_MISS",
+	OFPTFPT_NEXT_TABLES:         "OFPTFPT_NEXT_TABLES",
+	OFPTFPT_NEXT_TABLES_MISS:    "OFPTFPT_NEXT_TABLES_MISS",
+	OFPTFPT_WRITE_ACTIONS:       "OFPTFPT_WRITE_ACTIONS",
+	OFPTFPT_WRITE_ACTIONS_MISS:  "OFPTFPT_WRITE_ACTIONS_MISS",
+	OFPTFPT_APPLY_ACTIONS:       "OFPTFPT_APPLY_ACTIONS",
+	OFPTFPT_APPLY_ACTIONS_MISS:  "OFPTFPT_APPLY_ACTIONS_MISS",
+	OFPTFPT_MATCH:               "OFPTFPT_MATCH",
+	OFPTFPT_WILDCARDS:           "OFPTFPT_WILDCARDS",
+	OFPTFPT_WRITE_SETFIELD:      "OFPTFPT_WRITE_SETFIELD",
+	OFPTFPT_WRITE_SETFIELD_MISS: "OFPTFPT_WRITE_SETFIELD_MISS",
+	OFPTFPT_APPLY_SETFIELD:      "OFPTFPT_APPLY_SETFIELD",
+	OFPTFPT_APPLY_SETFIELD_MISS: "OFPTFPT_APPLY_SETFIELD_MISS",
+	OFPTFPT_EXPERIMENTER:        "OFPTFPT_EXPERIMENTER",
+	OFPTFPT_EXPERIMENTER_MISS:   "OFPTFPT_EXPERIMENTER_MISS",
+}
+
+var ofpQueuePropNames = map[uint64]string{
+	OFPQT_MIN_RATE:     "OFPQT_MIN_RATE",
+	OFPQT_MAX_RATE:     "OFPQT_MAX_RATE",
+	OFPQT_EXPERIMENTER: "OFPQT_EXPERIMENTER",
+}
+
+var ofpFlowModCommandNames = []string{
+	OFPFC_ADD:           "OFPFC_ADD",
+	OFPFC_MODIFY:        "OFPFC_MODIFY",
+	OFPFC_MODIFY_STRICT: "OFPFC_MODIFY_STRICT",
+	OFPFC_DELETE:        "OFPFC_DELETE",
+	OFPFC_DELETE_STRICT: "OFPFC_DELETE_STRICT",
+}
+
+var ofpGroupModCommandNames = []string{
+	OFPGC_ADD:    "OFPGC_ADD",
+	OFPGC_MODIFY: "OFPGC_MODIFY",
+	OFPGC_DELETE: "OFPGC_DELETE",
+}
+
+var ofpGroupTypeNames = []string{
+	OFPGT_ALL:      "OFPGT_ALL",
+	OFPGT_SELECT:   "OFPGT_SELECT",
+	OFPGT_INDIRECT: "OFPGT_INDIRECT",
+	OFPGT_FF:       "OFPGT_FF",
+}
+
+var ofpMeterModCommandNames = []string{
+	OFPMC_ADD:    "OFPMC_ADD",
+	OFPMC_MODIFY: "OFPMC_MODIFY",
+	OFPMC_DELETE: "OFPMC_DELETE",
+}
+
+var ofpPacketInReasonNames = []string{
+	OFPR_NO_MATCH:    "OFPR_NO_MATCH",
+	OFPR_ACTION:      "OFPR_ACTION",
+	OFPR_INVALID_TTL: "OFPR_INVALID_TTL",
+}
+
+var ofpFlowRemovedReasonNames = []string{
+	OFPRR_IDLE_TIMEOUT: "OFPRR_IDLE_TIMEOUT",
+	OFPRR_HARD_TIMEOUT: "OFPRR_HARD_TIMEOUT",
+	OFPRR_DELETE:       "OFPRR_DELETE",
+	OFPRR_GROUP_DELETE: "OFPRR_GROUP_DELETE",
+}
+
+var ofpPortReasonNames = []string{
+	OFPPR_ADD:    "OFPPR_ADD",
+	OFPPR_DELETE: "OFPPR_DELETE",
+	OFPPR_MODIFY: "OFPPR_MODIFY",
+}
+
+var ofpRoleNames = []string{
+	OFPCR_ROLE_NOCHANGE: "OFPCR_ROLE_NOCHANGE",
+	OFPCR_ROLE_EQUAL:    "OFPCR_ROLE_EQUAL",
+	OFPCT_ROLE_MASTER:   "OFPCR_ROLE_MASTER",
+	OFPCR_ROLE_SLAVE:    "OFPCR_ROLE_SLAVE",
+}
+
+var ofpConfigFragNames = []string{
+	OFPC_FLAG_NORMAL: "OFPC_FRAG_NORMAL",
+	OFPC_FLAG_DROP:   "OFPC_FRAG_DROP",
+	OFPC_FLAG_REASM:  "OFPC_FRAG_REASM",
+	OFPC_FLAG_MASK:   "OFPC_FRAG_MASK",
+}
+
+var ofpFlowModFlags = []ofpFlag{
+	{OFPFF_SEND_FLOW_REM, "OFPFF_SEND_FLOW_REM"},
+	{OFPFF_CHECK_OVERLAP, "OFPFF_CHECK_OVERLAP"},
+	{OFPFF_RESET_COUNTS, "OFPFF_RESET_COUNTS"},
+	{OFPFF_NO_PKT_COUNTS, "OFPFF_NO_PKT_COUNTS"},
+	{OFPFF_NO_BYT_COUNTS, "OFPFF_NO_BYT_COUNTS"},
+}
+
+var ofpCapabilitiesFlags = []ofpFlag{
+	{OFPC_FLOW_STATS, "OFPC_FLOW_STATS"},
+	{OFPC_TABLE_STATS, "OFPC_TABLE_STATS"},
+	{OFPC_PORT_STATS, "OFPC_PORT_STATS"},
+	{OFPC_GROUP_STATS, "OFPC_GROUP_STATS"},
+	{OFPC_IP_REASM, "OFPC_IP_REASM"},
+	{OFPC_QUEUE_STATS, "OFPC_QUEUE_STATS"},
+	{OFPC_PORT_BLOCKED, "OFPC_PORT_BLOCKED"},
+}
+
+var ofpPortConfigFlags = []ofpFlag{
+	{OFPPC_PORT_DOWN, "OFPPC_PORT_DOWN"},
+	{OFPPC_NO_RECV, "OFPPC_NO_RECV"},
+	{OFPPC_NO_FWD, "OFPPC_NO_FWD"},
+	{OFPPC_NO_PACKET_IN, "OFPPC_NO_PACKET_IN"},
+}
+
+var ofpPortStateFlags = []ofpFlag{
+	{OFPPS_LINK_DOWN, "OFPPS_LINK_DOWN"},
+	{OFPPS_BLOCKED, "OFPPS_BLOCKED"},
+	{OFPPS_LIVE, "OFPPS_LIVE"},
+}
+
+var ofpPortFeaturesFlags = []ofpFlag{
+	{OFPPF_10MB_HD, "OFPPF_10MB_HD"},
+	{OFPPF_10MB_FD, "OFPPF_10MB_FD"},
+	{OFPPF_100MB_HD, "OFPPF_100MB_HD"},
+	{OFPPF_100MB_FD, "OFPPF_100MB_FD"},
+	{OFPPF_1GB_HD, "OFPPF_1GB_HD"},
+	{OFPPF_1GB_FD, "OFPPF_1GB_FD"},
+	{OFPPF_10GB_FD, "OFPPF_10GB_FD"},
+	{OFPPF_40GB_FD, "OFPPF_40GB_FD"},
+	{OFPPF_100GB_FD, "OFPPF_100GB_FD"},
+	{OFPPF_1TB_FD, "OFPPF_1TB_FD"},
+	{OFPPF_OTHER, "OFPPF_OTHER"},
+	{OFPPF_COPPER, "OFPPF_COPPER"},
+	{OFPPF_FIBER, "OFPPF_FIBER"},
+	{OFPPF_AUTONEG, "OFPPF_AUTONEG"},
+	{OFPPF_PAUSE, "OFPPF_PAUSE"},
+	{OFPPF_PAUSE_ASYM, "OFPPF_PAUSE_ASYM"},
+}
+
+var ofpMeterFlags = []ofpFlag{
+	{OFPMF_KBPS, "OFPMF_KBPS"},
+	{OFPMF_PKTPS, "OFPMF_PKTPS"},
+	{OFPMF_BURST, "OFPMF_BURST"},
+	{OFPMF_STATS, "OFPMF_STATS"},
+}
+
+var ofpGroupCapabilitiesFlags = []ofpFlag{
+	{OFPGC_SELECT_WEIGHT, "OFPGC_SELECT_WEIGHT"},
+	{OFPGC_SELECT_LIVENESS, "OFPGC_SELECT_LIVENESS"},
+	{OFPGC_CHAINING, "OFPGC_CHAINING"},
+	{OFPGC_CHAINING_CHECKS, "OFPGC_CHAINING_CHECKS"},
+}
+
+var ofpMultipartRequestFlags = []ofpFlag{{OFPMPF_REQ_MORE, "OFPMPF_REQ_MORE"}}
+
+var ofpMultipartReplyFlags = []ofpFlag{{OFPMPF_REPLY_MORE, "OFPMPF_REPLY_MORE"}}
+
+/// flags of types by bit index, such as group types of group features and
+/// band types of meter features.
+func ofpBitFlags(names []string) []ofpFlag {
+	var flags []ofpFlag
+	for i, name := range names {
+		if name != "" && i < 32 {
+			flags = append(flags, ofpFlag{1 << uint(i), name})
+		}
+	}
+	return flags
+}
+
+/// band types of meter features by bit index
+var ofpMeterBandBits = []string{OFPMBT_DROP: "OFPMBT_DROP", OFPMBT_DSCP_REMARK: "OFPMBT_DSCP_REMARK"}
+
+/// names of types set in bitmap v. bits without name are printed in hex.
+func ofpBitsString(v uint32, names []string) string {
+	return ofpFlagsString(uint64(v), ofpBitFlags(names))
+}
+
+var ofpErrorTypeNames = []string{
+	OFPET_HELLO_FAILED:          "OFPET_HELLO_FAILED",
+	OFPET_BAD_REQUEST:           "OFPET_BAD_REQUEST",
+	OFPET_BAD_ACTION:            "OFPET_BAD_ACTION",
+	OFPET_BAD_INSTRUCTION:       "OFPET_BAD_INSTRUCTION",
+	OFPET_BAD_MATCH:             "OFPET_BAD_MATCH",
+	OFPET_FLOW_MOD_FAILED:       "OFPET_FLOW_MOD_FAILED",
+	OFPET_GROUP_MOD_FAILED:      "OFPET_GROUP_MOD_FAILED",
+	OFPET_PORT_MOD_FAILED:       "OFPET_PORT_MOD_FAILED",
+	OFPET_TABLE_MOD_FAILED:      "OFPET_TABLE_MOD_FAILED",
+	OFPET_QUEUE_OP_FAILED:       "OFPET_QUEUE_OP_FAILED",
+	OFPET_SWITCH_CONFIG_FAILED:  "OFPET_SWITCH_CONFIG_FAILED",
+	OFPET_ROLE_REQUEST_FAILED:   "OFPET_ROLE_REQUEST_FAILED",
+	OFPET_METER_MOD_FAILED:      "OFPET_METER_MOD_FAILED",
+	OFPET_TABLE_FEATURES_FAILED: "OFPET_TABLE_FEATURES_FAILED",
+}
+
+/// names of error codes by error type
+var ofpErrorCodeNames = map[uint16][]string{
+	OFPET_HELLO_FAILED: {"OFPHFC_INCOMPATIBLE", "OFPHFC_EPERM"},
+	OFPET_BAD_REQUEST: {"OFPBRC_BAD_VERSION", "OFPBRC_BAD_TYPE", "OFPBRC_BAD_MULTIPART",
+		"OFPBRC_BAD_EXPERIMENTER", "OFPBRC_BAD_EXP_TYPE", "OFPBRC_EPERM", "OFPBRC_BAD_LEN",
+		"OFPBRC_BUFFER_EMPTY", "OFPBRC_BUFFER_UNKNOWN", "OFPBRC_BAD_TABLE_ID", "OFPBRC_IS_SLAVE",
+		"OFPBRC_BAD_PORT", "OFPBRC_BAD_PACKET", "OFPBRC_MULTIPART_BUFFER_OVERFLOW"},
+	OFPET_BAD_ACTION: {"OFPBAC_BAD_TYPE", "OFPBAC_BAD_LEN", "OFPBAC_BAD_EXPERIMENTER",
+		"OFPBAC_BAD_EXP_TYPE", "OFPBAC_BAD_OUT_PORT", "OFPBAC_BAD_ARGUMENT", "OFPBAC_EPERM",
+		"OFPBAC_TOO_MANY", "OFPBAC_BAD_QUEUE", "OFPBAC_BAD_OUT_GROUP", "OFPBAC_MATCH_INCONSISTENT",
+		"OFPBAC_UNSUPPORTED_ORDER", "OFPBAC_BAD_TAG", "OFPBAC_BAD_SET_TYPE", "OFPBAC_BAD_SET_LEN",
+		"OFPBAC_BAD_SET_ARGUMENT"},
+	OFPET_BAD_INSTRUCTION: {"OFPBIC_UNKNOWN_INST", "OFPBIC_UNSUP_INST", "OFPBIC_BAD_TABLE_ID",
+		"OFPBIC_UNSUP_METADATA", "OFPBIC_UNSUP_METADATA_MASK", "OFPBIC_BAD_EXPERIMENTER",
+		"OFPBIC_BAD_EXP_TYPE", "OFPBIC_BAD_LEN", "OFPBIC_EPERM"},
+	OFPET_BAD_MATCH: {"OFPBMC_BAD_TYPE", "OFPBMC_BAD_LEN", "OFPBMC_BAD_TAG",
+		"OFPBMC_BAD_DL_ADDR_MASK", "OFPBMC_BAD_NW_ADDR_MASK", "OFPBMC_BAD_WILDCARDS",
+		"OFPBMC_BAD_FIELD", "OFPBMC_BAD_VALUE", "OFPBMC_BAD_MASK", "OFPBMC_BAD_PREREQ",
+		"OFPBMC_DUP_FIELD", "OFPBMC_EPERM"},
+	OFPET_FLOW_MOD_FAILED: {"OFPFMFC_UNKNOWN", "OFPFMFC_TABLE_FULL", "OFPFMFC_BAD_TABLE_ID",
+		"OFPFMFC_OVERLAP", "OFPFMFC_EPERM", "OFPFMFC_BAD_TIMEOUT", "OFPFMFC_BAD_COMMAND",
+		"OFPFMFC_BAD_FLAGS"},
+	OFPET_GROUP_MOD_FAILED: {"OFPGMFC_GROUP_EXISTS", "OFPGMFC_INVALID_GROUP",
+		"OFPGMFC_WEIGHT_UNSUPPORTED", "OFPGMFC_OUT_OF_GROUPS", "OFPGMFC_OUT_OF_BUCKETS",
+		"OFPGMFC_CHAINING_UNSUPPORTED", "OFPGMFC_WATCH_UNSUPPORTED", "OFPGMFC_LOOP",
+		"OFPGMFC_UNKNOWN_GROUP", "OFPGMFC_CHAINED_GROUP", "OFPGMFC_BAD_TYPE", "OFPGMFC_BAD_COMMAND",
+		"OFPGMFC_BAD_BUCKET", "OFPGMFC_BAD_WATCH", "OFPGMFC_EPERM"},
+	OFPET_PORT_MOD_FAILED: {"OFPPMFC_BAD_PORT", "OFPPMFC_BAD_HW_ADDR", "OFPPMFC_BAD_CONFIG",
+		"OFPPMFC_BAD_ADVERTISE", "OFPPMFC_EPERM"},
+	OFPET_TABLE_MOD_FAILED:     {"OFPTMFC_BAD_TABLE", "OFPTMFC_BAD_CONFIG", "OFPTMFC_EPERM"},
+	OFPET_QUEUE_OP_FAILED:      {"OFPQOFC_BAD_PORT", "OFPQOFC_BAD_QUEUE", "OFPQOFC_EPERM"},
+	OFPET_SWITCH_CONFIG_FAILED: {"OFPSCFC_BAD_FLAGS", "OFPSCFC_BAD_LEN", "OFPSCFC_EPERM"},
+	OFPET_ROLE_REQUEST_FAILED:  {"OFPRRFC_STALE", "OFPRRFC_UNSUP", "OFPRRFC_BAD_ROLE"},
+	OFPET_METER_MOD_FAILED: {"OFPMMFC_UNKNOWN", "OFPMMFC_METER_EXISTS", "OFPMMFC_INVALID_METER",
+		"OFPMMFC_UNKNOWN_METER", "OFPMMFC_BAD_COMMAND", "OFPMMFC_BAD_FLAGS", "OFPMMFC_BAD_RATE",
+		"OFPMMFC_BAD_BURST", "OFPMMFC_BAD_BAND", "OFPMMFC_BAD_BAND_VALUE", "OFPMMFC_OUT_OF_METERS",
+		"OFPMMFC_OUT_OF_BANDS"},
+	OFPET_TABLE_FEATURES_FAILED: {"OFPTFFC_BAD_TABLE", "OFPTFFC_BAD_METADATA", "OFPTFFC_BAD_TYPE",
+		"OFPTFFC_BAD_LEN", "OFPTFFC_BAD_ARGUMENT", "OFPTFFC_EPERM"},
+}
+
+/// ErrorTypeString returns symbolic name of error type, such as
+/// "OFPET_BAD_MATCH".
+func ErrorTypeString(errType uint16) string {
+	if errType == OFPET_EXPERIMENTER {
+		return "OFPET_EXPERIMENTER"
+	}
+	return ofpName(ofpErrorTypeNames, uint64(errType))
+}
+
+/// ErrorCodeString returns symbolic name of error code of type, such as
+/// "OFPBMC_BAD_PREREQ".
+func ErrorCodeString(errType uint16, code uint16) string {
+	return ofpName(ofpErrorCodeNames[errType], uint64(code))
+}
+
+/// TypeString returns symbolic name of message type, such as
+/// "OFPT_FLOW_MOD".
+func TypeString(t uint8) string {
+	return ofpName(ofpTypeNames, uint64(t))
+}
+
+// trim NUL padding of fixed length string
+func ofpCString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// join items, such as actions, as list of "[a, b]"
+func ofpListString(items []string) string {
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+/*****************************************************/
+/* Header                                            */
+/*****************************************************/
+
+/// String returns type and xid, such as "OFPT_ECHO_REQUEST (xid=0x1)".
+/// Messages without body, such as echo and barrier, are printed by this.
+func (h *OfpHeader) String() string {
+	return fmt.Sprintf("%s (xid=0x%x)", TypeString(h.Type), h.Xid)
+}
+
+// header of message followed by fields
+func ofpMessageString(h *OfpHeader, format string, args ...interface{}) string {
+	return h.String() + ": " + fmt.Sprintf(format, args...)
+}
+
+func (h *OfpHelloElemHeader) String() string {
+	if h.Type == OFPHET_VERSIONBITMAP {
+		return "OFPHET_VERSIONBITMAP"
+	}
+	return strconv.Itoa(int(h.Type))
+}
+
+func (h *OfpHelloElemVersionBitmap) String() string {
+	var versions []string
+	for i, bitmap := range h.Bitmaps {
+		for bit := 0; bit < 32; bit++ {
+			if bitmap&(1<<uint(bit)) != 0 {
+				versions = append(versions, fmt.Sprintf("0x%02x", i*32+bit))
+			}
+		}
+	}
+	return "OFPHET_VERSIONBITMAP(versions=" + ofpListString(versions) + ")"
+}
+
+func (m *OfpHello) String() string {
+	elements := make([]string, len(m.Elements))
+	for i := range m.Elements {
+		elements[i] = m.Elements[i].String()
+	}
+	return ofpMessageString(&m.Header, "elements=%s", ofpListString(elements))
+}
+
+func (m *OfpErrorMsg) String() string {
+	s := ofpMessageString(&m.Header, "type=%s code=%s",
+		ErrorTypeString(m.Type), ErrorCodeString(m.Type, m.Code))
+	// data begins with the request which causes the error
+	if len(m.Data) >= 8 {
+		req := OfpHeader{Version: m.Data[0], Type: m.Data[1],
+			Length: binary.BigEndian.Uint16(m.Data[2:]), Xid: binary.BigEndian.Uint32(m.Data[4:])}
+		return s + " request=" + req.String()
+	}
+	return s + fmt.Sprintf(" data_len=%d", len(m.Data))
+}
+
+func (m *OfpErrorExperimenterMsg) String() string {
+	return ofpMessageString(&m.Header, "type=OFPET_EXPERIMENTER exp_type=%d experimenter=0x%x data_len=%d",
+		m.ExpType, m.Experimenter, len(m.Data))
+}
+
+/*****************************************************/
+/* Switch Configuration                              */
+/*****************************************************/
+
+func (m *OfpSwitchFeatures) String() string {
+	return ofpMessageString(&m.Header,
+		"datapath_id=0x%016x n_buffers=%d n_tables=%d auxiliary_id=%d capabilities=%s",
+		m.DatapathId, m.NBuffers, m.NTables, m.AuxiliaryId,
+		ofpFlagsString(uint64(m.Capabilities), ofpCapabilitiesFlags))
+}
+
+func (m *OfpSwitchConfig) String() string {
+	return ofpMessageString(&m.Header, "flags=%s miss_send_len=%s",
+		ofpName(ofpConfigFragNames, uint64(m.Flags&OFPC_FLAG_MASK)), ofpMaxLenString(m.MissSendLen))
+}
+
+func (m *OfpTableMod) String() string {
+	return ofpMessageString(&m.Header, "table_id=%s config=0x%x", ofpTableString(m.TableId), m.Config)
+}
+
+func (p *OfpPort) String() string {
+	return fmt.Sprintf("ofp_port(port_no=%s, hw_addr=%s, name=%s, config=%s, state=%s, "+
+		"curr=%s, advertised=%s, supported=%s, peer=%s, curr_speed=%d, max_speed=%d)",
+		ofpPortString(p.PortNo), p.HwAddr, ofpCString(p.Name),
+		ofpFlagsString(uint64(p.Config), ofpPortConfigFlags),
+		ofpFlagsString(uint64(p.State), ofpPortStateFlags),
+		ofpFlagsString(uint64(p.Curr), ofpPortFeaturesFlags),
+		ofpFlagsString(uint64(p.Advertised), ofpPortFeaturesFlags),
+		ofpFlagsString(uint64(p.Supported), ofpPortFeaturesFlags),
+		ofpFlagsString(uint64(p.Peer), ofpPortFeaturesFlags),
+		p.CurrSpeed, p.MaxSpeed)
+}
+
+func (m *OfpPortStatus) String() string {
+	desc := "nil"
+	if m.Desc != nil {
+		desc = m.Desc.String()
+	}
+	return ofpMessageString(&m.Header, "reason=%s desc=%s",
+		ofpName(ofpPortReasonNames, uint64(m.Reason)), desc)
+}
+
+func (m *OfpPortMod) String() string {
+	return ofpMessageString(&m.Header, "port_no=%s hw_addr=%s config=%s mask=%s advertise=%s",
+		ofpPortString(m.PortNo), m.HwAddr,
+		ofpFlagsString(uint64(m.Config), ofpPortConfigFlags),
+		ofpFlagsString(uint64(m.Mask), ofpPortConfigFlags),
+		ofpFlagsString(uint64(m.Advertise), ofpPortFeaturesFlags))
+}
+
+func (m *OfpRole) String() string {
+	return ofpMessageString(&m.Header, "role=%s generation_id=%d",
+		ofpName(ofpRoleNames, uint64(m.Role)), m.GenerationId)
+}
+
+func (m *OfpAsyncConfig) String() string {
+	reasons := func(mask [2]uint32, names []string) string {
+		return fmt.Sprintf("{master=%s, slave=%s}",
+			ofpBitsString(mask[0], names), ofpBitsString(mask[1], names))
+	}
+	return ofpMessageString(&m.Header, "packet_in_mask=%s port_status_mask=%s flow_removed_mask=%s",
+		reasons(m.PacketInMask, ofpPacketInReasonNames),
+		reasons(m.PortStatusMask, ofpPortReasonNames),
+		reasons(m.FlowRemovedMask, ofpFlowRemovedReasonNames))
+}
+
+/*****************************************************/
+/* OXM Fields and Match                              */
+/*****************************************************/
+
+/// String of OXM field, such as "ipv4_dst=10.0.0.0/8" or
+/// "vlan_vid=OFPVID_PRESENT|10".
+func oxmFieldString(f OxmField) string {
+	e := newOxmEntry(f)
+	name := oxmFieldName(e.class, e.field)
+	if e.opaque != nil || int(e.field) >= len(ofctlFields) {
+		return fmt.Sprintf("%s=0x%x", name, e.value)
+	}
+	switch e.field {
+	case OFPXMT_OFB_IN_PORT, OFPXMT_OFB_IN_PHY_PORT:
+		return name + "=" + ofpPortString(uint32(oxmValue(e.value)))
+	case OFPXMT_OFB_VLAN_VID:
+		v := oxmValue(e.value)
+		switch {
+		case e.mask != nil:
+			return fmt.Sprintf("%s=0x%04x/0x%04x", name, v, oxmValue(e.mask))
+		case v == OFPVID_NONE:
+			return name + "=OFPVID_NONE"
+		case v&OFPVID_PRESENT != 0:
+			return fmt.Sprintf("%s=OFPVID_PRESENT|%d", name, v&^OFPVID_PRESENT)
+		}
+		return fmt.Sprintf("%s=%d", name, v)
+	case OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_ARP_OP:
+		return fmt.Sprintf("%s=%d", name, oxmValue(e.value))
+	}
+	return name + "=" + ofctlValueString(e)
+}
+
+func (m *OxmInPort) String() string        { return oxmFieldString(m) }
+func (m *OxmInPhyPort) String() string     { return oxmFieldString(m) }
+func (m *OxmMetadata) String() string      { return oxmFieldString(m) }
+func (m *OxmEth) String() string           { return oxmFieldString(m) }
+func (m *OxmEthType) String() string       { return oxmFieldString(m) }
+func (m *OxmVlanVid) String() string       { return oxmFieldString(m) }
+func (m *OxmVlanPcp) String() string       { return oxmFieldString(m) }
+func (m *OxmIpDscp) String() string        { return oxmFieldString(m) }
+func (m *OxmIpEcn) String() string         { return oxmFieldString(m) }
+func (m *OxmIpProto) String() string       { return oxmFieldString(m) }
+func (m *OxmIpv4) String() string          { return oxmFieldString(m) }
+func (m *OxmTcp) String() string           { return oxmFieldString(m) }
+func (m *OxmUdp) String() string           { return oxmFieldString(m) }
+func (m *OxmSctp) String() string          { return oxmFieldString(m) }
+func (m *OxmIcmpType) String() string      { return oxmFieldString(m) }
+func (m *OxmIcmpCode) String() string      { return oxmFieldString(m) }
+func (m *OxmArpOp) String() string         { return oxmFieldString(m) }
+func (m *OxmArpPa) String() string         { return oxmFieldString(m) }
+func (m *OxmArpHa) String() string         { return oxmFieldString(m) }
+func (m *OxmIpv6) String() string          { return oxmFieldString(m) }
+func (m *OxmIpv6FLabel) String() string    { return oxmFieldString(m) }
+func (m *OxmIcmpv6Type) String() string    { return oxmFieldString(m) }
+func (m *OxmIcmpv6Code) String() string    { return oxmFieldString(m) }
+func (m *OxmIpv6NdTarget) String() string  { return oxmFieldString(m) }
+func (m *OxmIpv6NdSll) String() string     { return oxmFieldString(m) }
+func (m *OxmIpv6NdTll) String() string     { return oxmFieldString(m) }
+func (m *OxmMplsLabel) String() string     { return oxmFieldString(m) }
+func (m *OxmMplsTc) String() string        { return oxmFieldString(m) }
+func (m *OxmMplsBos) String() string       { return oxmFieldString(m) }
+func (m *OxmPbbIsid) String() string       { return oxmFieldString(m) }
+func (m *OxmTunnelId) String() string      { return oxmFieldString(m) }
+func (m *OxmIpv6ExtHeader) String() string { return oxmFieldString(m) }
+
+/// String returns fields in the order of match, such as
+/// "{in_port=1, eth_type=0x0800}".
+func (m *OfpMatch) String() string {
+	if m == nil {
+		return "{}"
+	}
+	fields := make([]string, len(m.OxmFields))
+	for i, f := range m.OxmFields {
+		fields[i] = oxmFieldString(f)
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// name of OXM field of header in table features, such as "eth_dst/mask"
+func oxmIdString(header uint32) string {
+	name := oxmFieldName(oxmClass(header), oxmField(header))
+	if oxmHasMask(header) == 1 {
+		name += "/mask"
+	}
+	return name
+}
+
+/*****************************************************/
+/* Actions                                           */
+/*****************************************************/
+
+// name of action type followed by arguments
+func ofpActionString(t uint16, format string, args ...interface{}) string {
+	name := ofpMapName(ofpActionNames, uint64(t))
+	if format == "" {
+		return name
+	}
+	return name + "(" + fmt.Sprintf(format, args...) + ")"
+}
+
+func (a *OfpActionHeader) String() string {
+	return ofpMapName(ofpActionNames, uint64(a.Type))
+}
+
+func (a *OfpActionOutput) String() string {
+	return ofpActionString(a.ActionHeader.Type, "port=%s, max_len=%s",
+		ofpPortString(a.Port), ofpMaxLenString(a.MaxLen))
+}
+
+func (a *OfpActionCopyTtlOut) String() string {
+	return ofpActionString(a.ActionHeader.Type, "")
+}
+
+func (a *OfpActionCopyTtlIn) String() string {
+	return ofpActionString(a.ActionHeader.Type, "")
+}
+
+func (a *OfpActionSetMplsTtl) String() string {
+	return ofpActionString(a.ActionHeader.Type, "mpls_ttl=%d", a.MplsTtl)
+}
+
+func (a *OfpActionDecMplsTtl) String() string {
+	return ofpActionString(a.ActionHeader.Type, "")
+}
+
+func (a *OfpActionPush) String() string {
+	return ofpActionString(a.ActionHeader.Type, "ethertype=0x%04x", a.EtherType)
+}
+
+/// String of pop action. ethertype is printed only for pop_mpls, as it is
+/// ignored by other actions.
+func (a *OfpActionPop) String() string {
+	if a.ActionHeader.Type == OFPAT_POP_MPLS {
+		return ofpActionString(a.ActionHeader.Type, "ethertype=0x%04x", a.EtherType)
+	}
+	return ofpActionString(a.ActionHeader.Type, "")
+}
+
+func (a *OfpActionGroup) String() string {
+	return ofpActionString(a.ActionHeader.Type, "group_id=%s", ofpGroupString(a.GroupId))
+}
+
+func (a *OfpActionSetQueue) String() string {
+	return ofpActionString(a.ActionHeader.Type, "queue_id=%d", a.QueueId)
+}
+
+func (a *OfpActionSetNwTtl) String() string {
+	return ofpActionString(a.ActionHeader.Type, "nw_ttl=%d", a.NwTtl)
+}
+
+func (a *OfpActionDecNwTtl) String() string {
+	return ofpActionString(a.ActionHeader.Type, "")
+}
+
+func (a *OfpActionSetField) String() string {
+	if a.Oxm == nil {
+		return ofpActionString(a.ActionHeader.Type, "nil")
+	}
+	return ofpActionString(a.ActionHeader.Type, "%s", oxmFieldString(a.Oxm))
+}
+
+func (a *OfpActionExperimenter) String() string {
+	return ofpActionString(a.ActionHeader.Type, "experimenter=0x%x", a.Experimenter)
+}
+
+func ofpActionsString(actions []OfpAction) string {
+	list := make([]string, len(actions))
+	for i, a := range actions {
+		list[i] = fmt.Sprint(a)
+	}
+	return ofpListString(list)
+}
+
+/*****************************************************/
+/* Instructions                                      */
+/*****************************************************/
+
+func (i *OfpInstructionGotoTable) String() string {
+	return fmt.Sprintf("OFPIT_GOTO_TABLE(table_id=%d)", i.TableId)
+}
+
+func (i *OfpInstructionWriteMetadata) String() string {
+	return fmt.Sprintf("OFPIT_WRITE_METADATA(metadata=0x%x, metadata_mask=0x%x)", i.Metadata, i.MetadataMask)
+}
+
+func (i *OfpInstructionActions) String() string {
+	name := ofpMapName(ofpInstructionNames, uint64(i.Header.Type))
+	if i.Header.Type == OFPIT_CLEAR_ACTIONS {
+		return name
+	}
+	return name + "(actions=" + ofpActionsString(i.Actions) + ")"
+}
+
+func (i *OfpInstructionMeter) String() string {
+	return fmt.Sprintf("OFPIT_METER(meter_id=%s)", ofpMeterString(i.MeterId))
+}
+
+func (i *OfpInstructionExperimenter) String() string {
+	return fmt.Sprintf("OFPIT_EXPERIMENTER(experimenter=0x%x)", i.Experimenter)
+}
+
+func ofpInstructionsString(instructions []OfpInstruction) string {
+	list := make([]string, len(instructions))
+	for i, inst := range instructions {
+		list[i] = fmt.Sprint(inst)
+	}
+	return ofpListString(list)
+}
+
+/*****************************************************/
+/* Flow, Group and Meter                             */
+/*****************************************************/
+
+func (m *OfpFlowMod) String() string {
+	return ofpMessageString(&m.Header,
+		"command=%s table_id=%s priority=%d cookie=0x%x cookie_mask=0x%x idle_timeout=%d "+
+			"hard_timeout=%d buffer_id=%s out_port=%s out_group=%s flags=%s match=%s instructions=%s",
+		ofpName(ofpFlowModCommandNames, uint64(m.Command)), ofpTableString(m.TableId), m.Priority,
+		m.Cookie, m.CookieMask, m.IdleTimeout, m.HardTimeout, ofpBufferString(m.BufferId),
+		ofpPortString(m.OutPort), ofpGroupString(m.OutGroup),
+		ofpFlagsString(uint64(m.Flags), ofpFlowModFlags), m.Match, ofpInstructionsString(m.Instructions))
+}
+
+func (m *OfpFlowRemoved) String() string {
+	return ofpMessageString(&m.Header,
+		"reason=%s table_id=%d priority=%d cookie=0x%x duration=%s idle_timeout=%d "+
+			"hard_timeout=%d packet_count=%d byte_count=%d match=%s",
+		ofpName(ofpFlowRemovedReasonNames, uint64(m.Reason)), m.TableId, m.Priority, m.Cookie,
+		ofpDurationString(m.DurationSec, m.DurationNSec), m.IdleTimeout, m.HardTimeout,
+		m.PacketCount, m.ByteCount, m.Match)
+}
+
+func (b *OfpBucket) String() string {
+	return fmt.Sprintf("ofp_bucket(weight=%d, watch_port=%s, watch_group=%s, actions=%s)",
+		b.Weight, ofpPortString(b.WatchPort), ofpGroupString(b.WatchGroup), ofpActionsString(b.Actions))
+}
+
+func ofpBucketsString(buckets []*OfpBucket) string {
+	list := make([]string, len(buckets))
+	for i, b := range buckets {
+		list[i] = b.String()
+	}
+	return ofpListString(list)
+}
+
+func (m *OfpGroupMod) String() string {
+	return ofpMessageString(&m.Header, "command=%s type=%s group_id=%s buckets=%s",
+		ofpName(ofpGroupModCommandNames, uint64(m.Command)), ofpName(ofpGroupTypeNames, uint64(m.Type)),
+		ofpGroupString(m.GroupId), ofpBucketsString(m.Buckets))
+}
+
+// name of meter band type followed by rate and burst size
+func ofpMeterBandString(h *OfpMeterBandHeader, format string, args ...interface{}) string {
+	s := fmt.Sprintf("%s(rate=%d, burst_size=%d", ofpMapName(ofpMeterBandNames, uint64(h.Type)),
+		h.Rate, h.BurstSize)
+	if format != "" {
+		s += ", " + fmt.Sprintf(format, args...)
+	}
+	return s + ")"
+}
+
+func (b *OfpMeterBandHeader) String() string {
+	return ofpMeterBandString(b, "")
+}
+
+func (b *OfpMeterBandDrop) String() string {
+	return ofpMeterBandString(&b.Header, "")
+}
+
+func (b *OfpMeterBandDscpRemark) String() string {
+	return ofpMeterBandString(&b.Header, "prec_level=%d", b.PrecLevel)
+}
+
+func (b *OfpMeterBandExperimenter) String() string {
+	return ofpMeterBandString(&b.Header, "experimenter=0x%x", b.Experimenter)
+}
+
+func ofpMeterBandsString(bands []OfpMeterBand) string {
+	list := make([]string, len(bands))
+	for i, b := range bands {
+		list[i] = fmt.Sprint(b)
+	}
+	return ofpListString(list)
+}
+
+func (m *OfpMeterMod) String() string {
+	return ofpMessageString(&m.Header, "command=%s flags=%s meter_id=%s bands=%s",
+		ofpName(ofpMeterModCommandNames, uint64(m.Command)), ofpFlagsString(uint64(m.Flags), ofpMeterFlags),
+		ofpMeterString(m.MeterId), ofpMeterBandsString(m.Bands))
+}
+
+/*****************************************************/
+/* Packet                                            */
+/*****************************************************/
+
+/// String of PacketIn. Layers of data, such as "Ethernet/IPv4/TCP", are
+/// printed in addition to the length.
+func (m *OfpPacketIn) String() string {
+	data := m.GetData()
+	layers := ""
+	if pkt, _ := packet.Decode(data); pkt != nil && len(data) > 0 {
+		layers = " " + pkt.String()
+	}
+	return ofpMessageString(&m.Header,
+		"buffer_id=%s total_len=%d reason=%s table_id=%d cookie=0x%x match=%s data_len=%d%s",
+		ofpBufferString(m.BufferId), m.TotalLen, ofpName(ofpPacketInReasonNames, uint64(m.Reason)),
+		m.TableId, m.Cookie, m.match(), len(data), layers)
+}
+
+func (m *OfpPacketOut) String() string {
+	layers := ""
+	if pkt, _ := packet.Decode(m.Data); pkt != nil && len(m.Data) > 0 {
+		layers = " " + pkt.String()
+	}
+	return ofpMessageString(&m.Header, "buffer_id=%s in_port=%s actions=%s data_len=%d%s",
+		ofpBufferString(m.BufferId), ofpPortString(m.InPort), ofpActionsString(m.Actions),
+		len(m.Data), layers)
+}
+
+/*****************************************************/
+/* Queue                                             */
+/*****************************************************/
+
+func (p *OfpQueuePropHeader) String() string {
+	return ofpMapName(ofpQueuePropNames, uint64(p.Property))
+}
+
+func (p *OfpQueuePropMinRate) String() string {
+	return fmt.Sprintf("OFPQT_MIN_RATE(rate=%d)", p.Rate)
+}
+
+func (p *OfpQueuePropMaxRate) String() string {
+	return fmt.Sprintf("OFPQT_MAX_RATE(rate=%d)", p.Rate)
+}
+
+func (p *OfpQueuePropExperimenter) String() string {
+	return fmt.Sprintf("OFPQT_EXPERIMENTER(experimenter=0x%x, data_len=%d)", p.Experimenter, len(p.Data))
+}
+
+func (q *OfpPacketQueue) String() string {
+	props := make([]string, len(q.Properties))
+	for i, p := range q.Properties {
+		props[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("ofp_packet_queue(queue_id=%d, port=%s, properties=%s)",
+		q.QueueId, ofpPortString(q.Port), ofpListString(props))
+}
+
+func (m *OfpQueueGetConfigRequest) String() string {
+	return ofpMessageString(&m.Header, "port=%s", ofpPortString(m.Port))
+}
+
+func (m *OfpQueueGetConfigReply) String() string {
+	queues := make([]string, len(m.Queue))
+	for i, q := range m.Queue {
+		queues[i] = q.String()
+	}
+	return ofpMessageString(&m.Header, "port=%s queues=%s", ofpPortString(m.Port), ofpListString(queues))
+}
+
+/*****************************************************/
+/* Multipart                                         */
+/*****************************************************/
+
+func (m *OfpMultipartRequest) String() string {
+	s := ofpMessageString(&m.Header, "type=%s flags=%s", ofpMapName(ofpMultipartNames, uint64(m.Type)),
+		ofpFlagsString(uint64(m.Flags), ofpMultipartRequestFlags))
+	if m.Body != nil {
+		s += " body=" + fmt.Sprint(m.Body)
+	}
+	return s
+}
+
+func (m *OfpMultipartReply) String() string {
+	body := make([]string, len(m.Body))
+	for i, b := range m.Body {
+		body[i] = fmt.Sprint(b)
+	}
+	return ofpMessageString(&m.Header, "type=%s flags=%s body=%s", ofpMapName(ofpMultipartNames, uint64(m.Type)),
+		ofpFlagsString(uint64(m.Flags), ofpMultipartReplyFlags), ofpListString(body))
+}
+
+func (mp *OfpDescStats) String() string {
+	return fmt.Sprintf("ofp_desc(mfr_desc=%q, hw_desc=%q, sw_desc=%q, serial_num=%q, dp_desc=%q)",
+		ofpCString(mp.MfrDesc), ofpCString(mp.HwDesc), ofpCString(mp.SwDesc),
+		ofpCString(mp.SerialNum), ofpCString(mp.DpDesc))
+}
+
+func (mp *OfpFlowStatsRequest) String() string {
+	return fmt.Sprintf("ofp_flow_stats_request(table_id=%s, out_port=%s, out_group=%s, "+
+		"cookie=0x%x, cookie_mask=0x%x, match=%s)",
+		ofpTableString(mp.TableId), ofpPortString(mp.OutPort), ofpGroupString(mp.OutGroup),
+		mp.Cookie, mp.CookieMask, mp.Match)
+}
+
+func (mp *OfpFlowStats) String() string {
+	return fmt.Sprintf("ofp_flow_stats(table_id=%d, duration=%s, priority=%d, idle_timeout=%d, "+
+		"hard_timeout=%d, flags=%s, cookie=0x%x, packet_count=%d, byte_count=%d, match=%s, instructions=%s)",
+		mp.TableId, ofpDurationString(mp.DurationSec, mp.DurationNSec), mp.Priority, mp.IdleTimeout,
+		mp.HardTimeout, ofpFlagsString(uint64(mp.Flags), ofpFlowModFlags), mp.Cookie,
+		mp.PacketCount, mp.ByteCount, mp.Match, ofpInstructionsString(mp.Instructions))
+}
+
+func (mp *OfpAggregateStatsRequest) String() string {
+	return fmt.Sprintf("ofp_aggregate_stats_request(table_id=%s, out_port=%s, out_group=%s, "+
+		"cookie=0x%x, cookie_mask=0x%x, match=%s)",
+		ofpTableString(mp.TableId), ofpPortString(mp.OutPort), ofpGroupString(mp.OutGroup),
+		mp.Cookie, mp.CookieMask, mp.Match)
+}
+
+func (mp *OfpAggregateStats) String() string {
+	return fmt.Sprintf("ofp_aggregate_stats_reply(packet_count=%d, byte_count=%d, flow_count=%d)",
+		mp.PacketCount, mp.ByteCount, mp.FlowCount)
+}
+
+func (p *OfpTableFeaturePropHeader) String() string {
+	return ofpMapName(ofpTableFeaturePropNames, uint64(p.Type))
+}
+
+func (p *OfpInstructionId) String() string {
+	return ofpMapName(ofpInstructionNames, uint64(p.Type))
+}
+
+func (p *OfpTableFeaturePropInstructions) String() string {
+	ids := make([]string, len(p.InstructionIds))
+	for i, id := range p.InstructionIds {
+		ids[i] = id.String()
+	}
+	return fmt.Sprintf("%s(instruction_ids=%s)", p.PropHeader.String(), ofpListString(ids))
+}
+
+func (p *OfpTableFeaturePropNextTables) String() string {
+	ids := make([]string, len(p.NextTableIds))
+	for i, id := range p.NextTableIds {
+		ids[i] = strconv.Itoa(int(id))
+	}
+	return fmt.Sprintf("%s(next_table_ids=%s)", p.PropHeader.String(), ofpListString(ids))
+}
+
+func (p *OfpTableFeaturePropActions) String() string {
+	ids := make([]string, len(p.ActionIds))
+	for i := range p.ActionIds {
+		ids[i] = p.ActionIds[i].String()
+	}
+	return fmt.Sprintf("%s(action_ids=%s)", p.PropHeader.String(), ofpListString(ids))
+}
+
+func (p *OfpTableFeaturePropOxm) String() string {
+	ids := make([]string, len(p.OxmIds))
+	for i, id := range p.OxmIds {
+		ids[i] = oxmIdString(id)
+	}
+	return fmt.Sprintf("%s(oxm_ids=%s)", p.PropHeader.String(), ofpListString(ids))
+}
+
+func (p *OfpTableFeaturePropExperimenter) String() string {
+	return fmt.Sprintf("%s(experimenter=0x%x, exp_type=%d, data_len=%d)", p.PropHeader.String(),
+		p.Experimenter, p.ExpType, len(p.ExperimenterData)*4)
+}
+
+func (mp *OfpTableFeatures) String() string {
+	props := make([]string, len(mp.Properties))
+	for i, p := range mp.Properties {
+		props[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("ofp_table_features(table_id=%d, name=%s, metadata_match=0x%x, "+
+		"metadata_write=0x%x, config=0x%x, max_entries=%d, properties=%s)",
+		mp.TableId, ofpCString(mp.Name), mp.MetadataMatch, mp.MetadataWrite, mp.Config,
+		mp.MaxEntries, ofpListString(props))
+}
+
+func (mp *OfpTableStats) String() string {
+	return fmt.Sprintf("ofp_table_stats(table_id=%d, active_count=%d, lookup_count=%d, matched_count=%d)",
+		mp.TableId, mp.ActiveCount, mp.LookupCount, mp.MatchedCount)
+}
+
+func (mp *OfpPortStatsRequest) String() string {
+	return fmt.Sprintf("ofp_port_stats_request(port_no=%s)", ofpPortString(mp.PortNo))
+}
+
+func (mp *OfpPortStats) String() string {
+	return fmt.Sprintf("ofp_port_stats(port_no=%s, rx_packets=%d, tx_packets=%d, rx_bytes=%d, "+
+		"tx_bytes=%d, rx_dropped=%d, tx_dropped=%d, rx_errors=%d, tx_errors=%d, rx_frame_err=%d, "+
+		"rx_over_err=%d, rx_crc_err=%d, collisions=%d, duration=%s)",
+		ofpPortString(mp.PortNo), mp.RxPackets, mp.TxPackets, mp.RxBytes, mp.TxBytes,
+		mp.RxDropped, mp.TxDropped, mp.RxErrors, mp.TxErrors, mp.RxFrameErr, mp.RxOverErr,
+		mp.RxCrcErr, mp.Collisions, ofpDurationString(mp.DurationSec, mp.DurationNSec))
+}
+
+func (mp *OfpQueueStatsRequest) String() string {
+	return fmt.Sprintf("ofp_queue_stats_request(port_no=%s, queue_id=%s)",
+		ofpPortString(mp.PortNo), ofpQueueString(mp.QueueId))
+}
+
+func (mp *OfpQueueStats) String() string {
+	return fmt.Sprintf("ofp_queue_stats(port_no=%s, queue_id=%d, tx_bytes=%d, tx_packets=%d, "+
+		"tx_errors=%d, duration=%s)",
+		ofpPortString(mp.PortNo), mp.QueueId, mp.TxBytes, mp.TxPackets, mp.TxErrors,
+		ofpDurationString(mp.DurationSec, mp.DurationNSec))
+}
+
+func (mp *OfpGroupStatsRequest) String() string {
+	return fmt.Sprintf("ofp_group_stats_request(group_id=%s)", ofpGroupString(mp.GroupId))
+}
+
+func (c *OfpBucketCounter) String() string {
+	return fmt.Sprintf("ofp_bucket_counter(packet_count=%d, byte_count=%d)", c.PacketCount, c.ByteCount)
+}
+
+func (mp *OfpGroupStats) String() string {
+	counters := make([]string, len(mp.BucketStats))
+	for i, c := range mp.BucketStats {
+		counters[i] = c.String()
+	}
+	return fmt.Sprintf("ofp_group_stats(group_id=%d, ref_count=%d, packet_count=%d, byte_count=%d, "+
+		"duration=%s, bucket_stats=%s)",
+		mp.GroupId, mp.RefCount, mp.PacketCount, mp.ByteCount,
+		ofpDurationString(mp.DurationSec, mp.DurationNSec), ofpListString(counters))
+}
+
+func (mp *OfpGroupDescStats) String() string {
+	return fmt.Sprintf("ofp_group_desc(type=%s, group_id=%d, buckets=%s)",
+		ofpName(ofpGroupTypeNames, uint64(mp.Type)), mp.GroupId, ofpBucketsString(mp.Buckets))
+}
+
+func (mp *OfpGroupFeaturesStats) String() string {
+	var maxGroups, actions []string
+	for i := range ofpGroupTypeNames {
+		maxGroups = append(maxGroups, fmt.Sprintf("%s=%d", ofpGroupTypeNames[i], mp.MaxGroups[i]))
+		actions = append(actions, fmt.Sprintf("%s=0x%x", ofpGroupTypeNames[i], mp.Actions[i]))
+	}
+	return fmt.Sprintf("ofp_group_features(types=%s, capabilities=%s, max_groups=%s, actions=%s)",
+		ofpBitsString(mp.Type, ofpGroupTypeNames),
+		ofpFlagsString(uint64(mp.Capabilities), ofpGroupCapabilitiesFlags),
+		ofpListString(maxGroups), ofpListString(actions))
+}
+
+func (mp *OfpMeterMultipartRequest) String() string {
+	return fmt.Sprintf("ofp_meter_multipart_request(meter_id=%s)", ofpMeterString(mp.MeterId))
+}
+
+func (s *OfpMeterBandStats) String() string {
+	return fmt.Sprintf("ofp_meter_band_stats(packet_band_count=%d, byte_band_count=%d)",
+		s.PacketBandCount, s.ByteBandCount)
+}
+
+func (mp *OfpMeterStats) String() string {
+	bands := make([]string, len(mp.BandStats))
+	for i, b := range mp.BandStats {
+		bands[i] = b.String()
+	}
+	return fmt.Sprintf("ofp_meter_stats(meter_id=%d, flow_count=%d, packet_in_count=%d, "+
+		"byte_in_count=%d, duration=%s, band_stats=%s)",
+		mp.MeterId, mp.FlowCount, mp.PacketInCount, mp.ByteInCount,
+		ofpDurationString(mp.DurationSec, mp.DurationNSec), ofpListString(bands))
+}
+
+func (mp *OfpMeterConfig) String() string {
+	return fmt.Sprintf("ofp_meter_config(flags=%s, meter_id=%d, bands=%s)",
+		ofpFlagsString(uint64(mp.Flags), ofpMeterFlags), mp.MeterId, ofpMeterBandsString(mp.Bands))
+}
+
+func (mp *OfpMeterFeatures) String() string {
+	return fmt.Sprintf("ofp_meter_features(max_meter=%d, band_types=%s, capabilities=%s, "+
+		"max_bands=%d, max_color=%d)",
+		mp.MaxMeter, ofpBitsString(mp.BandTypes, ofpMeterBandBits),
+		ofpFlagsString(uint64(mp.Capabilities), ofpMeterFlags), mp.MaxBands, mp.MaxColor)
+}
+
+func (m *OfpExperimenterMultipartHeader) String() string {
+	return fmt.Sprintf("ofp_experimenter_multipart_header(experimenter=0x%x, exp_type=%d)",
+		m.Experimenter, m.ExpType)
+}
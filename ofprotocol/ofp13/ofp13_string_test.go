@@ -0,0 +1,138 @@
+package ofp13
+
+import (
+	"fmt"
+	"testing"
+)
+
+func expectString(t *testing.T, actual string, expected string) {
+	t.Helper()
+	if actual != expected {
+		t.Log("Expected string is : ", expected)
+		t.Log("Actual string is   : ", actual)
+		t.Error("String is invalid.")
+	}
+}
+
+/*****************************************************/
+/* Names and Flags                                   */
+/*****************************************************/
+func TestOfpFlagsString(t *testing.T) {
+	expectString(t, ofpFlagsString(0, ofpFlowModFlags), "0")
+	expectString(t, ofpFlagsString(OFPFF_SEND_FLOW_REM|OFPFF_NO_BYT_COUNTS|0x100, ofpFlowModFlags),
+		"OFPFF_SEND_FLOW_REM|OFPFF_NO_BYT_COUNTS|0x100")
+	expectString(t, TypeString(OFPT_FLOW_MOD), "OFPT_FLOW_MOD")
+	expectString(t, TypeString(200), "200")
+	expectString(t, ErrorCodeString(OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ), "OFPBMC_BAD_PREREQ")
+	expectString(t, ErrorTypeString(OFPET_EXPERIMENTER), "OFPET_EXPERIMENTER")
+}
+
+/*****************************************************/
+/* OXM Fields and Actions                            */
+/*****************************************************/
+func TestOxmFieldString(t *testing.T) {
+	ipDst, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	ethDst, _ := NewOxmEthDst("00:11:22:33:44:55")
+	ipv6Src, _ := NewOxmIpv6Src("2001:db8::1")
+	cases := []struct {
+		field    OxmField
+		expected string
+	}{
+		{NewOxmInPort(OFPP_LOCAL), "in_port=OFPP_LOCAL"},
+		{NewOxmInPort(3), "in_port=3"},
+		{NewOxmEthType(0x0800), "eth_type=0x0800"},
+		{ethDst, "eth_dst=00:11:22:33:44:55"},
+		{NewOxmVlanVid(OFPVID_PRESENT | 10), "vlan_vid=OFPVID_PRESENT|10"},
+		{NewOxmVlanVid(OFPVID_NONE), "vlan_vid=OFPVID_NONE"},
+		{ipDst, "ipv4_dst=10.0.0.0/8"},
+		{ipv6Src, "ipv6_src=2001:db8::1"},
+		{NewOxmIpProto(6), "ip_proto=6"},
+		{NewOxmTcpDst(80), "tcp_dst=80"},
+		{NewOxmMetadataW(0x1, 0xff), "metadata=0x1/0xff"},
+	}
+	for _, c := range cases {
+		expectString(t, fmt.Sprint(c.field), c.expected)
+	}
+	expectString(t, matchOf(NewOxmInPort(1), NewOxmEthType(0x0806)).String(), "{in_port=1, eth_type=0x0806}")
+}
+
+func TestOfpActionString(t *testing.T) {
+	actions := []OfpAction{
+		NewOfpActionOutput(OFPP_CONTROLLER, OFPCML_NO_BUFFER),
+		NewOfpActionPushVlan(),
+		NewOfpActionSetField(NewOxmVlanVid(OFPVID_PRESENT | 10)),
+		NewOfpActionPopVlan(0),
+		NewOfpActionGroup(1),
+		NewOfpActionDecNwTtl(),
+	}
+	expectString(t, ofpActionsString(actions),
+		"[OFPAT_OUTPUT(port=OFPP_CONTROLLER, max_len=OFPCML_NO_BUFFER), "+
+			"OFPAT_PUSH_VLAN(ethertype=0x8100), OFPAT_SET_FIELD(vlan_vid=OFPVID_PRESENT|10), "+
+			"OFPAT_POP_VLAN, OFPAT_GROUP(group_id=1), OFPAT_DEC_NW_TTL]")
+}
+
+/*****************************************************/
+/* Messages                                          */
+/*****************************************************/
+func TestOfpMessageString(t *testing.T) {
+	echo := NewOfpEchoRequest()
+	echo.Xid = 0x10
+	expectString(t, echo.String(), "OFPT_ECHO_REQUEST (xid=0x10)")
+
+	fm := flowModOf(matchOf(NewOxmInPort(1)), NewOfpActionOutput(2, OFPCML_NO_BUFFER))
+	fm.Header.Xid = 5
+	fm.Priority = 100
+	fm.Flags = OFPFF_SEND_FLOW_REM
+	fm.Instructions = append(fm.Instructions, NewOfpInstructionGotoTable(1))
+	expectString(t, fm.String(),
+		"OFPT_FLOW_MOD (xid=0x5): command=OFPFC_ADD table_id=0 priority=100 cookie=0x0 "+
+			"cookie_mask=0x0 idle_timeout=0 hard_timeout=0 buffer_id=OFP_NO_BUFFER out_port=OFPP_ANY "+
+			"out_group=OFPG_ANY flags=OFPFF_SEND_FLOW_REM match={in_port=1} "+
+			"instructions=[OFPIT_APPLY_ACTIONS(actions=[OFPAT_OUTPUT(port=2, max_len=OFPCML_NO_BUFFER)]), "+
+			"OFPIT_GOTO_TABLE(table_id=1)]")
+
+	em := NewOfpErrorMsg()
+	em.Header.Xid = 5
+	em.Type = OFPET_BAD_MATCH
+	em.Code = OFPBMC_BAD_PREREQ
+	em.Data = fm.Serialize()
+	expectString(t, em.String(),
+		"OFPT_ERROR (xid=0x5): type=OFPET_BAD_MATCH code=OFPBMC_BAD_PREREQ request=OFPT_FLOW_MOD (xid=0x5)")
+
+	mm := NewOfpMeterMod(OFPMC_ADD, OFPMF_KBPS|OFPMF_BURST, 1)
+	mm.AppendMeterBand(NewOfpMeterBandDrop(1000, 100))
+	mm.Header.Xid = 1
+	expectString(t, mm.String(),
+		"OFPT_METER_MOD (xid=0x1): command=OFPMC_ADD flags=OFPMF_KBPS|OFPMF_BURST meter_id=1 "+
+			"bands=[OFPMBT_DROP(rate=1000, burst_size=100)]")
+
+	req := NewOfpFlowStatsRequest(0, OFPTT_ALL, OFPP_ANY, OFPG_ANY, 0, 0, NewOfpMatch())
+	req.Header.Xid = 2
+	expectString(t, req.String(),
+		"OFPT_MULTIPART_REQUEST (xid=0x2): type=OFPMP_FLOW flags=0 body=ofp_flow_stats_request("+
+			"table_id=OFPTT_ALL, out_port=OFPP_ANY, out_group=OFPG_ANY, cookie=0x0, cookie_mask=0x0, match={})")
+}
+
+func TestOfpStringZeroValues(t *testing.T) {
+	// String of zero values must not panic
+	for _, v := range []fmt.Stringer{
+		&OfpHeader{}, &OfpHello{}, &OfpHelloElemVersionBitmap{}, &OfpErrorMsg{},
+		&OfpErrorExperimenterMsg{}, &OfpSwitchFeatures{}, &OfpSwitchConfig{}, &OfpTableMod{},
+		&OfpPort{}, &OfpPortStatus{}, &OfpPortMod{}, &OfpRole{}, &OfpAsyncConfig{}, &OfpMatch{},
+		&OfpActionSetField{}, &OfpInstructionActions{}, &OfpFlowMod{}, &OfpFlowRemoved{},
+		&OfpBucket{}, &OfpGroupMod{}, &OfpMeterMod{}, &OfpPacketIn{}, &OfpPacketOut{},
+		&OfpPacketQueue{}, &OfpQueueGetConfigRequest{}, &OfpQueueGetConfigReply{},
+		&OfpMultipartRequest{}, &OfpMultipartReply{}, &OfpDescStats{}, &OfpFlowStatsRequest{},
+		&OfpFlowStats{}, &OfpAggregateStatsRequest{}, &OfpAggregateStats{}, &OfpTableFeatures{},
+		&OfpTableFeaturePropInstructions{}, &OfpTableFeaturePropNextTables{},
+		&OfpTableFeaturePropActions{}, &OfpTableFeaturePropOxm{}, &OfpTableFeaturePropExperimenter{},
+		&OfpTableStats{}, &OfpPortStatsRequest{}, &OfpPortStats{}, &OfpQueueStatsRequest{},
+		&OfpQueueStats{}, &OfpGroupStatsRequest{}, &OfpGroupStats{}, &OfpGroupDescStats{},
+		&OfpGroupFeaturesStats{}, &OfpMeterMultipartRequest{}, &OfpMeterStats{},
+		&OfpMeterConfig{}, &OfpMeterFeatures{}, &OfpMeterBandDrop{}, &OfpQueuePropMinRate{},
+	} {
+		if v.String() == "" {
+			t.Errorf("String of %T is empty.", v)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+package ofp13
+
+import (
+	"fmt"
+)
+
+/*****************************************************/
+/* Validation                                        */
+/*****************************************************/
+
+/// ValidationError is an error found by Validate before the message is
+/// sent. Type and Code are those of OFPT_ERROR the switch would reply, such
+/// as OFPET_BAD_MATCH and OFPBMC_BAD_PREREQ. Field is the OXM field of
+/// OFPXMC_OPENFLOW_BASIC which caused the error, or -1.
+type ValidationError struct {
+	Type   uint16
+	Code   uint16
+	Field  int
+	Reason string
+}
+
+func newValidationError(t uint16, code uint16, field int, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{t, code, field, fmt.Sprintf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid message, %s (type %d code %d).", e.Reason, e.Type, e.Code)
+}
+
+/// Validator is implemented by messages which can be checked before they
+/// are sent.
+type Validator interface {
+	Validate() error
+}
+
+/// properties of OXM field of OFPXMC_OPENFLOW_BASIC, from table 11 of
+/// OpenFlow 1.3.5 spec. max is the maximum value of field with fewer bits
+/// than its size, or 0.
+type oxmFieldSpec struct {
+	name     string
+	size     int
+	maskable bool
+	max      uint64
+}
+
+var oxmFieldSpecs = [...]oxmFieldSpec{
+	OFPXMT_OFB_IN_PORT:        {"in_port", 4, false, 0},
+	OFPXMT_OFB_IN_PHY_PORT:    {"in_phy_port", 4, false, 0},
+	OFPXMT_OFB_METADATA:       {"metadata", 8, true, 0},
+	OFPXMT_OFB_ETH_DST:        {"eth_dst", 6, true, 0},
+	OFPXMT_OFB_ETH_SRC:        {"eth_src", 6, true, 0},
+	OFPXMT_OFB_ETH_TYPE:       {"eth_type", 2, false, 0},
+	OFPXMT_OFB_VLAN_VID:       {"vlan_vid", 2, true, 0x1fff},
+	OFPXMT_OFB_VLAN_PCP:       {"vlan_pcp", 1, false, 7},
+	OFPXMT_OFB_IP_DSCP:        {"ip_dscp", 1, false, 63},
+	OFPXMT_OFB_IP_ECN:         {"ip_ecn", 1, false, 3},
+	OFPXMT_OFB_IP_PROTO:       {"ip_proto", 1, false, 0},
+	OFPXMT_OFB_IPV4_SRC:       {"ipv4_src", 4, true, 0},
+	OFPXMT_OFB_IPV4_DST:       {"ipv4_dst", 4, true, 0},
+	OFPXMT_OFB_TCP_SRC:        {"tcp_src", 2, false, 0},
+	OFPXMT_OFB_TCP_DST:        {"tcp_dst", 2, false, 0},
+	OFPXMT_OFB_UDP_SRC:        {"udp_src", 2, false, 0},
+	OFPXMT_OFB_UDP_DST:        {"udp_dst", 2, false, 0},
+	OFPXMT_OFB_SCTP_SRC:       {"sctp_src", 2, false, 0},
+	OFPXMT_OFB_SCTP_DST:       {"sctp_dst", 2, false, 0},
+	OFPXMT_OFB_ICMPV4_TYPE:    {"icmpv4_type", 1, false, 0},
+	OFPXMT_OFB_ICMPV4_CODE:    {"icmpv4_code", 1, false, 0},
+	OFPXMT_OFB_ARP_OP:         {"arp_op", 2, false, 0},
+	OFPXMT_OFB_ARP_SPA:        {"arp_spa", 4, true, 0},
+	OFPXMT_OFB_ARP_TPA:        {"arp_tpa", 4, true, 0},
+	OFPXMT_OFB_ARP_SHA:        {"arp_sha", 6, true, 0},
+	OFPXMT_OFB_ARP_THA:        {"arp_tha", 6, true, 0},
+	OFPXMT_OFB_IPV6_SRC:       {"ipv6_src", 16, true, 0},
+	OFPXMT_OFB_IPV6_DST:       {"ipv6_dst", 16, true, 0},
+	OFPXMT_OFB_IPV6_FLABEL:    {"ipv6_flabel", 4, true, 0xfffff},
+	OFPXMT_OFB_ICMPV6_TYPE:    {"icmpv6_type", 1, false, 0},
+	OFPXMT_OFB_ICMPV6_CODE:    {"icmpv6_code", 1, false, 0},
+	OFPXMT_OFB_IPV6_ND_TARGET: {"ipv6_nd_target", 16, false, 0},
+	OFPXMT_OFB_IPV6_ND_SLL:    {"ipv6_nd_sll", 6, false, 0},
+	OFPXMT_OFB_IPV6_ND_TLL:    {"ipv6_nd_tll", 6, false, 0},
+	OFPXMT_OFB_MPLS_LABEL:     {"mpls_label", 4, false, 0xfffff},
+	OFPXMT_OFB_MPLS_TC:        {"mpls_tc", 1, false, 7},
+	OFPXMT_OFB_MPLS_BOS:       {"mpls_bos", 1, false, 1},
+	OFPXMT_OFB_PBB_ISID:       {"pbb_isid", 3, true, 0},
+	OFPXMT_OFB_TUNNEL_ID:      {"tunnel_id", 8, true, 0},
+	OFPXMT_OFB_IPV6_EXTHDR:    {"ipv6_exthdr", 2, true, 0x1ff},
+}
+
+// name of OXM field, such as "tcp_dst"
+func oxmFieldName(class uint32, field uint32) string {
+	if class == OFPXMC_OPENFLOW_BASIC && int(field) < len(oxmFieldSpecs) {
+		return oxmFieldSpecs[field].name
+	}
+	return fmt.Sprintf("oxm(0x%04x:%d)", class, field)
+}
+
+/// prerequisite of OXM field. the field requires field pre matched exactly
+/// with one of values, or matched with any value if values is nil.
+type oxmPrerequisite struct {
+	field  uint32
+	values []uint64
+}
+
+var oxmPrerequisites = map[uint32]oxmPrerequisite{
+	OFPXMT_OFB_IN_PHY_PORT:    {OFPXMT_OFB_IN_PORT, nil},
+	OFPXMT_OFB_VLAN_PCP:       {OFPXMT_OFB_VLAN_VID, nil},
+	OFPXMT_OFB_IP_DSCP:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x0800, 0x86dd}},
+	OFPXMT_OFB_IP_ECN:         {OFPXMT_OFB_ETH_TYPE, []uint64{0x0800, 0x86dd}},
+	OFPXMT_OFB_IP_PROTO:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x0800, 0x86dd}},
+	OFPXMT_OFB_IPV4_SRC:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x0800}},
+	OFPXMT_OFB_IPV4_DST:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x0800}},
+	OFPXMT_OFB_TCP_SRC:        {OFPXMT_OFB_IP_PROTO, []uint64{6}},
+	OFPXMT_OFB_TCP_DST:        {OFPXMT_OFB_IP_PROTO, []uint64{6}},
+	OFPXMT_OFB_UDP_SRC:        {OFPXMT_OFB_IP_PROTO, []uint64{17}},
+	OFPXMT_OFB_UDP_DST:        {OFPXMT_OFB_IP_PROTO, []uint64{17}},
+	OFPXMT_OFB_SCTP_SRC:       {OFPXMT_OFB_IP_PROTO, []uint64{132}},
+	OFPXMT_OFB_SCTP_DST:       {OFPXMT_OFB_IP_PROTO, []uint64{132}},
+	OFPXMT_OFB_ICMPV4_TYPE:    {OFPXMT_OFB_IP_PROTO, []uint64{1}},
+	OFPXMT_OFB_ICMPV4_CODE:    {OFPXMT_OFB_IP_PROTO, []uint64{1}},
+	OFPXMT_OFB_ARP_OP:         {OFPXMT_OFB_ETH_TYPE, []uint64{0x0806}},
+	OFPXMT_OFB_ARP_SPA:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x0806}},
+	OFPXMT_OFB_ARP_TPA:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x0806}},
+	OFPXMT_OFB_ARP_SHA:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x0806}},
+	OFPXMT_OFB_ARP_THA:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x0806}},
+	OFPXMT_OFB_IPV6_SRC:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x86dd}},
+	OFPXMT_OFB_IPV6_DST:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x86dd}},
+	OFPXMT_OFB_IPV6_FLABEL:    {OFPXMT_OFB_ETH_TYPE, []uint64{0x86dd}},
+	OFPXMT_OFB_ICMPV6_TYPE:    {OFPXMT_OFB_IP_PROTO, []uint64{58}},
+	OFPXMT_OFB_ICMPV6_CODE:    {OFPXMT_OFB_IP_PROTO, []uint64{58}},
+	OFPXMT_OFB_IPV6_ND_TARGET: {OFPXMT_OFB_ICMPV6_TYPE, []uint64{135, 136}},
+	OFPXMT_OFB_IPV6_ND_SLL:    {OFPXMT_OFB_ICMPV6_TYPE, []uint64{135}},
+	OFPXMT_OFB_IPV6_ND_TLL:    {OFPXMT_OFB_ICMPV6_TYPE, []uint64{136}},
+	OFPXMT_OFB_MPLS_LABEL:     {OFPXMT_OFB_ETH_TYPE, []uint64{0x8847, 0x8848}},
+	OFPXMT_OFB_MPLS_TC:        {OFPXMT_OFB_ETH_TYPE, []uint64{0x8847, 0x8848}},
+	OFPXMT_OFB_MPLS_BOS:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x8847, 0x8848}},
+	OFPXMT_OFB_PBB_ISID:       {OFPXMT_OFB_ETH_TYPE, []uint64{0x88e7}},
+	OFPXMT_OFB_IPV6_EXTHDR:    {OFPXMT_OFB_ETH_TYPE, []uint64{0x86dd}},
+}
+
+// value of field in big endian, or the first 8 bytes of longer field
+func oxmValue(value []byte) uint64 {
+	var v uint64
+	for i := 0; i < len(value) && i < 8; i++ {
+		v = v<<8 | uint64(value[i])
+	}
+	return v
+}
+
+/// fields known to be matched, used to check prerequisites of match fields
+/// and set-field actions. it is updated by push and pop actions.
+type oxmFieldSet map[uint32]oxmEntry
+
+func (s oxmFieldSet) satisfied(field uint32) bool {
+	pre, ok := oxmPrerequisites[field]
+	if !ok {
+		return true
+	}
+	e, ok := s[pre.field]
+	if !ok {
+		return false
+	}
+	if pre.field == OFPXMT_OFB_VLAN_VID {
+		// vlan_vid must not be OFPVID_NONE, so OFPVID_PRESENT is matched
+		if e.maskAt(0)&0x10 == 0 || e.value[0]&0x10 == 0 {
+			return false
+		}
+	}
+	if pre.values != nil {
+		if e.mask != nil {
+			return false
+		}
+		found := false
+		for _, v := range pre.values {
+			if oxmValue(e.value) == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return s.satisfied(pre.field)
+}
+
+// describe prerequisite of field for error message
+func prerequisiteString(field uint32) string {
+	pre := oxmPrerequisites[field]
+	if pre.field == OFPXMT_OFB_VLAN_VID {
+		return "vlan_vid with OFPVID_PRESENT"
+	}
+	if pre.values == nil {
+		return oxmFieldSpecs[pre.field].name
+	}
+	format := "%s=%d"
+	if pre.field == OFPXMT_OFB_ETH_TYPE {
+		format = "%s=0x%04x"
+	}
+	s := ""
+	for i, v := range pre.values {
+		if i > 0 {
+			s += " or "
+		}
+		s += fmt.Sprintf(format, oxmFieldSpecs[pre.field].name, v)
+	}
+	return s
+}
+
+// validate length and value of OXM field, without prerequisites
+func validateOxmEntry(e oxmEntry) *ValidationError {
+	field := int(e.field)
+	if int(e.field) >= len(oxmFieldSpecs) {
+		return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_FIELD, field,
+			"unknown field %d", e.field)
+	}
+	spec := oxmFieldSpecs[e.field]
+	if len(e.value) != spec.size {
+		return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_LEN, field,
+			"%s is %d bytes, but must be %d bytes", spec.name, len(e.value), spec.size)
+	}
+	if e.mask != nil {
+		if !spec.maskable {
+			return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_MASK, field,
+				"%s is not maskable", spec.name)
+		}
+		for i := range e.value {
+			if e.value[i]&^e.mask[i] != 0 {
+				return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_WILDCARDS, field,
+					"%s has bits set outside of its mask", spec.name)
+			}
+		}
+	}
+	v := oxmValue(e.value)
+	if spec.max != 0 && v > spec.max {
+		return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_VALUE, field,
+			"%s is %d, but must be at most %d", spec.name, v, spec.max)
+	}
+	if e.field == OFPXMT_OFB_VLAN_VID && e.maskAt(0)&0x10 != 0 && v&OFPVID_PRESENT == 0 && v != OFPVID_NONE {
+		return newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_VALUE, field,
+			"vlan_vid %d must be ORed with OFPVID_PRESENT", v)
+	}
+	return nil
+}
+
+/// Validate checks fields of match as a switch does on FlowMod: fields are
+/// known and not duplicated, lengths, masks and values are legal, and
+/// prerequisites of table 11 of the spec are matched exactly by preceding
+/// fields. For example tcp_dst requires ip_proto=6 before it, which requires
+/// eth_type=0x0800 or 0x86dd.
+/// It returns *ValidationError.
+func (m *OfpMatch) Validate() error {
+	if _, err := m.validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *OfpMatch) validate() (oxmFieldSet, *ValidationError) {
+	fields := make(oxmFieldSet)
+	if m == nil {
+		return fields, nil
+	}
+	if m.Type != OFPMT_OXM {
+		return nil, newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_TYPE, -1,
+			"match type %d is not OFPMT_OXM", m.Type)
+	}
+	for _, f := range m.OxmFields {
+		if f == nil {
+			return nil, newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_FIELD, -1, "field is nil")
+		}
+		e := newOxmEntry(f)
+		if e.opaque != nil {
+			// experimenter fields are not known
+			continue
+		}
+		if err := validateOxmEntry(e); err != nil {
+			return nil, err
+		}
+		if _, ok := fields[e.field]; ok {
+			return nil, newValidationError(OFPET_BAD_MATCH, OFPBMC_DUP_FIELD, int(e.field),
+				"%s is duplicated", oxmFieldSpecs[e.field].name)
+		}
+		fields[e.field] = e
+	}
+	// prerequisites must precede the field
+	seen := make(oxmFieldSet)
+	for _, f := range m.OxmFields {
+		if f.OxmClass() != OFPXMC_OPENFLOW_BASIC {
+			continue
+		}
+		field := f.OxmField()
+		if !seen.satisfied(field) {
+			format := "%s requires %s"
+			if fields.satisfied(field) {
+				format = "%s must follow %s"
+			}
+			return nil, newValidationError(OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ, int(field),
+				format, oxmFieldSpecs[field].name, prerequisiteString(field))
+		}
+		seen[field] = fields[field]
+	}
+	return fields, nil
+}
+
+// fields not allowed to be set by set-field action
+func oxmSettable(field uint32) bool {
+	switch field {
+	case OFPXMT_OFB_IN_PORT, OFPXMT_OFB_IN_PHY_PORT, OFPXMT_OFB_METADATA,
+		OFPXMT_OFB_ETH_TYPE, OFPXMT_OFB_IP_PROTO, OFPXMT_OFB_IPV6_EXTHDR:
+		return false
+	}
+	return int(field) < len(oxmFieldSpecs)
+}
+
+/// Validate checks set-field action alone: the field is settable, is not
+/// masked, and has legal length and value. Prerequisites are checked by
+/// Validate of OfpFlowMod, which knows the match.
+func (a *OfpActionSetField) Validate() error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (a *OfpActionSetField) validate() *ValidationError {
+	if a.Oxm == nil {
+		return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_SET_TYPE, -1, "set_field has no field")
+	}
+	e := newOxmEntry(a.Oxm)
+	if e.opaque != nil || !oxmSettable(e.field) {
+		return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_SET_TYPE, int(e.field),
+			"%s can not be set", oxmFieldName(e.class, e.field))
+	}
+	if e.mask != nil {
+		return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_SET_ARGUMENT, int(e.field),
+			"set_field of %s has mask", oxmFieldSpecs[e.field].name)
+	}
+	if err := validateOxmEntry(e); err != nil {
+		// report as error of action
+		err.Type = OFPET_BAD_ACTION
+		err.Code = OFPBAC_BAD_SET_ARGUMENT
+		if err.Field >= 0 && len(e.value) != oxmFieldSpecs[e.field].size {
+			err.Code = OFPBAC_BAD_SET_LEN
+		}
+		return err
+	}
+	return nil
+}
+
+/// validate list of actions. fields is updated by push and pop actions so
+/// that prerequisites of set-field are checked, or nil not to check them.
+func validateActions(actions []OfpAction, fields oxmFieldSet) *ValidationError {
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *OfpActionOutput:
+			if a.Port == 0 || a.Port == OFPP_ANY {
+				return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_OUT_PORT, -1,
+					"output to port 0x%x", a.Port)
+			}
+		case *OfpActionGroup:
+			if a.GroupId > OFPG_MAX {
+				return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_OUT_GROUP, -1,
+					"group 0x%x is reserved", a.GroupId)
+			}
+		case *OfpActionPush:
+			var allowed []uint16
+			switch a.ActionHeader.Type {
+			case OFPAT_PUSH_VLAN:
+				allowed = []uint16{0x8100, 0x88a8}
+			case OFPAT_PUSH_MPLS:
+				allowed = []uint16{0x8847, 0x8848}
+			case OFPAT_PUSH_PBB:
+				allowed = []uint16{0x88e7}
+			}
+			if !containsUint16(allowed, a.EtherType) {
+				return newValidationError(OFPET_BAD_ACTION, OFPBAC_BAD_ARGUMENT, -1,
+					"push of ethertype 0x%04x", a.EtherType)
+			}
+			if fields != nil {
+				fields.push(a)
+			}
+		case *OfpActionPop:
+			if fields != nil {
+				fields.pop(a)
+			}
+		case *OfpActionSetField:
+			if err := a.validate(); err != nil {
+				return err
+			}
+			field := a.Oxm.OxmField()
+			if fields != nil && !fields.satisfied(field) {
+				return newValidationError(OFPET_BAD_ACTION, OFPBAC_MATCH_INCONSISTENT, int(field),
+					"set_field of %s requires %s", oxmFieldSpecs[field].name, prerequisiteString(field))
+			}
+		}
+	}
+	return nil
+}
+
+func containsUint16(list []uint16, v uint16) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s oxmFieldSet) setExact(field uint32, value []byte) {
+	s[field] = oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: field, value: value}
+}
+
+// update fields with header pushed by action
+func (s oxmFieldSet) push(a *OfpActionPush) {
+	switch a.ActionHeader.Type {
+	case OFPAT_PUSH_VLAN:
+		s[OFPXMT_OFB_VLAN_VID] = oxmEntry{class: OFPXMC_OPENFLOW_BASIC, field: OFPXMT_OFB_VLAN_VID,
+			value: []byte{0x10, 0x00}, mask: []byte{0x10, 0x00}}
+	case OFPAT_PUSH_MPLS:
+		s.setExact(OFPXMT_OFB_ETH_TYPE, []byte{byte(a.EtherType >> 8), byte(a.EtherType)})
+		// fields of ip and above are no longer visible
+		for field, pre := range oxmPrerequisites {
+			if pre.field == OFPXMT_OFB_ETH_TYPE && field != OFPXMT_OFB_PBB_ISID {
+				delete(s, field)
+			}
+		}
+	}
+}
+
+// update fields with header popped by action
+func (s oxmFieldSet) pop(a *OfpActionPop) {
+	switch a.ActionHeader.Type {
+	case OFPAT_POP_VLAN:
+		delete(s, OFPXMT_OFB_VLAN_VID)
+		delete(s, OFPXMT_OFB_VLAN_PCP)
+	case OFPAT_POP_MPLS:
+		// the fields of the new payload are unknown but eth_type
+		delete(s, OFPXMT_OFB_MPLS_LABEL)
+		delete(s, OFPXMT_OFB_MPLS_TC)
+		delete(s, OFPXMT_OFB_MPLS_BOS)
+		s.setExact(OFPXMT_OFB_ETH_TYPE, []byte{byte(a.EtherType >> 8), byte(a.EtherType)})
+	}
+}
+
+func (s oxmFieldSet) copy() oxmFieldSet {
+	c := make(oxmFieldSet, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+/// Validate checks FlowMod before it is sent: command and table id, match
+/// fields by Validate of OfpMatch, instructions and their actions.
+/// Set-field actions are checked against the match, so that setting
+/// ipv4_dst of a flow without eth_type=0x0800 is an error, while
+/// vlan_vid can be set after push_vlan.
+/// It returns *ValidationError.
+func (m *OfpFlowMod) Validate() error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *OfpFlowMod) validate() *ValidationError {
+	if m.Command > OFPFC_DELETE_STRICT {
+		return newValidationError(OFPET_FLOW_MOD_FAILED, OFPFMFC_BAD_COMMAND, -1,
+			"flow_mod command %d is unknown", m.Command)
+	}
+	deleting := m.Command == OFPFC_DELETE || m.Command == OFPFC_DELETE_STRICT
+	if m.TableId == OFPTT_ALL && !deleting {
+		return newValidationError(OFPET_FLOW_MOD_FAILED, OFPFMFC_BAD_TABLE_ID, -1,
+			"table OFPTT_ALL is only for delete")
+	}
+	fields, err := m.Match.validate()
+	if err != nil {
+		return err
+	}
+	if deleting {
+		// instructions are ignored
+		return nil
+	}
+
+	seen := make(map[uint16]bool)
+	for _, inst := range m.Instructions {
+		if inst == nil {
+			return newValidationError(OFPET_BAD_INSTRUCTION, OFPBIC_UNKNOWN_INST, -1, "instruction is nil")
+		}
+		t := inst.InstructionType()
+		if seen[t] {
+			return newValidationError(OFPET_BAD_INSTRUCTION, OFPBIC_UNSUP_INST, -1,
+				"instruction type %d is duplicated", t)
+		}
+		seen[t] = true
+		switch i := inst.(type) {
+		case *OfpInstructionGotoTable:
+			if i.TableId <= m.TableId || i.TableId > OFPTT_MAX {
+				return newValidationError(OFPET_BAD_INSTRUCTION, OFPBIC_BAD_TABLE_ID, -1,
+					"goto_table %d from table %d", i.TableId, m.TableId)
+			}
+		case *OfpInstructionActions:
+			if t == OFPIT_CLEAR_ACTIONS {
+				if len(i.Actions) != 0 {
+					return newValidationError(OFPET_BAD_INSTRUCTION, OFPBIC_BAD_LEN, -1,
+						"clear_actions has actions")
+				}
+				continue
+			}
+			// apply_actions changes packet for the following instructions,
+			// but write_actions runs after them, so check it alone.
+			fs := fields
+			if t == OFPIT_WRITE_ACTIONS {
+				fs = fields.copy()
+			}
+			if err := validateActions(i.Actions, fs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/// Validate checks GroupMod before it is sent: command, type, group id, and
+/// buckets and their actions. Set-field actions in buckets are checked
+/// alone, since the group does not know matches of flows referring it.
+/// It returns *ValidationError.
+func (m *OfpGroupMod) Validate() error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *OfpGroupMod) validate() *ValidationError {
+	if m.Command > OFPGC_DELETE {
+		return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_COMMAND, -1,
+			"group_mod command %d is unknown", m.Command)
+	}
+	if m.Command == OFPGC_DELETE {
+		if m.GroupId > OFPG_MAX && m.GroupId != OFPG_ALL {
+			return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_INVALID_GROUP, -1,
+				"group 0x%x is reserved", m.GroupId)
+		}
+		return nil
+	}
+	if m.GroupId > OFPG_MAX {
+		return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_INVALID_GROUP, -1,
+			"group 0x%x is reserved", m.GroupId)
+	}
+	if m.Type > OFPGT_FF {
+		return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_TYPE, -1,
+			"group type %d is unknown", m.Type)
+	}
+	if m.Type == OFPGT_INDIRECT && len(m.Buckets) != 1 {
+		return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_BUCKET, -1,
+			"indirect group has %d buckets", len(m.Buckets))
+	}
+	for _, b := range m.Buckets {
+		if b == nil {
+			return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_BUCKET, -1, "bucket is nil")
+		}
+		if m.Type != OFPGT_SELECT && b.Weight != 0 {
+			return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_BUCKET, -1,
+				"bucket of non-select group has weight %d", b.Weight)
+		}
+		if m.Type == OFPGT_FF && b.WatchPort == OFPP_ANY && b.WatchGroup == OFPG_ANY {
+			return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_WATCH, -1,
+				"bucket of fast failover group watches nothing")
+		}
+		if err := validateActions(b.Actions, nil); err != nil {
+			return err
+		}
+		for _, a := range b.Actions {
+			if g, ok := a.(*OfpActionGroup); ok && g.GroupId == m.GroupId {
+				return newValidationError(OFPET_GROUP_MOD_FAILED, OFPGMFC_LOOP, -1,
+					"group 0x%x refers itself", m.GroupId)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package ofp13
+
+import (
+	"testing"
+)
+
+// check err is *ValidationError of type and code
+func expectValidationError(t *testing.T, err error, errType uint16, code uint16) {
+	t.Helper()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Log("Actual error is : ", err)
+		t.Errorf("ValidationError of type %d code %d is expected.", errType, code)
+		return
+	}
+	if verr.Type != errType || verr.Code != code {
+		t.Log("Actual error is : ", verr)
+		t.Errorf("ValidationError of type %d code %d is expected.", errType, code)
+	}
+}
+
+/*****************************************************/
+/* OfpMatch Validate                                 */
+/*****************************************************/
+func TestOfpMatchValidate(t *testing.T) {
+	ipDst, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	valid := matchOf(NewOxmInPort(1), NewOxmEthType(0x0800), ipDst,
+		NewOxmIpProto(6), NewOxmTcpDst(80))
+	if err := valid.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := NewOfpMatch().Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// tcp_dst without ip_proto
+	err := matchOf(NewOxmEthType(0x0800), NewOxmTcpDst(80)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	if verr, ok := err.(*ValidationError); ok && verr.Field != OFPXMT_OFB_TCP_DST {
+		t.Log("Actual field is : ", verr.Field)
+		t.Error("Field of error is invalid.")
+	}
+	// ip_proto without eth_type
+	err = matchOf(NewOxmIpProto(6), NewOxmTcpDst(80)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	// ipv4_dst of ipv6
+	err = matchOf(NewOxmEthType(0x86dd), ipDst).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	// prerequisites after dependent fields
+	err = matchOf(NewOxmTcpDst(80), NewOxmEthType(0x0800), NewOxmIpProto(6)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	if verr, ok := err.(*ValidationError); ok && verr.Field != OFPXMT_OFB_TCP_DST {
+		t.Log("Actual field is : ", verr.Field)
+		t.Error("Field of error is invalid.")
+	}
+	ndTarget, _ := NewOxmIpv6NdTarget("fe80::1")
+	err = matchOf(NewOxmEthType(0x86dd), NewOxmIpProto(58), ndTarget, NewOxmIcmpv6Type(135)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	err = matchOf(NewOxmVlanPcp(3), NewOxmVlanVid(OFPVID_PRESENT|10)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	// vlan_pcp requires vlan_vid other than OFPVID_NONE
+	err = matchOf(NewOxmVlanVid(OFPVID_NONE), NewOxmVlanPcp(3)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+	err = matchOf(NewOxmVlanVidW(OFPVID_PRESENT, OFPVID_PRESENT), NewOxmVlanPcp(3)).Validate()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// vlan_vid without OFPVID_PRESENT
+	err = matchOf(NewOxmVlanVid(10)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_VALUE)
+	if err := matchOf(NewOxmVlanVid(OFPVID_PRESENT | 10)).Validate(); err != nil {
+		t.Error(err)
+	}
+	// out of range
+	err = matchOf(NewOxmVlanVid(OFPVID_PRESENT|10), NewOxmVlanPcp(8)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_VALUE)
+	// duplicated
+	err = matchOf(NewOxmInPort(1), NewOxmInPort(2)).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_DUP_FIELD)
+	// value has bits outside of mask
+	vid := NewOxmVlanVidW(OFPVID_PRESENT|10, OFPVID_PRESENT)
+	err = matchOf(vid).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_WILDCARDS)
+	// mask of unmaskable field
+	target, _ := NewOxmIpv6SrcW("fe80::", 64)
+	target.TlvHeader = oxmHeaderW(OFPXMC_OPENFLOW_BASIC, OFPXMT_OFB_IPV6_ND_TARGET, 16)
+	err = matchOf(NewOxmEthType(0x86dd), NewOxmIpProto(58), NewOxmIcmpv6Type(135), target).Validate()
+	expectValidationError(t, err, OFPET_BAD_MATCH, OFPBMC_BAD_MASK)
+}
+
+/*****************************************************/
+/* OfpActionSetField Validate                        */
+/*****************************************************/
+func TestOfpActionSetFieldValidate(t *testing.T) {
+	if err := NewOfpActionSetField(NewOxmTcpDst(80)).Validate(); err != nil {
+		t.Error(err)
+	}
+	err := NewOfpActionSetField(NewOxmEthType(0x0800)).Validate()
+	expectValidationError(t, err, OFPET_BAD_ACTION, OFPBAC_BAD_SET_TYPE)
+	err = NewOfpActionSetField(NewOxmInPort(1)).Validate()
+	expectValidationError(t, err, OFPET_BAD_ACTION, OFPBAC_BAD_SET_TYPE)
+	ipDst, _ := NewOxmIpv4DstW("10.0.0.0", 8)
+	err = NewOfpActionSetField(ipDst).Validate()
+	expectValidationError(t, err, OFPET_BAD_ACTION, OFPBAC_BAD_SET_ARGUMENT)
+	err = NewOfpActionSetField(NewOxmVlanPcp(8)).Validate()
+	expectValidationError(t, err, OFPET_BAD_ACTION, OFPBAC_BAD_SET_ARGUMENT)
+}
+
+/*****************************************************/
+/* OfpFlowMod Validate                               */
+/*****************************************************/
+func flowModOf(match *OfpMatch, actions ...OfpAction) *OfpFlowMod {
+	inst := NewOfpInstructionActions(OFPIT_APPLY_ACTIONS)
+	for _, a := range actions {
+		inst.Append(a)
+	}
+	return NewOfpFlowModAdd(0, 0, 0, 10, 0, match, []OfpInstruction{inst})
+}
+
+func TestOfpFlowModValidate(t *testing.T) {
+	ipDst, _ := NewOxmIpv4Dst("10.0.0.1")
+	match := matchOf(NewOxmEthType(0x0800))
+	fm := flowModOf(match, NewOfpActionSetField(ipDst), NewOfpActionOutput(1, 0))
+	if err := fm.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// invalid match
+	fm = flowModOf(matchOf(NewOxmTcpDst(80)), NewOfpActionOutput(1, 0))
+	expectValidationError(t, fm.Validate(), OFPET_BAD_MATCH, OFPBMC_BAD_PREREQ)
+
+	// set-field inconsistent with match
+	fm = flowModOf(NewOfpMatch(), NewOfpActionSetField(ipDst))
+	expectValidationError(t, fm.Validate(), OFPET_BAD_ACTION, OFPBAC_MATCH_INCONSISTENT)
+
+	// vlan_vid can be set after push_vlan
+	vid := NewOxmVlanVid(OFPVID_PRESENT | 10)
+	fm = flowModOf(NewOfpMatch(), NewOfpActionSetField(NewOxmVlanPcp(1)))
+	expectValidationError(t, fm.Validate(), OFPET_BAD_ACTION, OFPBAC_MATCH_INCONSISTENT)
+	fm = flowModOf(NewOfpMatch(), NewOfpActionPushVlan(),
+		NewOfpActionSetField(vid), NewOfpActionSetField(NewOxmVlanPcp(1)))
+	if err := fm.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// output to OFPP_ANY
+	fm = flowModOf(NewOfpMatch(), NewOfpActionOutput(OFPP_ANY, 0))
+	expectValidationError(t, fm.Validate(), OFPET_BAD_ACTION, OFPBAC_BAD_OUT_PORT)
+
+	// goto_table must go forward
+	fm = NewOfpFlowModAdd(0, 0, 1, 10, 0, NewOfpMatch(),
+		[]OfpInstruction{NewOfpInstructionGotoTable(1)})
+	expectValidationError(t, fm.Validate(), OFPET_BAD_INSTRUCTION, OFPBIC_BAD_TABLE_ID)
+
+	// OFPTT_ALL is only for delete
+	fm = NewOfpFlowModAdd(0, 0, OFPTT_ALL, 10, 0, NewOfpMatch(), nil)
+	expectValidationError(t, fm.Validate(), OFPET_FLOW_MOD_FAILED, OFPFMFC_BAD_TABLE_ID)
+	fm = NewOfpFlowModDelete(0, 0, OFPTT_ALL, 0, OFPP_ANY, OFPG_ANY, 0, NewOfpMatch())
+	if err := fm.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+/*****************************************************/
+/* OfpGroupMod Validate                              */
+/*****************************************************/
+func TestOfpGroupModValidate(t *testing.T) {
+	newGroup := func(groupType uint8, buckets ...*OfpBucket) *OfpGroupMod {
+		gm := NewOfpGroupMod(OFPGC_ADD, groupType, 1)
+		for _, b := range buckets {
+			gm.Append(b)
+		}
+		return gm
+	}
+	newBucket := func(weight uint16, watchPort uint32, actions ...OfpAction) *OfpBucket {
+		b := NewOfpBucket(weight, watchPort, OFPG_ANY)
+		for _, a := range actions {
+			b.Append(a)
+		}
+		return b
+	}
+
+	gm := newGroup(OFPGT_SELECT, newBucket(1, OFPP_ANY, NewOfpActionOutput(1, 0)),
+		newBucket(2, OFPP_ANY, NewOfpActionOutput(2, 0)))
+	if err := gm.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	gm = newGroup(OFPGT_INDIRECT, newBucket(0, OFPP_ANY), newBucket(0, OFPP_ANY))
+	expectValidationError(t, gm.Validate(), OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_BUCKET)
+	gm = newGroup(OFPGT_ALL, newBucket(1, OFPP_ANY, NewOfpActionOutput(1, 0)))
+	expectValidationError(t, gm.Validate(), OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_BUCKET)
+	gm = newGroup(OFPGT_FF, newBucket(0, OFPP_ANY, NewOfpActionOutput(1, 0)))
+	expectValidationError(t, gm.Validate(), OFPET_GROUP_MOD_FAILED, OFPGMFC_BAD_WATCH)
+	gm = newGroup(OFPGT_ALL, newBucket(0, OFPP_ANY, NewOfpActionGroup(1)))
+	expectValidationError(t, gm.Validate(), OFPET_GROUP_MOD_FAILED, OFPGMFC_LOOP)
+	gm = newGroup(OFPGT_ALL, newBucket(0, OFPP_ANY, NewOfpActionSetField(NewOxmEthType(0x0800))))
+	expectValidationError(t, gm.Validate(), OFPET_BAD_ACTION, OFPBAC_BAD_SET_TYPE)
+
+	gm = NewOfpGroupMod(OFPGC_DELETE, OFPGT_ALL, OFPG_ALL)
+	if err := gm.Validate(); err != nil {
+		t.Error(err)
+	}
+}
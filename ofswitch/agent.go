@@ -0,0 +1,583 @@
+package ofswitch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+var DEFAULT_CONTROLLER_PORT = 6653
+
+// default values of switch features
+var DEFAULT_N_BUFFERS uint32 = 0
+var DEFAULT_N_TABLES uint8 = 254
+var DEFAULT_MAX_ENTRIES uint32 = 1024
+
+// multipart reply is split into messages of this size at most
+const MAX_MULTIPART_REPLY_SIZE = 0xffff
+
+var ErrAgentClosed = errors.New("agent is closed.")
+var ErrNotConnected = errors.New("agent is not connected.")
+
+/**
+ * Backend is the data plane of switch. It receives messages which modify
+ * the data plane, or which are sent out from it.
+ * Returned error is reported to the controller as OFPT_ERROR, with type and
+ * code of *Error, or OFPET_BAD_REQUEST/OFPBRC_EPERM for other errors.
+ */
+type Backend interface {
+	HandleFlowMod(msg *ofp13.OfpFlowMod, agent *Agent) error
+	HandleGroupMod(msg *ofp13.OfpGroupMod, agent *Agent) error
+	HandleMeterMod(msg *ofp13.OfpMeterMod, agent *Agent) error
+	HandlePacketOut(msg *ofp13.OfpPacketOut, agent *Agent) error
+}
+
+/**
+ * MultipartBackend is implemented by backends which answer multipart
+ * requests other than desc, port-desc and table-features, such as flow stats.
+ * Returned bodies are sent in multipart replies.
+ */
+type MultipartBackend interface {
+	HandleMultipartRequest(msg *ofp13.OfpMultipartRequest, agent *Agent) ([]ofp13.OfpMultipartBody, error)
+}
+
+/**
+ * MessageBackend is implemented by backends which handle the other messages
+ * not answered by the agent, such as PortMod and TableMod.
+ */
+type MessageBackend interface {
+	HandleMessage(msg ofp13.OFMessage, agent *Agent) error
+}
+
+/**
+ * Error is an error reported to the controller by OFPT_ERROR.
+ */
+type Error struct {
+	Type uint16
+	Code uint16
+}
+
+func NewError(t uint16, code uint16) *Error {
+	return &Error{t, code}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("openflow error, type %d code %d.", e.Type, e.Code)
+}
+
+/**
+ * Agent implements the switch side of OpenFlow 1.3.
+ * It performs the handshake as a switch with given datapath id and ports,
+ * answers echo, barrier, config, role, desc, port-desc and table-features
+ * requests, and delivers the other messages to its Backend.
+ * Messages are handled in received order, so a barrier reply is sent after
+ * the backend handled all preceding messages.
+ */
+type Agent struct {
+	DatapathId   uint64
+	NBuffers     uint32
+	NTables      uint8
+	Capabilities uint32
+	Desc         *ofp13.OfpDescStats
+	// reply of table-features request. if nil, features of NTables tables
+	// are created.
+	TableFeatures []*ofp13.OfpTableFeatures
+	Backend       Backend
+
+	mu          sync.Mutex
+	ports       []*ofp13.OfpPort
+	flags       uint16
+	missSendLen uint16
+	role        uint32
+	async       *ofp13.OfpAsyncConfig
+
+	writeMu   sync.Mutex
+	conn      net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+/**
+ * ctor
+ */
+func NewAgent(dpid uint64, ports []*ofp13.OfpPort, backend Backend) *Agent {
+	a := new(Agent)
+	a.DatapathId = dpid
+	a.NBuffers = DEFAULT_N_BUFFERS
+	a.NTables = DEFAULT_N_TABLES
+	a.Capabilities = ofp13.OFPC_FLOW_STATS | ofp13.OFPC_TABLE_STATS | ofp13.OFPC_PORT_STATS | ofp13.OFPC_GROUP_STATS
+	a.Desc = ofp13.NewOfpDescStats("gofc", "ofswitch", "ofswitch", "None", fmt.Sprintf("dpid:%016x", dpid))
+	a.Backend = backend
+	a.ports = append(a.ports, ports...)
+	a.missSendLen = ofp13.OFPCML_NO_BUFFER
+	a.async = ofp13.NewOfpGetAsyncReply()
+	// default of async config, slave receives port status only
+	a.async.PacketInMask = [2]uint32{1<<ofp13.OFPR_NO_MATCH | 1<<ofp13.OFPR_ACTION, 0}
+	a.async.PortStatusMask = [2]uint32{0x7, 0x7}
+	a.async.FlowRemovedMask = [2]uint32{0xf, 0}
+	a.done = make(chan struct{})
+	a.ready = make(chan struct{})
+	return a
+}
+
+/**
+ * Connect connects to controller at addr, "host:port" or "host" with
+ * DEFAULT_CONTROLLER_PORT, and serves the connection in background.
+ */
+func (a *Agent) Connect(addr string) error {
+	conn, err := dial(addr)
+	if err != nil {
+		return err
+	}
+	go a.Serve(conn)
+	return nil
+}
+
+func dial(addr string) (net.Conn, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, fmt.Sprint(DEFAULT_CONTROLLER_PORT))
+	}
+	return net.Dial("tcp", addr)
+}
+
+/**
+ * Serve runs the agent on conn until the connection is closed.
+ * It returns nil when closed by Close, or the error of reading conn.
+ */
+func (a *Agent) Serve(conn net.Conn) error {
+	a.writeMu.Lock()
+	if a.conn != nil {
+		a.writeMu.Unlock()
+		return errors.New("agent is already serving a connection.")
+	}
+	a.conn = conn
+	a.writeMu.Unlock()
+
+	select {
+	case <-a.done:
+		conn.Close()
+		return ErrAgentClosed
+	default:
+	}
+
+	if err := a.Send(ofp13.NewOfpHello()); err != nil {
+		a.Close()
+		return err
+	}
+
+	header := make([]byte, 8)
+	for {
+		packet, err := readMessage(conn, header)
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+			}
+			a.Close()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		a.handlePacket(packet)
+	}
+}
+
+// read an OpenFlow message from r. header is buffer of 8 bytes.
+func readMessage(r io.Reader, header []byte) ([]byte, error) {
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[2:]))
+	if length < len(header) {
+		return nil, fmt.Errorf("invalid message length %d.", length)
+	}
+	packet := make([]byte, length)
+	copy(packet, header)
+	if _, err := io.ReadFull(r, packet[len(header):]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+/**
+ * Close closes connection to the controller.
+ */
+func (a *Agent) Close() {
+	a.closeOnce.Do(func() {
+		close(a.done)
+		a.writeMu.Lock()
+		defer a.writeMu.Unlock()
+		if a.conn != nil {
+			a.conn.Close()
+		}
+	})
+}
+
+// Done returns channel which is closed when the agent is closed.
+func (a *Agent) Done() <-chan struct{} {
+	return a.done
+}
+
+// Ready returns channel which is closed when the agent answered the first
+// features request, that is, the handshake is completed.
+func (a *Agent) Ready() <-chan struct{} {
+	return a.ready
+}
+
+/**
+ * Send writes message to the controller.
+ */
+func (a *Agent) Send(msg ofp13.OFMessage) error {
+	select {
+	case <-a.done:
+		return ErrAgentClosed
+	default:
+	}
+
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	if a.conn == nil {
+		return ErrNotConnected
+	}
+	_, err := a.conn.Write(msg.Serialize())
+	return err
+}
+
+/**
+ * SendAsync writes asynchronous message, PacketIn, FlowRemoved or
+ * PortStatus, to the controller if its reason is enabled by async config
+ * of current role. Unlike Send, it returns nil when not connected.
+ */
+func (a *Agent) SendAsync(msg ofp13.OFMessage) error {
+	var masks [2]uint32
+	var reason uint8
+	a.mu.Lock()
+	switch m := msg.(type) {
+	case *ofp13.OfpPacketIn:
+		masks, reason = a.async.PacketInMask, m.Reason
+	case *ofp13.OfpFlowRemoved:
+		masks, reason = a.async.FlowRemovedMask, m.Reason
+	case *ofp13.OfpPortStatus:
+		masks, reason = a.async.PortStatusMask, m.Reason
+	default:
+		masks = [2]uint32{0xffffffff, 0xffffffff}
+	}
+	mask := masks[0]
+	if a.role == ofp13.OFPCR_ROLE_SLAVE {
+		mask = masks[1]
+	}
+	a.mu.Unlock()
+
+	if mask&(1<<reason) == 0 {
+		return nil
+	}
+	err := a.Send(msg)
+	if err == ErrNotConnected {
+		return nil
+	}
+	return err
+}
+
+// Config returns flags and miss_send_len set by the controller.
+func (a *Agent) Config() (uint16, uint16) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.flags, a.missSendLen
+}
+
+/*****************************************************/
+/* Ports                                             */
+/*****************************************************/
+
+// Ports returns copy of port list.
+func (a *Agent) Ports() []*ofp13.OfpPort {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ports := make([]*ofp13.OfpPort, len(a.ports))
+	copy(ports, a.ports)
+	return ports
+}
+
+// Port returns port of given number, or nil.
+func (a *Agent) Port(portNo uint32) *ofp13.OfpPort {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.ports {
+		if p.PortNo == portNo {
+			return p
+		}
+	}
+	return nil
+}
+
+/**
+ * AddPort adds port, or replaces port of the same number, and notifies the
+ * controller by PortStatus if connected.
+ */
+func (a *Agent) AddPort(port *ofp13.OfpPort) error {
+	a.mu.Lock()
+	reason := uint8(ofp13.OFPPR_ADD)
+	replaced := false
+	for i, p := range a.ports {
+		if p.PortNo == port.PortNo {
+			a.ports[i] = port
+			reason = ofp13.OFPPR_MODIFY
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		a.ports = append(a.ports, port)
+	}
+	a.mu.Unlock()
+
+	return a.sendPortStatus(reason, port)
+}
+
+/**
+ * ModifyPort replaces port of the same number and notifies the controller.
+ */
+func (a *Agent) ModifyPort(port *ofp13.OfpPort) error {
+	if a.Port(port.PortNo) == nil {
+		return fmt.Errorf("port %d does not exist.", port.PortNo)
+	}
+	return a.AddPort(port)
+}
+
+/**
+ * DeletePort removes port of given number and notifies the controller.
+ */
+func (a *Agent) DeletePort(portNo uint32) error {
+	a.mu.Lock()
+	var deleted *ofp13.OfpPort
+	for i, p := range a.ports {
+		if p.PortNo == portNo {
+			deleted = p
+			a.ports = append(a.ports[:i:i], a.ports[i+1:]...)
+			break
+		}
+	}
+	a.mu.Unlock()
+
+	if deleted == nil {
+		return fmt.Errorf("port %d does not exist.", portNo)
+	}
+	return a.sendPortStatus(ofp13.OFPPR_DELETE, deleted)
+}
+
+func (a *Agent) sendPortStatus(reason uint8, port *ofp13.OfpPort) error {
+	status := ofp13.NewOfpPortStatus()
+	status.Reason = reason
+	status.Desc = port
+	return a.SendAsync(status)
+}
+
+/*****************************************************/
+/* Message Handling                                  */
+/*****************************************************/
+func (a *Agent) handlePacket(packet []byte) {
+	msg := ofp13.ParseDirection(packet, ofp13.FROM_CONTROLLER)
+	if msg == nil {
+		fmt.Println("UnSupport Message")
+		a.sendError(packet, NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_TYPE))
+		return
+	}
+	if err := a.handleMessage(msg); err != nil {
+		a.sendError(packet, err)
+	}
+}
+
+func (a *Agent) handleMessage(msg ofp13.OFMessage) error {
+	switch m := msg.(type) {
+	case *ofp13.OfpHeader:
+		switch m.Type {
+		case ofp13.OFPT_ECHO_REQUEST:
+			reply := ofp13.NewOfpEchoReply()
+			reply.Xid = m.Xid
+			return a.Send(reply)
+		case ofp13.OFPT_FEATURES_REQUEST:
+			err := a.Send(a.featuresReply(m.Xid))
+			a.readyOnce.Do(func() { close(a.ready) })
+			return err
+		case ofp13.OFPT_BARRIER_REQUEST:
+			reply := ofp13.NewOfpBarrierReply()
+			reply.Xid = m.Xid
+			return a.Send(reply)
+		case ofp13.OFPT_GET_CONFIG_REQUEST:
+			a.mu.Lock()
+			reply := ofp13.NewOfpGetConfigReply(a.flags, a.missSendLen)
+			a.mu.Unlock()
+			reply.Header.Xid = m.Xid
+			return a.Send(reply)
+		case ofp13.OFPT_GET_ASYNC_REQUEST:
+			reply := ofp13.NewOfpGetAsyncReply()
+			a.mu.Lock()
+			reply.PacketInMask = a.async.PacketInMask
+			reply.PortStatusMask = a.async.PortStatusMask
+			reply.FlowRemovedMask = a.async.FlowRemovedMask
+			a.mu.Unlock()
+			reply.Header.Xid = m.Xid
+			return a.Send(reply)
+		case ofp13.OFPT_ECHO_REPLY:
+			return nil
+		}
+	case *ofp13.OfpHello:
+		return nil
+	case *ofp13.OfpErrorMsg:
+		fmt.Println("recv Error: type", m.Type, "code", m.Code)
+		return nil
+	case *ofp13.OfpSwitchConfig:
+		a.mu.Lock()
+		a.flags = m.Flags
+		a.missSendLen = m.MissSendLen
+		a.mu.Unlock()
+		return nil
+	case *ofp13.OfpAsyncConfig:
+		a.mu.Lock()
+		a.async.PacketInMask = m.PacketInMask
+		a.async.PortStatusMask = m.PortStatusMask
+		a.async.FlowRemovedMask = m.FlowRemovedMask
+		a.mu.Unlock()
+		return nil
+	case *ofp13.OfpRole:
+		reply := ofp13.NewOfpRoleReply()
+		reply.Header.Xid = m.Header.Xid
+		a.mu.Lock()
+		if m.Role != ofp13.OFPCR_ROLE_NOCHANGE {
+			a.role = m.Role
+		}
+		reply.Role = a.role
+		a.mu.Unlock()
+		reply.GenerationId = m.GenerationId
+		return a.Send(reply)
+	case *ofp13.OfpMultipartRequest:
+		return a.handleMultipartRequest(m)
+	case *ofp13.OfpFlowMod:
+		if a.Backend != nil {
+			return a.Backend.HandleFlowMod(m, a)
+		}
+	case *ofp13.OfpGroupMod:
+		if a.Backend != nil {
+			return a.Backend.HandleGroupMod(m, a)
+		}
+	case *ofp13.OfpMeterMod:
+		if a.Backend != nil {
+			return a.Backend.HandleMeterMod(m, a)
+		}
+	case *ofp13.OfpPacketOut:
+		if a.Backend != nil {
+			return a.Backend.HandlePacketOut(m, a)
+		}
+	}
+
+	if obj, ok := a.Backend.(MessageBackend); ok {
+		return obj.HandleMessage(msg, a)
+	}
+	return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_TYPE)
+}
+
+func (a *Agent) featuresReply(xid uint32) *ofp13.OfpSwitchFeatures {
+	reply := ofp13.NewOfpFeaturesReply()
+	reply.Header.Xid = xid
+	reply.DatapathId = a.DatapathId
+	reply.NBuffers = a.NBuffers
+	reply.NTables = a.NTables
+	reply.Capabilities = a.Capabilities
+	return reply
+}
+
+func (a *Agent) handleMultipartRequest(req *ofp13.OfpMultipartRequest) error {
+	var bodies []ofp13.OfpMultipartBody
+	switch req.Type {
+	case ofp13.OFPMP_DESC:
+		bodies = []ofp13.OfpMultipartBody{a.Desc}
+	case ofp13.OFPMP_PORT_DESC:
+		for _, p := range a.Ports() {
+			bodies = append(bodies, p)
+		}
+	case ofp13.OFPMP_TABLE_FEATURES:
+		for _, f := range a.tableFeatures() {
+			bodies = append(bodies, f)
+		}
+	default:
+		obj, ok := a.Backend.(MultipartBackend)
+		if !ok {
+			return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_MULTIPART)
+		}
+		var err error
+		if bodies, err = obj.HandleMultipartRequest(req, a); err != nil {
+			return err
+		}
+	}
+	return a.sendMultipartReply(req, bodies)
+}
+
+func (a *Agent) tableFeatures() []*ofp13.OfpTableFeatures {
+	if a.TableFeatures != nil {
+		return a.TableFeatures
+	}
+	features := make([]*ofp13.OfpTableFeatures, 0, a.NTables)
+	for i := 0; i < int(a.NTables); i++ {
+		name := make([]byte, ofp13.OFP_MAX_TABLE_NAME_LEN)
+		copy(name, fmt.Sprintf("table%d", i))
+		features = append(features, ofp13.NewOfpTableFeatures(
+			uint8(i), name, 0xffffffffffffffff, 0xffffffffffffffff, 0, DEFAULT_MAX_ENTRIES, nil))
+	}
+	return features
+}
+
+/**
+ * send bodies in multipart replies to req. bodies are split into replies
+ * with OFPMPF_REPLY_MORE flag if they exceed MAX_MULTIPART_REPLY_SIZE.
+ */
+func (a *Agent) sendMultipartReply(req *ofp13.OfpMultipartRequest, bodies []ofp13.OfpMultipartBody) error {
+	reply := newMultipartReply(req)
+	for _, body := range bodies {
+		if len(reply.Body) > 0 && reply.Size()+body.Size() > MAX_MULTIPART_REPLY_SIZE {
+			reply.Flags |= ofp13.OFPMPF_REPLY_MORE
+			if err := a.Send(reply); err != nil {
+				return err
+			}
+			reply = newMultipartReply(req)
+		}
+		reply.Append(body)
+	}
+	return a.Send(reply)
+}
+
+func newMultipartReply(req *ofp13.OfpMultipartRequest) *ofp13.OfpMultipartReply {
+	reply := ofp13.NewOfpMultipartReply()
+	reply.Header.Xid = req.Header.Xid
+	reply.Type = req.Type
+	return reply
+}
+
+// report err to the controller with head of the failed request
+func (a *Agent) sendError(packet []byte, err error) {
+	if err == ErrAgentClosed || err == ErrNotConnected {
+		return
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		fmt.Println(err)
+		e = NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_EPERM)
+	}
+	msg := ofp13.NewOfpErrorMsg()
+	msg.Header.Xid = binary.BigEndian.Uint32(packet[4:])
+	msg.Type = e.Type
+	msg.Code = e.Code
+	if len(packet) > 64 {
+		packet = packet[:64]
+	}
+	msg.Data = append([]byte(nil), packet...)
+	a.Send(msg)
+}
@@ -0,0 +1,256 @@
+package ofswitch
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+type recordBackend struct {
+	msgs     []ofp13.OFMessage
+	err      error
+	portStat int
+}
+
+func (b *recordBackend) HandleFlowMod(msg *ofp13.OfpFlowMod, agent *Agent) error {
+	b.msgs = append(b.msgs, msg)
+	return b.err
+}
+
+func (b *recordBackend) HandleGroupMod(msg *ofp13.OfpGroupMod, agent *Agent) error {
+	b.msgs = append(b.msgs, msg)
+	return b.err
+}
+
+func (b *recordBackend) HandleMeterMod(msg *ofp13.OfpMeterMod, agent *Agent) error {
+	b.msgs = append(b.msgs, msg)
+	return b.err
+}
+
+func (b *recordBackend) HandlePacketOut(msg *ofp13.OfpPacketOut, agent *Agent) error {
+	b.msgs = append(b.msgs, msg)
+	return b.err
+}
+
+func (b *recordBackend) HandleMultipartRequest(msg *ofp13.OfpMultipartRequest, agent *Agent) ([]ofp13.OfpMultipartBody, error) {
+	if msg.Type != ofp13.OFPMP_PORT_STATS {
+		return nil, NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_MULTIPART)
+	}
+	bodies := make([]ofp13.OfpMultipartBody, 0, b.portStat)
+	for i := 0; i < b.portStat; i++ {
+		bodies = append(bodies, &ofp13.OfpPortStats{PortNo: uint32(i)})
+	}
+	return bodies, nil
+}
+
+// controller side of connection to agent
+type testController struct {
+	t      *testing.T
+	conn   net.Conn
+	header []byte
+}
+
+func (c *testController) send(msg ofp13.OFMessage) {
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Write(msg.Serialize()); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testController) recv() ofp13.OFMessage {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet, err := readMessage(c.conn, c.header)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := ofp13.ParseDirection(packet, ofp13.FROM_SWITCH)
+	if msg == nil {
+		c.t.Fatalf("Unknown message type %d is received.", packet[1])
+	}
+	return msg
+}
+
+// start agent with two ports on net.Pipe, and receive its hello
+func newTestAgent(t *testing.T, backend Backend) (*Agent, *testController) {
+	p1, _ := ofp13.NewOfpPort(1, "00:00:00:00:00:01", "eth1")
+	p2, _ := ofp13.NewOfpPort(2, "00:00:00:00:00:02", "eth2")
+	agent := NewAgent(0x0102, []*ofp13.OfpPort{p1, p2}, backend)
+	return agent, newTestController(t, agent.Serve)
+}
+
+// run serve on net.Pipe, and receive hello from switch side
+func newTestController(t *testing.T, serve func(conn net.Conn) error) *testController {
+	switchSide, controllerSide := net.Pipe()
+	go serve(switchSide)
+	c := &testController{t, controllerSide, make([]byte, 8)}
+	if _, ok := c.recv().(*ofp13.OfpHello); !ok {
+		t.Fatal("Hello is not sent by agent.")
+	}
+	return c
+}
+
+/*****************************************************/
+/* Handshake                                         */
+/*****************************************************/
+func TestAgentHandshake(t *testing.T) {
+	agent, c := newTestAgent(t, &recordBackend{})
+	defer agent.Close()
+
+	c.send(ofp13.NewOfpHello())
+	select {
+	case <-agent.Ready():
+		t.Fatal("Agent is ready before features request.")
+	default:
+	}
+	req := ofp13.NewOfpFeaturesRequest()
+	c.send(req)
+	reply, ok := c.recv().(*ofp13.OfpSwitchFeatures)
+	if !ok || reply.DatapathId != 0x0102 || reply.Header.Xid != req.Xid || reply.NTables != DEFAULT_N_TABLES {
+		t.Log("Actual message is : ", reply)
+		t.Fatal("FeaturesReply is invalid.")
+	}
+	select {
+	case <-agent.Ready():
+	case <-time.After(5 * time.Second):
+		t.Error("Agent is not ready after features reply.")
+	}
+
+	echo := ofp13.NewOfpEchoRequest()
+	c.send(echo)
+	if h, ok := c.recv().(*ofp13.OfpHeader); !ok || h.Type != ofp13.OFPT_ECHO_REPLY || h.Xid != echo.Xid {
+		t.Error("EchoReply is invalid.")
+	}
+
+	c.send(ofp13.NewOfpSetConfig(0, 128))
+	c.send(ofp13.NewOfpGetConfig())
+	if config, ok := c.recv().(*ofp13.OfpSwitchConfig); !ok || config.MissSendLen != 128 {
+		t.Error("SetConfig is not applied.")
+	}
+}
+
+/*****************************************************/
+/* Backend                                           */
+/*****************************************************/
+func TestAgentBackend(t *testing.T) {
+	backend := &recordBackend{}
+	agent, c := newTestAgent(t, backend)
+	defer agent.Close()
+
+	flowMod := ofp13.NewOfpFlowModAdd(0, 0, 0, 100, 0, ofp13.NewOfpMatch(), nil)
+	c.send(flowMod)
+	c.send(ofp13.NewOfpGroupMod(ofp13.OFPGC_ADD, ofp13.OFPGT_ALL, 1))
+	c.send(ofp13.NewOfpMeterMod(ofp13.OFPMC_ADD, ofp13.OFPMF_KBPS, 1))
+	c.send(ofp13.NewOfpPacketOut(ofp13.OFP_NO_BUFFER, ofp13.OFPP_CONTROLLER, nil, make([]byte, 64)))
+	barrier := ofp13.NewOfpBarrierRequest()
+	c.send(barrier)
+
+	// preceding messages are handled before barrier reply
+	if h, ok := c.recv().(*ofp13.OfpHeader); !ok || h.Type != ofp13.OFPT_BARRIER_REPLY || h.Xid != barrier.Xid {
+		t.Fatal("BarrierReply is invalid.")
+	}
+	if len(backend.msgs) != 4 {
+		t.Fatalf("%d messages are delivered to backend, expected 4.", len(backend.msgs))
+	}
+	if fm, ok := backend.msgs[0].(*ofp13.OfpFlowMod); !ok || fm.Priority != 100 {
+		t.Error("FlowMod is not delivered to backend.")
+	}
+	if _, ok := backend.msgs[3].(*ofp13.OfpPacketOut); !ok {
+		t.Error("PacketOut is not delivered to backend.")
+	}
+
+	// error of backend is reported
+	backend.err = NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_TABLE_FULL)
+	c.send(flowMod)
+	errMsg, ok := c.recv().(*ofp13.OfpErrorMsg)
+	if !ok || errMsg.Type != ofp13.OFPET_FLOW_MOD_FAILED || errMsg.Code != ofp13.OFPFMFC_TABLE_FULL ||
+		errMsg.Header.Xid != flowMod.Header.Xid || len(errMsg.Data) != flowMod.Size() {
+		t.Log("Actual message is : ", errMsg)
+		t.Error("Error of backend is not reported.")
+	}
+}
+
+/*****************************************************/
+/* Multipart                                         */
+/*****************************************************/
+func TestAgentMultipart(t *testing.T) {
+	backend := &recordBackend{portStat: 1000}
+	agent, c := newTestAgent(t, backend)
+	defer agent.Close()
+
+	c.send(ofp13.NewOfpDescStatsRequest(0))
+	reply := c.recv().(*ofp13.OfpMultipartReply)
+	if desc, ok := reply.Body[0].(*ofp13.OfpDescStats); !ok || string(desc.MfrDesc[:4]) != "gofc" {
+		t.Error("DescStats is invalid.")
+	}
+
+	c.send(ofp13.NewOfpPortDescStatsRequest(0))
+	reply = c.recv().(*ofp13.OfpMultipartReply)
+	if len(reply.Body) != 2 || reply.Body[1].(*ofp13.OfpPort).PortNo != 2 {
+		t.Log("Actual body is : ", reply.Body)
+		t.Error("PortDesc is invalid.")
+	}
+
+	c.send(ofp13.NewOfpTableFeaturesStatsRequest(0, nil))
+	reply = c.recv().(*ofp13.OfpMultipartReply)
+	if len(reply.Body) != int(DEFAULT_N_TABLES) || reply.Flags != 0 {
+		t.Errorf("%d table features are replied, expected %d.", len(reply.Body), DEFAULT_N_TABLES)
+	}
+
+	// large reply is split
+	c.send(ofp13.NewOfpPortStatsRequest(ofp13.OFPP_ANY, 0))
+	count := 0
+	for {
+		reply = c.recv().(*ofp13.OfpMultipartReply)
+		count += len(reply.Body)
+		if reply.Flags&ofp13.OFPMPF_REPLY_MORE == 0 {
+			break
+		}
+	}
+	if count != backend.portStat {
+		t.Errorf("%d port stats are replied, expected %d.", count, backend.portStat)
+	}
+
+	c.send(ofp13.NewOfpQueueStatsRequest(ofp13.OFPP_ANY, ofp13.OFPQ_ALL, 0))
+	if errMsg, ok := c.recv().(*ofp13.OfpErrorMsg); !ok || errMsg.Code != ofp13.OFPBRC_BAD_MULTIPART {
+		t.Error("Unsupported multipart request is not reported.")
+	}
+}
+
+/*****************************************************/
+/* Ports                                             */
+/*****************************************************/
+func TestAgentPortStatus(t *testing.T) {
+	agent, c := newTestAgent(t, &recordBackend{})
+	defer agent.Close()
+
+	p3, _ := ofp13.NewOfpPort(3, "00:00:00:00:00:03", "eth3")
+	go agent.AddPort(p3)
+	if status, ok := c.recv().(*ofp13.OfpPortStatus); !ok || status.Reason != ofp13.OFPPR_ADD || status.Desc.PortNo != 3 {
+		t.Error("PortStatus of added port is invalid.")
+	}
+
+	go agent.DeletePort(1)
+	if status, ok := c.recv().(*ofp13.OfpPortStatus); !ok || status.Reason != ofp13.OFPPR_DELETE || status.Desc.PortNo != 1 {
+		t.Error("PortStatus of deleted port is invalid.")
+	}
+	if ports := agent.Ports(); len(ports) != 2 || ports[0].PortNo != 2 || ports[1].PortNo != 3 {
+		t.Error("Ports are not updated.")
+	}
+	if err := agent.DeletePort(1); err == nil {
+		t.Error("Deleting unknown port doesn't fail.")
+	}
+}
+
+func TestAgentClose(t *testing.T) {
+	agent, c := newTestAgent(t, &recordBackend{})
+	agent.Close()
+	if err := agent.Send(ofp13.NewOfpEchoRequest()); err != ErrAgentClosed {
+		t.Error("Send doesn't fail after agent is closed.")
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.conn.Read(make([]byte, 8)); err == nil {
+		t.Error("Connection is not closed.")
+	}
+}
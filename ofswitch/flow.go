@@ -0,0 +1,445 @@
+package ofswitch
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/*****************************************************/
+/* Match                                             */
+/*****************************************************/
+
+/**
+ * matcher is an OXM field of flow entry, with value and mask in OXM
+ * encoding. value is masked, and mask is all ones for field without mask.
+ */
+type matcher struct {
+	field uint32
+	value []byte
+	mask  []byte
+}
+
+func (m *matcher) matchValue(v []byte) bool {
+	if len(v) != len(m.value) {
+		return false
+	}
+	for i := range v {
+		if v[i]&m.mask[i] != m.value[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *matcher) exact() bool {
+	for _, b := range m.mask {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// prerequisite of OXM field, other field and its allowed values
+type prerequisite struct {
+	field  uint32
+	values [][]byte
+}
+
+// prerequisites of match fields. nil values means that the field must be
+// present with any value.
+var prerequisites = map[uint32]prerequisite{
+	ofp13.OFPXMT_OFB_IN_PHY_PORT:    {ofp13.OFPXMT_OFB_IN_PORT, nil},
+	ofp13.OFPXMT_OFB_VLAN_PCP:       {ofp13.OFPXMT_OFB_VLAN_VID, nil},
+	ofp13.OFPXMT_OFB_IP_DSCP:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv4), be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IP_ECN:         {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv4), be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IP_PROTO:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv4), be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IPV4_SRC:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv4)}},
+	ofp13.OFPXMT_OFB_IPV4_DST:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv4)}},
+	ofp13.OFPXMT_OFB_TCP_SRC:        {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoTCP}}},
+	ofp13.OFPXMT_OFB_TCP_DST:        {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoTCP}}},
+	ofp13.OFPXMT_OFB_UDP_SRC:        {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoUDP}}},
+	ofp13.OFPXMT_OFB_UDP_DST:        {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoUDP}}},
+	ofp13.OFPXMT_OFB_SCTP_SRC:       {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoSCTP}}},
+	ofp13.OFPXMT_OFB_SCTP_DST:       {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoSCTP}}},
+	ofp13.OFPXMT_OFB_ICMPV4_TYPE:    {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoICMP}}},
+	ofp13.OFPXMT_OFB_ICMPV4_CODE:    {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoICMP}}},
+	ofp13.OFPXMT_OFB_ARP_OP:         {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeARP)}},
+	ofp13.OFPXMT_OFB_ARP_SPA:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeARP)}},
+	ofp13.OFPXMT_OFB_ARP_TPA:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeARP)}},
+	ofp13.OFPXMT_OFB_ARP_SHA:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeARP)}},
+	ofp13.OFPXMT_OFB_ARP_THA:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeARP)}},
+	ofp13.OFPXMT_OFB_IPV6_SRC:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IPV6_DST:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IPV6_FLABEL:    {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_IPV6_EXTHDR:    {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeIPv6)}},
+	ofp13.OFPXMT_OFB_ICMPV6_TYPE:    {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoICMPv6}}},
+	ofp13.OFPXMT_OFB_ICMPV6_CODE:    {ofp13.OFPXMT_OFB_IP_PROTO, [][]byte{{ipProtoICMPv6}}},
+	ofp13.OFPXMT_OFB_IPV6_ND_TARGET: {ofp13.OFPXMT_OFB_ICMPV6_TYPE, [][]byte{{ndNeighborSol}, {ndNeighborAdv}}},
+	ofp13.OFPXMT_OFB_IPV6_ND_SLL:    {ofp13.OFPXMT_OFB_ICMPV6_TYPE, [][]byte{{ndNeighborSol}}},
+	ofp13.OFPXMT_OFB_IPV6_ND_TLL:    {ofp13.OFPXMT_OFB_ICMPV6_TYPE, [][]byte{{ndNeighborAdv}}},
+	ofp13.OFPXMT_OFB_MPLS_LABEL:     {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeMPLS), be16(ethTypeMPLSMC)}},
+	ofp13.OFPXMT_OFB_MPLS_TC:        {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeMPLS), be16(ethTypeMPLSMC)}},
+	ofp13.OFPXMT_OFB_MPLS_BOS:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(ethTypeMPLS), be16(ethTypeMPLSMC)}},
+	ofp13.OFPXMT_OFB_PBB_ISID:       {ofp13.OFPXMT_OFB_ETH_TYPE, [][]byte{be16(0x88e7)}},
+}
+
+func findMatcher(matchers []matcher, field uint32) *matcher {
+	for i := range matchers {
+		if matchers[i].field == field {
+			return &matchers[i]
+		}
+	}
+	return nil
+}
+
+// satisfied reports whether prerequisites of field are in matchers
+func satisfied(matchers []matcher, field uint32) bool {
+	pre, ok := prerequisites[field]
+	if !ok {
+		return true
+	}
+	m := findMatcher(matchers, pre.field)
+	if m == nil {
+		return false
+	}
+	if pre.values != nil {
+		found := false
+		for _, v := range pre.values {
+			if m.exact() && bytes.Equal(m.value, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return satisfied(matchers, pre.field)
+}
+
+/**
+ * compileMatch converts match into matchers sorted by field, and validates
+ * fields, masks and prerequisites.
+ */
+func compileMatch(match *ofp13.OfpMatch) ([]matcher, error) {
+	if match == nil {
+		return nil, nil
+	}
+	matchers := make([]matcher, 0, len(match.OxmFields))
+	for _, oxm := range match.OxmFields {
+		if oxm.OxmClass() != ofp13.OFPXMC_OPENFLOW_BASIC || oxm.OxmField() >= nOxmFields {
+			return nil, NewError(ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_BAD_FIELD)
+		}
+		if findMatcher(matchers, oxm.OxmField()) != nil {
+			return nil, NewError(ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_DUP_FIELD)
+		}
+		n := int(oxm.Length())
+		hasMask := oxm.OxmHasMask() == 1
+		if hasMask {
+			n /= 2
+		}
+		b := oxm.Serialize()
+		if (!hasMask && len(b) < 4+n) || (hasMask && len(b) < 4+2*n) {
+			return nil, NewError(ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_BAD_LEN)
+		}
+
+		m := matcher{oxm.OxmField(), append([]byte(nil), b[4:4+n]...), bytes.Repeat([]byte{0xff}, n)}
+		if hasMask {
+			copy(m.mask, b[4+n:4+2*n])
+			for i := range m.value {
+				if m.value[i]&^m.mask[i] != 0 {
+					return nil, NewError(ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_BAD_WILDCARDS)
+				}
+			}
+		}
+		matchers = append(matchers, m)
+	}
+	for _, m := range matchers {
+		if !satisfied(matchers, m.field) {
+			return nil, NewError(ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_BAD_PREREQ)
+		}
+	}
+	sort.Slice(matchers, func(i, j int) bool { return matchers[i].field < matchers[j].field })
+	return matchers, nil
+}
+
+/*****************************************************/
+/* Flow Entry                                        */
+/*****************************************************/
+
+type flowEntry struct {
+	tableId     uint8
+	priority    uint16
+	cookie      uint64
+	flags       uint16
+	idleTimeout uint16
+	hardTimeout uint16
+	match       *ofp13.OfpMatch
+	matchers    []matcher
+
+	instructions []ofp13.OfpInstruction
+	// instructions in execution order
+	program []ofp13.OfpInstruction
+
+	packetCount uint64
+	byteCount   uint64
+	created     time.Time
+	lastUsed    time.Time
+}
+
+// execution order of instructions
+var instructionOrder = map[uint16]int{
+	ofp13.OFPIT_METER:          0,
+	ofp13.OFPIT_APPLY_ACTIONS:  1,
+	ofp13.OFPIT_CLEAR_ACTIONS:  2,
+	ofp13.OFPIT_WRITE_ACTIONS:  3,
+	ofp13.OFPIT_WRITE_METADATA: 4,
+	ofp13.OFPIT_GOTO_TABLE:     5,
+}
+
+func (e *flowEntry) setInstructions(instructions []ofp13.OfpInstruction) {
+	e.instructions = instructions
+	e.program = append([]ofp13.OfpInstruction(nil), instructions...)
+	sort.SliceStable(e.program, func(i, j int) bool {
+		return instructionOrder[e.program[i].InstructionType()] < instructionOrder[e.program[j].InstructionType()]
+	})
+}
+
+func (e *flowEntry) matches(p *packet) bool {
+	for i := range e.matchers {
+		if !e.matchers[i].matchValue(p.fields[e.matchers[i].field]) {
+			return false
+		}
+	}
+	return true
+}
+
+// table-miss flow entry wildcards all fields and has priority 0
+func (e *flowEntry) isTableMiss() bool {
+	return e.priority == 0 && len(e.matchers) == 0
+}
+
+// sameMatch reports whether the entry has identical match fields
+func (e *flowEntry) sameMatch(matchers []matcher) bool {
+	if len(e.matchers) != len(matchers) {
+		return false
+	}
+	for i := range matchers {
+		if e.matchers[i].field != matchers[i].field ||
+			!bytes.Equal(e.matchers[i].value, matchers[i].value) ||
+			!bytes.Equal(e.matchers[i].mask, matchers[i].mask) {
+			return false
+		}
+	}
+	return true
+}
+
+// coveredBy reports whether every packet matching the entry matches matchers
+func (e *flowEntry) coveredBy(matchers []matcher) bool {
+	for _, r := range matchers {
+		m := findMatcher(e.matchers, r.field)
+		if m == nil {
+			return false
+		}
+		for i := range r.mask {
+			if m.mask[i]&r.mask[i] != r.mask[i] || m.value[i]&r.mask[i] != r.value[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// overlaps reports whether a packet can match both of the entry and matchers
+func (e *flowEntry) overlaps(matchers []matcher) bool {
+	for _, r := range matchers {
+		m := findMatcher(e.matchers, r.field)
+		if m == nil {
+			continue
+		}
+		for i := range r.mask {
+			if (m.value[i]^r.value[i])&m.mask[i]&r.mask[i] != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// actions of apply-actions and write-actions instructions
+func (e *flowEntry) actions() []ofp13.OfpAction {
+	var actions []ofp13.OfpAction
+	for _, inst := range e.instructions {
+		if i, ok := inst.(*ofp13.OfpInstructionActions); ok {
+			actions = append(actions, i.Actions...)
+		}
+	}
+	return actions
+}
+
+func (e *flowEntry) hasOutput(port uint32) bool {
+	for _, a := range e.actions() {
+		if o, ok := a.(*ofp13.OfpActionOutput); ok && o.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *flowEntry) hasGroup(groupId uint32) bool {
+	for _, a := range e.actions() {
+		if g, ok := a.(*ofp13.OfpActionGroup); ok && g.GroupId == groupId {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *flowEntry) hasMeter(meterId uint32) bool {
+	for _, inst := range e.instructions {
+		if i, ok := inst.(*ofp13.OfpInstructionMeter); ok && i.MeterId == meterId {
+			return true
+		}
+	}
+	return false
+}
+
+func duration(d time.Duration) (uint32, uint32) {
+	return uint32(d / time.Second), uint32(d % time.Second)
+}
+
+func (e *flowEntry) stats(now time.Time) *ofp13.OfpFlowStats {
+	stats := new(ofp13.OfpFlowStats)
+	stats.TableId = e.tableId
+	stats.DurationSec, stats.DurationNSec = duration(now.Sub(e.created))
+	stats.Priority = e.priority
+	stats.IdleTimeout = e.idleTimeout
+	stats.HardTimeout = e.hardTimeout
+	stats.Flags = e.flags
+	stats.Cookie = e.cookie
+	stats.PacketCount = e.packetCount
+	stats.ByteCount = e.byteCount
+	stats.Match = e.match
+	stats.Instructions = e.instructions
+	stats.Length = uint16(stats.Size())
+	return stats
+}
+
+// flowRemoved returns FlowRemoved message, or nil if not requested by flags
+func (e *flowEntry) flowRemoved(reason uint8, now time.Time) *ofp13.OfpFlowRemoved {
+	if e.flags&ofp13.OFPFF_SEND_FLOW_REM == 0 {
+		return nil
+	}
+	msg := ofp13.NewOfpFlowRemoved()
+	msg.Cookie = e.cookie
+	msg.Priority = e.priority
+	msg.Reason = reason
+	msg.TableId = e.tableId
+	msg.DurationSec, msg.DurationNSec = duration(now.Sub(e.created))
+	msg.IdleTimeout = e.idleTimeout
+	msg.HardTimeout = e.hardTimeout
+	msg.PacketCount = e.packetCount
+	msg.ByteCount = e.byteCount
+	msg.Match = e.match
+	return msg
+}
+
+/*****************************************************/
+/* Flow Table                                        */
+/*****************************************************/
+
+/**
+ * flowTable holds flow entries in descending order of priority.
+ * Entries of the same priority are kept in added order.
+ */
+type flowTable struct {
+	entries      []*flowEntry
+	config       uint32
+	lookupCount  uint64
+	matchedCount uint64
+}
+
+func (t *flowTable) lookup(p *packet) *flowEntry {
+	for _, e := range t.entries {
+		if e.matches(p) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (t *flowTable) find(priority uint16, matchers []matcher) *flowEntry {
+	for _, e := range t.entries {
+		if e.priority == priority && e.sameMatch(matchers) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (t *flowTable) insert(entry *flowEntry) {
+	i := sort.Search(len(t.entries), func(i int) bool { return t.entries[i].priority < entry.priority })
+	t.entries = append(t.entries, nil)
+	copy(t.entries[i+1:], t.entries[i:])
+	t.entries[i] = entry
+}
+
+func (t *flowTable) replace(old *flowEntry, entry *flowEntry) {
+	for i, e := range t.entries {
+		if e == old {
+			t.entries[i] = entry
+			return
+		}
+	}
+}
+
+func (t *flowTable) remove(entry *flowEntry) {
+	for i, e := range t.entries {
+		if e == entry {
+			t.entries = append(t.entries[:i], t.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+/**
+ * flowFilter selects flow entries by fields of FlowMod, or of flow and
+ * aggregate stats request.
+ */
+type flowFilter struct {
+	tableId    uint8
+	strict     bool
+	priority   uint16
+	matchers   []matcher
+	outPort    uint32
+	outGroup   uint32
+	cookie     uint64
+	cookieMask uint64
+}
+
+func (f *flowFilter) selects(e *flowEntry) bool {
+	if f.tableId != ofp13.OFPTT_ALL && e.tableId != f.tableId {
+		return false
+	}
+	if (e.cookie^f.cookie)&f.cookieMask != 0 {
+		return false
+	}
+	if f.strict {
+		if e.priority != f.priority || !e.sameMatch(f.matchers) {
+			return false
+		}
+	} else if !e.coveredBy(f.matchers) {
+		return false
+	}
+	if f.outPort != ofp13.OFPP_ANY && !e.hasOutput(f.outPort) {
+		return false
+	}
+	if f.outGroup != ofp13.OFPG_ANY && !e.hasGroup(f.outGroup) {
+		return false
+	}
+	return true
+}
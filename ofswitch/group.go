@@ -0,0 +1,287 @@
+package ofswitch
+
+import (
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// limit of group table
+var DEFAULT_MAX_GROUPS uint32 = 1024
+
+type group struct {
+	id          uint32
+	groupType   uint8
+	buckets     []*ofp13.OfpBucket
+	bucketStats []*ofp13.OfpBucketCounter
+	packetCount uint64
+	byteCount   uint64
+	created     time.Time
+}
+
+func (g *group) setBuckets(groupType uint8, buckets []*ofp13.OfpBucket) {
+	g.groupType = groupType
+	g.buckets = buckets
+	g.bucketStats = make([]*ofp13.OfpBucketCounter, len(buckets))
+	for i := range g.bucketStats {
+		g.bucketStats[i] = new(ofp13.OfpBucketCounter)
+	}
+}
+
+/*****************************************************/
+/* Group Mod                                         */
+/*****************************************************/
+func (s *Switch) groupMod(msg *ofp13.OfpGroupMod, now time.Time) ([]ofp13.OFMessage, error) {
+	switch msg.Command {
+	case ofp13.OFPGC_ADD:
+		if msg.GroupId > ofp13.OFPG_MAX {
+			return nil, NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_INVALID_GROUP)
+		}
+		if _, ok := s.groups[msg.GroupId]; ok {
+			return nil, NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_GROUP_EXISTS)
+		}
+		if uint32(len(s.groups)) >= DEFAULT_MAX_GROUPS {
+			return nil, NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_OUT_OF_GROUPS)
+		}
+		if err := s.checkGroup(msg); err != nil {
+			return nil, err
+		}
+		g := &group{id: msg.GroupId, created: now}
+		g.setBuckets(msg.Type, msg.Buckets)
+		s.groups[msg.GroupId] = g
+		return nil, nil
+
+	case ofp13.OFPGC_MODIFY:
+		g, ok := s.groups[msg.GroupId]
+		if !ok {
+			return nil, NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_UNKNOWN_GROUP)
+		}
+		if err := s.checkGroup(msg); err != nil {
+			return nil, err
+		}
+		g.setBuckets(msg.Type, msg.Buckets)
+		return nil, nil
+
+	case ofp13.OFPGC_DELETE:
+		deleted := make(map[uint32]bool)
+		for id := range s.groups {
+			if msg.GroupId == ofp13.OFPG_ALL || msg.GroupId == id {
+				deleted[id] = true
+				delete(s.groups, id)
+			}
+		}
+		// flow entries forwarding to deleted groups are removed
+		return s.removeFlows(func(e *flowEntry) bool {
+			for id := range deleted {
+				if e.hasGroup(id) {
+					return true
+				}
+			}
+			return false
+		}, ofp13.OFPRR_GROUP_DELETE, now), nil
+	}
+	return nil, NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_BAD_COMMAND)
+}
+
+func (s *Switch) checkGroup(msg *ofp13.OfpGroupMod) error {
+	switch msg.Type {
+	case ofp13.OFPGT_ALL, ofp13.OFPGT_SELECT, ofp13.OFPGT_FF:
+	case ofp13.OFPGT_INDIRECT:
+		if len(msg.Buckets) != 1 {
+			return NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_INVALID_GROUP)
+		}
+	default:
+		return NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_BAD_TYPE)
+	}
+
+	for _, b := range msg.Buckets {
+		if msg.Type == ofp13.OFPGT_FF && b.WatchPort == ofp13.OFPP_ANY && b.WatchGroup == ofp13.OFPG_ANY {
+			return NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_BAD_WATCH)
+		}
+		if err := s.checkActions(b.Actions, false); err != nil {
+			return err
+		}
+	}
+	if s.reachesGroup(msg.Buckets, msg.GroupId, 0) {
+		return NewError(ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_LOOP)
+	}
+	return nil
+}
+
+// reachesGroup reports whether buckets forward to group id directly or via
+// chained groups.
+func (s *Switch) reachesGroup(buckets []*ofp13.OfpBucket, id uint32, depth int) bool {
+	if depth > MAX_GROUP_DEPTH {
+		return true
+	}
+	for _, b := range buckets {
+		for _, a := range b.Actions {
+			ga, ok := a.(*ofp13.OfpActionGroup)
+			if !ok {
+				continue
+			}
+			if ga.GroupId == id {
+				return true
+			}
+			if g, ok := s.groups[ga.GroupId]; ok && s.reachesGroup(g.buckets, id, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*****************************************************/
+/* Liveness                                          */
+/*****************************************************/
+func (s *Switch) portLive(portNo uint32) bool {
+	port := s.Port(portNo)
+	return port != nil && port.Config&ofp13.OFPPC_PORT_DOWN == 0 && port.State&ofp13.OFPPS_LINK_DOWN == 0
+}
+
+func (s *Switch) bucketLive(b *ofp13.OfpBucket, depth int) bool {
+	if b.WatchPort != ofp13.OFPP_ANY && !s.portLive(b.WatchPort) {
+		return false
+	}
+	if b.WatchGroup != ofp13.OFPG_ANY && !s.groupLive(b.WatchGroup, depth+1) {
+		return false
+	}
+	return true
+}
+
+// group is live if any of its buckets is live
+func (s *Switch) groupLive(id uint32, depth int) bool {
+	g, ok := s.groups[id]
+	if !ok || depth > MAX_GROUP_DEPTH {
+		return false
+	}
+	for _, b := range g.buckets {
+		if s.bucketLive(b, depth) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * selectBuckets returns index of buckets to which packet is sent. select
+ * group chooses a live bucket by hash of the packet weighted by bucket weight,
+ * and fast-failover group chooses the first live bucket.
+ */
+func (s *Switch) selectBuckets(g *group, p *packet) []int {
+	switch g.groupType {
+	case ofp13.OFPGT_ALL:
+		indexes := make([]int, len(g.buckets))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	case ofp13.OFPGT_INDIRECT:
+		if len(g.buckets) > 0 {
+			return []int{0}
+		}
+	case ofp13.OFPGT_SELECT:
+		total := uint32(0)
+		for _, b := range g.buckets {
+			if s.bucketLive(b, 0) {
+				total += uint32(b.Weight)
+			}
+		}
+		if total == 0 {
+			return nil
+		}
+		h := p.hash() % total
+		for i, b := range g.buckets {
+			if !s.bucketLive(b, 0) {
+				continue
+			}
+			if h < uint32(b.Weight) {
+				return []int{i}
+			}
+			h -= uint32(b.Weight)
+		}
+	case ofp13.OFPGT_FF:
+		for i, b := range g.buckets {
+			if s.bucketLive(b, 0) {
+				return []int{i}
+			}
+		}
+	}
+	return nil
+}
+
+/*****************************************************/
+/* Stats                                             */
+/*****************************************************/
+
+// number of flow entries and groups forwarding to the group
+func (s *Switch) groupRefCount(id uint32) uint32 {
+	count := uint32(0)
+	s.forEachFlow(func(e *flowEntry) {
+		if e.hasGroup(id) {
+			count++
+		}
+	})
+	for _, g := range s.groups {
+		for _, b := range g.buckets {
+			for _, a := range b.Actions {
+				if ga, ok := a.(*ofp13.OfpActionGroup); ok && ga.GroupId == id {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+func (s *Switch) groupStats(id uint32, now time.Time) []ofp13.OfpMultipartBody {
+	var bodies []ofp13.OfpMultipartBody
+	for _, gid := range s.groupIds() {
+		if id != ofp13.OFPG_ALL && id != gid {
+			continue
+		}
+		g := s.groups[gid]
+		stats := new(ofp13.OfpGroupStats)
+		stats.GroupId = g.id
+		stats.RefCount = s.groupRefCount(g.id)
+		stats.PacketCount = g.packetCount
+		stats.ByteCount = g.byteCount
+		stats.DurationSec, stats.DurationNSec = duration(now.Sub(g.created))
+		for _, c := range g.bucketStats {
+			counter := *c
+			stats.BucketStats = append(stats.BucketStats, &counter)
+		}
+		stats.Length = uint16(stats.Size())
+		bodies = append(bodies, stats)
+	}
+	return bodies
+}
+
+func (s *Switch) groupDesc() []ofp13.OfpMultipartBody {
+	var bodies []ofp13.OfpMultipartBody
+	for _, gid := range s.groupIds() {
+		g := s.groups[gid]
+		desc := new(ofp13.OfpGroupDescStats)
+		desc.Type = g.groupType
+		desc.GroupId = g.id
+		desc.Buckets = g.buckets
+		desc.Length = uint16(desc.Size())
+		bodies = append(bodies, desc)
+	}
+	return bodies
+}
+
+func (s *Switch) groupFeatures() *ofp13.OfpGroupFeaturesStats {
+	features := new(ofp13.OfpGroupFeaturesStats)
+	features.Type = 1<<ofp13.OFPGT_ALL | 1<<ofp13.OFPGT_SELECT | 1<<ofp13.OFPGT_INDIRECT | 1<<ofp13.OFPGT_FF
+	features.Capabilities = ofp13.OFPGC_SELECT_WEIGHT | ofp13.OFPGC_SELECT_LIVENESS | ofp13.OFPGC_CHAINING
+	actions := uint32(0)
+	for _, t := range supportedActions {
+		actions |= 1 << t
+	}
+	for i := range features.MaxGroups {
+		features.MaxGroups[i] = DEFAULT_MAX_GROUPS
+		features.Actions[i] = actions
+	}
+	return features
+}
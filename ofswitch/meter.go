@@ -0,0 +1,236 @@
+package ofswitch
+
+import (
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// limit of meter table
+var DEFAULT_MAX_METERS uint32 = 1024
+var DEFAULT_MAX_BANDS uint8 = 16
+
+/**
+ * meter measures rate of packets by token bucket of each band. A band is
+ * applied when the packet exceeds its rate, and the band of the highest
+ * rate is chosen if the packet exceeds several bands.
+ */
+type meter struct {
+	id          uint32
+	flags       uint16
+	bands       []ofp13.OfpMeterBand
+	states      []*bandState
+	packetCount uint64
+	byteCount   uint64
+	created     time.Time
+}
+
+type bandState struct {
+	tokens      float64
+	last        time.Time
+	packetCount uint64
+	byteCount   uint64
+}
+
+func bandHeader(band ofp13.OfpMeterBand) *ofp13.OfpMeterBandHeader {
+	switch b := band.(type) {
+	case *ofp13.OfpMeterBandDrop:
+		return &b.Header
+	case *ofp13.OfpMeterBandDscpRemark:
+		return &b.Header
+	case *ofp13.OfpMeterBandExperimenter:
+		return &b.Header
+	}
+	return nil
+}
+
+func (m *meter) setBands(flags uint16, bands []ofp13.OfpMeterBand, now time.Time) {
+	m.flags = flags
+	m.bands = bands
+	m.states = make([]*bandState, len(bands))
+	for i, b := range bands {
+		m.states[i] = &bandState{tokens: m.capacity(bandHeader(b)), last: now}
+	}
+}
+
+// size of token bucket, burst size or tokens of one second
+func (m *meter) capacity(h *ofp13.OfpMeterBandHeader) float64 {
+	if m.flags&ofp13.OFPMF_BURST != 0 && h.BurstSize > 0 {
+		return float64(h.BurstSize)
+	}
+	return float64(h.Rate)
+}
+
+/**
+ * apply measures packet of given size at now, and returns the band to apply,
+ * or nil if the packet does not exceed any band.
+ */
+func (m *meter) apply(size int, now time.Time) ofp13.OfpMeterBand {
+	m.packetCount++
+	m.byteCount += uint64(size)
+
+	// tokens are kilobits or packets
+	cost := 1.0
+	if m.flags&ofp13.OFPMF_KBPS != 0 {
+		cost = float64(size*8) / 1000
+	}
+
+	hit := -1
+	for i, b := range m.bands {
+		h := bandHeader(b)
+		st := m.states[i]
+		if elapsed := now.Sub(st.last).Seconds(); elapsed > 0 {
+			st.tokens += elapsed * float64(h.Rate)
+			st.last = now
+		}
+		if c := m.capacity(h); st.tokens > c {
+			st.tokens = c
+		}
+
+		if st.tokens >= cost {
+			st.tokens -= cost
+		} else if hit < 0 || h.Rate > bandHeader(m.bands[hit]).Rate {
+			hit = i
+		}
+	}
+	if hit < 0 {
+		return nil
+	}
+	m.states[hit].packetCount++
+	m.states[hit].byteCount += uint64(size)
+	return m.bands[hit]
+}
+
+/*****************************************************/
+/* Meter Mod                                         */
+/*****************************************************/
+func (s *Switch) meterMod(msg *ofp13.OfpMeterMod, now time.Time) ([]ofp13.OFMessage, error) {
+	if msg.MeterId == 0 || (msg.MeterId > ofp13.OFPM_MAX && !(msg.Command == ofp13.OFPMC_DELETE && msg.MeterId == ofp13.OFPM_ALL)) {
+		return nil, NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_INVALID_METER)
+	}
+
+	switch msg.Command {
+	case ofp13.OFPMC_ADD:
+		if _, ok := s.meters[msg.MeterId]; ok {
+			return nil, NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_METER_EXISTS)
+		}
+		if uint32(len(s.meters)) >= DEFAULT_MAX_METERS {
+			return nil, NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_OUT_OF_METERS)
+		}
+		if err := checkMeter(msg); err != nil {
+			return nil, err
+		}
+		m := &meter{id: msg.MeterId, created: now}
+		m.setBands(msg.Flags, msg.Bands, now)
+		s.meters[msg.MeterId] = m
+		return nil, nil
+
+	case ofp13.OFPMC_MODIFY:
+		m, ok := s.meters[msg.MeterId]
+		if !ok {
+			return nil, NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_UNKNOWN_METER)
+		}
+		if err := checkMeter(msg); err != nil {
+			return nil, err
+		}
+		m.setBands(msg.Flags, msg.Bands, now)
+		return nil, nil
+
+	case ofp13.OFPMC_DELETE:
+		deleted := make(map[uint32]bool)
+		for id := range s.meters {
+			if msg.MeterId == ofp13.OFPM_ALL || msg.MeterId == id {
+				deleted[id] = true
+				delete(s.meters, id)
+			}
+		}
+		// flow entries using deleted meters are removed
+		return s.removeFlows(func(e *flowEntry) bool {
+			for id := range deleted {
+				if e.hasMeter(id) {
+					return true
+				}
+			}
+			return false
+		}, ofp13.OFPRR_DELETE, now), nil
+	}
+	return nil, NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_BAD_COMMAND)
+}
+
+func checkMeter(msg *ofp13.OfpMeterMod) error {
+	unit := msg.Flags & (ofp13.OFPMF_KBPS | ofp13.OFPMF_PKTPS)
+	if unit == 0 || unit == ofp13.OFPMF_KBPS|ofp13.OFPMF_PKTPS ||
+		msg.Flags&^(ofp13.OFPMF_KBPS|ofp13.OFPMF_PKTPS|ofp13.OFPMF_BURST|ofp13.OFPMF_STATS) != 0 {
+		return NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_BAD_FLAGS)
+	}
+	if len(msg.Bands) > int(DEFAULT_MAX_BANDS) {
+		return NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_OUT_OF_BANDS)
+	}
+	for _, b := range msg.Bands {
+		switch b.MeterBandType() {
+		case ofp13.OFPMBT_DROP, ofp13.OFPMBT_DSCP_REMARK:
+		default:
+			return NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_BAD_BAND)
+		}
+		if bandHeader(b).Rate == 0 {
+			return NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_BAD_RATE)
+		}
+	}
+	return nil
+}
+
+/*****************************************************/
+/* Stats                                             */
+/*****************************************************/
+func (s *Switch) meterStats(id uint32, now time.Time) []ofp13.OfpMultipartBody {
+	var bodies []ofp13.OfpMultipartBody
+	for _, mid := range s.meterIds() {
+		if id != ofp13.OFPM_ALL && id != mid {
+			continue
+		}
+		m := s.meters[mid]
+		stats := new(ofp13.OfpMeterStats)
+		stats.MeterId = m.id
+		s.forEachFlow(func(e *flowEntry) {
+			if e.hasMeter(m.id) {
+				stats.FlowCount++
+			}
+		})
+		stats.PacketInCount = m.packetCount
+		stats.ByteInCount = m.byteCount
+		stats.DurationSec, stats.DurationNSec = duration(now.Sub(m.created))
+		for _, st := range m.states {
+			stats.BandStats = append(stats.BandStats, &ofp13.OfpMeterBandStats{
+				PacketBandCount: st.packetCount, ByteBandCount: st.byteCount})
+		}
+		stats.Length = uint16(stats.Size())
+		bodies = append(bodies, stats)
+	}
+	return bodies
+}
+
+func (s *Switch) meterConfig(id uint32) []ofp13.OfpMultipartBody {
+	var bodies []ofp13.OfpMultipartBody
+	for _, mid := range s.meterIds() {
+		if id != ofp13.OFPM_ALL && id != mid {
+			continue
+		}
+		m := s.meters[mid]
+		config := new(ofp13.OfpMeterConfig)
+		config.Flags = m.flags
+		config.MeterId = m.id
+		config.Bands = m.bands
+		config.Length = uint16(config.Size())
+		bodies = append(bodies, config)
+	}
+	return bodies
+}
+
+func meterFeatures() *ofp13.OfpMeterFeatures {
+	features := new(ofp13.OfpMeterFeatures)
+	features.MaxMeter = DEFAULT_MAX_METERS
+	features.BandTypes = 1<<ofp13.OFPMBT_DROP | 1<<ofp13.OFPMBT_DSCP_REMARK
+	features.Capabilities = ofp13.OFPMF_KBPS | ofp13.OFPMF_PKTPS | ofp13.OFPMF_BURST | ofp13.OFPMF_STATS
+	features.MaxBands = DEFAULT_MAX_BANDS
+	return features
+}
@@ -0,0 +1,666 @@
+package ofswitch
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// ethernet types and ip protocols known by the pipeline
+const (
+	ethTypeIPv4    = 0x0800
+	ethTypeARP     = 0x0806
+	ethTypeVLAN    = 0x8100
+	ethTypeQinQ    = 0x88a8
+	ethTypeIPv6    = 0x86dd
+	ethTypeMPLS    = 0x8847
+	ethTypeMPLSMC  = 0x8848
+	ipProtoICMP    = 1
+	ipProtoTCP     = 6
+	ipProtoUDP     = 17
+	ipProtoICMPv6  = 58
+	ipProtoSCTP    = 132
+	nOxmFields     = ofp13.OFPXMT_OFB_IPV6_EXTHDR + 1
+	ndNeighborSol  = 135
+	ndNeighborAdv  = 136
+	ndOptSourceLL  = 1
+	ndOptTargetLL  = 2
+	ipv6HeaderSize = 40
+)
+
+/**
+ * packet is a frame processed in the pipeline, with its pipeline fields.
+ * fields holds value of each OXM field in its OXM encoding, or nil if the
+ * field is not present in the frame.
+ */
+type packet struct {
+	data      []byte
+	inPort    uint32
+	inPhyPort uint32
+	metadata  uint64
+	tunnelId  uint64
+	queueId   uint32
+
+	fields [nOxmFields][]byte
+	// offset of byte aligned field in data
+	offsets [nOxmFields]int
+
+	vlanOff    int // outermost vlan tag, or -1
+	mplsOff    int // outermost mpls label, or -1
+	ethTypeOff int // ether type following vlan tags
+	l3Off      int // -1 if l3 header is not present
+	l4Off      int // -1 if l4 header is not present
+	ethType    uint16
+	ipProto    uint8
+}
+
+func newPacket(data []byte, inPort uint32) *packet {
+	p := new(packet)
+	p.data = append([]byte(nil), data...)
+	p.inPort = inPort
+	p.inPhyPort = inPort
+	p.parse()
+	return p
+}
+
+func (p *packet) clone() *packet {
+	c := *p
+	c.data = append([]byte(nil), p.data...)
+	return &c
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func be32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func be64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// set field from n bytes of data at off
+func (p *packet) field(f int, off int, n int) {
+	if len(p.data) < off+n {
+		return
+	}
+	p.fields[f] = append([]byte(nil), p.data[off:off+n]...)
+	p.offsets[f] = off
+}
+
+/*****************************************************/
+/* Parse                                             */
+/*****************************************************/
+
+// extract fields from data. it is called again after data is modified.
+func (p *packet) parse() {
+	p.fields = [nOxmFields][]byte{}
+	p.vlanOff, p.mplsOff, p.l3Off, p.l4Off = -1, -1, -1, -1
+	p.ethType, p.ipProto = 0, 0
+
+	p.fields[ofp13.OFPXMT_OFB_IN_PORT] = be32(p.inPort)
+	p.fields[ofp13.OFPXMT_OFB_IN_PHY_PORT] = be32(p.inPhyPort)
+	p.fields[ofp13.OFPXMT_OFB_METADATA] = be64(p.metadata)
+	p.fields[ofp13.OFPXMT_OFB_TUNNEL_ID] = be64(p.tunnelId)
+
+	data := p.data
+	if len(data) < 14 {
+		return
+	}
+	p.field(ofp13.OFPXMT_OFB_ETH_DST, 0, 6)
+	p.field(ofp13.OFPXMT_OFB_ETH_SRC, 6, 6)
+
+	// vlan tags, vlan_vid is OFPVID_NONE for untagged frame
+	off := 12
+	ethType := binary.BigEndian.Uint16(data[off:])
+	p.fields[ofp13.OFPXMT_OFB_VLAN_VID] = be16(ofp13.OFPVID_NONE)
+	for (ethType == ethTypeVLAN || ethType == ethTypeQinQ) && len(data) >= off+6 {
+		if p.vlanOff < 0 {
+			p.vlanOff = off
+			tci := binary.BigEndian.Uint16(data[off+2:])
+			p.fields[ofp13.OFPXMT_OFB_VLAN_VID] = be16(ofp13.OFPVID_PRESENT | tci&0x0fff)
+			p.fields[ofp13.OFPXMT_OFB_VLAN_PCP] = []byte{uint8(tci >> 13)}
+		}
+		off += 4
+		ethType = binary.BigEndian.Uint16(data[off:])
+	}
+	p.ethTypeOff = off
+	p.ethType = ethType
+	p.field(ofp13.OFPXMT_OFB_ETH_TYPE, off, 2)
+	off += 2
+
+	switch ethType {
+	case ethTypeMPLS, ethTypeMPLSMC:
+		p.parseMpls(off)
+	case ethTypeARP:
+		p.parseArp(off)
+	case ethTypeIPv4:
+		p.parseIpv4(off)
+	case ethTypeIPv6:
+		p.parseIpv6(off)
+	}
+}
+
+func (p *packet) parseMpls(off int) {
+	if len(p.data) < off+4 {
+		return
+	}
+	p.mplsOff = off
+	v := binary.BigEndian.Uint32(p.data[off:])
+	p.fields[ofp13.OFPXMT_OFB_MPLS_LABEL] = be32(v >> 12)
+	p.fields[ofp13.OFPXMT_OFB_MPLS_TC] = []byte{uint8(v>>9) & 0x7}
+	p.fields[ofp13.OFPXMT_OFB_MPLS_BOS] = []byte{uint8(v>>8) & 0x1}
+}
+
+func (p *packet) parseArp(off int) {
+	if len(p.data) < off+28 {
+		return
+	}
+	p.field(ofp13.OFPXMT_OFB_ARP_OP, off+6, 2)
+	p.field(ofp13.OFPXMT_OFB_ARP_SHA, off+8, 6)
+	p.field(ofp13.OFPXMT_OFB_ARP_SPA, off+14, 4)
+	p.field(ofp13.OFPXMT_OFB_ARP_THA, off+18, 6)
+	p.field(ofp13.OFPXMT_OFB_ARP_TPA, off+24, 4)
+}
+
+func (p *packet) parseIpv4(off int) {
+	data := p.data
+	if len(data) < off+20 {
+		return
+	}
+	ihl := int(data[off]&0x0f) * 4
+	if ihl < 20 || len(data) < off+ihl {
+		return
+	}
+	p.l3Off = off
+	p.fields[ofp13.OFPXMT_OFB_IP_DSCP] = []byte{data[off+1] >> 2}
+	p.fields[ofp13.OFPXMT_OFB_IP_ECN] = []byte{data[off+1] & 0x3}
+	p.field(ofp13.OFPXMT_OFB_IP_PROTO, off+9, 1)
+	p.field(ofp13.OFPXMT_OFB_IPV4_SRC, off+12, 4)
+	p.field(ofp13.OFPXMT_OFB_IPV4_DST, off+16, 4)
+	p.ipProto = data[off+9]
+
+	// l4 header is only in the first fragment
+	if binary.BigEndian.Uint16(data[off+6:])&0x1fff != 0 {
+		return
+	}
+	p.parseL4(off + ihl)
+}
+
+func (p *packet) parseIpv6(off int) {
+	data := p.data
+	if len(data) < off+ipv6HeaderSize {
+		return
+	}
+	p.l3Off = off
+	vtf := binary.BigEndian.Uint32(data[off:])
+	tc := uint8(vtf >> 20)
+	p.fields[ofp13.OFPXMT_OFB_IP_DSCP] = []byte{tc >> 2}
+	p.fields[ofp13.OFPXMT_OFB_IP_ECN] = []byte{tc & 0x3}
+	p.fields[ofp13.OFPXMT_OFB_IPV6_FLABEL] = be32(vtf & 0xfffff)
+	p.field(ofp13.OFPXMT_OFB_IPV6_SRC, off+8, 16)
+	p.field(ofp13.OFPXMT_OFB_IPV6_DST, off+24, 16)
+
+	// walk extension headers
+	next := data[off+6]
+	hdr := off + ipv6HeaderSize
+	var exthdr uint16
+	l4 := true
+loop:
+	for len(data) >= hdr+2 {
+		length := (int(data[hdr+1]) + 1) * 8
+		switch next {
+		case 0:
+			exthdr |= ofp13.OFPIEH_HOP
+		case 43:
+			exthdr |= ofp13.OFPIEH_ROUTER
+		case 60:
+			exthdr |= ofp13.OFPIEH_DEST
+		case 51:
+			exthdr |= ofp13.OFPIEH_AUTH
+			length = (int(data[hdr+1]) + 2) * 4
+		case 44:
+			exthdr |= ofp13.OFPIEH_FRAG
+			length = 8
+			if len(data) >= hdr+4 && binary.BigEndian.Uint16(data[hdr+2:])&0xfff8 != 0 {
+				l4 = false
+			}
+		case 50:
+			exthdr |= ofp13.OFPIEH_ESP
+			l4 = false
+			break loop
+		case 59:
+			exthdr |= ofp13.OFPIEH_NONEXT
+			l4 = false
+			break loop
+		default:
+			break loop
+		}
+		next = data[hdr]
+		hdr += length
+	}
+	p.fields[ofp13.OFPXMT_OFB_IPV6_EXTHDR] = be16(exthdr)
+	p.fields[ofp13.OFPXMT_OFB_IP_PROTO] = []byte{next}
+	p.ipProto = next
+
+	if l4 {
+		p.parseL4(hdr)
+	}
+}
+
+func (p *packet) parseL4(off int) {
+	if len(p.data) < off {
+		return
+	}
+	p.l4Off = off
+	switch p.ipProto {
+	case ipProtoTCP:
+		p.field(ofp13.OFPXMT_OFB_TCP_SRC, off, 2)
+		p.field(ofp13.OFPXMT_OFB_TCP_DST, off+2, 2)
+	case ipProtoUDP:
+		p.field(ofp13.OFPXMT_OFB_UDP_SRC, off, 2)
+		p.field(ofp13.OFPXMT_OFB_UDP_DST, off+2, 2)
+	case ipProtoSCTP:
+		p.field(ofp13.OFPXMT_OFB_SCTP_SRC, off, 2)
+		p.field(ofp13.OFPXMT_OFB_SCTP_DST, off+2, 2)
+	case ipProtoICMP:
+		if p.ethType == ethTypeIPv4 {
+			p.field(ofp13.OFPXMT_OFB_ICMPV4_TYPE, off, 1)
+			p.field(ofp13.OFPXMT_OFB_ICMPV4_CODE, off+1, 1)
+		}
+	case ipProtoICMPv6:
+		if p.ethType == ethTypeIPv6 {
+			p.parseIcmpv6(off)
+		}
+	}
+}
+
+func (p *packet) parseIcmpv6(off int) {
+	data := p.data
+	p.field(ofp13.OFPXMT_OFB_ICMPV6_TYPE, off, 1)
+	p.field(ofp13.OFPXMT_OFB_ICMPV6_CODE, off+1, 1)
+	if len(data) < off+24 || (data[off] != ndNeighborSol && data[off] != ndNeighborAdv) {
+		return
+	}
+	p.field(ofp13.OFPXMT_OFB_IPV6_ND_TARGET, off+8, 16)
+
+	// link layer address options
+	for opt := off + 24; len(data) >= opt+8 && data[opt+1] != 0; opt += int(data[opt+1]) * 8 {
+		if data[off] == ndNeighborSol && data[opt] == ndOptSourceLL {
+			p.field(ofp13.OFPXMT_OFB_IPV6_ND_SLL, opt+2, 6)
+		}
+		if data[off] == ndNeighborAdv && data[opt] == ndOptTargetLL {
+			p.field(ofp13.OFPXMT_OFB_IPV6_ND_TLL, opt+2, 6)
+		}
+	}
+}
+
+// hash of addresses and ports, used to select bucket of select group
+func (p *packet) hash() uint32 {
+	h := fnv.New32a()
+	for _, f := range []int{
+		ofp13.OFPXMT_OFB_ETH_DST, ofp13.OFPXMT_OFB_ETH_SRC, ofp13.OFPXMT_OFB_ETH_TYPE,
+		ofp13.OFPXMT_OFB_IP_PROTO, ofp13.OFPXMT_OFB_IPV4_SRC, ofp13.OFPXMT_OFB_IPV4_DST,
+		ofp13.OFPXMT_OFB_IPV6_SRC, ofp13.OFPXMT_OFB_IPV6_DST,
+		ofp13.OFPXMT_OFB_TCP_SRC, ofp13.OFPXMT_OFB_TCP_DST,
+		ofp13.OFPXMT_OFB_UDP_SRC, ofp13.OFPXMT_OFB_UDP_DST,
+		ofp13.OFPXMT_OFB_SCTP_SRC, ofp13.OFPXMT_OFB_SCTP_DST,
+	} {
+		h.Write(p.fields[f])
+	}
+	return h.Sum32()
+}
+
+/*****************************************************/
+/* Modification                                      */
+/*****************************************************/
+
+// settable reports whether set-field action of field f is supported.
+func settable(f uint32) bool {
+	switch f {
+	case ofp13.OFPXMT_OFB_IN_PORT, ofp13.OFPXMT_OFB_IN_PHY_PORT, ofp13.OFPXMT_OFB_METADATA,
+		ofp13.OFPXMT_OFB_ETH_TYPE, ofp13.OFPXMT_OFB_IP_PROTO, ofp13.OFPXMT_OFB_IPV6_EXTHDR,
+		ofp13.OFPXMT_OFB_PBB_ISID:
+		return false
+	}
+	return f < nOxmFields
+}
+
+/**
+ * setField sets value of field f in OXM encoding. It is ignored if the field
+ * is not present in the packet. Checksums are updated.
+ */
+func (p *packet) setField(f uint32, value []byte) {
+	if !settable(f) || p.fields[f] == nil || len(value) < len(p.fields[f]) {
+		return
+	}
+	data := p.data
+	switch f {
+	case ofp13.OFPXMT_OFB_TUNNEL_ID:
+		p.tunnelId = binary.BigEndian.Uint64(value)
+	case ofp13.OFPXMT_OFB_VLAN_VID:
+		if p.vlanOff < 0 {
+			return
+		}
+		tci := binary.BigEndian.Uint16(data[p.vlanOff+2:])
+		tci = tci&^0x0fff | binary.BigEndian.Uint16(value)&0x0fff
+		binary.BigEndian.PutUint16(data[p.vlanOff+2:], tci)
+	case ofp13.OFPXMT_OFB_VLAN_PCP:
+		tci := binary.BigEndian.Uint16(data[p.vlanOff+2:])
+		tci = tci&0x1fff | uint16(value[0]&0x7)<<13
+		binary.BigEndian.PutUint16(data[p.vlanOff+2:], tci)
+	case ofp13.OFPXMT_OFB_IP_DSCP:
+		p.setTrafficClass(value[0]<<2, 0xfc)
+	case ofp13.OFPXMT_OFB_IP_ECN:
+		p.setTrafficClass(value[0]&0x3, 0x3)
+	case ofp13.OFPXMT_OFB_IPV6_FLABEL:
+		vtf := binary.BigEndian.Uint32(data[p.l3Off:])
+		vtf = vtf&^0xfffff | binary.BigEndian.Uint32(value)&0xfffff
+		binary.BigEndian.PutUint32(data[p.l3Off:], vtf)
+	case ofp13.OFPXMT_OFB_MPLS_LABEL:
+		p.setMplsBits(binary.BigEndian.Uint32(value)<<12, 0xfffff000)
+	case ofp13.OFPXMT_OFB_MPLS_TC:
+		p.setMplsBits(uint32(value[0]&0x7)<<9, 0xe00)
+	case ofp13.OFPXMT_OFB_MPLS_BOS:
+		p.setMplsBits(uint32(value[0]&0x1)<<8, 0x100)
+	default:
+		copy(data[p.offsets[f]:], value[:len(p.fields[f])])
+	}
+	p.updateChecksums()
+	p.parse()
+}
+
+/**
+ * remarkDscp increases drop precedence of AF codepoint (AFxy, dscp 8x+2y)
+ * by prec. Other codepoints are not changed.
+ */
+func (p *packet) remarkDscp(prec uint8) {
+	v := p.fields[ofp13.OFPXMT_OFB_IP_DSCP]
+	if v == nil {
+		return
+	}
+	class, drop := v[0]>>3, int((v[0]>>1)&0x3)
+	if v[0]&0x1 != 0 || class < 1 || class > 4 || drop == 0 {
+		return
+	}
+	drop += int(prec)
+	if drop > 3 {
+		drop = 3
+	}
+	p.setField(ofp13.OFPXMT_OFB_IP_DSCP, []byte{class<<3 | uint8(drop)<<1})
+}
+
+// set bits of tos of ipv4 or traffic class of ipv6
+func (p *packet) setTrafficClass(value uint8, mask uint8) {
+	data := p.data
+	if p.ethType == ethTypeIPv4 {
+		data[p.l3Off+1] = data[p.l3Off+1]&^mask | value&mask
+		return
+	}
+	vtf := binary.BigEndian.Uint32(data[p.l3Off:])
+	tc := uint8(vtf>>20)&^mask | value&mask
+	binary.BigEndian.PutUint32(data[p.l3Off:], vtf&^0x0ff00000|uint32(tc)<<20)
+}
+
+func (p *packet) setMplsBits(value uint32, mask uint32) {
+	v := binary.BigEndian.Uint32(p.data[p.mplsOff:])
+	binary.BigEndian.PutUint32(p.data[p.mplsOff:], v&^mask|value&mask)
+}
+
+// insert b into data at off
+func (p *packet) insert(off int, b []byte) {
+	data := make([]byte, 0, len(p.data)+len(b))
+	data = append(data, p.data[:off]...)
+	data = append(data, b...)
+	p.data = append(data, p.data[off:]...)
+}
+
+// remove n bytes of data at off
+func (p *packet) remove(off int, n int) {
+	data := make([]byte, 0, len(p.data)-n)
+	data = append(data, p.data[:off]...)
+	p.data = append(data, p.data[off+n:]...)
+}
+
+/**
+ * pushVlan pushes new outermost vlan tag. vid and pcp are copied from
+ * existing tag, or zero.
+ */
+func (p *packet) pushVlan(ethType uint16) {
+	if len(p.data) < 14 {
+		return
+	}
+	var tci uint16
+	if p.vlanOff >= 0 {
+		tci = binary.BigEndian.Uint16(p.data[p.vlanOff+2:])
+	}
+	tag := make([]byte, 4)
+	binary.BigEndian.PutUint16(tag, ethType)
+	binary.BigEndian.PutUint16(tag[2:], tci)
+	p.insert(12, tag)
+	p.parse()
+}
+
+func (p *packet) popVlan() {
+	if p.vlanOff < 0 {
+		return
+	}
+	p.remove(p.vlanOff, 4)
+	p.parse()
+}
+
+/**
+ * pushMpls pushes new outermost mpls label. label, tc and ttl are copied
+ * from existing label, or ttl is copied from ip header.
+ */
+func (p *packet) pushMpls(ethType uint16) {
+	if len(p.data) < 14 {
+		return
+	}
+	var label uint32
+	if p.mplsOff >= 0 {
+		label = binary.BigEndian.Uint32(p.data[p.mplsOff:]) &^ 0x100
+	} else {
+		label = 0x100
+		if off := p.ipTtlOffset(); off >= 0 {
+			label |= uint32(p.data[off])
+		}
+	}
+	binary.BigEndian.PutUint16(p.data[p.ethTypeOff:], ethType)
+	p.insert(p.ethTypeOff+2, be32(label))
+	p.parse()
+}
+
+func (p *packet) popMpls(ethType uint16) {
+	if p.mplsOff < 0 {
+		return
+	}
+	binary.BigEndian.PutUint16(p.data[p.ethTypeOff:], ethType)
+	p.remove(p.mplsOff, 4)
+	p.parse()
+}
+
+// offset of ttl of ip header, or -1
+func (p *packet) ipTtlOffset() int {
+	if p.l3Off < 0 {
+		return -1
+	}
+	if p.ethType == ethTypeIPv4 {
+		return p.l3Off + 8
+	}
+	return p.l3Off + 7
+}
+
+// offset of ttl of header inside outermost mpls label, or -1
+func (p *packet) innerTtlOffset() int {
+	if p.mplsOff < 0 || len(p.data) < p.mplsOff+5 {
+		return -1
+	}
+	inner := p.mplsOff + 4
+	if p.data[p.mplsOff+2]&0x1 == 0 {
+		if len(p.data) < inner+4 {
+			return -1
+		}
+		return inner + 3
+	}
+	switch p.data[inner] >> 4 {
+	case 4:
+		if len(p.data) >= inner+20 {
+			return inner + 8
+		}
+	case 6:
+		if len(p.data) >= inner+ipv6HeaderSize {
+			return inner + 7
+		}
+	}
+	return -1
+}
+
+// setTtl sets ttl at off, ignored if off is -1.
+func (p *packet) setTtl(off int, ttl uint8) {
+	if off < 0 {
+		return
+	}
+	p.data[off] = ttl
+	p.updateChecksums()
+}
+
+// decTtl decrements ttl at off. it returns false if the ttl is invalid.
+func (p *packet) decTtl(off int) bool {
+	if off < 0 {
+		return true
+	}
+	if p.data[off] <= 1 {
+		return false
+	}
+	p.data[off]--
+	p.updateChecksums()
+	return true
+}
+
+func (p *packet) mplsTtlOffset() int {
+	if p.mplsOff < 0 {
+		return -1
+	}
+	return p.mplsOff + 3
+}
+
+// copy ttl from next-to-outermost header to outermost header
+func (p *packet) copyTtlOut() {
+	if inner := p.innerTtlOffset(); inner >= 0 {
+		p.data[p.mplsOff+3] = p.data[inner]
+	}
+}
+
+// copy ttl from outermost header to next-to-outermost header
+func (p *packet) copyTtlIn() {
+	inner := p.innerTtlOffset()
+	if inner < 0 {
+		return
+	}
+	p.data[inner] = p.data[p.mplsOff+3]
+	ip := p.mplsOff + 4
+	if p.data[p.mplsOff+2]&0x1 != 0 && p.data[ip]>>4 == 4 {
+		ihl := int(p.data[ip]&0x0f) * 4
+		if ihl >= 20 && len(p.data) >= ip+ihl {
+			binary.BigEndian.PutUint16(p.data[ip+10:], 0)
+			binary.BigEndian.PutUint16(p.data[ip+10:], checksum(p.data[ip:ip+ihl], 0))
+		}
+	}
+}
+
+/*****************************************************/
+/* Checksum                                          */
+/*****************************************************/
+
+// sum of 16bit words
+func sum16(b []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// internet checksum of b, with partial sum of pseudo header
+func checksum(b []byte, sum uint32) uint16 {
+	sum = sum16(b, sum)
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+// recompute checksums of ipv4, tcp, udp, icmp and icmpv6 header
+func (p *packet) updateChecksums() {
+	if p.l3Off < 0 {
+		return
+	}
+	data := p.data
+	l3 := p.l3Off
+	var pseudo uint32
+	var l4Len int
+	switch p.ethType {
+	case ethTypeIPv4:
+		ihl := int(data[l3]&0x0f) * 4
+		binary.BigEndian.PutUint16(data[l3+10:], 0)
+		binary.BigEndian.PutUint16(data[l3+10:], checksum(data[l3:l3+ihl], 0))
+		if p.l4Off < 0 {
+			return
+		}
+		l4Len = int(binary.BigEndian.Uint16(data[l3+2:])) - ihl
+		pseudo = sum16(data[l3+12:l3+20], 0)
+	case ethTypeIPv6:
+		if p.l4Off < 0 {
+			return
+		}
+		l4Len = int(binary.BigEndian.Uint16(data[l3+4:])) - (p.l4Off - l3 - ipv6HeaderSize)
+		pseudo = sum16(data[l3+8:l3+ipv6HeaderSize], 0)
+	default:
+		return
+	}
+	if l4Len < 0 || p.l4Off+l4Len > len(data) {
+		l4Len = len(data) - p.l4Off
+	}
+	pseudo += uint32(p.ipProto) + uint32(l4Len)
+
+	segment := data[p.l4Off : p.l4Off+l4Len]
+	var off int
+	switch p.ipProto {
+	case ipProtoTCP:
+		off = 16
+	case ipProtoUDP:
+		off = 6
+		// checksum of udp over ipv4 is optional
+		if p.ethType == ethTypeIPv4 && len(segment) >= 8 && binary.BigEndian.Uint16(segment[off:]) == 0 {
+			return
+		}
+	case ipProtoICMP:
+		off = 2
+		pseudo = 0
+	case ipProtoICMPv6:
+		off = 2
+	default:
+		return
+	}
+	if len(segment) < off+2 {
+		return
+	}
+	binary.BigEndian.PutUint16(segment[off:], 0)
+	sum := checksum(segment, pseudo)
+	if sum == 0 && p.ipProto == ipProtoUDP {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(segment[off:], sum)
+}
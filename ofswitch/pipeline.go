@@ -0,0 +1,459 @@
+package ofswitch
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// limit of group chaining
+const MAX_GROUP_DEPTH = 16
+
+// actions supported by the pipeline
+var supportedActions = []uint16{
+	ofp13.OFPAT_OUTPUT,
+	ofp13.OFPAT_COPY_TTL_OUT,
+	ofp13.OFPAT_COPY_TTL_IN,
+	ofp13.OFPAT_SET_MPLS_TTL,
+	ofp13.OFPAT_DEC_MPLS_TTL,
+	ofp13.OFPAT_PUSH_VLAN,
+	ofp13.OFPAT_POP_VLAN,
+	ofp13.OFPAT_PUSH_MPLS,
+	ofp13.OFPAT_POP_MPLS,
+	ofp13.OFPAT_SET_QUEUE,
+	ofp13.OFPAT_GROUP,
+	ofp13.OFPAT_SET_NW_TTL,
+	ofp13.OFPAT_DEC_NW_TTL,
+	ofp13.OFPAT_SET_FIELD,
+}
+
+// execution order of action set
+var actionSetOrder = []uint16{
+	ofp13.OFPAT_COPY_TTL_IN,
+	ofp13.OFPAT_POP_VLAN,
+	ofp13.OFPAT_POP_MPLS,
+	ofp13.OFPAT_PUSH_MPLS,
+	ofp13.OFPAT_PUSH_VLAN,
+	ofp13.OFPAT_COPY_TTL_OUT,
+	ofp13.OFPAT_DEC_MPLS_TTL,
+	ofp13.OFPAT_DEC_NW_TTL,
+	ofp13.OFPAT_SET_MPLS_TTL,
+	ofp13.OFPAT_SET_NW_TTL,
+	ofp13.OFPAT_SET_FIELD,
+	ofp13.OFPAT_SET_QUEUE,
+	ofp13.OFPAT_GROUP,
+	ofp13.OFPAT_OUTPUT,
+}
+
+/**
+ * actionSet holds at most one action of each type, and one set-field action
+ * of each field.
+ */
+type actionSet struct {
+	actions map[uint16]ofp13.OfpAction
+	fields  map[uint32]ofp13.OfpAction
+}
+
+func newActionSet() *actionSet {
+	set := new(actionSet)
+	set.clear()
+	return set
+}
+
+func (set *actionSet) clear() {
+	set.actions = make(map[uint16]ofp13.OfpAction)
+	set.fields = make(map[uint32]ofp13.OfpAction)
+}
+
+func (set *actionSet) write(actions []ofp13.OfpAction) {
+	for _, a := range actions {
+		if sf, ok := a.(*ofp13.OfpActionSetField); ok {
+			set.fields[sf.Oxm.OxmField()] = a
+			continue
+		}
+		set.actions[a.OfpActionType()] = a
+	}
+}
+
+// list returns actions in execution order. output is ignored if group
+// action is present.
+func (set *actionSet) list() []ofp13.OfpAction {
+	var actions []ofp13.OfpAction
+	for _, t := range actionSetOrder {
+		if t == ofp13.OFPAT_SET_FIELD {
+			fields := make([]int, 0, len(set.fields))
+			for f := range set.fields {
+				fields = append(fields, int(f))
+			}
+			sort.Ints(fields)
+			for _, f := range fields {
+				actions = append(actions, set.fields[uint32(f)])
+			}
+			continue
+		}
+		if t == ofp13.OFPAT_OUTPUT && set.actions[ofp13.OFPAT_GROUP] != nil {
+			continue
+		}
+		if a, ok := set.actions[t]; ok {
+			actions = append(actions, a)
+		}
+	}
+	return actions
+}
+
+/**
+ * delivery is a frame sent out from port, or a message sent to the
+ * controller. They are delivered after the switch is unlocked.
+ */
+type delivery struct {
+	portNo uint32
+	frame  []byte
+	msg    ofp13.OFMessage
+}
+
+// execution holds state of processing a packet in the switch
+type execution struct {
+	now        time.Time
+	deliveries []delivery
+	tableId    uint8
+	entry      *flowEntry // matched flow entry
+	packetOut  bool       // processing actions of PacketOut
+	depth      int        // depth of group chaining
+}
+
+/*****************************************************/
+/* Pipeline                                          */
+/*****************************************************/
+
+/**
+ * runPipeline matches packet against flow tables from tableId, executes
+ * instructions of matched entries, and then executes the action set.
+ */
+func (s *Switch) runPipeline(x *execution, p *packet, tableId uint8) {
+	entry, current := x.entry, x.tableId
+	defer func() { x.entry, x.tableId = entry, current }()
+
+	set := newActionSet()
+	for {
+		t := s.table(tableId)
+		t.lookupCount++
+		e := t.lookup(p)
+		x.tableId = tableId
+		x.entry = e
+		if e == nil {
+			if s.MissSendToController {
+				s.packetIn(x, p, ofp13.OFPR_NO_MATCH, 0xffffffffffffffff)
+			}
+			return
+		}
+		t.matchedCount++
+		e.packetCount++
+		e.byteCount += uint64(len(p.data))
+		e.lastUsed = x.now
+
+		next, ok := s.executeInstructions(x, p, e, set)
+		if !ok {
+			return
+		}
+		if next < 0 {
+			break
+		}
+		tableId = uint8(next)
+	}
+	s.applyActions(x, p, set.list())
+}
+
+/**
+ * executeInstructions executes instructions of entry in order of meter,
+ * apply-actions, clear-actions, write-actions, write-metadata and goto-table.
+ * It returns next table id or -1, and false if the packet is dropped.
+ */
+func (s *Switch) executeInstructions(x *execution, p *packet, e *flowEntry, set *actionSet) (int, bool) {
+	next := -1
+	for _, inst := range e.program {
+		switch i := inst.(type) {
+		case *ofp13.OfpInstructionMeter:
+			if !s.applyMeter(x, p, i.MeterId) {
+				return -1, false
+			}
+		case *ofp13.OfpInstructionActions:
+			switch i.Header.Type {
+			case ofp13.OFPIT_APPLY_ACTIONS:
+				if !s.applyActions(x, p, i.Actions) {
+					return -1, false
+				}
+			case ofp13.OFPIT_CLEAR_ACTIONS:
+				set.clear()
+			case ofp13.OFPIT_WRITE_ACTIONS:
+				set.write(i.Actions)
+			}
+		case *ofp13.OfpInstructionWriteMetadata:
+			p.metadata = p.metadata&^i.MetadataMask | i.Metadata&i.MetadataMask
+			p.fields[ofp13.OFPXMT_OFB_METADATA] = be64(p.metadata)
+		case *ofp13.OfpInstructionGotoTable:
+			next = int(i.TableId)
+		}
+	}
+	return next, true
+}
+
+// applyMeter returns false if the packet is dropped by the meter
+func (s *Switch) applyMeter(x *execution, p *packet, id uint32) bool {
+	m, ok := s.meters[id]
+	if !ok {
+		return true
+	}
+	switch b := m.apply(len(p.data), x.now).(type) {
+	case *ofp13.OfpMeterBandDrop:
+		return false
+	case *ofp13.OfpMeterBandDscpRemark:
+		p.remarkDscp(b.PrecLevel)
+	}
+	return true
+}
+
+/*****************************************************/
+/* Actions                                           */
+/*****************************************************/
+
+// applyActions returns false if the packet is dropped by invalid ttl
+func (s *Switch) applyActions(x *execution, p *packet, actions []ofp13.OfpAction) bool {
+	for _, a := range actions {
+		if !s.applyAction(x, p, a) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Switch) applyAction(x *execution, p *packet, action ofp13.OfpAction) bool {
+	switch a := action.(type) {
+	case *ofp13.OfpActionOutput:
+		s.output(x, p, a.Port)
+	case *ofp13.OfpActionGroup:
+		s.applyGroup(x, p, a.GroupId)
+	case *ofp13.OfpActionSetField:
+		p.setField(a.Oxm.OxmField(), a.Oxm.Serialize()[4:])
+	case *ofp13.OfpActionPush:
+		switch a.ActionHeader.Type {
+		case ofp13.OFPAT_PUSH_VLAN:
+			p.pushVlan(a.EtherType)
+		case ofp13.OFPAT_PUSH_MPLS:
+			p.pushMpls(a.EtherType)
+		}
+	case *ofp13.OfpActionPop:
+		switch a.ActionHeader.Type {
+		case ofp13.OFPAT_POP_VLAN:
+			p.popVlan()
+		case ofp13.OFPAT_POP_MPLS:
+			p.popMpls(a.EtherType)
+		}
+	case *ofp13.OfpActionSetNwTtl:
+		p.setTtl(p.ipTtlOffset(), a.NwTtl)
+	case *ofp13.OfpActionDecNwTtl:
+		return p.decTtl(p.ipTtlOffset())
+	case *ofp13.OfpActionSetMplsTtl:
+		p.setTtl(p.mplsTtlOffset(), a.MplsTtl)
+	case *ofp13.OfpActionDecMplsTtl:
+		return p.decTtl(p.mplsTtlOffset())
+	case *ofp13.OfpActionCopyTtlOut:
+		p.copyTtlOut()
+	case *ofp13.OfpActionCopyTtlIn:
+		p.copyTtlIn()
+	case *ofp13.OfpActionSetQueue:
+		p.queueId = a.QueueId
+	}
+	return true
+}
+
+/**
+ * applyGroup sends copy of packet to the buckets chosen by the group. Actions
+ * of a bucket are applied in order.
+ */
+func (s *Switch) applyGroup(x *execution, p *packet, id uint32) {
+	g, ok := s.groups[id]
+	if !ok || x.depth >= MAX_GROUP_DEPTH {
+		return
+	}
+	g.packetCount++
+	g.byteCount += uint64(len(p.data))
+
+	x.depth++
+	for _, i := range s.selectBuckets(g, p) {
+		g.bucketStats[i].PacketCount++
+		g.bucketStats[i].ByteCount += uint64(len(p.data))
+		s.applyActions(x, p.clone(), g.buckets[i].Actions)
+	}
+	x.depth--
+}
+
+func (s *Switch) output(x *execution, p *packet, portNo uint32) {
+	switch portNo {
+	case ofp13.OFPP_CONTROLLER:
+		reason := uint8(ofp13.OFPR_ACTION)
+		cookie := uint64(0xffffffffffffffff)
+		if x.entry != nil {
+			cookie = x.entry.cookie
+			if x.entry.isTableMiss() {
+				reason = ofp13.OFPR_NO_MATCH
+			}
+		}
+		s.packetIn(x, p, reason, cookie)
+	case ofp13.OFPP_IN_PORT:
+		s.transmit(x, p.inPort, p)
+	case ofp13.OFPP_ALL, ofp13.OFPP_FLOOD:
+		for _, port := range s.Ports() {
+			if port.PortNo != p.inPort {
+				s.transmit(x, port.PortNo, p)
+			}
+		}
+	case ofp13.OFPP_TABLE:
+		if x.packetOut {
+			s.runPipeline(x, p.clone(), 0)
+		}
+	default:
+		// output to in_port requires OFPP_IN_PORT
+		if portNo <= ofp13.OFPP_MAX && portNo != p.inPort {
+			s.transmit(x, portNo, p)
+		}
+	}
+}
+
+func (s *Switch) transmit(x *execution, portNo uint32, p *packet) {
+	port := s.Port(portNo)
+	if port == nil {
+		return
+	}
+	c := s.counter(portNo, x.now)
+	if port.Config&(ofp13.OFPPC_PORT_DOWN|ofp13.OFPPC_NO_FWD) != 0 || port.State&ofp13.OFPPS_LINK_DOWN != 0 {
+		c.TxDropped++
+		return
+	}
+	c.TxPackets++
+	c.TxBytes += uint64(len(p.data))
+	x.deliveries = append(x.deliveries, delivery{portNo: portNo, frame: append([]byte(nil), p.data...)})
+}
+
+/**
+ * packetIn sends whole packet to the controller, since the switch has no
+ * buffer. Match has in_port and pipeline fields of the packet.
+ */
+func (s *Switch) packetIn(x *execution, p *packet, reason uint8, cookie uint64) {
+	if port := s.Port(p.inPort); port != nil && port.Config&ofp13.OFPPC_NO_PACKET_IN != 0 {
+		return
+	}
+	msg := ofp13.NewOfpPacketIn()
+	msg.BufferId = ofp13.OFP_NO_BUFFER
+	msg.TotalLen = uint16(len(p.data))
+	msg.Reason = reason
+	msg.TableId = x.tableId
+	msg.Cookie = cookie
+	msg.Match.Append(ofp13.NewOxmInPort(p.inPort))
+	if p.inPhyPort != p.inPort {
+		msg.Match.Append(ofp13.NewOxmInPhyPort(p.inPhyPort))
+	}
+	if p.metadata != 0 {
+		msg.Match.Append(ofp13.NewOxmMetadata(p.metadata))
+	}
+	if p.tunnelId != 0 {
+		msg.Match.Append(ofp13.NewOxmTunnelId(p.tunnelId))
+	}
+	msg.Data = append([]byte(nil), p.data...)
+	x.deliveries = append(x.deliveries, delivery{msg: msg})
+}
+
+/*****************************************************/
+/* Validation                                        */
+/*****************************************************/
+
+// checkActions validates actions of flow entry, bucket or PacketOut
+func (s *Switch) checkActions(actions []ofp13.OfpAction, packetOut bool) error {
+	for _, action := range actions {
+		switch a := action.(type) {
+		case *ofp13.OfpActionOutput:
+			switch a.Port {
+			case ofp13.OFPP_IN_PORT, ofp13.OFPP_ALL, ofp13.OFPP_FLOOD, ofp13.OFPP_CONTROLLER:
+			case ofp13.OFPP_TABLE:
+				if !packetOut {
+					return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_PORT)
+				}
+			default:
+				if a.Port == 0 || a.Port > ofp13.OFPP_MAX {
+					return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_PORT)
+				}
+			}
+		case *ofp13.OfpActionGroup:
+			if _, ok := s.groups[a.GroupId]; !ok {
+				return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_GROUP)
+			}
+		case *ofp13.OfpActionSetField:
+			if a.Oxm == nil || a.Oxm.OxmClass() != ofp13.OFPXMC_OPENFLOW_BASIC || !settable(a.Oxm.OxmField()) {
+				return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_SET_TYPE)
+			}
+			if a.Oxm.OxmHasMask() != 0 {
+				return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_SET_ARGUMENT)
+			}
+		case *ofp13.OfpActionPush:
+			switch a.ActionHeader.Type {
+			case ofp13.OFPAT_PUSH_VLAN:
+				if a.EtherType != ethTypeVLAN && a.EtherType != ethTypeQinQ {
+					return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_ARGUMENT)
+				}
+			case ofp13.OFPAT_PUSH_MPLS:
+				if a.EtherType != ethTypeMPLS && a.EtherType != ethTypeMPLSMC {
+					return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_ARGUMENT)
+				}
+			default:
+				return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_TYPE)
+			}
+		case *ofp13.OfpActionPop:
+			if a.ActionHeader.Type != ofp13.OFPAT_POP_VLAN && a.ActionHeader.Type != ofp13.OFPAT_POP_MPLS {
+				return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_TYPE)
+			}
+		case *ofp13.OfpActionSetNwTtl, *ofp13.OfpActionDecNwTtl,
+			*ofp13.OfpActionSetMplsTtl, *ofp13.OfpActionDecMplsTtl,
+			*ofp13.OfpActionCopyTtlOut, *ofp13.OfpActionCopyTtlIn,
+			*ofp13.OfpActionSetQueue:
+		default:
+			return NewError(ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_TYPE)
+		}
+	}
+	return nil
+}
+
+// checkInstructions validates instructions of flow entry in table tableId
+func (s *Switch) checkInstructions(tableId uint8, instructions []ofp13.OfpInstruction) error {
+	seen := make(map[uint16]bool)
+	for _, inst := range instructions {
+		if seen[inst.InstructionType()] {
+			return NewError(ofp13.OFPET_BAD_INSTRUCTION, ofp13.OFPBIC_UNSUP_INST)
+		}
+		seen[inst.InstructionType()] = true
+
+		switch i := inst.(type) {
+		case *ofp13.OfpInstructionGotoTable:
+			if i.TableId <= tableId || i.TableId >= s.NTables {
+				return NewError(ofp13.OFPET_BAD_INSTRUCTION, ofp13.OFPBIC_BAD_TABLE_ID)
+			}
+		case *ofp13.OfpInstructionWriteMetadata:
+		case *ofp13.OfpInstructionActions:
+			switch i.Header.Type {
+			case ofp13.OFPIT_APPLY_ACTIONS, ofp13.OFPIT_WRITE_ACTIONS:
+				if err := s.checkActions(i.Actions, false); err != nil {
+					return err
+				}
+			case ofp13.OFPIT_CLEAR_ACTIONS:
+			default:
+				return NewError(ofp13.OFPET_BAD_INSTRUCTION, ofp13.OFPBIC_UNKNOWN_INST)
+			}
+		case *ofp13.OfpInstructionMeter:
+			if _, ok := s.meters[i.MeterId]; !ok {
+				return NewError(ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_UNKNOWN_METER)
+			}
+		default:
+			return NewError(ofp13.OFPET_BAD_INSTRUCTION, ofp13.OFPBIC_UNSUP_INST)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,583 @@
+package ofswitch
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+// if true, packets which match no flow entry are sent to the controller.
+// OpenFlow 1.3 drops them unless a table-miss flow entry is installed.
+var DEFAULT_MISS_SEND_TO_CONTROLLER = true
+
+// interval to check timeouts of flow entries
+var EXPIRE_INTERVAL = time.Second
+
+/**
+ * Switch is an in-memory OpenFlow 1.3 switch. It is the backend of its
+ * embedded Agent, and maintains flow tables, groups and meters modified by
+ * the controller.
+ * Frames injected by Receive run through the multi-table pipeline
+ * synchronously, and frames sent out from ports are passed to Transmit.
+ * Counters are updated and answered by stats requests, PacketIn is sent on
+ * table miss and output to controller, and FlowRemoved on timeout and delete.
+ */
+type Switch struct {
+	*Agent
+	// Transmit is called with frames sent out from port. It is called after
+	// the switch is unlocked, so it may inject frames to another switch.
+	Transmit func(portNo uint32, frame []byte)
+	// send packets which match no flow entry to the controller
+	MissSendToController bool
+	// Now returns current time, used for timeouts, durations and meters.
+	// It can be replaced by fake clock for tests.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	tables   [ofp13.OFPTT_ALL]*flowTable
+	groups   map[uint32]*group
+	meters   map[uint32]*meter
+	counters map[uint32]*portCounter
+}
+
+type portCounter struct {
+	ofp13.OfpPortStats
+	created time.Time
+}
+
+/**
+ * ctor
+ */
+func NewSwitch(dpid uint64, ports []*ofp13.OfpPort) *Switch {
+	s := new(Switch)
+	s.Agent = NewAgent(dpid, ports, s)
+	s.MissSendToController = DEFAULT_MISS_SEND_TO_CONTROLLER
+	s.Now = time.Now
+	s.groups = make(map[uint32]*group)
+	s.meters = make(map[uint32]*meter)
+	s.counters = make(map[uint32]*portCounter)
+	return s
+}
+
+/**
+ * Connect connects to controller at addr like Agent.Connect, and serves the
+ * connection in background.
+ */
+func (s *Switch) Connect(addr string) error {
+	conn, err := dial(addr)
+	if err != nil {
+		return err
+	}
+	go s.Serve(conn)
+	return nil
+}
+
+/**
+ * Serve runs the agent on conn like Agent.Serve. Flow timeouts are checked
+ * at EXPIRE_INTERVAL while serving.
+ */
+func (s *Switch) Serve(conn net.Conn) error {
+	go s.expireLoop()
+	return s.Agent.Serve(conn)
+}
+
+func (s *Switch) expireLoop() {
+	ticker := time.NewTicker(EXPIRE_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.Done():
+			return
+		case <-ticker.C:
+			s.ExpireFlows()
+		}
+	}
+}
+
+/**
+ * Receive injects frame received on port into the pipeline. Frames output
+ * by the pipeline are passed to Transmit, and messages are sent to the
+ * controller before Receive returns.
+ */
+func (s *Switch) Receive(portNo uint32, frame []byte) {
+	s.mu.Lock()
+	x := &execution{now: s.Now()}
+	if port := s.Port(portNo); port != nil {
+		c := s.counter(portNo, x.now)
+		if port.Config&(ofp13.OFPPC_PORT_DOWN|ofp13.OFPPC_NO_RECV) != 0 || port.State&ofp13.OFPPS_LINK_DOWN != 0 {
+			c.RxDropped++
+		} else {
+			c.RxPackets++
+			c.RxBytes += uint64(len(frame))
+			s.runPipeline(x, newPacket(frame, portNo), 0)
+		}
+	}
+	s.mu.Unlock()
+
+	s.deliver(x.deliveries)
+}
+
+func (s *Switch) deliver(deliveries []delivery) {
+	for _, d := range deliveries {
+		if d.msg != nil {
+			s.sendAsync(d.msg)
+		} else if s.Transmit != nil {
+			s.Transmit(d.portNo, d.frame)
+		}
+	}
+}
+
+func (s *Switch) sendAsync(msgs ...ofp13.OFMessage) {
+	for _, msg := range msgs {
+		if err := s.SendAsync(msg); err != nil && err != ErrAgentClosed {
+			fmt.Println(err)
+		}
+	}
+}
+
+/**
+ * ExpireFlows removes flow entries whose idle or hard timeout is expired,
+ * and sends FlowRemoved for them.
+ */
+func (s *Switch) ExpireFlows() {
+	s.mu.Lock()
+	now := s.Now()
+	var msgs []ofp13.OFMessage
+	for _, t := range s.tables {
+		if t == nil {
+			continue
+		}
+		for _, e := range append([]*flowEntry(nil), t.entries...) {
+			var reason uint8
+			if e.hardTimeout > 0 && now.Sub(e.created) >= time.Duration(e.hardTimeout)*time.Second {
+				reason = ofp13.OFPRR_HARD_TIMEOUT
+			} else if e.idleTimeout > 0 && now.Sub(e.lastUsed) >= time.Duration(e.idleTimeout)*time.Second {
+				reason = ofp13.OFPRR_IDLE_TIMEOUT
+			} else {
+				continue
+			}
+			t.remove(e)
+			if msg := e.flowRemoved(reason, now); msg != nil {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	s.sendAsync(msgs...)
+}
+
+/*****************************************************/
+/* Tables                                            */
+/*****************************************************/
+func (s *Switch) table(id uint8) *flowTable {
+	if s.tables[id] == nil {
+		s.tables[id] = new(flowTable)
+	}
+	return s.tables[id]
+}
+
+func (s *Switch) forEachFlow(fn func(e *flowEntry)) {
+	for _, t := range s.tables {
+		if t == nil {
+			continue
+		}
+		for _, e := range t.entries {
+			fn(e)
+		}
+	}
+}
+
+// removeFlows removes flow entries selected by fn and returns FlowRemoved
+// messages for them.
+func (s *Switch) removeFlows(fn func(e *flowEntry) bool, reason uint8, now time.Time) []ofp13.OFMessage {
+	var msgs []ofp13.OFMessage
+	for _, t := range s.tables {
+		if t == nil {
+			continue
+		}
+		for _, e := range append([]*flowEntry(nil), t.entries...) {
+			if !fn(e) {
+				continue
+			}
+			t.remove(e)
+			if msg := e.flowRemoved(reason, now); msg != nil {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	return msgs
+}
+
+func (s *Switch) groupIds() []uint32 {
+	ids := make([]uint32, 0, len(s.groups))
+	for id := range s.groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (s *Switch) meterIds() []uint32 {
+	ids := make([]uint32, 0, len(s.meters))
+	for id := range s.meters {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (s *Switch) counter(portNo uint32, now time.Time) *portCounter {
+	c, ok := s.counters[portNo]
+	if !ok {
+		c = &portCounter{created: now}
+		c.PortNo = portNo
+		s.counters[portNo] = c
+	}
+	return c
+}
+
+/*****************************************************/
+/* Flow Mod                                          */
+/*****************************************************/
+func (s *Switch) flowMod(msg *ofp13.OfpFlowMod, now time.Time) ([]ofp13.OFMessage, error) {
+	matchers, err := compileMatch(msg.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Command {
+	case ofp13.OFPFC_ADD:
+		return nil, s.addFlow(msg, matchers, now)
+	case ofp13.OFPFC_MODIFY, ofp13.OFPFC_MODIFY_STRICT:
+		return nil, s.modifyFlows(msg, matchers)
+	case ofp13.OFPFC_DELETE, ofp13.OFPFC_DELETE_STRICT:
+		if msg.TableId >= s.NTables && msg.TableId != ofp13.OFPTT_ALL {
+			return nil, NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_BAD_TABLE_ID)
+		}
+		f := &flowFilter{
+			tableId:    msg.TableId,
+			strict:     msg.Command == ofp13.OFPFC_DELETE_STRICT,
+			priority:   msg.Priority,
+			matchers:   matchers,
+			outPort:    msg.OutPort,
+			outGroup:   msg.OutGroup,
+			cookie:     msg.Cookie,
+			cookieMask: msg.CookieMask,
+		}
+		return s.removeFlows(f.selects, ofp13.OFPRR_DELETE, now), nil
+	}
+	return nil, NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_BAD_COMMAND)
+}
+
+func (s *Switch) addFlow(msg *ofp13.OfpFlowMod, matchers []matcher, now time.Time) error {
+	if msg.TableId >= s.NTables {
+		return NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_BAD_TABLE_ID)
+	}
+	if msg.BufferId != ofp13.OFP_NO_BUFFER {
+		return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BUFFER_UNKNOWN)
+	}
+	if err := s.checkInstructions(msg.TableId, msg.Instructions); err != nil {
+		return err
+	}
+
+	t := s.table(msg.TableId)
+	if msg.Flags&ofp13.OFPFF_CHECK_OVERLAP != 0 {
+		for _, e := range t.entries {
+			if e.priority == msg.Priority && e.overlaps(matchers) {
+				return NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_OVERLAP)
+			}
+		}
+	}
+
+	entry := &flowEntry{
+		tableId:     msg.TableId,
+		priority:    msg.Priority,
+		cookie:      msg.Cookie,
+		flags:       msg.Flags,
+		idleTimeout: msg.IdleTimeout,
+		hardTimeout: msg.HardTimeout,
+		match:       msg.Match,
+		matchers:    matchers,
+		created:     now,
+		lastUsed:    now,
+	}
+	if entry.match == nil {
+		entry.match = ofp13.NewOfpMatch()
+	}
+	entry.setInstructions(msg.Instructions)
+
+	// identical flow entry is replaced
+	if old := t.find(msg.Priority, matchers); old != nil {
+		if msg.Flags&ofp13.OFPFF_RESET_COUNTS == 0 {
+			entry.packetCount = old.packetCount
+			entry.byteCount = old.byteCount
+		}
+		t.replace(old, entry)
+		return nil
+	}
+	if uint32(len(t.entries)) >= DEFAULT_MAX_ENTRIES {
+		return NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_TABLE_FULL)
+	}
+	t.insert(entry)
+	return nil
+}
+
+// modifyFlows replaces instructions of selected flow entries. Timeouts,
+// flags and cookie are kept.
+func (s *Switch) modifyFlows(msg *ofp13.OfpFlowMod, matchers []matcher) error {
+	if msg.TableId >= s.NTables {
+		return NewError(ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_BAD_TABLE_ID)
+	}
+	if msg.BufferId != ofp13.OFP_NO_BUFFER {
+		return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BUFFER_UNKNOWN)
+	}
+	if err := s.checkInstructions(msg.TableId, msg.Instructions); err != nil {
+		return err
+	}
+	f := &flowFilter{
+		tableId:    msg.TableId,
+		strict:     msg.Command == ofp13.OFPFC_MODIFY_STRICT,
+		priority:   msg.Priority,
+		matchers:   matchers,
+		outPort:    ofp13.OFPP_ANY,
+		outGroup:   ofp13.OFPG_ANY,
+		cookie:     msg.Cookie,
+		cookieMask: msg.CookieMask,
+	}
+	s.forEachFlow(func(e *flowEntry) {
+		if !f.selects(e) {
+			return
+		}
+		e.setInstructions(msg.Instructions)
+		if msg.Flags&ofp13.OFPFF_RESET_COUNTS != 0 {
+			e.packetCount = 0
+			e.byteCount = 0
+		}
+	})
+	return nil
+}
+
+/*****************************************************/
+/* Backend                                           */
+/*****************************************************/
+func (s *Switch) HandleFlowMod(msg *ofp13.OfpFlowMod, agent *Agent) error {
+	s.mu.Lock()
+	msgs, err := s.flowMod(msg, s.Now())
+	s.mu.Unlock()
+	s.sendAsync(msgs...)
+	return err
+}
+
+func (s *Switch) HandleGroupMod(msg *ofp13.OfpGroupMod, agent *Agent) error {
+	s.mu.Lock()
+	msgs, err := s.groupMod(msg, s.Now())
+	s.mu.Unlock()
+	s.sendAsync(msgs...)
+	return err
+}
+
+func (s *Switch) HandleMeterMod(msg *ofp13.OfpMeterMod, agent *Agent) error {
+	s.mu.Lock()
+	msgs, err := s.meterMod(msg, s.Now())
+	s.mu.Unlock()
+	s.sendAsync(msgs...)
+	return err
+}
+
+/**
+ * HandlePacketOut applies actions to the packet. Output to OFPP_TABLE runs
+ * the packet through the pipeline.
+ */
+func (s *Switch) HandlePacketOut(msg *ofp13.OfpPacketOut, agent *Agent) error {
+	if msg.BufferId != ofp13.OFP_NO_BUFFER {
+		return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BUFFER_UNKNOWN)
+	}
+	if msg.InPort > ofp13.OFPP_MAX && msg.InPort != ofp13.OFPP_CONTROLLER {
+		return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_PORT)
+	}
+
+	s.mu.Lock()
+	err := s.checkActions(msg.Actions, true)
+	x := &execution{now: s.Now(), packetOut: true}
+	if err == nil {
+		s.applyActions(x, newPacket(msg.Data, msg.InPort), msg.Actions)
+	}
+	s.mu.Unlock()
+
+	s.deliver(x.deliveries)
+	return err
+}
+
+/**
+ * HandleMessage handles PortMod, TableMod and QueueGetConfigRequest.
+ */
+func (s *Switch) HandleMessage(msg ofp13.OFMessage, agent *Agent) error {
+	switch m := msg.(type) {
+	case *ofp13.OfpPortMod:
+		port := s.Port(m.PortNo)
+		if port == nil {
+			return NewError(ofp13.OFPET_PORT_MOD_FAILED, ofp13.OFPPMFC_BAD_PORT)
+		}
+		if !bytes.Equal(port.HwAddr, m.HwAddr) {
+			return NewError(ofp13.OFPET_PORT_MOD_FAILED, ofp13.OFPPMFC_BAD_HW_ADDR)
+		}
+		modified := *port
+		modified.Config = port.Config&^m.Mask | m.Config&m.Mask
+		return s.ModifyPort(&modified)
+
+	case *ofp13.OfpTableMod:
+		if m.TableId >= s.NTables && m.TableId != ofp13.OFPTT_ALL {
+			return NewError(ofp13.OFPET_TABLE_MOD_FAILED, ofp13.OFPTMFC_BAD_TABLE)
+		}
+		s.mu.Lock()
+		for id := 0; id < int(s.NTables); id++ {
+			if m.TableId == ofp13.OFPTT_ALL || m.TableId == uint8(id) {
+				s.table(uint8(id)).config = m.Config
+			}
+		}
+		s.mu.Unlock()
+		return nil
+
+	case *ofp13.OfpQueueGetConfigRequest:
+		// no queue is configured
+		reply := ofp13.NewOfpQueueGetConfigReply()
+		reply.Header.Xid = m.Header.Xid
+		reply.Port = m.Port
+		return agent.Send(reply)
+	}
+	return NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_TYPE)
+}
+
+/*****************************************************/
+/* Stats                                             */
+/*****************************************************/
+func (s *Switch) HandleMultipartRequest(msg *ofp13.OfpMultipartRequest, agent *Agent) ([]ofp13.OfpMultipartBody, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	badLen := NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_LEN)
+
+	switch msg.Type {
+	case ofp13.OFPMP_FLOW:
+		req, ok := msg.Body.(*ofp13.OfpFlowStatsRequest)
+		if !ok {
+			return nil, badLen
+		}
+		f, err := newStatsFilter(req.TableId, req.OutPort, req.OutGroup, req.Cookie, req.CookieMask, req.Match)
+		if err != nil {
+			return nil, err
+		}
+		var bodies []ofp13.OfpMultipartBody
+		s.forEachFlow(func(e *flowEntry) {
+			if f.selects(e) {
+				bodies = append(bodies, e.stats(now))
+			}
+		})
+		return bodies, nil
+
+	case ofp13.OFPMP_AGGREGATE:
+		req, ok := msg.Body.(*ofp13.OfpAggregateStatsRequest)
+		if !ok {
+			return nil, badLen
+		}
+		f, err := newStatsFilter(req.TableId, req.OutPort, req.OutGroup, req.Cookie, req.CookieMask, req.Match)
+		if err != nil {
+			return nil, err
+		}
+		stats := new(ofp13.OfpAggregateStats)
+		s.forEachFlow(func(e *flowEntry) {
+			if f.selects(e) {
+				stats.PacketCount += e.packetCount
+				stats.ByteCount += e.byteCount
+				stats.FlowCount++
+			}
+		})
+		return []ofp13.OfpMultipartBody{stats}, nil
+
+	case ofp13.OFPMP_TABLE:
+		var bodies []ofp13.OfpMultipartBody
+		for id := 0; id < int(s.NTables); id++ {
+			stats := &ofp13.OfpTableStats{TableId: uint8(id)}
+			if t := s.tables[id]; t != nil {
+				stats.ActiveCount = uint32(len(t.entries))
+				stats.LookupCount = t.lookupCount
+				stats.MatchedCount = t.matchedCount
+			}
+			bodies = append(bodies, stats)
+		}
+		return bodies, nil
+
+	case ofp13.OFPMP_PORT_STATS:
+		req, ok := msg.Body.(*ofp13.OfpPortStatsRequest)
+		if !ok {
+			return nil, badLen
+		}
+		var bodies []ofp13.OfpMultipartBody
+		for _, port := range s.Ports() {
+			if req.PortNo != ofp13.OFPP_ANY && req.PortNo != port.PortNo {
+				continue
+			}
+			c := s.counter(port.PortNo, now)
+			stats := c.OfpPortStats
+			stats.DurationSec, stats.DurationNSec = duration(now.Sub(c.created))
+			bodies = append(bodies, &stats)
+		}
+		if req.PortNo != ofp13.OFPP_ANY && len(bodies) == 0 {
+			return nil, NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_PORT)
+		}
+		return bodies, nil
+
+	case ofp13.OFPMP_QUEUE:
+		// no queue is configured
+		return nil, nil
+
+	case ofp13.OFPMP_GROUP:
+		req, ok := msg.Body.(*ofp13.OfpGroupStatsRequest)
+		if !ok {
+			return nil, badLen
+		}
+		return s.groupStats(req.GroupId, now), nil
+
+	case ofp13.OFPMP_GROUP_DESC:
+		return s.groupDesc(), nil
+
+	case ofp13.OFPMP_GROUP_FEATURES:
+		return []ofp13.OfpMultipartBody{s.groupFeatures()}, nil
+
+	case ofp13.OFPMP_METER, ofp13.OFPMP_METER_CONFIG:
+		req, ok := msg.Body.(*ofp13.OfpMeterMultipartRequest)
+		if !ok {
+			return nil, badLen
+		}
+		if msg.Type == ofp13.OFPMP_METER {
+			return s.meterStats(req.MeterId, now), nil
+		}
+		return s.meterConfig(req.MeterId), nil
+
+	case ofp13.OFPMP_METER_FEATURES:
+		return []ofp13.OfpMultipartBody{meterFeatures()}, nil
+	}
+	return nil, NewError(ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_MULTIPART)
+}
+
+// filter of flow and aggregate stats request
+func newStatsFilter(tableId uint8, outPort uint32, outGroup uint32, cookie uint64, cookieMask uint64, match *ofp13.OfpMatch) (*flowFilter, error) {
+	matchers, err := compileMatch(match)
+	if err != nil {
+		return nil, err
+	}
+	return &flowFilter{
+		tableId:    tableId,
+		matchers:   matchers,
+		outPort:    outPort,
+		outGroup:   outGroup,
+		cookie:     cookie,
+		cookieMask: cookieMask,
+	}, nil
+}
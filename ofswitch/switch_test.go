@@ -0,0 +1,691 @@
+package ofswitch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+	pkt "github.com/Kmotiko/gofc/packet"
+)
+
+// frame sent out from port
+type testFrame struct {
+	portNo uint32
+	frame  []byte
+}
+
+// switch with four ports, fake clock and recorder of transmitted frames
+func newTestSwitch(t *testing.T) (*Switch, *time.Time, *[]testFrame) {
+	ports := make([]*ofp13.OfpPort, 0, 4)
+	for i := 1; i <= 4; i++ {
+		port, err := ofp13.NewOfpPort(uint32(i), "00:00:00:00:00:0"+string(rune('0'+i)), "eth"+string(rune('0'+i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ports = append(ports, port)
+	}
+	s := NewSwitch(0x0102, ports)
+
+	now := time.Unix(1000, 0)
+	s.Now = func() time.Time { return now }
+	frames := make([]testFrame, 0)
+	s.Transmit = func(portNo uint32, frame []byte) {
+		frames = append(frames, testFrame{portNo, frame})
+	}
+	return s, &now, &frames
+}
+
+// udp over ipv4 frame with valid checksums
+func newTestUdpFrame(ethSrc string, ethDst string, ipSrc string, ipDst string, dport uint16) []byte {
+	frame := make([]byte, 14+20+8+4)
+	src, _ := net.ParseMAC(ethSrc)
+	dst, _ := net.ParseMAC(ethDst)
+	copy(frame[0:], dst)
+	copy(frame[6:], src)
+	binary.BigEndian.PutUint16(frame[12:], ethTypeIPv4)
+
+	ip := frame[14:]
+	ip[0] = 0x45
+	ip[1] = 10 << 2 // AF11
+	binary.BigEndian.PutUint16(ip[2:], 20+8+4)
+	ip[8] = 64
+	ip[9] = ipProtoUDP
+	copy(ip[12:], net.ParseIP(ipSrc).To4())
+	copy(ip[16:], net.ParseIP(ipDst).To4())
+
+	udp := ip[20:]
+	binary.BigEndian.PutUint16(udp[0:], 10000)
+	binary.BigEndian.PutUint16(udp[2:], dport)
+	binary.BigEndian.PutUint16(udp[4:], 8+4)
+	binary.BigEndian.PutUint16(udp[6:], 0xffff)
+	copy(udp[8:], "test")
+
+	p := newPacket(frame, 0)
+	p.updateChecksums()
+	return p.data
+}
+
+// checksums of frame are not changed by recomputing
+func validChecksums(frame []byte) bool {
+	p := newPacket(frame, 0)
+	p.updateChecksums()
+	return bytes.Equal(p.data, frame)
+}
+
+func applyActions(actions ...ofp13.OfpAction) *ofp13.OfpInstructionActions {
+	inst := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+	for _, a := range actions {
+		inst.Append(a)
+	}
+	return inst
+}
+
+func writeActions(actions ...ofp13.OfpAction) *ofp13.OfpInstructionActions {
+	inst := ofp13.NewOfpInstructionActions(ofp13.OFPIT_WRITE_ACTIONS)
+	for _, a := range actions {
+		inst.Append(a)
+	}
+	return inst
+}
+
+func newTestMatch(fields ...ofp13.OxmField) *ofp13.OfpMatch {
+	match := ofp13.NewOfpMatch()
+	for _, f := range fields {
+		match.Append(f)
+	}
+	return match
+}
+
+func addFlow(t *testing.T, s *Switch, tableId uint8, priority uint16, match *ofp13.OfpMatch, instructions ...ofp13.OfpInstruction) {
+	fm := ofp13.NewOfpFlowModAdd(0, 0, tableId, priority, 0, match, instructions)
+	if err := s.HandleFlowMod(fm, s.Agent); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectError(t *testing.T, err error, errType uint16, code uint16) {
+	if e, ok := err.(*Error); !ok || e.Type != errType || e.Code != code {
+		t.Log("Actual error is : ", err)
+		t.Errorf("Error type %d, code %d is expected.", errType, code)
+	}
+}
+
+func expectPorts(t *testing.T, frames *[]testFrame, ports ...uint32) {
+	actual := make([]uint32, 0, len(*frames))
+	for _, f := range *frames {
+		actual = append(actual, f.portNo)
+	}
+	*frames = (*frames)[:0]
+	if len(actual) != len(ports) {
+		t.Log("Actual ports are : ", actual)
+		t.Errorf("Frames are expected to be sent to %v.", ports)
+		return
+	}
+	for i := range ports {
+		if actual[i] != ports[i] {
+			t.Log("Actual ports are : ", actual)
+			t.Errorf("Frames are expected to be sent to %v.", ports)
+			return
+		}
+	}
+}
+
+func stats(t *testing.T, s *Switch, req *ofp13.OfpMultipartRequest) []ofp13.OfpMultipartBody {
+	bodies, err := s.HandleMultipartRequest(req, s.Agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bodies
+}
+
+/*****************************************************/
+/* Pipeline                                          */
+/*****************************************************/
+func TestSwitchPipeline(t *testing.T) {
+	s, _, frames := newTestSwitch(t)
+	s.MissSendToController = false
+
+	// table 0 writes metadata and rewrites destination, then goes to table 1
+	ethDst, _ := ofp13.NewOxmEthDst("00:00:00:00:00:99")
+	addFlow(t, s, 0, 10, newTestMatch(ofp13.NewOxmInPort(1)),
+		ofp13.NewOfpInstructionWriteMetadata(0x5, 0xff),
+		applyActions(ofp13.NewOfpActionSetField(ethDst)),
+		ofp13.NewOfpInstructionGotoTable(1))
+
+	// table 1 tags vlan, and outputs by action set
+	addFlow(t, s, 1, 10, newTestMatch(
+		ofp13.NewOxmMetadata(0x5),
+		ofp13.NewOxmEthType(0x0800),
+		ofp13.NewOxmIpProto(17),
+		ofp13.NewOxmUdpDst(53)),
+		applyActions(
+			ofp13.NewOfpActionPushVlan(),
+			ofp13.NewOfpActionSetField(ofp13.NewOxmVlanVid(ofp13.OFPVID_PRESENT|10))),
+		writeActions(ofp13.NewOfpActionOutput(2, 0), ofp13.NewOfpActionDecNwTtl()))
+
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+	s.Receive(1, frame)
+	if len(*frames) != 1 || (*frames)[0].portNo != 2 {
+		t.Log("Actual frames are : ", *frames)
+		t.Fatal("Frame is not sent to port 2.")
+	}
+	out := (*frames)[0].frame
+	*frames = (*frames)[:0]
+	if !bytes.Equal(out[0:6], []byte{0, 0, 0, 0, 0, 0x99}) {
+		t.Error("Destination is not rewritten.")
+	}
+	if binary.BigEndian.Uint16(out[12:]) != ethTypeVLAN || binary.BigEndian.Uint16(out[14:])&0x0fff != 10 {
+		t.Error("Vlan tag is not pushed.")
+	}
+	if out[18+8] != 63 {
+		t.Error("TTL is not decremented.")
+	}
+	if !validChecksums(out) {
+		t.Error("Checksums are not updated.")
+	}
+	if !bytes.Equal(out[18+20:], frame[14+20:]) {
+		t.Error("UDP segment is modified.")
+	}
+
+	// frames from other port and to other udp port are dropped
+	s.Receive(2, frame)
+	s.Receive(1, newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 80))
+	expectPorts(t, frames)
+
+	flows := stats(t, s, ofp13.NewOfpFlowStatsRequest(0, ofp13.OFPTT_ALL, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch()))
+	if len(flows) != 2 {
+		t.Fatalf("%d flow stats are replied, expected 2.", len(flows))
+	}
+	if f := flows[0].(*ofp13.OfpFlowStats); f.TableId != 0 || f.PacketCount != 2 || f.ByteCount != uint64(2*len(frame)) {
+		t.Log("Actual stats is : ", f)
+		t.Error("Flow stats of table 0 is invalid.")
+	}
+	if f := flows[1].(*ofp13.OfpFlowStats); f.TableId != 1 || f.PacketCount != 1 {
+		t.Log("Actual stats is : ", f)
+		t.Error("Flow stats of table 1 is invalid.")
+	}
+
+	tables := stats(t, s, ofp13.NewOfpTableStatsRequest(0))
+	if len(tables) != int(s.NTables) {
+		t.Fatalf("%d table stats are replied, expected %d.", len(tables), s.NTables)
+	}
+	if ts := tables[0].(*ofp13.OfpTableStats); ts.ActiveCount != 1 || ts.LookupCount != 3 || ts.MatchedCount != 2 {
+		t.Log("Actual stats is : ", ts)
+		t.Error("Table stats is invalid.")
+	}
+
+	ports := stats(t, s, ofp13.NewOfpPortStatsRequest(2, 0))
+	if ps := ports[0].(*ofp13.OfpPortStats); len(ports) != 1 || ps.RxPackets != 1 || ps.TxPackets != 1 {
+		t.Log("Actual stats is : ", ps)
+		t.Error("Port stats is invalid.")
+	}
+	_, err := s.HandleMultipartRequest(ofp13.NewOfpPortStatsRequest(10, 0), s.Agent)
+	expectError(t, err, ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BAD_PORT)
+}
+
+func TestPacketModification(t *testing.T) {
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+
+	p := newPacket(frame, 1)
+	p.pushVlan(ethTypeVLAN)
+	p.setField(ofp13.OFPXMT_OFB_VLAN_PCP, []byte{5})
+	if len(p.data) != len(frame)+4 || p.fields[ofp13.OFPXMT_OFB_VLAN_PCP][0] != 5 {
+		t.Error("Vlan tag is not pushed.")
+	}
+	p.popVlan()
+	if !bytes.Equal(p.data, frame) {
+		t.Error("Frame is not restored by pop_vlan.")
+	}
+
+	p.pushMpls(ethTypeMPLS)
+	if p.fields[ofp13.OFPXMT_OFB_MPLS_BOS][0] != 1 || p.data[14+3] != 64 {
+		t.Error("MPLS shim is not pushed with TTL of ip header.")
+	}
+	p.popMpls(ethTypeIPv4)
+	if !bytes.Equal(p.data, frame) {
+		t.Error("Frame is not restored by pop_mpls.")
+	}
+
+	p.setField(ofp13.OFPXMT_OFB_IPV4_DST, []byte{192, 168, 0, 1})
+	p.setField(ofp13.OFPXMT_OFB_UDP_DST, be16(5353))
+	if !validChecksums(p.data) || !bytes.Equal(p.fields[ofp13.OFPXMT_OFB_IPV4_DST], []byte{192, 168, 0, 1}) {
+		t.Error("Fields are not set with valid checksums.")
+	}
+
+	// AF11 is remarked to AF12
+	p.remarkDscp(1)
+	if p.fields[ofp13.OFPXMT_OFB_IP_DSCP][0] != 12 {
+		t.Log("Actual dscp is : ", p.fields[ofp13.OFPXMT_OFB_IP_DSCP][0])
+		t.Error("DSCP is not remarked.")
+	}
+}
+
+/*****************************************************/
+/* PacketIn                                          */
+/*****************************************************/
+func TestSwitchPacketIn(t *testing.T) {
+	s, _, _ := newTestSwitch(t)
+	c := newTestController(t, s.Serve)
+	defer s.Close()
+	c.send(ofp13.NewOfpHello())
+
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+	recvPacketIn := func(inPort uint32) *ofp13.OfpPacketIn {
+		go s.Receive(inPort, frame)
+		pin, ok := c.recv().(*ofp13.OfpPacketIn)
+		if !ok {
+			t.Fatal("PacketIn is not sent.")
+		}
+		if !bytes.Equal(pin.GetData(), frame) || pin.BufferId != ofp13.OFP_NO_BUFFER {
+			t.Error("Data of PacketIn is invalid.")
+		}
+		return pin
+	}
+	flowMod := func(fm *ofp13.OfpFlowMod) {
+		c.send(fm)
+		barrier := ofp13.NewOfpBarrierRequest()
+		c.send(barrier)
+		if h, ok := c.recv().(*ofp13.OfpHeader); !ok || h.Type != ofp13.OFPT_BARRIER_REPLY {
+			t.Fatal("FlowMod is failed.")
+		}
+	}
+
+	// no flow entry
+	pin := recvPacketIn(1)
+	if pin.Reason != ofp13.OFPR_NO_MATCH || pin.TableId != 0 || pin.Cookie != 0xffffffffffffffff {
+		t.Log("Actual message is : ", pin)
+		t.Error("PacketIn on table miss is invalid.")
+	}
+
+	// table-miss flow entry
+	flowMod(ofp13.NewOfpFlowModAdd(7, 0, 0, 0, 0, ofp13.NewOfpMatch(),
+		[]ofp13.OfpInstruction{applyActions(ofp13.NewOfpActionOutput(ofp13.OFPP_CONTROLLER, ofp13.OFPCML_NO_BUFFER))}))
+	pin = recvPacketIn(2)
+	if pin.Reason != ofp13.OFPR_NO_MATCH || pin.Cookie != 7 {
+		t.Log("Actual message is : ", pin)
+		t.Error("PacketIn by table-miss flow entry is invalid.")
+	}
+
+	// output to controller in table 1
+	flowMod(ofp13.NewOfpFlowModAdd(8, 0, 0, 10, 0, newTestMatch(ofp13.NewOxmInPort(1)),
+		[]ofp13.OfpInstruction{
+			ofp13.NewOfpInstructionWriteMetadata(0x42, 0xffffffffffffffff),
+			ofp13.NewOfpInstructionGotoTable(1),
+		}))
+	flowMod(ofp13.NewOfpFlowModAdd(9, 0, 1, 10, 0, ofp13.NewOfpMatch(),
+		[]ofp13.OfpInstruction{applyActions(ofp13.NewOfpActionOutput(ofp13.OFPP_CONTROLLER, ofp13.OFPCML_NO_BUFFER))}))
+	pin = recvPacketIn(1)
+	if pin.Reason != ofp13.OFPR_ACTION || pin.TableId != 1 || pin.Cookie != 9 {
+		t.Log("Actual message is : ", pin)
+		t.Error("PacketIn by action is invalid.")
+	}
+	var inPort, metadata bool
+	for _, f := range pin.GetMatch().OxmFields {
+		switch v := f.(type) {
+		case *ofp13.OxmInPort:
+			inPort = v.Value == 1
+		case *ofp13.OxmMetadata:
+			metadata = v.Value == 0x42
+		}
+	}
+	if !inPort || !metadata {
+		t.Log("Actual match is : ", pin.GetMatch().OxmFields)
+		t.Error("Match of PacketIn does not have pipeline fields.")
+	}
+}
+
+/*****************************************************/
+/* Group                                             */
+/*****************************************************/
+func TestSwitchGroup(t *testing.T) {
+	s, _, frames := newTestSwitch(t)
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+
+	addGroup := func(id uint32, groupType uint8, buckets ...*ofp13.OfpBucket) error {
+		gm := ofp13.NewOfpGroupMod(ofp13.OFPGC_ADD, groupType, id)
+		for _, b := range buckets {
+			gm.Append(b)
+		}
+		return s.HandleGroupMod(gm, s.Agent)
+	}
+	bucket := func(weight uint16, watchPort uint32, action ofp13.OfpAction) *ofp13.OfpBucket {
+		b := ofp13.NewOfpBucket(weight, watchPort, ofp13.OFPG_ANY)
+		b.Append(action)
+		return b
+	}
+	toGroup := func(id uint32) {
+		fm := ofp13.NewOfpFlowModModify(0, 0, 0, 10, 0, newTestMatch(ofp13.NewOxmInPort(1)),
+			[]ofp13.OfpInstruction{applyActions(ofp13.NewOfpActionGroup(id))})
+		fm.Command = ofp13.OFPFC_MODIFY_STRICT
+		if err := s.HandleFlowMod(fm, s.Agent); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := addGroup(1, ofp13.OFPGT_ALL,
+		bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionOutput(2, 0)),
+		bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionOutput(3, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if err := addGroup(2, ofp13.OFPGT_FF,
+		bucket(0, 2, ofp13.NewOfpActionOutput(2, 0)),
+		bucket(0, 3, ofp13.NewOfpActionOutput(3, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if err := addGroup(3, ofp13.OFPGT_SELECT,
+		bucket(1, 2, ofp13.NewOfpActionOutput(2, 0)),
+		bucket(1, 3, ofp13.NewOfpActionOutput(3, 0))); err != nil {
+		t.Fatal(err)
+	}
+	if err := addGroup(4, ofp13.OFPGT_INDIRECT, bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionGroup(1))); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, addGroup(1, ofp13.OFPGT_ALL), ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_GROUP_EXISTS)
+	expectError(t, addGroup(5, ofp13.OFPGT_FF, bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionOutput(2, 0))),
+		ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_BAD_WATCH)
+	expectError(t, addGroup(5, ofp13.OFPGT_ALL, bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionGroup(6))),
+		ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_GROUP)
+
+	addFlow(t, s, 0, 10, newTestMatch(ofp13.NewOxmInPort(1)), applyActions(ofp13.NewOfpActionGroup(1)))
+	s.Receive(1, frame)
+	expectPorts(t, frames, 2, 3)
+
+	// fast failover to port 3 when port 2 is down
+	toGroup(2)
+	s.Receive(1, frame)
+	expectPorts(t, frames, 2)
+	portMod, _ := ofp13.NewOfpPortMod(2, "00:00:00:00:00:02", ofp13.OFPPC_PORT_DOWN, ofp13.OFPPC_PORT_DOWN, 0)
+	if err := s.HandleMessage(portMod, s.Agent); err != nil {
+		t.Fatal(err)
+	}
+	s.Receive(1, frame)
+	expectPorts(t, frames, 3)
+
+	// select group chooses the same live bucket for the same flow
+	toGroup(3)
+	s.Receive(1, frame)
+	s.Receive(1, frame)
+	expectPorts(t, frames, 3, 3)
+
+	// indirect group chained to group 1, frame to port 2 is dropped
+	toGroup(4)
+	s.Receive(1, frame)
+	expectPorts(t, frames, 3)
+
+	groups := stats(t, s, ofp13.NewOfpGroupStatsRequest(1, 0))
+	if g := groups[0].(*ofp13.OfpGroupStats); len(groups) != 1 || g.RefCount != 1 || g.PacketCount != 2 || len(g.BucketStats) != 2 {
+		t.Log("Actual stats is : ", g)
+		t.Error("Group stats is invalid.")
+	}
+
+	// group 1 forwarding to group 4 makes loop
+	gm := ofp13.NewOfpGroupMod(ofp13.OFPGC_MODIFY, ofp13.OFPGT_ALL, 1)
+	gm.Append(bucket(0, ofp13.OFPP_ANY, ofp13.NewOfpActionGroup(4)))
+	expectError(t, s.HandleGroupMod(gm, s.Agent), ofp13.OFPET_GROUP_MOD_FAILED, ofp13.OFPGMFC_LOOP)
+
+	// flow entry forwarding to deleted group is removed
+	if err := s.HandleGroupMod(ofp13.NewOfpGroupMod(ofp13.OFPGC_DELETE, 0, 4), s.Agent); err != nil {
+		t.Fatal(err)
+	}
+	flows := stats(t, s, ofp13.NewOfpFlowStatsRequest(0, ofp13.OFPTT_ALL, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch()))
+	if len(flows) != 0 {
+		t.Error("Flow entry of deleted group is not removed.")
+	}
+	if descs := stats(t, s, ofp13.NewOfpGroupDescStatsRequest(0)); len(descs) != 3 {
+		t.Errorf("%d groups remain, expected 3.", len(descs))
+	}
+}
+
+/*****************************************************/
+/* Meter                                             */
+/*****************************************************/
+func TestSwitchMeter(t *testing.T) {
+	s, now, frames := newTestSwitch(t)
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+
+	mm := ofp13.NewOfpMeterMod(ofp13.OFPMC_ADD, ofp13.OFPMF_PKTPS|ofp13.OFPMF_STATS, 1)
+	mm.AppendMeterBand(ofp13.NewOfpMeterBandDrop(5, 0))
+	if err := s.HandleMeterMod(mm, s.Agent); err != nil {
+		t.Fatal(err)
+	}
+	mm = ofp13.NewOfpMeterMod(ofp13.OFPMC_ADD, ofp13.OFPMF_PKTPS, 2)
+	mm.AppendMeterBand(ofp13.NewOfpMeterBandDscpRemark(1, 0, 1))
+	if err := s.HandleMeterMod(mm, s.Agent); err != nil {
+		t.Fatal(err)
+	}
+	mm = ofp13.NewOfpMeterMod(ofp13.OFPMC_ADD, ofp13.OFPMF_PKTPS|ofp13.OFPMF_KBPS, 3)
+	mm.AppendMeterBand(ofp13.NewOfpMeterBandDrop(5, 0))
+	expectError(t, s.HandleMeterMod(mm, s.Agent), ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_BAD_FLAGS)
+
+	addFlow(t, s, 0, 10, newTestMatch(ofp13.NewOxmInPort(1)),
+		ofp13.NewOfpInstructionMeter(1), applyActions(ofp13.NewOfpActionOutput(2, 0)))
+	addFlow(t, s, 0, 10, newTestMatch(ofp13.NewOxmInPort(3)),
+		ofp13.NewOfpInstructionMeter(2), applyActions(ofp13.NewOfpActionOutput(4, 0)))
+
+	// packets over 5 pktps are dropped until tokens are refilled
+	for i := 0; i < 6; i++ {
+		s.Receive(1, frame)
+	}
+	expectPorts(t, frames, 2, 2, 2, 2, 2)
+	*now = now.Add(time.Second)
+	s.Receive(1, frame)
+	expectPorts(t, frames, 2)
+
+	meters := stats(t, s, ofp13.NewOfpMeterStatsRequest(1, 0))
+	if m := meters[0].(*ofp13.OfpMeterStats); len(meters) != 1 || m.FlowCount != 1 || m.PacketInCount != 7 || m.BandStats[0].PacketBandCount != 1 {
+		t.Log("Actual stats is : ", m)
+		t.Error("Meter stats is invalid.")
+	}
+
+	// drop precedence is increased over 1 pktps
+	s.Receive(3, frame)
+	s.Receive(3, frame)
+	if len(*frames) != 2 || (*frames)[0].frame[15] != 10<<2 || (*frames)[1].frame[15] != 12<<2 {
+		t.Log("Actual frames are : ", *frames)
+		t.Error("DSCP is not remarked.")
+	}
+
+	// flow entry using deleted meter is removed
+	if err := s.HandleMeterMod(ofp13.NewOfpMeterMod(ofp13.OFPMC_DELETE, 0, ofp13.OFPM_ALL), s.Agent); err != nil {
+		t.Fatal(err)
+	}
+	if flows := stats(t, s, ofp13.NewOfpFlowStatsRequest(0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch())); len(flows) != 0 {
+		t.Error("Flow entries of deleted meters are not removed.")
+	}
+}
+
+/*****************************************************/
+/* FlowMod                                           */
+/*****************************************************/
+func TestSwitchFlowMod(t *testing.T) {
+	s, _, frames := newTestSwitch(t)
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+	flowMod := func(flags uint16, priority uint16, match *ofp13.OfpMatch, instructions ...ofp13.OfpInstruction) error {
+		fm := ofp13.NewOfpFlowModAdd(0, 0, 0, priority, flags, match, instructions)
+		return s.HandleFlowMod(fm, s.Agent)
+	}
+	flowCount := func() int {
+		return len(stats(t, s, ofp13.NewOfpFlowStatsRequest(0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch())))
+	}
+
+	expectError(t, flowMod(0, 10, newTestMatch(ofp13.NewOxmTcpDst(80))),
+		ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_BAD_PREREQ)
+	expectError(t, flowMod(0, 10, newTestMatch(ofp13.NewOxmInPort(1), ofp13.NewOxmInPort(2))),
+		ofp13.OFPET_BAD_MATCH, ofp13.OFPBMC_DUP_FIELD)
+	expectError(t, flowMod(0, 10, ofp13.NewOfpMatch(), ofp13.NewOfpInstructionGotoTable(0)),
+		ofp13.OFPET_BAD_INSTRUCTION, ofp13.OFPBIC_BAD_TABLE_ID)
+	expectError(t, flowMod(0, 10, ofp13.NewOfpMatch(), applyActions(ofp13.NewOfpActionGroup(1))),
+		ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_GROUP)
+	expectError(t, flowMod(0, 10, ofp13.NewOfpMatch(), ofp13.NewOfpInstructionMeter(1)),
+		ofp13.OFPET_METER_MOD_FAILED, ofp13.OFPMMFC_UNKNOWN_METER)
+	if flowCount() != 0 {
+		t.Fatal("Invalid flow entry is added.")
+	}
+
+	// overlap is checked among the same priority
+	ipv4 := newTestMatch(ofp13.NewOxmEthType(0x0800))
+	if err := flowMod(0, 10, ipv4, applyActions(ofp13.NewOfpActionOutput(2, 0))); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, flowMod(ofp13.OFPFF_CHECK_OVERLAP, 10, newTestMatch(ofp13.NewOxmInPort(1))),
+		ofp13.OFPET_FLOW_MOD_FAILED, ofp13.OFPFMFC_OVERLAP)
+	if err := flowMod(ofp13.OFPFF_CHECK_OVERLAP, 10, newTestMatch(ofp13.NewOxmEthType(0x0806)),
+		applyActions(ofp13.NewOfpActionOutput(3, 0))); err != nil {
+		t.Error(err)
+	}
+
+	// identical flow entry is replaced with counters
+	s.Receive(1, frame)
+	expectPorts(t, frames, 2)
+	if err := flowMod(0, 10, ipv4, applyActions(ofp13.NewOfpActionOutput(4, 0))); err != nil {
+		t.Fatal(err)
+	}
+	s.Receive(1, frame)
+	expectPorts(t, frames, 4)
+	flows := stats(t, s, ofp13.NewOfpFlowStatsRequest(0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ipv4))
+	if len(flows) != 1 || flows[0].(*ofp13.OfpFlowStats).PacketCount != 2 {
+		t.Error("Counters are not kept by replacing flow entry.")
+	}
+	if err := flowMod(ofp13.OFPFF_RESET_COUNTS, 10, ipv4, applyActions(ofp13.NewOfpActionOutput(4, 0))); err != nil {
+		t.Fatal(err)
+	}
+	aggregate := stats(t, s, ofp13.NewOfpAggregateStatsRequest(0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch()))
+	if a := aggregate[0].(*ofp13.OfpAggregateStats); a.PacketCount != 0 || a.FlowCount != 2 {
+		t.Log("Actual stats is : ", a)
+		t.Error("Counters are not reset.")
+	}
+
+	// strict delete requires the same priority
+	del := ofp13.NewOfpFlowModDelete(0, 0, 0, 20, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, ipv4)
+	del.Command = ofp13.OFPFC_DELETE_STRICT
+	if err := s.HandleFlowMod(del, s.Agent); err != nil || flowCount() != 2 {
+		t.Error("Flow entry of other priority is deleted.")
+	}
+
+	// delete by out_port
+	del = ofp13.NewOfpFlowModDelete(0, 0, 0, 0, 3, ofp13.OFPG_ANY, 0, ofp13.NewOfpMatch())
+	// constructor does not set out_port
+	del.OutPort = 3
+	if err := s.HandleFlowMod(del, s.Agent); err != nil || flowCount() != 1 {
+		t.Error("Flow entry is not deleted by out_port.")
+	}
+	del = ofp13.NewOfpFlowModDelete(0, 0, ofp13.OFPTT_ALL, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, ofp13.NewOfpMatch())
+	if err := s.HandleFlowMod(del, s.Agent); err != nil || flowCount() != 0 {
+		t.Error("Flow entries are not deleted.")
+	}
+}
+
+/*****************************************************/
+/* Timeout                                           */
+/*****************************************************/
+func TestSwitchFlowRemoved(t *testing.T) {
+	s, now, _ := newTestSwitch(t)
+	start := *now
+	c := newTestController(t, s.Serve)
+	defer s.Close()
+	c.send(ofp13.NewOfpHello())
+
+	add := func(cookie uint64, idle uint16, hard uint16, match *ofp13.OfpMatch) {
+		fm := ofp13.NewOfpFlowModAdd(cookie, 0, 0, 10, ofp13.OFPFF_SEND_FLOW_REM, match,
+			[]ofp13.OfpInstruction{applyActions(ofp13.NewOfpActionOutput(2, 0))})
+		fm.IdleTimeout = idle
+		fm.HardTimeout = hard
+		if err := s.HandleFlowMod(fm, s.Agent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expire := func(d time.Duration) *ofp13.OfpFlowRemoved {
+		s.mu.Lock()
+		*now = start.Add(d)
+		s.mu.Unlock()
+		go s.ExpireFlows()
+		removed, ok := c.recv().(*ofp13.OfpFlowRemoved)
+		if !ok {
+			t.Fatal("FlowRemoved is not sent.")
+		}
+		return removed
+	}
+	add(1, 10, 0, newTestMatch(ofp13.NewOxmInPort(1)))
+	add(2, 0, 20, newTestMatch(ofp13.NewOxmInPort(2)))
+	add(3, 0, 0, newTestMatch(ofp13.NewOxmInPort(3)))
+
+	s.mu.Lock()
+	*now = start.Add(5 * time.Second)
+	s.mu.Unlock()
+	s.Receive(1, newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53))
+
+	if removed := expire(15 * time.Second); removed.Cookie != 1 || removed.Reason != ofp13.OFPRR_IDLE_TIMEOUT ||
+		removed.PacketCount != 1 || removed.DurationSec != 15 || removed.IdleTimeout != 10 {
+		t.Log("Actual message is : ", removed)
+		t.Error("FlowRemoved by idle timeout is invalid.")
+	}
+	if removed := expire(20 * time.Second); removed.Cookie != 2 || removed.Reason != ofp13.OFPRR_HARD_TIMEOUT {
+		t.Log("Actual message is : ", removed)
+		t.Error("FlowRemoved by hard timeout is invalid.")
+	}
+
+	c.send(ofp13.NewOfpFlowModDelete(0, 0, 0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, ofp13.NewOfpMatch()))
+	if removed, ok := c.recv().(*ofp13.OfpFlowRemoved); !ok || removed.Cookie != 3 || removed.Reason != ofp13.OFPRR_DELETE {
+		t.Log("Actual message is : ", removed)
+		t.Error("FlowRemoved by delete is invalid.")
+	}
+}
+
+/*****************************************************/
+/* PacketOut                                         */
+/*****************************************************/
+func TestSwitchPacketOut(t *testing.T) {
+	s, _, frames := newTestSwitch(t)
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+	addFlow(t, s, 0, 10, newTestMatch(ofp13.NewOxmInPort(ofp13.OFPP_CONTROLLER)),
+		applyActions(ofp13.NewOfpActionOutput(3, 0)))
+
+	packetOut := func(inPort uint32, actions ...ofp13.OfpAction) error {
+		return s.HandlePacketOut(ofp13.NewOfpPacketOut(ofp13.OFP_NO_BUFFER, inPort, actions, frame), s.Agent)
+	}
+	if err := packetOut(ofp13.OFPP_CONTROLLER, ofp13.NewOfpActionOutput(ofp13.OFPP_TABLE, 0)); err != nil {
+		t.Fatal(err)
+	}
+	expectPorts(t, frames, 3)
+	if err := packetOut(1, ofp13.NewOfpActionOutput(ofp13.OFPP_FLOOD, 0)); err != nil {
+		t.Fatal(err)
+	}
+	expectPorts(t, frames, 2, 3, 4)
+	if err := packetOut(1, ofp13.NewOfpActionOutput(ofp13.OFPP_IN_PORT, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*frames) != 1 || !bytes.Equal((*frames)[0].frame, frame) {
+		t.Error("Data of PacketOut is modified.")
+	}
+	expectPorts(t, frames, 1)
+
+	pout := ofp13.NewOfpPacketOut(1, 1, nil, frame)
+	expectError(t, s.HandlePacketOut(pout, s.Agent), ofp13.OFPET_BAD_REQUEST, ofp13.OFPBRC_BUFFER_UNKNOWN)
+	expectError(t, packetOut(1, ofp13.NewOfpActionOutput(0, 0)), ofp13.OFPET_BAD_ACTION, ofp13.OFPBAC_BAD_OUT_PORT)
+}
+
+/*****************************************************/
+/* Exact Match                                       */
+/*****************************************************/
+func TestSwitchExactMatchFromPacket(t *testing.T) {
+	s, _, frames := newTestSwitch(t)
+	s.MissSendToController = false
+
+	frame := newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 53)
+	decoded, err := pkt.Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFlow(t, s, 0, 10, ofp13.NewOfpMatchFromPacket(1, decoded), applyActions(ofp13.NewOfpActionOutput(2, 0)))
+
+	// match created from frame is accepted, and matches only the frame
+	s.Receive(1, frame)
+	expectPorts(t, frames, 2)
+	s.Receive(3, frame)
+	expectPorts(t, frames)
+	s.Receive(1, newTestUdpFrame("00:00:00:00:00:01", "00:00:00:00:00:02", "10.0.0.1", "10.0.0.2", 54))
+	expectPorts(t, frames)
+}
@@ -0,0 +1,210 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// default ttl and hop limit of built packets
+var DEFAULT_TTL uint8 = 64
+
+// lldp ttl of probes
+var DEFAULT_LLDP_TTL uint16 = 120
+
+// lldp chassis id and port id subtypes
+const (
+	LLDP_CHASSIS_ID_MAC_ADDRESS = 4
+	LLDP_CHASSIS_ID_LOCAL       = 7
+	LLDP_PORT_ID_COMPONENT      = 2
+	LLDP_PORT_ID_LOCAL          = 7
+)
+
+// icmp unreachable codes
+const (
+	ICMP_NET_UNREACHABLE   = 0
+	ICMP_HOST_UNREACHABLE  = 1
+	ICMP_PROTO_UNREACHABLE = 2
+	ICMP_PORT_UNREACHABLE  = 3
+)
+
+var (
+	Broadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	// nearest bridge group address of lldp
+	LLDPMulticast = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+)
+
+/*****************************************************/
+/* Builder                                           */
+/*****************************************************/
+
+/**
+ * NewARPRequest returns broadcast arp request for targetIP from the host of
+ * srcHw and srcIP.
+ */
+func NewARPRequest(srcHw net.HardwareAddr, srcIP net.IP, targetIP net.IP) *Packet {
+	return &Packet{
+		Ethernet: &Ethernet{Dst: Broadcast, Src: srcHw, EtherType: ETH_TYPE_ARP},
+		Arp:      newARP(1, srcHw, srcIP, make(net.HardwareAddr, 6), targetIP),
+	}
+}
+
+/**
+ * NewARPReply returns arp reply telling srcIP is at srcHw to the host of
+ * dstHw and dstIP.
+ */
+func NewARPReply(srcHw net.HardwareAddr, srcIP net.IP, dstHw net.HardwareAddr, dstIP net.IP) *Packet {
+	return &Packet{
+		Ethernet: &Ethernet{Dst: dstHw, Src: srcHw, EtherType: ETH_TYPE_ARP},
+		Arp:      newARP(2, srcHw, srcIP, dstHw, dstIP),
+	}
+}
+
+func newARP(op uint16, sha net.HardwareAddr, spa net.IP, tha net.HardwareAddr, tpa net.IP) *ARP {
+	return &ARP{HwType: 1, ProtoType: ETH_TYPE_IPV4, Op: op, SHA: sha, SPA: spa.To4(), THA: tha, TPA: tpa.To4()}
+}
+
+/**
+ * NewLLDPProbe returns lldp frame sent from port portNo of switch dpid, as
+ * used for topology discovery. Chassis id is "dpid:<dpid in hex>" of
+ * locally assigned subtype, and port id is port number of port component
+ * subtype. They are parsed by ProbeSource.
+ */
+func NewLLDPProbe(srcHw net.HardwareAddr, dpid uint64, portNo uint32) *Packet {
+	chassis := append([]byte{LLDP_CHASSIS_ID_LOCAL}, fmt.Sprintf("dpid:%016x", dpid)...)
+	port := make([]byte, 5)
+	port[0] = LLDP_PORT_ID_COMPONENT
+	binary.BigEndian.PutUint32(port[1:], portNo)
+	return &Packet{
+		Ethernet: &Ethernet{Dst: LLDPMulticast, Src: srcHw, EtherType: ETH_TYPE_LLDP},
+		Lldp:     &LLDP{ChassisID: chassis, PortID: port, TTL: DEFAULT_LLDP_TTL},
+	}
+}
+
+/**
+ * ProbeSource returns datapath id and port number of lldp created by
+ * NewLLDPProbe.
+ */
+func (l *LLDP) ProbeSource() (uint64, uint32, error) {
+	var dpid uint64
+	if len(l.ChassisID) < 1 || l.ChassisID[0] != LLDP_CHASSIS_ID_LOCAL {
+		return 0, 0, errors.New("chassis id is not of probe.")
+	}
+	if _, err := fmt.Sscanf(string(l.ChassisID[1:]), "dpid:%x", &dpid); err != nil {
+		return 0, 0, errors.New("chassis id is not of probe.")
+	}
+	if len(l.PortID) != 5 || l.PortID[0] != LLDP_PORT_ID_COMPONENT {
+		return 0, 0, errors.New("port id is not of probe.")
+	}
+	return dpid, binary.BigEndian.Uint32(l.PortID[1:]), nil
+}
+
+/**
+ * NewIPv4Packet returns ipv4 packet without upper layer. Set a layer such
+ * as Udp, Tcp or Icmpv4 and Payload, then Serialize it.
+ */
+func NewIPv4Packet(srcHw net.HardwareAddr, dstHw net.HardwareAddr, srcIP net.IP, dstIP net.IP) *Packet {
+	return &Packet{
+		Ethernet: &Ethernet{Dst: dstHw, Src: srcHw, EtherType: ETH_TYPE_IPV4},
+		Ipv4:     &IPv4{TTL: DEFAULT_TTL, Src: srcIP.To4(), Dst: dstIP.To4()},
+	}
+}
+
+/**
+ * NewIPv6Packet returns ipv6 packet without upper layer, like
+ * NewIPv4Packet.
+ */
+func NewIPv6Packet(srcHw net.HardwareAddr, dstHw net.HardwareAddr, srcIP net.IP, dstIP net.IP) *Packet {
+	return &Packet{
+		Ethernet: &Ethernet{Dst: dstHw, Src: srcHw, EtherType: ETH_TYPE_IPV6},
+		Ipv6:     &IPv6{HopLimit: DEFAULT_TTL, Src: srcIP.To16(), Dst: dstIP.To16()},
+	}
+}
+
+// NewUDPPacket returns udp over ipv4 packet.
+func NewUDPPacket(srcHw net.HardwareAddr, dstHw net.HardwareAddr, srcIP net.IP, dstIP net.IP,
+	srcPort uint16, dstPort uint16, payload []byte) *Packet {
+	p := NewIPv4Packet(srcHw, dstHw, srcIP, dstIP)
+	p.Udp = &UDP{SrcPort: srcPort, DstPort: dstPort}
+	p.Payload = payload
+	return p
+}
+
+// NewICMPEchoRequest returns icmp echo request of id and seq.
+func NewICMPEchoRequest(srcHw net.HardwareAddr, dstHw net.HardwareAddr, srcIP net.IP, dstIP net.IP,
+	id uint16, seq uint16, payload []byte) *Packet {
+	p := NewIPv4Packet(srcHw, dstHw, srcIP, dstIP)
+	rest := make([]byte, 4)
+	binary.BigEndian.PutUint16(rest[0:], id)
+	binary.BigEndian.PutUint16(rest[2:], seq)
+	p.Icmpv4 = &ICMPv4{Type: ICMP_ECHO_REQUEST, Rest: rest}
+	p.Payload = payload
+	return p
+}
+
+/*****************************************************/
+/* Reply                                             */
+/*****************************************************/
+
+/**
+ * ARPReply returns arp reply to arp request p, telling the requested
+ * address is at hwAddr.
+ */
+func (p *Packet) ARPReply(hwAddr net.HardwareAddr) (*Packet, error) {
+	if p.Arp == nil || p.Arp.Op != 1 {
+		return nil, errors.New("packet is not arp request.")
+	}
+	reply := NewARPReply(hwAddr, p.Arp.TPA, p.Arp.SHA, p.Arp.SPA)
+	reply.Vlans = p.Vlans
+	return reply, nil
+}
+
+/**
+ * ICMPEchoReply returns icmp echo reply to echo request p. Addresses are
+ * swapped, and id, sequence and data are copied from the request.
+ */
+func (p *Packet) ICMPEchoReply() (*Packet, error) {
+	if p.Ipv4 == nil || p.Icmpv4 == nil || p.Icmpv4.Type != ICMP_ECHO_REQUEST {
+		return nil, errors.New("packet is not icmp echo request.")
+	}
+	reply := NewIPv4Packet(p.Ethernet.Dst, p.Ethernet.Src, p.Ipv4.Dst, p.Ipv4.Src)
+	reply.Vlans = p.Vlans
+	reply.Icmpv4 = &ICMPv4{Type: ICMP_ECHO_REPLY, Rest: p.Icmpv4.Rest}
+	reply.Payload = p.Payload
+	return reply, nil
+}
+
+/**
+ * ICMPUnreachable returns icmp destination unreachable of code to the
+ * sender of ipv4 packet p, sent from the router of srcHw and srcIP.
+ * It contains ip header and the first 8 bytes of payload of p.
+ */
+func (p *Packet) ICMPUnreachable(code uint8, srcHw net.HardwareAddr, srcIP net.IP) (*Packet, error) {
+	if p.Ipv4 == nil {
+		return nil, errors.New("packet is not ipv4.")
+	}
+	if p.Icmpv4 != nil && p.Icmpv4.Type != ICMP_ECHO_REQUEST && p.Icmpv4.Type != ICMP_ECHO_REPLY {
+		// no icmp error for icmp error
+		return nil, errors.New("packet is icmp error.")
+	}
+	orig := *p
+	orig.Vlans = nil
+	orig.Mpls = nil
+	frame, err := orig.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	ip := frame[14:]
+	n := int(ip[0]&0x0f)*4 + 8
+	if n > len(ip) {
+		n = len(ip)
+	}
+	quoted := ip[:n]
+
+	reply := NewIPv4Packet(srcHw, p.Ethernet.Src, srcIP, p.Ipv4.Src)
+	reply.Vlans = p.Vlans
+	reply.Icmpv4 = &ICMPv4{Type: ICMP_UNREACHABLE, Code: code}
+	reply.Payload = quoted
+	return reply, nil
+}
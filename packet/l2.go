@@ -0,0 +1,175 @@
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+/*****************************************************/
+/* Ethernet                                          */
+/*****************************************************/
+
+/**
+ * Ethernet header. EtherType is the type of the payload after vlan tags,
+ * same as OXM eth_type.
+ */
+type Ethernet struct {
+	Dst       net.HardwareAddr
+	Src       net.HardwareAddr
+	EtherType uint16
+}
+
+func (e *Ethernet) decode(data []byte) ([]byte, error) {
+	if len(data) < 14 {
+		return nil, ErrTruncated
+	}
+	e.Dst = net.HardwareAddr(data[0:6])
+	e.Src = net.HardwareAddr(data[6:12])
+	e.EtherType = binary.BigEndian.Uint16(data[12:])
+	return data[14:], nil
+}
+
+/**
+ * Dot1Q is a 802.1Q or 802.1ad vlan tag. TPID is the ether type which
+ * preceded the tag, and EtherType is the type following it.
+ */
+type Dot1Q struct {
+	TPID      uint16
+	Priority  uint8
+	DEI       bool
+	VID       uint16
+	EtherType uint16
+}
+
+func (v *Dot1Q) decode(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrTruncated
+	}
+	tci := binary.BigEndian.Uint16(data)
+	v.Priority = uint8(tci >> 13)
+	v.DEI = tci&0x1000 != 0
+	v.VID = tci & 0x0fff
+	v.EtherType = binary.BigEndian.Uint16(data[2:])
+	return data[4:], nil
+}
+
+/*****************************************************/
+/* MPLS                                              */
+/*****************************************************/
+type MPLS struct {
+	Label uint32
+	TC    uint8
+	BoS   bool
+	TTL   uint8
+}
+
+func (m *MPLS) decode(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, ErrTruncated
+	}
+	v := binary.BigEndian.Uint32(data)
+	m.Label = v >> 12
+	m.TC = uint8(v>>9) & 0x7
+	m.BoS = v&0x100 != 0
+	m.TTL = uint8(v)
+	return data[4:], nil
+}
+
+/*****************************************************/
+/* ARP                                               */
+/*****************************************************/
+
+/**
+ * ARP for ethernet and ipv4. Other hardware or protocol types are decoded
+ * as malformed.
+ */
+type ARP struct {
+	HwType    uint16
+	ProtoType uint16
+	Op        uint16
+	SHA       net.HardwareAddr
+	SPA       net.IP
+	THA       net.HardwareAddr
+	TPA       net.IP
+}
+
+func (a *ARP) decode(data []byte) error {
+	if len(data) < 28 {
+		return ErrTruncated
+	}
+	a.HwType = binary.BigEndian.Uint16(data[0:])
+	a.ProtoType = binary.BigEndian.Uint16(data[2:])
+	if data[4] != 6 || data[5] != 4 {
+		return errMalformed("arp", "address length is not ethernet and ipv4")
+	}
+	a.Op = binary.BigEndian.Uint16(data[6:])
+	a.SHA = net.HardwareAddr(data[8:14])
+	a.SPA = net.IP(data[14:18])
+	a.THA = net.HardwareAddr(data[18:24])
+	a.TPA = net.IP(data[24:28])
+	return nil
+}
+
+/*****************************************************/
+/* LLDP                                              */
+/*****************************************************/
+
+// lldp tlv types
+const (
+	LLDP_TLV_END        = 0
+	LLDP_TLV_CHASSIS_ID = 1
+	LLDP_TLV_PORT_ID    = 2
+	LLDP_TLV_TTL        = 3
+)
+
+type LLDPTLV struct {
+	Type  uint8
+	Value []byte
+}
+
+/**
+ * LLDP data unit. ChassisID and PortID include their subtype as the first
+ * byte. All TLVs including mandatory ones are in TLVs.
+ */
+type LLDP struct {
+	ChassisID []byte
+	PortID    []byte
+	TTL       uint16
+	TLVs      []LLDPTLV
+}
+
+func (l *LLDP) decode(data []byte) error {
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return ErrTruncated
+		}
+		hdr := binary.BigEndian.Uint16(data)
+		tlv := LLDPTLV{Type: uint8(hdr >> 9)}
+		length := int(hdr & 0x1ff)
+		if len(data) < 2+length {
+			return ErrTruncated
+		}
+		tlv.Value = data[2 : 2+length]
+		data = data[2+length:]
+		if tlv.Type == LLDP_TLV_END {
+			break
+		}
+		l.TLVs = append(l.TLVs, tlv)
+
+		switch tlv.Type {
+		case LLDP_TLV_CHASSIS_ID:
+			l.ChassisID = tlv.Value
+		case LLDP_TLV_PORT_ID:
+			l.PortID = tlv.Value
+		case LLDP_TLV_TTL:
+			if length < 2 {
+				return errMalformed("lldp", "ttl is too short")
+			}
+			l.TTL = binary.BigEndian.Uint16(tlv.Value)
+		}
+	}
+	if l.ChassisID == nil || l.PortID == nil {
+		return errMalformed("lldp", "mandatory tlv is missing")
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+/*****************************************************/
+/* IPv4                                              */
+/*****************************************************/
+type IPv4 struct {
+	IHL        uint8
+	DSCP       uint8
+	ECN        uint8
+	Length     uint16
+	Id         uint16
+	Flags      uint8
+	FragOffset uint16
+	TTL        uint8
+	Protocol   uint8
+	Checksum   uint16
+	Src        net.IP
+	Dst        net.IP
+	Options    []byte
+}
+
+func (ip *IPv4) decode(data []byte) ([]byte, error) {
+	if len(data) < 20 {
+		return nil, ErrTruncated
+	}
+	if data[0]>>4 != 4 {
+		return nil, errMalformed("ipv4", "version is not 4")
+	}
+	ip.IHL = data[0] & 0x0f
+	hlen := int(ip.IHL) * 4
+	if hlen < 20 {
+		return nil, errMalformed("ipv4", "header length is too short")
+	}
+	if len(data) < hlen {
+		return nil, ErrTruncated
+	}
+	ip.DSCP = data[1] >> 2
+	ip.ECN = data[1] & 0x3
+	ip.Length = binary.BigEndian.Uint16(data[2:])
+	ip.Id = binary.BigEndian.Uint16(data[4:])
+	frag := binary.BigEndian.Uint16(data[6:])
+	ip.Flags = uint8(frag >> 13)
+	ip.FragOffset = frag & 0x1fff
+	ip.TTL = data[8]
+	ip.Protocol = data[9]
+	ip.Checksum = binary.BigEndian.Uint16(data[10:])
+	ip.Src = net.IP(data[12:16])
+	ip.Dst = net.IP(data[16:20])
+	ip.Options = data[20:hlen]
+
+	// trim ethernet padding
+	rest := data[hlen:]
+	if int(ip.Length) >= hlen && int(ip.Length) <= len(data) {
+		rest = data[hlen:ip.Length]
+	}
+	return rest, nil
+}
+
+/*****************************************************/
+/* IPv6                                              */
+/*****************************************************/
+
+// ipv6 extension header types
+const (
+	IPV6_EXT_HOP    = 0
+	IPV6_EXT_ROUTER = 43
+	IPV6_EXT_FRAG   = 44
+	IPV6_EXT_ESP    = 50
+	IPV6_EXT_AUTH   = 51
+	IPV6_EXT_NONEXT = 59
+	IPV6_EXT_DEST   = 60
+)
+
+// ipv6 extension header pseudo-field bits, same as OFPIEH_* of ofp13
+const (
+	IEH_NONEXT = 1 << 0
+	IEH_ESP    = 1 << 1
+	IEH_AUTH   = 1 << 2
+	IEH_DEST   = 1 << 3
+	IEH_FRAG   = 1 << 4
+	IEH_ROUTER = 1 << 5
+	IEH_HOP    = 1 << 6
+	IEH_UNREP  = 1 << 7
+	IEH_UNSEQ  = 1 << 8
+)
+
+type IPv6ExtHeader struct {
+	Type uint8
+	Data []byte
+}
+
+/**
+ * IPv6 header. NextHeader is the next header field of the fixed header,
+ * and Protocol is the upper layer protocol after extension headers.
+ */
+type IPv6 struct {
+	DSCP       uint8
+	ECN        uint8
+	FlowLabel  uint32
+	Length     uint16
+	NextHeader uint8
+	HopLimit   uint8
+	Src        net.IP
+	Dst        net.IP
+	ExtHeaders []IPv6ExtHeader
+	Protocol   uint8
+}
+
+func (ip *IPv6) decode(data []byte) ([]byte, error) {
+	if len(data) < 40 {
+		return nil, ErrTruncated
+	}
+	vtf := binary.BigEndian.Uint32(data)
+	if vtf>>28 != 6 {
+		return nil, errMalformed("ipv6", "version is not 6")
+	}
+	tc := uint8(vtf >> 20)
+	ip.DSCP = tc >> 2
+	ip.ECN = tc & 0x3
+	ip.FlowLabel = vtf & 0xfffff
+	ip.Length = binary.BigEndian.Uint16(data[4:])
+	ip.NextHeader = data[6]
+	ip.HopLimit = data[7]
+	ip.Src = net.IP(data[8:24])
+	ip.Dst = net.IP(data[24:40])
+
+	rest := data[40:]
+	if int(ip.Length) <= len(rest) {
+		rest = rest[:ip.Length]
+	}
+
+	// walk extension headers
+	next := ip.NextHeader
+loop:
+	for {
+		var length int
+		switch next {
+		case IPV6_EXT_HOP, IPV6_EXT_ROUTER, IPV6_EXT_DEST:
+			if len(rest) < 2 {
+				return nil, ErrTruncated
+			}
+			length = (int(rest[1]) + 1) * 8
+		case IPV6_EXT_AUTH:
+			if len(rest) < 2 {
+				return nil, ErrTruncated
+			}
+			length = (int(rest[1]) + 2) * 4
+		case IPV6_EXT_FRAG:
+			length = 8
+		case IPV6_EXT_ESP, IPV6_EXT_NONEXT:
+			// the rest is encrypted or empty
+			ip.ExtHeaders = append(ip.ExtHeaders, IPv6ExtHeader{Type: next})
+			break loop
+		default:
+			break loop
+		}
+		if len(rest) < length {
+			return nil, ErrTruncated
+		}
+		ip.ExtHeaders = append(ip.ExtHeaders, IPv6ExtHeader{Type: next, Data: rest[:length]})
+		next = rest[0]
+		rest = rest[length:]
+	}
+	ip.Protocol = next
+	return rest, nil
+}
+
+// hasL4 reports whether upper layer header follows extension headers.
+func (ip *IPv6) hasL4() bool {
+	for _, h := range ip.ExtHeaders {
+		switch h.Type {
+		case IPV6_EXT_ESP, IPV6_EXT_NONEXT:
+			return false
+		case IPV6_EXT_FRAG:
+			if binary.BigEndian.Uint16(h.Data[2:])&0xfff8 != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/**
+ * ExtHeaderFlags returns IEH_* bits of extension headers, which is the
+ * value of OXM ipv6_exthdr.
+ */
+func (ip *IPv6) ExtHeaderFlags() uint16 {
+	var flags uint16
+	for _, h := range ip.ExtHeaders {
+		switch h.Type {
+		case IPV6_EXT_HOP:
+			flags |= IEH_HOP
+		case IPV6_EXT_ROUTER:
+			flags |= IEH_ROUTER
+		case IPV6_EXT_DEST:
+			flags |= IEH_DEST
+		case IPV6_EXT_AUTH:
+			flags |= IEH_AUTH
+		case IPV6_EXT_FRAG:
+			flags |= IEH_FRAG
+		case IPV6_EXT_ESP:
+			flags |= IEH_ESP
+		case IPV6_EXT_NONEXT:
+			flags |= IEH_NONEXT
+		}
+	}
+	return flags
+}
@@ -0,0 +1,221 @@
+package packet
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+/*****************************************************/
+/* TCP                                               */
+/*****************************************************/
+
+// tcp flags
+const (
+	TCP_FIN = 1 << 0
+	TCP_SYN = 1 << 1
+	TCP_RST = 1 << 2
+	TCP_PSH = 1 << 3
+	TCP_ACK = 1 << 4
+	TCP_URG = 1 << 5
+	TCP_ECE = 1 << 6
+	TCP_CWR = 1 << 7
+)
+
+type TCP struct {
+	SrcPort    uint16
+	DstPort    uint16
+	Seq        uint32
+	Ack        uint32
+	DataOffset uint8
+	Flags      uint8
+	Window     uint16
+	Checksum   uint16
+	Urgent     uint16
+	Options    []byte
+}
+
+func (t *TCP) decode(data []byte) ([]byte, error) {
+	if len(data) < 20 {
+		return nil, ErrTruncated
+	}
+	t.SrcPort = binary.BigEndian.Uint16(data[0:])
+	t.DstPort = binary.BigEndian.Uint16(data[2:])
+	t.Seq = binary.BigEndian.Uint32(data[4:])
+	t.Ack = binary.BigEndian.Uint32(data[8:])
+	t.DataOffset = data[12] >> 4
+	hlen := int(t.DataOffset) * 4
+	if hlen < 20 {
+		return nil, errMalformed("tcp", "data offset is too short")
+	}
+	if len(data) < hlen {
+		return nil, ErrTruncated
+	}
+	t.Flags = data[13]
+	t.Window = binary.BigEndian.Uint16(data[14:])
+	t.Checksum = binary.BigEndian.Uint16(data[16:])
+	t.Urgent = binary.BigEndian.Uint16(data[18:])
+	t.Options = data[20:hlen]
+	return data[hlen:], nil
+}
+
+/*****************************************************/
+/* UDP                                               */
+/*****************************************************/
+type UDP struct {
+	SrcPort  uint16
+	DstPort  uint16
+	Length   uint16
+	Checksum uint16
+}
+
+func (u *UDP) decode(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	u.SrcPort = binary.BigEndian.Uint16(data[0:])
+	u.DstPort = binary.BigEndian.Uint16(data[2:])
+	u.Length = binary.BigEndian.Uint16(data[4:])
+	u.Checksum = binary.BigEndian.Uint16(data[6:])
+	return data[8:], nil
+}
+
+/*****************************************************/
+/* SCTP                                              */
+/*****************************************************/
+
+// SCTP common header. Chunks are left in the payload.
+type SCTP struct {
+	SrcPort         uint16
+	DstPort         uint16
+	VerificationTag uint32
+	Checksum        uint32
+}
+
+func (s *SCTP) decode(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, ErrTruncated
+	}
+	s.SrcPort = binary.BigEndian.Uint16(data[0:])
+	s.DstPort = binary.BigEndian.Uint16(data[2:])
+	s.VerificationTag = binary.BigEndian.Uint32(data[4:])
+	s.Checksum = binary.BigEndian.Uint32(data[8:])
+	return data[12:], nil
+}
+
+/*****************************************************/
+/* ICMP                                              */
+/*****************************************************/
+
+// icmp types
+const (
+	ICMP_ECHO_REPLY   = 0
+	ICMP_UNREACHABLE  = 3
+	ICMP_ECHO_REQUEST = 8
+	ICMP_TIME_EXCEED  = 11
+)
+
+/**
+ * ICMPv4 header. Rest is the 4 bytes following checksum, such as id and
+ * sequence of echo.
+ */
+type ICMPv4 struct {
+	Type     uint8
+	Code     uint8
+	Checksum uint16
+	Rest     []byte
+}
+
+func (i *ICMPv4) decode(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	i.Type = data[0]
+	i.Code = data[1]
+	i.Checksum = binary.BigEndian.Uint16(data[2:])
+	i.Rest = data[4:8]
+	return data[8:], nil
+}
+
+// icmpv6 types
+const (
+	ICMPV6_ECHO_REQUEST  = 128
+	ICMPV6_ECHO_REPLY    = 129
+	ICMPV6_ROUTER_SOL    = 133
+	ICMPV6_ROUTER_ADV    = 134
+	ICMPV6_NEIGHBOR_SOL  = 135
+	ICMPV6_NEIGHBOR_ADV  = 136
+	ICMPV6_OPT_SOURCE_LL = 1
+	ICMPV6_OPT_TARGET_LL = 2
+)
+
+/**
+ * ICMPv6 header. For neighbor solicitation and advertisement, Target and
+ * link layer address options are decoded, same as OXM ipv6_nd_* fields.
+ */
+type ICMPv6 struct {
+	Type     uint8
+	Code     uint8
+	Checksum uint16
+	Rest     []byte
+	Target   net.IP
+	SLL      net.HardwareAddr
+	TLL      net.HardwareAddr
+}
+
+func (i *ICMPv6) decode(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	i.Type = data[0]
+	i.Code = data[1]
+	i.Checksum = binary.BigEndian.Uint16(data[2:])
+	i.Rest = data[4:8]
+	if i.Type != ICMPV6_NEIGHBOR_SOL && i.Type != ICMPV6_NEIGHBOR_ADV {
+		return data[8:], nil
+	}
+
+	if len(data) < 24 {
+		return nil, ErrTruncated
+	}
+	i.Target = net.IP(data[8:24])
+	opts := data[24:]
+	for len(opts) >= 2 {
+		length := int(opts[1]) * 8
+		if length == 0 {
+			return nil, errMalformed("icmpv6", "option length is zero")
+		}
+		if len(opts) < length {
+			return nil, ErrTruncated
+		}
+		if length >= 8 {
+			if i.Type == ICMPV6_NEIGHBOR_SOL && opts[0] == ICMPV6_OPT_SOURCE_LL {
+				i.SLL = net.HardwareAddr(opts[2:8])
+			}
+			if i.Type == ICMPV6_NEIGHBOR_ADV && opts[0] == ICMPV6_OPT_TARGET_LL {
+				i.TLL = net.HardwareAddr(opts[2:8])
+			}
+		}
+		opts = opts[length:]
+	}
+	return nil, nil
+}
+
+/*****************************************************/
+/* VXLAN                                             */
+/*****************************************************/
+type VXLAN struct {
+	Flags uint8
+	VNI   uint32
+}
+
+func (v *VXLAN) decode(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, ErrTruncated
+	}
+	v.Flags = data[0]
+	if v.Flags&0x08 == 0 {
+		return nil, errMalformed("vxlan", "vni flag is not set")
+	}
+	v.VNI = binary.BigEndian.Uint32(data[4:]) >> 8
+	return data[8:], nil
+}
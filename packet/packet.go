@@ -0,0 +1,309 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ether types
+const (
+	ETH_TYPE_IPV4   = 0x0800
+	ETH_TYPE_ARP    = 0x0806
+	ETH_TYPE_VLAN   = 0x8100
+	ETH_TYPE_QINQ   = 0x88a8
+	ETH_TYPE_IPV6   = 0x86dd
+	ETH_TYPE_MPLS   = 0x8847
+	ETH_TYPE_MPLSMC = 0x8848
+	ETH_TYPE_LLDP   = 0x88cc
+)
+
+// ip protocols
+const (
+	IP_PROTO_ICMP   = 1
+	IP_PROTO_TCP    = 6
+	IP_PROTO_UDP    = 17
+	IP_PROTO_ICMPV6 = 58
+	IP_PROTO_SCTP   = 132
+)
+
+// udp port of vxlan
+var VXLAN_PORT uint16 = 4789
+
+var ErrTruncated = errors.New("packet is truncated.")
+
+func errMalformed(layer string, reason string) error {
+	return fmt.Errorf("%s is malformed, %s.", layer, reason)
+}
+
+/**
+ * Packet is an ethernet frame decoded into layers. Layers which are not
+ * present in the frame are nil.
+ * Fields are typed like OXM fields, so that they can be used to create
+ * OfpMatch of the frame.
+ */
+type Packet struct {
+	Ethernet *Ethernet
+	// vlan tags, outermost first
+	Vlans []*Dot1Q
+	// mpls labels, outermost first
+	Mpls   []*MPLS
+	Arp    *ARP
+	Lldp   *LLDP
+	Ipv4   *IPv4
+	Ipv6   *IPv6
+	Tcp    *TCP
+	Udp    *UDP
+	Sctp   *SCTP
+	Icmpv4 *ICMPv4
+	Icmpv6 *ICMPv6
+	Vxlan  *VXLAN
+	// frame encapsulated by vxlan
+	Inner *Packet
+	// payload of the innermost decoded layer
+	Payload []byte
+	// whole frame
+	Data []byte
+}
+
+/**
+ * Decode decodes ethernet frame. If the frame is truncated or malformed,
+ * it returns the layers decoded so far with error.
+ * Layers refer data without copy.
+ */
+func Decode(data []byte) (*Packet, error) {
+	p := &Packet{Data: data}
+	err := p.decodeEthernet(data)
+	return p, err
+}
+
+// IpProto returns protocol of ipv4 or upper layer protocol of ipv6, or 0.
+func (p *Packet) IpProto() uint8 {
+	if p.Ipv4 != nil {
+		return p.Ipv4.Protocol
+	}
+	if p.Ipv6 != nil {
+		return p.Ipv6.Protocol
+	}
+	return 0
+}
+
+// EthType returns ether type of the payload of vlan tags, or 0.
+func (p *Packet) EthType() uint16 {
+	if p.Ethernet == nil {
+		return 0
+	}
+	return p.Ethernet.EtherType
+}
+
+// String returns names of decoded layers, such as "Ethernet/IPv4/UDP".
+func (p *Packet) String() string {
+	s := ""
+	add := func(name string) {
+		if s != "" {
+			s += "/"
+		}
+		s += name
+	}
+	if p.Ethernet != nil {
+		add("Ethernet")
+	}
+	for range p.Vlans {
+		add("Dot1Q")
+	}
+	for range p.Mpls {
+		add("MPLS")
+	}
+	for _, l := range []struct {
+		present bool
+		name    string
+	}{
+		{p.Arp != nil, "ARP"}, {p.Lldp != nil, "LLDP"},
+		{p.Ipv4 != nil, "IPv4"}, {p.Ipv6 != nil, "IPv6"},
+		{p.Tcp != nil, "TCP"}, {p.Udp != nil, "UDP"}, {p.Sctp != nil, "SCTP"},
+		{p.Icmpv4 != nil, "ICMPv4"}, {p.Icmpv6 != nil, "ICMPv6"}, {p.Vxlan != nil, "VXLAN"},
+	} {
+		if l.present {
+			add(l.name)
+		}
+	}
+	if p.Inner != nil {
+		s += fmt.Sprintf("[%s]", p.Inner)
+	}
+	return s
+}
+
+/*****************************************************/
+/* Decode                                            */
+/*****************************************************/
+func (p *Packet) decodeEthernet(data []byte) error {
+	eth := new(Ethernet)
+	rest, err := eth.decode(data)
+	if err != nil {
+		return err
+	}
+	p.Ethernet = eth
+
+	// vlan tags
+	for eth.EtherType == ETH_TYPE_VLAN || eth.EtherType == ETH_TYPE_QINQ {
+		tag := &Dot1Q{TPID: eth.EtherType}
+		if rest, err = tag.decode(rest); err != nil {
+			return err
+		}
+		p.Vlans = append(p.Vlans, tag)
+		eth.EtherType = tag.EtherType
+	}
+	return p.decodeEtherType(eth.EtherType, rest)
+}
+
+func (p *Packet) decodeEtherType(ethType uint16, data []byte) error {
+	p.Payload = data
+	switch ethType {
+	case ETH_TYPE_MPLS, ETH_TYPE_MPLSMC:
+		return p.decodeMpls(data)
+	case ETH_TYPE_ARP:
+		arp := new(ARP)
+		if err := arp.decode(data); err != nil {
+			return err
+		}
+		p.Arp = arp
+		p.Payload = nil
+	case ETH_TYPE_LLDP:
+		lldp := new(LLDP)
+		if err := lldp.decode(data); err != nil {
+			return err
+		}
+		p.Lldp = lldp
+		p.Payload = nil
+	case ETH_TYPE_IPV4:
+		return p.decodeIpv4(data)
+	case ETH_TYPE_IPV6:
+		return p.decodeIpv6(data)
+	}
+	return nil
+}
+
+func (p *Packet) decodeMpls(data []byte) error {
+	for {
+		label := new(MPLS)
+		rest, err := label.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Mpls = append(p.Mpls, label)
+		data = rest
+		p.Payload = data
+		if label.BoS {
+			break
+		}
+	}
+
+	// payload type is guessed by ip version
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] >> 4 {
+	case 4:
+		return p.decodeIpv4(data)
+	case 6:
+		return p.decodeIpv6(data)
+	}
+	return nil
+}
+
+func (p *Packet) decodeIpv4(data []byte) error {
+	ip := new(IPv4)
+	rest, err := ip.decode(data)
+	if err != nil {
+		return err
+	}
+	p.Ipv4 = ip
+	p.Payload = rest
+
+	// l4 header is only in the first fragment
+	if ip.FragOffset != 0 {
+		return nil
+	}
+	return p.decodeL4(ip.Protocol, rest)
+}
+
+func (p *Packet) decodeIpv6(data []byte) error {
+	ip := new(IPv6)
+	rest, err := ip.decode(data)
+	if err != nil {
+		return err
+	}
+	p.Ipv6 = ip
+	p.Payload = rest
+	if !ip.hasL4() {
+		return nil
+	}
+	return p.decodeL4(ip.Protocol, rest)
+}
+
+func (p *Packet) decodeL4(proto uint8, data []byte) error {
+	switch proto {
+	case IP_PROTO_TCP:
+		tcp := new(TCP)
+		rest, err := tcp.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Tcp = tcp
+		p.Payload = rest
+	case IP_PROTO_UDP:
+		udp := new(UDP)
+		rest, err := udp.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Udp = udp
+		p.Payload = rest
+		if udp.DstPort == VXLAN_PORT {
+			return p.decodeVxlan(rest)
+		}
+	case IP_PROTO_SCTP:
+		sctp := new(SCTP)
+		rest, err := sctp.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Sctp = sctp
+		p.Payload = rest
+	case IP_PROTO_ICMP:
+		if p.Ipv4 == nil {
+			return nil
+		}
+		icmp := new(ICMPv4)
+		rest, err := icmp.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Icmpv4 = icmp
+		p.Payload = rest
+	case IP_PROTO_ICMPV6:
+		if p.Ipv6 == nil {
+			return nil
+		}
+		icmp := new(ICMPv6)
+		rest, err := icmp.decode(data)
+		if err != nil {
+			return err
+		}
+		p.Icmpv6 = icmp
+		p.Payload = rest
+	}
+	return nil
+}
+
+func (p *Packet) decodeVxlan(data []byte) error {
+	vxlan := new(VXLAN)
+	rest, err := vxlan.decode(data)
+	if err != nil {
+		return err
+	}
+	p.Vxlan = vxlan
+	p.Payload = rest
+	inner, err := Decode(rest)
+	p.Inner = inner
+	return err
+}
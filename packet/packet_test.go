@@ -0,0 +1,476 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+var (
+	testSrc = net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	testDst = net.HardwareAddr{0x00, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+func join(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func be16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func ethHeader(ethType uint16) []byte {
+	return join(testDst, testSrc, be16(ethType))
+}
+
+func ipv4Header(proto uint8, payloadLen int) []byte {
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	ip[1] = 10<<2 | 1
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+payloadLen))
+	ip[8] = 64
+	ip[9] = proto
+	copy(ip[12:], net.IPv4(10, 0, 0, 1).To4())
+	copy(ip[16:], net.IPv4(10, 0, 0, 2).To4())
+	return ip
+}
+
+func ipv6Header(next uint8, payloadLen int) []byte {
+	ip := make([]byte, 40)
+	binary.BigEndian.PutUint32(ip, 6<<28|uint32(46<<2)<<20|0x12345)
+	binary.BigEndian.PutUint16(ip[4:], uint16(payloadLen))
+	ip[6] = next
+	ip[7] = 255
+	copy(ip[8:], net.ParseIP("fe80::1"))
+	copy(ip[24:], net.ParseIP("fe80::2"))
+	return ip
+}
+
+func udpHeader(sport uint16, dport uint16, payloadLen int) []byte {
+	return join(be16(sport), be16(dport), be16(uint16(8+payloadLen)), be16(0))
+}
+
+/*****************************************************/
+/* L2                                                */
+/*****************************************************/
+func TestDecodeQinQ(t *testing.T) {
+	arp := join(be16(1), be16(ETH_TYPE_IPV4), []byte{6, 4}, be16(1),
+		testSrc, net.IPv4(10, 0, 0, 1).To4(), make([]byte, 6), net.IPv4(10, 0, 0, 2).To4())
+	frame := join(ethHeader(ETH_TYPE_QINQ), be16(100), be16(ETH_TYPE_VLAN), be16(5<<13|0x1000|200), be16(ETH_TYPE_ARP), arp)
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "Ethernet/Dot1Q/Dot1Q/ARP" {
+		t.Log("Actual layers are : ", p)
+		t.Error("Layers are invalid.")
+	}
+	if p.EthType() != ETH_TYPE_ARP || p.Ethernet.Src.String() != testSrc.String() {
+		t.Error("Ethernet is invalid.")
+	}
+	if len(p.Vlans) != 2 || p.Vlans[0].TPID != ETH_TYPE_QINQ || p.Vlans[0].VID != 100 {
+		t.Error("Outer tag is invalid.")
+	}
+	if inner := p.Vlans[1]; inner.VID != 200 || inner.Priority != 5 || !inner.DEI {
+		t.Log("Actual tag is : ", inner)
+		t.Error("Inner tag is invalid.")
+	}
+	if p.Arp.Op != 1 || !p.Arp.TPA.Equal(net.IPv4(10, 0, 0, 2)) || p.Arp.SHA.String() != testSrc.String() {
+		t.Log("Actual arp is : ", p.Arp)
+		t.Error("Arp is invalid.")
+	}
+}
+
+func TestDecodeLLDP(t *testing.T) {
+	tlv := func(typ uint8, value []byte) []byte {
+		return join(be16(uint16(typ)<<9|uint16(len(value))), value)
+	}
+	frame := join(ethHeader(ETH_TYPE_LLDP),
+		tlv(LLDP_TLV_CHASSIS_ID, []byte("\x07dpid:1")),
+		tlv(LLDP_TLV_PORT_ID, []byte("\x02\x00\x00\x00\x03")),
+		tlv(LLDP_TLV_TTL, be16(120)),
+		tlv(5, []byte("s1")),
+		tlv(LLDP_TLV_END, nil))
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := p.Lldp
+	if string(l.ChassisID[1:]) != "dpid:1" || binary.BigEndian.Uint32(l.PortID[1:]) != 3 || l.TTL != 120 || len(l.TLVs) != 4 {
+		t.Log("Actual lldp is : ", l)
+		t.Error("Lldp is invalid.")
+	}
+
+	// port id is missing
+	frame = join(ethHeader(ETH_TYPE_LLDP), tlv(LLDP_TLV_CHASSIS_ID, []byte("\x07a")), tlv(LLDP_TLV_END, nil))
+	if _, err := Decode(frame); err == nil {
+		t.Error("Malformed lldp is decoded.")
+	}
+}
+
+func TestDecodeMPLS(t *testing.T) {
+	udp := udpHeader(1000, 2000, 0)
+	frame := join(ethHeader(ETH_TYPE_MPLS),
+		[]byte{0x00, 0x01, 0x00, 0x40}, // label 16
+		[]byte{0x00, 0x02, 0x1b, 0x3f}, // label 33, tc 5, bos
+		ipv4Header(IP_PROTO_UDP, len(udp)), udp)
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Mpls) != 2 || p.Mpls[0].Label != 16 || p.Mpls[0].BoS || p.Mpls[0].TTL != 64 {
+		t.Error("Outer label is invalid.")
+	}
+	if m := p.Mpls[1]; m.Label != 33 || m.TC != 5 || !m.BoS || m.TTL != 63 {
+		t.Log("Actual label is : ", m)
+		t.Error("Inner label is invalid.")
+	}
+	if p.Ipv4 == nil || p.Udp == nil || p.Udp.DstPort != 2000 {
+		t.Error("Payload of mpls is not decoded.")
+	}
+}
+
+/*****************************************************/
+/* L3, L4                                            */
+/*****************************************************/
+func TestDecodeIPv4(t *testing.T) {
+	tcp := make([]byte, 24)
+	binary.BigEndian.PutUint16(tcp[0:], 40000)
+	binary.BigEndian.PutUint16(tcp[2:], 80)
+	tcp[12] = 6 << 4
+	tcp[13] = TCP_SYN | TCP_ACK
+	payload := []byte("hello")
+	ip := ipv4Header(IP_PROTO_TCP, len(tcp)+len(payload))
+	// ethernet padding is not a payload
+	frame := join(ethHeader(ETH_TYPE_IPV4), ip, tcp, payload, make([]byte, 8))
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "Ethernet/IPv4/TCP" || p.IpProto() != IP_PROTO_TCP {
+		t.Log("Actual layers are : ", p)
+		t.Error("Layers are invalid.")
+	}
+	if p.Ipv4.DSCP != 10 || p.Ipv4.ECN != 1 || !p.Ipv4.Dst.Equal(net.IPv4(10, 0, 0, 2)) {
+		t.Log("Actual ipv4 is : ", p.Ipv4)
+		t.Error("Ipv4 is invalid.")
+	}
+	if p.Tcp.SrcPort != 40000 || p.Tcp.DstPort != 80 || p.Tcp.Flags != TCP_SYN|TCP_ACK || len(p.Tcp.Options) != 4 {
+		t.Log("Actual tcp is : ", p.Tcp)
+		t.Error("Tcp is invalid.")
+	}
+	if !bytes.Equal(p.Payload, payload) {
+		t.Log("Actual payload is : ", p.Payload)
+		t.Error("Payload is invalid.")
+	}
+
+	// l4 is not decoded in non-first fragment
+	binary.BigEndian.PutUint16(ip[6:], 100)
+	frame = join(ethHeader(ETH_TYPE_IPV4), ip, tcp, payload)
+	if p, err := Decode(frame); err != nil || p.Tcp != nil || len(p.Payload) != len(tcp)+len(payload) {
+		t.Error("Fragment is invalid.")
+	}
+}
+
+func TestDecodeIPv6(t *testing.T) {
+	udp := udpHeader(546, 547, 0)
+	hop := []byte{IPV6_EXT_DEST, 0, 1, 4, 0, 0, 0, 0}
+	dest := []byte{IPV6_EXT_FRAG, 1, 1, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	frag := []byte{IP_PROTO_UDP, 0, 0, 0, 0, 0, 0, 1}
+	ext := join(hop, dest, frag)
+	frame := join(ethHeader(ETH_TYPE_IPV6), ipv6Header(IPV6_EXT_HOP, len(ext)+len(udp)), ext, udp)
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := p.Ipv6
+	if ip.DSCP != 46 || ip.FlowLabel != 0x12345 || ip.HopLimit != 255 || !ip.Src.Equal(net.ParseIP("fe80::1")) {
+		t.Log("Actual ipv6 is : ", ip)
+		t.Error("Ipv6 is invalid.")
+	}
+	if len(ip.ExtHeaders) != 3 || ip.NextHeader != IPV6_EXT_HOP || ip.Protocol != IP_PROTO_UDP {
+		t.Log("Actual extension headers are : ", ip.ExtHeaders)
+		t.Error("Extension headers are invalid.")
+	}
+	if ip.ExtHeaderFlags() != IEH_HOP|IEH_DEST|IEH_FRAG {
+		t.Log("Actual flags are : ", ip.ExtHeaderFlags())
+		t.Error("Extension header flags are invalid.")
+	}
+	if p.Udp == nil || p.Udp.DstPort != 547 {
+		t.Error("Udp of the first fragment is not decoded.")
+	}
+
+	// l4 is not decoded in non-first fragment
+	frag[3] = 8
+	frame = join(ethHeader(ETH_TYPE_IPV6), ipv6Header(IPV6_EXT_HOP, len(ext)+len(udp)), hop, dest, frag, udp)
+	if p, err := Decode(frame); err != nil || p.Udp != nil {
+		t.Error("Fragment is invalid.")
+	}
+}
+
+func TestDecodeICMP(t *testing.T) {
+	echo := []byte{ICMP_ECHO_REQUEST, 0, 0, 0, 0, 1, 0, 2, 'p', 'i', 'n', 'g'}
+	frame := join(ethHeader(ETH_TYPE_IPV4), ipv4Header(IP_PROTO_ICMP, len(echo)), echo)
+	p, err := Decode(frame)
+	if err != nil || p.Icmpv4 == nil || p.Icmpv4.Type != ICMP_ECHO_REQUEST || string(p.Payload) != "ping" {
+		t.Log("Actual packet is : ", p, err)
+		t.Error("Icmpv4 is invalid.")
+	}
+
+	// neighbor solicitation with source link layer address
+	ns := join([]byte{ICMPV6_NEIGHBOR_SOL, 0, 0, 0, 0, 0, 0, 0}, net.ParseIP("fe80::2"),
+		[]byte{ICMPV6_OPT_SOURCE_LL, 1}, testSrc)
+	frame = join(ethHeader(ETH_TYPE_IPV6), ipv6Header(IP_PROTO_ICMPV6, len(ns)), ns)
+	p, err = Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	icmp := p.Icmpv6
+	if icmp.Type != ICMPV6_NEIGHBOR_SOL || !icmp.Target.Equal(net.ParseIP("fe80::2")) ||
+		icmp.SLL.String() != testSrc.String() || icmp.TLL != nil {
+		t.Log("Actual icmpv6 is : ", icmp)
+		t.Error("Neighbor solicitation is invalid.")
+	}
+}
+
+/*****************************************************/
+/* Tunnel                                            */
+/*****************************************************/
+func TestDecodeVXLAN(t *testing.T) {
+	arp := join(be16(1), be16(ETH_TYPE_IPV4), []byte{6, 4}, be16(2), make([]byte, 20))
+	inner := join(ethHeader(ETH_TYPE_ARP), arp)
+	vxlan := join([]byte{0x08, 0, 0, 0}, []byte{0x00, 0x12, 0x34, 0x00}, inner)
+	frame := join(ethHeader(ETH_TYPE_IPV4), ipv4Header(IP_PROTO_UDP, 8+len(vxlan)),
+		udpHeader(50000, VXLAN_PORT, len(vxlan)), vxlan)
+
+	p, err := Decode(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "Ethernet/IPv4/UDP/VXLAN[Ethernet/ARP]" {
+		t.Log("Actual layers are : ", p)
+		t.Error("Layers are invalid.")
+	}
+	if p.Vxlan.VNI != 0x1234 || p.Inner.Arp.Op != 2 {
+		t.Error("Vxlan is invalid.")
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	udp := udpHeader(1000, 2000, 0)
+	frame := join(ethHeader(ETH_TYPE_IPV4), ipv4Header(IP_PROTO_UDP, len(udp)), udp)
+
+	// layers before truncation are returned
+	p, err := Decode(frame[:14+20+4])
+	if err != ErrTruncated {
+		t.Log("Actual error is : ", err)
+		t.Error("Truncation is not detected.")
+	}
+	if p.Ethernet == nil || p.Ipv4 == nil || p.Udp != nil {
+		t.Log("Actual layers are : ", p)
+		t.Error("Decoded layers are invalid.")
+	}
+
+	for i := 0; i < len(frame); i++ {
+		if _, err := Decode(frame[:i]); err == nil {
+			t.Error("Truncated frame is decoded at ", i)
+		}
+	}
+}
+
+/*****************************************************/
+/* Serialize                                         */
+/*****************************************************/
+
+// serialize p, and decode it again
+func roundTrip(t *testing.T, p *Packet) ([]byte, *Packet) {
+	frame, err := p.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(frame)
+	if err != nil {
+		t.Log("Actual frame is : ", frame)
+		t.Fatal(err)
+	}
+	return frame, decoded
+}
+
+// checksum of ipv4 header and l4 segment are valid
+func validChecksums(p *Packet) bool {
+	var segment []byte
+	var pseudo uint32
+	switch {
+	case p.Ipv4 != nil:
+		hdr := p.Data[len(p.Data)-int(p.Ipv4.Length):]
+		if checksum(hdr[:p.Ipv4.IHL*4], 0) != 0 {
+			return false
+		}
+		segment = hdr[p.Ipv4.IHL*4:]
+		pseudo = sum16(hdr[12:20], 0) + uint32(p.Ipv4.Protocol) + uint32(len(segment))
+		if p.Icmpv4 != nil {
+			pseudo = 0
+		}
+	case p.Ipv6 != nil:
+		ext := 0
+		for _, h := range p.Ipv6.ExtHeaders {
+			ext += len(h.Data)
+		}
+		hdr := p.Data[len(p.Data)-int(p.Ipv6.Length)-40:]
+		segment = hdr[40+ext:]
+		pseudo = sum16(hdr[8:40], 0) + uint32(p.Ipv6.Protocol) + uint32(len(segment))
+	default:
+		return true
+	}
+	return checksum(segment, pseudo) == 0
+}
+
+func TestSerialize(t *testing.T) {
+	p := NewUDPPacket(testSrc, testDst, net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 1000, 53, []byte("query"))
+	p.Vlans = []*Dot1Q{{TPID: ETH_TYPE_QINQ, VID: 100}, {VID: 200, Priority: 3}}
+	p.Ipv4.DSCP = 46
+	frame, decoded := roundTrip(t, p)
+	if len(frame) != 14+8+20+8+5 || decoded.String() != "Ethernet/Dot1Q/Dot1Q/IPv4/UDP" {
+		t.Log("Actual layers are : ", decoded)
+		t.Error("Serialized frame is invalid.")
+	}
+	if decoded.Vlans[0].TPID != ETH_TYPE_QINQ || decoded.Vlans[1].TPID != ETH_TYPE_VLAN || decoded.Vlans[1].Priority != 3 {
+		t.Error("Vlan tags are invalid.")
+	}
+	if decoded.Ipv4.Length != 20+8+5 || decoded.Ipv4.Protocol != IP_PROTO_UDP || decoded.Ipv4.TTL != DEFAULT_TTL ||
+		decoded.Ipv4.DSCP != 46 || decoded.Udp.Length != 8+5 || string(decoded.Payload) != "query" {
+		t.Log("Actual ipv4 is : ", decoded.Ipv4)
+		t.Log("Actual udp is : ", decoded.Udp)
+		t.Error("Lengths are invalid.")
+	}
+	if !validChecksums(decoded) {
+		t.Error("Checksums are invalid.")
+	}
+
+	// tcp over ipv6 with extension header
+	p = NewIPv6Packet(testSrc, testDst, net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"))
+	p.Ipv6.ExtHeaders = []IPv6ExtHeader{{Type: IPV6_EXT_DEST, Data: make([]byte, 8)}}
+	p.Tcp = &TCP{SrcPort: 40000, DstPort: 443, Flags: TCP_SYN, Options: []byte{2, 4, 5, 0xb4, 1}}
+	_, decoded = roundTrip(t, p)
+	if decoded.Ipv6.NextHeader != IPV6_EXT_DEST || decoded.Ipv6.Protocol != IP_PROTO_TCP || decoded.Tcp.DataOffset != 7 {
+		t.Log("Actual ipv6 is : ", decoded.Ipv6)
+		t.Error("Next headers are invalid.")
+	}
+	if !validChecksums(decoded) {
+		t.Error("Checksums of tcp over ipv6 are invalid.")
+	}
+
+	// decoded frame is serialized as it is
+	decoded, _ = Decode(frame)
+	if again, err := decoded.Serialize(); err != nil || !bytes.Equal(again, frame) {
+		t.Log("Expected frame is : ", frame)
+		t.Log("Actual frame is   : ", again)
+		t.Error("Decoded frame is not serialized as it is.")
+	}
+
+	if _, err := (&Packet{Ethernet: &Ethernet{}, Udp: &UDP{}}).Serialize(); err == nil {
+		t.Error("Udp without ip layer is serialized.")
+	}
+}
+
+func TestSerializeTunnel(t *testing.T) {
+	inner := NewARPRequest(testSrc, net.IPv4(192, 168, 0, 1), net.IPv4(192, 168, 0, 2))
+	p := NewUDPPacket(testSrc, testDst, net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 50000, VXLAN_PORT, nil)
+	p.Vxlan = &VXLAN{VNI: 5000}
+	p.Inner = inner
+	p.Mpls = []*MPLS{{Label: 16, TTL: 64}, {Label: 17, TTL: 64}}
+	_, decoded := roundTrip(t, p)
+	if decoded.String() != "Ethernet/MPLS/MPLS/IPv4/UDP/VXLAN[Ethernet/ARP]" {
+		t.Log("Actual layers are : ", decoded)
+		t.Fatal("Layers are invalid.")
+	}
+	if decoded.Mpls[0].BoS || !decoded.Mpls[1].BoS || decoded.Vxlan.VNI != 5000 ||
+		!decoded.Inner.Arp.TPA.Equal(net.IPv4(192, 168, 0, 2)) {
+		t.Error("Tunnel is invalid.")
+	}
+}
+
+/*****************************************************/
+/* Builder                                           */
+/*****************************************************/
+func TestARPReply(t *testing.T) {
+	req := NewARPRequest(testSrc, net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2))
+	_, decoded := roundTrip(t, req)
+	if decoded.Ethernet.Dst.String() != Broadcast.String() || decoded.Arp.Op != 1 {
+		t.Error("Arp request is invalid.")
+	}
+
+	reply, err := decoded.ARPReply(testDst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, decoded = roundTrip(t, reply)
+	arp := decoded.Arp
+	if arp.Op != 2 || arp.SHA.String() != testDst.String() || !arp.SPA.Equal(net.IPv4(10, 0, 0, 2)) ||
+		arp.THA.String() != testSrc.String() || !arp.TPA.Equal(net.IPv4(10, 0, 0, 1)) ||
+		decoded.Ethernet.Dst.String() != testSrc.String() {
+		t.Log("Actual arp is : ", arp)
+		t.Error("Arp reply is invalid.")
+	}
+	if _, err := decoded.ARPReply(testDst); err == nil {
+		t.Error("Arp reply is answered.")
+	}
+}
+
+func TestICMPReply(t *testing.T) {
+	req := NewICMPEchoRequest(testSrc, testDst, net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 7, 1, []byte("ping"))
+	_, decoded := roundTrip(t, req)
+	if !validChecksums(decoded) {
+		t.Error("Checksum of echo request is invalid.")
+	}
+
+	reply, err := decoded.ICMPEchoReply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, decoded = roundTrip(t, reply)
+	if decoded.Icmpv4.Type != ICMP_ECHO_REPLY || !bytes.Equal(decoded.Icmpv4.Rest, []byte{0, 7, 0, 1}) ||
+		string(decoded.Payload) != "ping" || !decoded.Ipv4.Dst.Equal(net.IPv4(10, 0, 0, 1)) ||
+		decoded.Ethernet.Dst.String() != testSrc.String() || !validChecksums(decoded) {
+		t.Log("Actual icmp is : ", decoded.Icmpv4)
+		t.Error("Echo reply is invalid.")
+	}
+
+	udp := NewUDPPacket(testSrc, testDst, net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 1000, 53, []byte("query"))
+	router := net.HardwareAddr{0x02, 0, 0, 0, 0, 0xfe}
+	unreach, err := udp.ICMPUnreachable(ICMP_PORT_UNREACHABLE, router, net.IPv4(10, 0, 0, 254))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, decoded = roundTrip(t, unreach)
+	if decoded.Icmpv4.Type != ICMP_UNREACHABLE || decoded.Icmpv4.Code != ICMP_PORT_UNREACHABLE ||
+		len(decoded.Payload) != 20+8 || !validChecksums(decoded) {
+		t.Log("Actual packet is : ", decoded)
+		t.Error("Unreachable is invalid.")
+	}
+	// quoted header refers the original packet
+	quoted, _ := Decode(append(append([]byte(nil), decoded.Data[:14]...), decoded.Payload...))
+	if quoted.Udp == nil || quoted.Udp.DstPort != 53 || !quoted.Ipv4.Src.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Error("Quoted packet is invalid.")
+	}
+}
+
+func TestLLDPProbe(t *testing.T) {
+	_, decoded := roundTrip(t, NewLLDPProbe(testSrc, 0x1234, 5))
+	if decoded.Ethernet.Dst.String() != LLDPMulticast.String() || decoded.Lldp.TTL != DEFAULT_LLDP_TTL {
+		t.Error("Lldp probe is invalid.")
+	}
+	dpid, portNo, err := decoded.Lldp.ProbeSource()
+	if err != nil || dpid != 0x1234 || portNo != 5 {
+		t.Log("Actual source is : ", dpid, portNo, err)
+		t.Error("Source of probe is invalid.")
+	}
+}
@@ -0,0 +1,397 @@
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+/**
+ * Serialize returns the frame of layers in p. Lengths, checksums, header
+ * lengths, ether types, ip protocols, next headers and mpls bottom of stack
+ * are computed from the layers, and other fields are written as they are.
+ * Payload is appended to the innermost layer. If Vxlan is set, the frame of
+ * Inner is used as its payload instead.
+ */
+func (p *Packet) Serialize() ([]byte, error) {
+	if p.Ethernet == nil {
+		return nil, errors.New("ethernet layer is missing.")
+	}
+	body, ethType, err := p.serializeEtherType()
+	if err != nil {
+		return nil, err
+	}
+
+	// mpls labels
+	if len(p.Mpls) > 0 {
+		labels := make([]byte, 4*len(p.Mpls))
+		for i, m := range p.Mpls {
+			v := m.Label<<12 | uint32(m.TC&0x7)<<9 | uint32(m.TTL)
+			if i == len(p.Mpls)-1 {
+				v |= 0x100
+			}
+			binary.BigEndian.PutUint32(labels[4*i:], v)
+		}
+		body = append(labels, body...)
+		ethType = ETH_TYPE_MPLS
+		if p.Ethernet.EtherType == ETH_TYPE_MPLSMC {
+			ethType = ETH_TYPE_MPLSMC
+		}
+	}
+
+	frame := make([]byte, 14, 14+4*len(p.Vlans)+len(body))
+	copy(frame[0:6], p.Ethernet.Dst)
+	copy(frame[6:12], p.Ethernet.Src)
+	// vlan tags, each followed by the type of the next
+	next := ethType
+	for i := len(p.Vlans) - 1; i >= 0; i-- {
+		v := p.Vlans[i]
+		tag := make([]byte, 4)
+		tci := uint16(v.Priority&0x7)<<13 | v.VID&0x0fff
+		if v.DEI {
+			tci |= 0x1000
+		}
+		binary.BigEndian.PutUint16(tag[0:], tci)
+		binary.BigEndian.PutUint16(tag[2:], next)
+		body = append(tag, body...)
+		next = v.TPID
+		if next == 0 {
+			next = ETH_TYPE_VLAN
+		}
+	}
+	binary.BigEndian.PutUint16(frame[12:], next)
+	return append(frame, body...), nil
+}
+
+// payload of ethernet or mpls, and its ether type
+func (p *Packet) serializeEtherType() ([]byte, uint16, error) {
+	layers := 0
+	for _, present := range []bool{p.Arp != nil, p.Lldp != nil, p.Ipv4 != nil, p.Ipv6 != nil} {
+		if present {
+			layers++
+		}
+	}
+	if layers > 1 {
+		return nil, 0, errors.New("multiple network layers are set.")
+	}
+
+	switch {
+	case p.Arp != nil:
+		return p.Arp.serialize(), ETH_TYPE_ARP, nil
+	case p.Lldp != nil:
+		return p.Lldp.serialize(), ETH_TYPE_LLDP, nil
+	case p.Ipv4 != nil:
+		segment, proto, err := p.serializeL4()
+		if err != nil {
+			return nil, 0, err
+		}
+		return p.Ipv4.serialize(proto, segment), ETH_TYPE_IPV4, nil
+	case p.Ipv6 != nil:
+		segment, proto, err := p.serializeL4()
+		if err != nil {
+			return nil, 0, err
+		}
+		data, err := p.Ipv6.serialize(proto, segment)
+		return data, ETH_TYPE_IPV6, err
+	}
+	if p.hasL4() {
+		return nil, 0, errors.New("ip layer is missing.")
+	}
+	return p.Payload, p.Ethernet.EtherType, nil
+}
+
+func (p *Packet) hasL4() bool {
+	return p.Tcp != nil || p.Udp != nil || p.Sctp != nil || p.Icmpv4 != nil || p.Icmpv6 != nil
+}
+
+// l4 segment with zero checksum except sctp, and its ip protocol
+func (p *Packet) serializeL4() ([]byte, int, error) {
+	layers := 0
+	for _, present := range []bool{p.Tcp != nil, p.Udp != nil, p.Sctp != nil, p.Icmpv4 != nil, p.Icmpv6 != nil} {
+		if present {
+			layers++
+		}
+	}
+	if layers > 1 {
+		return nil, 0, errors.New("multiple transport layers are set.")
+	}
+	if p.Icmpv4 != nil && p.Ipv4 == nil {
+		return nil, 0, errors.New("icmpv4 is not over ipv4.")
+	}
+	if p.Icmpv6 != nil && p.Ipv6 == nil {
+		return nil, 0, errors.New("icmpv6 is not over ipv6.")
+	}
+
+	payload := p.Payload
+	if p.Vxlan != nil {
+		if p.Udp == nil {
+			return nil, 0, errors.New("vxlan is not over udp.")
+		}
+		if p.Inner != nil {
+			inner, err := p.Inner.Serialize()
+			if err != nil {
+				return nil, 0, err
+			}
+			payload = inner
+		}
+		payload = append(p.Vxlan.serialize(), payload...)
+	}
+
+	switch {
+	case p.Tcp != nil:
+		return append(p.Tcp.serialize(), payload...), IP_PROTO_TCP, nil
+	case p.Udp != nil:
+		return p.Udp.serialize(payload), IP_PROTO_UDP, nil
+	case p.Sctp != nil:
+		segment := append(p.Sctp.serialize(), payload...)
+		binary.LittleEndian.PutUint32(segment[8:], crc32.Checksum(segment, castagnoli))
+		return segment, IP_PROTO_SCTP, nil
+	case p.Icmpv4 != nil:
+		return append(p.Icmpv4.serialize(), payload...), IP_PROTO_ICMP, nil
+	case p.Icmpv6 != nil:
+		return append(p.Icmpv6.serialize(), payload...), IP_PROTO_ICMPV6, nil
+	}
+	// protocol of ip layer is used
+	return payload, -1, nil
+}
+
+// offset of checksum in l4 segment, or -1 if it is not computed with ip
+func checksumOffset(proto uint8) int {
+	switch proto {
+	case IP_PROTO_TCP:
+		return 16
+	case IP_PROTO_UDP:
+		return 6
+	case IP_PROTO_ICMP, IP_PROTO_ICMPV6:
+		return 2
+	}
+	return -1
+}
+
+// write checksum of segment with partial sum of pseudo header
+func putChecksum(segment []byte, proto uint8, pseudo uint32) {
+	off := checksumOffset(proto)
+	if off < 0 || len(segment) < off+2 {
+		return
+	}
+	if proto == IP_PROTO_ICMP {
+		pseudo = 0
+	}
+	sum := checksum(segment, pseudo)
+	if sum == 0 && proto == IP_PROTO_UDP {
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(segment[off:], sum)
+}
+
+/*****************************************************/
+/* Layers                                            */
+/*****************************************************/
+func (a *ARP) serialize() []byte {
+	data := make([]byte, 28)
+	binary.BigEndian.PutUint16(data[0:], a.HwType)
+	binary.BigEndian.PutUint16(data[2:], a.ProtoType)
+	data[4] = 6
+	data[5] = 4
+	binary.BigEndian.PutUint16(data[6:], a.Op)
+	copy(data[8:14], a.SHA)
+	copy(data[14:18], a.SPA.To4())
+	copy(data[18:24], a.THA)
+	copy(data[24:28], a.TPA.To4())
+	return data
+}
+
+// mandatory tlvs, other tlvs and end of lldpdu
+func (l *LLDP) serialize() []byte {
+	data := make([]byte, 0, 64)
+	tlv := func(typ uint8, value []byte) {
+		data = append(data, byte(typ<<1|uint8(len(value)>>8)), byte(len(value)))
+		data = append(data, value...)
+	}
+	ttl := make([]byte, 2)
+	binary.BigEndian.PutUint16(ttl, l.TTL)
+	tlv(LLDP_TLV_CHASSIS_ID, l.ChassisID)
+	tlv(LLDP_TLV_PORT_ID, l.PortID)
+	tlv(LLDP_TLV_TTL, ttl)
+	for _, t := range l.TLVs {
+		if t.Type > LLDP_TLV_TTL {
+			tlv(t.Type, t.Value)
+		}
+	}
+	tlv(LLDP_TLV_END, nil)
+	return data
+}
+
+// proto is -1 if protocol of ip is used
+func (ip *IPv4) serialize(proto int, payload []byte) []byte {
+	options := ip.Options
+	if len(options)%4 != 0 {
+		options = append(append([]byte(nil), options...), make([]byte, 4-len(options)%4)...)
+	}
+	hlen := 20 + len(options)
+	data := make([]byte, hlen, hlen+len(payload))
+	data[0] = 4<<4 | uint8(hlen/4)
+	data[1] = ip.DSCP<<2 | ip.ECN&0x3
+	binary.BigEndian.PutUint16(data[2:], uint16(hlen+len(payload)))
+	binary.BigEndian.PutUint16(data[4:], ip.Id)
+	binary.BigEndian.PutUint16(data[6:], uint16(ip.Flags&0x7)<<13|ip.FragOffset&0x1fff)
+	data[8] = ip.TTL
+	data[9] = ip.Protocol
+	if proto >= 0 {
+		data[9] = uint8(proto)
+	}
+	copy(data[12:16], ip.Src.To4())
+	copy(data[16:20], ip.Dst.To4())
+	copy(data[20:], options)
+	binary.BigEndian.PutUint16(data[10:], checksum(data[:hlen], 0))
+
+	segment := append([]byte(nil), payload...)
+	if proto >= 0 {
+		pseudo := sum16(data[12:20], 0) + uint32(proto) + uint32(len(segment))
+		putChecksum(segment, uint8(proto), pseudo)
+	}
+	return append(data, segment...)
+}
+
+// proto is -1 if protocol of ip is used
+func (ip *IPv6) serialize(proto int, payload []byte) ([]byte, error) {
+	upper := ip.Protocol
+	if proto >= 0 {
+		upper = uint8(proto)
+	}
+
+	// extension headers, chained by next header of each
+	ext := make([]byte, 0)
+	for i, h := range ip.ExtHeaders {
+		next := upper
+		if i+1 < len(ip.ExtHeaders) {
+			next = ip.ExtHeaders[i+1].Type
+		}
+		switch h.Type {
+		case IPV6_EXT_ESP, IPV6_EXT_NONEXT:
+			ext = append(ext, h.Data...)
+			continue
+		}
+		if len(h.Data) < 8 || len(h.Data)%4 != 0 {
+			return nil, errMalformed("ipv6", "extension header length is invalid")
+		}
+		hdr := append([]byte(nil), h.Data...)
+		hdr[0] = next
+		ext = append(ext, hdr...)
+	}
+
+	data := make([]byte, 40, 40+len(ext)+len(payload))
+	tc := uint32(ip.DSCP<<2 | ip.ECN&0x3)
+	binary.BigEndian.PutUint32(data[0:], 6<<28|tc<<20|ip.FlowLabel&0xfffff)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(ext)+len(payload)))
+	data[6] = upper
+	if len(ip.ExtHeaders) > 0 {
+		data[6] = ip.ExtHeaders[0].Type
+	}
+	data[7] = ip.HopLimit
+	copy(data[8:24], ip.Src.To16())
+	copy(data[24:40], ip.Dst.To16())
+
+	segment := append([]byte(nil), payload...)
+	if proto >= 0 {
+		pseudo := sum16(data[8:40], 0) + uint32(proto) + uint32(len(segment))
+		putChecksum(segment, uint8(proto), pseudo)
+	}
+	data = append(data, ext...)
+	return append(data, segment...), nil
+}
+
+func (t *TCP) serialize() []byte {
+	options := t.Options
+	if len(options)%4 != 0 {
+		options = append(append([]byte(nil), options...), make([]byte, 4-len(options)%4)...)
+	}
+	hlen := 20 + len(options)
+	data := make([]byte, hlen)
+	binary.BigEndian.PutUint16(data[0:], t.SrcPort)
+	binary.BigEndian.PutUint16(data[2:], t.DstPort)
+	binary.BigEndian.PutUint32(data[4:], t.Seq)
+	binary.BigEndian.PutUint32(data[8:], t.Ack)
+	data[12] = uint8(hlen/4) << 4
+	data[13] = t.Flags
+	binary.BigEndian.PutUint16(data[14:], t.Window)
+	binary.BigEndian.PutUint16(data[18:], t.Urgent)
+	copy(data[20:], options)
+	return data
+}
+
+func (u *UDP) serialize(payload []byte) []byte {
+	data := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(data[0:], u.SrcPort)
+	binary.BigEndian.PutUint16(data[2:], u.DstPort)
+	binary.BigEndian.PutUint16(data[4:], uint16(8+len(payload)))
+	return append(data, payload...)
+}
+
+func (s *SCTP) serialize() []byte {
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint16(data[0:], s.SrcPort)
+	binary.BigEndian.PutUint16(data[2:], s.DstPort)
+	binary.BigEndian.PutUint32(data[4:], s.VerificationTag)
+	return data
+}
+
+func (i *ICMPv4) serialize() []byte {
+	data := make([]byte, 8)
+	data[0] = i.Type
+	data[1] = i.Code
+	copy(data[4:8], i.Rest)
+	return data
+}
+
+func (i *ICMPv6) serialize() []byte {
+	data := make([]byte, 8, 32)
+	data[0] = i.Type
+	data[1] = i.Code
+	copy(data[4:8], i.Rest)
+	if i.Type != ICMPV6_NEIGHBOR_SOL && i.Type != ICMPV6_NEIGHBOR_ADV {
+		return data
+	}
+	data = append(data, make([]byte, 16)...)
+	copy(data[8:24], i.Target.To16())
+	if i.Type == ICMPV6_NEIGHBOR_SOL && i.SLL != nil {
+		data = append(append(data, ICMPV6_OPT_SOURCE_LL, 1), i.SLL...)
+	}
+	if i.Type == ICMPV6_NEIGHBOR_ADV && i.TLL != nil {
+		data = append(append(data, ICMPV6_OPT_TARGET_LL, 1), i.TLL...)
+	}
+	return data
+}
+
+func (v *VXLAN) serialize() []byte {
+	data := make([]byte, 8)
+	data[0] = v.Flags | 0x08
+	binary.BigEndian.PutUint32(data[4:], v.VNI<<8)
+	return data
+}
+
+/*****************************************************/
+/* Checksum                                          */
+/*****************************************************/
+
+// sum of 16bit words
+func sum16(b []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// internet checksum of b, with partial sum of pseudo header
+func checksum(b []byte, sum uint32) uint16 {
+	sum = sum16(b, sum)
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,130 @@
+package gofc
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+/**
+ * HandlerPanic describes a panic recovered from an application handler.
+ */
+type HandlerPanic struct {
+	// name of application which panicked
+	App string
+	// type of message being handled, e.g. "*ofp13.OfpPacketIn"
+	MessageType string
+	// datapath id of the switch which sent the message
+	DatapathId uint64
+	// value passed to panic
+	Value interface{}
+	// stack trace of the panicked goroutine
+	Stack []byte
+	// number of panics of the application so far
+	Count int
+	// true if the application was disabled by this panic
+	Disabled bool
+}
+
+// PanicHook is called each time a handler panics.
+type PanicHook func(p *HandlerPanic)
+
+// defaultPanicHook logs the panic with its stack.
+func defaultPanicHook(p *HandlerPanic) {
+	fmt.Println(fmt.Sprintf("application %s panicked while handling %s from dpid %d: %v",
+		p.App, p.MessageType, p.DatapathId, p.Value))
+	fmt.Println(string(p.Stack))
+	if p.Disabled {
+		fmt.Println("application", p.App, "is disabled after", p.Count, "panics")
+	}
+}
+
+// SetPanicHook replaces the hook called when a handler panics.
+// nil restores the default hook, which logs to stdout.
+func (manager *AppManager) SetPanicHook(hook PanicHook) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if hook == nil {
+		hook = defaultPanicHook
+	}
+	manager.panicHook = hook
+}
+
+// SetPanicLimit sets the number of panics after which an application is
+// disabled. Disabled applications are no longer dispatched messages, while
+// the switch connection and other applications keep working.
+// 0 means applications are never disabled, which is the default.
+func (manager *AppManager) SetPanicLimit(limit int) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.panicLimit = limit
+}
+
+// IsApplicationDisabled reports whether app was disabled by panics.
+func (manager *AppManager) IsApplicationDisabled(app interface{}) bool {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	for _, entry := range manager.applications {
+		if entry.app == app {
+			return entry.disabled
+		}
+	}
+	return false
+}
+
+// EnableApplication re-enables app disabled by panics and resets its count.
+func (manager *AppManager) EnableApplication(app interface{}) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	for _, entry := range manager.applications {
+		if entry.app == app {
+			entry.disabled = false
+			entry.panics = 0
+			return nil
+		}
+	}
+	return fmt.Errorf("application %s is not registered.", appName(app))
+}
+
+// count a panic of app, disable it if it exceeds the limit and call the hook.
+func (manager *AppManager) handlePanic(app interface{}, p *HandlerPanic) {
+	manager.mutex.Lock()
+	hook := manager.panicHook
+	for _, entry := range manager.applications {
+		if entry.app != app {
+			continue
+		}
+		entry.panics++
+		p.Count = entry.panics
+		if manager.panicLimit > 0 && entry.panics >= manager.panicLimit && !entry.disabled {
+			entry.disabled = true
+			p.Disabled = true
+		}
+		break
+	}
+	manager.mutex.Unlock()
+
+	hook(p)
+}
+
+/**
+ * invoke handler of app, recovering from panic in it.
+ * panicked handler doesn't consume the message.
+ */
+func (dp *Datapath) safeInvokeHandler(app interface{}, msg ofp13.OFMessage) (result EventResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			p := &HandlerPanic{
+				App:         appName(app),
+				MessageType: fmt.Sprintf("%T", msg),
+				DatapathId:  dp.datapathId,
+				Value:       r,
+				Stack:       debug.Stack(),
+			}
+			GetAppManager().handlePanic(app, p)
+			result = EventContinue
+		}
+	}()
+	return dp.invokeHandler(app, msg)
+}
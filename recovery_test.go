@@ -0,0 +1,66 @@
+package gofc
+
+import (
+	"testing"
+
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+type panicApp struct{}
+
+func (app *panicApp) Name() string                   { return "panic" }
+func (app *panicApp) Init(manager *AppManager) error { return nil }
+func (app *panicApp) Start() error                   { return nil }
+func (app *panicApp) Stop() error                    { return nil }
+
+func (app *panicApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *Datapath) EventResult {
+	panic("handler bug")
+}
+
+/*****************************************************/
+/* Recovery                                          */
+/*****************************************************/
+func TestHandlerPanicRecovery(t *testing.T) {
+	withAppManager(func(manager *AppManager) {
+		trace := make([]string, 0)
+		panics := make([]*HandlerPanic, 0)
+		app := &panicApp{}
+		manager.RegistApplicationWithPriority(app, 100)
+		manager.RegistApplication(&recordApp{"forwarding", &trace})
+		manager.SetPanicHook(func(p *HandlerPanic) {
+			panics = append(panics, p)
+		})
+		manager.SetPanicLimit(2)
+
+		dp := NewDatapath(nil)
+		dp.datapathId = 0x1234
+		for i := 0; i < 3; i++ {
+			dp.dispatchHandler(ofp13.NewOfpPacketIn())
+		}
+
+		expect := []string{"forwarding", "forwarding", "forwarding"}
+		if !equalTrace(expect, trace) {
+			t.Log("Expected trace is : ", expect)
+			t.Log("Actual trace is   : ", trace)
+			t.Error("Panic in handler stopped dispatching.")
+		}
+		if len(panics) != 2 {
+			t.Fatalf("panic hook was called %d times, expected 2.", len(panics))
+		}
+		p := panics[0]
+		if p.App != "panic" || p.MessageType != "*ofp13.OfpPacketIn" ||
+			p.DatapathId != 0x1234 || p.Value != "handler bug" || len(p.Stack) == 0 {
+			t.Log("Actual panic is : ", p.App, p.MessageType, p.DatapathId, p.Value)
+			t.Error("Panic is not reported correctly.")
+		}
+		if p.Disabled || !panics[1].Disabled || !manager.IsApplicationDisabled(app) {
+			t.Error("Application is not disabled after reaching panic limit.")
+		}
+
+		manager.EnableApplication(app)
+		dp.dispatchHandler(ofp13.NewOfpPacketIn())
+		if manager.IsApplicationDisabled(app) || len(panics) != 3 || panics[2].Count != 1 {
+			t.Error("Failed to re-enable application.")
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/Kmotiko/gofc"
+	"github.com/Kmotiko/gofc/builder"
 	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
 )
 
@@ -17,43 +18,23 @@ func NewSampleController() *SampleController {
 }
 
 func (c *SampleController) HandleSwitchFeatures(msg *ofp13.OfpSwitchFeatures, dp *gofc.Datapath) {
-	// create match
-	ethdst, _ := ofp13.NewOxmEthDst("00:00:00:00:00:00")
-	if ethdst == nil {
-		fmt.Println(ethdst)
+	// create flow mod
+	fm, err := builder.Flow().
+		Match(builder.EthDst("00:00:00:00:00:00")).
+		Apply(builder.SetField(builder.EthDst("11:22:33:44:55:66"))).
+		Flags(ofp13.OFPFF_SEND_FLOW_REM).
+		Modify()
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	match := ofp13.NewOfpMatch()
-	match.Append(ethdst)
-
-	// create Instruction
-	instruction := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
-
-	// create actions
-	seteth, _ := ofp13.NewOxmEthDst("11:22:33:44:55:66")
-	instruction.Append(ofp13.NewOfpActionSetField(seteth))
-
-	// append Instruction
-	instructions := make([]ofp13.OfpInstruction, 0)
-	instructions = append(instructions, instruction)
-
-	// create flow mod
-	fm := ofp13.NewOfpFlowModModify(
-		0, // cookie
-		0, // cookie mask
-		0, // tableid
-		0, // priority
-		ofp13.OFPFF_SEND_FLOW_REM,
-		match,
-		instructions,
-	)
 
 	// send FlowMod
 	dp.Send(fm)
 
 	// Create and send AggregateStatsRequest
 	mf := ofp13.NewOfpMatch()
-	mf.Append(ethdst)
+	mf.Append(fm.Match.OxmFields[0])
 	mp := ofp13.NewOfpAggregateStatsRequest(0, 0, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, mf)
 	dp.Send(mp)
 }
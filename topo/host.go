@@ -0,0 +1,247 @@
+package topo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// frames received by host are queued up to this size, and dropped over it
+var DEFAULT_HOST_QUEUE_SIZE = 1024
+
+const (
+	ethTypeIPv4 = 0x0800
+	ethTypeARP  = 0x0806
+	ipProtoUDP  = 17
+)
+
+// port of udp probe sent by Ping
+const PROBE_PORT = 9
+
+var broadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+/**
+ * Host is an end node with a mac and ipv4 address. It answers arp requests
+ * for its address, and queues all received frames to be read by Recv.
+ */
+type Host struct {
+	name    string
+	HwAddr  net.HardwareAddr
+	IP      net.IP
+	network *Network
+	recv    chan []byte
+	probe   uint64
+}
+
+// AddHost adds host of given name, mac address and ipv4 address.
+func (n *Network) AddHost(name string, hwAddr string, ip string) (*Host, error) {
+	mac, err := net.ParseMAC(hwAddr)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.ParseIP(ip).To4()
+	if addr == nil {
+		return nil, fmt.Errorf("invalid ipv4 address %s.", ip)
+	}
+	h := &Host{name: name, HwAddr: mac, IP: addr, network: n}
+	h.recv = make(chan []byte, DEFAULT_HOST_QUEUE_SIZE)
+
+	n.mu.Lock()
+	n.hosts = append(n.hosts, h)
+	n.mu.Unlock()
+	return h, nil
+}
+
+// Hosts returns hosts in added order.
+func (n *Network) Hosts() []*Host {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Host(nil), n.hosts...)
+}
+
+// Host returns host named name, or nil.
+func (n *Network) Host(name string) *Host {
+	for _, h := range n.Hosts() {
+		if h.name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+func (h *Host) Name() string {
+	return h.name
+}
+
+/**
+ * Send sends frame to the link of host. Frame is dropped if the host is not
+ * linked or the link is down.
+ */
+func (h *Host) Send(frame []byte) {
+	h.network.transmit(Endpoint{h, 0}, frame)
+}
+
+/**
+ * Recv returns the next received frame, or ErrTimeout if no frame is
+ * received within timeout.
+ */
+func (h *Host) Recv(timeout time.Duration) ([]byte, error) {
+	select {
+	case frame := <-h.recv:
+		return frame, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+func (h *Host) receive(frame []byte) {
+	if reply := h.arpReply(frame); reply != nil {
+		h.Send(reply)
+	}
+	select {
+	case h.recv <- frame:
+	default:
+	}
+}
+
+/*****************************************************/
+/* Frame                                             */
+/*****************************************************/
+
+/**
+ * UDPFrame returns udp over ipv4 frame from host to dst.
+ */
+func (h *Host) UDPFrame(dst *Host, dstPort uint16, payload []byte) []byte {
+	frame := make([]byte, 14+20+8+len(payload))
+	copy(frame[0:], dst.HwAddr)
+	copy(frame[6:], h.HwAddr)
+	binary.BigEndian.PutUint16(frame[12:], ethTypeIPv4)
+
+	ip := frame[14:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+8+len(payload)))
+	ip[8] = 64
+	ip[9] = ipProtoUDP
+	copy(ip[12:], h.IP)
+	copy(ip[16:], dst.IP)
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip[:20], 0))
+
+	udp := ip[20:]
+	binary.BigEndian.PutUint16(udp[0:], dstPort)
+	binary.BigEndian.PutUint16(udp[2:], dstPort)
+	binary.BigEndian.PutUint16(udp[4:], uint16(8+len(payload)))
+	copy(udp[8:], payload)
+	pseudo := sum16(ip[12:20], 0) + ipProtoUDP + uint32(len(udp))
+	if sum := checksum(udp, pseudo); sum != 0 {
+		binary.BigEndian.PutUint16(udp[6:], sum)
+	} else {
+		binary.BigEndian.PutUint16(udp[6:], 0xffff)
+	}
+	return frame
+}
+
+/**
+ * ARPRequest returns broadcast arp request for ip from host.
+ */
+func (h *Host) ARPRequest(ip net.IP) []byte {
+	return arp(1, h.HwAddr, h.IP, broadcast, net.HardwareAddr(make([]byte, 6)), ip.To4())
+}
+
+// arp reply to frame if it is arp request for address of host, or nil
+func (h *Host) arpReply(frame []byte) []byte {
+	if len(frame) < 14+28 || binary.BigEndian.Uint16(frame[12:]) != ethTypeARP {
+		return nil
+	}
+	body := frame[14:]
+	if binary.BigEndian.Uint16(body[6:]) != 1 || !bytes.Equal(body[24:28], h.IP) {
+		return nil
+	}
+	sha := net.HardwareAddr(body[8:14])
+	spa := net.IP(body[14:18])
+	return arp(2, h.HwAddr, h.IP, sha, sha, spa)
+}
+
+func arp(op uint16, sha net.HardwareAddr, spa net.IP, dst net.HardwareAddr, tha net.HardwareAddr, tpa net.IP) []byte {
+	frame := make([]byte, 14+28)
+	copy(frame[0:], dst)
+	copy(frame[6:], sha)
+	binary.BigEndian.PutUint16(frame[12:], ethTypeARP)
+
+	body := frame[14:]
+	binary.BigEndian.PutUint16(body[0:], 1) // ethernet
+	binary.BigEndian.PutUint16(body[2:], ethTypeIPv4)
+	body[4] = 6
+	body[5] = 4
+	binary.BigEndian.PutUint16(body[6:], op)
+	copy(body[8:], sha)
+	copy(body[14:], spa)
+	copy(body[18:], tha)
+	copy(body[24:], tpa)
+	return frame
+}
+
+// sum of 16bit words
+func sum16(b []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// internet checksum of b, with partial sum of pseudo header
+func checksum(b []byte, sum uint32) uint16 {
+	sum = sum16(b, sum)
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+/*****************************************************/
+/* Reachability                                      */
+/*****************************************************/
+
+/**
+ * Ping sends udp probe from src to dst, and waits until dst receives it.
+ * Other frames received by dst meanwhile are discarded.
+ */
+func Ping(src *Host, dst *Host, timeout time.Duration) error {
+	payload := []byte(fmt.Sprintf("probe %s %d", src.name, atomic.AddUint64(&src.probe, 1)))
+	src.Send(src.UDPFrame(dst, PROBE_PORT, payload))
+
+	deadline := time.Now().Add(timeout)
+	for {
+		frame, err := dst.Recv(deadline.Sub(time.Now()))
+		if err != nil {
+			return fmt.Errorf("%s can't reach %s.", src.name, dst.name)
+		}
+		if bytes.HasSuffix(frame, payload) {
+			return nil
+		}
+	}
+}
+
+/**
+ * PingAll pings between all pairs of hosts, and returns error of the first
+ * unreachable pair.
+ */
+func (n *Network) PingAll(timeout time.Duration) error {
+	hosts := n.Hosts()
+	for _, src := range hosts {
+		for _, dst := range hosts {
+			if src == dst {
+				continue
+			}
+			if err := Ping(src, dst, timeout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package topo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Kmotiko/gofc"
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+	"github.com/Kmotiko/gofc/ofswitch"
+)
+
+// frames delivered at once are limited to stop broadcast storm in loop
+// topology. frames over this limit are dropped.
+var MAX_DELIVERIES = 100000
+
+var ErrTimeout = errors.New("timeout.")
+
+/**
+ * Node is a switch or a host in the network.
+ */
+type Node interface {
+	Name() string
+}
+
+/**
+ * Endpoint is a port of node. Port number of host is always 0.
+ */
+type Endpoint struct {
+	Node   Node
+	PortNo uint32
+}
+
+func (e Endpoint) String() string {
+	return fmt.Sprintf("%s:%d", e.Node.Name(), e.PortNo)
+}
+
+/**
+ * Link connects two endpoints. Frames are not carried while it is down.
+ */
+type Link struct {
+	A       Endpoint
+	B       Endpoint
+	network *Network
+	up      bool
+}
+
+/**
+ * Hop is a frame carried by link, recorded in trace of the network.
+ */
+type Hop struct {
+	From  Endpoint
+	To    Endpoint
+	Frame []byte
+}
+
+func (h Hop) String() string {
+	return fmt.Sprintf("%s -> %s (%d bytes)", h.From, h.To, len(h.Frame))
+}
+
+/**
+ * Network is a virtual topology of emulated switches and hosts connected by
+ * links, like mininet. Frames sent out from a port are delivered to the
+ * peer of its link, and recorded in the trace.
+ * Frames are delivered in order on the goroutine which sent the first of
+ * them, so a frame sent by a host reached every destination when Send
+ * returns, unless it is relayed by the controller.
+ */
+type Network struct {
+	mu       sync.Mutex
+	switches []*Switch
+	hosts    []*Host
+	links    []*Link
+	ports    map[Endpoint]*Link
+	trace    []Hop
+	queue    []Hop
+	draining bool
+}
+
+/**
+ * ctor
+ */
+func NewNetwork() *Network {
+	n := new(Network)
+	n.ports = make(map[Endpoint]*Link)
+	return n
+}
+
+/**
+ * Linear creates network of n switches connected in line, and a host
+ * connected to each switch, like linear topology of mininet.
+ * Switches are named s1...sn with datapath id 1...n, and hosts are named
+ * h1...hn with mac address 00:00:00:00:00:0x and ip address 10.0.0.x.
+ */
+func Linear(n int) (*Network, error) {
+	network := NewNetwork()
+	var prev *Switch
+	for i := 1; i <= n; i++ {
+		s := network.AddSwitch(fmt.Sprintf("s%d", i), uint64(i))
+		h, err := network.AddHost(fmt.Sprintf("h%d", i),
+			fmt.Sprintf("00:00:00:00:%02x:%02x", i>>8, i&0xff), fmt.Sprintf("10.0.%d.%d", i>>8, i&0xff))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := network.AddLink(h, s); err != nil {
+			return nil, err
+		}
+		if prev != nil {
+			if _, err := network.AddLink(prev, s); err != nil {
+				return nil, err
+			}
+		}
+		prev = s
+	}
+	return network, nil
+}
+
+/*****************************************************/
+/* Switch                                            */
+/*****************************************************/
+
+/**
+ * Switch is an emulated switch in the network. Ports are added by AddLink.
+ */
+type Switch struct {
+	*ofswitch.Switch
+	name     string
+	network  *Network
+	nextPort uint32
+}
+
+func (s *Switch) Name() string {
+	return s.name
+}
+
+/**
+ * Flows returns flow entries in table tableId, or in all tables if tableId
+ * is OFPTT_ALL, as flow stats.
+ */
+func (s *Switch) Flows(tableId uint8) []*ofp13.OfpFlowStats {
+	req := ofp13.NewOfpFlowStatsRequest(0, tableId, ofp13.OFPP_ANY, ofp13.OFPG_ANY, 0, 0, ofp13.NewOfpMatch())
+	bodies, err := s.HandleMultipartRequest(req, s.Agent)
+	if err != nil {
+		return nil
+	}
+	flows := make([]*ofp13.OfpFlowStats, 0, len(bodies))
+	for _, body := range bodies {
+		flows = append(flows, body.(*ofp13.OfpFlowStats))
+	}
+	return flows
+}
+
+// AddSwitch adds switch of given name and datapath id without ports.
+func (n *Network) AddSwitch(name string, dpid uint64) *Switch {
+	s := &Switch{name: name, network: n, nextPort: 1}
+	s.Switch = ofswitch.NewSwitch(dpid, nil)
+	s.Transmit = func(portNo uint32, frame []byte) {
+		n.transmit(Endpoint{s, portNo}, frame)
+	}
+
+	n.mu.Lock()
+	n.switches = append(n.switches, s)
+	n.mu.Unlock()
+	return s
+}
+
+// Switches returns switches in added order.
+func (n *Network) Switches() []*Switch {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Switch(nil), n.switches...)
+}
+
+// Switch returns switch named name, or nil.
+func (n *Network) Switch(name string) *Switch {
+	for _, s := range n.Switches() {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+/*****************************************************/
+/* Link                                              */
+/*****************************************************/
+
+/**
+ * AddLink connects nodes a and b. A new port is added to switch, and
+ * notified to the controller if connected. Host can have only one link.
+ */
+func (n *Network) AddLink(a Node, b Node) (*Link, error) {
+	n.mu.Lock()
+	link := &Link{network: n, up: true}
+	var err error
+	if link.A, err = n.endpoint(a); err == nil {
+		link.B, err = n.endpoint(b)
+	}
+	if err != nil {
+		n.mu.Unlock()
+		return nil, err
+	}
+	n.links = append(n.links, link)
+	n.ports[link.A] = link
+	n.ports[link.B] = link
+	n.mu.Unlock()
+
+	for _, e := range []Endpoint{link.A, link.B} {
+		s, ok := e.Node.(*Switch)
+		if !ok {
+			continue
+		}
+		mac := fmt.Sprintf("02:%02x:%02x:%02x:%02x:%02x",
+			byte(s.DatapathId>>24), byte(s.DatapathId>>16), byte(s.DatapathId>>8), byte(s.DatapathId), byte(e.PortNo))
+		port, err := ofp13.NewOfpPort(e.PortNo, mac, fmt.Sprintf("%s-eth%d", s.name, e.PortNo))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.AddPort(port); err != nil {
+			return nil, err
+		}
+	}
+	return link, nil
+}
+
+// allocate endpoint of node for new link
+func (n *Network) endpoint(node Node) (Endpoint, error) {
+	switch v := node.(type) {
+	case *Switch:
+		if v.network != n {
+			return Endpoint{}, fmt.Errorf("switch %s is not in this network.", v.name)
+		}
+		e := Endpoint{v, v.nextPort}
+		v.nextPort++
+		return e, nil
+	case *Host:
+		if v.network != n {
+			return Endpoint{}, fmt.Errorf("host %s is not in this network.", v.name)
+		}
+		e := Endpoint{v, 0}
+		if n.ports[e] != nil {
+			return Endpoint{}, fmt.Errorf("host %s is already linked.", v.name)
+		}
+		return e, nil
+	}
+	return Endpoint{}, errors.New("unknown node.")
+}
+
+// Links returns links in added order.
+func (n *Network) Links() []*Link {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]*Link(nil), n.links...)
+}
+
+// Link returns link between nodes a and b, or nil.
+func (n *Network) Link(a Node, b Node) *Link {
+	for _, l := range n.Links() {
+		if (l.A.Node == a && l.B.Node == b) || (l.A.Node == b && l.B.Node == a) {
+			return l
+		}
+	}
+	return nil
+}
+
+// peer returns the other endpoint of e
+func (l *Link) peer(e Endpoint) Endpoint {
+	if l.A == e {
+		return l.B
+	}
+	return l.A
+}
+
+// Up reports whether the link carries frames.
+func (l *Link) Up() bool {
+	l.network.mu.Lock()
+	defer l.network.mu.Unlock()
+	return l.up
+}
+
+/**
+ * SetUp brings the link up or down. Link state of switch ports is changed,
+ * and PortStatus is sent to the controller.
+ */
+func (l *Link) SetUp(up bool) error {
+	l.network.mu.Lock()
+	l.up = up
+	l.network.mu.Unlock()
+
+	for _, e := range []Endpoint{l.A, l.B} {
+		s, ok := e.Node.(*Switch)
+		if !ok {
+			continue
+		}
+		port := s.Port(e.PortNo)
+		if port == nil {
+			continue
+		}
+		modified := *port
+		if up {
+			modified.State &^= ofp13.OFPPS_LINK_DOWN
+		} else {
+			modified.State |= ofp13.OFPPS_LINK_DOWN
+		}
+		if err := s.ModifyPort(&modified); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*****************************************************/
+/* Delivery                                          */
+/*****************************************************/
+
+// transmit carries frame sent out from endpoint to its peer
+func (n *Network) transmit(from Endpoint, frame []byte) {
+	n.mu.Lock()
+	link := n.ports[from]
+	if link == nil || !link.up {
+		n.mu.Unlock()
+		return
+	}
+	hop := Hop{from, link.peer(from), frame}
+	n.trace = append(n.trace, hop)
+	n.queue = append(n.queue, hop)
+	if n.draining {
+		// delivered by the goroutine draining the queue
+		n.mu.Unlock()
+		return
+	}
+
+	n.draining = true
+	for count := 0; len(n.queue) > 0; count++ {
+		if count >= MAX_DELIVERIES {
+			fmt.Println("too many frames are delivered, dropped", len(n.queue), "frames.")
+			n.queue = nil
+			break
+		}
+		hop := n.queue[0]
+		n.queue = n.queue[1:]
+		n.mu.Unlock()
+
+		switch to := hop.To.Node.(type) {
+		case *Switch:
+			to.Receive(hop.To.PortNo, hop.Frame)
+		case *Host:
+			to.receive(hop.Frame)
+		}
+		n.mu.Lock()
+	}
+	n.draining = false
+	n.mu.Unlock()
+}
+
+// Trace returns frames carried by links since last ClearTrace.
+func (n *Network) Trace() []Hop {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Hop(nil), n.trace...)
+}
+
+func (n *Network) ClearTrace() {
+	n.mu.Lock()
+	n.trace = nil
+	n.mu.Unlock()
+}
+
+/*****************************************************/
+/* Controller                                        */
+/*****************************************************/
+
+/**
+ * ConnectPipe connects all switches to the gofc controller running in this
+ * process by net.Pipe. Applications must be registered and started by the
+ * caller.
+ */
+func (n *Network) ConnectPipe() {
+	for _, s := range n.Switches() {
+		switchSide, controllerSide := net.Pipe()
+		go s.Serve(switchSide)
+		gofc.ServeConn(controllerSide)
+	}
+}
+
+/**
+ * Connect connects all switches to the controller at addr by TCP, such as
+ * gofc.ServerLoop listening on loopback.
+ */
+func (n *Network) Connect(addr string) error {
+	for _, s := range n.Switches() {
+		if err := s.Connect(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * WaitReady waits until all switches completed the handshake with the
+ * controller, or returns ErrTimeout.
+ */
+func (n *Network) WaitReady(timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for _, s := range n.Switches() {
+		select {
+		case <-s.Ready():
+		case <-deadline:
+			return ErrTimeout
+		}
+	}
+	return nil
+}
+
+/**
+ * WaitFor polls cond until it returns true, or returns ErrTimeout. It is
+ * used to wait for state changed by the controller asynchronously, such as
+ * flow entries installed on features reply.
+ */
+func WaitFor(timeout time.Duration, cond func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+// Close disconnects all switches from the controller.
+func (n *Network) Close() {
+	for _, s := range n.Switches() {
+		s.Close()
+	}
+}
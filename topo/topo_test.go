@@ -0,0 +1,255 @@
+package topo
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kmotiko/gofc"
+	"github.com/Kmotiko/gofc/ofprotocol/ofp13"
+)
+
+const testTimeout = 5 * time.Second
+
+/**
+ * learning switch application: table-miss flow entry is installed on
+ * handshake, and flow entries to learned ports are installed on PacketIn.
+ */
+type learningApp struct {
+	mu         sync.Mutex
+	ports      map[*gofc.Datapath]map[string]uint32
+	portStatus chan *ofp13.OfpPortStatus
+}
+
+func newLearningApp() *learningApp {
+	app := new(learningApp)
+	app.ports = make(map[*gofc.Datapath]map[string]uint32)
+	app.portStatus = make(chan *ofp13.OfpPortStatus, 16)
+	return app
+}
+
+func (app *learningApp) HandleSwitchFeatures(msg *ofp13.OfpSwitchFeatures, dp *gofc.Datapath) {
+	inst := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+	inst.Append(ofp13.NewOfpActionOutput(ofp13.OFPP_CONTROLLER, ofp13.OFPCML_NO_BUFFER))
+	dp.Send(ofp13.NewOfpFlowModAdd(0, 0, 0, 0, 0, ofp13.NewOfpMatch(), []ofp13.OfpInstruction{inst}))
+}
+
+func (app *learningApp) HandlePacketIn(msg *ofp13.OfpPacketIn, dp *gofc.Datapath) {
+	data := msg.GetData()
+	inPort, _ := msg.GetMatch().InPort()
+	dst := net.HardwareAddr(data[0:6]).String()
+	src := net.HardwareAddr(data[6:12]).String()
+
+	app.mu.Lock()
+	if app.ports[dp] == nil {
+		app.ports[dp] = make(map[string]uint32)
+	}
+	app.ports[dp][src] = inPort
+	outPort, ok := app.ports[dp][dst]
+	app.mu.Unlock()
+
+	if !ok {
+		outPort = ofp13.OFPP_FLOOD
+	} else {
+		ethDst, _ := ofp13.NewOxmEthDst(dst)
+		match := ofp13.NewOfpMatch()
+		match.Append(ethDst)
+		inst := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+		inst.Append(ofp13.NewOfpActionOutput(outPort, 0))
+		dp.Send(ofp13.NewOfpFlowModAdd(0, 0, 0, 10, 0, match, []ofp13.OfpInstruction{inst}))
+	}
+	actions := []ofp13.OfpAction{ofp13.NewOfpActionOutput(outPort, 0)}
+	dp.Send(ofp13.NewOfpPacketOut(ofp13.OFP_NO_BUFFER, inPort, actions, append([]byte(nil), data...)))
+}
+
+func (app *learningApp) HandlePortStatus(msg *ofp13.OfpPortStatus, dp *gofc.Datapath) {
+	select {
+	case app.portStatus <- msg:
+	default:
+	}
+}
+
+// register app to gofc, and unregister by returned func
+func registLearningApp(t *testing.T) (*learningApp, func()) {
+	app := newLearningApp()
+	if err := gofc.GetAppManager().RegistApplication(app); err != nil {
+		t.Fatal(err)
+	}
+	return app, func() { gofc.GetAppManager().UnregistApplication(app) }
+}
+
+// wait until table-miss flow entry is installed on all switches
+func waitTableMiss(t *testing.T, n *Network) {
+	if err := n.WaitReady(testTimeout); err != nil {
+		t.Fatal("Switches are not connected.")
+	}
+	err := WaitFor(testTimeout, func() bool {
+		for _, s := range n.Switches() {
+			if len(s.Flows(0)) == 0 {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal("Table-miss flow entries are not installed.")
+	}
+}
+
+/*****************************************************/
+/* Topology                                          */
+/*****************************************************/
+func TestLinear(t *testing.T) {
+	n, err := Linear(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Switches()) != 3 || len(n.Hosts()) != 3 || len(n.Links()) != 5 {
+		t.Fatal("Linear topology is invalid.")
+	}
+	s2 := n.Switch("s2")
+	if ports := s2.Ports(); len(ports) != 3 || !strings.HasPrefix(string(ports[0].Name), "s2-eth1") {
+		t.Log("Actual ports are : ", ports)
+		t.Error("Ports are not added by links.")
+	}
+	if l := n.Link(n.Switch("s1"), s2); l == nil || l.A.PortNo != 2 || l.B.PortNo != 2 {
+		t.Error("Link between s1 and s2 is invalid.")
+	}
+	if _, err := n.AddLink(n.Host("h1"), s2); err == nil {
+		t.Error("Host is linked twice.")
+	}
+}
+
+/*****************************************************/
+/* Trace                                             */
+/*****************************************************/
+func TestTrace(t *testing.T) {
+	n, _ := Linear(2)
+	h1, h2 := n.Host("h1"), n.Host("h2")
+
+	// forward all frames from port 1 to port 2, and from port 2 to port 1
+	for _, s := range n.Switches() {
+		s.MissSendToController = false
+		for _, ports := range [][2]uint32{{1, 2}, {2, 1}} {
+			match := ofp13.NewOfpMatch()
+			match.Append(ofp13.NewOxmInPort(ports[0]))
+			inst := ofp13.NewOfpInstructionActions(ofp13.OFPIT_APPLY_ACTIONS)
+			inst.Append(ofp13.NewOfpActionOutput(ports[1], 0))
+			fm := ofp13.NewOfpFlowModAdd(0, 0, 0, 10, 0, match, []ofp13.OfpInstruction{inst})
+			if err := s.HandleFlowMod(fm, s.Agent); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := Ping(h1, h2, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"h1:0 -> s1:1", "s1:2 -> s2:2", "s2:1 -> h2:0"}
+	trace := n.Trace()
+	if len(trace) != len(expected) {
+		t.Log("Actual trace is : ", trace)
+		t.Fatal("Trace is invalid.")
+	}
+	for i, hop := range trace {
+		if hop.From.String()+" -> "+hop.To.String() != expected[i] {
+			t.Log("Actual trace is : ", trace)
+			t.Error("Trace is invalid.")
+			break
+		}
+	}
+
+	// arp request is answered by host
+	n.ClearTrace()
+	h2.Send(h2.ARPRequest(h1.IP))
+	reply, err := h2.Recv(time.Second)
+	if err != nil || reply[21] != 2 || net.HardwareAddr(reply[6:12]).String() != h1.HwAddr.String() {
+		t.Error("Arp reply is not received.")
+	}
+	if len(n.Trace()) != 6 {
+		t.Log("Actual trace is : ", n.Trace())
+		t.Error("Trace of arp is invalid.")
+	}
+}
+
+/*****************************************************/
+/* Controller                                        */
+/*****************************************************/
+func TestPipeController(t *testing.T) {
+	app, unregist := registLearningApp(t)
+	defer unregist()
+
+	n, _ := Linear(3)
+	n.ConnectPipe()
+	defer n.Close()
+	waitTableMiss(t, n)
+
+	if err := n.PingAll(testTimeout); err != nil {
+		t.Fatal(err)
+	}
+	// h1 to h3 is forwarded by flow entries after learning
+	if len(n.Switch("s2").Flows(0)) < 2 {
+		t.Error("Flow entries are not installed by controller.")
+	}
+
+	// link down is notified, and frames are not carried
+	h1, h3 := n.Host("h1"), n.Host("h3")
+	link := n.Link(n.Switch("s2"), n.Switch("s3"))
+	if err := link.SetUp(false); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-app.portStatus:
+			if msg.Reason != ofp13.OFPPR_MODIFY || msg.Desc.State&ofp13.OFPPS_LINK_DOWN == 0 {
+				t.Log("Actual message is : ", msg)
+				t.Error("PortStatus of link down is invalid.")
+			}
+		case <-time.After(testTimeout):
+			t.Fatal("PortStatus is not received.")
+		}
+	}
+	if err := Ping(h1, h3, 100*time.Millisecond); err == nil {
+		t.Error("Frame is carried by link down.")
+	}
+
+	if err := link.SetUp(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Ping(h1, h3, testTimeout); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTCPController(t *testing.T) {
+	_, unregist := registLearningApp(t)
+	defer unregist()
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			gofc.ServeConn(conn)
+		}
+	}()
+
+	n, _ := Linear(2)
+	if err := n.Connect(listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	waitTableMiss(t, n)
+
+	if err := n.PingAll(testTimeout); err != nil {
+		t.Error(err)
+	}
+}